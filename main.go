@@ -1,12 +1,27 @@
 package main
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
 	log "github.com/sirupsen/logrus"
 	"github.com/wolfi-dev/wolfictl/pkg/cli"
 )
 
 func main() {
-	if err := cli.New().Execute(); err != nil {
+	// the first interrupt cancels the context of the command so it can stop and clean up after itself, a second one
+	// kills it right away
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := cli.New().ExecuteContext(ctx)
+	stop()
+	if err != nil {
 		log.Fatalf("error during command execution: %v", err)
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"sort"
 
 	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
 )
 
 // Latest returns the latest entry among the given set of entries for an
@@ -24,3 +26,45 @@ func Latest(entries []advisoryconfigs.Entry) *advisoryconfigs.Entry {
 	latestEntry := items[len(items)-1]
 	return &latestEntry
 }
+
+// LatestEvent returns the event of the latest entry of the package's advisory of the vulnerability, found under its
+// ID or one of its aliases, and false if the package has no advisory of the vulnerability.
+func LatestEvent(doc advisoryconfigs.Document, vulnID string, aliases vuln.Aliases) (Event, bool) {
+	latest := latestEntryOf(doc, vulnID, aliases)
+	if latest == nil {
+		return "", false
+	}
+	return EventOf(*latest), true
+}
+
+// Triaged returns the event of the latest entry of the package's advisory of the vulnerability, found under its ID or
+// one of its aliases, if the event settles the vulnerability for the package version: a false positive, a fix that
+// isn't planned, or a fix in that version or an earlier one. It returns false if the vulnerability still needs triage
+// for the version.
+func Triaged(doc advisoryconfigs.Document, vulnID, packageVersion string, aliases vuln.Aliases) (Event, bool) {
+	latest := latestEntryOf(doc, vulnID, aliases)
+	if latest == nil {
+		return "", false
+	}
+
+	event := EventOf(*latest)
+	switch event {
+	case EventFalsePositive, EventFixNotPlanned:
+		return event, true
+	case EventFixed:
+		// versions from before the fix are still vulnerable
+		if latest.FixedVersion != "" && dag.CompareVersions(packageVersion, latest.FixedVersion) < 0 {
+			return "", false
+		}
+		return event, true
+	}
+	return "", false
+}
+
+func latestEntryOf(doc advisoryconfigs.Document, vulnID string, aliases vuln.Aliases) *advisoryconfigs.Entry {
+	id, ok := advisoryIDOf(doc.Advisories, vulnID, aliases)
+	if !ok {
+		return nil
+	}
+	return Latest(doc.Advisories[id])
+}
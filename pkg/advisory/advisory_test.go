@@ -0,0 +1,48 @@
+package advisory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/stretchr/testify/assert"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+)
+
+func TestTriaged(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, 10, d, 10, 0, 0, 0, time.UTC) }
+	detected := advisoryconfigs.Entry{Timestamp: day(11), Status: vex.StatusUnderInvestigation}
+	doc := advisoryconfigs.Document{
+		Package: advisoryconfigs.Package{Name: "curl"},
+		Advisories: advisoryconfigs.Advisories{
+			"CVE-2023-38545": {detected, {Timestamp: day(12), Status: vex.StatusFixed, FixedVersion: "8.4.0-r0"}},
+			"CVE-2023-38546": {detected},
+			"CVE-2023-28319": {{Timestamp: day(11), Status: vex.StatusNotAffected, Justification: vex.VulnerableCodeNotPresent}},
+			"CVE-2023-28320": {{Timestamp: day(11), Status: vex.StatusAffected, ActionStatement: FixNotPlannedAction}},
+		},
+	}
+	aliases := vuln.Aliases{"GHSA-wgvw-4c2w-fxwx": {"CVE-2023-38545"}}
+
+	cases := []struct {
+		vuln, version string
+		want          Event
+		wantTriaged   bool
+	}{
+		{"CVE-2023-38545", "8.4.0-r0", EventFixed, true},
+		{"CVE-2023-38545", "8.4.0-r1", EventFixed, true},
+		{"CVE-2023-38545", "8.3.0-r0", "", false},
+		{"GHSA-wgvw-4c2w-fxwx", "8.4.0-r0", EventFixed, true},
+		{"CVE-2023-38546", "8.4.0-r0", "", false},
+		{"CVE-2023-28319", "8.4.0-r0", EventFalsePositive, true},
+		{"CVE-2023-28320", "8.4.0-r0", EventFixNotPlanned, true},
+		{"CVE-2099-0001", "8.4.0-r0", "", false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.vuln+"@"+tt.version, func(t *testing.T) {
+			event, triaged := Triaged(doc, tt.vuln, tt.version, aliases)
+			assert.Equal(t, tt.wantTriaged, triaged)
+			assert.Equal(t, tt.want, event)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package advisory
+
+import (
+	"sort"
+
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+)
+
+// advisoryIDOf returns the ID the advisories have the vulnerability under, either id or one of its aliases, and false
+// if they don't have it.
+func advisoryIDOf(advisories advisoryconfigs.Advisories, id string, aliases vuln.Aliases) (string, bool) {
+	if _, ok := advisories[id]; ok {
+		return id, true
+	}
+
+	ids := make([]string, 0, len(advisories))
+	for existing := range advisories {
+		ids = append(ids, existing)
+	}
+	sort.Strings(ids)
+	for _, existing := range ids {
+		if aliases.Same(id, existing) {
+			return existing, true
+		}
+	}
+	return "", false
+}
+
+// aliasGroups returns the IDs of the advisories grouped by vulnerability, the IDs of each group being aliases of
+// each other, sorted.
+func aliasGroups(advisories advisoryconfigs.Advisories, aliases vuln.Aliases) [][]string {
+	ids := make([]string, 0, len(advisories))
+	for id := range advisories {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	grouped := make(map[string]bool)
+	var groups [][]string
+	for _, id := range ids {
+		if grouped[id] {
+			continue
+		}
+		group := []string{id}
+		grouped[id] = true
+		for _, other := range ids {
+			if !grouped[other] && aliases.Same(id, other) {
+				group = append(group, other)
+				grouped[other] = true
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
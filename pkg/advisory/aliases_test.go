@@ -0,0 +1,107 @@
+package advisory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+)
+
+var curlAliases = vuln.Aliases{
+	"GHSA-66p4-3h4c-hcfw": {"CVE-2023-0001"},
+	"CVE-2023-0001":       {"GHSA-66p4-3h4c-hcfw"},
+}
+
+func TestCreateAndUpdateWithAliases(t *testing.T) {
+	dir := t.TempDir()
+	b, err := os.ReadFile("testdata/discover/curl.advisories.yaml")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "curl.advisories.yaml"), b, 0o600))
+
+	advisoryCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	require.NoError(t, err)
+
+	req := Request{
+		Package:       "curl",
+		Vulnerability: "GHSA-66p4-3h4c-hcfw",
+		Status:        vex.StatusUnderInvestigation,
+		Timestamp:     time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+	err = Create(req, CreateOptions{AdvisoryCfgs: advisoryCfgs, Aliases: curlAliases})
+	assert.ErrorContains(t, err, "advisory already exists for GHSA-66p4-3h4c-hcfw, as its alias CVE-2023-0001")
+
+	req.Status, req.FixedVersion = vex.StatusFixed, "8.1.0-r0"
+	require.NoError(t, Update(req, UpdateOptions{AdvisoryCfgs: advisoryCfgs, Aliases: curlAliases}))
+
+	advisoryCfgs, err = advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	require.NoError(t, err)
+	curl, err := advisoryCfgs.Select().WhereName("curl").First()
+	require.NoError(t, err)
+	advisories := curl.Configuration().Advisories
+	assert.Len(t, advisories, 1, "the advisory is updated under the ID it has")
+	require.Len(t, advisories["CVE-2023-0001"], 2)
+	assert.Equal(t, "8.1.0-r0", advisories["CVE-2023-0001"][1].FixedVersion)
+
+	// without the aliases, they're different vulnerabilities
+	err = Update(req, UpdateOptions{AdvisoryCfgs: advisoryCfgs})
+	assert.ErrorContains(t, err, "no advisory exists for GHSA-66p4-3h4c-hcfw")
+}
+
+func TestCreateUnderCanonicalID(t *testing.T) {
+	dir := t.TempDir()
+	advisoryCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	require.NoError(t, err)
+
+	req := Request{
+		Package:       "wget",
+		Vulnerability: "GHSA-66p4-3h4c-hcfw",
+		Status:        vex.StatusUnderInvestigation,
+		Timestamp:     time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, Create(req, CreateOptions{AdvisoryCfgs: advisoryCfgs, Aliases: curlAliases}))
+	err = Create(req, CreateOptions{AdvisoryCfgs: advisoryCfgs, Aliases: curlAliases})
+	assert.ErrorContains(t, err, "advisory already exists for GHSA-66p4-3h4c-hcfw, as its alias CVE-2023-0001")
+
+	advisoryCfgs, err = advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	require.NoError(t, err)
+	wget, err := advisoryCfgs.Select().WhereName("wget").First()
+	require.NoError(t, err)
+	assert.Contains(t, wget.Configuration().Advisories, "CVE-2023-0001", "the advisory is kept under the CVE of the GHSA")
+	assert.Len(t, wget.Configuration().Advisories, 1)
+}
+
+func TestExportSecfixesWithAliases(t *testing.T) {
+	advisories := advisoryconfigs.Advisories{
+		"CVE-2023-0001": {{
+			Timestamp: time.Date(2023, 5, 18, 0, 0, 0, 0, time.UTC),
+			Status:    vex.StatusUnderInvestigation,
+		}},
+		"GHSA-66p4-3h4c-hcfw": {{
+			Timestamp:    time.Date(2023, 5, 19, 0, 0, 0, 0, time.UTC),
+			Status:       vex.StatusFixed,
+			FixedVersion: "8.1.0-r0",
+		}},
+		"CVE-2023-0002": {{
+			Timestamp:     time.Date(2023, 5, 18, 0, 0, 0, 0, time.UTC),
+			Status:        vex.StatusNotAffected,
+			Justification: vex.VulnerableCodeNotPresent,
+		}},
+	}
+
+	assert.Equal(t, Secfixes{
+		"0":        {"CVE-2023-0002"},
+		"8.1.0-r0": {"GHSA-66p4-3h4c-hcfw"},
+	}, exportSecfixes(advisories, nil), "without the aliases, scanners reporting the CVE aren't told it's fixed")
+
+	assert.Equal(t, Secfixes{
+		"0":        {"CVE-2023-0002"},
+		"8.1.0-r0": {"CVE-2023-0001", "GHSA-66p4-3h4c-hcfw"},
+	}, exportSecfixes(advisories, curlAliases), "the latest entry of the vulnerability is listed under all its IDs")
+}
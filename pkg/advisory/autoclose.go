@@ -0,0 +1,137 @@
+package advisory
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+)
+
+var reVulnerabilityID = regexp.MustCompile(`(?i)\b(CVE-\d{4}-\d{4,}|GHSA(-[23456789cfghjmpqrvwx]{4}){3})\b`)
+
+// AutoCloseOptions configures the AutoClose operation.
+type AutoCloseOptions struct {
+	// AdvisoryCfgs is the Index of advisory configurations on which to operate.
+	AdvisoryCfgs *configs.Index[advisoryconfigs.Document]
+
+	// Package is the name of the package whose version was bumped.
+	Package string
+
+	// FixedVersion is the full version (including the epoch) the bump introduced, e.g. "1.2.3-r0".
+	FixedVersion string
+
+	// Vulnerabilities are the IDs the bump claims to fix. Only pending advisories for these are closed.
+	Vulnerabilities []string
+
+	// Timestamp is used for the appended "fixed" entries.
+	Timestamp time.Time
+
+	// Confirm, if set, is called before each advisory is closed, and the advisory is left alone if it returns
+	// false.
+	Confirm func(req Request) (bool, error)
+}
+
+// AutoClose appends a "fixed" entry to each advisory of the package that is still pending (its latest status is
+// under_investigation or affected) and is for one of the given vulnerabilities. It returns the requests that were
+// applied.
+func AutoClose(opts AutoCloseOptions) ([]Request, error) {
+	if opts.FixedVersion == "" {
+		return nil, fmt.Errorf("fixed version cannot be empty")
+	}
+
+	advisoryCfgs := opts.AdvisoryCfgs.Select().WhereName(opts.Package)
+	if advisoryCfgs.Len() == 0 {
+		return nil, nil
+	}
+
+	var applied []Request
+	for _, vulnID := range pendingVulnerabilities(advisoryCfgs.Configurations(), opts.Vulnerabilities) {
+		req := Request{
+			Package:       opts.Package,
+			Vulnerability: vulnID,
+			Status:        vex.StatusFixed,
+			FixedVersion:  opts.FixedVersion,
+			Timestamp:     opts.Timestamp,
+		}
+
+		if opts.Confirm != nil {
+			ok, err := opts.Confirm(req)
+			if err != nil {
+				return applied, err
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		if err := Update(req, UpdateOptions{AdvisoryCfgs: opts.AdvisoryCfgs}); err != nil {
+			return applied, err
+		}
+		applied = append(applied, req)
+	}
+
+	return applied, nil
+}
+
+// pendingVulnerabilities returns the sorted IDs among vulnIDs that have a pending advisory in docs.
+func pendingVulnerabilities(docs []advisoryconfigs.Document, vulnIDs []string) []string {
+	wanted := make(map[string]struct{}, len(vulnIDs))
+	for _, id := range vulnIDs {
+		wanted[id] = struct{}{}
+	}
+
+	var pending []string
+	for _, id := range PendingVulnerabilities(docs) {
+		if _, ok := wanted[id]; ok {
+			pending = append(pending, id)
+		}
+	}
+	return pending
+}
+
+// PendingVulnerabilities returns the sorted IDs of the vulnerabilities with a pending advisory in docs, i.e. one whose
+// latest status is under_investigation or affected.
+func PendingVulnerabilities(docs []advisoryconfigs.Document) []string {
+	var pending []string
+	for i := range docs {
+		for id, entries := range docs[i].Advisories {
+			if latest := Latest(entries); latest != nil && isPending(latest.Status) {
+				pending = append(pending, id)
+			}
+		}
+	}
+
+	sort.Strings(pending)
+	return pending
+}
+
+func isPending(status vex.Status) bool {
+	return status == vex.StatusUnderInvestigation || status == vex.StatusAffected
+}
+
+// ExtractVulnerabilityIDs returns the unique CVE and GHSA IDs mentioned in text, in the order they first appear,
+// normalized to the case used in advisories.
+func ExtractVulnerabilityIDs(text string) []string {
+	seen := make(map[string]struct{})
+	var ids []string
+	for _, match := range reVulnerabilityID.FindAllString(text, -1) {
+		var id string
+		if strings.HasPrefix(strings.ToUpper(match), "CVE") {
+			id = strings.ToUpper(match)
+		} else {
+			id = "GHSA" + strings.ToLower(match[len("GHSA"):])
+		}
+
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	return ids
+}
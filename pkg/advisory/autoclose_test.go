@@ -0,0 +1,69 @@
+package advisory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+)
+
+func TestExtractVulnerabilityIDs(t *testing.T) {
+	text := `curl/8.1.0 package update
+
+fixes: cve-2023-0001
+Fixes: CVE-2023-0002, GHSA-33PG-M6JH-5237 and CVE-2023-0001 again`
+
+	assert.Equal(t, []string{"CVE-2023-0001", "CVE-2023-0002", "GHSA-33pg-m6jh-5237"}, ExtractVulnerabilityIDs(text))
+	assert.Empty(t, ExtractVulnerabilityIDs("no vulnerabilities here, CVE-23-1 is not an ID"))
+}
+
+func TestAutoClose(t *testing.T) {
+	dir := t.TempDir()
+	b, err := os.ReadFile("testdata/autoclose/curl.advisories.yaml")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "curl.advisories.yaml"), b, 0o600))
+
+	advisoryCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	require.NoError(t, err)
+
+	var asked []string
+	timestamp := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	applied, err := AutoClose(AutoCloseOptions{
+		AdvisoryCfgs: advisoryCfgs,
+		Package:      "curl",
+		FixedVersion: "8.1.0-r0",
+		// CVE-2023-0003 isn't pending, CVE-2023-0004 isn't referenced, CVE-2023-9999 has no advisory
+		Vulnerabilities: []string{"CVE-2023-0001", "CVE-2023-0002", "CVE-2023-0003", "CVE-2023-9999"},
+		Timestamp:       timestamp,
+		Confirm: func(req Request) (bool, error) {
+			asked = append(asked, req.Vulnerability)
+			return req.Vulnerability != "CVE-2023-0002", nil
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"CVE-2023-0001", "CVE-2023-0002"}, asked)
+	require.Len(t, applied, 1)
+	assert.Equal(t, "CVE-2023-0001", applied[0].Vulnerability)
+
+	// re-read from disk to make sure the change was written
+	advisoryCfgs, err = advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	require.NoError(t, err)
+	doc := advisoryCfgs.Select().WhereName("curl").Configurations()[0]
+
+	latest := Latest(doc.Advisories["CVE-2023-0001"])
+	require.NotNil(t, latest)
+	assert.Equal(t, vex.StatusFixed, latest.Status)
+	assert.Equal(t, "8.1.0-r0", latest.FixedVersion)
+	assert.True(t, timestamp.Equal(latest.Timestamp))
+
+	for _, id := range []string{"CVE-2023-0002", "CVE-2023-0003", "CVE-2023-0004"} {
+		assert.NotEqual(t, vex.StatusFixed, Latest(doc.Advisories[id]).Status, id)
+	}
+}
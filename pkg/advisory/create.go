@@ -5,18 +5,25 @@ import (
 
 	"github.com/wolfi-dev/wolfictl/pkg/configs"
 	"github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
 )
 
 // CreateOptions configures the Create operation.
 type CreateOptions struct {
 	// AdvisoryCfgs is the Index of advisory configurations on which to operate.
 	AdvisoryCfgs *configs.Index[advisory.Document]
+
+	// Aliases are the aliases of the vulnerability, so that it isn't created again under another ID.
+	Aliases vuln.Aliases
 }
 
 // Create creates a new advisory in the `advisories` section of the configuration
-// at the provided path.
+// at the provided path. The advisory is kept under the canonical ID of the vulnerability
+// among its aliases, e.g. the CVE of a GHSA.
 func Create(req Request, opts CreateOptions) error {
-	vulnID := req.Vulnerability
+	vulnID := vuln.CanonicalID(opts.Aliases.Of(req.Vulnerability)...)
+	requested := req.Vulnerability
+	req.Vulnerability = vulnID
 	advisoryEntry := req.toAdvisoryEntry()
 
 	advisoryCfgs := opts.AdvisoryCfgs.Select().WhereName(req.Package)
@@ -31,8 +38,11 @@ func Create(req Request, opts CreateOptions) error {
 		// i.e. exactly one advisories file for this package
 		u := advisory.NewAdvisoriesSectionUpdater(func(cfg advisory.Document) (advisory.Advisories, error) {
 			advisories := cfg.Advisories
-			if _, existsAlready := advisories[vulnID]; existsAlready {
-				return advisory.Advisories{}, fmt.Errorf("advisory already exists for %s", vulnID)
+			if existing, existsAlready := advisoryIDOf(advisories, requested, opts.Aliases); existsAlready {
+				if existing != requested {
+					return advisory.Advisories{}, fmt.Errorf("advisory already exists for %s, as its alias %s", requested, existing)
+				}
+				return advisory.Advisories{}, fmt.Errorf("advisory already exists for %s", requested)
 			}
 
 			advisories[vulnID] = append(advisories[vulnID], advisoryEntry)
@@ -0,0 +1,186 @@
+package advisory
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+)
+
+// ChangeKind is how an advisory changed between two states of the advisories repository.
+type ChangeKind string
+
+const (
+	// ChangeAdded is an advisory that didn't exist before.
+	ChangeAdded ChangeKind = "added"
+	// ChangeRemoved is an advisory that doesn't exist anymore.
+	ChangeRemoved ChangeKind = "removed"
+	// ChangeTransitioned is an advisory whose new entries changed its state, e.g. from detected to fixed.
+	ChangeTransitioned ChangeKind = "transitioned"
+	// ChangeUpdated is an advisory with new entries that left its state as it was.
+	ChangeUpdated ChangeKind = "updated"
+	// ChangeRewritten is an advisory whose existing entries were changed or removed, rather than only added to.
+	ChangeRewritten ChangeKind = "rewritten"
+)
+
+// AdvisoryState is the state of an advisory, as of its latest entry.
+type AdvisoryState struct {
+	Event         Event             `json:"event,omitempty"`
+	Status        vex.Status        `json:"status"`
+	FixedVersion  string            `json:"fixedVersion,omitempty"`
+	Justification vex.Justification `json:"justification,omitempty"`
+	Action        string            `json:"action,omitempty"`
+}
+
+func stateOf(entry advisoryconfigs.Entry) AdvisoryState {
+	return AdvisoryState{
+		Event:         EventOf(entry),
+		Status:        entry.Status,
+		FixedVersion:  entry.FixedVersion,
+		Justification: entry.Justification,
+		Action:        entry.ActionStatement,
+	}
+}
+
+func (s AdvisoryState) String() string {
+	switch s.Event {
+	case EventFixed:
+		return fmt.Sprintf("fixed in %s", s.FixedVersion)
+	case EventFalsePositive:
+		return fmt.Sprintf("false-positive (%s)", s.Justification)
+	case EventDetected, EventFixNotPlanned:
+		return string(s.Event)
+	}
+	if s.Action != "" {
+		return fmt.Sprintf("%s (%s)", s.Status, s.Action)
+	}
+	return string(s.Status)
+}
+
+// AdvisoryChange is a change to the advisory of a vulnerability of a package. Old is the state before the change,
+// and New the one after, nil for added and removed advisories respectively.
+type AdvisoryChange struct {
+	Package       string         `json:"package"`
+	Vulnerability string         `json:"vulnerability"`
+	Kind          ChangeKind     `json:"kind"`
+	Old           *AdvisoryState `json:"old,omitempty"`
+	New           *AdvisoryState `json:"new,omitempty"`
+
+	// NewEntries is the number of entries added to the advisory.
+	NewEntries int `json:"newEntries,omitempty"`
+}
+
+func (c AdvisoryChange) String() string {
+	prefix := fmt.Sprintf("%s: %s", c.Package, c.Vulnerability)
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("%s new advisory, %s", prefix, c.New)
+	case ChangeRemoved:
+		return fmt.Sprintf("%s advisory removed, was %s", prefix, c.Old)
+	case ChangeTransitioned:
+		return fmt.Sprintf("%s %s → %s", prefix, c.Old, c.New)
+	case ChangeUpdated:
+		events := "events"
+		if c.NewEntries == 1 {
+			events = "event"
+		}
+		return fmt.Sprintf("%s %d new %s, still %s", prefix, c.NewEntries, events, c.New)
+	case ChangeRewritten:
+		return fmt.Sprintf("%s history rewritten, %s → %s", prefix, c.Old, c.New)
+	}
+	return prefix
+}
+
+// Diff returns the changes to the advisories of the documents between base and current, two states of the
+// advisories repository, sorted by package and vulnerability.
+func Diff(base, current []advisoryconfigs.Document) []AdvisoryChange {
+	baseByKey := advisoriesByKey(base)
+	currentByKey := advisoriesByKey(current)
+
+	var changes []AdvisoryChange
+	for k, baseEntries := range baseByKey {
+		currentEntries, ok := currentByKey[k]
+		if !ok {
+			if latest := Latest(baseEntries); latest != nil {
+				old := stateOf(*latest)
+				changes = append(changes, AdvisoryChange{Package: k.pkg, Vulnerability: k.vuln, Kind: ChangeRemoved, Old: &old})
+			}
+			continue
+		}
+		if c, ok := diffEntries(baseEntries, currentEntries); ok {
+			c.Package, c.Vulnerability = k.pkg, k.vuln
+			changes = append(changes, c)
+		}
+	}
+	for k, currentEntries := range currentByKey {
+		if _, ok := baseByKey[k]; ok {
+			continue
+		}
+		if latest := Latest(currentEntries); latest != nil {
+			n := stateOf(*latest)
+			changes = append(changes, AdvisoryChange{Package: k.pkg, Vulnerability: k.vuln, Kind: ChangeAdded, New: &n, NewEntries: len(currentEntries)})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Package != changes[j].Package {
+			return changes[i].Package < changes[j].Package
+		}
+		return changes[i].Vulnerability < changes[j].Vulnerability
+	})
+	return changes
+}
+
+type advisoryKey struct {
+	pkg, vuln string
+}
+
+func advisoriesByKey(docs []advisoryconfigs.Document) map[advisoryKey][]advisoryconfigs.Entry {
+	m := make(map[advisoryKey][]advisoryconfigs.Entry)
+	for _, doc := range docs {
+		for vuln, entries := range doc.Advisories {
+			m[advisoryKey{pkg: doc.Package.Name, vuln: vuln}] = entries
+		}
+	}
+	return m
+}
+
+// diffEntries returns the change between the entries of an advisory, and false if they're the same.
+func diffEntries(base, current []advisoryconfigs.Entry) (AdvisoryChange, bool) {
+	oldLatest, newLatest := Latest(base), Latest(current)
+	if oldLatest == nil || newLatest == nil {
+		// an advisory without entries has no state to compare
+		return AdvisoryChange{}, false
+	}
+	old, n := stateOf(*oldLatest), stateOf(*newLatest)
+	c := AdvisoryChange{Old: &old, New: &n}
+
+	appended := len(current) >= len(base)
+	for i := 0; appended && i < len(base); i++ {
+		appended = entriesEqual(base[i], current[i])
+	}
+	switch {
+	case !appended:
+		c.Kind = ChangeRewritten
+	case len(current) == len(base):
+		return AdvisoryChange{}, false
+	case old != n:
+		c.Kind = ChangeTransitioned
+	default:
+		c.Kind = ChangeUpdated
+	}
+	if appended {
+		c.NewEntries = len(current) - len(base)
+	}
+	return c, true
+}
+
+func entriesEqual(a, b advisoryconfigs.Entry) bool {
+	return a.Timestamp.Equal(b.Timestamp) &&
+		a.Status == b.Status &&
+		a.Justification == b.Justification &&
+		a.ImpactStatement == b.ImpactStatement &&
+		a.ActionStatement == b.ActionStatement &&
+		a.FixedVersion == b.FixedVersion
+}
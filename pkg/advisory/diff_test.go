@@ -0,0 +1,80 @@
+package advisory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/stretchr/testify/assert"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+)
+
+func TestDiff(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, 5, d, 10, 0, 0, 0, time.UTC) }
+	detected := advisoryconfigs.Entry{Timestamp: day(18), Status: vex.StatusUnderInvestigation}
+	fixed := advisoryconfigs.Entry{Timestamp: day(19), Status: vex.StatusFixed, FixedVersion: "8.1.0-r0"}
+	redetected := advisoryconfigs.Entry{Timestamp: day(19), Status: vex.StatusUnderInvestigation}
+	falsePositive := advisoryconfigs.Entry{Timestamp: day(18), Status: vex.StatusNotAffected, Justification: vex.VulnerableCodeNotPresent}
+
+	base := []advisoryconfigs.Document{
+		{
+			Package: advisoryconfigs.Package{Name: "curl"},
+			Advisories: advisoryconfigs.Advisories{
+				"CVE-2023-28319": {detected},
+				"CVE-2023-28320": {detected},
+				"CVE-2023-28321": {detected},
+				"CVE-2023-28322": {detected},
+				"CVE-2023-28323": {detected},
+			},
+		},
+		{
+			Package: advisoryconfigs.Package{Name: "zlib"},
+			Advisories: advisoryconfigs.Advisories{
+				"CVE-2022-37434": {falsePositive},
+			},
+		},
+	}
+	current := []advisoryconfigs.Document{
+		{
+			Package: advisoryconfigs.Package{Name: "curl"},
+			Advisories: advisoryconfigs.Advisories{
+				"CVE-2023-28319": {detected, fixed},
+				"CVE-2023-28320": {detected, redetected},
+				"CVE-2023-28321": {fixed},
+				"CVE-2023-28322": {detected},
+				"CVE-2023-38545": {detected},
+			},
+		},
+		{
+			Package: advisoryconfigs.Package{Name: "zlib"},
+			Advisories: advisoryconfigs.Advisories{
+				"CVE-2022-37434": {falsePositive},
+			},
+		},
+	}
+
+	changes := Diff(base, current)
+
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	assert.Equal(t, []string{
+		"curl: CVE-2023-28319 detected → fixed in 8.1.0-r0",
+		"curl: CVE-2023-28320 1 new event, still detected",
+		"curl: CVE-2023-28321 history rewritten, detected → fixed in 8.1.0-r0",
+		"curl: CVE-2023-28323 advisory removed, was detected",
+		"curl: CVE-2023-38545 new advisory, detected",
+	}, got)
+
+	assert.Equal(t, AdvisoryChange{
+		Package:       "curl",
+		Vulnerability: "CVE-2023-28319",
+		Kind:          ChangeTransitioned,
+		Old:           &AdvisoryState{Event: EventDetected, Status: vex.StatusUnderInvestigation},
+		New:           &AdvisoryState{Event: EventFixed, Status: vex.StatusFixed, FixedVersion: "8.1.0-r0"},
+		NewEntries:    1,
+	}, changes[0])
+
+	assert.Empty(t, Diff(current, current))
+}
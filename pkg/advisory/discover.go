@@ -2,12 +2,14 @@ package advisory
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"path/filepath"
 	"sort"
 	"time"
 
-	"chainguard.dev/melange/pkg/build"
 	"github.com/openvex/go-vex/pkg/vex"
 	"github.com/samber/lo"
 	"github.com/savioxavier/termlink"
@@ -23,43 +25,42 @@ type DiscoverOptions struct {
 	// SelectedPackages is a list of packages to include in search. If empty, all packages will be included in search.
 	SelectedPackages []string
 
-	// BuildCfgs is the Index of build configurations on which to operate.
-	BuildCfgs *configs.Index[build.Configuration]
-
 	// AdvisoryCfgs is the Index of advisories on which to operate.
 	AdvisoryCfgs *configs.Index[advisoryconfigs.Document]
 
 	// PackageRepositoryURL is the URL to the distro's package repository (e.g. "https://packages.wolfi.dev/os").
 	PackageRepositoryURL string
 
+	// PackagesDir is the directory of the packages of the latest build, with a subdirectory per arch (e.g.
+	// "./packages"). If set, its packages are searched for instead of the ones published to PackageRepositoryURL.
+	PackagesDir string
+
 	// The Arches to select during discovery (e.g. "x86_64").
 	Arches []string
 
 	// VulnerabilityDetector is how Discover finds for vulnerabilities for packages.
 	VulnerabilityDetector vuln.Detector
-}
 
-// Discover searches for new vulnerabilities that match packages in a config
-// index, and adds new advisories to configs for vulnerabilities that haven't
-// been noted yet.
-func Discover(opts DiscoverOptions) error {
-	ctx := context.Background()
+	// AliasFinder finds the aliases of the vulnerabilities found, so that the ones the advisories have under another
+	// ID aren't recorded again. If nil, only their own IDs are looked for.
+	AliasFinder vuln.AliasFinder
+}
 
-	packageRepositoryURL := opts.PackageRepositoryURL
-	if packageRepositoryURL == "" {
-		return fmt.Errorf("package repository URL must be specified")
+// Discover searches for new vulnerabilities that match the latest versions of
+// the published or built packages, and adds a detected entry to the advisories
+// of their origin package for vulnerabilities that haven't been noted yet.
+func Discover(ctx context.Context, opts DiscoverOptions) error {
+	apkindexes, err := discoverIndexes(ctx, opts)
+	if err != nil {
+		return err
 	}
 
-	var apkindexes []*repository.ApkIndex
-	for _, arch := range opts.Arches {
-		apkindex, err := index.Index(arch, packageRepositoryURL)
-		if err != nil {
-			return fmt.Errorf("unable to get APKINDEX for arch %q: %w", arch, err)
-		}
-		apkindexes = append(apkindexes, apkindex)
-	}
+	return discover(ctx, opts, apkindexes)
+}
 
+func discover(ctx context.Context, opts DiscoverOptions, apkindexes []*repository.ApkIndex) error {
 	packagesToLookup := determinePackagesToLookup(apkindexes, opts.SelectedPackages)
+	versions := latestPublishedVersions(apkindexes)
 
 	vulnMatches, err := opts.VulnerabilityDetector.VulnerabilitiesForPackages(ctx, packagesToLookup...)
 	if err != nil {
@@ -69,7 +70,7 @@ func Discover(opts DiscoverOptions) error {
 	for _, pkg := range packagesToLookup {
 		pkgVulnMatches := vulnMatches[pkg]
 
-		err := processPkgVulnMatches(opts, pkg, pkgVulnMatches)
+		err := processPkgVulnMatches(ctx, opts, pkg, upstreamVersion(versions[pkg]), pkgVulnMatches)
 		if err != nil {
 			return err
 		}
@@ -78,21 +79,58 @@ func Discover(opts DiscoverOptions) error {
 	return nil
 }
 
-func processPkgVulnMatches(opts DiscoverOptions, pkg string, matches []vuln.Match) error {
-	buildCfgEntry, _ := opts.BuildCfgs.Select().WhereName(pkg).First() //nolint:errcheck
-	buildCfg := buildCfgEntry.Configuration()
+// discoverIndexes returns the indexes of the packages to search for, the built ones if there's a packages dir, and
+// the published ones otherwise.
+func discoverIndexes(ctx context.Context, opts DiscoverOptions) ([]*repository.ApkIndex, error) {
+	if opts.PackagesDir == "" && opts.PackageRepositoryURL == "" {
+		return nil, fmt.Errorf("package repository URL or packages dir must be specified")
+	}
+
+	var apkindexes []*repository.ApkIndex
+	for _, arch := range opts.Arches {
+		var apkindex *repository.ApkIndex
+		var err error
+		if opts.PackagesDir != "" {
+			apkindex, err = index.FromDirectory(filepath.Join(opts.PackagesDir, arch))
+			if errors.Is(err, fs.ErrNotExist) {
+				// nothing was built for this arch
+				continue
+			}
+		} else {
+			apkindex, err = index.Index(ctx, arch, opts.PackageRepositoryURL)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to get APKINDEX for arch %q: %w", arch, err)
+		}
+		apkindexes = append(apkindexes, apkindex)
+	}
+	return apkindexes, nil
+}
 
+func processPkgVulnMatches(ctx context.Context, opts DiscoverOptions, pkg, version string, matches []vuln.Match) error {
 	for i := range matches {
 		match := matches[i]
-		if !match.CPE.VersionRange.Includes(buildCfg.Package.Version) {
+		if !match.CPE.VersionRange.Includes(version) {
 			continue
 		}
 
 		advCfgEntries := opts.AdvisoryCfgs.Select().WhereName(pkg)
-		vulnID := match.Vulnerability.ID
+		matchedID := match.Vulnerability.ID
+		var aliases vuln.Aliases
+		if opts.AliasFinder != nil {
+			found, err := opts.AliasFinder.Aliases(ctx, matchedID)
+			if err != nil {
+				return err
+			}
+			aliases = vuln.Aliases{matchedID: found}
+		}
+		// the advisory is kept under the canonical ID of the vulnerability, e.g. the CVE of a GHSA
+		vulnID := vuln.CanonicalID(aliases.Of(matchedID)...)
 		if advCfgEntries.Len() == 0 {
 			// create a brand-new advisory config
 
+			log.Printf("🐛 new potential vulnerability for package %q: %s", pkg, hyperlinkCVE(vulnID))
+
 			err := createAdvisoryConfig(opts.AdvisoryCfgs, Request{
 				Package:       pkg,
 				Vulnerability: vulnID,
@@ -110,8 +148,10 @@ func processPkgVulnMatches(opts DiscoverOptions, pkg string, matches []vuln.Matc
 
 		advCfgEntry, _ := advCfgEntries.First() //nolint:errcheck
 		advCfg := advCfgEntry.Configuration()
-		if _, ok := advCfg.Advisories[vulnID]; ok {
-			// advisory already exists in config
+		if existing, ok := advisoryIDOf(advCfg.Advisories, matchedID, aliases); ok {
+			if existing != matchedID {
+				log.Printf("advisory for %s already exists for package %q, as its alias %s", matchedID, advCfg.Package.Name, existing)
+			}
 			continue
 		}
 
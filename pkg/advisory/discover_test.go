@@ -0,0 +1,85 @@
+package advisory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+)
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	b, err := os.ReadFile("testdata/discover/curl.advisories.yaml")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "curl.advisories.yaml"), b, 0o600))
+
+	advisoryCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	require.NoError(t, err)
+
+	detector := fakeDetector{
+		"curl": {
+			// already triaged
+			match("CVE-2023-0001", vuln.VersionRange{VersionRangeUpper: "8.2.0"}),
+			// the latest built version, 8.1.0, is affected
+			match("CVE-2023-0002", vuln.VersionRange{VersionRangeUpper: "8.2.0"}),
+			// only the older version is affected
+			match("CVE-2023-0003", vuln.VersionRange{VersionRangeUpper: "8.1.0"}),
+			// already triaged, as its alias
+			match("GHSA-66p4-3h4c-hcfw", vuln.VersionRange{VersionRangeUpper: "8.2.0"}),
+		},
+		"zlib": {
+			match("CVE-2022-37434", vuln.VersionRange{VersionRangeUpper: "1.2.13"}),
+			// recorded under its CVE
+			match("GHSA-2222-3333-4444", vuln.VersionRange{VersionRangeUpper: "1.2.13"}),
+		},
+	}
+	apkindex := &repository.ApkIndex{Packages: []*repository.Package{
+		{Name: "curl", Origin: "curl", Version: "8.0.1-r0"},
+		{Name: "curl", Origin: "curl", Version: "8.1.0-r0"},
+		{Name: "libcurl4", Origin: "curl", Version: "8.1.0-r0"},
+		{Name: "zlib", Origin: "zlib", Version: "1.2.12-r3"},
+		{Name: "zlib-dev", Origin: "zlib", Version: "1.2.12-r3"},
+	}}
+
+	err = discover(context.Background(), DiscoverOptions{
+		AdvisoryCfgs:          advisoryCfgs,
+		VulnerabilityDetector: detector,
+		AliasFinder: fakeAliasFinder(vuln.Aliases{
+			"GHSA-66p4-3h4c-hcfw": curlAliases["GHSA-66p4-3h4c-hcfw"],
+			"GHSA-2222-3333-4444": {"CVE-2022-43680"},
+		}),
+	}, []*repository.ApkIndex{apkindex})
+	require.NoError(t, err)
+
+	advisoryCfgs, err = advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	require.NoError(t, err)
+
+	curl, err := advisoryCfgs.Select().WhereName("curl").First()
+	require.NoError(t, err)
+	advisories := curl.Configuration().Advisories
+	assert.Len(t, advisories, 2)
+	assert.Equal(t, vex.StatusNotAffected, advisories["CVE-2023-0001"][0].Status, "triaged advisories are left alone")
+	require.Len(t, advisories["CVE-2023-0002"], 1)
+	assert.Equal(t, vex.StatusUnderInvestigation, advisories["CVE-2023-0002"][0].Status)
+
+	zlib, err := advisoryCfgs.Select().WhereName("zlib").First()
+	require.NoError(t, err)
+	require.Len(t, zlib.Configuration().Advisories["CVE-2022-37434"], 1)
+	assert.Equal(t, vex.StatusUnderInvestigation, zlib.Configuration().Advisories["CVE-2022-37434"][0].Status)
+	assert.Contains(t, zlib.Configuration().Advisories, "CVE-2022-43680")
+	assert.NotContains(t, zlib.Configuration().Advisories, "GHSA-2222-3333-4444")
+}
+
+type fakeAliasFinder vuln.Aliases
+
+func (f fakeAliasFinder) Aliases(_ context.Context, id string) ([]string, error) {
+	return f[id], nil
+}
@@ -0,0 +1,159 @@
+package advisory
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+)
+
+// Embargo is security work on a vulnerability of a package that is kept private until LiftsAt, e.g. in an issue of a
+// private mirror of the advisories repository.
+type Embargo struct {
+	Package       string
+	Vulnerability string
+	LiftsAt       time.Time
+}
+
+// ParseEmbargo reads an Embargo from text that declares it with lines like:
+//
+//	package: curl
+//	vulnerability: CVE-2023-38545
+//	embargo-until: 2023-10-11T06:00:00Z
+//
+// embargo-until can also be a date, in which case the embargo lifts at midnight UTC. Other lines are ignored, so the
+// declaration can be part of an issue description.
+func ParseEmbargo(text string) (Embargo, error) {
+	var e Embargo
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "package":
+			e.Package = value
+		case "vulnerability":
+			e.Vulnerability = value
+		case "embargo-until":
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				if t, err = time.Parse(time.DateOnly, value); err != nil {
+					return Embargo{}, fmt.Errorf("unable to parse embargo-until %q, expected an RFC 3339 timestamp or a date", value)
+				}
+			}
+			e.LiftsAt = t
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Embargo{}, err
+	}
+
+	var errs []error
+	if e.Package == "" {
+		errs = append(errs, errors.New("no package declared"))
+	}
+	if e.Vulnerability == "" {
+		errs = append(errs, errors.New("no vulnerability declared"))
+	}
+	if e.LiftsAt.IsZero() {
+		errs = append(errs, errors.New("no embargo-until declared"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Embargo{}, err
+	}
+	return e, nil
+}
+
+// Lifted returns true if the embargo is over at the given time.
+func (e Embargo) Lifted(now time.Time) bool {
+	return !now.Before(e.LiftsAt)
+}
+
+// MigrateEmbargoedOptions configures the MigrateEmbargoed operation.
+type MigrateEmbargoedOptions struct {
+	// PrivateCfgs is the Index of advisory configurations the embargoed work was recorded in.
+	PrivateCfgs *configs.Index[advisoryconfigs.Document]
+
+	// PublicCfgs is the Index of advisory configurations the entries are migrated to.
+	PublicCfgs *configs.Index[advisoryconfigs.Document]
+}
+
+// MigrateEmbargoed copies the entries of the embargoed advisory from the private advisory configurations to the public
+// ones, and returns the requests that were applied. Entries recorded before the embargo lifted are dated at the lift,
+// since that's when the information became public, a second after one another so they keep their order, and entries
+// the public advisory has already are skipped, so a failed migration can be run again.
+func MigrateEmbargoed(e Embargo, opts MigrateEmbargoedOptions) ([]Request, error) {
+	privateCfgs := opts.PrivateCfgs.Select().WhereName(e.Package)
+	if count := privateCfgs.Len(); count != 1 {
+		return nil, fmt.Errorf("cannot migrate embargoed advisory: found %d private advisory documents for package %q", count, e.Package)
+	}
+	entries := privateCfgs.Configurations()[0].Advisories[e.Vulnerability]
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("cannot migrate embargoed advisory: no private advisory for %s in %q", e.Vulnerability, e.Package)
+	}
+
+	var public []advisoryconfigs.Entry
+	for _, doc := range opts.PublicCfgs.Select().WhereName(e.Package).Configurations() {
+		public = append(public, doc.Advisories[e.Vulnerability]...)
+	}
+
+	var applied []Request
+	var previous time.Time
+	for _, entry := range entries {
+		if entry.Timestamp.Before(e.LiftsAt) {
+			entry.Timestamp = e.LiftsAt
+		}
+		// the latest entry is the one with the latest timestamp, which has to stay the last one
+		if !previous.IsZero() && !entry.Timestamp.After(previous) {
+			entry.Timestamp = previous.Add(time.Second)
+		}
+		previous = entry.Timestamp
+		if containsEntry(public, entry) {
+			continue
+		}
+
+		req := requestFromEntry(e.Package, e.Vulnerability, entry)
+		if err := req.Validate(); err != nil {
+			return applied, fmt.Errorf("invalid private advisory entry for %s in %q: %w", e.Vulnerability, e.Package, err)
+		}
+		err := Apply([]Request{req}, ApplyOptions{AdvisoryCfgs: opts.PublicCfgs})
+		if err != nil {
+			return applied, err
+		}
+		public = append(public, entry)
+		applied = append(applied, req)
+	}
+
+	return applied, nil
+}
+
+func containsEntry(entries []advisoryconfigs.Entry, entry advisoryconfigs.Entry) bool {
+	for _, e := range entries {
+		if e.Timestamp.Equal(entry.Timestamp) && e.Status == entry.Status && e.Justification == entry.Justification &&
+			e.ImpactStatement == entry.ImpactStatement && e.ActionStatement == entry.ActionStatement &&
+			e.FixedVersion == entry.FixedVersion {
+			return true
+		}
+	}
+	return false
+}
+
+func requestFromEntry(packageName, vulnID string, entry advisoryconfigs.Entry) Request {
+	return Request{
+		Package:       packageName,
+		Vulnerability: vulnID,
+		Status:        entry.Status,
+		Action:        entry.ActionStatement,
+		Impact:        entry.ImpactStatement,
+		Justification: entry.Justification,
+		FixedVersion:  entry.FixedVersion,
+		Timestamp:     entry.Timestamp,
+	}
+}
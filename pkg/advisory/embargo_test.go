@@ -0,0 +1,86 @@
+package advisory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+)
+
+func TestParseEmbargo(t *testing.T) {
+	e, err := ParseEmbargo(`Heap overflow in the SOCKS5 proxy handshake, reported by the curl security team.
+
+package: curl
+vulnerability: CVE-2023-38545
+Embargo-Until: 2023-10-11T06:00:00Z
+`)
+	require.NoError(t, err)
+	assert.Equal(t, Embargo{
+		Package:       "curl",
+		Vulnerability: "CVE-2023-38545",
+		LiftsAt:       time.Date(2023, 10, 11, 6, 0, 0, 0, time.UTC),
+	}, e)
+	assert.False(t, e.Lifted(time.Date(2023, 10, 11, 5, 59, 0, 0, time.UTC)))
+	assert.True(t, e.Lifted(time.Date(2023, 10, 11, 6, 0, 0, 0, time.UTC)))
+
+	e, err = ParseEmbargo("package: curl\nvulnerability: CVE-2023-38545\nembargo-until: 2023-10-11")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 10, 11, 0, 0, 0, 0, time.UTC), e.LiftsAt)
+
+	_, err = ParseEmbargo("package: curl\nembargo-until: soon")
+	assert.ErrorContains(t, err, "unable to parse embargo-until")
+
+	_, err = ParseEmbargo("package: curl")
+	assert.ErrorContains(t, err, "no vulnerability declared")
+	assert.ErrorContains(t, err, "no embargo-until declared")
+}
+
+func TestMigrateEmbargoed(t *testing.T) {
+	privateCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS("testdata/embargo/private"))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	b, err := os.ReadFile("testdata/embargo/public/curl.advisories.yaml")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "curl.advisories.yaml"), b, 0o600))
+	publicCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	require.NoError(t, err)
+
+	lift := time.Date(2023, 10, 11, 6, 0, 0, 0, time.UTC)
+	e := Embargo{Package: "curl", Vulnerability: "CVE-2023-38545", LiftsAt: lift}
+	opts := MigrateEmbargoedOptions{PrivateCfgs: privateCfgs, PublicCfgs: publicCfgs}
+
+	applied, err := MigrateEmbargoed(e, opts)
+	require.NoError(t, err)
+	require.Len(t, applied, 3)
+
+	// re-read from disk to make sure the change was written
+	publicCfgs, err = advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	require.NoError(t, err)
+	doc := publicCfgs.Select().WhereName("curl").Configurations()[0]
+
+	entries := doc.Advisories["CVE-2023-38545"]
+	require.Len(t, entries, 3)
+	// nothing is dated before the embargo lifted, and the entries keep their order
+	assert.Equal(t, vex.StatusAffected, entries[0].Status)
+	assert.True(t, lift.Equal(entries[0].Timestamp))
+	assert.True(t, lift.Add(time.Second).Equal(entries[1].Timestamp))
+	assert.True(t, time.Date(2023, 10, 12, 9, 0, 0, 0, time.UTC).Equal(entries[2].Timestamp))
+	assert.Equal(t, "8.4.0-r1", Latest(entries).FixedVersion)
+	assert.Len(t, doc.Advisories["CVE-2023-0001"], 1)
+
+	// migrating again is a no-op
+	opts.PublicCfgs = publicCfgs
+	applied, err = MigrateEmbargoed(e, opts)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	_, err = MigrateEmbargoed(Embargo{Package: "curl", Vulnerability: "CVE-2023-9999", LiftsAt: lift}, opts)
+	assert.ErrorContains(t, err, "no private advisory")
+}
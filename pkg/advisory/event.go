@@ -0,0 +1,88 @@
+package advisory
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+)
+
+// Event is a step of the triage of a vulnerability of a package. Advisory documents record events as entries with
+// the VEX status, and the statement it requires, that the event amounts to.
+type Event string
+
+const (
+	// EventDetected is recorded when a vulnerability is found to possibly affect a package, pending investigation.
+	EventDetected Event = "detected"
+	// EventFixed is recorded when a version of the package fixes the vulnerability.
+	EventFixed Event = "fixed"
+	// EventFalsePositive is recorded when the vulnerability turns out not to affect the package, for the reason
+	// given by the justification.
+	EventFalsePositive Event = "false-positive"
+	// EventFixNotPlanned is recorded when the package is affected but won't be fixed, e.g. because upstream
+	// abandoned the version.
+	EventFixNotPlanned Event = "fix-not-planned"
+)
+
+// FixNotPlannedAction is the action statement of the entries of EventFixNotPlanned.
+const FixNotPlannedAction = "fix not planned"
+
+// Events are the events of the triage of a vulnerability, in the order they usually happen in.
+var Events = []Event{EventDetected, EventFixed, EventFalsePositive, EventFixNotPlanned}
+
+// ParseEvent returns the Event named s.
+func ParseEvent(s string) (Event, error) {
+	for _, e := range Events {
+		if string(e) == s {
+			return e, nil
+		}
+	}
+	names := make([]string, 0, len(Events))
+	for _, e := range Events {
+		names = append(names, string(e))
+	}
+	return "", fmt.Errorf("unknown event %q, must be one of: %s", s, strings.Join(names, ", "))
+}
+
+// Status returns the VEX status the entries of the event have.
+func (e Event) Status() vex.Status {
+	switch e {
+	case EventDetected:
+		return vex.StatusUnderInvestigation
+	case EventFixed:
+		return vex.StatusFixed
+	case EventFalsePositive:
+		return vex.StatusNotAffected
+	case EventFixNotPlanned:
+		return vex.StatusAffected
+	}
+	return ""
+}
+
+// Apply sets the status of the request to the one of the event, along with the action statement of
+// EventFixNotPlanned if the request has none.
+func (e Event) Apply(req *Request) {
+	req.Status = e.Status()
+	if e == EventFixNotPlanned && req.Action == "" {
+		req.Action = FixNotPlannedAction
+	}
+}
+
+// EventOf returns the event an entry records, or "" for affected entries with an action other than not fixing it,
+// which are no event but a plan to fix.
+func EventOf(entry advisory.Entry) Event {
+	switch entry.Status {
+	case vex.StatusUnderInvestigation:
+		return EventDetected
+	case vex.StatusFixed:
+		return EventFixed
+	case vex.StatusNotAffected:
+		return EventFalsePositive
+	case vex.StatusAffected:
+		if entry.ActionStatement == FixNotPlannedAction {
+			return EventFixNotPlanned
+		}
+	}
+	return ""
+}
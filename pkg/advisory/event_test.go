@@ -0,0 +1,57 @@
+package advisory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvent(t *testing.T) {
+	for _, event := range Events {
+		t.Run(string(event), func(t *testing.T) {
+			parsed, err := ParseEvent(string(event))
+			require.NoError(t, err)
+			assert.Equal(t, event, parsed)
+
+			req := Request{
+				Package:       "curl",
+				Vulnerability: "CVE-2023-0001",
+				Justification: vex.VulnerableCodeNotPresent,
+				FixedVersion:  "8.1.0-r0",
+				Timestamp:     time.Now(),
+			}
+			event.Apply(&req)
+			require.NoError(t, req.Validate())
+			assert.Equal(t, event, EventOf(req.toAdvisoryEntry()), "the entry of the request records the event")
+		})
+	}
+
+	_, err := ParseEvent("resolved")
+	assert.EqualError(t, err, `unknown event "resolved", must be one of: detected, fixed, false-positive, fix-not-planned`)
+
+	req := Request{Action: "upgrade to 8.1.0"}
+	EventFixNotPlanned.Apply(&req)
+	assert.Equal(t, "upgrade to 8.1.0", req.Action, "the action given is kept")
+	assert.Equal(t, Event(""), EventOf(req.toAdvisoryEntry()), "affected entries planning a fix are no event")
+}
+
+func TestRequest_Validate(t *testing.T) {
+	valid := Request{
+		Package:       "curl",
+		Vulnerability: "CVE-2023-0001",
+		Status:        vex.StatusNotAffected,
+		Justification: vex.ComponentNotPresent,
+	}
+	require.NoError(t, valid.Validate())
+
+	unknownStatus := valid
+	unknownStatus.Status = "resolved"
+	assert.ErrorContains(t, unknownStatus.Validate(), `status "resolved" is not one of`)
+
+	unknownJustification := valid
+	unknownJustification.Justification = "not_important"
+	assert.ErrorContains(t, unknownJustification.Validate(), `justification "not_important" is not one of`)
+}
@@ -0,0 +1,115 @@
+package advisory
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
+	"github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+	"golang.org/x/exp/slices"
+)
+
+// falsePositiveVersion is the version secfixes list the vulnerabilities that don't affect any version of a package
+// under, which scanners read as never affecting it.
+const falsePositiveVersion = "0"
+
+// ExportOptions contains the options for exporting advisory data.
+type ExportOptions struct {
+	AdvisoryCfgIndices []*configs.Index[advisory.Document]
+
+	URLPrefix string
+	Repo      string
+
+	// Ecosystem is the OSV ecosystem of the packages, the name of the distro.
+	Ecosystem string
+
+	// Aliases are the aliases of the vulnerabilities of the advisories. The advisories of aliases of each other are
+	// exported as the one of their vulnerability, under all its IDs.
+	Aliases vuln.Aliases
+}
+
+// ExportSecDB exports the advisories of the given indices as the security database of the packages of an
+// architecture. Unlike BuildDatabase, it compiles the advisories themselves, not the secfixes they are synced to: the
+// vulnerabilities whose latest advisory entry is fixed are listed under the version that fixed them, and the ones
+// whose latest entry is not affected under version "0", so that scanners stop reporting them. Vulnerabilities still
+// under investigation or affecting the package aren't listed.
+func ExportSecDB(opts ExportOptions, arch string) ([]byte, error) {
+	var packageEntries []PackageEntry
+
+	for _, index := range opts.AdvisoryCfgIndices {
+		for _, cfg := range index.Select().Configurations() {
+			secfixes := exportSecfixes(cfg.Advisories, opts.Aliases)
+			if len(secfixes) == 0 {
+				continue
+			}
+
+			packageEntries = append(packageEntries, PackageEntry{
+				Pkg: Package{
+					Name:     cfg.Package.Name,
+					Secfixes: secfixes,
+				},
+			})
+		}
+	}
+
+	if len(packageEntries) == 0 {
+		return nil, ErrNoPackageSecurityData
+	}
+
+	sort.SliceStable(packageEntries, func(i, j int) bool {
+		return packageEntries[i].Pkg.Name < packageEntries[j].Pkg.Name
+	})
+
+	db := Database{
+		APKURL:    apkURL,
+		Archs:     []string{arch},
+		Repo:      opts.Repo,
+		URLPrefix: opts.URLPrefix,
+		Packages:  packageEntries,
+	}
+
+	return json.MarshalIndent(db, "", "  ")
+}
+
+// exportSecfixes returns the secfixes the latest entries of the advisories amount to. The advisories of aliases of
+// each other amount to the latest entry among them, listed under all the IDs of the vulnerability, so that scanners
+// reporting any of them read it.
+func exportSecfixes(advisories advisory.Advisories, aliases vuln.Aliases) Secfixes {
+	secfixes := make(Secfixes)
+	for _, group := range aliasGroups(advisories, aliases) {
+		var entries []advisory.Entry
+		var ids []string
+		for _, id := range group {
+			entries = append(entries, advisories[id]...)
+			for _, alias := range aliases.Of(id) {
+				if !slices.Contains(ids, alias) {
+					ids = append(ids, alias)
+				}
+			}
+		}
+
+		latest := Latest(entries)
+		if latest == nil {
+			continue
+		}
+
+		switch latest.Status {
+		case vex.StatusFixed:
+			if latest.FixedVersion == "" {
+				continue
+			}
+			secfixes[latest.FixedVersion] = append(secfixes[latest.FixedVersion], ids...)
+
+		case vex.StatusNotAffected:
+			secfixes[falsePositiveVersion] = append(secfixes[falsePositiveVersion], ids...)
+		}
+	}
+
+	for _, vulns := range secfixes {
+		sort.Strings(vulns)
+	}
+
+	return secfixes
+}
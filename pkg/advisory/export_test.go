@@ -0,0 +1,80 @@
+package advisory
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+)
+
+func TestExportSecDB(t *testing.T) {
+	index, err := advisoryconfigs.NewIndex(rwos.DirFS("./testdata/export/advisories"))
+	require.NoError(t, err)
+
+	opts := ExportOptions{
+		AdvisoryCfgIndices: []*configs.Index[advisoryconfigs.Document]{index},
+		URLPrefix:          "https://packages.wolfi.dev",
+		Repo:               "os",
+	}
+
+	database, err := ExportSecDB(opts, "aarch64")
+	require.NoError(t, err)
+
+	expectedDatabase, err := os.ReadFile("./testdata/export/security.json")
+	require.NoError(t, err)
+
+	if diff := cmp.Diff(string(expectedDatabase), string(database)); diff != "" {
+		t.Errorf("ExportSecDB() produced an unexpected database (-want +got):\n%s", diff)
+	}
+
+	empty, err := advisoryconfigs.NewIndex(rwos.DirFS("./testdata/db/advisories-empty"))
+	require.NoError(t, err)
+	opts.AdvisoryCfgIndices = []*configs.Index[advisoryconfigs.Document]{empty}
+	_, err = ExportSecDB(opts, "aarch64")
+	assert.ErrorIs(t, err, ErrNoPackageSecurityData)
+}
+
+func TestExportOSV(t *testing.T) {
+	index, err := advisoryconfigs.NewIndex(rwos.DirFS("./testdata/export/advisories"))
+	require.NoError(t, err)
+
+	opts := ExportOptions{
+		AdvisoryCfgIndices: []*configs.Index[advisoryconfigs.Document]{index},
+		Ecosystem:          "Wolfi",
+	}
+
+	records, err := ExportOSV(opts)
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(records))
+	for i := range records {
+		ids = append(ids, records[i].ID)
+	}
+	assert.Equal(t, []string{
+		"WOLFI-brotli-CVE-2020-8927",
+		"WOLFI-curl-CVE-2023-28319",
+		"WOLFI-curl-CVE-2023-28320",
+		"WOLFI-glibc-CVE-2023-4911",
+	}, ids, "only the fixed and affected advisories are exported")
+
+	curl := records[1]
+	assert.Equal(t, OSVSchemaVersion, curl.SchemaVersion)
+	assert.Equal(t, []string{"CVE-2023-28319"}, curl.Aliases)
+	assert.Equal(t, "2023-05-18T10:00:00Z", curl.Published.Format(time.RFC3339), "published with the first entry")
+	assert.Equal(t, "2023-05-19T10:00:00Z", curl.Modified.Format(time.RFC3339), "modified with the latest entry")
+	assert.Equal(t, []OSVAffected{{
+		Package: OSVPackage{Ecosystem: "Wolfi", Name: "curl", PURL: "pkg:apk/wolfi/curl"},
+		Ranges:  []OSVRange{{Type: "ECOSYSTEM", Events: []OSVEvent{{Introduced: "0"}, {Fixed: "8.1.0-r0"}}}},
+	}}, curl.Affected)
+	assert.Equal(t, []OSVReference{{Type: "ADVISORY", URL: "https://nvd.nist.gov/vuln/detail/CVE-2023-28319"}}, curl.References)
+
+	glibc := records[3]
+	assert.Equal(t, []OSVEvent{{Introduced: "0"}}, glibc.Affected[0].Ranges[0].Events, "all versions are affected")
+	assert.Equal(t, "fix not planned", glibc.Details)
+}
@@ -0,0 +1,97 @@
+package advisory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+	"golang.org/x/exp/slices"
+)
+
+// VulnerabilityDetailer looks up the details of a vulnerability of a package, e.g. from NVD.
+type VulnerabilityDetailer interface {
+	VulnerabilityDetails(ctx context.Context, packageName, id string) (*vuln.Details, error)
+}
+
+// OpenVulnerabilities returns the vulnerabilities of the advisories of a package still to triage, the ones whose
+// latest event is EventDetected, sorted.
+func OpenVulnerabilities(doc advisory.Document) []string {
+	var open []string
+	for id, entries := range doc.Advisories {
+		if latest := Latest(entries); latest != nil && EventOf(*latest) == EventDetected {
+			open = append(open, id)
+		}
+	}
+	sort.Strings(open)
+	return open
+}
+
+// Triage is what is known about an open vulnerability of a package, for a responder to record what they determine.
+type Triage struct {
+	Package, Vulnerability string
+
+	// PackageVersion is the full version of the package in the distro, e.g. "1.2.3-r1".
+	PackageVersion string
+
+	// Details are the details of the vulnerability, nil if they couldn't be looked up.
+	Details *vuln.Details
+
+	// UpstreamStatus says whether upstream fixed the vulnerability, and whether the package has the fix.
+	UpstreamStatus string
+
+	// Suggested is the event the upstream status suggests recording, or "" if it suggests none.
+	Suggested Event
+}
+
+// NewTriage returns the triage of a vulnerability of a package given its details, which may be nil.
+func NewTriage(packageName, packageVersion, vulnerability string, details *vuln.Details) Triage {
+	t := Triage{
+		Package:        packageName,
+		Vulnerability:  vulnerability,
+		PackageVersion: packageVersion,
+		Details:        details,
+	}
+	if details == nil || len(details.AffectedVersions) == 0 {
+		t.UpstreamStatus = "unknown, no affected versions are known"
+		return t
+	}
+
+	ranges := details.AffectedVersions
+	upstream := upstreamVersion(packageVersion)
+	fixedIn := ""
+	if fixes := upstreamFixes(ranges); len(fixes) > 0 {
+		fixedIn = fmt.Sprintf(", upstream fixed it in %s", strings.Join(fixes, " and "))
+	}
+
+	switch {
+	case anyIncludes(ranges, upstream):
+		if fixedIn == "" {
+			fixedIn = ", upstream has no fix yet"
+		}
+		t.UpstreamStatus = fmt.Sprintf("the packaged version %s is affected%s", upstream, fixedIn)
+	case anyPast(ranges, upstream):
+		t.UpstreamStatus = fmt.Sprintf("the packaged version %s is past the affected versions%s", upstream, fixedIn)
+		t.Suggested = EventFixed
+	default:
+		t.UpstreamStatus = fmt.Sprintf("the packaged version %s isn't among the affected versions", upstream)
+		t.Suggested = EventFalsePositive
+	}
+	return t
+}
+
+// upstreamFixes returns the first versions past the vulnerable ranges, the ones the vulnerability was fixed in.
+func upstreamFixes(ranges []vuln.VersionRange) []string {
+	var fixes []string
+	for _, vr := range ranges {
+		if vr.SingleVersion != "" || vr.VersionRangeUpper == "" || vr.VersionRangeUpperInclusive {
+			continue
+		}
+		if !slices.Contains(fixes, vr.VersionRangeUpper) {
+			fixes = append(fixes, vr.VersionRangeUpper)
+		}
+	}
+	return fixes
+}
@@ -0,0 +1,82 @@
+package advisory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/stretchr/testify/assert"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+)
+
+func TestOpenVulnerabilities(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, 5, d, 0, 0, 0, 0, time.UTC) }
+	doc := advisoryconfigs.Document{
+		Package: advisoryconfigs.Package{Name: "curl"},
+		Advisories: advisoryconfigs.Advisories{
+			"CVE-2023-0003": {{Timestamp: day(1), Status: vex.StatusUnderInvestigation}},
+			"CVE-2023-0001": {{Timestamp: day(1), Status: vex.StatusUnderInvestigation}},
+			"CVE-2023-0002": {
+				{Timestamp: day(1), Status: vex.StatusUnderInvestigation},
+				{Timestamp: day(2), Status: vex.StatusFixed, FixedVersion: "8.1.0-r0"},
+			},
+			"CVE-2023-0004": {{Timestamp: day(1), Status: vex.StatusAffected, ActionStatement: FixNotPlannedAction}},
+		},
+	}
+
+	assert.Equal(t, []string{"CVE-2023-0001", "CVE-2023-0003"}, OpenVulnerabilities(doc))
+}
+
+func TestNewTriage(t *testing.T) {
+	fixedIn810 := &vuln.Details{AffectedVersions: []vuln.VersionRange{{VersionRangeLower: "7.0.0", VersionRangeLowerInclusive: true, VersionRangeUpper: "8.1.0"}}}
+	unfixed := &vuln.Details{AffectedVersions: []vuln.VersionRange{{VersionRangeUpper: "8.1.0", VersionRangeUpperInclusive: true}}}
+
+	cases := []struct {
+		name           string
+		packageVersion string
+		details        *vuln.Details
+		expectedStatus string
+		expectedEvent  Event
+	}{
+		{
+			name:           "affected",
+			packageVersion: "8.0.1-r0",
+			details:        fixedIn810,
+			expectedStatus: "the packaged version 8.0.1 is affected, upstream fixed it in 8.1.0",
+		},
+		{
+			name:           "affected without fix",
+			packageVersion: "8.1.0-r0",
+			details:        unfixed,
+			expectedStatus: "the packaged version 8.1.0 is affected, upstream has no fix yet",
+		},
+		{
+			name:           "past the fix",
+			packageVersion: "8.1.0-r1",
+			details:        fixedIn810,
+			expectedStatus: "the packaged version 8.1.0 is past the affected versions, upstream fixed it in 8.1.0",
+			expectedEvent:  EventFixed,
+		},
+		{
+			name:           "before the affected versions",
+			packageVersion: "6.5.0-r0",
+			details:        fixedIn810,
+			expectedStatus: "the packaged version 6.5.0 isn't among the affected versions",
+			expectedEvent:  EventFalsePositive,
+		},
+		{
+			name:           "no details",
+			packageVersion: "8.0.1-r0",
+			expectedStatus: "unknown, no affected versions are known",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			triage := NewTriage("curl", tt.packageVersion, "CVE-2023-0001", tt.details)
+			assert.Equal(t, tt.expectedStatus, triage.UpstreamStatus)
+			assert.Equal(t, tt.expectedEvent, triage.Suggested)
+		})
+	}
+}
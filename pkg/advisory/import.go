@@ -0,0 +1,200 @@
+package advisory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+// ForeignStatus is what another distro determined about a vulnerability of its package.
+type ForeignStatus string
+
+const (
+	// ForeignStatusFixed is a vulnerability the distro fixed in a version of its package.
+	ForeignStatusFixed ForeignStatus = "fixed"
+	// ForeignStatusNotAffected is a vulnerability the distro found not to affect its package.
+	ForeignStatusNotAffected ForeignStatus = "not-affected"
+	// ForeignStatusOpen is a vulnerability affecting the package of the distro, not fixed yet.
+	ForeignStatusOpen ForeignStatus = "open"
+)
+
+// ForeignAdvisory is what the security data of another distro, e.g. Alpine's secdb, has about a vulnerability of a
+// package.
+type ForeignAdvisory struct {
+	Package       string
+	Vulnerability string
+	Status        ForeignStatus
+
+	// FixedVersion is the version of the package of the distro that fixed the vulnerability, in the distro's version
+	// scheme, for ForeignStatusFixed.
+	FixedVersion string
+}
+
+// ParseAlpineSecDB returns the advisories of an Alpine security database, e.g. the one of the main repository of
+// edge. Vulnerabilities listed under version "0" are the ones that don't affect the package.
+func ParseAlpineSecDB(r io.Reader) ([]ForeignAdvisory, error) {
+	var db Database
+	if err := json.NewDecoder(r).Decode(&db); err != nil {
+		return nil, fmt.Errorf("unable to decode Alpine secdb: %w", err)
+	}
+
+	var advisories []ForeignAdvisory
+	for _, p := range db.Packages {
+		for version, vulns := range p.Pkg.Secfixes {
+			for _, v := range vulns {
+				// entries can list the aliases of the vulnerability after its ID
+				fields := strings.Fields(v)
+				if len(fields) == 0 {
+					continue
+				}
+				a := ForeignAdvisory{Package: p.Pkg.Name, Vulnerability: fields[0], Status: ForeignStatusFixed, FixedVersion: version}
+				if version == falsePositiveVersion {
+					a.Status, a.FixedVersion = ForeignStatusNotAffected, ""
+				}
+				advisories = append(advisories, a)
+			}
+		}
+	}
+	sortForeignAdvisories(advisories)
+	return advisories, nil
+}
+
+// debianTracker is the JSON data of the Debian security tracker: the vulnerabilities of each source package, with
+// their status in each release.
+type debianTracker map[string]map[string]struct {
+	Releases map[string]struct {
+		Status       string `json:"status"`
+		FixedVersion string `json:"fixed_version"`
+	} `json:"releases"`
+}
+
+// ParseDebianTracker returns the advisories of the Debian security tracker data for a release, e.g. "sid". Fixed
+// version "0" is Debian's way to say the package isn't affected, and vulnerabilities whose status is undetermined
+// are left out.
+func ParseDebianTracker(r io.Reader, release string) ([]ForeignAdvisory, error) {
+	var tracker debianTracker
+	if err := json.NewDecoder(r).Decode(&tracker); err != nil {
+		return nil, fmt.Errorf("unable to decode Debian security tracker data: %w", err)
+	}
+
+	var advisories []ForeignAdvisory
+	for pkg, vulns := range tracker {
+		for id, v := range vulns {
+			rel, ok := v.Releases[release]
+			if !ok {
+				continue
+			}
+			a := ForeignAdvisory{Package: pkg, Vulnerability: id}
+			switch {
+			case rel.Status == "resolved" && rel.FixedVersion == "0":
+				a.Status = ForeignStatusNotAffected
+			case rel.Status == "resolved":
+				a.Status, a.FixedVersion = ForeignStatusFixed, rel.FixedVersion
+			case rel.Status == "open":
+				a.Status = ForeignStatusOpen
+			default:
+				continue
+			}
+			advisories = append(advisories, a)
+		}
+	}
+	sortForeignAdvisories(advisories)
+	return advisories, nil
+}
+
+func sortForeignAdvisories(advisories []ForeignAdvisory) {
+	sort.Slice(advisories, func(i, j int) bool {
+		if advisories[i].Package != advisories[j].Package {
+			return advisories[i].Package < advisories[j].Package
+		}
+		return advisories[i].Vulnerability < advisories[j].Vulnerability
+	})
+}
+
+// ImportOptions configures the ProposeImport operation.
+type ImportOptions struct {
+	// Source names the security data imported in the notes of the proposals, e.g. "Alpine secdb".
+	Source string
+
+	// AdvisoryCfgs is the Index of advisories, whose vulnerabilities aren't proposed again.
+	AdvisoryCfgs *configs.Index[advisoryconfigs.Document]
+
+	// PackageVersions are the current versions of the packages of the distro, by name. Only advisories of packages
+	// named like them are imported.
+	PackageVersions map[string]string
+}
+
+var (
+	debianEpoch    = regexp.MustCompile(`^\d+:`)
+	foreignVersion = regexp.MustCompile(`^[0-9][0-9A-Za-z._]*`)
+)
+
+// foreignUpstreamVersion returns the upstream version of a version of another distro's package, e.g. "7.88.1" of
+// Alpine's "7.88.1-r1" and Debian's "1:7.88.1-10+deb12u1", and false if there's none.
+func foreignUpstreamVersion(v string) (string, bool) {
+	v = debianEpoch.ReplaceAllString(v, "")
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		v = v[:i]
+	}
+	upstream := foreignVersion.FindString(v)
+	return upstream, upstream != ""
+}
+
+// ProposeImport returns the advisory events the advisories of another distro suggest for the packages of the distro,
+// as a manifest to review and apply, so that triage of the packages they share starts with what the other distro
+// found. Vulnerabilities the advisories have already aren't proposed.
+//
+// Every proposal is detected, with what the other distro found as its impact, so that what's applied without review
+// is recorded as needing investigation rather than as a finding of the distro. The note of a proposal suggests the
+// event to record instead once confirmed: false positives for the vulnerabilities the other distro found not to
+// affect its package, and fixed in the current version of the package for the ones it fixed, if that's at least the
+// upstream version it fixed them in.
+func ProposeImport(advisories []ForeignAdvisory, opts ImportOptions) []ManifestEntry {
+	var proposals []ManifestEntry
+	for _, a := range advisories {
+		version, ok := opts.PackageVersions[a.Package]
+		if !ok || hasAdvisory(opts.AdvisoryCfgs, a.Package, a.Vulnerability) {
+			continue
+		}
+
+		entry := ManifestEntry{
+			Package:       a.Package,
+			Vulnerability: a.Vulnerability,
+			Status:        string(vex.StatusUnderInvestigation),
+		}
+		switch a.Status {
+		case ForeignStatusNotAffected:
+			entry.Impact = fmt.Sprintf("%s: doesn't affect the package", opts.Source)
+			entry.Note = fmt.Sprintf("confirm and record as %s (%s)", vex.StatusNotAffected, vex.VulnerableCodeNotPresent)
+
+		case ForeignStatusFixed:
+			entry.Impact = fmt.Sprintf("%s: fixed in %s", opts.Source, a.FixedVersion)
+			upstream, ok := foreignUpstreamVersion(a.FixedVersion)
+			switch {
+			case !ok:
+				entry.Note = "needs confirmation"
+			case dag.CompareVersions(upstreamVersion(version), upstream) < 0:
+				entry.Note = fmt.Sprintf("needs confirmation, %s is older than the fix", version)
+			default:
+				entry.Note = fmt.Sprintf("confirm and record as %s in the first version that fixed it, at most %s", vex.StatusFixed, version)
+			}
+
+		case ForeignStatusOpen:
+			entry.Impact = fmt.Sprintf("%s: not fixed yet", opts.Source)
+			entry.Note = "needs confirmation"
+
+		default:
+			continue
+		}
+		proposals = append(proposals, entry)
+	}
+	return proposals
+}
@@ -0,0 +1,124 @@
+package advisory
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+)
+
+func TestParseAlpineSecDB(t *testing.T) {
+	f, err := os.Open("testdata/import/alpine-main.json")
+	require.NoError(t, err)
+	defer f.Close()
+
+	advisories, err := ParseAlpineSecDB(f)
+	require.NoError(t, err)
+	assert.Equal(t, []ForeignAdvisory{
+		{Package: "curl", Vulnerability: "CVE-2021-22897", Status: ForeignStatusNotAffected},
+		{Package: "curl", Vulnerability: "CVE-2023-28319", Status: ForeignStatusFixed, FixedVersion: "8.1.0-r0"},
+		{Package: "curl", Vulnerability: "CVE-2023-28320", Status: ForeignStatusFixed, FixedVersion: "8.1.0-r0"},
+		{Package: "curl", Vulnerability: "CVE-2023-38545", Status: ForeignStatusFixed, FixedVersion: "8.4.0-r0"},
+		{Package: "musl", Vulnerability: "CVE-2020-28928", Status: ForeignStatusFixed, FixedVersion: "1.2.2_pre2-r0"},
+	}, advisories)
+}
+
+func TestParseDebianTracker(t *testing.T) {
+	f, err := os.Open("testdata/import/debian.json")
+	require.NoError(t, err)
+	defer f.Close()
+
+	advisories, err := ParseDebianTracker(f, "sid")
+	require.NoError(t, err)
+	assert.Equal(t, []ForeignAdvisory{
+		{Package: "apache2", Vulnerability: "CVE-2023-25690", Status: ForeignStatusFixed, FixedVersion: "2.4.56+dfsg-1"},
+		{Package: "curl", Vulnerability: "CVE-2021-22897", Status: ForeignStatusNotAffected},
+		{Package: "curl", Vulnerability: "CVE-2023-28319", Status: ForeignStatusFixed, FixedVersion: "8.1.0-1"},
+		{Package: "curl", Vulnerability: "CVE-2023-38546", Status: ForeignStatusOpen},
+	}, advisories, "undetermined vulnerabilities are left out")
+}
+
+func TestForeignUpstreamVersion(t *testing.T) {
+	for v, want := range map[string]string{
+		"8.1.0-r0":            "8.1.0",
+		"1.2.2_pre2-r0":       "1.2.2_pre2",
+		"1:7.88.1-10+deb12u1": "7.88.1",
+		"2.4.56+dfsg-1":       "2.4.56",
+		"1.0":                 "1.0",
+	} {
+		got, ok := foreignUpstreamVersion(v)
+		assert.True(t, ok, v)
+		assert.Equal(t, want, got, v)
+	}
+	_, ok := foreignUpstreamVersion("unknown")
+	assert.False(t, ok)
+}
+
+func TestProposeImport(t *testing.T) {
+	dir := t.TempDir()
+	b, err := os.ReadFile("testdata/export/advisories/curl.advisories.yaml")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "curl.advisories.yaml"), b, 0o600))
+	advisoryCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	require.NoError(t, err)
+
+	f, err := os.Open("testdata/import/alpine-main.json")
+	require.NoError(t, err)
+	defer f.Close()
+	foreign, err := ParseAlpineSecDB(f)
+	require.NoError(t, err)
+
+	proposals := ProposeImport(foreign, ImportOptions{
+		Source:       "Alpine secdb",
+		AdvisoryCfgs: advisoryCfgs,
+		// no musl, so its advisories aren't proposed
+		PackageVersions: map[string]string{"curl": "8.2.1-r0"},
+	})
+	assert.Equal(t, []ManifestEntry{
+		{
+			Package:       "curl",
+			Vulnerability: "CVE-2021-22897",
+			Status:        "under_investigation",
+			Impact:        "Alpine secdb: doesn't affect the package",
+			Note:          "confirm and record as not_affected (vulnerable_code_not_present)",
+		},
+		{
+			Package:       "curl",
+			Vulnerability: "CVE-2023-38545",
+			Status:        "under_investigation",
+			Impact:        "Alpine secdb: fixed in 8.4.0-r0",
+			Note:          "needs confirmation, 8.2.1-r0 is older than the fix",
+		},
+	}, proposals, "the advisories of CVE-2023-28319 and CVE-2023-28320 exist already")
+
+	proposals = ProposeImport(foreign, ImportOptions{
+		Source:          "Alpine secdb",
+		AdvisoryCfgs:    advisoryCfgs,
+		PackageVersions: map[string]string{"musl": "1.2.4-r1"},
+	})
+	assert.Equal(t, []ManifestEntry{{
+		Package:       "musl",
+		Vulnerability: "CVE-2020-28928",
+		Status:        "under_investigation",
+		Impact:        "Alpine secdb: fixed in 1.2.2_pre2-r0",
+		Note:          "confirm and record as fixed in the first version that fixed it, at most 1.2.4-r1",
+	}}, proposals)
+
+	// the proposals are a manifest to apply, which records what the other distro found but not the note
+	for _, format := range []ManifestFormat{ManifestFormatJSON, ManifestFormatCSV} {
+		var buf bytes.Buffer
+		require.NoError(t, WriteManifest(&buf, format, proposals))
+		reqs, err := ParseManifest(&buf, format, time.Now())
+		require.NoError(t, err, format)
+		require.Len(t, reqs, 1, format)
+		assert.Equal(t, vex.StatusUnderInvestigation, reqs[0].Status, format)
+		assert.Equal(t, "Alpine secdb: fixed in 1.2.2_pre2-r0", reqs[0].toAdvisoryEntry().ImpactStatement, format)
+	}
+}
@@ -0,0 +1,237 @@
+package advisory
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+)
+
+// ManifestFormat is the encoding of a manifest of advisory events.
+type ManifestFormat string
+
+const (
+	ManifestFormatJSON ManifestFormat = "json"
+	ManifestFormatCSV  ManifestFormat = "csv"
+)
+
+// ManifestEntry is a single advisory event of a manifest. In a JSON manifest, entries are the elements of a
+// top-level array. In a CSV manifest, they're the rows after a header naming the columns with the same keys as JSON.
+type ManifestEntry struct {
+	Package       string `json:"package"`
+	Vulnerability string `json:"vulnerability"`
+	Status        string `json:"status"`
+	Action        string `json:"action,omitempty"`
+	Impact        string `json:"impact,omitempty"`
+	Justification string `json:"justification,omitempty"`
+	FixedVersion  string `json:"fixedVersion,omitempty"`
+
+	// Timestamp is in RFC 3339 format. If it's empty, the default timestamp passed to ParseManifest is used.
+	Timestamp string `json:"timestamp,omitempty"`
+
+	// Note is for the reviewers of the manifest, e.g. where a proposed event comes from. It isn't recorded.
+	Note string `json:"note,omitempty"`
+}
+
+var manifestColumns = []string{"package", "vulnerability", "status", "action", "impact", "justification", "fixedVersion", "timestamp", "note"}
+
+// ParseManifest decodes the entries of a manifest into requests. Entries without a timestamp get defaultTimestamp.
+// The requests aren't validated, see ApplyOptions.
+func ParseManifest(r io.Reader, format ManifestFormat, defaultTimestamp time.Time) ([]Request, error) {
+	var entries []ManifestEntry
+	switch format {
+	case ManifestFormatJSON:
+		if err := json.NewDecoder(r).Decode(&entries); err != nil {
+			return nil, fmt.Errorf("unable to decode manifest: %w", err)
+		}
+	case ManifestFormatCSV:
+		var err error
+		entries, err = decodeCSVManifest(r)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode manifest: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown manifest format %q", format)
+	}
+
+	reqs := make([]Request, 0, len(entries))
+	for i, e := range entries {
+		timestamp := defaultTimestamp
+		if e.Timestamp != "" {
+			t, err := time.Parse(time.RFC3339, e.Timestamp)
+			if err != nil {
+				return nil, fmt.Errorf("entry %d: unable to parse timestamp: %w", i+1, err)
+			}
+			timestamp = t
+		}
+
+		reqs = append(reqs, Request{
+			Package:       e.Package,
+			Vulnerability: e.Vulnerability,
+			Status:        vex.Status(e.Status),
+			Action:        e.Action,
+			Impact:        e.Impact,
+			Justification: vex.Justification(e.Justification),
+			FixedVersion:  e.FixedVersion,
+			Timestamp:     timestamp,
+		})
+	}
+
+	return reqs, nil
+}
+
+func decodeCSVManifest(r io.Reader) ([]ManifestEntry, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read header: %w", err)
+	}
+	for _, col := range header {
+		known := false
+		for _, c := range manifestColumns {
+			if col == c {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown column %q", col)
+		}
+	}
+
+	var entries []ManifestEntry
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		values := make(map[string]string, len(header))
+		for i, col := range header {
+			values[col] = record[i]
+		}
+		entries = append(entries, ManifestEntry{
+			Package:       values["package"],
+			Vulnerability: values["vulnerability"],
+			Status:        values["status"],
+			Action:        values["action"],
+			Impact:        values["impact"],
+			Justification: values["justification"],
+			FixedVersion:  values["fixedVersion"],
+			Timestamp:     values["timestamp"],
+			Note:          values["note"],
+		})
+	}
+}
+
+// WriteManifest encodes the entries as a manifest, for ParseManifest to decode.
+func WriteManifest(w io.Writer, format ManifestFormat, entries []ManifestEntry) error {
+	switch format {
+	case ManifestFormatJSON:
+		if entries == nil {
+			entries = []ManifestEntry{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+
+	case ManifestFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(manifestColumns); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			err := cw.Write([]string{e.Package, e.Vulnerability, e.Status, e.Action, e.Impact, e.Justification, e.FixedVersion, e.Timestamp, e.Note})
+			if err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	default:
+		return fmt.Errorf("unknown manifest format %q", format)
+	}
+}
+
+// ApplyOptions configures the Apply operation.
+type ApplyOptions struct {
+	// AdvisoryCfgs is the Index of advisory configurations on which to operate.
+	AdvisoryCfgs *configs.Index[advisoryconfigs.Document]
+
+	// BuildCfgs, if set, is the Index of build configurations requests are checked against, so advisories can only
+	// be added for packages the distro has.
+	BuildCfgs *configs.Index[build.Configuration]
+}
+
+// Apply adds the event of each request to the advisory it's for, creating the advisory (and the package's advisory
+// document) if there isn't one yet. All requests are validated first, and nothing is written unless every one of them
+// is valid.
+func Apply(reqs []Request, opts ApplyOptions) error {
+	if err := validateBatch(reqs, opts); err != nil {
+		return err
+	}
+
+	for _, req := range reqs {
+		var err error
+		if hasAdvisory(opts.AdvisoryCfgs, req.Package, req.Vulnerability) {
+			err = Update(req, UpdateOptions{AdvisoryCfgs: opts.AdvisoryCfgs})
+		} else {
+			err = Create(req, CreateOptions{AdvisoryCfgs: opts.AdvisoryCfgs})
+		}
+		if err != nil {
+			return fmt.Errorf("unable to apply %s %s: %w", req.Package, req.Vulnerability, err)
+		}
+	}
+
+	return nil
+}
+
+// validateBatch returns every problem with reqs at once, so a manifest can be fixed in one go.
+func validateBatch(reqs []Request, opts ApplyOptions) error {
+	var errs []error
+	seen := make(map[string]int)
+	for i, req := range reqs {
+		if err := req.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("entry %d: %w", i+1, err))
+			continue
+		}
+
+		key := req.Package + " " + req.Vulnerability
+		if first, ok := seen[key]; ok {
+			errs = append(errs, fmt.Errorf("entry %d: %s %s is already in entry %d", i+1, req.Package, req.Vulnerability, first))
+			continue
+		}
+		seen[key] = i + 1
+
+		if opts.BuildCfgs != nil && opts.BuildCfgs.Select().WhereName(req.Package).Len() == 0 {
+			errs = append(errs, fmt.Errorf("entry %d: no build configuration for package %q", i+1, req.Package))
+		}
+		if count := opts.AdvisoryCfgs.Select().WhereName(req.Package).Len(); count > 1 {
+			errs = append(errs, fmt.Errorf("entry %d: found %d advisory documents for package %q", i+1, count, req.Package))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func hasAdvisory(advisoryCfgs *configs.Index[advisoryconfigs.Document], packageName, vulnID string) bool {
+	for _, doc := range advisoryCfgs.Select().WhereName(packageName).Configurations() {
+		if _, ok := doc.Advisories[vulnID]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package advisory
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+)
+
+func TestParseManifest(t *testing.T) {
+	timestamp := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, format := range []ManifestFormat{ManifestFormatJSON, ManifestFormatCSV} {
+		t.Run(string(format), func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", "apply", "manifest."+string(format)))
+			require.NoError(t, err)
+			defer f.Close()
+
+			reqs, err := ParseManifest(f, format, timestamp)
+			require.NoError(t, err)
+			require.Len(t, reqs, 3)
+
+			assert.Equal(t, Request{
+				Package:       "curl",
+				Vulnerability: "CVE-2023-0001",
+				Status:        vex.StatusFixed,
+				FixedVersion:  "8.1.0-r0",
+				Timestamp:     timestamp,
+			}, reqs[0])
+			assert.Equal(t, vex.VulnerableCodeNotPresent, reqs[1].Justification)
+			assert.Equal(t, "openssl", reqs[2].Package)
+		})
+	}
+
+	_, err := ParseManifest(strings.NewReader("package,vuln\ncurl,CVE-2023-0001\n"), ManifestFormatCSV, timestamp)
+	assert.ErrorContains(t, err, `unknown column "vuln"`)
+}
+
+func TestApply(t *testing.T) {
+	timestamp := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	b, err := os.ReadFile("testdata/apply/curl.advisories.yaml")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "curl.advisories.yaml"), b, 0o600))
+
+	advisoryCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	require.NoError(t, err)
+
+	reqs := []Request{
+		{Package: "curl", Vulnerability: "CVE-2023-0001", Status: vex.StatusFixed, FixedVersion: "8.1.0-r0", Timestamp: timestamp},
+		{Package: "curl", Vulnerability: "CVE-2023-9999", Status: vex.StatusNotAffected, Justification: vex.VulnerableCodeNotPresent, Timestamp: timestamp},
+		{Package: "openssl", Vulnerability: "CVE-2023-9999", Status: vex.StatusNotAffected, Justification: vex.VulnerableCodeNotPresent, Timestamp: timestamp},
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := append([]Request{}, reqs...)
+		invalid = append(invalid,
+			Request{Package: "curl", Vulnerability: "CVE-2023-0001", Status: vex.StatusFixed, FixedVersion: "8.1.0-r1"},
+			Request{Package: "zlib", Vulnerability: "CVE-2023-0005", Status: vex.StatusFixed},
+		)
+		err := Apply(invalid, ApplyOptions{AdvisoryCfgs: advisoryCfgs})
+		assert.ErrorContains(t, err, "entry 4: curl CVE-2023-0001 is already in entry 1")
+		assert.ErrorContains(t, err, "entry 5: fixed version cannot be empty")
+
+		// nothing is written if any request is invalid
+		after, err := os.ReadFile(filepath.Join(dir, "curl.advisories.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, string(b), string(after))
+		assert.NoFileExists(t, filepath.Join(dir, "openssl.advisories.yaml"))
+	})
+
+	require.NoError(t, Apply(reqs, ApplyOptions{AdvisoryCfgs: advisoryCfgs}))
+
+	// re-read from disk to make sure the changes were written
+	advisoryCfgs, err = advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	require.NoError(t, err)
+
+	curl := advisoryCfgs.Select().WhereName("curl").Configurations()[0]
+	latest := Latest(curl.Advisories["CVE-2023-0001"])
+	require.NotNil(t, latest)
+	assert.Equal(t, vex.StatusFixed, latest.Status)
+	assert.Len(t, curl.Advisories["CVE-2023-0001"], 2)
+	assert.Len(t, curl.Advisories["CVE-2023-9999"], 1)
+
+	openssl := advisoryCfgs.Select().WhereName("openssl").Configurations()
+	require.Len(t, openssl, 1)
+	assert.Equal(t, vex.StatusNotAffected, Latest(openssl[0].Advisories["CVE-2023-9999"]).Status)
+}
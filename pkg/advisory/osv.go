@@ -0,0 +1,148 @@
+package advisory
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+)
+
+// OSVSchemaVersion is the version of the OSV schema, https://ossf.github.io/osv-schema/, exported records follow.
+const OSVSchemaVersion = "1.5.0"
+
+// OSV is an OSV record of the advisory of a vulnerability of a package.
+type OSV struct {
+	SchemaVersion string         `json:"schema_version"`
+	ID            string         `json:"id"`
+	Modified      time.Time      `json:"modified"`
+	Published     time.Time      `json:"published"`
+	Aliases       []string       `json:"aliases,omitempty"`
+	Details       string         `json:"details,omitempty"`
+	Affected      []OSVAffected  `json:"affected"`
+	References    []OSVReference `json:"references,omitempty"`
+}
+
+// OSVAffected is a package an OSV record is about, and the versions of it the vulnerability affects.
+type OSVAffected struct {
+	Package OSVPackage `json:"package"`
+	Ranges  []OSVRange `json:"ranges"`
+}
+
+type OSVPackage struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+	PURL      string `json:"purl,omitempty"`
+}
+
+// OSVRange is a range of affected versions, starting at the version of its introduced event and ending before the
+// version of its fixed event, if it has one.
+type OSVRange struct {
+	Type   string     `json:"type"`
+	Events []OSVEvent `json:"events"`
+}
+
+type OSVEvent struct {
+	Introduced string `json:"introduced,omitempty"`
+	Fixed      string `json:"fixed,omitempty"`
+}
+
+type OSVReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// ExportOSV exports the advisories of the given indices as OSV records, in the ecosystem of the options. Only the
+// advisories whose latest entry is fixed or affected are exported, since OSV records the vulnerabilities packages
+// have: the fixed ones affect the versions before their fixed version, and the affected ones all versions. The
+// records are sorted by ID.
+func ExportOSV(opts ExportOptions) ([]OSV, error) {
+	var records []OSV
+
+	for _, index := range opts.AdvisoryCfgIndices {
+		for _, cfg := range index.Select().Configurations() {
+			for vuln, entries := range cfg.Advisories {
+				if record, ok := exportOSV(opts.Ecosystem, cfg.Package.Name, vuln, opts.Aliases.Of(vuln), entries); ok {
+					records = append(records, record)
+				}
+			}
+		}
+	}
+
+	if len(records) == 0 {
+		return nil, ErrNoPackageSecurityData
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+
+	return records, nil
+}
+
+// exportOSV returns the OSV record of the advisory of vuln for a package, and false if the advisory has none. The
+// record is an alias of vuln and its aliases.
+func exportOSV(ecosystem, packageName, vuln string, aliases []string, entries []advisory.Entry) (OSV, bool) {
+	latest := Latest(entries)
+	if latest == nil {
+		return OSV{}, false
+	}
+
+	events := []OSVEvent{{Introduced: "0"}}
+	switch latest.Status {
+	case vex.StatusFixed:
+		if latest.FixedVersion == "" {
+			return OSV{}, false
+		}
+		events = append(events, OSVEvent{Fixed: latest.FixedVersion})
+	case vex.StatusAffected:
+		// no version fixes it
+	default:
+		return OSV{}, false
+	}
+
+	published := latest.Timestamp
+	for _, e := range entries {
+		if e.Timestamp.Before(published) {
+			published = e.Timestamp
+		}
+	}
+
+	details := latest.ImpactStatement
+	if details == "" {
+		details = latest.ActionStatement
+	}
+
+	return OSV{
+		SchemaVersion: OSVSchemaVersion,
+		ID:            fmt.Sprintf("%s-%s-%s", strings.ToUpper(ecosystem), packageName, vuln),
+		Modified:      latest.Timestamp.UTC(),
+		Published:     published.UTC(),
+		Aliases:       aliases,
+		Details:       details,
+		Affected: []OSVAffected{
+			{
+				Package: OSVPackage{
+					Ecosystem: ecosystem,
+					Name:      packageName,
+					PURL:      fmt.Sprintf("pkg:apk/%s/%s", strings.ToLower(ecosystem), packageName),
+				},
+				Ranges: []OSVRange{{Type: "ECOSYSTEM", Events: events}},
+			},
+		},
+		References: osvReferences(vuln),
+	}, true
+}
+
+// osvReferences returns the advisories of the databases vuln is from.
+func osvReferences(vuln string) []OSVReference {
+	switch {
+	case strings.HasPrefix(vuln, "CVE-"):
+		return []OSVReference{{Type: "ADVISORY", URL: "https://nvd.nist.gov/vuln/detail/" + vuln}}
+	case strings.HasPrefix(vuln, "GHSA-"):
+		return []OSVReference{{Type: "ADVISORY", URL: "https://github.com/advisories/" + vuln}}
+	}
+	return nil
+}
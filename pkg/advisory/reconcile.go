@@ -0,0 +1,253 @@
+package advisory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/index"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+	"golang.org/x/exp/slices"
+)
+
+// DriftKind is how an advisory disagrees with the package that was published.
+type DriftKind string
+
+const (
+	// DriftStillAffected is an advisory that is still pending (under_investigation or affected), while the published
+	// version of the package is past the vulnerable versions.
+	DriftStillAffected DriftKind = "still-affected"
+
+	// DriftReintroduced is an advisory marked fixed, while the published version of the package, which is at or past
+	// the fixed version, is vulnerable again.
+	DriftReintroduced DriftKind = "reintroduced"
+)
+
+// Drift is an advisory whose status doesn't match the latest published version of its package.
+type Drift struct {
+	Kind          DriftKind
+	Package       string
+	Vulnerability string
+
+	// Status is the latest status of the advisory.
+	Status vex.Status
+
+	// FixedVersion is the fixed version recorded by the advisory, if it's fixed.
+	FixedVersion string
+
+	// PublishedVersion is the latest published version of the package, e.g. "1.2.3-r1".
+	PublishedVersion string
+}
+
+// Key identifies the drift, so it can be reported once for as long as it lasts.
+func (d Drift) Key() string {
+	return strings.Join([]string{string(d.Kind), d.Package, d.Vulnerability, d.PublishedVersion}, "/")
+}
+
+func (d Drift) String() string {
+	switch d.Kind {
+	case DriftStillAffected:
+		return fmt.Sprintf("%s %s is %s, but the published %s is past the vulnerable versions", d.Package, d.Vulnerability, d.Status, d.PublishedVersion)
+	case DriftReintroduced:
+		return fmt.Sprintf("%s %s was fixed in %s, but the published %s is vulnerable again", d.Package, d.Vulnerability, d.FixedVersion, d.PublishedVersion)
+	}
+	return fmt.Sprintf("%s %s: %s", d.Package, d.Vulnerability, d.Kind)
+}
+
+// ReconcileOptions configures the Reconcile operation.
+type ReconcileOptions struct {
+	// SelectedPackages is a list of packages to reconcile. If empty, all packages with advisories are reconciled.
+	SelectedPackages []string
+
+	// AdvisoryCfgs is the Index of advisories to reconcile.
+	AdvisoryCfgs *configs.Index[advisoryconfigs.Document]
+
+	// PackageRepositoryURL is the URL to the distro's package repository (e.g. "https://packages.wolfi.dev/os").
+	PackageRepositoryURL string
+
+	// The Arches whose published packages are compared (e.g. "x86_64").
+	Arches []string
+
+	// VulnerabilityDetector is how Reconcile finds which versions of packages are vulnerable.
+	VulnerabilityDetector vuln.Detector
+}
+
+// Reconcile compares the advisories with the latest versions of their packages in the package repository, according
+// to the vulnerability detector, and returns the advisories that drifted, sorted by package and vulnerability. It
+// doesn't change any advisory.
+func Reconcile(ctx context.Context, opts ReconcileOptions) ([]Drift, error) {
+	if opts.PackageRepositoryURL == "" {
+		return nil, fmt.Errorf("package repository URL must be specified")
+	}
+
+	var apkindexes []*repository.ApkIndex
+	for _, arch := range opts.Arches {
+		apkindex, err := index.Index(ctx, arch, opts.PackageRepositoryURL)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get APKINDEX for arch %q: %w", arch, err)
+		}
+		apkindexes = append(apkindexes, apkindex)
+	}
+
+	return reconcile(ctx, opts, latestPublishedVersions(apkindexes))
+}
+
+func reconcile(ctx context.Context, opts ReconcileOptions, published map[string]string) ([]Drift, error) {
+	docs := opts.AdvisoryCfgs.Select().Configurations()
+
+	var names []string
+	for i := range docs {
+		name := docs[i].Package.Name
+		if len(opts.SelectedPackages) > 0 && !slices.Contains(opts.SelectedPackages, name) {
+			continue
+		}
+		if _, ok := published[name]; ok && len(docs[i].Advisories) > 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	sort.Strings(names)
+
+	vulnMatches, err := opts.VulnerabilityDetector.VulnerabilitiesForPackages(ctx, names...)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []Drift
+	for i := range docs {
+		name := docs[i].Package.Name
+		if !slices.Contains(names, name) {
+			continue
+		}
+		drifts = append(drifts, packageDrifts(docs[i], published[name], vulnMatches[name])...)
+	}
+
+	sort.SliceStable(drifts, func(i, j int) bool {
+		if drifts[i].Package != drifts[j].Package {
+			return drifts[i].Package < drifts[j].Package
+		}
+		return drifts[i].Vulnerability < drifts[j].Vulnerability
+	})
+	return drifts, nil
+}
+
+// packageDrifts returns the advisories of doc that drifted from the published version of the package, given the
+// detector's matches for the package.
+func packageDrifts(doc advisoryconfigs.Document, publishedVersion string, matches []vuln.Match) []Drift {
+	rangesByID := make(map[string][]vuln.VersionRange)
+	for _, m := range matches {
+		rangesByID[m.Vulnerability.ID] = append(rangesByID[m.Vulnerability.ID], m.CPE.VersionRange)
+	}
+	upstream := upstreamVersion(publishedVersion)
+
+	var drifts []Drift
+	for id, entries := range doc.Advisories {
+		latest := Latest(entries)
+		ranges := rangesByID[id]
+		if latest == nil || len(ranges) == 0 {
+			// without a match, the detector doesn't know which versions are vulnerable
+			continue
+		}
+
+		d := Drift{
+			Package:          doc.Package.Name,
+			Vulnerability:    id,
+			Status:           latest.Status,
+			PublishedVersion: publishedVersion,
+		}
+		switch {
+		case isPending(latest.Status) && !anyIncludes(ranges, upstream) && anyPast(ranges, upstream):
+			d.Kind = DriftStillAffected
+		case latest.Status == vex.StatusFixed && latest.FixedVersion != "" &&
+			dag.CompareVersions(publishedVersion, latest.FixedVersion) >= 0 && anyIncludes(ranges, upstream):
+			d.Kind = DriftReintroduced
+			d.FixedVersion = latest.FixedVersion
+		default:
+			continue
+		}
+		drifts = append(drifts, d)
+	}
+	return drifts
+}
+
+// anyIncludes reports whether v is one of the versions of any of the vulnerable ranges. The versions are compared with
+// dag.CompareVersions, like the fixed versions of advisories, so a range and a fixed version never disagree on the
+// order of two versions.
+func anyIncludes(ranges []vuln.VersionRange, v string) bool {
+	for _, vr := range ranges {
+		if vr.SingleVersion != "" {
+			if dag.CompareVersions(v, vr.SingleVersion) == 0 {
+				return true
+			}
+			continue
+		}
+		if vr.VersionRangeLower != "" && !within(dag.CompareVersions(v, vr.VersionRangeLower), vr.VersionRangeLowerInclusive) {
+			continue
+		}
+		if vr.VersionRangeUpper != "" && !within(dag.CompareVersions(vr.VersionRangeUpper, v), vr.VersionRangeUpperInclusive) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// within reports whether a version is on the inside of a bound, given the comparison of the version on the inside
+// with the bound.
+func within(cmp int, inclusive bool) bool {
+	return cmp > 0 || (inclusive && cmp == 0)
+}
+
+// anyPast reports whether v is newer than all the versions of any of the vulnerable ranges, which means the
+// vulnerability was fixed in a version up to v.
+func anyPast(ranges []vuln.VersionRange, v string) bool {
+	for _, vr := range ranges {
+		bound, inclusive := vr.VersionRangeUpper, vr.VersionRangeUpperInclusive
+		if vr.SingleVersion != "" {
+			bound, inclusive = vr.SingleVersion, true
+		}
+		if bound == "" {
+			continue
+		}
+		if within(dag.CompareVersions(v, bound), !inclusive) {
+			return true
+		}
+	}
+	return false
+}
+
+// upstreamVersion drops the epoch of a package version, e.g. "1.2.3" of "1.2.3-r1", as vulnerable version ranges are
+// of upstream versions.
+func upstreamVersion(v string) string {
+	if i := strings.LastIndex(v, "-r"); i >= 0 {
+		return v[:i]
+	}
+	return v
+}
+
+// latestPublishedVersions returns the latest version of each origin package in the indexes.
+func latestPublishedVersions(apkindexes []*repository.ApkIndex) map[string]string {
+	latest := make(map[string]string)
+	for _, apkindex := range apkindexes {
+		if apkindex == nil {
+			continue
+		}
+		for _, pkg := range apkindex.Packages {
+			if pkg.Origin == "" || pkg.Name != pkg.Origin {
+				continue
+			}
+			if current, ok := latest[pkg.Name]; !ok || dag.CompareVersions(current, pkg.Version) < 0 {
+				latest[pkg.Name] = pkg.Version
+			}
+		}
+	}
+	return latest
+}
@@ -0,0 +1,120 @@
+package advisory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+)
+
+type fakeDetector map[string][]vuln.Match
+
+func (d fakeDetector) VulnerabilitiesForPackages(_ context.Context, names ...string) (map[string][]vuln.Match, error) {
+	result := make(map[string][]vuln.Match)
+	for _, name := range names {
+		result[name] = d[name]
+	}
+	return result, nil
+}
+
+func match(id string, vr vuln.VersionRange) vuln.Match {
+	return vuln.Match{
+		Package:       vuln.Package{Name: "curl"},
+		CPE:           vuln.CPE{VersionRange: vr},
+		Vulnerability: vuln.Vulnerability{ID: id},
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	advisoryCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS("testdata/reconcile"))
+	require.NoError(t, err)
+
+	detector := fakeDetector{"curl": {
+		// fixed in 8.1.0, which is published, but the advisory is still affected
+		match("CVE-2023-0001", vuln.VersionRange{VersionRangeUpper: "8.1.0"}),
+		// the published version is still vulnerable, so under_investigation is right
+		match("CVE-2023-0002", vuln.VersionRange{VersionRangeUpper: "8.2.0"}),
+		// fixed in 8.0.1, but reintroduced in 8.1.0
+		match("CVE-2023-0003", vuln.VersionRange{VersionRangeUpper: "8.0.1"}),
+		match("CVE-2023-0003", vuln.VersionRange{VersionRangeLower: "8.1.0", VersionRangeLowerInclusive: true}),
+		// the fix isn't published yet
+		match("CVE-2023-0004", vuln.VersionRange{VersionRangeUpper: "8.2.0"}),
+		// CVE-2023-0005 isn't known to the detector
+	}}
+
+	drifts, err := reconcile(context.Background(), ReconcileOptions{
+		AdvisoryCfgs:          advisoryCfgs,
+		VulnerabilityDetector: detector,
+	}, map[string]string{"curl": "8.1.0-r1"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []Drift{
+		{
+			Kind:             DriftStillAffected,
+			Package:          "curl",
+			Vulnerability:    "CVE-2023-0001",
+			Status:           vex.StatusAffected,
+			PublishedVersion: "8.1.0-r1",
+		},
+		{
+			Kind:             DriftReintroduced,
+			Package:          "curl",
+			Vulnerability:    "CVE-2023-0003",
+			Status:           vex.StatusFixed,
+			FixedVersion:     "8.0.1-r0",
+			PublishedVersion: "8.1.0-r1",
+		},
+	}, drifts)
+
+	// packages that aren't selected or published are left out
+	drifts, err = reconcile(context.Background(), ReconcileOptions{
+		SelectedPackages:      []string{"wget"},
+		AdvisoryCfgs:          advisoryCfgs,
+		VulnerabilityDetector: detector,
+	}, map[string]string{"curl": "8.1.0-r1"})
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+}
+
+func TestLatestPublishedVersions(t *testing.T) {
+	apkindex := &repository.ApkIndex{Packages: []*repository.Package{
+		{Name: "curl", Origin: "curl", Version: "8.1.0-r0"},
+		{Name: "curl", Origin: "curl", Version: "8.1.0-r10"},
+		{Name: "curl", Origin: "curl", Version: "8.0.1-r3"},
+		{Name: "libcurl4", Origin: "curl", Version: "8.2.0-r0"},
+	}}
+
+	assert.Equal(t, map[string]string{"curl": "8.1.0-r10"}, latestPublishedVersions([]*repository.ApkIndex{apkindex, nil}))
+}
+
+func TestVersionRanges(t *testing.T) {
+	upTo := vuln.VersionRange{VersionRangeLower: "7.0.0", VersionRangeLowerInclusive: true, VersionRangeUpper: "8.1.0"}
+	single := vuln.VersionRange{SingleVersion: "8.1.0"}
+	// a bound the APK version syntax doesn't parse, compared as a string
+	prerelease := vuln.VersionRange{VersionRangeUpper: "8.1.0-beta", VersionRangeUpperInclusive: true}
+
+	for _, tt := range []struct {
+		ranges         []vuln.VersionRange
+		version        string
+		includes, past bool
+	}{
+		{ranges: []vuln.VersionRange{upTo}, version: "7.0.0", includes: true},
+		{ranges: []vuln.VersionRange{upTo}, version: "8.0.10", includes: true},
+		{ranges: []vuln.VersionRange{upTo}, version: "8.1.0", past: true},
+		{ranges: []vuln.VersionRange{upTo}, version: "6.9.9"},
+		{ranges: []vuln.VersionRange{single}, version: "8.1.0", includes: true},
+		{ranges: []vuln.VersionRange{single}, version: "8.1.1", past: true},
+		{ranges: []vuln.VersionRange{prerelease}, version: "8.1.0-beta", includes: true},
+		{ranges: []vuln.VersionRange{prerelease}, version: "8.2.0", past: true},
+		{ranges: []vuln.VersionRange{{}}, version: "8.2.0", includes: true},
+	} {
+		assert.Equal(t, tt.includes, anyIncludes(tt.ranges, tt.version), "%v includes %s", tt.ranges, tt.version)
+		assert.Equal(t, tt.past, anyPast(tt.ranges, tt.version), "%s past %v", tt.version, tt.ranges)
+	}
+}
@@ -2,6 +2,8 @@ package advisory
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/openvex/go-vex/pkg/vex"
@@ -34,6 +36,14 @@ func (req Request) Validate() error {
 		return errors.New("status cannot be empty")
 	}
 
+	if !req.Status.Valid() {
+		return fmt.Errorf("status %q is not one of: %s", req.Status, strings.Join(vex.Statuses(), ", "))
+	}
+
+	if req.Justification != "" && !req.Justification.Valid() {
+		return fmt.Errorf("justification %q is not one of: %s", req.Justification, strings.Join(vex.Justifications(), ", "))
+	}
+
 	switch req.Status {
 	case vex.StatusFixed:
 		if req.FixedVersion == "" {
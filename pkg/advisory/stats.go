@@ -0,0 +1,358 @@
+package advisory
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+)
+
+// SeverityUnknown is the severity of vulnerabilities whose severity isn't known.
+const SeverityUnknown = "UNKNOWN"
+
+// Severities are the severities of vulnerabilities, most severe first.
+var Severities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", SeverityUnknown}
+
+// DefaultSLAs are the days by severity within which open advisories are expected to be resolved.
+var DefaultSLAs = map[string]int{
+	"CRITICAL": 7,
+	"HIGH":     30,
+	"MEDIUM":   90,
+	"LOW":      180,
+}
+
+// SeverityLevel returns the severity of a vulnerability from the severity NVD or GitHub rate it with, e.g. "HIGH 7.5"
+// or "MODERATE 5.5", one of Severities.
+func SeverityLevel(severity string) string {
+	fields := strings.Fields(strings.ToUpper(severity))
+	if len(fields) == 0 {
+		return SeverityUnknown
+	}
+	level := fields[0]
+	if level == "MODERATE" {
+		// GitHub's name for it
+		level = "MEDIUM"
+	}
+	for _, s := range Severities {
+		if level == s {
+			return level
+		}
+	}
+	return SeverityUnknown
+}
+
+// ageBuckets are the upper bounds in days of the ages open advisories are counted by, the last one unbounded.
+var ageBuckets = []struct {
+	label   string
+	maxDays int
+}{
+	{"< 7 days", 7},
+	{"7-30 days", 30},
+	{"30-90 days", 90},
+	{"> 90 days", -1},
+}
+
+// StatsOptions configures the computation of Stats.
+type StatsOptions struct {
+	// Now is when the ages of the open advisories are computed at.
+	Now time.Time
+
+	// Severity returns the severity of a vulnerability of a package, one of Severities. If nil, all severities are
+	// unknown.
+	Severity func(packageName, vulnerability string) string
+
+	// SLAs are the days by severity within which open advisories are expected to be resolved. Advisories of
+	// severities without one are never overdue.
+	SLAs map[string]int
+
+	// Top is the number of packages with the most open advisories to report, all if 0.
+	Top int
+}
+
+// Stats summarizes the state of the triage: the advisories still open, by severity and age, the packages with the
+// most of them, and how long fixing vulnerabilities took.
+type Stats struct {
+	Generated time.Time `json:"generated"`
+
+	// Open is the number of advisories whose latest entry is detected or affected.
+	Open int `json:"open"`
+
+	BySeverity  []SeverityStats `json:"bySeverity"`
+	ByAge       []AgeStats      `json:"byAge"`
+	TopPackages []PackageStats  `json:"topPackages"`
+
+	// Fixed is the number of advisories whose latest entry is fixed, and MeanDaysToRemediation the mean time from
+	// their first entry to their first fixed one.
+	Fixed                 int     `json:"fixed"`
+	MeanDaysToRemediation float64 `json:"meanDaysToRemediation"`
+
+	OpenAdvisories []OpenAdvisory `json:"openAdvisories"`
+}
+
+// SeverityStats are the open advisories of a severity.
+type SeverityStats struct {
+	Severity string `json:"severity"`
+	Open     int    `json:"open"`
+	// Overdue is the number of open advisories older than the SLA of the severity.
+	Overdue int `json:"overdue"`
+	SLADays int `json:"slaDays,omitempty"`
+}
+
+// AgeStats are the open advisories detected within an age range.
+type AgeStats struct {
+	Age  string `json:"age"`
+	Open int    `json:"open"`
+}
+
+// PackageStats are the open advisories of a package.
+type PackageStats struct {
+	Package    string `json:"package"`
+	Open       int    `json:"open"`
+	OldestDays int    `json:"oldestDays"`
+}
+
+// OpenAdvisory is an advisory whose latest entry is detected or affected.
+type OpenAdvisory struct {
+	Package       string    `json:"package"`
+	Vulnerability string    `json:"vulnerability"`
+	Severity      string    `json:"severity"`
+	Detected      time.Time `json:"detected"`
+	AgeDays       int       `json:"ageDays"`
+	Overdue       bool      `json:"overdue"`
+}
+
+// NewStats computes the Stats of the advisories of the documents.
+func NewStats(docs []advisoryconfigs.Document, opts StatsOptions) Stats {
+	stats := Stats{Generated: opts.Now}
+
+	var remediationDays float64
+	for _, doc := range docs {
+		for id, entries := range doc.Advisories {
+			latest := Latest(entries)
+			if latest == nil {
+				continue
+			}
+			first := firstEntry(entries)
+
+			switch latest.Status {
+			case vex.StatusUnderInvestigation, vex.StatusAffected:
+				severity := SeverityUnknown
+				if opts.Severity != nil {
+					severity = opts.Severity(doc.Package.Name, id)
+				}
+				ageDays := int(opts.Now.Sub(first.Timestamp).Hours() / 24)
+				sla, hasSLA := opts.SLAs[severity]
+				stats.OpenAdvisories = append(stats.OpenAdvisories, OpenAdvisory{
+					Package:       doc.Package.Name,
+					Vulnerability: id,
+					Severity:      severity,
+					Detected:      first.Timestamp,
+					AgeDays:       ageDays,
+					Overdue:       hasSLA && ageDays > sla,
+				})
+
+			case vex.StatusFixed:
+				stats.Fixed++
+				remediationDays += firstFixed(entries).Timestamp.Sub(first.Timestamp).Hours() / 24
+			}
+		}
+	}
+	if stats.Fixed > 0 {
+		stats.MeanDaysToRemediation = remediationDays / float64(stats.Fixed)
+	}
+
+	// oldest first
+	sort.Slice(stats.OpenAdvisories, func(i, j int) bool {
+		a, b := stats.OpenAdvisories[i], stats.OpenAdvisories[j]
+		if !a.Detected.Equal(b.Detected) {
+			return a.Detected.Before(b.Detected)
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Vulnerability < b.Vulnerability
+	})
+	stats.Open = len(stats.OpenAdvisories)
+
+	for _, severity := range Severities {
+		s := SeverityStats{Severity: severity, SLADays: opts.SLAs[severity]}
+		for _, a := range stats.OpenAdvisories {
+			if a.Severity == severity {
+				s.Open++
+				if a.Overdue {
+					s.Overdue++
+				}
+			}
+		}
+		stats.BySeverity = append(stats.BySeverity, s)
+	}
+
+	for _, bucket := range ageBuckets {
+		stats.ByAge = append(stats.ByAge, AgeStats{Age: bucket.label})
+	}
+	for _, a := range stats.OpenAdvisories {
+		for i, bucket := range ageBuckets {
+			if bucket.maxDays < 0 || a.AgeDays < bucket.maxDays {
+				stats.ByAge[i].Open++
+				break
+			}
+		}
+	}
+
+	stats.TopPackages = topPackages(stats.OpenAdvisories, opts.Top)
+
+	return stats
+}
+
+// topPackages returns the packages with the most open advisories, the ones with the oldest first among packages with
+// as many.
+func topPackages(open []OpenAdvisory, top int) []PackageStats {
+	byName := make(map[string]*PackageStats)
+	var packages []*PackageStats
+	for _, a := range open {
+		p, ok := byName[a.Package]
+		if !ok {
+			p = &PackageStats{Package: a.Package}
+			byName[a.Package] = p
+			packages = append(packages, p)
+		}
+		p.Open++
+		if a.AgeDays > p.OldestDays {
+			p.OldestDays = a.AgeDays
+		}
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		a, b := packages[i], packages[j]
+		if a.Open != b.Open {
+			return a.Open > b.Open
+		}
+		if a.OldestDays != b.OldestDays {
+			return a.OldestDays > b.OldestDays
+		}
+		return a.Package < b.Package
+	})
+	if top > 0 && len(packages) > top {
+		packages = packages[:top]
+	}
+
+	result := make([]PackageStats, 0, len(packages))
+	for _, p := range packages {
+		result = append(result, *p)
+	}
+	return result
+}
+
+func firstEntry(entries []advisoryconfigs.Entry) advisoryconfigs.Entry {
+	first := entries[0]
+	for _, e := range entries[1:] {
+		if e.Timestamp.Before(first.Timestamp) {
+			first = e
+		}
+	}
+	return first
+}
+
+// firstFixed returns the earliest fixed entry, given there's one.
+func firstFixed(entries []advisoryconfigs.Entry) advisoryconfigs.Entry {
+	var fixed *advisoryconfigs.Entry
+	for i := range entries {
+		e := entries[i]
+		if e.Status == vex.StatusFixed && (fixed == nil || e.Timestamp.Before(fixed.Timestamp)) {
+			fixed = &e
+		}
+	}
+	return *fixed
+}
+
+// StatsFormat is the encoding of Stats.
+type StatsFormat string
+
+const (
+	StatsFormatJSON     StatsFormat = "json"
+	StatsFormatCSV      StatsFormat = "csv"
+	StatsFormatMarkdown StatsFormat = "markdown"
+)
+
+// Write encodes the stats to w. CSV has a row per open advisory, Markdown the summary tables for weekly reports.
+func (s Stats) Write(w io.Writer, format StatsFormat) error {
+	switch format {
+	case StatsFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	case StatsFormatCSV:
+		return s.writeCSV(w)
+	case StatsFormatMarkdown:
+		return s.writeMarkdown(w)
+	default:
+		return fmt.Errorf("unknown stats format %q", format)
+	}
+}
+
+func (s Stats) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"package", "vulnerability", "severity", "detected", "age_days", "overdue"}); err != nil {
+		return err
+	}
+	for _, a := range s.OpenAdvisories {
+		row := []string{
+			a.Package,
+			a.Vulnerability,
+			a.Severity,
+			a.Detected.Format(time.RFC3339),
+			strconv.Itoa(a.AgeDays),
+			strconv.FormatBool(a.Overdue),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (s Stats) writeMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Advisory stats\n\nAs of %s, %d open advisories, %d of them overdue. %d fixed, in %.1f days on average.\n\n",
+		s.Generated.Format(time.DateOnly), s.Open, s.overdue(), s.Fixed, s.MeanDaysToRemediation)
+
+	b.WriteString("## By severity\n\n| Severity | Open | Overdue | SLA |\n| --- | --- | --- | --- |\n")
+	for _, sev := range s.BySeverity {
+		sla := "-"
+		if sev.SLADays > 0 {
+			sla = fmt.Sprintf("%d days", sev.SLADays)
+		}
+		fmt.Fprintf(&b, "| %s | %d | %d | %s |\n", sev.Severity, sev.Open, sev.Overdue, sla)
+	}
+
+	b.WriteString("\n## By age since detection\n\n| Age | Open |\n| --- | --- |\n")
+	for _, age := range s.ByAge {
+		fmt.Fprintf(&b, "| %s | %d |\n", age.Age, age.Open)
+	}
+
+	if len(s.TopPackages) > 0 {
+		b.WriteString("\n## Packages with the most open advisories\n\n| Package | Open | Oldest |\n| --- | --- | --- |\n")
+		for _, p := range s.TopPackages {
+			fmt.Fprintf(&b, "| %s | %d | %d days |\n", p.Package, p.Open, p.OldestDays)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (s Stats) overdue() int {
+	n := 0
+	for _, sev := range s.BySeverity {
+		n += sev.Overdue
+	}
+	return n
+}
@@ -0,0 +1,101 @@
+package advisory
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+)
+
+func TestNewStats(t *testing.T) {
+	now := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) time.Time { return now.AddDate(0, 0, -days) }
+	detected := func(days int) advisoryconfigs.Entry {
+		return advisoryconfigs.Entry{Timestamp: daysAgo(days), Status: vex.StatusUnderInvestigation}
+	}
+	fixed := func(days int) advisoryconfigs.Entry {
+		return advisoryconfigs.Entry{Timestamp: daysAgo(days), Status: vex.StatusFixed, FixedVersion: "1.0.0-r0"}
+	}
+
+	docs := []advisoryconfigs.Document{
+		{
+			Package: advisoryconfigs.Package{Name: "curl"},
+			Advisories: advisoryconfigs.Advisories{
+				"CVE-2023-0001": {detected(40)},
+				"CVE-2023-0002": {detected(3)},
+				"CVE-2023-0003": {detected(20), fixed(10)},
+				"CVE-2023-0004": {detected(10), {Timestamp: daysAgo(5), Status: vex.StatusNotAffected, Justification: vex.ComponentNotPresent}},
+			},
+		},
+		{
+			Package: advisoryconfigs.Package{Name: "zlib"},
+			Advisories: advisoryconfigs.Advisories{
+				"CVE-2022-37434": {detected(100), {Timestamp: daysAgo(90), Status: vex.StatusAffected, ActionStatement: "fix not planned"}},
+				"CVE-2023-0005":  {detected(30), fixed(28), detected(20), fixed(0)},
+			},
+		},
+	}
+	severities := map[string]string{
+		"CVE-2023-0001":  "CRITICAL",
+		"CVE-2023-0002":  "HIGH",
+		"CVE-2022-37434": "MEDIUM",
+	}
+
+	stats := NewStats(docs, StatsOptions{
+		Now:      now,
+		Severity: func(_, vulnerability string) string { return SeverityLevel(severities[vulnerability]) },
+		SLAs:     DefaultSLAs,
+	})
+
+	assert.Equal(t, 3, stats.Open)
+	assert.Equal(t, []OpenAdvisory{
+		{Package: "zlib", Vulnerability: "CVE-2022-37434", Severity: "MEDIUM", Detected: daysAgo(100), AgeDays: 100, Overdue: true},
+		{Package: "curl", Vulnerability: "CVE-2023-0001", Severity: "CRITICAL", Detected: daysAgo(40), AgeDays: 40, Overdue: true},
+		{Package: "curl", Vulnerability: "CVE-2023-0002", Severity: "HIGH", Detected: daysAgo(3), AgeDays: 3},
+	}, stats.OpenAdvisories)
+	assert.Equal(t, []SeverityStats{
+		{Severity: "CRITICAL", Open: 1, Overdue: 1, SLADays: 7},
+		{Severity: "HIGH", Open: 1, SLADays: 30},
+		{Severity: "MEDIUM", Open: 1, Overdue: 1, SLADays: 90},
+		{Severity: "LOW", SLADays: 180},
+		{Severity: "UNKNOWN"},
+	}, stats.BySeverity)
+	assert.Equal(t, []AgeStats{
+		{Age: "< 7 days", Open: 1},
+		{Age: "7-30 days"},
+		{Age: "30-90 days", Open: 1},
+		{Age: "> 90 days", Open: 1},
+	}, stats.ByAge)
+	assert.Equal(t, []PackageStats{
+		{Package: "curl", Open: 2, OldestDays: 40},
+		{Package: "zlib", Open: 1, OldestDays: 100},
+	}, stats.TopPackages)
+	assert.Equal(t, 2, stats.Fixed)
+	assert.InDelta(t, 6, stats.MeanDaysToRemediation, 0.001, "from the first entry to the first fixed one, 10 and 2 days")
+
+	var csv bytes.Buffer
+	require.NoError(t, stats.Write(&csv, StatsFormatCSV))
+	assert.Equal(t, `package,vulnerability,severity,detected,age_days,overdue
+zlib,CVE-2022-37434,MEDIUM,2023-03-23T00:00:00Z,100,true
+curl,CVE-2023-0001,CRITICAL,2023-05-22T00:00:00Z,40,true
+curl,CVE-2023-0002,HIGH,2023-06-28T00:00:00Z,3,false
+`, csv.String())
+
+	var md bytes.Buffer
+	require.NoError(t, stats.Write(&md, StatsFormatMarkdown))
+	assert.Contains(t, md.String(), "As of 2023-07-01, 3 open advisories, 2 of them overdue. 2 fixed, in 6.0 days on average.")
+	assert.Contains(t, md.String(), "| CRITICAL | 1 | 1 | 7 days |")
+	assert.Contains(t, md.String(), "| curl | 2 | 40 days |")
+}
+
+func TestSeverityLevel(t *testing.T) {
+	assert.Equal(t, "HIGH", SeverityLevel("HIGH 7.5"))
+	assert.Equal(t, "MEDIUM", SeverityLevel("MODERATE 5.5"))
+	assert.Equal(t, "CRITICAL", SeverityLevel("critical"))
+	assert.Equal(t, SeverityUnknown, SeverityLevel(""))
+	assert.Equal(t, SeverityUnknown, SeverityLevel("NONE 0.0"))
+}
@@ -2,19 +2,26 @@ package advisory
 
 import (
 	"fmt"
+	"path"
+	"sort"
 
 	"github.com/wolfi-dev/wolfictl/pkg/configs"
 	"github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
 )
 
 // UpdateOptions configures the Update operation.
 type UpdateOptions struct {
 	// AdvisoryCfgs is the Index of advisory configurations on which to operate.
 	AdvisoryCfgs *configs.Index[advisory.Document]
+
+	// Aliases are the aliases of the vulnerability, so that its advisory is updated if it's under another ID.
+	Aliases vuln.Aliases
 }
 
 // Update adds a new entry to an existing advisory (named by the vuln parameter)
-// in the configuration at the provided path.
+// in the configuration at the provided path. If the advisory is under an alias
+// of the vulnerability, the entry is added to it there.
 func Update(req Request, opts UpdateOptions) error {
 	vulnID := req.Vulnerability
 	advisoryEntry := req.toAdvisoryEntry()
@@ -26,11 +33,12 @@ func Update(req Request, opts UpdateOptions) error {
 
 	u := advisory.NewAdvisoriesSectionUpdater(func(cfg advisory.Document) (advisory.Advisories, error) {
 		advisories := cfg.Advisories
-		if _, existsAlready := advisories[vulnID]; !existsAlready {
+		existing, existsAlready := advisoryIDOf(advisories, vulnID, opts.Aliases)
+		if !existsAlready {
 			return advisory.Advisories{}, fmt.Errorf("no advisory exists for %s", vulnID)
 		}
 
-		advisories[vulnID] = append(advisories[vulnID], advisoryEntry)
+		advisories[existing] = append(advisories[existing], advisoryEntry)
 
 		return advisories, nil
 	})
@@ -41,3 +49,42 @@ func Update(req Request, opts UpdateOptions) error {
 
 	return nil
 }
+
+// UpdateMatching adds the entry of the request to the advisory for its vulnerability of every package whose name
+// matches the glob pattern, e.g. "py3-*", so that one determination, like an ecosystem-wide false positive, is
+// recorded for all of them at once. The package of the request is ignored, and packages without an advisory for the
+// vulnerability are left alone. It returns the packages updated, and updates none unless there's at least one and the
+// request is valid for them.
+func UpdateMatching(req Request, pattern string, opts UpdateOptions) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid package pattern %q: %w", pattern, err)
+	}
+
+	var packages []string
+	for _, doc := range opts.AdvisoryCfgs.Select().Configurations() {
+		name := doc.Package.Name
+		if matched, _ := path.Match(pattern, name); !matched { //nolint:errcheck // the pattern is valid
+			continue
+		}
+		if _, ok := advisoryIDOf(doc.Advisories, req.Vulnerability, opts.Aliases); ok {
+			packages = append(packages, name)
+		}
+	}
+	if len(packages) == 0 {
+		return nil, fmt.Errorf("no package matching %q has an advisory for %s", pattern, req.Vulnerability)
+	}
+	sort.Strings(packages)
+
+	req.Package = packages[0]
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	for _, name := range packages {
+		req.Package = name
+		if err := Update(req, opts); err != nil {
+			return nil, err
+		}
+	}
+	return packages, nil
+}
@@ -0,0 +1,59 @@
+package advisory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+)
+
+func TestUpdateMatching(t *testing.T) {
+	dir := t.TempDir()
+	for name, vulnID := range map[string]string{
+		"py3-requests": "CVE-2024-1234",
+		"py3-urllib3":  "CVE-2024-1234",
+		"py3-six":      "CVE-2023-0001",
+		"curl":         "CVE-2024-1234",
+	} {
+		doc := fmt.Sprintf("package:\n  name: %s\n\nadvisories:\n  %s:\n    - timestamp: 2024-01-01T00:00:00Z\n      status: under_investigation\n", name, vulnID)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+".advisories.yaml"), []byte(doc), 0o600))
+	}
+	advisoryCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	require.NoError(t, err)
+	opts := UpdateOptions{AdvisoryCfgs: advisoryCfgs}
+
+	req := Request{
+		Vulnerability: "CVE-2024-1234",
+		Status:        vex.StatusNotAffected,
+		Justification: vex.VulnerableCodeNotPresent,
+		Timestamp:     time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	_, err = UpdateMatching(req, "py3-[", opts)
+	assert.ErrorContains(t, err, "invalid package pattern")
+	_, err = UpdateMatching(req, "go-*", opts)
+	assert.ErrorContains(t, err, `no package matching "go-*" has an advisory for CVE-2024-1234`)
+	invalid := req
+	invalid.Justification = ""
+	_, err = UpdateMatching(invalid, "py3-*", opts)
+	assert.Error(t, err, "the request is validated before anything is updated")
+
+	packages, err := UpdateMatching(req, "py3-*", opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"py3-requests", "py3-urllib3"}, packages)
+
+	advisoryCfgs, err = advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	require.NoError(t, err)
+	for name, entries := range map[string]int{"py3-requests": 2, "py3-urllib3": 2, "curl": 1} {
+		doc, err := advisoryCfgs.Select().WhereName(name).First()
+		require.NoError(t, err)
+		assert.Len(t, doc.Configuration().Advisories["CVE-2024-1234"], entries, name)
+	}
+}
@@ -0,0 +1,222 @@
+package advisory
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"time"
+
+	"chainguard.dev/melange/pkg/build"
+	version "github.com/knqyf263/go-apk-version"
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+	"gopkg.in/yaml.v3"
+)
+
+// advisoriesFileSuffix is the suffix of the names of advisory documents, which are named after their package.
+const advisoriesFileSuffix = ".advisories.yaml"
+
+var (
+	reCVEID  = regexp.MustCompile(`^CVE-\d{4}-\d{4,}$`)
+	reGHSAID = regexp.MustCompile(`^GHSA(-[23456789cfghjmpqrvwx]{4}){3}$`)
+)
+
+// ValidVulnerabilityID reports whether advisories can be kept under the ID of a vulnerability, which has to be a CVE
+// or GHSA ID.
+func ValidVulnerabilityID(id string) bool {
+	return reCVEID.MatchString(id) || reGHSAID.MatchString(id)
+}
+
+// ValidateOptions configures the Validate operation.
+type ValidateOptions struct {
+	// AdvisoryFsys is the advisories repository, whose advisory documents are validated.
+	AdvisoryFsys fs.FS
+
+	// BuildCfgs is the Index of the build configurations of the distro, whose packages advisories have to be about. If
+	// nil, which packages exist isn't checked.
+	BuildCfgs *configs.Index[build.Configuration]
+
+	// APKIndexes are the indexes of the package repository, which tell the version of packages when their
+	// vulnerabilities were detected. If empty, fixed versions aren't checked against them.
+	APKIndexes []*repository.ApkIndex
+}
+
+// ValidationError is a problem of an advisory document, or of one of its advisories.
+type ValidationError struct {
+	Path          string
+	Vulnerability string
+	Err           error
+}
+
+func (e ValidationError) Error() string {
+	if e.Vulnerability == "" {
+		return fmt.Sprintf("%s: %s", e.Path, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Path, e.Vulnerability, e.Err)
+}
+
+func (e ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks the advisory documents of the advisories repository, and returns their problems, sorted by path and
+// vulnerability:
+//
+//   - documents have to follow the schema, without unknown fields, and be named after their package
+//   - their package has to be one the distro builds
+//   - vulnerabilities have to be CVE or GHSA IDs
+//   - entries have to be in chronological order, with a VEX status and the statement it requires
+//   - fixed versions have to be package versions, not older than the version of the package when the vulnerability
+//     was detected
+func Validate(opts ValidateOptions) ([]ValidationError, error) {
+	paths, err := fs.Glob(opts.AdvisoryFsys, "*.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	packages := make(map[string]bool)
+	if opts.BuildCfgs != nil {
+		for _, cfg := range opts.BuildCfgs.Select().Configurations() {
+			packages[cfg.Package.Name] = true
+		}
+	}
+	published := publishedVersions(opts.APKIndexes)
+
+	var problems []ValidationError
+	for _, path := range paths {
+		b, err := fs.ReadFile(opts.AdvisoryFsys, path)
+		if err != nil {
+			return nil, err
+		}
+
+		var doc advisoryconfigs.Document
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(&doc); err != nil {
+			problems = append(problems, ValidationError{Path: path, Err: fmt.Errorf("doesn't follow the advisory document schema: %w", err)})
+			continue
+		}
+
+		problems = append(problems, validateDocument(path, doc, packages, published)...)
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Path != problems[j].Path {
+			return problems[i].Path < problems[j].Path
+		}
+		return problems[i].Vulnerability < problems[j].Vulnerability
+	})
+	return problems, nil
+}
+
+func validateDocument(path string, doc advisoryconfigs.Document, packages map[string]bool, published map[string][]repository.Package) []ValidationError {
+	var problems []ValidationError
+	docProblem := func(format string, args ...any) {
+		problems = append(problems, ValidationError{Path: path, Err: fmt.Errorf(format, args...)})
+	}
+
+	name := doc.Package.Name
+	switch {
+	case name == "":
+		docProblem("package name is missing")
+	case path != name+advisoriesFileSuffix:
+		docProblem("document of package %s should be named %s", name, name+advisoriesFileSuffix)
+	}
+	if name != "" && len(packages) > 0 && !packages[name] {
+		docProblem("package %s isn't built by any config of the distro", name)
+	}
+
+	for id, entries := range doc.Advisories {
+		for _, err := range validateAdvisory(name, id, entries, published[name]) {
+			problems = append(problems, ValidationError{Path: path, Vulnerability: id, Err: err})
+		}
+	}
+	return problems
+}
+
+func validateAdvisory(packageName, id string, entries []advisoryconfigs.Entry, published []repository.Package) []error {
+	var errs []error
+	if !ValidVulnerabilityID(id) {
+		errs = append(errs, errors.New("isn't a CVE or GHSA ID"))
+	}
+	if len(entries) == 0 {
+		errs = append(errs, errors.New("has no entries"))
+	}
+
+	var detected time.Time
+	for i := range entries {
+		e := entries[i]
+		if e.Timestamp.IsZero() {
+			errs = append(errs, fmt.Errorf("entry %d has no timestamp", i+1))
+		} else if i > 0 && e.Timestamp.Before(entries[i-1].Timestamp) {
+			errs = append(errs, fmt.Errorf("entry %d (%s) is older than the entry before it (%s)", i+1, e.Timestamp.Format(time.RFC3339), entries[i-1].Timestamp.Format(time.RFC3339)))
+		}
+		if detected.IsZero() {
+			detected = e.Timestamp
+		}
+
+		req := Request{
+			Package:       packageName,
+			Vulnerability: id,
+			Status:        e.Status,
+			Action:        e.ActionStatement,
+			Impact:        e.ImpactStatement,
+			Justification: e.Justification,
+			FixedVersion:  e.FixedVersion,
+		}
+		if err := req.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("entry %d: %w", i+1, err))
+			continue
+		}
+
+		if e.Status != vex.StatusFixed {
+			continue
+		}
+		if _, err := version.NewVersion(e.FixedVersion); err != nil {
+			errs = append(errs, fmt.Errorf("entry %d: fixed version %q isn't a package version: %w", i+1, e.FixedVersion, err))
+			continue
+		}
+		if atDetection := versionAt(published, detected); atDetection != "" && dag.CompareVersions(e.FixedVersion, atDetection) < 0 {
+			errs = append(errs, fmt.Errorf("entry %d: fixed version %s is older than %s, the version of the package when the vulnerability was detected", i+1, e.FixedVersion, atDetection))
+		}
+	}
+	return errs
+}
+
+// publishedVersions returns the packages of the indexes by their origin, the origin packages themselves only.
+func publishedVersions(apkindexes []*repository.ApkIndex) map[string][]repository.Package {
+	published := make(map[string][]repository.Package)
+	for _, apkindex := range apkindexes {
+		if apkindex == nil {
+			continue
+		}
+		for _, pkg := range apkindex.Packages {
+			if pkg.Origin == "" || pkg.Name != pkg.Origin {
+				continue
+			}
+			published[pkg.Name] = append(published[pkg.Name], *pkg)
+		}
+	}
+	return published
+}
+
+// versionAt returns the latest version of the published packages built by t, or "" if none was.
+func versionAt(published []repository.Package, t time.Time) string {
+	latest := ""
+	for i := range published {
+		p := published[i]
+		if p.BuildTime.IsZero() || p.BuildTime.After(t) {
+			continue
+		}
+		if latest == "" || dag.CompareVersions(latest, p.Version) < 0 {
+			latest = p.Version
+		}
+	}
+	return latest
+}
@@ -0,0 +1,59 @@
+package advisory
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	buildconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/build"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+)
+
+func TestValidate(t *testing.T) {
+	buildCfgs, err := buildconfigs.NewIndex(rwos.DirFS("./testdata/validate/distro"))
+	require.NoError(t, err)
+
+	apkindex := &repository.ApkIndex{Packages: []*repository.Package{
+		{Name: "curl", Origin: "curl", Version: "7.88.1-r0", BuildTime: time.Date(2023, 3, 20, 0, 0, 0, 0, time.UTC)},
+		{Name: "curl", Origin: "curl", Version: "8.0.1-r0", BuildTime: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{Name: "libcurl4", Origin: "curl", Version: "8.0.1-r0", BuildTime: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{Name: "curl", Origin: "curl", Version: "8.1.0-r0", BuildTime: time.Date(2023, 5, 19, 0, 0, 0, 0, time.UTC)},
+	}}
+
+	problems, err := Validate(ValidateOptions{
+		AdvisoryFsys: os.DirFS("./testdata/validate/advisories"),
+		BuildCfgs:    buildCfgs,
+		APKIndexes:   []*repository.ApkIndex{apkindex},
+	})
+	require.NoError(t, err)
+
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.Error())
+	}
+
+	expected := []string{
+		"curl.advisories.yaml: CVE-2023-28320: entry 2 (2023-05-18T10:00:00Z) is older than the entry before it (2023-05-19T10:00:00Z)",
+		"curl.advisories.yaml: CVE-2023-28321: entry 1: fixed version 7.88.1-r0 is older than 8.0.1-r0, the version of the package when the vulnerability was detected",
+		`curl.advisories.yaml: CVE-2023-28322: entry 1: fixed version "not a version" isn't a package version: invalid version`,
+		"curl.advisories.yaml: CVE-23-1: isn't a CVE or GHSA ID",
+		"curl.advisories.yaml: CVE-23-1: entry 1: justification cannot be empty if status is 'not affected'",
+		"openssl.advisories.yaml: doesn't follow the advisory document schema: yaml: unmarshal errors:\n  line 3: field version not found in type advisory.Package",
+		"zlib.advisories.yaml: package zlib isn't built by any config of the distro",
+	}
+
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("Validate() returned unexpected problems (-want +got):\n%s", diff)
+	}
+}
+
+func TestValidVulnerabilityID(t *testing.T) {
+	assert.True(t, ValidVulnerabilityID("CVE-2023-46218"))
+	assert.True(t, ValidVulnerabilityID("GHSA-66p4-3h4c-hcfw"))
+	assert.False(t, ValidVulnerabilityID("GO-2023-2102"))
+	assert.False(t, ValidVulnerabilityID("PYSEC-2023-117"))
+}
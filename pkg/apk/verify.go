@@ -0,0 +1,166 @@
+package apk
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1" //nolint:gosec // apks are signed and indexed by the SHA-1 of their control segment
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ParsePublicKey parses an RSA public key in PEM, like the ones apks are signed with.
+func ParsePublicKey(b []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM encoded key found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("key is a %T, not an RSA key", key)
+	}
+	return rsaKey, nil
+}
+
+// VerifySignature checks that the apk is signed by one of the keys, by the file names of the keys, like
+// wolfi-signing.rsa.pub. If checksum isn't empty, it also checks that the apk is the one the index lists with that
+// checksum, the SHA-1 of its control segment. The data segment is checked against the datahash of the .PKGINFO of the
+// signed control segment, so everything the apk contains is authenticated by the signature.
+func VerifySignature(apk []byte, keys map[string]*rsa.PublicKey, checksum []byte) error {
+	segments, err := splitSegments(apk)
+	if err != nil {
+		return err
+	}
+	if len(segments) != 3 {
+		return fmt.Errorf("apk has %d gzip streams, a signed apk has a signature, a control and a data stream", len(segments))
+	}
+	signature, control, data := segments[0], segments[1], segments[2]
+
+	keyName, alg, sig, err := readSignature(signature)
+	if err != nil {
+		return err
+	}
+	key, ok := keys[keyName]
+	if !ok {
+		return fmt.Errorf("apk is signed with %s, which isn't a trusted key", keyName)
+	}
+
+	controlSHA1 := sha1.Sum(control) //nolint:gosec // see the import
+	digest := controlSHA1[:]
+	if alg == crypto.SHA256 {
+		controlSHA256 := sha256.Sum256(control)
+		digest = controlSHA256[:]
+	}
+	if err := rsa.VerifyPKCS1v15(key, alg, digest, sig); err != nil {
+		return fmt.Errorf("signature of the apk by %s is invalid: %w", keyName, err)
+	}
+
+	if len(checksum) > 0 && !bytes.Equal(checksum, controlSHA1[:]) {
+		return errors.New("apk doesn't have the checksum the index lists for it")
+	}
+
+	dataHash, err := readDataHash(control)
+	if err != nil {
+		return err
+	}
+	dataSHA256 := sha256.Sum256(data)
+	if hex.EncodeToString(dataSHA256[:]) != dataHash {
+		return errors.New("data of the apk doesn't have the hash its signed control segment lists")
+	}
+	return nil
+}
+
+// readDataHash returns the datahash of the .PKGINFO in the control segment of an apk, the hex encoded SHA-256 of its
+// data segment.
+func readDataHash(control []byte) (string, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(control))
+	if err != nil {
+		return "", err
+	}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			// the control segment isn't the end of the archive, so it ends without the end of archive marker
+			return "", errors.New("control segment of the apk has no .PKGINFO")
+		}
+		if hdr.Name != ".PKGINFO" {
+			continue
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return "", fmt.Errorf("unable to read .PKGINFO: %w", err)
+		}
+		for _, line := range strings.Split(string(b), "\n") {
+			if k, v, ok := strings.Cut(line, " = "); ok && k == "datahash" {
+				return strings.TrimSpace(v), nil
+			}
+		}
+		return "", errors.New(".PKGINFO of the apk has no datahash")
+	}
+}
+
+// splitSegments returns the gzip streams an apk is made of.
+func splitSegments(apk []byte) ([][]byte, error) {
+	// a bytes.Reader is read by gzip byte by byte, so what's left of it is where the next stream starts
+	r := bytes.NewReader(apk)
+	var segments [][]byte
+	for start := 0; r.Len() > 0; {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read gzip stream %d of apk: %w", len(segments)+1, err)
+		}
+		zr.Multistream(false)
+		if _, err := io.Copy(io.Discard, zr); err != nil {
+			return nil, fmt.Errorf("unable to read gzip stream %d of apk: %w", len(segments)+1, err)
+		}
+		end := len(apk) - r.Len()
+		segments = append(segments, apk[start:end])
+		start = end
+	}
+	return segments, nil
+}
+
+// readSignature returns the name of the key and the hash the signature in the signature segment of an apk was made
+// with, and the signature. The segment has a file like .SIGN.RSA.wolfi-signing.rsa.pub, or .SIGN.RSA256.<key> for
+// signatures of SHA-256 hashes.
+func readSignature(segment []byte) (keyName string, alg crypto.Hash, sig []byte, err error) {
+	zr, err := gzip.NewReader(bytes.NewReader(segment))
+	if err != nil {
+		return "", 0, nil, err
+	}
+	tr := tar.NewReader(zr)
+	hdr, err := tr.Next()
+	if err != nil {
+		return "", 0, nil, fmt.Errorf("apk isn't signed: %w", err)
+	}
+
+	name := path.Base(hdr.Name)
+	switch {
+	case strings.HasPrefix(name, ".SIGN.RSA256."):
+		keyName, alg = strings.TrimPrefix(name, ".SIGN.RSA256."), crypto.SHA256
+	case strings.HasPrefix(name, ".SIGN.RSA."):
+		keyName, alg = strings.TrimPrefix(name, ".SIGN.RSA."), crypto.SHA1
+	default:
+		return "", 0, nil, fmt.Errorf("apk isn't signed, its first file is %s", hdr.Name)
+	}
+
+	sig = make([]byte, hdr.Size)
+	if _, err := io.ReadFull(tr, sig); err != nil {
+		return "", 0, nil, fmt.Errorf("unable to read signature: %w", err)
+	}
+	return keyName, alg, sig, nil
+}
@@ -0,0 +1,92 @@
+package apk
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" //nolint:gosec // see verify.go
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// segment returns a gzip stream of a tar archive of the file, without the end of the archive unless it's the last
+// segment of an apk.
+func segment(t *testing.T, name string, content []byte, last bool) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))}))
+	_, err := tw.Write(content)
+	require.NoError(t, err)
+	if last {
+		require.NoError(t, tw.Close())
+	} else {
+		require.NoError(t, tw.Flush())
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// signedAPK returns an apk signed with the key, and the checksum of its control segment.
+func signedAPK(t *testing.T, key *rsa.PrivateKey, keyName string) (apk, checksum []byte) {
+	data := segment(t, "usr/bin/curl", []byte("#!/bin/sh\n"), true)
+	dataHash := sha256.Sum256(data)
+	control := segment(t, ".PKGINFO", []byte("pkgname = curl\npkgver = 8.1.0-r0\ndatahash = "+hex.EncodeToString(dataHash[:])+"\n"), false)
+	sum := sha1.Sum(control) //nolint:gosec // see verify.go
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA1, sum[:])
+	require.NoError(t, err)
+
+	apk = append(apk, segment(t, ".SIGN.RSA."+keyName, sig, false)...)
+	apk = append(apk, control...)
+	apk = append(apk, data...)
+	return apk, sum[:]
+}
+
+func TestVerifySignature(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	apk, checksum := signedAPK(t, key, "wolfi-signing.rsa.pub")
+	trusted := map[string]*rsa.PublicKey{"wolfi-signing.rsa.pub": &key.PublicKey}
+
+	assert.NoError(t, VerifySignature(apk, trusted, checksum))
+	assert.NoError(t, VerifySignature(apk, trusted, nil))
+
+	assert.ErrorContains(t, VerifySignature(apk, map[string]*rsa.PublicKey{"other.rsa.pub": &other.PublicKey}, nil), "isn't a trusted key")
+	assert.ErrorContains(t, VerifySignature(apk, map[string]*rsa.PublicKey{"wolfi-signing.rsa.pub": &other.PublicKey}, nil), "invalid")
+	assert.ErrorContains(t, VerifySignature(apk, trusted, []byte("not the checksum")), "checksum")
+
+	control := len(apk) - len(segment(t, "usr/bin/curl", []byte("#!/bin/sh\n"), true))
+	swapped := append(append([]byte{}, apk[:control]...), segment(t, "usr/bin/curl", []byte("#!/bin/sh\nrm -rf /\n"), true)...)
+	assert.ErrorContains(t, VerifySignature(swapped, trusted, checksum), "hash", "the data is authenticated too")
+	appended := append(append([]byte{}, apk...), segment(t, "usr/bin/wget", []byte("#!/bin/sh\n"), true)...)
+	assert.ErrorContains(t, VerifySignature(appended, trusted, checksum), "4 gzip streams", "streams after the data aren't accepted")
+
+	unsigned := segment(t, ".PKGINFO", []byte("pkgname = curl\n"), true)
+	assert.Error(t, VerifySignature(unsigned, trusted, nil))
+}
+
+func TestParsePublicKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	parsed, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(parsed))
+
+	_, err = ParsePublicKey([]byte("not a key"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,283 @@
+package builder
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+// Cache stores the artifacts of builds by cache key, so builds whose inputs haven't changed can be skipped.
+type Cache interface {
+	// Fetch copies the artifacts of t stored under key into repo, and reports whether they were found.
+	Fetch(ctx context.Context, key, repo string, t Task) (bool, error)
+
+	// Store saves the artifacts of t from repo under key.
+	Store(ctx context.Context, key, repo string, t Task) error
+}
+
+// CacheKey returns the cache key of a build: a digest of the package's melange config, the patches it applies, the
+// architecture, and the exact versions its build environment resolved to. Any change to those produces a different
+// key.
+func CacheKey(t Task, env []dag.Package) (string, error) {
+	config, err := os.ReadFile(t.Config.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read config of %s: %w", t, err)
+	}
+	patches, err := patchDigests(t.Config)
+	if err != nil {
+		return "", fmt.Errorf("failed to read patches of %s: %w", t, err)
+	}
+
+	deps := make([]string, 0, len(env))
+	for _, p := range env {
+		deps = append(deps, fmt.Sprintf("%s=%s@%s", p.Name(), p.Version(), p.Source()))
+	}
+	sort.Strings(deps)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "arch:%s\n", t.Arch)
+	fmt.Fprintf(h, "config:%x\n", sha256.Sum256(config))
+	for _, p := range patches {
+		fmt.Fprintf(h, "patch:%s\n", p)
+	}
+	for _, d := range deps {
+		fmt.Fprintf(h, "dep:%s\n", d)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// patchDigests returns the digests of the files the patch steps of a config apply, as name:digest sorted by name.
+// Patches live in the source directory of the package, next to its config and named after it, like the Makefile
+// passes to melange build with --source-dir.
+func patchDigests(c *dag.Configuration) ([]string, error) {
+	var names []string
+	var walk func(steps []build.Pipeline)
+	walk = func(steps []build.Pipeline) {
+		for i := range steps {
+			if steps[i].Uses == "patch" {
+				names = append(names, strings.Fields(steps[i].With["patches"])...)
+			}
+			walk(steps[i].Pipeline)
+		}
+	}
+	walk(c.Pipeline)
+	for i := range c.Subpackages {
+		walk(c.Subpackages[i].Pipeline)
+	}
+
+	dir := filepath.Join(filepath.Dir(c.Path), c.Package.Name)
+	digests := make([]string, 0, len(names))
+	for _, name := range names {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, fmt.Sprintf("%s:%x", name, sha256.Sum256(b)))
+	}
+	sort.Strings(digests)
+	return digests, nil
+}
+
+// Artifacts returns the repository relative paths of the apks the task builds, for the package and each of its
+// subpackages.
+func (t Task) Artifacts() []string {
+	artifacts := []string{t.Target()}
+	for i := range t.Config.Subpackages {
+		artifacts = append(artifacts, path.Join("packages", t.Arch, fmt.Sprintf("%s-%s-r%d.apk", t.Config.Subpackages[i].Name, t.Config.Package.Version, t.Config.Package.Epoch)))
+	}
+	return artifacts
+}
+
+// repoPath returns where an artifact of t lives in repo
+func repoPath(repo string, t Task, artifact string) string {
+	return filepath.Join(repo, t.Arch, filepath.Base(artifact))
+}
+
+// DirCache is a Cache in a local directory, with a subdirectory for each key.
+type DirCache struct {
+	Dir string
+}
+
+func (c DirCache) Fetch(_ context.Context, key, repo string, t Task) (bool, error) {
+	for _, a := range t.Artifacts() {
+		if _, err := os.Stat(filepath.Join(c.Dir, key, filepath.Base(a))); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return false, nil
+			}
+			return false, err
+		}
+	}
+	for _, a := range t.Artifacts() {
+		if err := copyFile(filepath.Join(c.Dir, key, filepath.Base(a)), repoPath(repo, t, a)); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (c DirCache) Store(_ context.Context, key, repo string, t Task) error {
+	for _, a := range t.Artifacts() {
+		if err := copyFile(repoPath(repo, t, a), filepath.Join(c.Dir, key, filepath.Base(a))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OCICache is a Cache in an OCI registry. The artifacts of each build are stored as a single layer image, tagged
+// with the cache key.
+type OCICache struct {
+	Repo string
+}
+
+func (c OCICache) ref(key string) (name.Reference, error) {
+	// tags are limited to 128 characters, a sha256 hex digest is 64
+	return name.NewTag(fmt.Sprintf("%s:%s", c.Repo, key), name.WeakValidation)
+}
+
+func (c OCICache) Fetch(ctx context.Context, key, repo string, t Task) (bool, error) {
+	ref, err := c.ref(key)
+	if err != nil {
+		return false, err
+	}
+	img, err := remote.Image(ref, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		var terr *transport.Error
+		if errors.As(err, &terr) && terr.StatusCode == 404 {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get %s: %w", ref, err)
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return false, err
+	}
+	if len(layers) != 1 {
+		return false, fmt.Errorf("expected 1 layer in %s, found %d", ref, len(layers))
+	}
+	rc, err := layers[0].Uncompressed()
+	if err != nil {
+		return false, err
+	}
+	defer rc.Close()
+
+	wanted := make(map[string]string)
+	for _, a := range t.Artifacts() {
+		wanted[filepath.Base(a)] = repoPath(repo, t, a)
+	}
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return false, err
+		}
+		dst, ok := wanted[hdr.Name]
+		if !ok {
+			continue
+		}
+		if err := writeFile(dst, tr); err != nil {
+			return false, err
+		}
+		delete(wanted, hdr.Name)
+	}
+	if len(wanted) != 0 {
+		return false, fmt.Errorf("%s is missing %d artifacts of %s", ref, len(wanted), t)
+	}
+	return true, nil
+}
+
+func (c OCICache) Store(ctx context.Context, key, repo string, t Task) error {
+	ref, err := c.ref(key)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, a := range t.Artifacts() {
+		b, err := os.ReadFile(repoPath(repo, t, a))
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{
+			Name: filepath.Base(a),
+			Mode: 0o644,
+			Size: int64(len(b)),
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(b); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	})
+	if err != nil {
+		return err
+	}
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	if err != nil {
+		return err
+	}
+	if err := remote.Write(ref, img, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain)); err != nil {
+		return fmt.Errorf("failed to push %s: %w", ref, err)
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeFile(dst, f)
+}
+
+func writeFile(dst string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, r); err != nil {
+		// don't leave a truncated apk in the repository, e.g. when the build is canceled during a fetch
+		os.Remove(dst)
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	return nil
+}
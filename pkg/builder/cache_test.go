@@ -0,0 +1,125 @@
+package builder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+func testTask(t *testing.T, name string) Task {
+	testDir := "../dag/testdata/complex"
+	pkgs, err := dag.NewPackages(os.DirFS(testDir), testDir)
+	require.NoError(t, err)
+	for _, c := range pkgs.Config(name, true) {
+		if c.Package.Name == name {
+			return Task{Config: c, Arch: "x86_64"}
+		}
+	}
+	t.Fatalf("package %s not found", name)
+	return Task{}
+}
+
+func TestCacheKey(t *testing.T) {
+	g := testGraph(t)
+	task := testTask(t, "two")
+	env, err := g.BuildEnvironment("two", "")
+	require.NoError(t, err)
+
+	key, err := CacheKey(task, env)
+	require.NoError(t, err)
+	again, err := CacheKey(task, env)
+	require.NoError(t, err)
+	assert.Equal(t, key, again, "keys should be stable")
+
+	other := task
+	other.Arch = "aarch64"
+	otherKey, err := CacheKey(other, env)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, otherKey, "architecture should change the key")
+
+	noDeps, err := CacheKey(task, nil)
+	require.NoError(t, err)
+	if len(env) > 0 {
+		assert.NotEqual(t, key, noDeps, "dependencies should change the key")
+	}
+
+	dir := t.TempDir()
+	b, err := os.ReadFile(task.Config.Path)
+	require.NoError(t, err)
+	modified := filepath.Join(dir, "two.yaml")
+	require.NoError(t, os.WriteFile(modified, append(b, '\n'), 0o600))
+	cfg := *task.Config
+	cfg.Path = modified
+	changed := task
+	changed.Config = &cfg
+	changedKey, err := CacheKey(changed, env)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, changedKey, "config should change the key")
+}
+
+func TestCacheKey_patches(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "patched.yaml"), []byte(`package:
+  name: patched
+  version: 1.0.0
+  epoch: 0
+pipeline:
+  - uses: patch
+    with:
+      patches: fix-build.patch  cve.patch
+`), 0o600))
+	patch := func(name, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "patched"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "patched", name), []byte(content), 0o600))
+	}
+	pkgs, err := dag.NewPackages(os.DirFS(dir), dir)
+	require.NoError(t, err)
+	task := Task{Config: pkgs.Config("patched", true)[0], Arch: "x86_64"}
+
+	_, err = CacheKey(task, nil)
+	assert.ErrorContains(t, err, "failed to read patches of patched-1.0.0-r0")
+
+	patch("fix-build.patch", "--- a\n+++ b\n")
+	patch("cve.patch", "--- c\n+++ d\n")
+	key, err := CacheKey(task, nil)
+	require.NoError(t, err)
+
+	patch("cve.patch", "--- c\n+++ e\n")
+	changed, err := CacheKey(task, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, changed, "patches should change the key")
+}
+
+func TestDirCache(t *testing.T) {
+	ctx := context.Background()
+	task := testTask(t, "one")
+	repo := t.TempDir()
+	c := DirCache{Dir: t.TempDir()}
+
+	hit, err := c.Fetch(ctx, "key", repo, task)
+	require.NoError(t, err)
+	assert.False(t, hit)
+
+	for _, a := range task.Artifacts() {
+		p := repoPath(repo, task, a)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(a), 0o600))
+	}
+	require.NoError(t, c.Store(ctx, "key", repo, task))
+
+	fresh := t.TempDir()
+	hit, err = c.Fetch(ctx, "key", fresh, task)
+	require.NoError(t, err)
+	assert.True(t, hit)
+	for _, a := range task.Artifacts() {
+		b, err := os.ReadFile(repoPath(fresh, task, a))
+		require.NoError(t, err)
+		assert.Equal(t, a, string(b))
+	}
+}
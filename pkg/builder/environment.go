@@ -0,0 +1,169 @@
+package builder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	apko_build "chainguard.dev/apko/pkg/build"
+	"chainguard.dev/apko/pkg/build/types"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+// environmentSuffix is the extension of recorded build environments, which sit next to the apks they were built
+// into.
+const environmentSuffix = ".env.json"
+
+// Environment is the build environment a package was built in, as resolved by the Graph: the exact version and
+// repository of every package installed into its build sandbox.
+type Environment struct {
+	Package  string               `json:"package"`
+	Version  string               `json:"version"`
+	Arch     string               `json:"arch"`
+	Packages []EnvironmentPackage `json:"packages"`
+}
+
+// EnvironmentPackage is a single package of an Environment.
+type EnvironmentPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Source  string `json:"source"`
+}
+
+// NewEnvironment returns the Environment of t, from the packages its build environment resolved to.
+func NewEnvironment(t Task, env []dag.Package) *Environment {
+	e := &Environment{
+		Package:  t.Name(),
+		Version:  t.Config.Version(),
+		Arch:     t.Arch,
+		Packages: make([]EnvironmentPackage, 0, len(env)),
+	}
+	for _, p := range env {
+		e.Packages = append(e.Packages, EnvironmentPackage{Name: p.Name(), Version: p.Version(), Source: p.Source()})
+	}
+	return e
+}
+
+// Dependencies returns the packages of the environment, so it can be compared with dag.DiffBuildEnvironments.
+func (e *Environment) Dependencies() []dag.Package {
+	deps := make([]dag.Package, 0, len(e.Packages))
+	for _, p := range e.Packages {
+		deps = append(deps, recordedPackage{p: p})
+	}
+	return deps
+}
+
+// recordedPackage is a dag.Package read back from a recorded Environment.
+type recordedPackage struct {
+	p EnvironmentPackage
+}
+
+func (r recordedPackage) Name() string    { return r.p.Name }
+func (r recordedPackage) Version() string { return r.p.Version }
+func (r recordedPackage) Source() string  { return r.p.Source }
+func (r recordedPackage) String() string  { return r.p.Name + "-" + r.p.Version }
+func (r recordedPackage) Resolved() bool  { return true }
+
+// EnvironmentFile returns the repository relative path the build environment of the task is recorded at.
+func (t Task) EnvironmentFile() string {
+	return path.Join("packages", t.Arch, fmt.Sprintf("%s-%s%s", t.Name(), t.Config.Version(), environmentSuffix))
+}
+
+// WriteEnvironment records e as the build environment of t in repo.
+func WriteEnvironment(repo string, t Task, e *Environment) error {
+	b, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	dst := repoPath(repo, t, t.EnvironmentFile())
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, append(b, '\n'), 0o600)
+}
+
+// LoadEnvironment reads a recorded build environment.
+func LoadEnvironment(path string) (*Environment, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	e := &Environment{}
+	if err := json.Unmarshal(b, e); err != nil {
+		return nil, fmt.Errorf("failed to parse build environment %s: %w", path, err)
+	}
+	return e, nil
+}
+
+// FindEnvironment returns the recorded build environment of the named package for arch in repo. Unless a version
+// is given, with or without the epoch, the latest version recorded is returned.
+func FindEnvironment(repo, arch, name, version string) (*Environment, error) {
+	entries, err := os.ReadDir(filepath.Join(repo, arch))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("no build environments recorded for %s in %s", arch, repo)
+		}
+		return nil, err
+	}
+
+	var found *Environment
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), name+"-") || !strings.HasSuffix(entry.Name(), environmentSuffix) {
+			continue
+		}
+		// the prefix also matches packages whose name starts with the one we want, e.g. curl-dev for curl
+		e, err := LoadEnvironment(filepath.Join(repo, arch, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if e.Package != name || (version != "" && e.Version != version && !strings.HasPrefix(e.Version, version+"-r")) {
+			continue
+		}
+		if found == nil || dag.CompareVersions(e.Version, found.Version) > 0 {
+			found = e
+		}
+	}
+	if found == nil {
+		if version != "" {
+			return nil, fmt.Errorf("no build environment recorded for %s-%s in %s", name, version, filepath.Join(repo, arch))
+		}
+		return nil, fmt.Errorf("no build environment recorded for %s in %s", name, filepath.Join(repo, arch))
+	}
+	return found, nil
+}
+
+// AssembleGuest installs the build environment of t into dir with apko, the way melange assembles its guest, with
+// the packages of the local repository repo available, signed with signingKey unless that's empty.
+func AssembleGuest(t Task, repo, signingKey, dir string) error {
+	repo, err := filepath.Abs(repo)
+	if err != nil {
+		return err
+	}
+	opts := []apko_build.Option{
+		apko_build.WithImageConfiguration(t.Config.Environment),
+		apko_build.WithArch(types.ParseArchitecture(t.Arch)),
+		apko_build.WithExtraRepos([]string{repo}),
+		apko_build.WithLocal(true),
+	}
+	if signingKey != "" {
+		// the local repository is signed with the key the index is regenerated with
+		opts = append(opts, apko_build.WithExtraKeys([]string{signingKey + ".pub"}))
+	}
+	bc, err := apko_build.New(dir, opts...)
+	if err != nil {
+		return err
+	}
+	if err := bc.Refresh(); err != nil {
+		return err
+	}
+	if _, err := bc.BuildImage(); err != nil {
+		return err
+	}
+	return nil
+}
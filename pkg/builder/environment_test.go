@@ -0,0 +1,63 @@
+package builder
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+func TestScheduler_RunRecordsEnvironments(t *testing.T) {
+	repo := t.TempDir()
+	s := NewScheduler(&fakeExecutor{}, repo, "x86_64")
+	s.Logger = log.New(io.Discard, "", 0)
+	s.Output = io.Discard
+	s.RecordEnvironments = true
+
+	g := testGraph(t)
+	require.NoError(t, s.Run(context.Background(), g))
+	assert.Equal(t, "packages/x86_64/two-4.5.6-r1.env.json", s.Summary.Results[2].Environment)
+
+	env, err := FindEnvironment(repo, "x86_64", "two", "")
+	require.NoError(t, err)
+	assert.Equal(t, "4.5.6-r1", env.Version)
+	resolved, err := g.BuildEnvironment("two", "")
+	require.NoError(t, err)
+	assert.Empty(t, dag.DiffBuildEnvironments(resolved, env.Dependencies()))
+
+	// the latest version is found unless one is asked for
+	env, err = FindEnvironment(repo, "x86_64", "one", "")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.8-r1", env.Version)
+	env, err = FindEnvironment(repo, "x86_64", "one", "1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3-r1", env.Version)
+
+	// three-other's environment starts with three-, but isn't the one of a package named three
+	_, err = FindEnvironment(repo, "x86_64", "three", "")
+	assert.ErrorContains(t, err, "no build environment recorded for three")
+}
+
+func TestEnvironmentDiff(t *testing.T) {
+	previous := &Environment{Packages: []EnvironmentPackage{
+		{Name: "busybox", Version: "1.36.0-r0", Source: "https://packages.wolfi.dev/os"},
+		{Name: "openssl", Version: "3.1.0-r0", Source: "https://packages.wolfi.dev/os"},
+	}}
+	current := &Environment{Packages: []EnvironmentPackage{
+		{Name: "busybox", Version: "1.36.0-r0", Source: "https://packages.wolfi.dev/os"},
+		{Name: "openssl", Version: "3.1.1-r0", Source: "local"},
+	}}
+
+	assert.Equal(t, []dag.EnvironmentChange{{
+		Name:       "openssl",
+		OldVersion: "3.1.0-r0",
+		NewVersion: "3.1.1-r0",
+		OldSource:  "https://packages.wolfi.dev/os",
+		NewSource:  "local",
+	}}, dag.DiffBuildEnvironments(previous.Dependencies(), current.Dependencies()))
+}
@@ -0,0 +1,66 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+// Task is a single origin package to build.
+type Task struct {
+	Config *dag.Configuration
+	Arch   string
+
+	// Repositories and Keyring are added to the ones of the config to resolve the build environment with, e.g. a
+	// published repository that has the dependencies that aren't built.
+	Repositories []string
+	Keyring      []string
+
+	// Output receives the build output of the task.
+	Output io.Writer
+}
+
+// Name returns the name of the origin package built by the task.
+func (t Task) Name() string {
+	return t.Config.Package.Name
+}
+
+// Target returns the repository relative path of the package's apk, which is also the make target that builds it.
+func (t Task) Target() string {
+	return fmt.Sprintf("packages/%s/%s-%s-r%d.apk", t.Arch, t.Config.Package.Name, t.Config.Package.Version, t.Config.Package.Epoch)
+}
+
+// MelangeOpts returns the options of melange build the task needs on top of the ones of the Makefile, passed to make
+// as MELANGE_EXTRA_OPTS.
+func (t Task) MelangeOpts() string {
+	var opts []string
+	for _, r := range t.Repositories {
+		opts = append(opts, "--repository-append", r)
+	}
+	for _, k := range t.Keyring {
+		opts = append(opts, "--keyring-append", k)
+	}
+	return strings.Join(opts, " ")
+}
+
+func (t Task) String() string {
+	return t.Config.String()
+}
+
+// Executor runs builds somewhere, e.g. on this machine, on a Kubernetes cluster, or on remote hosts.
+//
+// The Scheduler calls Sync before each wave so the executor can see everything built so far, Build for every
+// package of the wave, possibly concurrently, and Collect once the wave is done.
+type Executor interface {
+	// Sync makes the shared local repository available to the builds of the next wave.
+	Sync(ctx context.Context, repo string) error
+
+	// Build builds a single package.
+	Build(ctx context.Context, t Task) error
+
+	// Collect copies the artifacts built since the last Sync back into the shared local repository.
+	Collect(ctx context.Context, repo string) error
+}
@@ -0,0 +1,253 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chainguard-dev/kontext"
+	"github.com/google/go-containerregistry/pkg/name"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp" // Implicit GCP auth.
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/utils/pointer"
+)
+
+// Kubernetes builds each package in its own Job. The directory of melange configs is bundled into an image
+// with kontext once, and the shared repository is exchanged with the Jobs through a GCS bucket, so gsutil needs
+// to be available locally.
+type Kubernetes struct {
+	Dir         string
+	Namespace   string
+	BundleRepo  string
+	Bucket      string // e.g. gs://my-bucket/builds/
+	SDKImage    string
+	GCloudImage string
+	CPU, RAM    string
+
+	clientset  *kubernetes.Clientset
+	bundleOnce sync.Once
+	bundle     string
+	bundleErr  error
+}
+
+// NewKubernetes returns a Kubernetes executor using the current kubeconfig context.
+func NewKubernetes(k *Kubernetes) (*Kubernetes, error) {
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		&clientcmd.ConfigOverrides{},
+	).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	k.clientset = clientset
+	k.Bucket = strings.TrimSuffix(k.Bucket, "/") + "/"
+	return k, nil
+}
+
+func (k *Kubernetes) Sync(ctx context.Context, repo string) error {
+	k.bundleOnce.Do(func() {
+		t, err := name.NewTag(k.BundleRepo, name.WeakValidation)
+		if err != nil {
+			k.bundleErr = err
+			return
+		}
+		dig, err := kontext.Bundle(ctx, k.Dir, t)
+		if err != nil {
+			k.bundleErr = fmt.Errorf("bundling %s: %w", k.Dir, err)
+			return
+		}
+		k.bundle = dig.String()
+	})
+	if k.bundleErr != nil {
+		return k.bundleErr
+	}
+	return gsutilRsync(ctx, repo, k.Bucket+"packages")
+}
+
+func (k *Kubernetes) Build(ctx context.Context, t Task) error {
+	job, err := k.clientset.BatchV1().Jobs(k.Namespace).Create(ctx, k.job(t), metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("creating job for %s: %w", t, err)
+	}
+	fmt.Fprintf(t.Output, "created job %s for %s\n", job.Name, t)
+
+	defer func() {
+		propagation := metav1.DeletePropagationBackground
+		if err := k.clientset.BatchV1().Jobs(k.Namespace).Delete(context.Background(), job.Name, metav1.DeleteOptions{
+			PropagationPolicy: &propagation,
+		}); err != nil {
+			fmt.Fprintf(t.Output, "failed to delete job %s: %v\n", job.Name, err)
+		}
+	}()
+
+	var failed bool
+	if err := wait.PollImmediateUntilWithContext(ctx, 10*time.Second, func(ctx context.Context) (bool, error) {
+		job, err = k.clientset.BatchV1().Jobs(k.Namespace).Get(ctx, job.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, c := range job.Status.Conditions {
+			if c.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch c.Type {
+			case batchv1.JobComplete:
+				return true, nil
+			case batchv1.JobFailed:
+				failed = true
+				return true, nil
+			}
+		}
+		return false, nil
+	}); err != nil {
+		return fmt.Errorf("waiting for job %s: %w", job.Name, err)
+	}
+
+	if err := k.logs(ctx, job.Name, t.Output); err != nil {
+		fmt.Fprintf(t.Output, "failed to get logs for job %s: %v\n", job.Name, err)
+	}
+	if failed {
+		return fmt.Errorf("job %s for %s failed", job.Name, t)
+	}
+	return nil
+}
+
+func (k *Kubernetes) Collect(ctx context.Context, repo string) error {
+	return gsutilRsync(ctx, k.Bucket+"packages", repo)
+}
+
+func (k *Kubernetes) logs(ctx context.Context, jobName string, w io.Writer) error {
+	pods, err := k.clientset.CoreV1().Pods(k.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "job-name=" + jobName,
+	})
+	if err != nil {
+		return err
+	}
+	for i := range pods.Items {
+		rc, err := k.clientset.CoreV1().Pods(k.Namespace).GetLogs(pods.Items[i].Name, &corev1.PodLogOptions{
+			Container: "build",
+		}).Stream(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (k *Kubernetes) job(t Task) *batchv1.Job {
+	workspace := []corev1.VolumeMount{{
+		Name:      "workspace",
+		MountPath: "/workspace",
+	}}
+	// Minimums required by Autopilot.
+	minimum := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:              resource.MustParse("1"),
+			corev1.ResourceMemory:           resource.MustParse("2Gi"),
+			corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+		},
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "build-" + t.Name() + "-",
+			Namespace:    k.Namespace,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: pointer.Int32(0),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					InitContainers: []corev1.Container{{
+						Name:         "init",
+						Image:        k.bundle,
+						WorkingDir:   "/workspace",
+						VolumeMounts: workspace,
+						Resources:    minimum,
+					}, {
+						Name:         "fetch-packages",
+						Image:        k.GCloudImage,
+						WorkingDir:   "/workspace",
+						VolumeMounts: workspace,
+						Command: []string{"bash", "-c", fmt.Sprintf(`
+set -euo pipefail
+mkdir -p ./packages
+gsutil -m rsync -r %spackages ./packages || true
+`, k.Bucket)},
+						Resources: minimum,
+					}, {
+						Name:         "build",
+						Image:        k.SDKImage,
+						WorkingDir:   "/workspace",
+						VolumeMounts: workspace,
+						SecurityContext: &corev1.SecurityContext{
+							Privileged: pointer.Bool(true),
+						},
+						Command: []string{"sh", "-c", fmt.Sprintf(`
+set -eu
+git config --global --add safe.directory /workspace
+MELANGE=/usr/bin/melange KEY=melange.rsa make melange.rsa
+MELANGE=/usr/bin/melange MELANGE_DIR=/usr/share/melange KEY=melange.rsa ARCH=%s REPO=./packages MELANGE_EXTRA_OPTS=%s make %s
+`, t.Arch, shellQuote(t.MelangeOpts()), t.Target())},
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								corev1.ResourceCPU:              resource.MustParse(k.CPU),
+								corev1.ResourceMemory:           resource.MustParse(k.RAM),
+								corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+							},
+						},
+					}},
+					Containers: []corev1.Container{{
+						Name:         "upload",
+						Image:        k.GCloudImage,
+						WorkingDir:   "/workspace",
+						VolumeMounts: workspace,
+						// upload subpackages too, without clobbering what was fetched
+						Command:   []string{"sh", "-c", fmt.Sprintf("gsutil -m cp -n ./packages/%s/*.apk %spackages/%s/", t.Arch, k.Bucket, t.Arch)},
+						Resources: minimum,
+					}},
+					Volumes: []corev1.Volume{{
+						Name: "workspace",
+						VolumeSource: corev1.VolumeSource{
+							EmptyDir: &corev1.EmptyDirVolumeSource{},
+						},
+					}},
+				},
+			},
+		},
+	}
+
+	if t.Arch == "aarch64" {
+		job.Spec.Template.Spec.NodeSelector = map[string]string{
+			"kubernetes.io/arch": "arm64",
+		}
+	}
+	return job
+}
+
+func gsutilRsync(ctx context.Context, src, dst string) error {
+	b, err := exec.CommandContext(ctx, "gsutil", "-m", "rsync", "-r", src, dst).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("gsutil rsync %s to %s: %w: %s", src, dst, err, b)
+	}
+	return nil
+}
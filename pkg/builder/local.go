@@ -0,0 +1,41 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Local builds packages on this machine by running make in the directory of melange configs. Builds write their
+// apks straight into the repository, so there is nothing to sync or collect.
+type Local struct {
+	Dir string
+
+	// Wrapper, if set, is a command make runs under, e.g. strace to trace the builds.
+	Wrapper []string
+}
+
+func (l Local) Sync(context.Context, string) error {
+	return nil
+}
+
+func (l Local) Build(ctx context.Context, t Task) error {
+	args := append(append([]string{}, l.Wrapper...), "make", t.Target())
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...) //nolint:gosec // the wrapper is given by the caller
+	cmd.Dir = l.Dir
+	cmd.Env = append(os.Environ(), "ARCH="+t.Arch)
+	if opts := t.MelangeOpts(); opts != "" {
+		cmd.Env = append(cmd.Env, "MELANGE_EXTRA_OPTS="+opts)
+	}
+	cmd.Stdout = t.Output
+	cmd.Stderr = t.Output
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("make %s: %w", t.Target(), err)
+	}
+	return nil
+}
+
+func (l Local) Collect(context.Context, string) error {
+	return nil
+}
@@ -0,0 +1,88 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// syncWriter serializes writes from concurrent builds to a single writer.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// prefixWriter tags every line written to it with a prefix, so the output of concurrent builds can be told apart.
+// Only whole lines are written through, the rest is buffered until the next newline or Close.
+type prefixWriter struct {
+	prefix []byte
+	w      io.Writer
+	buf    bytes.Buffer
+}
+
+func newPrefixWriter(w io.Writer, prefix string) *prefixWriter {
+	return &prefixWriter{
+		prefix: []byte(prefix),
+		w:      w,
+	}
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf.Write(b)
+	for {
+		i := bytes.IndexByte(p.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		if err := p.writeLine(p.buf.Next(i + 1)); err != nil {
+			return len(b), err
+		}
+	}
+}
+
+// Close writes out a trailing partial line.
+func (p *prefixWriter) Close() error {
+	if p.buf.Len() == 0 {
+		return nil
+	}
+	return p.writeLine(append(p.buf.Next(p.buf.Len()), '\n'))
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	_, err := p.w.Write(append(append([]byte{}, p.prefix...), line...))
+	return err
+}
+
+// taskOutput returns where the output of t goes: the shared output with a prefix, and the task's log file in
+// logDir, if set. The returned func flushes and closes everything, and returns the path of the log file.
+func taskOutput(out io.Writer, logDir string, t Task) (io.Writer, func() (string, error), error) {
+	terminal := newPrefixWriter(out, fmt.Sprintf("[%s] ", t))
+	if logDir == "" {
+		return terminal, func() (string, error) { return "", terminal.Close() }, nil
+	}
+
+	path := filepath.Join(logDir, t.Arch, t.String()+".log")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return io.MultiWriter(terminal, f), func() (string, error) {
+		terr := terminal.Close()
+		if err := f.Close(); err != nil {
+			return path, err
+		}
+		return path, terr
+	}, nil
+}
@@ -0,0 +1,82 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+
+	"chainguard.dev/apko/pkg/build/types"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+// NativeArch returns the apk architecture of this machine, e.g. x86_64.
+func NativeArch() string {
+	return types.ParseArchitecture(runtime.GOARCH).ToAPK()
+}
+
+// Emulated reports whether builds for arch run under QEMU emulation on this machine.
+func Emulated(arch string) bool {
+	return types.ParseArchitecture(arch).ToAPK() != NativeArch()
+}
+
+// MultiArch builds the packages of a Graph for several architectures at once, with a Scheduler per architecture.
+// Each Scheduler has its own Executor and concurrency limit, so slow emulated builds of one architecture don't take
+// the place of native builds of another.
+type MultiArch struct {
+	Schedulers []*Scheduler
+
+	// Summary merges the outcome of every architecture of the last Run.
+	Summary *Summary
+}
+
+// Run runs the Scheduler of every architecture concurrently. A failure of one architecture doesn't stop the others.
+func (m *MultiArch) Run(ctx context.Context, g *dag.Graph) error {
+	if len(m.Schedulers) == 1 {
+		err := m.Schedulers[0].Run(ctx, g)
+		m.Summary = m.Schedulers[0].Summary
+		return err
+	}
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	var eg errgroup.Group
+	for _, s := range m.Schedulers {
+		s := s
+		eg.Go(func() error {
+			if err := s.Run(ctx, g); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", s.Arch, err))
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	_ = eg.Wait()
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	err := errors.Join(errs...)
+	m.Summary = m.merge(err)
+	return err
+}
+
+func (m *MultiArch) merge(err error) *Summary {
+	merged := &Summary{}
+	for _, s := range m.Schedulers {
+		if s.Summary == nil {
+			continue
+		}
+		if merged.StartTime.IsZero() || s.Summary.StartTime.Before(merged.StartTime) {
+			merged.StartTime = s.Summary.StartTime
+		}
+		merged.Results = append(merged.Results, s.Summary.Results...)
+	}
+	merged.finish(err)
+	return merged
+}
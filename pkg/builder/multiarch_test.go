@@ -0,0 +1,47 @@
+package builder
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiArch_Run(t *testing.T) {
+	native := &fakeExecutor{}
+	emulated := &fakeExecutor{fail: "two"}
+	m := &MultiArch{}
+	for _, s := range []*Scheduler{
+		NewScheduler(native, "packages", "x86_64"),
+		NewScheduler(emulated, "packages", "aarch64"),
+	} {
+		s.Logger = log.New(io.Discard, "", 0)
+		s.Output = io.Discard
+		m.Schedulers = append(m.Schedulers, s)
+	}
+
+	err := m.Run(context.Background(), testGraph(t))
+	assert.ErrorContains(t, err, "aarch64: failed to build two-4.5.6-r1")
+	assert.NotContains(t, err.Error(), "x86_64")
+
+	// the failure on aarch64 doesn't hold back x86_64
+	assert.Contains(t, native.events, "packages/x86_64/three-other-7.8.9-r1.apk")
+	assert.NotContains(t, emulated.events, "packages/aarch64/three-other-7.8.9-r1.apk")
+
+	assert.Len(t, m.Summary.Results, 8)
+	assert.Equal(t, 6, m.Summary.Count(StatusBuilt))
+	assert.Equal(t, 1, m.Summary.Count(StatusFailed))
+	assert.Equal(t, 1, m.Summary.Count(StatusSkipped))
+}
+
+func TestEmulated(t *testing.T) {
+	assert.False(t, Emulated(NativeArch()))
+
+	other := "aarch64"
+	if NativeArch() == other {
+		other = "x86_64"
+	}
+	assert.True(t, Emulated(other))
+}
@@ -0,0 +1,99 @@
+package builder
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+// Plan is what a Scheduler would build, wave by wave, along with how long it's expected to take.
+type Plan struct {
+	Waves []PlannedWave
+
+	// CriticalPath is the estimated time of the whole build with unlimited parallelism: every wave takes as long
+	// as its slowest package.
+	CriticalPath time.Duration
+
+	// Parallelism is the number of concurrent builds needed to reach the CriticalPath time, the size of the
+	// largest wave.
+	Parallelism int
+
+	// Unknown is the number of packages without any recorded timings, which are estimated as taking no time.
+	Unknown int
+}
+
+// PlannedWave is a single wave of a Plan.
+type PlannedWave struct {
+	Builds   []PlannedBuild
+	Duration time.Duration
+}
+
+// PlannedBuild is a single package of a Plan.
+type PlannedBuild struct {
+	Task     Task
+	Estimate time.Duration
+	Known    bool
+}
+
+// NewPlan returns the Plan of building g for arch, with estimates from timings, which may be nil.
+func NewPlan(g *dag.Graph, arch string, timings *Timings) (*Plan, error) {
+	waves, err := g.Waves()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute build waves: %w", err)
+	}
+
+	p := &Plan{}
+	for _, wave := range waves {
+		var pw PlannedWave
+		for _, c := range wave {
+			b := PlannedBuild{
+				Task: Task{Config: c, Arch: arch},
+			}
+			if timings != nil {
+				b.Estimate, b.Known = timings.Estimate(b.Task)
+			}
+			if !b.Known {
+				p.Unknown++
+			}
+			if b.Estimate > pw.Duration {
+				pw.Duration = b.Estimate
+			}
+			pw.Builds = append(pw.Builds, b)
+		}
+
+		p.Waves = append(p.Waves, pw)
+		p.CriticalPath += pw.Duration
+		if len(pw.Builds) > p.Parallelism {
+			p.Parallelism = len(pw.Builds)
+		}
+	}
+	return p, nil
+}
+
+// Write prints the plan in a human readable table.
+func (p *Plan) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, wave := range p.Waves {
+		fmt.Fprintf(tw, "wave %d/%d\t\t%s\n", i+1, len(p.Waves), wave.Duration)
+		for _, b := range wave.Builds {
+			estimate := "unknown"
+			if b.Known {
+				estimate = b.Estimate.String()
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", b.Task, b.Task.Target(), estimate)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\ncritical path: %s\n", p.CriticalPath)
+	fmt.Fprintf(w, "parallelism needed: %d\n", p.Parallelism)
+	if p.Unknown > 0 {
+		fmt.Fprintf(w, "%d packages have no recorded timings and are not included in the estimates\n", p.Unknown)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package builder
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timings.json")
+	timings, err := LoadTimings(path)
+	require.NoError(t, err)
+
+	task := testTask(t, "two")
+	_, ok := timings.Estimate(task)
+	assert.False(t, ok)
+
+	for i := 1; i <= timingsHistory+1; i++ {
+		timings.Record(task, time.Duration(i)*time.Minute)
+	}
+	require.NoError(t, timings.Save())
+
+	timings, err = LoadTimings(path)
+	require.NoError(t, err)
+	estimate, ok := timings.Estimate(task)
+	require.True(t, ok)
+	// the first run was dropped, leaving 2..6 minutes
+	assert.Equal(t, 4*time.Minute, estimate)
+
+	other := task
+	other.Arch = "aarch64"
+	_, ok = timings.Estimate(other)
+	assert.False(t, ok)
+}
+
+func TestNewPlan(t *testing.T) {
+	timings, err := LoadTimings(filepath.Join(t.TempDir(), "timings.json"))
+	require.NoError(t, err)
+	timings.Record(testTask(t, "one"), 2*time.Minute)
+	timings.Record(testTask(t, "two"), 3*time.Minute)
+	timings.Record(testTask(t, "three-other"), 1*time.Minute)
+
+	plan, err := NewPlan(testGraph(t), "x86_64", timings)
+	require.NoError(t, err)
+
+	require.Len(t, plan.Waves, 3)
+	assert.Len(t, plan.Waves[0].Builds, 2)
+	assert.Equal(t, 2*time.Minute, plan.Waves[0].Duration)
+	assert.Equal(t, 6*time.Minute, plan.CriticalPath)
+	assert.Equal(t, 2, plan.Parallelism)
+	assert.Equal(t, 0, plan.Unknown)
+
+	var buf bytes.Buffer
+	require.NoError(t, plan.Write(&buf))
+	assert.Regexp(t, `two-4\.5\.6-r1\.apk\s+3m0s`, buf.String())
+	assert.Contains(t, buf.String(), "critical path: 6m0s")
+}
@@ -0,0 +1,409 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/index"
+)
+
+// Scheduler builds the packages of a Graph wave by wave with an Executor. The packages of a wave don't depend on
+// each other and are built concurrently, and every wave's artifacts are collected into the shared local
+// repository before the next wave starts.
+type Scheduler struct {
+	Executor Executor
+
+	// Repo is the shared local repository, e.g. ./packages.
+	Repo string
+	Arch string
+
+	// Repositories and Keyring are added to the ones of every config to resolve build environments with.
+	Repositories []string
+	Keyring      []string
+
+	// Jobs is the maximum number of builds to run at once, 0 means no limit.
+	Jobs int
+
+	// KeepGoing keeps building the packages that don't depend on a failed build, instead of stopping at the first
+	// failure. The packages that do are skipped.
+	KeepGoing bool
+	// MaxFailures, if positive, stops the run once that many builds failed, even when keeping going.
+	MaxFailures int
+
+	// Preflight, if set, is called before each package is built or fetched from the cache. A package it returns an
+	// error for fails without being built.
+	Preflight func(t Task) error
+
+	// Cache, if set, is consulted before each build, and receives the artifacts of every build that missed.
+	Cache Cache
+
+	// Timings, if set, receives the duration of every successful build, and is saved after each wave.
+	Timings *Timings
+
+	// Reindex regenerates the APKINDEX of the repository after every wave, so the next wave resolves the packages
+	// that were just built instead of stale versions. It's signed with SigningKey, unless that's empty.
+	Reindex    bool
+	SigningKey string
+
+	// RecordEnvironments records the resolved build environment of every package that is built or fetched from the
+	// cache next to its apk, see Environment.
+	RecordEnvironments bool
+
+	// LogDir, if set, receives a log file of every build, in a subdirectory per architecture.
+	LogDir string
+
+	// Summary is the outcome of the last Run.
+	Summary *Summary
+
+	Logger *log.Logger
+	// Output receives the output of all builds, every line prefixed with the package it's from.
+	Output io.Writer
+}
+
+// NewScheduler returns a Scheduler that builds with e into repo.
+func NewScheduler(e Executor, repo, arch string) *Scheduler {
+	return &Scheduler{
+		Executor: e,
+		Repo:     repo,
+		Arch:     arch,
+		Logger:   log.New(log.Writer(), "wolfictl build: ", log.LstdFlags|log.Lmsgprefix),
+		Output:   os.Stdout,
+	}
+}
+
+// Run builds every local package in g. The outcome of every package is recorded in the Summary, even when Run
+// fails, and the failed builds are logged along with the packages skipped because of them.
+func (s *Scheduler) Run(ctx context.Context, g *dag.Graph) error {
+	s.Summary = newSummary()
+	err := s.run(ctx, g)
+	s.Summary.finish(err)
+	if s.Summary.Count(StatusFailed) > 0 {
+		var report strings.Builder
+		if rerr := s.Summary.WriteFailureReport(&report); rerr == nil {
+			s.Logger.Printf("failed builds:\n%s", report.String())
+		}
+	}
+	return err
+}
+
+func (s *Scheduler) run(ctx context.Context, g *dag.Graph) error {
+	waves, err := g.Waves()
+	if err != nil {
+		return fmt.Errorf("failed to compute build waves: %w", err)
+	}
+	deps, err := g.BuildDependencies()
+	if err != nil {
+		return fmt.Errorf("failed to compute build dependencies: %w", err)
+	}
+
+	out := &syncWriter{w: s.Output}
+	f := &failures{blocked: make(map[string][]string)}
+	for i, wave := range waves {
+		s.Logger.Printf("wave %d/%d: building %d packages", i+1, len(waves), len(wave))
+
+		if err := s.Executor.Sync(ctx, s.Repo); err != nil {
+			s.skip(waves, i)
+			return fmt.Errorf("failed to sync repository before wave %d: %w", i+1, err)
+		}
+
+		var (
+			mu     sync.Mutex
+			misses = make(map[string]Task)
+		)
+		eg, wctx := errgroup.WithContext(ctx)
+		if s.Jobs > 0 {
+			eg.SetLimit(s.Jobs)
+		}
+		for _, c := range wave {
+			t := Task{
+				Config:       c,
+				Arch:         s.Arch,
+				Repositories: s.Repositories,
+				Keyring:      s.Keyring,
+			}
+			n := i + 1
+			if blockers := f.blockers(deps[t.String()]); len(blockers) > 0 {
+				f.block(t.String(), blockers)
+				s.Logger.Printf("skipping %s, it depends on failed builds of %s", t, strings.Join(blockers, ", "))
+				s.Summary.record(BuildResult{
+					Package:   t.Name(),
+					Version:   t.Config.Version(),
+					Arch:      t.Arch,
+					Wave:      n,
+					Status:    StatusSkipped,
+					BlockedBy: blockers,
+				})
+				continue
+			}
+			eg.Go(func() error {
+				key, err := s.runTask(wctx, g, out, n, t)
+				var canceled canceledError
+				if errors.As(err, &canceled) {
+					return err
+				}
+				if err != nil {
+					if !s.keepGoing() || f.fail(t.String()) == s.MaxFailures {
+						return err
+					}
+					s.Logger.Printf("%v, building packages that don't depend on it", err)
+					return nil
+				}
+				if key != "" {
+					mu.Lock()
+					misses[key] = t
+					mu.Unlock()
+				}
+				return nil
+			})
+		}
+		err := eg.Wait()
+		if s.Timings != nil {
+			// keep the timings of the builds that succeeded, even if the wave failed
+			if serr := s.Timings.Save(); serr != nil {
+				s.Logger.Printf("failed to save build timings: %v", serr)
+			}
+		}
+		if err != nil {
+			s.skip(waves, i+1)
+			if s.keepGoing() && ctx.Err() == nil {
+				return fmt.Errorf("stopping after %d failed builds: %w", s.MaxFailures, f)
+			}
+			return err
+		}
+
+		if err := s.Executor.Collect(ctx, s.Repo); err != nil {
+			s.skip(waves, i+1)
+			return fmt.Errorf("failed to collect artifacts of wave %d: %w", i+1, err)
+		}
+
+		// artifacts are only guaranteed to be in the repository once they have been collected
+		for key, t := range misses {
+			if err := s.Cache.Store(ctx, key, s.Repo, t); err != nil {
+				s.skip(waves, i+1)
+				return fmt.Errorf("failed to store %s in cache: %w", t, err)
+			}
+		}
+
+		if s.Reindex {
+			if err := index.Generate(ctx, filepath.Join(s.Repo, s.Arch), s.SigningKey); err != nil {
+				s.skip(waves, i+1)
+				return fmt.Errorf("failed to regenerate index after wave %d: %w", i+1, err)
+			}
+		}
+	}
+
+	if len(f.failed) > 0 {
+		return f
+	}
+	return nil
+}
+
+func (s *Scheduler) keepGoing() bool {
+	return s.KeepGoing || s.MaxFailures > 0
+}
+
+// failures tracks the failed builds of a run, and the builds skipped because they depend on them.
+type failures struct {
+	mu     sync.Mutex
+	failed []string
+
+	// blocked maps skipped packages to the failed builds they depend on, transitively
+	blocked map[string][]string
+}
+
+// fail records a failed build, and returns the number of failures so far.
+func (f *failures) fail(pkg string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failed = append(f.failed, pkg)
+	return len(f.failed)
+}
+
+func (f *failures) block(pkg string, blockers []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.blocked[pkg] = blockers
+}
+
+// blockers returns the failed builds that any of deps is, or depends on.
+func (f *failures) blockers(deps []string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	seen := make(map[string]bool)
+	var blockers []string
+	add := func(pkg string) {
+		if !seen[pkg] {
+			seen[pkg] = true
+			blockers = append(blockers, pkg)
+		}
+	}
+	for _, dep := range deps {
+		for _, failed := range f.failed {
+			if dep == failed {
+				add(dep)
+			}
+		}
+		for _, b := range f.blocked[dep] {
+			add(b)
+		}
+	}
+	sort.Strings(blockers)
+	return blockers
+}
+
+// canceledError is the error of a build that was canceled, which doesn't count as a failure.
+type canceledError struct {
+	error
+}
+
+func (e canceledError) Unwrap() error {
+	return e.error
+}
+
+func (f *failures) Error() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	failed := append([]string(nil), f.failed...)
+	sort.Strings(failed)
+	return fmt.Sprintf("%d packages failed to build: %s", len(failed), strings.Join(failed, ", "))
+}
+
+// runTask builds a single package of wave n, unless it's cached, and records the result. It returns the cache key
+// of a package that was built, so its artifacts can be stored once collected.
+func (s *Scheduler) runTask(ctx context.Context, g *dag.Graph, out io.Writer, n int, t Task) (string, error) {
+	r := BuildResult{
+		Package: t.Name(),
+		Version: t.Config.Version(),
+		Arch:    t.Arch,
+		Wave:    n,
+	}
+	fail := func(err error) (string, error) {
+		r.Error = err.Error()
+		if ctx.Err() != nil {
+			// the build didn't fail by itself, it was stopped
+			r.Status = StatusCanceled
+			s.Summary.record(r)
+			return "", canceledError{err}
+		}
+		r.Status = StatusFailed
+		r.ExitCode = exitCode(err)
+		s.Summary.record(r)
+		return "", err
+	}
+
+	if ctx.Err() != nil {
+		// another build of the wave failed before this one started
+		r.Status = StatusSkipped
+		s.Summary.record(r)
+		return "", nil
+	}
+
+	if s.Preflight != nil {
+		if err := s.Preflight(t); err != nil {
+			return fail(fmt.Errorf("%s failed preflight checks: %w", t, err))
+		}
+	}
+
+	var env []dag.Package
+	if s.Cache != nil || s.RecordEnvironments {
+		var err error
+		if env, err = g.BuildEnvironment(t.Name(), t.Config.Version()); err != nil {
+			return fail(fmt.Errorf("failed to resolve build environment of %s: %w", t, err))
+		}
+	}
+	record := func() {
+		if !s.RecordEnvironments {
+			return
+		}
+		if err := WriteEnvironment(s.Repo, t, NewEnvironment(t, env)); err != nil {
+			s.Logger.Printf("failed to record build environment of %s: %v", t, err)
+			return
+		}
+		r.Environment = t.EnvironmentFile()
+	}
+
+	key, hit, err := s.fetchCached(ctx, env, t)
+	if err != nil {
+		return fail(err)
+	}
+	if hit {
+		s.Logger.Printf("skipping %s, found in cache", t)
+		r.Status = StatusCached
+		r.Artifacts = t.Artifacts()
+		record()
+		s.Summary.record(r)
+		return "", nil
+	}
+
+	output, closeOutput, err := taskOutput(out, s.LogDir, t)
+	if err != nil {
+		return fail(fmt.Errorf("failed to create log of %s: %w", t, err))
+	}
+	t.Output = output
+
+	s.Logger.Printf("building %s", t)
+	start := time.Now()
+	err = s.Executor.Build(ctx, t)
+	r.Duration = time.Since(start)
+	var cerr error
+	if r.Log, cerr = closeOutput(); cerr != nil {
+		s.Logger.Printf("failed to write log of %s: %v", t, cerr)
+	}
+	if err != nil {
+		return fail(fmt.Errorf("failed to build %s: %w", t, err))
+	}
+
+	s.Logger.Printf("built %s in %s", t, r.Duration.Round(time.Second))
+	r.Status = StatusBuilt
+	r.Artifacts = t.Artifacts()
+	record()
+	s.Summary.record(r)
+	if s.Timings != nil {
+		s.Timings.Record(t, r.Duration)
+	}
+	return key, nil
+}
+
+// skip records every package of the waves from index i on as skipped.
+func (s *Scheduler) skip(waves [][]*dag.Configuration, i int) {
+	for ; i < len(waves); i++ {
+		for _, c := range waves[i] {
+			s.Summary.record(BuildResult{
+				Package: c.Package.Name,
+				Version: c.Version(),
+				Arch:    s.Arch,
+				Wave:    i + 1,
+				Status:  StatusSkipped,
+			})
+		}
+	}
+}
+
+// fetchCached looks the task up in the cache by its resolved build environment, returning its cache key and
+// whether its artifacts were fetched. The key is empty when there is no cache.
+func (s *Scheduler) fetchCached(ctx context.Context, env []dag.Package, t Task) (string, bool, error) {
+	if s.Cache == nil {
+		return "", false, nil
+	}
+	key, err := CacheKey(t, env)
+	if err != nil {
+		return "", false, err
+	}
+	hit, err := s.Cache.Fetch(ctx, key, s.Repo, t)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to fetch %s from cache: %w", t, err)
+	}
+	return key, hit, nil
+}
@@ -0,0 +1,311 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+type fakeExecutor struct {
+	mu     sync.Mutex
+	events []string
+	wave   []string
+	fail   string
+	opts   []string
+}
+
+func (f *fakeExecutor) Sync(context.Context, string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, "sync")
+	return nil
+}
+
+func (f *fakeExecutor) Build(_ context.Context, t Task) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fmt.Fprintf(t.Output, "hello from %s\nno newline", t.Name())
+	if t.Name() == f.fail || t.String() == f.fail {
+		return errors.New("boom")
+	}
+	f.wave = append(f.wave, t.Target())
+	f.opts = append(f.opts, t.MelangeOpts())
+	return nil
+}
+
+func (f *fakeExecutor) Collect(context.Context, string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sort.Strings(f.wave)
+	f.events = append(f.events, f.wave...)
+	f.events = append(f.events, "collect")
+	f.wave = nil
+	return nil
+}
+
+// memCache is a Cache that remembers keys, without any artifacts.
+type memCache struct {
+	mu   sync.Mutex
+	keys map[string]string
+}
+
+func (m *memCache) Fetch(_ context.Context, key, _ string, _ Task) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.keys[key]
+	return ok, nil
+}
+
+func (m *memCache) Store(_ context.Context, key, _ string, t Task) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys[key] = t.String()
+	return nil
+}
+
+func testGraph(t *testing.T) *dag.Graph {
+	testDir := "../dag/testdata/complex"
+	pkgs, err := dag.NewPackages(os.DirFS(testDir), testDir)
+	require.NoError(t, err)
+	g, err := dag.NewGraph(pkgs, dag.WithAllowUnresolved())
+	require.NoError(t, err)
+	return g
+}
+
+func TestScheduler_Run(t *testing.T) {
+	e := &fakeExecutor{}
+	s := NewScheduler(e, "packages", "x86_64")
+	s.Jobs = 2
+	s.Logger = log.New(io.Discard, "", 0)
+
+	require.NoError(t, s.Run(context.Background(), testGraph(t)))
+	assert.Equal(t, []string{
+		"sync",
+		"packages/x86_64/one-1.2.3-r1.apk",
+		"packages/x86_64/one-1.2.8-r1.apk",
+		"collect",
+		"sync",
+		"packages/x86_64/two-4.5.6-r1.apk",
+		"collect",
+		"sync",
+		"packages/x86_64/three-other-7.8.9-r1.apk",
+		"collect",
+	}, e.events)
+}
+
+func TestScheduler_RunRepositories(t *testing.T) {
+	e := &fakeExecutor{}
+	s := NewScheduler(e, "packages", "x86_64")
+	s.Logger = log.New(io.Discard, "", 0)
+	s.Output = io.Discard
+	s.Repositories = []string{"https://packages.wolfi.dev/os"}
+	s.Keyring = []string{"https://packages.wolfi.dev/os/wolfi-signing.rsa.pub"}
+
+	require.NoError(t, s.Run(context.Background(), testGraph(t)))
+	require.Len(t, e.opts, 4)
+	for _, opts := range e.opts {
+		assert.Equal(t, "--repository-append https://packages.wolfi.dev/os --keyring-append https://packages.wolfi.dev/os/wolfi-signing.rsa.pub", opts)
+	}
+}
+
+func TestScheduler_RunStopsOnFailure(t *testing.T) {
+	e := &fakeExecutor{fail: "two"}
+	s := NewScheduler(e, "packages", "x86_64")
+	s.Logger = log.New(io.Discard, "", 0)
+
+	err := s.Run(context.Background(), testGraph(t))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to build two-4.5.6-r1")
+	assert.NotContains(t, e.events, "packages/x86_64/three-other-7.8.9-r1.apk")
+
+	statuses := make(map[string]BuildStatus)
+	for _, r := range s.Summary.Results {
+		statuses[r.Package+"-"+r.Version] = r.Status
+	}
+	assert.Equal(t, map[string]BuildStatus{
+		"one-1.2.3-r1":         StatusBuilt,
+		"one-1.2.8-r1":         StatusBuilt,
+		"two-4.5.6-r1":         StatusFailed,
+		"three-other-7.8.9-r1": StatusSkipped,
+	}, statuses)
+	assert.Equal(t, 1, s.Summary.Count(StatusFailed))
+	assert.Contains(t, s.Summary.Error, "boom")
+}
+
+// cancelExecutor fails a build once another one has started, which runs until it's canceled.
+type cancelExecutor struct {
+	fakeExecutor
+	block   string
+	started chan struct{}
+}
+
+func (c *cancelExecutor) Build(ctx context.Context, t Task) error {
+	switch t.String() {
+	case c.block:
+		close(c.started)
+		<-ctx.Done()
+		return fmt.Errorf("melange build: %w", ctx.Err())
+	case c.fail:
+		<-c.started
+		return errors.New("boom")
+	}
+	return c.fakeExecutor.Build(ctx, t)
+}
+
+func TestScheduler_RunCancelsOnFailure(t *testing.T) {
+	e := &cancelExecutor{fakeExecutor: fakeExecutor{fail: "one-1.2.8-r1"}, block: "one-1.2.3-r1", started: make(chan struct{})}
+	s := NewScheduler(e, "packages", "x86_64")
+	s.Logger = log.New(io.Discard, "", 0)
+	s.Output = io.Discard
+	s.Jobs = 2
+
+	err := s.Run(context.Background(), testGraph(t))
+	assert.ErrorContains(t, err, "failed to build one-1.2.8-r1: boom")
+
+	statuses := make(map[string]BuildStatus)
+	for _, r := range s.Summary.Results {
+		statuses[r.String()] = r.Status
+	}
+	assert.Equal(t, StatusCanceled, statuses["one-1.2.3-r1"], "a build stopped by another's failure didn't fail")
+	assert.Equal(t, StatusFailed, statuses["one-1.2.8-r1"])
+	assert.Equal(t, 1, s.Summary.Count(StatusFailed))
+}
+
+func TestScheduler_RunKeepsGoing(t *testing.T) {
+	e := &fakeExecutor{fail: "one-1.2.8-r1"}
+	s := NewScheduler(e, "packages", "x86_64")
+	s.Logger = log.New(io.Discard, "", 0)
+	s.Output = io.Discard
+	s.KeepGoing = true
+
+	err := s.Run(context.Background(), testGraph(t))
+	assert.EqualError(t, err, "1 packages failed to build: one-1.2.8-r1")
+	assert.Equal(t, []string{
+		"sync",
+		"packages/x86_64/one-1.2.3-r1.apk",
+		"collect",
+		"sync",
+		"packages/x86_64/two-4.5.6-r1.apk",
+		"collect",
+		"sync",
+		"collect",
+	}, e.events)
+
+	r := s.Summary.Results[3]
+	assert.Equal(t, "three-other-7.8.9-r1", r.String())
+	assert.Equal(t, StatusSkipped, r.Status)
+	assert.Equal(t, []string{"one-1.2.8-r1"}, r.BlockedBy)
+	assert.Equal(t, map[string][]string{"one-1.2.8-r1": {"three-other-7.8.9-r1"}}, s.Summary.BlastRadius)
+
+	var report bytes.Buffer
+	require.NoError(t, s.Summary.WriteFailureReport(&report))
+	assert.Equal(t, "one-1.2.8-r1 failed (exit code 1): failed to build one-1.2.8-r1: boom\n  skipped 1 dependent packages: three-other-7.8.9-r1\n", report.String())
+}
+
+func TestScheduler_RunMaxFailures(t *testing.T) {
+	e := &fakeExecutor{fail: "one"}
+	s := NewScheduler(e, "packages", "x86_64")
+	s.Logger = log.New(io.Discard, "", 0)
+	s.Output = io.Discard
+	s.Jobs = 1
+	s.MaxFailures = 1
+
+	err := s.Run(context.Background(), testGraph(t))
+	assert.ErrorContains(t, err, "stopping after 1 failed builds")
+	assert.Equal(t, []string{"sync"}, e.events)
+	assert.Equal(t, 1, s.Summary.Count(StatusFailed))
+	assert.Equal(t, 3, s.Summary.Count(StatusSkipped))
+}
+
+func TestScheduler_RunLogs(t *testing.T) {
+	var out bytes.Buffer
+	logDir := t.TempDir()
+	s := NewScheduler(&fakeExecutor{}, "packages", "x86_64")
+	s.Logger = log.New(io.Discard, "", 0)
+	s.Output = &out
+	s.LogDir = logDir
+
+	require.NoError(t, s.Run(context.Background(), testGraph(t)))
+	assert.Contains(t, out.String(), "[two-4.5.6-r1] hello from two\n[two-4.5.6-r1] no newline\n")
+
+	b, err := os.ReadFile(filepath.Join(logDir, "x86_64", "two-4.5.6-r1.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello from two\nno newline", string(b))
+
+	var summary bytes.Buffer
+	require.NoError(t, s.Summary.WriteJSON(&summary))
+	assert.Contains(t, summary.String(), `"status": "built"`)
+	require.Len(t, s.Summary.Results, 4)
+	r := s.Summary.Results[2]
+	assert.Equal(t, "two", r.Package)
+	assert.Equal(t, 2, r.Wave)
+	assert.Equal(t, filepath.Join(logDir, "x86_64", "two-4.5.6-r1.log"), r.Log)
+	assert.Equal(t, []string{"packages/x86_64/two-4.5.6-r1.apk"}, r.Artifacts)
+}
+
+func TestScheduler_RunSkipsCached(t *testing.T) {
+	c := &memCache{keys: make(map[string]string)}
+	g := testGraph(t)
+
+	e := &fakeExecutor{}
+	s := NewScheduler(e, "packages", "x86_64")
+	s.Cache = c
+	s.Logger = log.New(io.Discard, "", 0)
+	require.NoError(t, s.Run(context.Background(), g))
+	assert.Contains(t, e.events, "packages/x86_64/two-4.5.6-r1.apk")
+	assert.Len(t, c.keys, 4)
+
+	e = &fakeExecutor{}
+	s.Executor = e
+	require.NoError(t, s.Run(context.Background(), g))
+	assert.Equal(t, []string{"sync", "collect", "sync", "collect", "sync", "collect"}, e.events)
+}
+
+func TestScheduler_RunReindexes(t *testing.T) {
+	repo := t.TempDir()
+	s := NewScheduler(&fakeExecutor{}, repo, "x86_64")
+	s.Logger = log.New(io.Discard, "", 0)
+	s.Output = io.Discard
+	s.Reindex = true
+
+	err := s.Run(context.Background(), testGraph(t))
+	assert.ErrorContains(t, err, "failed to regenerate index after wave 1")
+	assert.Equal(t, 2, s.Summary.Count(StatusSkipped))
+
+	require.NoError(t, os.Mkdir(filepath.Join(repo, "x86_64"), 0o755))
+	require.NoError(t, s.Run(context.Background(), testGraph(t)))
+	assert.FileExists(t, filepath.Join(repo, "x86_64", "APKINDEX.tar.gz"))
+}
+
+func TestScheduler_RunPreflight(t *testing.T) {
+	e := &fakeExecutor{}
+	s := NewScheduler(e, "packages", "x86_64")
+	s.Logger = log.New(io.Discard, "", 0)
+	s.Output = io.Discard
+	s.KeepGoing = true
+	s.Preflight = func(t Task) error {
+		if t.Name() == "two" {
+			return errors.New("not hermetic")
+		}
+		return nil
+	}
+
+	err := s.Run(context.Background(), testGraph(t))
+	assert.EqualError(t, err, "1 packages failed to build: two-4.5.6-r1")
+	assert.NotContains(t, e.events, "packages/x86_64/two-4.5.6-r1.apk")
+	assert.Equal(t, "two-4.5.6-r1 failed preflight checks: not hermetic", s.Summary.Results[2].Error)
+	assert.Equal(t, StatusSkipped, s.Summary.Results[3].Status)
+}
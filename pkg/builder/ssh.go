@@ -0,0 +1,106 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// SSH builds packages on a pool of remote hosts, each running one build at a time. Every host needs make,
+// melange and rsync installed. Sync copies the melange configs and the shared repository to each host with
+// rsync, and Collect copies the apks the hosts built back.
+type SSH struct {
+	// Dir is the local directory of melange configs.
+	Dir string
+	// RemoteDir is where the configs are copied to on each host.
+	RemoteDir string
+
+	hosts []string
+	pool  chan string
+}
+
+// NewSSH returns an SSH executor for the given hosts, which can be anything ssh accepts, e.g. user@host.
+func NewSSH(dir, remoteDir string, hosts []string) (*SSH, error) {
+	if len(hosts) == 0 {
+		return nil, errors.New("no ssh hosts given")
+	}
+	pool := make(chan string, len(hosts))
+	for _, h := range hosts {
+		pool <- h
+	}
+	return &SSH{
+		Dir:       dir,
+		RemoteDir: remoteDir,
+		hosts:     hosts,
+		pool:      pool,
+	}, nil
+}
+
+func (s *SSH) Sync(ctx context.Context, repo string) error {
+	var g errgroup.Group
+	for _, host := range s.hosts {
+		host := host
+		g.Go(func() error {
+			// the repository is usually under Dir, but copy it explicitly in case it isn't
+			if err := s.rsync(ctx, s.Dir+"/", host+":"+s.RemoteDir+"/", "--exclude", "packages/"); err != nil {
+				return err
+			}
+			return s.rsync(ctx, repo+"/", host+":"+s.remoteRepo()+"/")
+		})
+	}
+	return g.Wait()
+}
+
+func (s *SSH) Build(ctx context.Context, t Task) error {
+	var host string
+	select {
+	case host = <-s.pool:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { s.pool <- host }()
+
+	fmt.Fprintf(t.Output, "building %s on %s\n", t, host)
+
+	script := fmt.Sprintf("cd %s && ARCH=%s MELANGE_EXTRA_OPTS=%s make %s", shellQuote(s.RemoteDir), shellQuote(t.Arch), shellQuote(t.MelangeOpts()), shellQuote(t.Target()))
+	cmd := exec.CommandContext(ctx, "ssh", host, script)
+	cmd.Stdout = t.Output
+	cmd.Stderr = t.Output
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("make %s on %s: %w", t.Target(), host, err)
+	}
+	return nil
+}
+
+func (s *SSH) Collect(ctx context.Context, repo string) error {
+	// hosts share nothing, so collect from each of them in turn to avoid clobbering the APKINDEX
+	for _, host := range s.hosts {
+		if err := s.rsync(ctx, host+":"+s.remoteRepo()+"/", repo+"/", "--include", "*/", "--include", "*.apk", "--exclude", "*"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SSH) remoteRepo() string {
+	return filepath.Join(s.RemoteDir, "packages")
+}
+
+func (s *SSH) rsync(ctx context.Context, src, dst string, args ...string) error {
+	args = append([]string{"-a"}, args...)
+	args = append(args, src, dst)
+	b, err := exec.CommandContext(ctx, "rsync", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("rsync %s to %s: %w: %s", src, dst, err, b)
+	}
+	return nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,198 @@
+package builder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// BuildStatus is the outcome of a single package of a Scheduler run.
+type BuildStatus string
+
+const (
+	StatusBuilt   BuildStatus = "built"
+	StatusCached  BuildStatus = "cached"
+	StatusFailed  BuildStatus = "failed"
+	StatusSkipped BuildStatus = "skipped"
+	// StatusCanceled is a build that was stopped before it finished, because another build failed or the run was
+	// interrupted.
+	StatusCanceled BuildStatus = "canceled"
+)
+
+// BuildResult is the outcome of a single package, as recorded in a Summary.
+type BuildResult struct {
+	Package  string        `json:"package"`
+	Version  string        `json:"version"`
+	Arch     string        `json:"arch"`
+	Wave     int           `json:"wave"`
+	Status   BuildStatus   `json:"status"`
+	Duration time.Duration `json:"duration"`
+	ExitCode int           `json:"exitCode"`
+
+	// Artifacts are the repository relative paths of the apks, for packages that were built or fetched from the
+	// cache.
+	Artifacts []string `json:"artifacts,omitempty"`
+	Log       string   `json:"log,omitempty"`
+	Error     string   `json:"error,omitempty"`
+
+	// Environment is the repository relative path of the build environment recorded for the package, if any.
+	Environment string `json:"environment,omitempty"`
+
+	// BlockedBy are the failed builds a skipped package depends on, directly or transitively.
+	BlockedBy []string `json:"blockedBy,omitempty"`
+}
+
+func (r BuildResult) String() string {
+	return r.Package + "-" + r.Version
+}
+
+// Summary is a machine-readable account of a Scheduler run, meant for CI.
+type Summary struct {
+	StartTime time.Time     `json:"startTime"`
+	EndTime   time.Time     `json:"endTime"`
+	Results   []BuildResult `json:"results"`
+	Error     string        `json:"error,omitempty"`
+
+	// BlastRadius maps every failed build to the packages that were skipped because they depend on it.
+	BlastRadius map[string][]string `json:"blastRadius,omitempty"`
+
+	mu sync.Mutex
+}
+
+func newSummary() *Summary {
+	return &Summary{StartTime: time.Now()}
+}
+
+func (s *Summary) record(r BuildResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Results = append(s.Results, r)
+}
+
+func (s *Summary) finish(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.EndTime = time.Now()
+	if err != nil {
+		s.Error = err.Error()
+	}
+	sort.SliceStable(s.Results, func(i, j int) bool {
+		if s.Results[i].Wave != s.Results[j].Wave {
+			return s.Results[i].Wave < s.Results[j].Wave
+		}
+		if s.Results[i].Package != s.Results[j].Package {
+			return s.Results[i].Package < s.Results[j].Package
+		}
+		return s.Results[i].Version < s.Results[j].Version
+	})
+
+	radius := make(map[string][]string)
+	for i := range s.Results {
+		r := s.Results[i]
+		if r.Status == StatusFailed && radius[r.String()] == nil {
+			radius[r.String()] = []string{}
+		}
+		for _, b := range r.BlockedBy {
+			// the same package can be blocked on several architectures
+			if !slices.Contains(radius[b], r.String()) {
+				radius[b] = append(radius[b], r.String())
+			}
+		}
+	}
+	if len(radius) > 0 {
+		s.BlastRadius = radius
+	}
+}
+
+// WriteFailureReport writes every failed build of the summary to w, along with the packages skipped because of it.
+func (s *Summary) WriteFailureReport(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.Results {
+		r := s.Results[i]
+		if r.Status != StatusFailed {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s failed (exit code %d): %s\n", r, r.ExitCode, r.Error); err != nil {
+			return err
+		}
+		if blocked := s.BlastRadius[r.String()]; len(blocked) > 0 {
+			if _, err := fmt.Fprintf(w, "  skipped %d dependent packages: %s\n", len(blocked), strings.Join(blocked, ", ")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Count returns the number of packages with the given status.
+func (s *Summary) Count(status BuildStatus) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for i := range s.Results {
+		if s.Results[i].Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteJSON writes the summary to w as indented JSON.
+func (s *Summary) WriteJSON(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// WriteFile writes the summary to path as JSON, or to stdout if path is "-".
+func (s *Summary) WriteFile(path string) error {
+	if path == "-" {
+		return s.WriteJSON(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadSummary reads a summary written by WriteFile
+func ReadSummary(path string) (*Summary, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Summary{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("failed to parse build summary %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// exitCode returns the exit code of the command behind a build error, 0 for no error, or 1 if it isn't known.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return 1
+}
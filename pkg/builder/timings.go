@@ -0,0 +1,97 @@
+package builder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// timingsHistory is how many of the most recent build durations of each package are kept.
+const timingsHistory = 5
+
+// Timings is a database of how long previous builds of each package took, used to estimate future builds. It's
+// keyed by architecture and package name rather than version, since the build time of a package rarely changes
+// much between versions.
+type Timings struct {
+	path string
+
+	mu sync.Mutex
+	// Durations holds the most recent durations of each package, oldest first, by architecture and package name.
+	Durations map[string]map[string][]time.Duration `json:"durations"`
+}
+
+// LoadTimings reads the timings database at path. A database that doesn't exist yet is empty.
+func LoadTimings(path string) (*Timings, error) {
+	t := &Timings{
+		path:      path,
+		Durations: make(map[string]map[string][]time.Duration),
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, t); err != nil {
+		return nil, fmt.Errorf("failed to parse timings database %s: %w", path, err)
+	}
+	if t.Durations == nil {
+		t.Durations = make(map[string]map[string][]time.Duration)
+	}
+	return t, nil
+}
+
+// Record adds the duration of a successful build of t.
+func (t *Timings) Record(task Task, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	byName, ok := t.Durations[task.Arch]
+	if !ok {
+		byName = make(map[string][]time.Duration)
+		t.Durations[task.Arch] = byName
+	}
+	durations := append(byName[task.Name()], d)
+	if len(durations) > timingsHistory {
+		durations = durations[len(durations)-timingsHistory:]
+	}
+	byName[task.Name()] = durations
+}
+
+// Estimate returns the mean of the recorded durations of the task's package, and false if there are none.
+func (t *Timings) Estimate(task Task) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	durations := t.Durations[task.Arch][task.Name()]
+	if len(durations) == 0 {
+		return 0, false
+	}
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return total / time.Duration(len(durations)), true
+}
+
+// Save writes the database back to the path it was loaded from.
+func (t *Timings) Save() error {
+	t.mu.Lock()
+	b, err := json.MarshalIndent(t, "", "  ")
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(t.path, b, 0o600)
+}
@@ -0,0 +1,159 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher calls a function every time files it watches change, for an edit-build loop.
+type Watcher struct {
+	// Files are watched for changes. They're watched through their directory, so editors that replace files
+	// instead of writing them in place are noticed too.
+	Files []string
+	// Dirs are watched for changes to anything under them, recursively.
+	Dirs []string
+
+	// Debounce is how long to wait for changes to settle before calling the function, so saving several files at
+	// once only triggers it once.
+	Debounce time.Duration
+
+	Logger *log.Logger
+}
+
+// NewWatcher returns a Watcher of files and everything under dirs.
+func NewWatcher(files, dirs []string) *Watcher {
+	return &Watcher{
+		Files:    files,
+		Dirs:     dirs,
+		Debounce: 500 * time.Millisecond,
+		Logger:   log.New(log.Writer(), "wolfictl build: ", log.LstdFlags|log.Lmsgprefix),
+	}
+}
+
+// Run calls fn with the paths that changed, sorted, every time any of the watched files changes, until ctx is done.
+// Changes made while fn runs trigger another call once it returns. An error from fn is logged, and doesn't stop
+// watching.
+func (w *Watcher) Run(ctx context.Context, fn func(ctx context.Context, changed []string) error) error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	defer fw.Close()
+
+	files := make(map[string]bool, len(w.Files))
+	for _, f := range w.Files {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return err
+		}
+		files[abs] = true
+		if err := fw.Add(filepath.Dir(abs)); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", f, err)
+		}
+	}
+	dirs := make([]string, 0, len(w.Dirs))
+	for _, d := range w.Dirs {
+		abs, err := filepath.Abs(d)
+		if err != nil {
+			return err
+		}
+		if err := addTree(fw, abs); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", d, err)
+		}
+		dirs = append(dirs, abs)
+	}
+
+	watched := func(path string) bool {
+		if files[path] {
+			return true
+		}
+		for _, d := range dirs {
+			if path == d || strings.HasPrefix(path, d+string(filepath.Separator)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var (
+		changed = make(map[string]bool)
+		timer   *time.Timer
+		fire    <-chan time.Time
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return nil
+			}
+			w.Logger.Printf("watch error: %v", err)
+		case ev, ok := <-fw.Events:
+			if !ok {
+				return nil
+			}
+			if !watched(ev.Name) || ev.Op == fsnotify.Chmod {
+				continue
+			}
+			if ev.Op&fsnotify.Create != 0 {
+				// directories created under a watched directory are watched too
+				if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() && !files[ev.Name] {
+					if err := addTree(fw, ev.Name); err != nil {
+						w.Logger.Printf("failed to watch %s: %v", ev.Name, err)
+					}
+				}
+			}
+			changed[ev.Name] = true
+			if timer == nil {
+				timer = time.NewTimer(w.Debounce)
+			} else {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(w.Debounce)
+			}
+			fire = timer.C
+		case <-fire:
+			fire = nil
+			paths := make([]string, 0, len(changed))
+			for p := range changed {
+				paths = append(paths, p)
+			}
+			sort.Strings(paths)
+			changed = make(map[string]bool)
+
+			if err := fn(ctx, paths); err != nil {
+				w.Logger.Printf("%v", err)
+			}
+			if ctx.Err() == nil {
+				w.Logger.Printf("watching for changes")
+			}
+		}
+	}
+}
+
+// addTree watches dir and every directory under it.
+func addTree(fw *fsnotify.Watcher, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		return fw.Add(path)
+	})
+}
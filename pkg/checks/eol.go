@@ -0,0 +1,334 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+const (
+	// DefaultEOLURL is the endoflife.date API, which tracks the release cycles of projects and when their support ends
+	DefaultEOLURL = "https://endoflife.date"
+	// DefaultEOLOverridesFile is where the overrides of the end-of-life check are read from, in the directory of the
+	// melange configs
+	DefaultEOLOverridesFile = ".eol.yaml"
+)
+
+// EOLOverrides complete and correct what endoflife.date says about packages.
+type EOLOverrides struct {
+	Packages map[string]EOLOverride `yaml:"packages"`
+}
+
+// EOLOverride is what's known about the end-of-life of a package beyond endoflife.date.
+type EOLOverride struct {
+	// Product is the product of the package on endoflife.date, e.g. eclipse-temurin for openjdk-17. It defaults to the
+	// name of the package, without the version stream suffix of packages like nodejs-18.
+	Product string `yaml:"product,omitempty"`
+	// Cycle is the release cycle of the package on endoflife.date, e.g. 18. It defaults to the longest cycle the
+	// version of the package starts with.
+	Cycle string `yaml:"cycle,omitempty"`
+	// EOL is when the version stream of the package reaches end-of-life, for projects endoflife.date doesn't track.
+	EOL time.Time `yaml:"eol,omitempty"`
+	// Unmaintained is why the project of the package is no longer maintained upstream, e.g. its repository was
+	// archived.
+	Unmaintained string `yaml:"unmaintained,omitempty"`
+	// Ignore leaves the package out of the check, e.g. when it's supported downstream past its end-of-life.
+	Ignore bool `yaml:"ignore,omitempty"`
+}
+
+// ReadEOLOverrides reads EOLOverrides from a YAML file, a file that doesn't exist has no overrides.
+func ReadEOLOverrides(path string) (*EOLOverrides, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &EOLOverrides{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	o := &EOLOverrides{}
+	if err := yaml.Unmarshal(b, o); err != nil {
+		return nil, fmt.Errorf("failed to parse end-of-life overrides %s: %w", path, err)
+	}
+	return o, nil
+}
+
+// EOLOptions configures the end-of-life check of the melange configs in Dir.
+type EOLOptions struct {
+	Dir string
+
+	// Packages, if not empty, restricts the check to these packages.
+	Packages []string
+
+	Overrides *EOLOverrides
+
+	// Within also reports the packages that reach end-of-life within this long from Now.
+	Now    time.Time
+	Within time.Duration
+
+	// URL is the endoflife.date API, and Client the client of its requests.
+	URL    string
+	Client *http.Client
+}
+
+// EOLPackage is a package whose version stream is past, or near, its end-of-life, or whose project is no longer
+// maintained.
+type EOLPackage struct {
+	Package string `json:"package"`
+	Version string `json:"version"`
+	// Product and Cycle are the release cycle of the package on endoflife.date, empty for overrides.
+	Product string `json:"product,omitempty"`
+	Cycle   string `json:"cycle,omitempty"`
+	// EOL is the end-of-life date, as YYYY-MM-DD, or "yes" if endoflife.date doesn't say when.
+	EOL string `json:"eol,omitempty"`
+	// LatestCycle is the newest release cycle of the product, to migrate to.
+	LatestCycle  string `json:"latestCycle,omitempty"`
+	Unmaintained string `json:"unmaintained,omitempty"`
+
+	// Path is the melange config of the package.
+	Path string `json:"path"`
+}
+
+func (e EOLPackage) String() string {
+	if e.Unmaintained != "" {
+		return fmt.Sprintf("%s %s: unmaintained: %s", e.Package, e.Version, e.Unmaintained)
+	}
+	s := fmt.Sprintf("%s %s: end-of-life %s", e.Package, e.Version, e.EOL)
+	if e.Product != "" {
+		s += fmt.Sprintf(" (%s %s", e.Product, e.Cycle)
+		if e.LatestCycle != "" && e.LatestCycle != e.Cycle {
+			s += fmt.Sprintf(", latest %s", e.LatestCycle)
+		}
+		s += ")"
+	}
+	return s
+}
+
+// IssueTitle is the title of the issue tracking the migration of the package, which is found by its title. It doesn't
+// have the version of the package, so the issue stays the same as the package is updated within its version stream.
+func (e EOLPackage) IssueTitle() string {
+	if e.Unmaintained != "" {
+		return fmt.Sprintf("%s is unmaintained", e.Package)
+	}
+	return fmt.Sprintf("%s reaches end-of-life", e.Package)
+}
+
+// IssueBody is the description of the issue tracking the migration of the package.
+func (e EOLPackage) IssueBody() string {
+	var b strings.Builder
+	if e.Unmaintained != "" {
+		fmt.Fprintf(&b, "The project of `%s` is no longer maintained upstream: %s\n", e.Package, e.Unmaintained)
+	} else {
+		fmt.Fprintf(&b, "`%s` %s reaches end-of-life on %s", e.Package, e.Version, e.EOL)
+		if e.Product != "" {
+			fmt.Fprintf(&b, ", according to [endoflife.date](https://endoflife.date/%s) for the %s release cycle of %s", e.Product, e.Cycle, e.Product)
+		}
+		b.WriteString(".\n")
+		if e.LatestCycle != "" && e.LatestCycle != e.Cycle {
+			fmt.Fprintf(&b, "\nThe latest release cycle is %s.\n", e.LatestCycle)
+		}
+	}
+	fmt.Fprintf(&b, "\nMelange config: `%s`\n", e.Path)
+	return b.String()
+}
+
+// eolCycle is a release cycle of a product on endoflife.date. EOL is a date, or a boolean if the date isn't known.
+type eolCycle struct {
+	Cycle json.RawMessage `json:"cycle"`
+	EOL   json.RawMessage `json:"eol"`
+}
+
+// name returns the name of the cycle, which the API returns as a string or a number
+func (c eolCycle) name() string {
+	var s string
+	if err := json.Unmarshal(c.Cycle, &s); err == nil {
+		return s
+	}
+	return string(c.Cycle)
+}
+
+// eol returns the end-of-life date of the cycle, and if it has one at all
+func (c eolCycle) eol() (date time.Time, known, eol bool) {
+	var b bool
+	if err := json.Unmarshal(c.EOL, &b); err == nil {
+		return time.Time{}, false, b
+	}
+	var s string
+	if err := json.Unmarshal(c.EOL, &s); err != nil {
+		return time.Time{}, false, false
+	}
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, false, false
+	}
+	return d, true, true
+}
+
+// streamSuffix matches the version stream suffix of packages like nodejs-18 or python-3.11
+var streamSuffix = regexp.MustCompile(`-[0-9][0-9.]*$`)
+
+// CheckEOL returns the packages of the melange configs in Dir past their end-of-life, or reaching it within Within
+// of Now, and the ones whose project is unmaintained, sorted by package.
+func (o EOLOptions) CheckEOL(ctx context.Context) ([]EOLPackage, error) {
+	configs, err := melange.ReadAllPackagesFromRepo(o.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read melange configs from %s: %w", o.Dir, err)
+	}
+	names := append([]string{}, o.Packages...)
+	if len(names) == 0 {
+		for name := range configs {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	overrides := o.Overrides
+	if overrides == nil {
+		overrides = &EOLOverrides{}
+	}
+	deadline := o.Now.Add(o.Within)
+
+	var products map[string]bool
+	cycles := make(map[string][]eolCycle)
+	var found []EOLPackage
+	for _, name := range names {
+		p, ok := configs[name]
+		if !ok {
+			return nil, fmt.Errorf("no melange config for package %s in %s", name, o.Dir)
+		}
+		override := overrides.Packages[name]
+		if override.Ignore {
+			continue
+		}
+		e := EOLPackage{Package: name, Version: p.Config.Package.Version, Path: filepath.Join(p.Dir, p.Filename)}
+
+		switch {
+		case override.Unmaintained != "":
+			e.Unmaintained = override.Unmaintained
+			found = append(found, e)
+			continue
+		case !override.EOL.IsZero():
+			if override.EOL.Before(deadline) {
+				e.EOL = override.EOL.Format("2006-01-02")
+				found = append(found, e)
+			}
+			continue
+		}
+
+		if products == nil {
+			if products, err = o.fetchProducts(ctx); err != nil {
+				return nil, err
+			}
+		}
+		product := override.Product
+		if product == "" {
+			product = name
+			if !products[product] {
+				product = streamSuffix.ReplaceAllString(name, "")
+			}
+		}
+		if !products[product] {
+			continue
+		}
+		if _, ok := cycles[product]; !ok {
+			if cycles[product], err = o.fetchCycles(ctx, product); err != nil {
+				return nil, err
+			}
+		}
+
+		c, ok := findCycle(cycles[product], e.Version, override.Cycle)
+		if !ok {
+			continue
+		}
+		date, known, eol := c.eol()
+		switch {
+		case known && date.Before(deadline):
+			e.EOL = date.Format("2006-01-02")
+		case !known && eol:
+			e.EOL = "yes"
+		default:
+			continue
+		}
+		e.Product, e.Cycle = product, c.name()
+		if len(cycles[product]) > 0 {
+			e.LatestCycle = cycles[product][0].name()
+		}
+		found = append(found, e)
+	}
+	return found, nil
+}
+
+// findCycle returns the given cycle, or the longest one the version is in, like 3.11 for 3.11.4
+func findCycle(cycles []eolCycle, version, cycle string) (eolCycle, bool) {
+	var best eolCycle
+	var ok bool
+	for _, c := range cycles {
+		n := c.name()
+		if cycle != "" {
+			if n == cycle {
+				return c, true
+			}
+			continue
+		}
+		if (version == n || strings.HasPrefix(version, n+".")) && (!ok || len(n) > len(best.name())) {
+			best, ok = c, true
+		}
+	}
+	return best, ok
+}
+
+func (o EOLOptions) fetchProducts(ctx context.Context) (map[string]bool, error) {
+	var names []string
+	if err := o.get(ctx, "/api/all.json", &names); err != nil {
+		return nil, err
+	}
+	products := make(map[string]bool, len(names))
+	for _, n := range names {
+		products[n] = true
+	}
+	return products, nil
+}
+
+// fetchCycles returns the release cycles of a product, newest first
+func (o EOLOptions) fetchCycles(ctx context.Context, product string) ([]eolCycle, error) {
+	var cycles []eolCycle
+	err := o.get(ctx, fmt.Sprintf("/api/%s.json", product), &cycles)
+	return cycles, err
+}
+
+func (o EOLOptions) get(ctx context.Context, path string, v any) error {
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u := strings.TrimSuffix(o.URL, "/") + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("failed creating GET request %s: %w", u, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed getting URI %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("non ok http response for URI %s code: %v: %s", u, resp.StatusCode, b)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", u, err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEOLServer(t *testing.T) *httptest.Server {
+	responses := map[string]string{
+		"/api/all.json": `["nodejs", "python", "eclipse-temurin"]`,
+		"/api/nodejs.json": `[
+			{"cycle": "20", "eol": "2026-04-30"},
+			{"cycle": "16", "eol": "2023-09-11"},
+			{"cycle": "14", "eol": "2023-04-30"}
+		]`,
+		"/api/python.json": `[
+			{"cycle": "3.12", "eol": "2028-10-02"},
+			{"cycle": "3.8", "eol": "2023-11-14"}
+		]`,
+		"/api/eclipse-temurin.json": `[
+			{"cycle": 21, "eol": false},
+			{"cycle": 8, "eol": true}
+		]`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, err := w.Write([]byte(body))
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheckEOL(t *testing.T) {
+	server := newEOLServer(t)
+	overridesFile := filepath.Join(t.TempDir(), DefaultEOLOverridesFile)
+	require.NoError(t, os.WriteFile(overridesFile, []byte(`
+packages:
+  openjdk-8:
+    product: eclipse-temurin
+    cycle: "8"
+  libarchived:
+    unmaintained: the upstream repository was archived
+  libold:
+    eol: 2023-06-30
+  nodejs-14:
+    ignore: true
+`), 0o600))
+	overrides, err := ReadEOLOverrides(overridesFile)
+	require.NoError(t, err)
+
+	o := EOLOptions{
+		Dir:       "testdata/eol",
+		Overrides: overrides,
+		Now:       time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC),
+		URL:       server.URL,
+		Client:    server.Client(),
+	}
+	found, err := o.CheckEOL(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []EOLPackage{
+		{Package: "libarchived", Version: "1.0.0", Unmaintained: "the upstream repository was archived", Path: "testdata/eol/libarchived.yaml"},
+		{Package: "libold", Version: "2.1.0", EOL: "2023-06-30", Path: "testdata/eol/libold.yaml"},
+		{Package: "nodejs-16", Version: "16.20.2", Product: "nodejs", Cycle: "16", EOL: "2023-09-11", LatestCycle: "20", Path: "testdata/eol/nodejs-16.yaml"},
+		{Package: "openjdk-8", Version: "8.382.05", Product: "eclipse-temurin", Cycle: "8", EOL: "yes", LatestCycle: "21", Path: "testdata/eol/openjdk-8.yaml"},
+	}, found)
+
+	o.Within = 30 * 24 * time.Hour
+	o.Packages = []string{"python-3.8"}
+	found, err = o.CheckEOL(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, found, "a version stream reaching end-of-life in more than 30 days shouldn't be reported")
+
+	o.Within = 60 * 24 * time.Hour
+	found, err = o.CheckEOL(context.Background())
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "python-3.8 3.8.18: end-of-life 2023-11-14 (python 3.8, latest 3.12)", found[0].String())
+	assert.Equal(t, "python-3.8 reaches end-of-life", found[0].IssueTitle())
+
+	o.Packages = []string{"missing"}
+	_, err = o.CheckEOL(context.Background())
+	assert.ErrorContains(t, err, "no melange config for package missing")
+}
+
+func TestCheckEOL_versionBump(t *testing.T) {
+	server := newEOLServer(t)
+	dir := t.TempDir()
+	config, err := os.ReadFile("testdata/eol/nodejs-16.yaml")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nodejs-16.yaml"), config, 0o600))
+
+	o := EOLOptions{
+		Dir:    dir,
+		Now:    time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC),
+		URL:    server.URL,
+		Client: server.Client(),
+	}
+	check := func() EOLPackage {
+		// the packages found may be written to stdout as JSON, which nothing else may write to
+		stdout := os.Stdout
+		read, write, err := os.Pipe()
+		require.NoError(t, err)
+		os.Stdout = write
+		found, err := o.CheckEOL(context.Background())
+		os.Stdout = stdout
+		require.NoError(t, write.Close())
+		require.NoError(t, err)
+		written, err := io.ReadAll(read)
+		require.NoError(t, err)
+		assert.Empty(t, string(written))
+		require.Len(t, found, 1)
+		return found[0]
+	}
+
+	before := check()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nodejs-16.yaml"), bytes.Replace(config, []byte("16.20.2"), []byte("16.20.3"), 1), 0o600))
+	after := check()
+	assert.Equal(t, "16.20.3", after.Version)
+	assert.Equal(t, before.IssueTitle(), after.IssueTitle(), "the issue of the package is found again after its version is bumped")
+}
+
+func TestReadEOLOverrides_missing(t *testing.T) {
+	overrides, err := ReadEOLOverrides(filepath.Join(t.TempDir(), DefaultEOLOverridesFile))
+	require.NoError(t, err)
+	assert.Empty(t, overrides.Packages)
+}
@@ -0,0 +1,155 @@
+package checks
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+	"github.com/wolfi-dev/wolfictl/pkg/sarif"
+)
+
+// NetworkAccess is a pipeline step of a melange config that reaches the network outside of the fetch and
+// git-checkout steps, so the build depends on more than its declared sources.
+type NetworkAccess struct {
+	Package string
+
+	// Step locates the step in the config, e.g. pipeline[2] or subpackages[0].pipeline[1].
+	Step   string
+	Reason string
+
+	// Path is the melange config of the package.
+	Path string
+}
+
+func (n NetworkAccess) String() string {
+	return fmt.Sprintf("%s: %s %s", n.Package, n.Step, n.Reason)
+}
+
+// networkCommand is a command that reaches the network when run in a pipeline, unless offline matches the line
+// it's on.
+type networkCommand struct {
+	re      *regexp.Regexp
+	offline *regexp.Regexp
+	reason  string
+}
+
+// command matches a command where a line of a script runs it: at the start of the line or after an operator like &&
+// or |, possibly after sudo, env or variable assignments, so arguments like --with-curl and words in strings don't
+// match.
+func command(pattern string) *regexp.Regexp {
+	return regexp.MustCompile(`(?:^|[;&|(` + "`" + `])\s*(?:(?:sudo|exec|env|time)\s+|\w+=\S*\s+)*(?:\S*/)?(?:` + pattern + `)`)
+}
+
+// networkCommands are the commands that download something. Go builds aren't among them: they only download the
+// modules that aren't vendored in the sources, which configs don't tell, unlike the go commands downloading modules.
+var networkCommands = []networkCommand{
+	{re: command(`(curl|wget)(\s|$)`), reason: "downloads with curl or wget"},
+	{re: command(`git\s+(clone|fetch|pull|ls-remote|submodule\s+update)\b`), reason: "fetches a git repository"},
+	{re: command(`go\s+(get|mod\s+(download|vendor|tidy)|install\s+\S+@\S+)\b`), reason: "downloads go modules"},
+	{re: command(`pip3?\s+install\b`), offline: regexp.MustCompile(`--no-index\b`), reason: "installs python packages"},
+	{re: command(`npm\s+(install|ci)\b|yarn(\s+install\b|\s*$)`), offline: regexp.MustCompile(`--offline\b`), reason: "installs node modules"},
+	{re: command(`cargo\s+(build|fetch|install|vendor)\b`), offline: regexp.MustCompile(`--(offline|frozen)\b`), reason: "downloads crates"},
+	{re: command(`gem\s+install\b`), offline: regexp.MustCompile(`--local\b`), reason: "installs gems"},
+	{re: command(`(mvn|mvnw|gradle|gradlew)(\s|$)`), offline: regexp.MustCompile(`(\s-o\b|--offline\b)`), reason: "downloads java dependencies"},
+}
+
+// FindNetworkAccess returns the steps of cfg's pipelines, and its subpackages' pipelines, that reach the network
+// outside of fetch and git-checkout steps.
+func FindNetworkAccess(cfg *build.Configuration) []NetworkAccess {
+	var found []NetworkAccess
+	var walk func(steps []build.Pipeline, path string)
+	walk = func(steps []build.Pipeline, path string) {
+		for i := range steps {
+			p := &steps[i]
+			step := fmt.Sprintf("%s[%d]", path, i)
+			if p.Uses == "fetch" || p.Uses == "git-checkout" {
+				continue
+			}
+			if reason := scriptNetworkAccess(p.Runs); reason != "" {
+				found = append(found, NetworkAccess{Package: cfg.Package.Name, Step: step, Reason: reason})
+			}
+			walk(p.Pipeline, step+".pipeline")
+		}
+	}
+	walk(cfg.Pipeline, "pipeline")
+	for i := range cfg.Subpackages {
+		walk(cfg.Subpackages[i].Pipeline, fmt.Sprintf("subpackages[%d].pipeline", i))
+	}
+	return found
+}
+
+// scriptNetworkAccess returns why the script reaches the network, or an empty string if it doesn't.
+func scriptNetworkAccess(script string) string {
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, c := range networkCommands {
+			if c.re.MatchString(line) && (c.offline == nil || !c.offline.MatchString(line)) {
+				return fmt.Sprintf("runs %q, which %s", line, c.reason)
+			}
+		}
+	}
+	return ""
+}
+
+// HermeticOptions configures the hermeticity check of the melange configs in Dir.
+type HermeticOptions struct {
+	Dir string
+
+	// Packages, if not empty, restricts the check to these packages.
+	Packages []string
+}
+
+// NetworkAccesses are the steps of melange configs that reach the network.
+type NetworkAccesses []NetworkAccess
+
+// CheckHermetic returns the steps of the melange configs in Dir that reach the network, sorted by package.
+func (o HermeticOptions) CheckHermetic() (NetworkAccesses, error) {
+	configs, err := melange.ReadAllPackagesFromRepo(o.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read melange configs from %s: %w", o.Dir, err)
+	}
+
+	wanted := make(map[string]bool)
+	for _, p := range o.Packages {
+		if _, ok := configs[p]; !ok {
+			return nil, fmt.Errorf("no melange config for package %s in %s", p, o.Dir)
+		}
+		wanted[p] = true
+	}
+
+	var found NetworkAccesses
+	for name, p := range configs {
+		if len(wanted) > 0 && !wanted[name] {
+			continue
+		}
+		for _, n := range FindNetworkAccess(&p.Config) {
+			n.Path = filepath.Join(p.Dir, p.Filename)
+			found = append(found, n)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Package < found[j].Package })
+	return found, nil
+}
+
+const ruleNetworkAccess = "hermetic/network-access"
+
+// SARIF returns the steps as a SARIF log, located at the configs of their packages.
+func (n NetworkAccesses) SARIF() *sarif.Log {
+	log := sarif.New(sarif.Rule{
+		ID:                   ruleNetworkAccess,
+		ShortDescription:     sarif.Message{Text: "pipelines should only reach the network in fetch and git-checkout steps"},
+		DefaultConfiguration: sarif.Configuration{Level: sarif.LevelWarning},
+	})
+	for _, a := range n {
+		log.Add(ruleNetworkAccess, fmt.Sprintf("%s %s", a.Step, a.Reason), filepath.ToSlash(a.Path), sarif.LineOf(a.Path, "pipeline:"))
+	}
+	return log
+}
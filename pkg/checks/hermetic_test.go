@@ -0,0 +1,51 @@
+package checks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckHermetic(t *testing.T) {
+	found, err := HermeticOptions{Dir: "testdata/hermetic"}.CheckHermetic()
+	require.NoError(t, err)
+
+	assert.Equal(t, NetworkAccesses{
+		{
+			Package: "leaky",
+			Step:    "pipeline[1]",
+			Reason:  `runs "curl -fsSL https://example.com/extra.patch | patch -p1", which downloads with curl or wget`,
+			Path:    "testdata/hermetic/leaky.yaml",
+		},
+		{
+			Package: "leaky",
+			Step:    "pipeline[4]",
+			Reason:  `runs "test -d vendor || GOFLAGS=-mod=mod go mod download", which downloads go modules`,
+			Path:    "testdata/hermetic/leaky.yaml",
+		},
+		{
+			Package: "leaky",
+			Step:    "subpackages[0].pipeline[0]",
+			Reason:  `runs "npm ci", which installs node modules`,
+			Path:    "testdata/hermetic/leaky.yaml",
+		},
+	}, found)
+
+	found, err = HermeticOptions{Dir: "testdata/hermetic", Packages: []string{"vendored"}}.CheckHermetic()
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	_, err = HermeticOptions{Dir: "testdata/hermetic", Packages: []string{"missing"}}.CheckHermetic()
+	assert.ErrorContains(t, err, "no melange config for package missing")
+}
+
+func TestNetworkAccessesSARIF(t *testing.T) {
+	found, err := HermeticOptions{Dir: "testdata/hermetic", Packages: []string{"leaky"}}.CheckHermetic()
+	require.NoError(t, err)
+
+	results := found.SARIF().Runs[0].Results
+	require.Len(t, results, 3)
+	assert.Equal(t, "hermetic/network-access", results[0].RuleID)
+	assert.Equal(t, 7, results[0].Locations[0].PhysicalLocation.Region.StartLine)
+}
@@ -0,0 +1,177 @@
+package checks
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+	"gopkg.in/yaml.v3"
+
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+	"github.com/wolfi-dev/wolfictl/pkg/sarif"
+)
+
+const (
+	SourceRepository = "repository"
+	SourceKeyring    = "keyring"
+)
+
+// IntakePolicy lists the external repositories and keys that melange configs are approved to use. Entries can be
+// glob patterns, as matched by path.Match, e.g. https://packages.wolfi.dev/*.
+type IntakePolicy struct {
+	Repositories []string `yaml:"repositories"`
+	Keyring      []string `yaml:"keyring"`
+}
+
+// ReadIntakePolicy reads an IntakePolicy from a YAML file.
+func ReadIntakePolicy(path string) (*IntakePolicy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &IntakePolicy{}
+	if err := yaml.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("failed to parse intake policy %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Approved reports whether the policy approves the repository or key of the given kind.
+func (p IntakePolicy) Approved(kind, source string) bool {
+	approved := p.Repositories
+	if kind == SourceKeyring {
+		approved = p.Keyring
+	}
+	for _, a := range approved {
+		if a == source {
+			return true
+		}
+		if ok, err := path.Match(a, source); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// UnapprovedSource is an external repository or key a melange config uses without the approval of the intake
+// policy.
+type UnapprovedSource struct {
+	Package string
+	// Kind is SourceRepository or SourceKeyring.
+	Kind   string
+	Source string
+
+	// Path is the melange config of the package.
+	Path string
+}
+
+func (u UnapprovedSource) String() string {
+	return fmt.Sprintf("%s: %s %s isn't approved", u.Package, u.Kind, u.Source)
+}
+
+// externalSources returns the repositories and keys of cfg's build environment, keyed by kind. Repositories are
+// stripped of their @tag prefix, and local repositories, which don't widen the supply chain, are left out.
+func externalSources(cfg *build.Configuration) map[string][]string {
+	sources := make(map[string][]string)
+	for _, r := range cfg.Environment.Contents.Repositories {
+		if strings.HasPrefix(r, "@") {
+			if _, after, ok := strings.Cut(r, " "); ok {
+				r = strings.TrimSpace(after)
+			}
+		}
+		if !strings.Contains(r, "://") {
+			continue
+		}
+		sources[SourceRepository] = append(sources[SourceRepository], r)
+	}
+	for _, k := range cfg.Environment.Contents.Keyring {
+		if !strings.Contains(k, "://") {
+			continue
+		}
+		sources[SourceKeyring] = append(sources[SourceKeyring], k)
+	}
+	return sources
+}
+
+// IntakeOptions configures the intake check of the melange configs in Dir.
+type IntakeOptions struct {
+	Dir    string
+	Policy *IntakePolicy
+
+	// BaseDir, if set, is the same repository of melange configs before the change being checked. Only the sources
+	// that are new compared to it are checked, so sources that were already used don't need approval again.
+	BaseDir string
+}
+
+// UnapprovedSources are the external sources of melange configs the intake policy doesn't approve.
+type UnapprovedSources []UnapprovedSource
+
+// CheckIntake returns the external repositories and keys of the melange configs in Dir the policy doesn't approve,
+// sorted by package.
+func (o IntakeOptions) CheckIntake() (UnapprovedSources, error) {
+	configs, err := melange.ReadAllPackagesFromRepo(o.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read melange configs from %s: %w", o.Dir, err)
+	}
+
+	known := make(map[string]bool)
+	if o.BaseDir != "" {
+		base, err := melange.ReadAllPackagesFromRepo(o.BaseDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read melange configs from %s: %w", o.BaseDir, err)
+		}
+		for _, p := range base {
+			for kind, sources := range externalSources(&p.Config) {
+				for _, s := range sources {
+					known[kind+" "+s] = true
+				}
+			}
+		}
+	}
+
+	var found UnapprovedSources
+	for name, p := range configs {
+		for kind, sources := range externalSources(&p.Config) {
+			for _, s := range sources {
+				if known[kind+" "+s] || o.Policy.Approved(kind, s) {
+					continue
+				}
+				found = append(found, UnapprovedSource{
+					Package: name,
+					Kind:    kind,
+					Source:  s,
+					Path:    filepath.Join(p.Dir, p.Filename),
+				})
+			}
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Package != found[j].Package {
+			return found[i].Package < found[j].Package
+		}
+		if found[i].Kind != found[j].Kind {
+			return found[i].Kind > found[j].Kind
+		}
+		return found[i].Source < found[j].Source
+	})
+	return found, nil
+}
+
+const ruleUnapprovedSource = "intake/unapproved-source"
+
+// SARIF returns the sources as a SARIF log, located at the configs of their packages.
+func (u UnapprovedSources) SARIF() *sarif.Log {
+	log := sarif.New(sarif.Rule{
+		ID:                   ruleUnapprovedSource,
+		ShortDescription:     sarif.Message{Text: "external repositories and keys need to be approved in the intake policy"},
+		DefaultConfiguration: sarif.Configuration{Level: sarif.LevelError},
+	})
+	for _, s := range u {
+		log.Add(ruleUnapprovedSource, fmt.Sprintf("%s %s isn't approved", s.Kind, s.Source), filepath.ToSlash(s.Path), sarif.LineOf(s.Path, s.Source))
+	}
+	return log
+}
@@ -0,0 +1,49 @@
+package checks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckIntake(t *testing.T) {
+	policy, err := ReadIntakePolicy("testdata/intake/policy.yaml")
+	require.NoError(t, err)
+
+	found, err := IntakeOptions{Dir: "testdata/intake/head", Policy: policy}.CheckIntake()
+	require.NoError(t, err)
+	assert.Equal(t, UnapprovedSources{
+		{Package: "legacy", Kind: SourceRepository, Source: "https://legacy.example.com/apk", Path: "testdata/intake/head/legacy.yaml"},
+		{Package: "overlay", Kind: SourceRepository, Source: "https://apk.example.com/extras", Path: "testdata/intake/head/overlay.yaml"},
+		{Package: "overlay", Kind: SourceKeyring, Source: "https://apk.example.com/extras/extras.rsa.pub", Path: "testdata/intake/head/overlay.yaml"},
+	}, found)
+
+	// sources that were already used before the change don't need approval again
+	found, err = IntakeOptions{Dir: "testdata/intake/head", BaseDir: "testdata/intake/base", Policy: policy}.CheckIntake()
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, "overlay", found[0].Package)
+	assert.Equal(t, "overlay", found[1].Package)
+}
+
+func TestIntakePolicy_Approved(t *testing.T) {
+	p := IntakePolicy{
+		Repositories: []string{"https://packages.wolfi.dev/*"},
+		Keyring:      []string{"https://packages.wolfi.dev/os/wolfi-signing.rsa.pub"},
+	}
+	assert.True(t, p.Approved(SourceRepository, "https://packages.wolfi.dev/os"))
+	assert.False(t, p.Approved(SourceRepository, "https://packages.wolfi.dev/bootstrap/stage3"))
+	assert.False(t, p.Approved(SourceKeyring, "https://packages.wolfi.dev/os"))
+	assert.True(t, p.Approved(SourceKeyring, "https://packages.wolfi.dev/os/wolfi-signing.rsa.pub"))
+}
+
+func TestUnapprovedSourcesSARIF(t *testing.T) {
+	found := UnapprovedSources{
+		{Package: "overlay", Kind: SourceKeyring, Source: "https://apk.example.com/extras/extras.rsa.pub", Path: "testdata/intake/head/overlay.yaml"},
+	}
+	results := found.SARIF().Runs[0].Results
+	require.Len(t, results, 1)
+	assert.Equal(t, "intake/unapproved-source", results[0].RuleID)
+	assert.Equal(t, 14, results[0].Locations[0].PhysicalLocation.Region.StartLine)
+}
@@ -0,0 +1,187 @@
+package checks
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+
+	"github.com/wolfi-dev/wolfictl/pkg/license"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+	"github.com/wolfi-dev/wolfictl/pkg/sarif"
+)
+
+const (
+	ruleLicenseInvalid       = "license/invalid"
+	ruleLicenseNotNormalized = "license/not-normalized"
+	ruleLicenseMismatch      = "license/mismatch"
+)
+
+// LicenseFinding is a problem with the licenses a melange config declares in its copyright.
+type LicenseFinding struct {
+	Package string
+	// Rule is the kind of problem: license/invalid, license/not-normalized or license/mismatch.
+	Rule   string
+	Reason string
+
+	// Path is the melange config of the package.
+	Path string
+}
+
+func (f LicenseFinding) String() string {
+	return fmt.Sprintf("%s: %s", f.Package, f.Reason)
+}
+
+// LicenseFindings are the problems with the licenses of melange configs.
+type LicenseFindings []LicenseFinding
+
+// LicenseOptions configures the license check of the melange configs in Dir.
+type LicenseOptions struct {
+	Logger *log.Logger
+	Dir    string
+
+	// Packages, if not empty, restricts the check to these packages.
+	Packages []string
+
+	// Offline only checks that the declared licenses are normalized SPDX license expressions, without fetching the
+	// sources to compare them with the licenses they have.
+	Offline bool
+
+	// Fetch puts the sources of a package into a directory, melange.FetchSources if nil.
+	Fetch func(cfg *build.Configuration, dir string) error
+}
+
+// CheckLicenses returns the problems with the licenses the melange configs in Dir declare, sorted by package: licenses
+// that aren't SPDX license expressions of identifiers on the SPDX License List, or aren't written in their normalized
+// form, and, unless Offline, licenses that don't match the ones detected in the license files of the sources of the
+// package at its version. Packages whose sources can't be fetched are only checked offline.
+func (o LicenseOptions) CheckLicenses() (LicenseFindings, error) {
+	configs, err := melange.ReadAllPackagesFromRepo(o.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read melange configs from %s: %w", o.Dir, err)
+	}
+	fetch := o.Fetch
+	if fetch == nil {
+		fetch = melange.FetchSources
+	}
+
+	names := o.Packages
+	if len(names) == 0 {
+		for name := range configs {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var found LicenseFindings
+	for _, name := range names {
+		p, ok := configs[name]
+		if !ok {
+			return nil, fmt.Errorf("no melange config for package %s in %s", name, o.Dir)
+		}
+		cfg := &p.Config
+		path := filepath.Join(p.Dir, p.Filename)
+		findings, declared := DeclaredLicenseFindings(cfg)
+		if !o.Offline && len(declared) > 0 {
+			mismatches, err := o.mismatches(cfg, declared, fetch)
+			if err != nil {
+				o.Logger.Printf("skipping the sources of %s: %v", name, err)
+			}
+			findings = append(findings, mismatches...)
+		}
+		for _, f := range findings {
+			f.Path = path
+			found = append(found, f)
+		}
+	}
+	return found, nil
+}
+
+// DeclaredLicenseFindings returns the problems with the licenses of the copyright of cfg that show without its sources,
+// and the licenses that are valid SPDX license expressions.
+func DeclaredLicenseFindings(cfg *build.Configuration) (findings []LicenseFinding, valid []string) {
+	name := cfg.Package.Name
+	if len(cfg.Package.Copyright) == 0 {
+		return []LicenseFinding{{Package: name, Rule: ruleLicenseInvalid, Reason: "declares no copyright, so no license"}}, nil
+	}
+	for i, c := range cfg.Package.Copyright {
+		normalized, unknown, err := license.Normalize(c.License)
+		switch {
+		case err != nil:
+			findings = append(findings, LicenseFinding{Package: name, Rule: ruleLicenseInvalid, Reason: fmt.Sprintf("copyright[%d] license %q isn't an SPDX license expression: %v", i, c.License, err)})
+		case len(unknown) > 0:
+			findings = append(findings, LicenseFinding{Package: name, Rule: ruleLicenseInvalid, Reason: fmt.Sprintf("copyright[%d] license %q uses %s, not on the SPDX License List", i, c.License, strings.Join(unknown, ", "))})
+			valid = append(valid, normalized)
+		case normalized != c.License:
+			findings = append(findings, LicenseFinding{Package: name, Rule: ruleLicenseNotNormalized, Reason: fmt.Sprintf("copyright[%d] license %q is normally written %q", i, c.License, normalized)})
+			valid = append(valid, normalized)
+		default:
+			valid = append(valid, normalized)
+		}
+	}
+	return findings, valid
+}
+
+// mismatches fetches the sources of cfg, and compares the licenses detected in them with the declared ones
+func (o LicenseOptions) mismatches(cfg *build.Configuration, declared []string, fetch func(cfg *build.Configuration, dir string) error) ([]LicenseFinding, error) {
+	dir, err := os.MkdirTemp("", "wolfictl-src-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	if err := fetch(cfg, dir); err != nil {
+		return nil, err
+	}
+	detected, err := license.Detect(os.DirFS(dir))
+	if err != nil {
+		return nil, err
+	}
+	if len(detected) == 0 {
+		o.Logger.Printf("no license detected in the sources of %s", cfg.Package.Name)
+		return nil, nil
+	}
+
+	return LicenseMismatches(cfg.Package.Name, declared, detected)
+}
+
+// LicenseMismatches returns the licenses detected in the sources of a package that it doesn't declare, and the ones
+// it declares that weren't detected.
+func LicenseMismatches(name string, declared []string, detected []license.Detection) ([]LicenseFinding, error) {
+	undeclared, undetected, err := license.Mismatches(declared, detected)
+	if err != nil {
+		return nil, err
+	}
+	var findings []LicenseFinding
+	for _, d := range undeclared {
+		findings = append(findings, LicenseFinding{Package: name, Rule: ruleLicenseMismatch, Reason: fmt.Sprintf("the sources have %s in %s, which isn't declared", d.License, d.File)})
+	}
+	for _, l := range undetected {
+		findings = append(findings, LicenseFinding{Package: name, Rule: ruleLicenseMismatch, Reason: fmt.Sprintf("declares %s, which isn't in the license files of the sources", l)})
+	}
+	return findings, nil
+}
+
+// SARIF returns the findings as a SARIF log, located at the copyright of the configs of their packages.
+func (l LicenseFindings) SARIF() *sarif.Log {
+	log := sarif.New(sarif.Rule{
+		ID:                   ruleLicenseInvalid,
+		ShortDescription:     sarif.Message{Text: "licenses should be SPDX license expressions of identifiers on the SPDX License List"},
+		DefaultConfiguration: sarif.Configuration{Level: sarif.LevelError},
+	}, sarif.Rule{
+		ID:                   ruleLicenseNotNormalized,
+		ShortDescription:     sarif.Message{Text: "licenses should be written in their normalized form"},
+		DefaultConfiguration: sarif.Configuration{Level: sarif.LevelWarning},
+	}, sarif.Rule{
+		ID:                   ruleLicenseMismatch,
+		ShortDescription:     sarif.Message{Text: "declared licenses should match the ones of the sources"},
+		DefaultConfiguration: sarif.Configuration{Level: sarif.LevelWarning},
+	})
+	for _, f := range l {
+		log.Add(f.Rule, f.Reason, filepath.ToSlash(f.Path), sarif.LineOf(f.Path, "copyright:"))
+	}
+	return log
+}
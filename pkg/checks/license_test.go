@@ -0,0 +1,99 @@
+package checks
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mitLicense = `Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+`
+
+func TestCheckLicenses(t *testing.T) {
+	// the sources of all packages are MIT, those of sloppy have a GPL notice too
+	fetch := func(cfg *build.Configuration, dir string) error {
+		if err := os.WriteFile(filepath.Join(dir, "LICENSE"), []byte(mitLicense), 0o600); err != nil {
+			return err
+		}
+		if cfg.Package.Name != "sloppy" {
+			return nil
+		}
+		notice := "under the terms of the GNU General Public License as published by\nthe Free Software Foundation; either version 2 of the License, or\n(at your option) any later version.\n"
+		return os.WriteFile(filepath.Join(dir, "COPYING"), []byte(notice), 0o600)
+	}
+	o := LicenseOptions{Logger: log.New(io.Discard, "", 0), Dir: "testdata/license", Fetch: fetch}
+
+	found, err := o.CheckLicenses()
+	require.NoError(t, err)
+	assert.Equal(t, LicenseFindings{
+		{
+			Package: "sloppy",
+			Rule:    "license/not-normalized",
+			Reason:  `copyright[0] license "gpl-2.0+ and mit" is normally written "GPL-2.0-or-later AND MIT"`,
+			Path:    "testdata/license/sloppy.yaml",
+		},
+		{
+			Package: "wrong",
+			Rule:    "license/invalid",
+			Reason:  `copyright[1] license "MIT/X11" uses MIT/X11, not on the SPDX License List`,
+			Path:    "testdata/license/wrong.yaml",
+		},
+		{
+			Package: "wrong",
+			Rule:    "license/mismatch",
+			Reason:  "the sources have MIT in LICENSE, which isn't declared",
+			Path:    "testdata/license/wrong.yaml",
+		},
+		{
+			Package: "wrong",
+			Rule:    "license/mismatch",
+			Reason:  "declares Apache-2.0, which isn't in the license files of the sources",
+			Path:    "testdata/license/wrong.yaml",
+		},
+	}, found)
+
+	o.Offline = true
+	o.Packages = []string{"wrong"}
+	found, err = o.CheckLicenses()
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "license/invalid", found[0].Rule)
+
+	// packages whose sources can't be fetched are only checked offline
+	o.Offline = false
+	o.Packages = []string{"good"}
+	o.Fetch = func(*build.Configuration, string) error { return errors.New("not found") }
+	found, err = o.CheckLicenses()
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	o.Packages = []string{"missing"}
+	_, err = o.CheckLicenses()
+	assert.ErrorContains(t, err, "no melange config for package missing")
+}
+
+func TestLicenseFindingsSARIF(t *testing.T) {
+	o := LicenseOptions{Dir: "testdata/license", Offline: true, Packages: []string{"sloppy"}}
+	found, err := o.CheckLicenses()
+	require.NoError(t, err)
+
+	results := found.SARIF().Runs[0].Results
+	require.Len(t, results, 1)
+	assert.Equal(t, "license/not-normalized", results[0].RuleID)
+	assert.Equal(t, 6, results[0].Locations[0].PhysicalLocation.Region.StartLine)
+}
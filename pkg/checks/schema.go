@@ -0,0 +1,239 @@
+package checks
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+	"github.com/wolfi-dev/wolfictl/pkg/sarif"
+	"github.com/wolfi-dev/wolfictl/pkg/schema"
+)
+
+// SchemaPinFile pins the version of the melange JSON schema the melange configs of a repository are checked against.
+const SchemaPinFile = ".melange-schema.yaml"
+
+// melangeSchemaURL is where the JSON schema of melange configs is published, by melange release.
+const melangeSchemaURL = "https://raw.githubusercontent.com/chainguard-dev/melange/%s/pkg/config/schema.json"
+
+// SchemaPin is the melange JSON schema a repository of melange configs follows, e.g.
+//
+//	version: v0.5.0
+type SchemaPin struct {
+	// Version is the melange release whose schema the configs are checked against.
+	Version string `yaml:"version"`
+	// Schema, if set, is where the schema is read from instead, a URL or a path relative to the repository.
+	Schema string `yaml:"schema"`
+}
+
+// ReadSchemaPin reads the SchemaPinFile of a repository of melange configs, a repository without one has an empty pin.
+func ReadSchemaPin(dir string) (*SchemaPin, error) {
+	path := filepath.Join(dir, SchemaPinFile)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &SchemaPin{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p := &SchemaPin{}
+	if err := yaml.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("failed to parse schema pin %s: %w", path, err)
+	}
+	if p.Schema != "" && !isURL(p.Schema) && !filepath.IsAbs(p.Schema) {
+		p.Schema = filepath.Join(dir, p.Schema)
+	}
+	return p, nil
+}
+
+// Location returns where the schema of the pin is read from.
+func (p SchemaPin) Location() (string, error) {
+	switch {
+	case p.Schema != "":
+		return p.Schema, nil
+	case p.Version != "":
+		return fmt.Sprintf(melangeSchemaURL, p.Version), nil
+	}
+	return "", fmt.Errorf("no melange schema version pinned, add one to %s or set it with --schema-version", SchemaPinFile)
+}
+
+// SchemaOptions configures the schema check of the melange configs in Dir.
+type SchemaOptions struct {
+	Dir string
+	// Version and Schema override the SchemaPin of Dir.
+	Version string
+	Schema  string
+	Client  *http.Client
+}
+
+// SchemaViolation is a part of a melange config that doesn't conform to the melange JSON schema.
+type SchemaViolation struct {
+	schema.Violation
+	// File is the melange config.
+	File string
+}
+
+func (v SchemaViolation) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", v.File, v.Line, v.Column, v.Violation)
+}
+
+// SchemaViolations are the schema violations of the melange configs of a repository.
+type SchemaViolations []SchemaViolation
+
+// CheckSchema validates the melange configs in Dir against the melange JSON schema the repository pins, returning the
+// violations sorted by file and line. It also returns where the schema was read from.
+func (o SchemaOptions) CheckSchema() (violations SchemaViolations, location string, err error) {
+	pin, err := ReadSchemaPin(o.Dir)
+	if err != nil {
+		return nil, "", err
+	}
+	if o.Version != "" {
+		pin = &SchemaPin{Version: o.Version}
+	}
+	if o.Schema != "" {
+		pin = &SchemaPin{Schema: o.Schema}
+	}
+	location, err = pin.Location()
+	if err != nil {
+		return nil, "", err
+	}
+	s, err := o.readSchema(location)
+	if err != nil {
+		return nil, "", err
+	}
+	// the melange schema forbids unknown fields, like the ones wolfictl adds to the update block
+	for path, fields := range melange.ExtensionFields() {
+		if err := s.Allow(path, fields...); err != nil {
+			return nil, "", err
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(o.Dir, "*.yaml"))
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		// dotfiles, like the schema pin, aren't melange configs
+		if strings.HasPrefix(filepath.Base(f), ".") {
+			continue
+		}
+		found, err := validateConfig(s, f)
+		if err != nil {
+			return nil, "", err
+		}
+		violations = append(violations, found...)
+	}
+	return violations, location, nil
+}
+
+// validateConfig validates the file if it's a melange config.
+func validateConfig(s *schema.Schema, file string) ([]SchemaViolation, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(b, doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	if !isMelangeConfig(doc) {
+		return nil, nil
+	}
+	found, err := s.Validate(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate %s: %w", file, err)
+	}
+	violations := make([]SchemaViolation, 0, len(found))
+	for _, v := range found {
+		violations = append(violations, SchemaViolation{Violation: v, File: file})
+	}
+	return violations, nil
+}
+
+// isMelangeConfig reports whether a YAML document has a package block, which other YAML files of a repository of
+// melange configs, like the intake policy, don't.
+func isMelangeConfig(doc *yaml.Node) bool {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return false
+	}
+	m := doc.Content[0]
+	for i := 0; i < len(m.Content); i += 2 {
+		if m.Content[i].Value == "package" {
+			return true
+		}
+	}
+	return false
+}
+
+func (o SchemaOptions) readSchema(location string) (*schema.Schema, error) {
+	if !isURL(location) {
+		b, err := os.ReadFile(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read melange schema: %w", err)
+		}
+		return schema.Parse(b)
+	}
+
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get melange schema %s: %w", location, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get melange schema %s: %s", location, resp.Status)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read melange schema %s: %w", location, err)
+	}
+	return schema.Parse(b)
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://")
+}
+
+// schemaRules describe the kinds of violations, in the order they're reported to SARIF.
+var schemaRules = []struct {
+	kind        schema.Kind
+	description string
+}{
+	{schema.KindUnknownField, "melange configs can only use the fields of the melange schema"},
+	{schema.KindTypeMismatch, "values in melange configs must have the type the melange schema expects"},
+	{schema.KindMissingField, "melange configs must set the fields the melange schema requires"},
+	{schema.KindInvalidValue, "values in melange configs must be allowed by the melange schema"},
+}
+
+func schemaRuleID(kind schema.Kind) string {
+	return "schema/" + string(kind)
+}
+
+// SARIF returns the violations as a SARIF log, located at their lines.
+func (v SchemaViolations) SARIF() *sarif.Log {
+	rules := make([]sarif.Rule, 0, len(schemaRules))
+	for _, r := range schemaRules {
+		rules = append(rules, sarif.Rule{
+			ID:                   schemaRuleID(r.kind),
+			ShortDescription:     sarif.Message{Text: r.description},
+			DefaultConfiguration: sarif.Configuration{Level: sarif.LevelError},
+		})
+	}
+	log := sarif.New(rules...)
+	for _, s := range v {
+		log.Add(schemaRuleID(s.Kind), s.Violation.String(), filepath.ToSlash(s.File), s.Line)
+	}
+	return log
+}
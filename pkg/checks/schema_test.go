@@ -0,0 +1,68 @@
+package checks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSchema(t *testing.T) {
+	found, location, err := SchemaOptions{Dir: "testdata/schema"}.CheckSchema()
+	require.NoError(t, err)
+	assert.Equal(t, "testdata/schema/schema.json", location)
+
+	var got []string
+	for _, v := range found {
+		got = append(got, v.String())
+	}
+	// good.yaml uses the update monitors and the package.cpe wolfictl adds to melange configs, which the schema forbids
+	assert.Equal(t, []string{
+		`testdata/schema/bad.yaml:2:3: package: missing required field "epoch"`,
+		`testdata/schema/bad.yaml:3:12: package.version: must be of type string: "number"`,
+		`testdata/schema/bad.yaml:6:7: package.target-architecture[0]: should be one of [all x86_64 aarch64]`,
+		`testdata/schema/bad.yaml:7:3: package: unknown field "license"`,
+		`testdata/schema/bad.yaml:13:25: pipeline[0].with.strip-components: must be of type string: "number"`,
+		`testdata/schema/bad.yaml:16:9: pipeline[1].pipeline[0]: unknown field "workdir"`,
+		`testdata/schema/bad.yaml:19:12: update.enabled: must be of type boolean: "string"`,
+	}, got)
+}
+
+func TestCheckSchema_version(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata/schema")))
+	defer server.Close()
+
+	// the schema flag overrides the pin
+	found, location, err := SchemaOptions{Dir: "testdata/schema", Schema: server.URL + "/schema.json", Client: server.Client()}.CheckSchema()
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/schema.json", location)
+	assert.Len(t, found, 7)
+
+	_, _, err = SchemaOptions{Dir: "testdata/schema", Schema: server.URL + "/nope.json", Client: server.Client()}.CheckSchema()
+	assert.ErrorContains(t, err, "404 Not Found")
+
+	// released schemas are published on GitHub
+	pin := SchemaPin{Version: "v0.5.0"}
+	location, err = pin.Location()
+	require.NoError(t, err)
+	assert.Equal(t, "https://raw.githubusercontent.com/chainguard-dev/melange/v0.5.0/pkg/config/schema.json", location)
+
+	// repositories without a pin need one
+	_, _, err = SchemaOptions{Dir: "testdata/intake/head"}.CheckSchema()
+	assert.ErrorContains(t, err, "no melange schema version pinned")
+}
+
+func TestSchemaViolationsSARIF(t *testing.T) {
+	found, _, err := SchemaOptions{Dir: "testdata/schema"}.CheckSchema()
+	require.NoError(t, err)
+
+	results := found.SARIF().Runs[0].Results
+	require.Len(t, results, 7)
+	assert.Equal(t, "schema/missing-field", results[0].RuleID)
+	assert.Equal(t, "schema/type-mismatch", results[1].RuleID)
+	assert.Equal(t, 1, results[1].RuleIndex)
+	assert.Equal(t, 3, results[1].Locations[0].PhysicalLocation.Region.StartLine)
+	assert.Equal(t, "testdata/schema/bad.yaml", results[1].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+}
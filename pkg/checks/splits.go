@@ -0,0 +1,325 @@
+package checks
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+
+	"github.com/wolfi-dev/wolfictl/pkg/configs/yamledit"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+type SplitsOptions struct {
+	Logger      *log.Logger
+	Dir         string
+	PackagesDir string
+	Arch        string
+	// Packages are the packages to check, all the packages of Dir if empty.
+	Packages []string
+}
+
+// Split is a standard subpackage that a package could be split into.
+type Split struct {
+	// Suffix is appended to the name of the package to name the subpackage, e.g. dev.
+	Suffix string
+	// Pipelines are the split pipelines of melange the subpackage runs to move its files out of the package.
+	Pipelines []string
+	// Files are the files of the package the subpackage would get.
+	Files []string
+}
+
+// SuggestedSplits are the standard subpackages suggested for a package, and the patch adding them to its melange config.
+type SuggestedSplits struct {
+	Package string
+	Splits  []Split
+	// Patch is a unified diff of the melange config of the package, relative to Dir.
+	Patch string
+}
+
+// APKEntry is a file of an apk, other than its metadata.
+type APKEntry struct {
+	// Path is the path of the file, without a leading slash.
+	Path    string
+	Symlink bool
+}
+
+// standardSplits are the standard subpackages and their split pipelines, in the order they're added, which is the order
+// they run in: static before dev, which would otherwise get the static libraries too
+var standardSplits = []struct {
+	suffix    string
+	pipelines []string
+}{
+	{"static", []string{"split/static"}},
+	{"dev", []string{"split/dev"}},
+	{"doc", []string{"split/manpages", "split/infodir"}},
+	{"lang", []string{"split/locales"}},
+}
+
+// splitMoves reports whether the split pipelines move a file of a package
+var splitMoves = map[string]func(e APKEntry) bool{
+	"split/static":   func(e APKEntry) bool { return isStaticLibrary(e.Path) },
+	"split/dev":      isDevFile,
+	"split/manpages": func(e APKEntry) bool { return strings.HasPrefix(e.Path, "usr/share/man/") },
+	"split/infodir":  func(e APKEntry) bool { return strings.HasPrefix(e.Path, "usr/share/info/") },
+	"split/locales":  func(e APKEntry) bool { return strings.HasPrefix(e.Path, "usr/share/locale/") },
+}
+
+// devFiles are the prefixes of the files split/dev moves, other than headers, static libraries and .so links
+var devFiles = []string{
+	"usr/include/", "usr/lib/pkgconfig/", "usr/share/pkgconfig/", "usr/share/aclocal/", "usr/share/gettext/",
+	"usr/share/vala/vapi/", "usr/lib/cmake/",
+}
+
+// CheckSplits suggests splitting the packages built from the melange configs of Dir, whose apks are in PackagesDir,
+// into the standard subpackages for the files they ship that split pipelines of melange would move: development
+// files, static libraries, documentation and translations. Packages that weren't built are skipped.
+func (o SplitsOptions) CheckSplits() ([]SuggestedSplits, error) {
+	configs, err := melange.ReadAllPackagesFromRepo(o.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read melange configs from %s: %w", o.Dir, err)
+	}
+	names := o.Packages
+	if len(names) == 0 {
+		for name := range configs {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var suggested []SuggestedSplits
+	for _, name := range names {
+		p, ok := configs[name]
+		if !ok {
+			return nil, fmt.Errorf("no melange config for package %s", name)
+		}
+		cfg := &p.Config
+		if cfg.Package.Name != name {
+			// a subpackage
+			continue
+		}
+
+		filename := fmt.Sprintf("%s-%s-r%d.apk", name, cfg.Package.Version, cfg.Package.Epoch)
+		apk, err := os.Open(filepath.Join(o.PackagesDir, o.Arch, filename))
+		if errors.Is(err, fs.ErrNotExist) {
+			if len(o.Packages) > 0 {
+				o.Logger.Printf("skipping %s, %s wasn't built", name, filename)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries, err := ReadAPKEntries(apk)
+		apk.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+		}
+
+		splits := SuggestSplits(cfg, entries)
+		if len(splits) == 0 {
+			continue
+		}
+		src, err := os.ReadFile(filepath.Join(p.Dir, p.Filename))
+		if err != nil {
+			return nil, err
+		}
+		patched, err := AddSplits(src, cfg, splits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add subpackages to %s: %w", p.Filename, err)
+		}
+		patch, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(src)),
+			B:        difflib.SplitLines(string(patched)),
+			FromFile: "a/" + p.Filename,
+			ToFile:   "b/" + p.Filename,
+			Context:  3,
+		})
+		if err != nil {
+			return nil, err
+		}
+		suggested = append(suggested, SuggestedSplits{Package: name, Splits: splits, Patch: patch})
+	}
+	return suggested, nil
+}
+
+// ReadAPKEntries returns the files of an apk, leaving out its signature, its metadata and its SBOM.
+func ReadAPKEntries(r io.Reader) ([]APKEntry, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	// the signature, control and data sections are gzipped tar streams one after the other, which read as one
+	tr := tar.NewReader(zr)
+	var entries []APKEntry
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(path.Clean(header.Name), "/")
+		if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "var/lib/db/sbom/") {
+			continue
+		}
+		switch header.Typeflag {
+		case tar.TypeReg:
+			entries = append(entries, APKEntry{Path: name})
+		case tar.TypeSymlink:
+			entries = append(entries, APKEntry{Path: name, Symlink: true})
+		}
+	}
+	return entries, nil
+}
+
+// SuggestSplits returns the standard subpackages the split pipelines of melange would move some of the files of a
+// package into. Subpackages the config already has, with the same name or split pipelines, aren't suggested, nor are
+// the ones that would get all the files of the package.
+func SuggestSplits(cfg *build.Configuration, entries []APKEntry) []Split {
+	existing := make(map[string]bool)
+	for _, name := range subpackageNames(cfg) {
+		existing[name] = true
+	}
+	for i := range cfg.Subpackages {
+		for _, step := range cfg.Subpackages[i].Pipeline {
+			existing[step.Uses] = true
+		}
+	}
+
+	moved := make(map[string]bool)
+	var splits []Split
+	for _, s := range standardSplits {
+		if existing[cfg.Package.Name+"-"+s.suffix] {
+			continue
+		}
+		split := Split{Suffix: s.suffix}
+		for _, pipeline := range s.pipelines {
+			if existing[pipeline] {
+				continue
+			}
+			var files []string
+			for _, e := range entries {
+				if !moved[e.Path] && splitMoves[pipeline](e) {
+					files = append(files, e.Path)
+				}
+			}
+			if len(files) > 0 {
+				split.Pipelines = append(split.Pipelines, pipeline)
+				split.Files = append(split.Files, files...)
+			}
+		}
+		if len(split.Files) == 0 || len(split.Files)+len(moved) == len(entries) {
+			continue
+		}
+		for _, f := range split.Files {
+			moved[f] = true
+		}
+		splits = append(splits, split)
+	}
+	return splits
+}
+
+// splitSubpackage is the subpackage of a Split in a melange config
+type splitSubpackage struct {
+	Name         string             `yaml:"name"`
+	Description  string             `yaml:"description,omitempty"`
+	Dependencies *splitDependencies `yaml:"dependencies,omitempty"`
+	Pipeline     []splitStep        `yaml:"pipeline"`
+}
+
+type splitDependencies struct {
+	Runtime []string `yaml:"runtime"`
+}
+
+type splitStep struct {
+	Uses string `yaml:"uses"`
+}
+
+// AddSplits adds the subpackages of splits to the melange config src of cfg, keeping its formatting. The -dev
+// subpackage depends on the package at runtime, like its headers' libraries.
+func AddSplits(src []byte, cfg *build.Configuration, splits []Split) ([]byte, error) {
+	doc, err := yamledit.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Root.Kind != yaml.DocumentNode || len(doc.Root.Content) != 1 || doc.Root.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("not a melange config")
+	}
+	root := doc.Root.Content[0]
+
+	var subpackages *yaml.Node
+	insertAt := len(root.Content)
+	for i := 0; i < len(root.Content); i += 2 {
+		switch root.Content[i].Value {
+		case "subpackages":
+			subpackages = root.Content[i+1]
+		case "pipeline":
+			insertAt = i + 2
+		}
+	}
+	if subpackages == nil || subpackages.Kind != yaml.SequenceNode {
+		if subpackages != nil {
+			return nil, errors.New("subpackages isn't a list")
+		}
+		subpackages = &yaml.Node{Kind: yaml.SequenceNode}
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: "subpackages"}
+		root.Content = append(root.Content[:insertAt], append([]*yaml.Node{key, subpackages}, root.Content[insertAt:]...)...)
+	}
+
+	for _, s := range splits {
+		sub := splitSubpackage{Name: cfg.Package.Name + "-" + s.Suffix}
+		if cfg.Package.Description != "" {
+			sub.Description = fmt.Sprintf("%s (%s)", cfg.Package.Description, s.Suffix)
+		}
+		if s.Suffix == "dev" {
+			sub.Dependencies = &splitDependencies{Runtime: []string{cfg.Package.Name}}
+		}
+		for _, p := range s.Pipelines {
+			sub.Pipeline = append(sub.Pipeline, splitStep{Uses: p})
+		}
+		n := &yaml.Node{}
+		if err := n.Encode(sub); err != nil {
+			return nil, err
+		}
+		subpackages.Content = append(subpackages.Content, n)
+	}
+	return doc.Bytes()
+}
+
+// isStaticLibrary reports whether a file is a static library split/static moves
+func isStaticLibrary(p string) bool {
+	return path.Ext(p) == ".a" && (strings.HasPrefix(p, "lib/") || strings.HasPrefix(p, "usr/"))
+}
+
+// isDevFile reports whether split/dev moves a file: headers, pkg-config and CMake files, *-config scripts, the .so
+// links used to link with libraries, and static libraries
+func isDevFile(e APKEntry) bool {
+	for _, prefix := range devFiles {
+		if strings.HasPrefix(e.Path, prefix) {
+			return true
+		}
+	}
+	dir, base := path.Split(e.Path)
+	switch {
+	case dir == "usr/bin/" && strings.HasSuffix(base, "-config"):
+		return true
+	case e.Symlink && path.Ext(base) == ".so" && (dir == "lib/" || dir == "usr/lib/"):
+		return true
+	}
+	return isStaticLibrary(e.Path)
+}
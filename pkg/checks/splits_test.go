@@ -0,0 +1,126 @@
+package checks
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSplits(t *testing.T) {
+	packagesDir := t.TempDir()
+	apk, err := os.ReadFile(filepath.Join("testdata", "hello-wolfi-2.12-r1.apk"))
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(packagesDir, "aarch64"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(packagesDir, "aarch64", "hello-wolfi-2.12-r1.apk"), apk, 0o600))
+
+	o := SplitsOptions{Logger: log.New(io.Discard, "", 0), Dir: "testdata/splits", PackagesDir: packagesDir, Arch: "aarch64"}
+	suggested, err := o.CheckSplits()
+	require.NoError(t, err)
+	require.Len(t, suggested, 1)
+	assert.Equal(t, "hello-wolfi", suggested[0].Package)
+	require.Len(t, suggested[0].Splits, 2)
+	assert.Equal(t, []string{"split/infodir"}, suggested[0].Splits[0].Pipelines)
+	assert.Equal(t, []string{"usr/share/info/hello.info"}, suggested[0].Splits[0].Files)
+	assert.Equal(t, []string{"split/locales"}, suggested[0].Splits[1].Pipelines)
+	// the context lines of blank lines are a single space
+	assert.Equal(t, strings.Join([]string{
+		"--- a/hello-wolfi.yaml",
+		"+++ b/hello-wolfi.yaml",
+		"@@ -25,6 +25,16 @@",
+		" ",
+		"   - uses: autoconf/make-install",
+		" ",
+		"+subpackages:",
+		"+  - name: hello-wolfi-doc",
+		"+    description: the GNU hello world program (doc)",
+		"+    pipeline:",
+		"+      - uses: split/infodir",
+		"+  - name: hello-wolfi-lang",
+		"+    description: the GNU hello world program (lang)",
+		"+    pipeline:",
+		"+      - uses: split/locales",
+		"+",
+		" update:",
+		"   enabled: true",
+		"   release-monitor:",
+		"",
+	}, "\n"), suggested[0].Patch)
+
+	// not built
+	suggested, err = SplitsOptions{Logger: log.New(io.Discard, "", 0), Dir: "testdata/splits", PackagesDir: t.TempDir(), Arch: "aarch64"}.CheckSplits()
+	require.NoError(t, err)
+	assert.Empty(t, suggested)
+}
+
+func TestReadAPKEntries(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "hello-wolfi-2.12-r1.apk"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	entries, err := ReadAPKEntries(f)
+	require.NoError(t, err)
+	assert.Equal(t, APKEntry{Path: "usr/bin/hello"}, entries[0])
+	assert.Equal(t, APKEntry{Path: "usr/share/info/hello.info"}, entries[1])
+	for _, e := range entries[2:] {
+		assert.Regexp(t, `^usr/share/locale/[^/]+/LC_MESSAGES/hello\.mo$`, e.Path)
+	}
+}
+
+func TestSuggestSplits(t *testing.T) {
+	library := []APKEntry{
+		{Path: "usr/lib/libfoo.so.1.2.3"},
+		{Path: "usr/lib/libfoo.so.1", Symlink: true},
+		{Path: "usr/lib/libfoo.so", Symlink: true},
+		{Path: "usr/lib/libfoo.a"},
+		{Path: "usr/include/foo.h"},
+		{Path: "usr/lib/pkgconfig/foo.pc"},
+		{Path: "usr/share/man/man3/foo.3"},
+	}
+	tests := []struct {
+		name        string
+		subpackages []build.Subpackage
+		entries     []APKEntry
+		want        []Split
+	}{
+		{
+			name:    "library",
+			entries: library,
+			want: []Split{
+				{Suffix: "static", Pipelines: []string{"split/static"}, Files: []string{"usr/lib/libfoo.a"}},
+				{Suffix: "dev", Pipelines: []string{"split/dev"}, Files: []string{"usr/lib/libfoo.so", "usr/include/foo.h", "usr/lib/pkgconfig/foo.pc"}},
+				{Suffix: "doc", Pipelines: []string{"split/manpages"}, Files: []string{"usr/share/man/man3/foo.3"}},
+			},
+		},
+		{
+			name: "existing subpackages",
+			subpackages: []build.Subpackage{
+				{Name: "${{package.name}}-dev"},
+				{Name: "foo-manpages", Pipeline: []build.Pipeline{{Uses: "split/manpages"}}},
+			},
+			entries: library,
+			want: []Split{
+				{Suffix: "static", Pipelines: []string{"split/static"}, Files: []string{"usr/lib/libfoo.a"}},
+			},
+		},
+		{
+			name: "headers only",
+			entries: []APKEntry{
+				{Path: "usr/include/foo.h"},
+				{Path: "usr/lib/pkgconfig/foo.pc"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &build.Configuration{Package: build.Package{Name: "foo"}, Subpackages: tt.subpackages}
+			assert.Equal(t, tt.want, SuggestSplits(cfg, tt.entries))
+		})
+	}
+}
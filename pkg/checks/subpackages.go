@@ -0,0 +1,212 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/pkg/errors"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/index"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+	"github.com/wolfi-dev/wolfictl/pkg/sarif"
+)
+
+type SubpackagesOptions struct {
+	Logger      *log.Logger
+	Dir         string
+	PackagesDir string
+	Repo        string
+	Arch        string
+}
+
+// StaleSubpackage is a subpackage that a melange config and the packages actually built from it disagree on.
+type StaleSubpackage struct {
+	Origin  string
+	Name    string
+	Version string
+
+	// Path is the melange config of the origin package.
+	Path string
+}
+
+func (s StaleSubpackage) String() string {
+	return fmt.Sprintf("%s-%s (from %s)", s.Name, s.Version, s.Origin)
+}
+
+// StaleSubpackages reports the subpackages that melange configs and the packages built from them disagree on.
+type StaleSubpackages struct {
+	// NeverProduced are subpackages of the current version of a config that was built, that neither the index nor
+	// the last build has, typically because their split rules don't match anything anymore.
+	NeverProduced []StaleSubpackage
+
+	// Undeclared are subpackages in the index that their origin package doesn't declare anymore.
+	Undeclared []StaleSubpackage
+}
+
+func (s StaleSubpackages) Empty() bool {
+	return len(s.NeverProduced) == 0 && len(s.Undeclared) == 0
+}
+
+func (s StaleSubpackages) Write(w io.Writer) error {
+	for _, section := range []struct {
+		title string
+		subs  []StaleSubpackage
+	}{
+		{"declared but never produced", s.NeverProduced},
+		{"in the index but no longer declared", s.Undeclared},
+	} {
+		if len(section.subs) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%d subpackages %s:\n", len(section.subs), section.title); err != nil {
+			return err
+		}
+		for _, sub := range section.subs {
+			if _, err := fmt.Fprintf(w, "  %s\n", sub); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func NewSubpackages() *SubpackagesOptions {
+	return &SubpackagesOptions{
+		Logger: log.New(log.Writer(), "wolfictl check stale-subpackages: ", log.LstdFlags|log.Lmsgprefix),
+	}
+}
+
+// CheckSubpackages compares the subpackages declared in the melange configs of Dir with the published index of Repo
+// and the apks of the last build in PackagesDir.
+func (o *SubpackagesOptions) CheckSubpackages(ctx context.Context) (StaleSubpackages, error) {
+	configs, err := melange.ReadAllPackagesFromRepo(o.Dir)
+	if err != nil {
+		return StaleSubpackages{}, errors.Wrapf(err, "failed to read melange configs from %s", o.Dir)
+	}
+	cfgs := make([]*melange.Packages, 0, len(configs))
+	for _, p := range configs {
+		cfgs = append(cfgs, p)
+	}
+
+	idx, err := index.Index(ctx, o.Arch, o.Repo)
+	if err != nil {
+		return StaleSubpackages{}, errors.Wrapf(err, "failed to get the index of %s", o.Repo)
+	}
+
+	produced := func(name, version string) bool {
+		_, err := os.Stat(filepath.Join(o.PackagesDir, o.Arch, fmt.Sprintf("%s-%s.apk", name, version)))
+		return err == nil
+	}
+	return FindStaleSubpackages(cfgs, idx, produced), nil
+}
+
+// FindStaleSubpackages compares the subpackages declared in cfgs with the packages in idx, and the ones produced by
+// the last build. Versions passed to produced include the epoch, e.g. 1.2.3-r1.
+func FindStaleSubpackages(cfgs []*melange.Packages, idx *repository.ApkIndex, produced func(name, version string) bool) StaleSubpackages {
+	indexed := make(map[string]bool)
+	// the latest version in the index of every subpackage, by origin
+	indexedSubs := make(map[string]map[string]string)
+	for _, p := range idx.Packages {
+		indexed[p.Name+"-"+p.Version] = true
+		if p.Origin == "" || p.Origin == p.Name {
+			continue
+		}
+		if indexedSubs[p.Origin] == nil {
+			indexedSubs[p.Origin] = make(map[string]string)
+		}
+		if v, ok := indexedSubs[p.Origin][p.Name]; !ok || dag.CompareVersions(p.Version, v) > 0 {
+			indexedSubs[p.Origin][p.Name] = p.Version
+		}
+	}
+	exists := func(name, version string) bool {
+		return indexed[name+"-"+version] || produced(name, version)
+	}
+
+	var stale StaleSubpackages
+	for _, p := range cfgs {
+		cfg := &p.Config
+		path := filepath.Join(p.Dir, p.Filename)
+		version := fmt.Sprintf("%s-r%d", cfg.Package.Version, cfg.Package.Epoch)
+
+		declared := make(map[string]bool)
+		built := exists(cfg.Package.Name, version)
+		for _, name := range subpackageNames(cfg) {
+			declared[name] = true
+			if built && !exists(name, version) {
+				stale.NeverProduced = append(stale.NeverProduced, StaleSubpackage{
+					Origin:  cfg.Package.Name,
+					Name:    name,
+					Version: version,
+					Path:    path,
+				})
+			}
+		}
+
+		for name, v := range indexedSubs[cfg.Package.Name] {
+			if !declared[name] {
+				stale.Undeclared = append(stale.Undeclared, StaleSubpackage{
+					Origin:  cfg.Package.Name,
+					Name:    name,
+					Version: v,
+					Path:    path,
+				})
+			}
+		}
+	}
+
+	for _, subs := range [][]StaleSubpackage{stale.NeverProduced, stale.Undeclared} {
+		sort.Slice(subs, func(i, j int) bool {
+			if subs[i].Origin != subs[j].Origin {
+				return subs[i].Origin < subs[j].Origin
+			}
+			return subs[i].Name < subs[j].Name
+		})
+	}
+	return stale
+}
+
+// subpackageNames returns the names of the subpackages cfg declares, with package variables substituted.
+func subpackageNames(cfg *build.Configuration) []string {
+	names := make([]string, 0, len(cfg.Subpackages))
+	for i := range cfg.Subpackages {
+		names = append(names, strings.NewReplacer(
+			"${{package.name}}", cfg.Package.Name,
+			"${{package.version}}", cfg.Package.Version,
+		).Replace(cfg.Subpackages[i].Name))
+	}
+	return names
+}
+
+const (
+	ruleNeverProduced = "stale-subpackages/never-produced"
+	ruleUndeclared    = "stale-subpackages/undeclared"
+)
+
+// SARIF returns the stale subpackages as a SARIF log, located at the configs of their origin packages.
+func (s StaleSubpackages) SARIF() *sarif.Log {
+	log := sarif.New(sarif.Rule{
+		ID:                   ruleNeverProduced,
+		ShortDescription:     sarif.Message{Text: "declared subpackages should be produced by the build"},
+		DefaultConfiguration: sarif.Configuration{Level: sarif.LevelWarning},
+	}, sarif.Rule{
+		ID:                   ruleUndeclared,
+		ShortDescription:     sarif.Message{Text: "subpackages in the index should still be declared by their origin package"},
+		DefaultConfiguration: sarif.Configuration{Level: sarif.LevelWarning},
+	})
+	for _, sub := range s.NeverProduced {
+		log.Add(ruleNeverProduced, fmt.Sprintf("subpackage %s-%s is declared but was never produced", sub.Name, sub.Version), filepath.ToSlash(sub.Path), sarif.LineOf(sub.Path, sub.Name))
+	}
+	for _, sub := range s.Undeclared {
+		log.Add(ruleUndeclared, fmt.Sprintf("subpackage %s-%s is in the index but %s no longer declares it", sub.Name, sub.Version, sub.Origin), filepath.ToSlash(sub.Path), sarif.LineOf(sub.Path, "subpackages:"))
+	}
+	return log
+}
@@ -0,0 +1,69 @@
+package checks
+
+import (
+	"bytes"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+func TestFindStaleSubpackages(t *testing.T) {
+	cfgs := []*melange.Packages{{
+		Config: build.Configuration{
+			Package: build.Package{Name: "bind", Version: "1.2.3", Epoch: 1},
+			Subpackages: []build.Subpackage{
+				{Name: "${{package.name}}-dev"},
+				{Name: "bind-doc"},
+				{Name: "bind-utils"},
+			},
+		},
+		Dir:      "testdata",
+		Filename: "subpackages_melange.yaml",
+	}, {
+		// not built yet, so its subpackages can't be missing
+		Config: build.Configuration{
+			Package:     build.Package{Name: "grape", Version: "2.0.0"},
+			Subpackages: []build.Subpackage{{Name: "grape-dev"}},
+		},
+		Dir:      "testdata",
+		Filename: "grape.yaml",
+	}}
+
+	idx := &repository.ApkIndex{Packages: []*repository.Package{
+		{Name: "bind", Version: "1.2.3-r1", Origin: "bind"},
+		{Name: "bind-dev", Version: "1.2.3-r1", Origin: "bind"},
+		{Name: "bind-libs", Version: "1.2.2-r0", Origin: "bind"},
+		{Name: "bind-libs", Version: "1.2.10-r0", Origin: "bind"},
+		{Name: "grape-dev", Version: "1.0.0-r0", Origin: "grape"},
+	}}
+	produced := func(name, version string) bool {
+		return name == "bind-doc" && version == "1.2.3-r1"
+	}
+
+	stale := FindStaleSubpackages(cfgs, idx, produced)
+	assert.Equal(t, []StaleSubpackage{{Origin: "bind", Name: "bind-utils", Version: "1.2.3-r1", Path: "testdata/subpackages_melange.yaml"}}, stale.NeverProduced)
+	assert.Equal(t, []StaleSubpackage{{Origin: "bind", Name: "bind-libs", Version: "1.2.10-r0", Path: "testdata/subpackages_melange.yaml"}}, stale.Undeclared)
+
+	var b bytes.Buffer
+	require.NoError(t, stale.Write(&b))
+	assert.Equal(t, `1 subpackages declared but never produced:
+  bind-utils-1.2.3-r1 (from bind)
+1 subpackages in the index but no longer declared:
+  bind-libs-1.2.10-r0 (from bind)
+`, b.String())
+
+	log := stale.SARIF()
+	results := log.Runs[0].Results
+	require.Len(t, results, 2)
+	assert.Equal(t, "stale-subpackages/never-produced", results[0].RuleID)
+	assert.Equal(t, "testdata/subpackages_melange.yaml", results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 1, results[0].Locations[0].PhysicalLocation.Region.StartLine)
+	assert.Equal(t, "stale-subpackages/undeclared", results[1].RuleID)
+	assert.Equal(t, 1, results[1].RuleIndex)
+	assert.Equal(t, 7, results[1].Locations[0].PhysicalLocation.Region.StartLine)
+}
@@ -0,0 +1,402 @@
+package checks
+
+import (
+	"bufio"
+	"context"
+	"debug/elf"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/wolfi-dev/wolfictl/pkg/builder"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/tar"
+)
+
+// installedDB is where apk records the packages installed into a root, and their files
+const installedDB = "lib/apk/db/installed"
+
+type UnusedDepsOptions struct {
+	Logger *log.Logger
+	// Dir is the directory of melange configs, with the Makefile that builds them.
+	Dir string
+	// Repo is the local repository the package is built into, and whose packages its build environment can use.
+	Repo       string
+	SigningKey string
+	Arch       string
+	// Ignore are environment packages that are never reported, e.g. because builds need them without reading
+	// their files.
+	Ignore []string
+	// Output receives the output of the build.
+	Output io.Writer
+}
+
+// InstalledPackage is a package of an apk installed database.
+type InstalledPackage struct {
+	Name     string
+	Depends  []string
+	Provides []string
+	// Files are the paths of the files of the package, from the root it's installed in.
+	Files []string
+}
+
+// CheckUnusedDeps builds a package, tracing the files its build accesses with strace, and returns the packages of its
+// build environment that the build doesn't appear to need: neither their files, nor those of the dependencies only
+// they pull into the environment, were accessed by the build or are libraries linked by what it built.
+func (o UnusedDepsOptions) CheckUnusedDeps(ctx context.Context, name string) ([]string, error) {
+	if _, err := exec.LookPath("strace"); err != nil {
+		return nil, fmt.Errorf("strace is needed to trace the build: %w", err)
+	}
+	pkgs, err := dag.NewPackages(os.DirFS(o.Dir), o.Dir)
+	if err != nil {
+		return nil, err
+	}
+	configs := pkgs.Config(name, true)
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no melange config for package %s", name)
+	}
+	cfg := configs[0]
+	t := builder.Task{Config: cfg, Arch: o.Arch, Output: o.Output}
+
+	guest, err := os.MkdirTemp("", "wolfictl-guest-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(guest)
+	o.Logger.Printf("assembling the build environment of %s", name)
+	if err := builder.AssembleGuest(t, o.Repo, o.SigningKey, guest); err != nil {
+		return nil, fmt.Errorf("failed to assemble build environment of %s: %w", name, err)
+	}
+	f, err := os.Open(filepath.Join(guest, installedDB))
+	if err != nil {
+		return nil, err
+	}
+	installed, err := ReadInstalledDB(f)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the packages of the build environment: %w", err)
+	}
+	// the files are looked up in a copy of the build environment melange assembles, which has the same packages
+	links := symlinks(guest, installed)
+
+	trace, err := os.CreateTemp("", "wolfictl-trace-*")
+	if err != nil {
+		return nil, err
+	}
+	trace.Close()
+	defer os.Remove(trace.Name())
+	o.Logger.Printf("building %s", name)
+	local := builder.Local{Dir: o.Dir, Wrapper: []string{"strace", "-f", "-qq", "-e", "trace=file,process", "-o", trace.Name()}}
+	if err := local.Build(ctx, t); err != nil {
+		return nil, err
+	}
+
+	f, err = os.Open(trace.Name())
+	if err != nil {
+		return nil, err
+	}
+	accessed, err := ParseTrace(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	for p := range accessed {
+		for target, ok := links[p]; ok && !accessed[target]; target, ok = links[target] {
+			accessed[target] = true
+		}
+	}
+
+	needed, err := o.neededLibraries(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var unused []string
+	for _, p := range UnusedEnvironmentPackages(cfg.Environment.Contents.Packages, installed, accessed, needed) {
+		if !contains(o.Ignore, p) {
+			unused = append(unused, p)
+		}
+	}
+	return unused, nil
+}
+
+// neededLibraries returns the sonames the ELF files of the apks built from cfg link to
+func (o UnusedDepsOptions) neededLibraries(cfg *dag.Configuration) ([]string, error) {
+	dir, err := os.MkdirTemp("", "wolfictl-apk-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	names := []string{cfg.Package.Name}
+	for i := range cfg.Subpackages {
+		names = append(names, cfg.Subpackages[i].Name)
+	}
+	for _, n := range names {
+		apk, err := os.Open(filepath.Join(o.Repo, o.Arch, fmt.Sprintf("%s-%s-r%d.apk", n, cfg.Package.Version, cfg.Package.Epoch)))
+		if errors.Is(err, fs.ErrNotExist) {
+			// subpackages that end up empty aren't written
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		err = tar.Untar(apk, filepath.Join(dir, n))
+		apk.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to untar %s: %w", n, err)
+		}
+	}
+
+	var needed []string
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		f, err := elf.Open(p)
+		if err != nil {
+			// not an ELF file
+			return nil
+		}
+		defer f.Close()
+		libs, err := f.ImportedLibraries()
+		if err != nil {
+			return nil
+		}
+		needed = append(needed, libs...)
+		return nil
+	})
+	return needed, err
+}
+
+// ReadInstalledDB reads the packages of an apk installed database, lib/apk/db/installed.
+func ReadInstalledDB(r io.Reader) ([]InstalledPackage, error) {
+	var pkgs []InstalledPackage
+	var p *InstalledPackage
+	dir := ""
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			p = nil
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+		if p == nil {
+			pkgs = append(pkgs, InstalledPackage{})
+			p = &pkgs[len(pkgs)-1]
+			dir = ""
+		}
+		switch key {
+		case "P":
+			p.Name = value
+		case "D":
+			p.Depends = strings.Fields(value)
+		case "p":
+			p.Provides = strings.Fields(value)
+		case "F":
+			dir = value
+		case "R":
+			p.Files = append(p.Files, path.Join("/", dir, value))
+		}
+	}
+	return pkgs, scanner.Err()
+}
+
+var (
+	// e.g. 123 openat(AT_FDCWD, "/usr/lib/libz.so.1", O_RDONLY|O_CLOEXEC) = 3, with -f
+	traceCall = regexp.MustCompile(`^(\d+)\s+(?:<\.\.\. )?(\w+)(?:\(| resumed>)(.*)$`)
+	// the result of a syscall, which is the pid of the child for clone and fork
+	traceResult = regexp.MustCompile(`\)\s+=\s+(\d+)`)
+	tracePath   = regexp.MustCompile(`"(/[^"]*)"`)
+)
+
+// ParseTrace returns the absolute paths accessed in the sandbox of a build, from the output of strace -f tracing
+// its file and process syscalls: those of the command bubblewrap runs in the sandbox and its children, that see the
+// build environment as their root. What bubblewrap does before, setting up the sandbox, accesses the paths of the
+// host, as make and melange do.
+func ParseTrace(r io.Reader) (map[string]bool, error) {
+	sandboxed := make(map[string]bool)
+	// bwrap are bubblewrap and the processes it forks, until they run the command of the sandbox
+	bwrap := make(map[string]bool)
+	accessed := make(map[string]bool)
+	found := false
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		m := traceCall.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		pid, call, args := m[1], m[2], m[3]
+		switch call {
+		case "execve", "execveat":
+			p := tracePath.FindStringSubmatch(args)
+			switch {
+			case p != nil && path.Base(p[1]) == "bwrap" && !sandboxed[pid]:
+				bwrap[pid] = true
+				found = true
+				continue
+			case bwrap[pid]:
+				// bubblewrap runs the command once the build environment is the root
+				delete(bwrap, pid)
+				sandboxed[pid] = true
+			}
+		case "clone", "clone3", "fork", "vfork":
+			if child := traceResult.FindStringSubmatch(args); child != nil {
+				bwrap[child[1]] = bwrap[pid]
+				sandboxed[child[1]] = sandboxed[pid]
+			}
+		}
+		if !sandboxed[pid] {
+			continue
+		}
+		if call == "execve" || call == "execveat" {
+			// the other paths are arguments
+			if p := tracePath.FindStringSubmatch(args); p != nil {
+				accessed[path.Clean(p[1])] = true
+			}
+			continue
+		}
+		for _, p := range tracePath.FindAllStringSubmatch(args, -1) {
+			accessed[path.Clean(p[1])] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("the build didn't run in bubblewrap, so the files it accessed are unknown")
+	}
+	return accessed, nil
+}
+
+// UnusedEnvironmentPackages returns the packages declared in a build environment whose files weren't accessed, and
+// none of whose dependencies that no other declared package pulls in was accessed either, or provides a library in
+// needed.
+func UnusedEnvironmentPackages(declared []string, installed []InstalledPackage, accessed map[string]bool, needed []string) []string {
+	providers := make(map[string]*InstalledPackage)
+	owners := make(map[string]string)
+	for i := range installed {
+		p := &installed[i]
+		providers[p.Name] = p
+		for _, prov := range p.Provides {
+			name, _, _ := strings.Cut(prov, "=")
+			if _, ok := providers[name]; !ok {
+				providers[name] = p
+			}
+		}
+		for _, f := range p.Files {
+			owners[f] = p.Name
+		}
+	}
+
+	used := make(map[string]bool)
+	for f := range accessed {
+		if owner, ok := owners[f]; ok {
+			used[owner] = true
+		}
+	}
+	for _, soname := range needed {
+		if p, ok := providers["so:"+soname]; ok {
+			used[p.Name] = true
+		}
+	}
+
+	closures := make(map[string]map[string]bool)
+	pulledBy := make(map[string]int)
+	var names []string
+	for _, d := range declared {
+		p, ok := providers[dependencyName(d)]
+		if !ok {
+			continue
+		}
+		closure := make(map[string]bool)
+		var walk func(p *InstalledPackage)
+		walk = func(p *InstalledPackage) {
+			if closure[p.Name] {
+				return
+			}
+			closure[p.Name] = true
+			for _, dep := range p.Depends {
+				// conflicts start with a !, and aren't in the environment
+				if dep, ok := providers[dependencyName(dep)]; ok {
+					walk(dep)
+				}
+			}
+		}
+		walk(p)
+		if _, ok := closures[p.Name]; ok {
+			continue
+		}
+		closures[p.Name] = closure
+		names = append(names, p.Name)
+		for n := range closure {
+			pulledBy[n]++
+		}
+	}
+
+	var unused []string
+	for _, name := range names {
+		needed := used[name]
+		for n := range closures[name] {
+			if used[n] && pulledBy[n] == 1 {
+				needed = true
+				break
+			}
+		}
+		if !needed {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// dependencyName returns the name of a dependency without its version constraint
+func dependencyName(dep string) string {
+	if i := strings.IndexAny(dep, "=<>~"); i >= 0 {
+		return dep[:i]
+	}
+	return dep
+}
+
+// symlinks returns the targets of the files of installed packages that are symlinks in root, by their path from root
+func symlinks(root string, installed []InstalledPackage) map[string]string {
+	links := make(map[string]string)
+	for _, p := range installed {
+		for _, f := range p.Files {
+			target, err := os.Readlink(filepath.Join(root, f))
+			if err != nil {
+				continue
+			}
+			if !path.IsAbs(target) {
+				target = path.Join(path.Dir(f), target)
+			}
+			links[f] = path.Clean(target)
+		}
+	}
+	return links
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
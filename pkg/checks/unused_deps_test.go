@@ -0,0 +1,64 @@
+package checks
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadInstalledDB(t *testing.T) {
+	f, err := os.Open("testdata/unused_deps/installed")
+	require.NoError(t, err)
+	defer f.Close()
+
+	installed, err := ReadInstalledDB(f)
+	require.NoError(t, err)
+	require.Len(t, installed, 12)
+	assert.Equal(t, InstalledPackage{
+		Name:     "gcc",
+		Depends:  []string{"binutils", "so:libc.so.6"},
+		Provides: []string{"cmd:gcc=13.1.0-r0", "cmd:cc=13.1.0-r0"},
+		Files:    []string{"/usr/bin/gcc", "/usr/bin/cc"},
+	}, installed[4])
+	assert.Empty(t, installed[6].Files)
+}
+
+func TestParseTrace(t *testing.T) {
+	f, err := os.Open("testdata/unused_deps/trace")
+	require.NoError(t, err)
+	defer f.Close()
+
+	accessed, err := ParseTrace(f)
+	require.NoError(t, err)
+	// only what runs in the sandbox counts, not melange, make or bubblewrap setting up the sandbox reading the files
+	// of the host
+	assert.Equal(t, map[string]bool{
+		"/bin/sh":             true,
+		"/usr/bin/cc":         true,
+		"/usr/include/zlib.h": true,
+		"/usr/lib/libc.so.6":  true,
+		"/etc/ld.so.preload":  true,
+	}, accessed)
+
+	_, err = ParseTrace(strings.NewReader(`100 execve("/usr/bin/make", ["make"], 0x7ffd /* 30 vars */) = 0` + "\n"))
+	assert.ErrorContains(t, err, "bubblewrap")
+}
+
+func TestUnusedEnvironmentPackages(t *testing.T) {
+	f, err := os.Open("testdata/unused_deps/installed")
+	require.NoError(t, err)
+	defer f.Close()
+	installed, err := ReadInstalledDB(f)
+	require.NoError(t, err)
+
+	declared := []string{"build-base", "busybox", "zlib-dev", "openssl-dev>=3", "python3"}
+	accessed := map[string]bool{"/bin/sh": true, "/usr/bin/cc": true, "/usr/include/zlib.h": true, "/usr/lib/libc.so.6": true}
+
+	// build-base is needed for gcc, that nothing else pulls in, but python3 only pulls in zlib, which zlib-dev does too
+	assert.Equal(t, []string{"openssl-dev", "python3"}, UnusedEnvironmentPackages(declared, installed, accessed, nil))
+	// openssl-dev is needed for the libcrypto the package links to
+	assert.Equal(t, []string{"python3"}, UnusedEnvironmentPackages(declared, installed, accessed, []string{"libc.so.6", "libcrypto.so.3"}))
+}
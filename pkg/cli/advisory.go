@@ -1,10 +1,12 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"sort"
+	"strings"
 	"time"
 
 	"chainguard.dev/melange/pkg/build"
@@ -37,9 +39,18 @@ func Advisory() *cobra.Command {
 	cmd.AddCommand(AdvisoryList())
 	cmd.AddCommand(AdvisoryCreate())
 	cmd.AddCommand(AdvisoryUpdate())
+	cmd.AddCommand(AdvisoryGuide())
+	cmd.AddCommand(AdvisoryApply())
+	cmd.AddCommand(AdvisoryImport())
+	cmd.AddCommand(AdvisoryAutoClose())
+	cmd.AddCommand(AdvisoryReconcile())
 	cmd.AddCommand(AdvisorySyncSecfixes())
 	cmd.AddCommand(AdvisoryDiscover())
 	cmd.AddCommand(AdvisoryDB())
+	cmd.AddCommand(AdvisoryExport())
+	cmd.AddCommand(AdvisoryValidate())
+	cmd.AddCommand(AdvisoryDiff())
+	cmd.AddCommand(AdvisoryStats())
 
 	return cmd
 }
@@ -106,14 +117,15 @@ func doFollowupSync(selection configs.Selection[advisoryconfigs.Document]) error
 }
 
 type advisoryRequestParams struct {
-	packageName, vuln, status, action, impact, justification, timestamp, fixedVersion string
-	sync                                                                              bool
+	packageName, vuln, event, status, action, impact, justification, timestamp, fixedVersion string
+	sync                                                                                     bool
 }
 
 func (p *advisoryRequestParams) addFlags(cmd *cobra.Command) {
 	addPackageFlag(&p.packageName, cmd)
 	addVulnFlag(&p.vuln, cmd)
 
+	cmd.Flags().StringVarP(&p.event, "event", "e", "", fmt.Sprintf("triage event, recorded as the status it amounts to, one of: %s", eventNames()))
 	cmd.Flags().StringVarP(&p.status, "status", "s", "", "status for VEX statement")
 	cmd.Flags().StringVar(&p.action, "action", "", "action statement for VEX statement (used only for affected status)")
 	cmd.Flags().StringVar(&p.impact, "impact", "", "impact statement for VEX statement (used only for not_affected status)")
@@ -129,7 +141,7 @@ func (p *advisoryRequestParams) advisoryRequest() (advisory.Request, error) {
 		return advisory.Request{}, fmt.Errorf("unable to process timestamp: %w", err)
 	}
 
-	return advisory.Request{
+	req := advisory.Request{
 		Package:       p.packageName,
 		Vulnerability: p.vuln,
 		Status:        vex.Status(p.status),
@@ -138,7 +150,29 @@ func (p *advisoryRequestParams) advisoryRequest() (advisory.Request, error) {
 		Justification: vex.Justification(p.justification),
 		Timestamp:     timestamp,
 		FixedVersion:  p.fixedVersion,
-	}, nil
+	}
+
+	if p.event != "" {
+		if p.status != "" {
+			return advisory.Request{}, errors.New("--event and --status can't both be given, the event sets the status")
+		}
+		event, err := advisory.ParseEvent(p.event)
+		if err != nil {
+			return advisory.Request{}, err
+		}
+		event.Apply(&req)
+	}
+
+	return req, nil
+}
+
+// eventNames returns the names of the advisory events, for flag usages.
+func eventNames() string {
+	names := make([]string, 0, len(advisory.Events))
+	for _, e := range advisory.Events {
+		names = append(names, string(e))
+	}
+	return strings.Join(names, ", ")
 }
 
 func addPackageFlag(val *string, cmd *cobra.Command) {
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln/ghsa"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln/osv"
+)
+
+// aliasParams are the flags of the resolution of the aliases of vulnerabilities, from OSV and GitHub, so that a
+// vulnerability's advisory is found under any of its IDs.
+type aliasParams struct {
+	cacheDir string
+}
+
+func (p *aliasParams) addFlagsTo(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&p.cacheDir, "alias-cache-dir", "", "directory to cache the aliases of vulnerabilities in (default is wolfictl/aliases in the user cache directory)")
+}
+
+// resolver returns the resolver of aliases, looking up GHSAs with githubToken, or GITHUB_TOKEN if it's empty.
+func (p *aliasParams) resolver(githubToken string) (*vuln.AliasResolver, error) {
+	cacheDir := p.cacheDir
+	if cacheDir == "" {
+		dir, err := vuln.DefaultAliasCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("unable to determine the alias cache dir, use --alias-cache-dir: %w", err)
+		}
+		cacheDir = dir
+	}
+
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN")
+	}
+
+	return &vuln.AliasResolver{
+		Finders: []vuln.AliasFinder{
+			osv.NewClient(http.DefaultClient, osv.DefaultHost),
+			ghsa.NewClient(http.DefaultClient, ghsa.DefaultHost, githubToken),
+		},
+		CacheDir: cacheDir,
+	}, nil
+}
+
+// resolveOrWarn returns the aliases of the vulnerabilities, or none if they can't be resolved, e.g. offline, in which
+// case advisories are only found under the IDs given.
+func (p *aliasParams) resolveOrWarn(ctx context.Context, githubToken string, ids ...string) vuln.Aliases {
+	r, err := p.resolver(githubToken)
+	if err == nil {
+		var aliases vuln.Aliases
+		if aliases, err = r.Resolve(ctx, ids...); err == nil {
+			return aliases
+		}
+	}
+	log.Printf("⚠️  unable to resolve aliases, looking for advisories under the given IDs only: %s", err)
+	return nil
+}
@@ -0,0 +1,349 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	buildconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/build"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/distro"
+	wolfigit "github.com/wolfi-dev/wolfictl/pkg/git"
+)
+
+func AdvisoryApply() *cobra.Command {
+	p := &applyParams{}
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "apply many advisory events at once from a JSON or CSV manifest",
+		Long: `apply many advisory events at once from a JSON or CSV manifest
+
+A JSON manifest is an array of objects, a CSV manifest has a header row naming
+its columns. Both use the keys package, vulnerability, status, action, impact,
+justification, fixedVersion, timestamp and note, which is for reviewers of the
+manifest and isn't recorded. Events without a timestamp get the one of
+--timestamp.
+
+Every event is validated before anything is written. Events for a vulnerability
+the package has no advisory for yet create the advisory. Unless --no-commit is
+given, the changed advisory documents are committed in a single commit.`,
+		Example: `  wolfictl advisory apply --from manifest.json --dry-run
+  wolfictl advisory apply --from not-affected.csv -m "Mark CVE-2023-1234 not affected"`,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			distroRepoDir := resolveDistroDir(p.distroRepoDir)
+			advisoriesRepoDir := resolveAdvisoriesDir(p.advisoriesRepoDir)
+			if distroRepoDir == "" || advisoriesRepoDir == "" {
+				if p.doNotDetectDistro {
+					return fmt.Errorf("distro repo dir and/or advisories repo dir was left unspecified")
+				}
+
+				d, err := distro.Detect()
+				if err != nil {
+					return fmt.Errorf("distro repo dir and/or advisories repo dir was left unspecified, and distro auto-detection failed: %w", err)
+				}
+
+				distroRepoDir = d.DistroRepoDir
+				advisoriesRepoDir = d.AdvisoriesRepoDir
+				_, _ = fmt.Fprint(os.Stderr, renderDetectedDistro(d))
+			}
+
+			timestamp, err := resolveTimestamp(p.timestamp)
+			if err != nil {
+				return err
+			}
+
+			reqs, err := readManifest(p.from, p.format, timestamp)
+			if err != nil {
+				return err
+			}
+			if len(reqs) == 0 {
+				return fmt.Errorf("manifest %s has no entries", p.from)
+			}
+
+			buildCfgs, err := buildconfigs.NewIndex(rwos.DirFS(distroRepoDir))
+			if err != nil {
+				return fmt.Errorf("unable to select packages: %w", err)
+			}
+
+			before, err := readAdvisoryDocuments(advisoriesRepoDir)
+			if err != nil {
+				return err
+			}
+
+			if !p.dryRun && !p.noCommit {
+				// checked before applying, so a refusal doesn't leave the manifest applied but uncommitted
+				if err := checkNothingStaged(advisoriesRepoDir); err != nil {
+					return err
+				}
+			}
+
+			dir := advisoriesRepoDir
+			if p.dryRun {
+				dir, err = os.MkdirTemp("", "wolfictl-advisory-apply-*")
+				if err != nil {
+					return err
+				}
+				defer os.RemoveAll(dir)
+				for name, b := range before {
+					if err := os.WriteFile(filepath.Join(dir, name), b, 0o600); err != nil {
+						return err
+					}
+				}
+			}
+
+			advisoryCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(dir))
+			if err != nil {
+				return err
+			}
+			err = advisory.Apply(reqs, advisory.ApplyOptions{
+				AdvisoryCfgs: advisoryCfgs,
+				BuildCfgs:    buildCfgs,
+			})
+			if err != nil {
+				return fmt.Errorf("unable to apply manifest %s: %w", p.from, err)
+			}
+
+			after, err := readAdvisoryDocuments(dir)
+			if err != nil {
+				return err
+			}
+			changed := changedDocuments(before, after)
+
+			if p.dryRun {
+				return writeDocumentsDiff(cmd.OutOrStdout(), changed, before, after)
+			}
+
+			if p.sync {
+				for _, pkg := range requestedPackages(reqs) {
+					if err := doFollowupSync(advisoryCfgs.Select().WhereName(pkg)); err != nil {
+						return err
+					}
+				}
+				// syncing can touch more documents
+				if after, err = readAdvisoryDocuments(dir); err != nil {
+					return err
+				}
+				changed = changedDocuments(before, after)
+			}
+
+			if p.noCommit {
+				return nil
+			}
+			message := p.message
+			if message == "" {
+				message = applyCommitMessage(reqs)
+			}
+			return commitDocuments(advisoriesRepoDir, changed, message)
+		},
+	}
+
+	p.addFlagsTo(cmd)
+	return cmd
+}
+
+type applyParams struct {
+	doNotDetectDistro bool
+
+	distroRepoDir, advisoriesRepoDir string
+
+	from, format, timestamp, message string
+	dryRun, noCommit, sync           bool
+}
+
+func (p *applyParams) addFlagsTo(cmd *cobra.Command) {
+	addNoDistroDetectionFlag(&p.doNotDetectDistro, cmd)
+
+	addDistroDirFlag(&p.distroRepoDir, cmd)
+	addAdvisoriesDirFlag(&p.advisoriesRepoDir, cmd)
+
+	cmd.Flags().StringVar(&p.from, "from", "", "manifest of advisory events to apply")
+	_ = cmd.MarkFlagRequired("from")
+	cmd.Flags().StringVar(&p.format, "format", "", fmt.Sprintf("format of the manifest, one of: %s, %s (default: from the file extension)", advisory.ManifestFormatJSON, advisory.ManifestFormatCSV))
+	cmd.Flags().StringVar(&p.timestamp, "timestamp", "now", "timestamp for events that don't have one")
+	cmd.Flags().BoolVar(&p.dryRun, "dry-run", false, "print the diff of the advisory documents instead of changing them")
+	cmd.Flags().BoolVar(&p.noCommit, "no-commit", false, "leave the changed advisory documents uncommitted")
+	cmd.Flags().StringVarP(&p.message, "message", "m", "", "commit message (default: generated from the manifest)")
+	cmd.Flags().BoolVar(&p.sync, "sync", false, "synchronize secfixes data immediately after updating advisories")
+}
+
+func readManifest(path, format string, timestamp time.Time) ([]advisory.Request, error) {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reqs, err := advisory.ParseManifest(f, advisory.ManifestFormat(format), timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read manifest %s: %w", path, err)
+	}
+	return reqs, nil
+}
+
+// readAdvisoryDocuments returns the contents of the files an advisory index of dir is made of, by name.
+func readAdvisoryDocuments(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make(map[string][]byte)
+	for _, e := range entries {
+		if !e.Type().IsRegular() || strings.HasPrefix(e.Name(), ".") || !strings.HasSuffix(e.Name(), ".yaml") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		docs[e.Name()] = b
+	}
+	return docs, nil
+}
+
+// changedDocuments returns the sorted names of the documents that were added or changed from before to after.
+func changedDocuments(before, after map[string][]byte) []string {
+	var changed []string
+	for name, b := range after {
+		if old, ok := before[name]; !ok || string(old) != string(b) {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func writeDocumentsDiff(w io.Writer, names []string, before, after map[string][]byte) error {
+	for _, name := range names {
+		from := "a/" + name
+		if _, ok := before[name]; !ok {
+			from = "/dev/null"
+		}
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(before[name])),
+			B:        difflib.SplitLines(string(after[name])),
+			FromFile: from,
+			ToFile:   "b/" + name,
+			Context:  3,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprint(w, diff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func requestedPackages(reqs []advisory.Request) []string {
+	var pkgs []string
+	seen := make(map[string]bool)
+	for _, req := range reqs {
+		if !seen[req.Package] {
+			seen[req.Package] = true
+			pkgs = append(pkgs, req.Package)
+		}
+	}
+	return pkgs
+}
+
+// applyCommitMessage describes reqs by what they have in common, e.g. "Mark CVE-2023-1234 not_affected for 200
+// packages", followed by a line for each request.
+func applyCommitMessage(reqs []advisory.Request) string {
+	vuln, status := reqs[0].Vulnerability, reqs[0].Status
+	for _, req := range reqs[1:] {
+		if req.Vulnerability != vuln {
+			vuln = ""
+		}
+		if req.Status != status {
+			status = ""
+		}
+	}
+
+	var subject string
+	switch {
+	case vuln != "" && status != "":
+		subject = fmt.Sprintf("Mark %s %s", vuln, status)
+	case vuln != "":
+		subject = fmt.Sprintf("Update %s", vuln)
+	default:
+		subject = fmt.Sprintf("Apply %d advisory events", len(reqs))
+	}
+	if pkgs := requestedPackages(reqs); len(pkgs) == 1 {
+		subject += " for " + pkgs[0]
+	} else {
+		subject += fmt.Sprintf(" for %d packages", len(pkgs))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(subject + "\n\n")
+	for _, req := range reqs {
+		fmt.Fprintf(&sb, "- %s: %s %s\n", req.Package, req.Vulnerability, req.Status)
+	}
+	return sb.String()
+}
+
+// checkNothingStaged returns an error if changes are staged in the advisories repository already, so the commit of
+// what's applied only has that.
+func checkNothingStaged(dir string) error {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return fmt.Errorf("unable to open advisories repository %s: %w", dir, err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return err
+	}
+	for path, s := range status {
+		if s.Staging != git.Unmodified && s.Staging != git.Untracked {
+			return fmt.Errorf("not applying, %s already has staged changes to %s, which would be committed with the manifest", dir, path)
+		}
+	}
+	return nil
+}
+
+// commitDocuments commits the named documents of the advisories repository.
+func commitDocuments(dir string, names []string, message string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return fmt.Errorf("unable to open advisories repository %s: %w", dir, err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if _, err := wt.Add(name); err != nil {
+			return fmt.Errorf("unable to stage %s: %w", name, err)
+		}
+	}
+	if _, err := wt.Commit(message, &git.CommitOptions{Author: wolfigit.GetGitAuthorSignature()}); err != nil {
+		return fmt.Errorf("failed to git commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckNothingStaged(t *testing.T) {
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "curl.advisories.yaml"), []byte("package:\n  name: curl\n"), 0o600))
+	assert.NoError(t, checkNothingStaged(dir), "untracked files aren't committed")
+
+	_, err = wt.Add("curl.advisories.yaml")
+	require.NoError(t, err)
+	assert.ErrorContains(t, checkNothingStaged(dir), "already has staged changes to curl.advisories.yaml")
+}
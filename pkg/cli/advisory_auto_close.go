@@ -0,0 +1,279 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/google/go-github/v50/github"
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	buildconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/build"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/distro"
+	"github.com/wolfi-dev/wolfictl/pkg/gh"
+	wolfigit "github.com/wolfi-dev/wolfictl/pkg/git"
+	"golang.org/x/oauth2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	confirmPrompt = "prompt"
+	confirmAuto   = "auto"
+	confirmDryRun = "dry-run"
+)
+
+var rePullRequestURL = regexp.MustCompile(`^https://github\.com/([^/]+)/([^/]+)/pull/(\d+)/?$`)
+
+func AdvisoryAutoClose() *cobra.Command {
+	p := &autoCloseParams{}
+	cmd := &cobra.Command{
+		Use:   "auto-close",
+		Short: "mark pending advisories as fixed once a version bump that fixes them has merged",
+		Long: `mark pending advisories as fixed once a version bump that fixes them has merged
+
+The pull request is looked up on GitHub (GITHUB_TOKEN is used if set). For every
+package config the pull request bumps the version of, the vulnerabilities
+mentioned in the pull request's title and body (e.g. "fixes: CVE-2023-1234")
+that still have a pending advisory (under_investigation or affected) get a
+"fixed" entry with the package's new version.
+
+The distro repo dir is expected to be checked out at the merged revision, since
+that's where the new versions are read from.`,
+		Example: `  wolfictl advisory auto-close --pr https://github.com/wolfi-dev/os/pull/1234
+  wolfictl advisory auto-close --pr 1234 --confirm auto --sync`,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch p.confirm {
+			case confirmPrompt, confirmAuto, confirmDryRun:
+			default:
+				return fmt.Errorf("unknown confirmation mode %q, must be one of: %s, %s, %s", p.confirm, confirmPrompt, confirmAuto, confirmDryRun)
+			}
+
+			distroRepoDir := resolveDistroDir(p.distroRepoDir)
+			advisoriesRepoDir := resolveAdvisoriesDir(p.advisoriesRepoDir)
+			if distroRepoDir == "" || advisoriesRepoDir == "" {
+				if p.doNotDetectDistro {
+					return fmt.Errorf("distro repo dir and/or advisories repo dir was left unspecified")
+				}
+
+				d, err := distro.Detect()
+				if err != nil {
+					return fmt.Errorf("distro repo dir and/or advisories repo dir was left unspecified, and distro auto-detection failed: %w", err)
+				}
+
+				distroRepoDir = d.DistroRepoDir
+				advisoriesRepoDir = d.AdvisoriesRepoDir
+				_, _ = fmt.Fprint(os.Stderr, renderDetectedDistro(d))
+			}
+
+			owner, repo, number, err := parsePullRequestRef(p.pr, distroRepoDir)
+			if err != nil {
+				return err
+			}
+
+			timestamp, err := resolveTimestamp(p.timestamp)
+			if err != nil {
+				return err
+			}
+
+			advisoryCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(advisoriesRepoDir))
+			if err != nil {
+				return err
+			}
+
+			buildCfgs, err := buildconfigs.NewIndex(rwos.DirFS(distroRepoDir))
+			if err != nil {
+				return fmt.Errorf("unable to select packages: %w", err)
+			}
+
+			ctx := cmd.Context()
+			ghClient := newGitHubOptions(ctx)
+
+			pr, err := ghClient.FetchPullRequest(ctx, owner, repo, number)
+			if err != nil {
+				return fmt.Errorf("unable to get pull request %s/%s#%d: %w", owner, repo, number, err)
+			}
+			if !pr.GetMerged() {
+				return fmt.Errorf("pull request %s has not been merged", pr.GetHTMLURL())
+			}
+
+			vulns := advisory.ExtractVulnerabilityIDs(pr.GetTitle() + "\n" + pr.GetBody())
+			if len(vulns) == 0 {
+				log.Printf("INFO: pull request %s doesn't mention any vulnerabilities, nothing to close", pr.GetHTMLURL())
+				return nil
+			}
+
+			files, err := ghClient.ListPullRequestFiles(ctx, owner, repo, number)
+			if err != nil {
+				return fmt.Errorf("unable to list files of pull request %s: %w", pr.GetHTMLURL(), err)
+			}
+
+			confirm := newAutoCloseConfirmFunc(p.confirm, os.Stdin, os.Stderr)
+			var closed []advisory.Request
+			bumped, err := bumpedPackages(files, buildCfgs, func(path string) ([]byte, error) {
+				return ghClient.FetchFileContent(ctx, owner, repo, path, pr.GetBase().GetSHA())
+			})
+			if err != nil {
+				return fmt.Errorf("unable to compare the versions of the packages of %s: %w", pr.GetHTMLURL(), err)
+			}
+			for _, cfg := range bumped {
+				applied, err := advisory.AutoClose(advisory.AutoCloseOptions{
+					AdvisoryCfgs:    advisoryCfgs,
+					Package:         cfg.Package.Name,
+					FixedVersion:    fmt.Sprintf("%s-r%d", cfg.Package.Version, cfg.Package.Epoch),
+					Vulnerabilities: vulns,
+					Timestamp:       timestamp,
+					Confirm:         confirm,
+				})
+				closed = append(closed, applied...)
+				if err != nil {
+					return err
+				}
+			}
+
+			if len(closed) == 0 {
+				log.Printf("INFO: no pending advisories were closed by %s", pr.GetHTMLURL())
+				return nil
+			}
+			for _, req := range closed {
+				log.Printf("INFO: %s %s fixed in %s", req.Package, req.Vulnerability, req.FixedVersion)
+			}
+
+			if p.sync {
+				for _, req := range closed {
+					if err := doFollowupSync(advisoryCfgs.Select().WhereName(req.Package)); err != nil {
+						return err
+					}
+				}
+			}
+
+			return nil
+		},
+	}
+
+	p.addFlagsTo(cmd)
+	return cmd
+}
+
+type autoCloseParams struct {
+	doNotDetectDistro bool
+
+	distroRepoDir, advisoriesRepoDir string
+
+	pr, confirm, timestamp string
+	sync                   bool
+}
+
+func (p *autoCloseParams) addFlagsTo(cmd *cobra.Command) {
+	addNoDistroDetectionFlag(&p.doNotDetectDistro, cmd)
+
+	addDistroDirFlag(&p.distroRepoDir, cmd)
+	addAdvisoriesDirFlag(&p.advisoriesRepoDir, cmd)
+
+	cmd.Flags().StringVar(&p.pr, "pr", "", "merged pull request, as a URL or a number in the distro repository")
+	_ = cmd.MarkFlagRequired("pr")
+	cmd.Flags().StringVar(&p.confirm, "confirm", confirmPrompt, fmt.Sprintf("how to confirm each advisory update, one of: %s (ask for each), %s (apply all), %s (only print)", confirmPrompt, confirmAuto, confirmDryRun))
+	cmd.Flags().StringVar(&p.timestamp, "timestamp", "now", "timestamp for the fixed entries")
+	cmd.Flags().BoolVar(&p.sync, "sync", false, "synchronize secfixes data immediately after updating advisories")
+}
+
+// parsePullRequestRef accepts a pull request URL, or a number of a pull request in the repository the distro dir
+// was cloned from.
+func parsePullRequestRef(ref, distroRepoDir string) (owner, repo string, number int, err error) {
+	if m := rePullRequestURL.FindStringSubmatch(ref); m != nil {
+		number, err = strconv.Atoi(m[3])
+		return m[1], m[2], number, err
+	}
+
+	number, err = strconv.Atoi(strings.TrimPrefix(ref, "#"))
+	if err != nil {
+		return "", "", 0, fmt.Errorf("%q is neither a pull request URL nor a number", ref)
+	}
+	u, err := wolfigit.GetRemoteURLFromDir(distroRepoDir)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("unable to find the GitHub repository of %s: %w", distroRepoDir, err)
+	}
+	return u.Organisation, u.Name, number, nil
+}
+
+func newGitHubOptions(ctx context.Context) gh.GitOptions {
+	client := http.DefaultClient
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		client = oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+	}
+
+	return gh.GitOptions{
+		GithubClient: github.NewClient(client),
+		Logger:       log.New(log.Writer(), "wolfictl advisory auto-close: ", log.LstdFlags|log.Lmsgprefix),
+	}
+}
+
+// bumpedPackages returns the build configurations whose package.version the changed files change, comparing them
+// with the configs base returns the content of before the change, nil for the ones that didn't exist.
+func bumpedPackages(files []*github.CommitFile, buildCfgs *configs.Index[build.Configuration], base func(path string) ([]byte, error)) ([]build.Configuration, error) {
+	var cfgs []build.Configuration
+	for _, f := range files {
+		if f.GetStatus() == "removed" {
+			continue
+		}
+		changed := buildCfgs.Select().WhereFilePath(f.GetFilename()).Configurations()
+		if len(changed) == 0 {
+			continue
+		}
+
+		path := f.GetFilename()
+		if f.GetPreviousFilename() != "" {
+			path = f.GetPreviousFilename()
+		}
+		b, err := base(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get %s before the change: %w", path, err)
+		}
+		var before build.Configuration
+		if err := yaml.Unmarshal(b, &before); err != nil {
+			return nil, fmt.Errorf("unable to parse %s before the change: %w", path, err)
+		}
+		for _, cfg := range changed {
+			if cfg.Package.Version != before.Package.Version {
+				cfgs = append(cfgs, cfg)
+			}
+		}
+	}
+	return cfgs, nil
+}
+
+func newAutoCloseConfirmFunc(mode string, in io.Reader, out io.Writer) func(advisory.Request) (bool, error) {
+	switch mode {
+	case confirmAuto:
+		return nil
+	case confirmDryRun:
+		return func(req advisory.Request) (bool, error) {
+			fmt.Fprintf(out, "would mark %s %s as fixed in %s\n", req.Package, req.Vulnerability, req.FixedVersion)
+			return false, nil
+		}
+	default:
+		r := bufio.NewReader(in)
+		return func(req advisory.Request) (bool, error) {
+			fmt.Fprintf(out, "mark %s %s as fixed in %s? [y/N] ", req.Package, req.Vulnerability, req.FixedVersion)
+			answer, err := r.ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return false, err
+			}
+			answer = strings.ToLower(strings.TrimSpace(answer))
+			return answer == "y" || answer == "yes", nil
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	buildconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/build"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+)
+
+func TestBumpedPackages(t *testing.T) {
+	dir := t.TempDir()
+	config := func(name, version string) string {
+		return "package:\n  name: " + name + "\n  version: " + version + "\n  epoch: 0\n"
+	}
+	for name, version := range map[string]string{"curl": "8.4.0", "jq": "1.7", "zlib": "1.3", "new": "1.0.0"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(config(name, version)), 0o600))
+	}
+	buildCfgs, err := buildconfigs.NewIndex(rwos.DirFS(dir))
+	require.NoError(t, err)
+
+	base := map[string]string{
+		"curl.yaml": config("curl", "8.3.0"),
+		// only the version of a subpackage or of a pipeline step changed
+		"jq.yaml": config("jq", "1.7") + "subpackages:\n  - name: jq-dev\n    version: 1.7.1\n",
+		// renamed
+		"libz.yaml": config("zlib", "1.2.13"),
+	}
+	files := []*github.CommitFile{
+		{Filename: github.String("curl.yaml"), Status: github.String("modified")},
+		{Filename: github.String("jq.yaml"), Status: github.String("modified")},
+		{Filename: github.String("zlib.yaml"), PreviousFilename: github.String("libz.yaml"), Status: github.String("renamed")},
+		{Filename: github.String("new.yaml"), Status: github.String("added")},
+		{Filename: github.String("gone.yaml"), Status: github.String("removed")},
+		{Filename: github.String("README.md"), Status: github.String("modified")},
+	}
+	cfgs, err := bumpedPackages(files, buildCfgs, func(path string) ([]byte, error) {
+		b, ok := base[path]
+		if !ok {
+			return nil, nil
+		}
+		return []byte(b), nil
+	})
+	require.NoError(t, err)
+
+	var names []string
+	for _, cfg := range cfgs {
+		names = append(names, cfg.Package.Name)
+	}
+	assert.Equal(t, []string{"curl", "zlib", "new"}, names)
+}
@@ -1,7 +1,9 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"os"
 
 	"chainguard.dev/melange/pkg/build"
@@ -15,6 +17,7 @@ import (
 	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
 	"github.com/wolfi-dev/wolfictl/pkg/distro"
 	"github.com/wolfi-dev/wolfictl/pkg/index"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
 	"gitlab.alpinelinux.org/alpine/go/repository"
 )
 
@@ -72,7 +75,7 @@ func AdvisoryCreate() *cobra.Command {
 
 			var apkindexes []*repository.ApkIndex
 			for _, arch := range archs {
-				idx, err := index.Index(arch, packageRepositoryURL)
+				idx, err := index.Index(cmd.Context(), arch, packageRepositoryURL)
 				if err != nil {
 					return fmt.Errorf("unable to load APKINDEX for %s: %w", arch, err)
 				}
@@ -128,12 +131,22 @@ func AdvisoryCreate() *cobra.Command {
 			opts := advisory.CreateOptions{
 				AdvisoryCfgs: advisoryCfgs,
 			}
+			if !p.noAliases {
+				opts.Aliases = p.aliases.resolveOrWarn(cmd.Context(), p.enrichment.githubToken, req.Vulnerability)
+			}
 
+			if id := vuln.CanonicalID(opts.Aliases.Of(req.Vulnerability)...); id != req.Vulnerability {
+				log.Printf("recording %s under its alias %s", req.Vulnerability, id)
+			}
 			err = advisory.Create(req, opts)
 			if err != nil {
 				return err
 			}
 
+			if !p.noEnrich {
+				p.showVulnerabilityDetails(cmd.Context(), req)
+			}
+
 			if p.requestParams.sync {
 				err := doFollowupSync(advisoryCfgs.Select().WhereName(req.Package))
 				if err != nil {
@@ -157,6 +170,10 @@ type createParams struct {
 	distroRepoDir, advisoriesRepoDir string
 	archs                            []string
 	packageRepositoryURL             string
+	enrichment                       enrichmentParams
+	noEnrich                         bool
+	aliases                          aliasParams
+	noAliases                        bool
 }
 
 func (p *createParams) addFlagsTo(cmd *cobra.Command) {
@@ -168,4 +185,24 @@ func (p *createParams) addFlagsTo(cmd *cobra.Command) {
 	addAdvisoriesDirFlag(&p.advisoriesRepoDir, cmd)
 	cmd.Flags().StringSliceVar(&p.archs, "arch", []string{"x86_64", "aarch64"}, "package architectures to find published versions for")
 	cmd.Flags().StringVarP(&p.packageRepositoryURL, "package-repo-url", "r", "", "URL of the APK package repository")
+	p.enrichment.addFlagsTo(cmd)
+	cmd.Flags().BoolVar(&p.noEnrich, "no-enrich", false, "do not look up the severity and context of the vulnerability")
+	p.aliases.addFlagsTo(cmd)
+	cmd.Flags().BoolVar(&p.noAliases, "no-aliases", false, "do not look for an existing advisory under the aliases of the vulnerability")
+}
+
+// showVulnerabilityDetails prints the severity and context of the vulnerability of the new advisory. Not being able to
+// look them up doesn't fail the command, as the advisory is created already.
+func (p *createParams) showVulnerabilityDetails(ctx context.Context, req advisory.Request) {
+	enricher, err := p.enrichment.enricher()
+	if err != nil {
+		log.Printf("⚠️  unable to look up the details of %s: %s", req.Vulnerability, err)
+		return
+	}
+	details, err := enricher.VulnerabilityDetails(ctx, req.Package, req.Vulnerability)
+	if err != nil {
+		log.Printf("⚠️  unable to look up the details of %s: %s", req.Vulnerability, err)
+		return
+	}
+	_, _ = fmt.Fprint(os.Stderr, renderVulnerabilityDetails(details))
 }
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/distro"
+	"github.com/wolfi-dev/wolfictl/pkg/git"
+)
+
+func AdvisoryDiff() *cobra.Command {
+	p := &advisoryDiffParams{}
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "summarize the changes to the advisories since a git revision",
+		Long: `summarize the changes to the advisories since a git revision
+
+The advisories of the advisories repo dir are compared with the ones of the
+--base git revision of it, and every advisory that changed is printed as one
+line, for reviewing pull requests to the advisories repo without reading raw
+YAML diffs:
+
+  curl: CVE-2023-38545 new advisory, detected
+  curl: CVE-2023-28319 detected → fixed in 8.1.0-r0
+  curl: CVE-2023-28320 1 new event, still detected
+  curl: CVE-2023-28321 history rewritten, detected → fixed in 8.1.0-r0
+  curl: CVE-2023-28323 advisory removed, was detected
+
+An advisory is rewritten when its existing entries were changed or removed,
+rather than only added to, which is worth a closer look in review.`,
+		Example: `  wolfictl advisory diff --base origin/main
+  wolfictl advisory diff --base HEAD~1 --json`,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if p.base == "" {
+				return fmt.Errorf("no git revision to compare against specified, use --base")
+			}
+
+			advisoriesRepoDir := resolveAdvisoriesDir(p.advisoriesRepoDir)
+			if advisoriesRepoDir == "" {
+				if p.doNotDetectDistro {
+					return fmt.Errorf("no advisories repo dir specified")
+				}
+
+				d, err := distro.Detect()
+				if err != nil {
+					return fmt.Errorf("no advisories repo dir specified, and distro auto-detection failed: %w", err)
+				}
+
+				advisoriesRepoDir = d.AdvisoriesRepoDir
+				_, _ = fmt.Fprint(os.Stderr, renderDetectedDistro(d))
+			}
+
+			baseDir, cleanup, err := git.CheckoutRevision(advisoriesRepoDir, p.base)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			base, err := advisoryconfigs.NewIndex(rwos.DirFS(baseDir))
+			if err != nil {
+				return fmt.Errorf("unable to read the advisories at %s: %w", p.base, err)
+			}
+			current, err := advisoryconfigs.NewIndex(rwos.DirFS(advisoriesRepoDir))
+			if err != nil {
+				return err
+			}
+
+			changes := advisory.Diff(base.Select().Configurations(), current.Select().Configurations())
+			return advisoryDiff(changes, p.outputJSON, cmd.OutOrStdout())
+		},
+	}
+
+	p.addFlagsTo(cmd)
+	return cmd
+}
+
+type advisoryDiffParams struct {
+	doNotDetectDistro bool
+
+	advisoriesRepoDir string
+	base              string
+	outputJSON        bool
+}
+
+func (p *advisoryDiffParams) addFlagsTo(cmd *cobra.Command) {
+	addNoDistroDetectionFlag(&p.doNotDetectDistro, cmd)
+
+	addAdvisoriesDirFlag(&p.advisoriesRepoDir, cmd)
+	cmd.Flags().StringVar(&p.base, "base", "", "git revision of the advisories repo to compare against")
+	cmd.Flags().BoolVar(&p.outputJSON, "json", false, "print the changes as JSON")
+}
+
+func advisoryDiff(changes []advisory.AdvisoryChange, outputJSON bool, w io.Writer) error {
+	if outputJSON {
+		if changes == nil {
+			changes = []advisory.AdvisoryChange{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(changes)
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "no changes to the advisories")
+		return nil
+	}
+	for _, c := range changes {
+		fmt.Fprintln(w, c)
+	}
+	return nil
+}
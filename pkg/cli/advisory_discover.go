@@ -24,8 +24,20 @@ const envVarNameForNVDAPIKey = "WOLFICTL_NVD_API_KEY"
 func AdvisoryDiscover() *cobra.Command {
 	p := &discoverParams{}
 	cmd := &cobra.Command{
-		Use:           "discover",
-		Short:         "search for new potential vulnerabilities and create advisories for them",
+		Use:   "discover",
+		Short: "search for new potential vulnerabilities and create advisories for them",
+		Long: `search for new potential vulnerabilities and create advisories for them
+
+The latest version of every package published to the package repository, or
+built into --packages-dir, is searched for in NVD. Subpackages are searched for
+as the origin package they're built from. Vulnerabilities affecting that
+version that the advisories of the origin package don't have yet get an
+advisory with a detected entry, to triage them with 'wolfictl advisory guide'.
+Vulnerabilities already in the advisories are left alone, whatever was
+determined about them, including the ones recorded under an alias, e.g. the
+GHSA of a CVE, unless --no-aliases is given.`,
+		Example: `  wolfictl advisory discover
+  wolfictl advisory discover --package curl --packages-dir ./packages`,
 		SilenceErrors: true,
 		Args:          cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -69,16 +81,25 @@ func AdvisoryDiscover() *cobra.Command {
 
 			selectedPackages := getSelectedOrDistroPackages(p.packageName, buildCfgs)
 
-			apiKey := p.resolveNVDAPIKey()
+			apiKey := resolveNVDAPIKey(p.nvdAPIKey)
 
-			err = advisory.Discover(advisory.DiscoverOptions{
+			opts := advisory.DiscoverOptions{
 				SelectedPackages:      selectedPackages,
-				BuildCfgs:             buildCfgs,
 				AdvisoryCfgs:          advisoryCfgs,
 				PackageRepositoryURL:  packageRepositoryURL,
+				PackagesDir:           p.packagesDir,
 				Arches:                []string{"x86_64", "aarch64"},
 				VulnerabilityDetector: nvdapi.NewDetector(http.DefaultClient, nvdapi.DefaultHost, apiKey),
-			})
+			}
+			if !p.noAliases {
+				resolver, err := p.aliases.resolver("")
+				if err != nil {
+					return err
+				}
+				opts.AliasFinder = resolver
+			}
+
+			err = advisory.Discover(cmd.Context(), opts)
 			if err != nil {
 				return err
 			}
@@ -102,8 +123,12 @@ type discoverParams struct {
 	distroRepoDir, advisoriesRepoDir string
 
 	packageRepositoryURL string
+	packagesDir          string
 
 	nvdAPIKey string
+
+	aliases   aliasParams
+	noAliases bool
 }
 
 func (p *discoverParams) addFlagsTo(cmd *cobra.Command) {
@@ -115,15 +140,23 @@ func (p *discoverParams) addFlagsTo(cmd *cobra.Command) {
 	addAdvisoriesDirFlag(&p.advisoriesRepoDir, cmd)
 
 	cmd.Flags().StringVarP(&p.packageRepositoryURL, "package-repo-url", "r", "", "URL of the APK package repository")
+	cmd.Flags().StringVar(&p.packagesDir, "packages-dir", "", "directory of the packages of the latest build, to search for instead of the published ones")
+
+	addNVDAPIKeyFlag(&p.nvdAPIKey, cmd)
+
+	p.aliases.addFlagsTo(cmd)
+	cmd.Flags().BoolVar(&p.noAliases, "no-aliases", false, "do not look for vulnerabilities in the advisories under their aliases")
+}
 
-	cmd.Flags().StringVar(&p.nvdAPIKey, "nvd-api-key", "", fmt.Sprintf("NVD API key (Can also be set via the environment variable '%s'. Using an API key significantly increases the rate limit for API requests. If you need an NVD API key, go to https://nvd.nist.gov/developers/request-an-api-key .)", envVarNameForNVDAPIKey))
+func addNVDAPIKeyFlag(val *string, cmd *cobra.Command) {
+	cmd.Flags().StringVar(val, "nvd-api-key", "", fmt.Sprintf("NVD API key (Can also be set via the environment variable '%s'. Using an API key significantly increases the rate limit for API requests. If you need an NVD API key, go to https://nvd.nist.gov/developers/request-an-api-key .)", envVarNameForNVDAPIKey))
 }
 
-func (p *discoverParams) resolveNVDAPIKey() string {
+func resolveNVDAPIKey(cliFlagValue string) string {
 	// TODO: use Viper for this!
 
-	if p.nvdAPIKey != "" {
-		return p.nvdAPIKey
+	if cliFlagValue != "" {
+		return cliFlagValue
 	}
 
 	keyFromEnv := os.Getenv(envVarNameForNVDAPIKey)
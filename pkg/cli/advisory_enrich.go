@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/cli/styles"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln/ghsa"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln/nvdapi"
+)
+
+// enrichmentParams are the flags of the lookup of the details of vulnerabilities, from NVD for CVEs and GitHub for
+// GHSAs, cached to have them offline after the first lookup.
+type enrichmentParams struct {
+	nvdAPIKey, githubToken string
+	cacheDir               string
+	cacheMaxAge            time.Duration
+}
+
+func (p *enrichmentParams) addFlagsTo(cmd *cobra.Command) {
+	addNVDAPIKeyFlag(&p.nvdAPIKey, cmd)
+	cmd.Flags().StringVar(&p.githubToken, "github-token", "", "GitHub token to look up GHSAs with (can also be set with environment variable `GITHUB_TOKEN`)")
+	cmd.Flags().StringVar(&p.cacheDir, "vuln-cache-dir", "", "directory to cache the details of vulnerabilities in (default is wolfictl/vulnerabilities in the user cache directory)")
+	cmd.Flags().DurationVar(&p.cacheMaxAge, "vuln-cache-max-age", 7*24*time.Hour, "how long to use cached details of vulnerabilities before looking them up again, 0 for forever")
+}
+
+func (p *enrichmentParams) enricher() (*vuln.Enricher, error) {
+	cacheDir := p.cacheDir
+	if cacheDir == "" {
+		dir, err := vuln.DefaultCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("unable to determine the vulnerability cache dir, use --vuln-cache-dir: %w", err)
+		}
+		cacheDir = dir
+	}
+
+	githubToken := p.githubToken
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN")
+	}
+
+	return &vuln.Enricher{
+		Detailers: map[string]vuln.Detailer{
+			"CVE-":  nvdapi.NewDetector(http.DefaultClient, nvdapi.DefaultHost, resolveNVDAPIKey(p.nvdAPIKey)),
+			"GHSA-": ghsa.NewClient(http.DefaultClient, ghsa.DefaultHost, githubToken),
+		},
+		CacheDir: cacheDir,
+		MaxAge:   p.cacheMaxAge,
+	}, nil
+}
+
+// renderVulnerabilityDetails renders the severity and context of a vulnerability, for a responder to see what it's
+// about.
+func renderVulnerabilityDetails(d *vuln.Details) string {
+	var lines []string
+	title := d.ID
+	if d.Severity != "" {
+		title = fmt.Sprintf("%s (%s)", title, d.Severity)
+	}
+	lines = append(lines, styles.Accented().Copy().Bold(true).Render(title))
+	if d.Description != "" {
+		lines = append(lines, d.Description)
+	}
+	if len(d.CWEs) > 0 {
+		lines = append(lines, styles.Secondary().Render("CWEs: ")+strings.Join(d.CWEs, ", "))
+	}
+	if d.URL != "" {
+		lines = append(lines, styles.Faint().Render(d.URL))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/distro"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	exportFormatSecDB = "secdb"
+	exportFormatOSV   = "osv"
+)
+
+var exportFormats = []string{exportFormatSecDB, exportFormatOSV}
+
+// secDBFileName is the name scanners look for the security database of an architecture under.
+const secDBFileName = "security.json"
+
+func AdvisoryExport() *cobra.Command {
+	p := &exportParams{}
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "export advisory data for vulnerability scanners",
+		Long: `export advisory data for vulnerability scanners
+
+With --format secdb, the advisories are compiled into an Alpine-compatible
+security database, which scanners like Grype and Trivy read to stop reporting
+the vulnerabilities packages were fixed for, or found not to be affected by.
+The vulnerabilities whose latest advisory entry is fixed are listed under
+their fixed version, and the ones whose latest entry is not_affected under
+version "0".
+
+With --format osv, every advisory whose latest entry is fixed or affected is
+exported as an OSV record, to <output-dir>/<id>.json, for submission to OSV.dev
+and osv-scanner. The fixed ones affect the versions before their fixed
+version, and the affected ones all versions.
+
+A secdb database is written for every --arch, to
+<output-dir>/<arch>/security.json, the layout the package repository serves
+them under. Without --output-dir, the database of the only --arch is written
+to stdout.`,
+		Example: `  wolfictl advisory export --format secdb --arch x86_64 > security.json
+  wolfictl advisory export --format secdb --arch x86_64,aarch64 --output-dir dist
+  wolfictl advisory export --format osv --output-dir osv`,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch p.format {
+			case exportFormatSecDB:
+				if len(p.archs) == 0 {
+					return fmt.Errorf("no architecture specified")
+				}
+				if p.outputDir == "" && len(p.archs) > 1 {
+					return fmt.Errorf("a database is exported per architecture, --output-dir is required for %d of them", len(p.archs))
+				}
+			case exportFormatOSV:
+				if p.outputDir == "" {
+					return fmt.Errorf("a record is exported per advisory, --output-dir is required")
+				}
+			default:
+				return fmt.Errorf("unknown format %q, must be one of: %s", p.format, strings.Join(exportFormats, ", "))
+			}
+
+			if len(p.advisoriesRepoDirs) == 0 {
+				if p.doNotDetectDistro {
+					return fmt.Errorf("no advisories repo dir specified")
+				}
+
+				d, err := distro.Detect()
+				if err != nil {
+					return fmt.Errorf("no advisories repo dir specified, and distro auto-detection failed: %w", err)
+				}
+
+				p.advisoriesRepoDirs = append(p.advisoriesRepoDirs, d.AdvisoriesRepoDir)
+				_, _ = fmt.Fprint(os.Stderr, renderDetectedDistro(d))
+			}
+
+			indices := make([]*configs.Index[advisoryconfigs.Document], 0, len(p.advisoriesRepoDirs))
+			for _, dir := range p.advisoriesRepoDirs {
+				index, err := advisoryconfigs.NewIndex(rwos.DirFS(dir))
+				if err != nil {
+					return fmt.Errorf("unable to index advisory configs for directory %q: %w", dir, err)
+				}
+
+				indices = append(indices, index)
+			}
+
+			opts := advisory.ExportOptions{
+				AdvisoryCfgIndices: indices,
+				URLPrefix:          p.urlPrefix,
+				Repo:               p.repo,
+				Ecosystem:          p.ecosystem,
+			}
+			if p.resolveAliases {
+				r, err := p.aliases.resolver("")
+				if err != nil {
+					return err
+				}
+				if opts.Aliases, err = r.Resolve(cmd.Context(), advisoryIDs(indices)...); err != nil {
+					return err
+				}
+			}
+
+			if p.format == exportFormatOSV {
+				return p.exportOSV(opts)
+			}
+
+			for _, arch := range p.archs {
+				database, err := advisory.ExportSecDB(opts, arch)
+				if err != nil {
+					return err
+				}
+
+				if p.outputDir == "" {
+					_, err = os.Stdout.Write(database)
+					if err != nil {
+						return fmt.Errorf("unable to write the security database: %w", err)
+					}
+					continue
+				}
+
+				dir := filepath.Join(p.outputDir, arch)
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					return fmt.Errorf("unable to create output directory: %w", err)
+				}
+				path := filepath.Join(dir, secDBFileName)
+				if err := os.WriteFile(path, database, 0o644); err != nil { //nolint:gosec // the database is public
+					return fmt.Errorf("unable to write the security database of %s: %w", arch, err)
+				}
+				_, _ = fmt.Fprintf(os.Stderr, "wrote %s\n", path)
+			}
+
+			return nil
+		},
+	}
+
+	p.addFlagsTo(cmd)
+	return cmd
+}
+
+type exportParams struct {
+	doNotDetectDistro bool
+
+	advisoriesRepoDirs []string
+
+	format    string
+	outputDir string
+	ecosystem string
+
+	urlPrefix string
+	archs     []string
+	repo      string
+
+	resolveAliases bool
+	aliases        aliasParams
+}
+
+func (p *exportParams) addFlagsTo(cmd *cobra.Command) {
+	addNoDistroDetectionFlag(&p.doNotDetectDistro, cmd)
+
+	cmd.Flags().StringSliceVarP(&p.advisoriesRepoDirs, "advisories-repo-dir", "a", nil, "directory containing an advisories repository")
+
+	cmd.Flags().StringVarP(&p.format, "format", "f", exportFormatSecDB, "format to export the advisories in, one of: "+strings.Join(exportFormats, ", "))
+	cmd.Flags().StringVarP(&p.outputDir, "output-dir", "o", "", "directory to write a secdb database per architecture, or an OSV record per advisory, to (default: stdout, for a single secdb architecture)")
+
+	cmd.Flags().StringVar(&p.urlPrefix, "url-prefix", "https://packages.wolfi.dev", "URL scheme and hostname for the package repository")
+	cmd.Flags().StringSliceVar(&p.archs, "arch", []string{"x86_64"}, "the package architectures to export a security database for")
+	cmd.Flags().StringVar(&p.repo, "repo", "os", "the name of the package repository")
+	cmd.Flags().StringVar(&p.ecosystem, "ecosystem", "Wolfi", "the OSV ecosystem of the packages")
+
+	cmd.Flags().BoolVar(&p.resolveAliases, "resolve-aliases", false, "resolve the aliases of the vulnerabilities, to export the advisories of one vulnerability as one, under all its IDs")
+	p.aliases.addFlagsTo(cmd)
+}
+
+// advisoryIDs returns the IDs of the vulnerabilities of the advisories, sorted and without duplicates.
+func advisoryIDs(indices []*configs.Index[advisoryconfigs.Document]) []string {
+	var ids []string
+	for _, index := range indices {
+		for _, doc := range index.Select().Configurations() {
+			for id := range doc.Advisories {
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return slices.Compact(ids)
+}
+
+// exportOSV writes the OSV records of the advisories to the output directory, one file per record.
+func (p *exportParams) exportOSV(opts advisory.ExportOptions) error {
+	records, err := advisory.ExportOSV(opts)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(p.outputDir, 0o755); err != nil {
+		return fmt.Errorf("unable to create output directory: %w", err)
+	}
+	for i := range records {
+		b, err := json.MarshalIndent(records[i], "", "  ")
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(p.outputDir, records[i].ID+".json")
+		if err := os.WriteFile(path, b, 0o644); err != nil { //nolint:gosec // the records are public
+			return fmt.Errorf("unable to write the OSV record %s: %w", records[i].ID, err)
+		}
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "wrote %d OSV records to %s\n", len(records), p.outputDir)
+
+	return nil
+}
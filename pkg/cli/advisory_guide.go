@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/cli/components/advisory/guide"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	buildconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/build"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/distro"
+	"github.com/wolfi-dev/wolfictl/pkg/index"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+)
+
+func AdvisoryGuide() *cobra.Command {
+	p := &guideParams{}
+	cmd := &cobra.Command{
+		Use:   "guide",
+		Short: "walk through the open vulnerabilities of a package to triage them",
+		Long: `walk through the open vulnerabilities of a package to triage them
+
+The open vulnerabilities of the package, the ones whose latest advisory event
+is detected, are shown one at a time, with their description, severity and
+affected versions from NVD or GitHub, and whether upstream fixed them in a
+version the package is at. The details are cached, so they're shown offline
+once they were looked up. For each of them, record a determination, which is
+appended to the advisory as the event it is: fixed, with the version that
+fixed it, false-positive, with why the package isn't affected, or
+fix-not-planned. The determination the upstream fix suggests is selected
+first, and vulnerabilities can be skipped to triage them later.`,
+		Example:       `  wolfictl advisory guide --package curl`,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if p.packageName == "" {
+				return fmt.Errorf("no package specified, use --package")
+			}
+
+			archs := p.archs
+			packageRepositoryURL := p.packageRepositoryURL
+			distroRepoDir := resolveDistroDir(p.distroRepoDir)
+			advisoriesRepoDir := resolveAdvisoriesDir(p.advisoriesRepoDir)
+			if distroRepoDir == "" || advisoriesRepoDir == "" {
+				if p.doNotDetectDistro {
+					return fmt.Errorf("distro repo dir and/or advisories repo dir was left unspecified")
+				}
+
+				d, err := distro.Detect()
+				if err != nil {
+					return fmt.Errorf("distro repo dir and/or advisories repo dir was left unspecified, and distro auto-detection failed: %w", err)
+				}
+
+				if len(archs) == 0 {
+					archs = d.SupportedArchitectures
+				}
+
+				if packageRepositoryURL == "" {
+					packageRepositoryURL = d.APKRepositoryURL
+				}
+
+				distroRepoDir = d.DistroRepoDir
+				advisoriesRepoDir = d.AdvisoriesRepoDir
+				_, _ = fmt.Fprint(os.Stderr, renderDetectedDistro(d))
+			}
+
+			advisoryCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(advisoriesRepoDir))
+			if err != nil {
+				return err
+			}
+			doc, err := advisoryCfgs.Select().WhereName(p.packageName).First()
+			if err != nil {
+				return fmt.Errorf("no advisories found for package %q", p.packageName)
+			}
+			open := advisory.OpenVulnerabilities(*doc.Configuration())
+			if len(open) == 0 {
+				_, _ = fmt.Fprintf(os.Stderr, "%s has no open vulnerabilities to triage 🎉\n", p.packageName)
+				return nil
+			}
+
+			buildCfgs, err := buildconfigs.NewIndex(rwos.DirFS(distroRepoDir))
+			if err != nil {
+				return fmt.Errorf("unable to select packages: %w", err)
+			}
+			packageVersion := ""
+			if entry, err := buildCfgs.Select().WhereName(p.packageName).First(); err == nil {
+				cfg := entry.Configuration()
+				packageVersion = fmt.Sprintf("%s-r%d", cfg.Package.Version, cfg.Package.Epoch)
+			}
+
+			var apkindexes []*repository.ApkIndex
+			for _, arch := range archs {
+				idx, err := index.Index(cmd.Context(), arch, packageRepositoryURL)
+				if err != nil {
+					return fmt.Errorf("unable to load APKINDEX for %s: %w", arch, err)
+				}
+				apkindexes = append(apkindexes, idx)
+			}
+
+			detailer, err := p.enrichment.enricher()
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+
+			m := guide.New(guide.Configuration{
+				Vulnerabilities: open,
+				TriageFunc: func(vulnerability string) (advisory.Triage, error) {
+					details, err := detailer.VulnerabilityDetails(ctx, p.packageName, vulnerability)
+					return advisory.NewTriage(p.packageName, packageVersion, vulnerability, details), err
+				},
+				AllowedFixedVersionsFunc: newAllowedFixedVersionsFunc(apkindexes, buildCfgs),
+				RecordFunc: func(req advisory.Request) error {
+					req.Timestamp = time.Now()
+					if err := req.Validate(); err != nil {
+						return err
+					}
+					return advisory.Update(req, advisory.UpdateOptions{AdvisoryCfgs: advisoryCfgs})
+				},
+				Request: advisory.Request{Package: p.packageName},
+			})
+
+			returnedModel, err := tea.NewProgram(m).Run()
+			if err != nil {
+				return err
+			}
+			m, ok := returnedModel.(guide.Model)
+			if !ok {
+				return fmt.Errorf("unexpected model type: %T", returnedModel)
+			}
+			if err := m.Err(); err != nil {
+				return fmt.Errorf("unable to record the determination: %w", err)
+			}
+
+			for _, r := range m.Recorded {
+				_, _ = fmt.Fprintf(os.Stderr, "recorded %s\n", r)
+			}
+
+			if p.sync && len(m.Recorded) > 0 {
+				return doFollowupSync(advisoryCfgs.Select().WhereName(p.packageName))
+			}
+
+			return nil
+		},
+	}
+
+	p.addFlagsTo(cmd)
+	return cmd
+}
+
+type guideParams struct {
+	doNotDetectDistro bool
+
+	packageName                      string
+	distroRepoDir, advisoriesRepoDir string
+	archs                            []string
+	packageRepositoryURL             string
+	enrichment                       enrichmentParams
+	sync                             bool
+}
+
+func (p *guideParams) addFlagsTo(cmd *cobra.Command) {
+	addNoDistroDetectionFlag(&p.doNotDetectDistro, cmd)
+
+	addPackageFlag(&p.packageName, cmd)
+	addDistroDirFlag(&p.distroRepoDir, cmd)
+	addAdvisoriesDirFlag(&p.advisoriesRepoDir, cmd)
+	cmd.Flags().StringSliceVar(&p.archs, "arch", []string{"x86_64", "aarch64"}, "package architectures to find published versions for")
+	cmd.Flags().StringVarP(&p.packageRepositoryURL, "package-repo-url", "r", "", "URL of the APK package repository")
+	p.enrichment.addFlagsTo(cmd)
+	cmd.Flags().BoolVar(&p.sync, "sync", true, "synchronize secfixes data after recording determinations")
+}
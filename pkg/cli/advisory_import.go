@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	buildconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/build"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/distro"
+)
+
+const (
+	importSourceAlpine = "alpine"
+	importSourceDebian = "debian"
+)
+
+// importDefaultLocations are where the security data of the distros advisories can be imported from is published.
+var importDefaultLocations = map[string][]string{
+	importSourceAlpine: {
+		"https://secdb.alpinelinux.org/edge/main.json",
+		"https://secdb.alpinelinux.org/edge/community.json",
+	},
+	importSourceDebian: {
+		"https://security-tracker.debian.org/tracker/data/json",
+	},
+}
+
+func AdvisoryImport() *cobra.Command {
+	p := &importParams{}
+	cmd := &cobra.Command{
+		Use:   "import {alpine|debian}",
+		Short: "propose advisory events from the security data of another distro",
+		Long: `propose advisory events from the security data of another distro
+
+The security data of Alpine (its secdb, of edge by default) or Debian (its
+security tracker, of the --release, sid by default) is read, and for the
+packages the distro has under the same name, advisory events are proposed for
+the vulnerabilities the advisories don't have yet.
+
+Every proposal is detected, with what the other distro found as its impact,
+e.g. "Alpine secdb: fixed in 8.4.0-r0", and a note suggesting what to record
+instead once confirmed:
+
+  - for the ones the other distro isn't affected by, a false positive
+  - for the ones it fixed, fixed in the current version of the package if that's
+    at least the upstream version it fixed them in
+
+The proposals are written as a manifest for a responder to confirm, edit or
+remove before applying it with 'wolfictl advisory apply'. Nothing is written to
+the advisories.`,
+		Example: `  wolfictl advisory import alpine -o alpine.json
+  wolfictl advisory import debian --release bookworm --format csv -o debian.csv
+  wolfictl advisory apply --from alpine.json`,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		ValidArgs:     []string{importSourceAlpine, importSourceDebian},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source := args[0]
+			locations, ok := importDefaultLocations[source]
+			if !ok {
+				return fmt.Errorf("unknown distro %q to import from, must be one of: %s, %s", source, importSourceAlpine, importSourceDebian)
+			}
+			if len(p.from) > 0 {
+				locations = p.from
+			}
+			format := advisory.ManifestFormat(p.format)
+			if format != advisory.ManifestFormatJSON && format != advisory.ManifestFormatCSV {
+				return fmt.Errorf("unknown format %q, must be one of: %s, %s", p.format, advisory.ManifestFormatJSON, advisory.ManifestFormatCSV)
+			}
+
+			distroRepoDir := resolveDistroDir(p.distroRepoDir)
+			advisoriesRepoDir := resolveAdvisoriesDir(p.advisoriesRepoDir)
+			if distroRepoDir == "" || advisoriesRepoDir == "" {
+				if p.doNotDetectDistro {
+					return fmt.Errorf("distro repo dir and/or advisories repo dir was left unspecified")
+				}
+
+				d, err := distro.Detect()
+				if err != nil {
+					return fmt.Errorf("distro repo dir and/or advisories repo dir was left unspecified, and distro auto-detection failed: %w", err)
+				}
+
+				distroRepoDir = d.DistroRepoDir
+				advisoriesRepoDir = d.AdvisoriesRepoDir
+				_, _ = fmt.Fprint(os.Stderr, renderDetectedDistro(d))
+			}
+
+			advisoryCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(advisoriesRepoDir))
+			if err != nil {
+				return err
+			}
+			buildCfgs, err := buildconfigs.NewIndex(rwos.DirFS(distroRepoDir))
+			if err != nil {
+				return fmt.Errorf("unable to select packages: %w", err)
+			}
+
+			var foreign []advisory.ForeignAdvisory
+			for _, location := range locations {
+				advisories, err := p.readForeignAdvisories(cmd.Context(), source, location)
+				if err != nil {
+					return err
+				}
+				foreign = append(foreign, advisories...)
+			}
+
+			sourceName := "Alpine secdb"
+			if source == importSourceDebian {
+				sourceName = fmt.Sprintf("the Debian security tracker (%s)", p.release)
+			}
+			proposals := advisory.ProposeImport(foreign, advisory.ImportOptions{
+				Source:          sourceName,
+				AdvisoryCfgs:    advisoryCfgs,
+				PackageVersions: packageVersions(buildCfgs),
+			})
+			_, _ = fmt.Fprintf(os.Stderr, "proposing %d advisory event(s) from %s\n", len(proposals), sourceName)
+
+			var w io.Writer = cmd.OutOrStdout()
+			if p.output != "" {
+				file, err := os.Create(p.output)
+				if err != nil {
+					return fmt.Errorf("unable to open output file: %w", err)
+				}
+				defer file.Close()
+				w = file
+			}
+			return advisory.WriteManifest(w, format, proposals)
+		},
+	}
+
+	p.addFlagsTo(cmd)
+	return cmd
+}
+
+type importParams struct {
+	doNotDetectDistro bool
+
+	distroRepoDir, advisoriesRepoDir string
+
+	from    []string
+	release string
+	format  string
+	output  string
+}
+
+func (p *importParams) addFlagsTo(cmd *cobra.Command) {
+	addNoDistroDetectionFlag(&p.doNotDetectDistro, cmd)
+
+	addDistroDirFlag(&p.distroRepoDir, cmd)
+	addAdvisoriesDirFlag(&p.advisoriesRepoDir, cmd)
+
+	cmd.Flags().StringSliceVar(&p.from, "from", nil, "URLs or files of the security data to import (default: the published data of the distro)")
+	cmd.Flags().StringVar(&p.release, "release", "sid", "Debian release whose status of the vulnerabilities to import")
+	cmd.Flags().StringVarP(&p.format, "format", "f", string(advisory.ManifestFormatJSON), fmt.Sprintf("format of the manifest, one of: %s, %s", advisory.ManifestFormatJSON, advisory.ManifestFormatCSV))
+	cmd.Flags().StringVarP(&p.output, "output", "o", "", "output location of the manifest (default: stdout)")
+}
+
+// readForeignAdvisories reads the security data of the distro at the location, a URL or a file.
+func (p *importParams) readForeignAdvisories(ctx context.Context, source, location string) ([]advisory.ForeignAdvisory, error) {
+	var r io.ReadCloser
+	if strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, http.NoBody)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch %s: %w", location, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unable to fetch %s: got response status %d", location, resp.StatusCode)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(location)
+		if err != nil {
+			return nil, err
+		}
+		r = f
+	}
+	defer r.Close()
+
+	var advisories []advisory.ForeignAdvisory
+	var err error
+	if source == importSourceDebian {
+		advisories, err = advisory.ParseDebianTracker(r, p.release)
+	} else {
+		advisories, err = advisory.ParseAlpineSecDB(r)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", location, err)
+	}
+	return advisories, nil
+}
+
+// packageVersions returns the current version of every package of the distro, by name.
+func packageVersions(buildCfgs *configs.Index[build.Configuration]) map[string]string {
+	versions := make(map[string]string)
+	for _, cfg := range buildCfgs.Select().Configurations() {
+		versions[cfg.Package.Name] = fmt.Sprintf("%s-r%d", cfg.Package.Version, cfg.Package.Epoch)
+	}
+	return versions
+}
@@ -36,6 +36,12 @@ func AdvisoryList() *cobra.Command {
 				_, _ = fmt.Fprint(os.Stderr, renderDetectedDistro(d))
 			}
 
+			if p.event != "" {
+				if _, err := advisory.ParseEvent(p.event); err != nil {
+					return err
+				}
+			}
+
 			advisoriesFsys := rwos.DirFS(advisoriesRepoDir)
 			advisoryCfgs, err := advisoryconfigs.NewIndex(advisoriesFsys)
 			if err != nil {
@@ -70,6 +76,11 @@ func AdvisoryList() *cobra.Command {
 						continue
 					}
 
+					if p.event != "" && advisory.EventOf(*latest) != advisory.Event(p.event) {
+						// user only wants to see advisories whose latest event is a particular one
+						continue
+					}
+
 					if p.history {
 						sort.SliceStable(entries, func(i, j int) bool {
 							return entries[i].Timestamp.Before(entries[j].Timestamp)
@@ -107,6 +118,7 @@ type listParams struct {
 	vuln        string
 	history     bool
 	unresolved  bool
+	event       string
 }
 
 func (p *listParams) addFlagsTo(cmd *cobra.Command) {
@@ -119,6 +131,7 @@ func (p *listParams) addFlagsTo(cmd *cobra.Command) {
 
 	cmd.Flags().BoolVar(&p.history, "history", false, "show full history for advisories")
 	cmd.Flags().BoolVar(&p.unresolved, "unresolved", false, fmt.Sprintf("only show advisories whose latest status is %s or %s", vex.StatusAffected, vex.StatusUnderInvestigation))
+	cmd.Flags().StringVarP(&p.event, "event", "e", "", fmt.Sprintf("only show advisories whose latest event is this one, one of: %s", eventNames()))
 }
 
 func renderListItem(entry advisoryconfigs.Entry) string {
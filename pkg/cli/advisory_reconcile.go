@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwfsOS "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/distro"
+	"github.com/wolfi-dev/wolfictl/pkg/notify"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln/nvdapi"
+)
+
+func AdvisoryReconcile() *cobra.Command {
+	p := &reconcileParams{}
+	cmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "find advisories whose status drifted from the published packages",
+		Long: `find advisories whose status drifted from the published packages
+
+The latest published version of every package with advisories is looked up in
+the package repository, and checked against the vulnerable versions NVD knows
+of. Two kinds of drift are flagged:
+
+  still-affected  the advisory is under_investigation or affected, but the
+                  published version is past the vulnerable versions
+  reintroduced    the advisory is fixed, but the published version, at or past
+                  the fixed version, is vulnerable again
+
+Advisories aren't changed, the findings are printed, and posted to
+--notify-webhook if set.
+
+With --watch, the check runs again every --interval until interrupted. The
+advisories are read from disk again every time, so keep the advisories repo
+dir up to date, e.g. with a git pull in a cron job. Only findings that are new
+since the previous check are posted.`,
+		Example: `  wolfictl advisory reconcile
+  wolfictl advisory reconcile --watch --interval 6h --notify-webhook https://hooks.slack.com/services/...`,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			packageRepositoryURL := p.packageRepositoryURL
+
+			advisoriesRepoDir := resolveAdvisoriesDir(p.advisoriesRepoDir)
+			if advisoriesRepoDir == "" || packageRepositoryURL == "" {
+				if p.doNotDetectDistro {
+					return fmt.Errorf("advisories repo dir and/or package repository URL was left unspecified")
+				}
+
+				d, err := distro.Detect()
+				if err != nil {
+					return fmt.Errorf("advisories repo dir and/or package repository URL was left unspecified, and distro auto-detection failed: %w", err)
+				}
+
+				if advisoriesRepoDir == "" {
+					advisoriesRepoDir = d.AdvisoriesRepoDir
+				}
+				if packageRepositoryURL == "" {
+					packageRepositoryURL = d.APKRepositoryURL
+				}
+
+				_, _ = fmt.Fprint(os.Stderr, renderDetectedDistro(d))
+			}
+
+			var selectedPackages []string
+			if p.packageName != "" {
+				selectedPackages = []string{p.packageName}
+			}
+
+			r := &reconciler{
+				advisoriesRepoDir: advisoriesRepoDir,
+				opts: advisory.ReconcileOptions{
+					SelectedPackages:      selectedPackages,
+					PackageRepositoryURL:  packageRepositoryURL,
+					Arches:                []string{"x86_64", "aarch64"},
+					VulnerabilityDetector: nvdapi.NewDetector(http.DefaultClient, nvdapi.DefaultHost, resolveNVDAPIKey(p.nvdAPIKey)),
+				},
+				reported: make(map[string]bool),
+				logger:   log.New(log.Writer(), "wolfictl advisory reconcile: ", log.LstdFlags|log.Lmsgprefix),
+			}
+			if p.notifyWebhook != "" {
+				r.notifier = notify.Webhook{URL: p.notifyWebhook}
+			}
+
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if !p.watch {
+				return r.run(ctx)
+			}
+
+			if p.interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return r.watch(ctx, p.interval)
+		},
+	}
+
+	p.addFlagsTo(cmd)
+	return cmd
+}
+
+type reconcileParams struct {
+	doNotDetectDistro bool
+
+	packageName string
+
+	advisoriesRepoDir string
+
+	packageRepositoryURL string
+
+	nvdAPIKey string
+
+	notifyWebhook string
+
+	watch    bool
+	interval time.Duration
+}
+
+func (p *reconcileParams) addFlagsTo(cmd *cobra.Command) {
+	addNoDistroDetectionFlag(&p.doNotDetectDistro, cmd)
+
+	addPackageFlag(&p.packageName, cmd)
+
+	addAdvisoriesDirFlag(&p.advisoriesRepoDir, cmd)
+
+	cmd.Flags().StringVarP(&p.packageRepositoryURL, "package-repo-url", "r", "", "URL of the APK package repository")
+
+	addNVDAPIKeyFlag(&p.nvdAPIKey, cmd)
+
+	cmd.Flags().StringVar(&p.notifyWebhook, "notify-webhook", "", "incoming webhook URL to post findings to, e.g. of Slack or Mattermost")
+	cmd.Flags().BoolVar(&p.watch, "watch", false, "check again every --interval until interrupted")
+	cmd.Flags().DurationVar(&p.interval, "interval", 6*time.Hour, "how often to check with --watch")
+}
+
+// reconciler checks the advisories for drift, and reports each finding once for as long as it lasts.
+type reconciler struct {
+	advisoriesRepoDir string
+	opts              advisory.ReconcileOptions
+	notifier          notify.Notifier
+	logger            *log.Logger
+
+	// reported are the keys of the drifts found by the previous check
+	reported map[string]bool
+}
+
+// run checks once, and prints all the findings.
+func (r *reconciler) run(ctx context.Context) error {
+	drifts, err := r.check(ctx)
+	if err != nil {
+		return err
+	}
+	if len(drifts) == 0 {
+		log.Printf("INFO: no advisories drifted from the published packages")
+		return nil
+	}
+	for _, d := range drifts {
+		fmt.Printf("%s: %s\n", d.Kind, d)
+	}
+	return r.notify(ctx, drifts)
+}
+
+// watch checks every interval until ctx is done. A failed check is logged, and doesn't stop watching.
+func (r *reconciler) watch(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		drifts, err := r.check(ctx)
+		if err != nil {
+			r.logger.Printf("check failed: %v", err)
+		} else {
+			r.logger.Printf("%d advisories drifted, %d of them new", len(drifts), len(r.unreported(drifts)))
+			if err := r.notify(ctx, r.unreported(drifts)); err != nil {
+				r.logger.Printf("%v", err)
+			}
+			r.remember(drifts)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *reconciler) check(ctx context.Context) ([]advisory.Drift, error) {
+	advisoryCfgs, err := advisoryconfigs.NewIndex(rwfsOS.DirFS(r.advisoriesRepoDir))
+	if err != nil {
+		return nil, err
+	}
+	opts := r.opts
+	opts.AdvisoryCfgs = advisoryCfgs
+	return advisory.Reconcile(ctx, opts)
+}
+
+// unreported returns the drifts that weren't found by the previous check.
+func (r *reconciler) unreported(drifts []advisory.Drift) []advisory.Drift {
+	var fresh []advisory.Drift
+	for _, d := range drifts {
+		if !r.reported[d.Key()] {
+			fresh = append(fresh, d)
+		}
+	}
+	return fresh
+}
+
+// remember replaces the drifts of the previous check, so a drift that was resolved is reported again if it comes
+// back.
+func (r *reconciler) remember(drifts []advisory.Drift) {
+	r.reported = make(map[string]bool, len(drifts))
+	for _, d := range drifts {
+		r.reported[d.Key()] = true
+	}
+}
+
+func (r *reconciler) notify(ctx context.Context, drifts []advisory.Drift) error {
+	if r.notifier == nil || len(drifts) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(drifts))
+	for _, d := range drifts {
+		lines = append(lines, fmt.Sprintf("- %s: %s", d.Kind, d))
+	}
+	return r.notifier.Notify(ctx, notify.Message{
+		Title: fmt.Sprintf("%d advisories drifted from the published packages", len(drifts)),
+		Text:  strings.Join(lines, "\n"),
+	})
+}
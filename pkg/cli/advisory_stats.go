@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/distro"
+)
+
+var statsFormats = []string{string(advisory.StatsFormatMarkdown), string(advisory.StatsFormatJSON), string(advisory.StatsFormatCSV)}
+
+func AdvisoryStats() *cobra.Command {
+	p := &statsParams{}
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "report the open advisories by severity and age, and the time to remediation",
+		Long: `report the open advisories by severity and age, and the time to remediation
+
+Advisories whose latest event is detected, or fix-not-planned, are open. They
+are counted by severity, with how many are older than the --sla of their
+severity, by age since they were detected, and by package, the --top packages
+with the most of them listed. The mean time to remediation is the mean time
+from the first event of the fixed advisories to the first fixed one.
+
+Severities are the ones NVD or GitHub rate the vulnerabilities with, looked up
+and cached like the details shown by 'wolfictl advisory create', unless
+--no-enrich is given, in which case they're all unknown.
+
+The report is written as Markdown tables (--format markdown), as JSON with
+every open advisory (--format json), or as CSV with a row per open advisory
+(--format csv), to embed in weekly security reports.`,
+		Example: `  wolfictl advisory stats
+  wolfictl advisory stats --format json -o stats.json
+  wolfictl advisory stats --sla critical=3,high=14 --no-enrich`,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format := advisory.StatsFormat(p.format)
+			switch format {
+			case advisory.StatsFormatMarkdown, advisory.StatsFormatJSON, advisory.StatsFormatCSV:
+			default:
+				return fmt.Errorf("unknown format %q, must be one of: %s", p.format, strings.Join(statsFormats, ", "))
+			}
+
+			slas := make(map[string]int, len(advisory.DefaultSLAs))
+			for severity, days := range advisory.DefaultSLAs {
+				slas[severity] = days
+			}
+			for severity, days := range p.slas {
+				level := advisory.SeverityLevel(severity)
+				if level == advisory.SeverityUnknown {
+					return fmt.Errorf("unknown severity %q in --sla, must be one of: critical, high, medium, low", severity)
+				}
+				slas[level] = days
+			}
+
+			advisoriesRepoDir := resolveAdvisoriesDir(p.advisoriesRepoDir)
+			if advisoriesRepoDir == "" {
+				if p.doNotDetectDistro {
+					return fmt.Errorf("no advisories repo dir specified")
+				}
+
+				d, err := distro.Detect()
+				if err != nil {
+					return fmt.Errorf("no advisories repo dir specified, and distro auto-detection failed: %w", err)
+				}
+
+				advisoriesRepoDir = d.AdvisoriesRepoDir
+				_, _ = fmt.Fprint(os.Stderr, renderDetectedDistro(d))
+			}
+
+			advisoryCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(advisoriesRepoDir))
+			if err != nil {
+				return err
+			}
+
+			opts := advisory.StatsOptions{
+				Now:  time.Now().UTC(),
+				SLAs: slas,
+				Top:  p.top,
+			}
+			if !p.noEnrich {
+				enricher, err := p.enrichment.enricher()
+				if err != nil {
+					return err
+				}
+				failed := 0
+				opts.Severity = func(packageName, vulnerability string) string {
+					details, err := enricher.VulnerabilityDetails(cmd.Context(), packageName, vulnerability)
+					if err != nil {
+						failed++
+						return advisory.SeverityUnknown
+					}
+					return advisory.SeverityLevel(details.Severity)
+				}
+				defer func() {
+					if failed > 0 {
+						log.Printf("⚠️  unable to look up the severity of %d vulnerabilities, counted as unknown", failed)
+					}
+				}()
+			}
+
+			stats := advisory.NewStats(advisoryCfgs.Select().Configurations(), opts)
+
+			var w io.Writer = cmd.OutOrStdout()
+			if p.output != "" {
+				file, err := os.Create(p.output)
+				if err != nil {
+					return fmt.Errorf("unable to open output file: %w", err)
+				}
+				defer file.Close()
+				w = file
+			}
+			return stats.Write(w, format)
+		},
+	}
+
+	p.addFlagsTo(cmd)
+	return cmd
+}
+
+type statsParams struct {
+	doNotDetectDistro bool
+
+	advisoriesRepoDir string
+
+	format string
+	output string
+	top    int
+	slas   map[string]int
+
+	enrichment enrichmentParams
+	noEnrich   bool
+}
+
+func (p *statsParams) addFlagsTo(cmd *cobra.Command) {
+	addNoDistroDetectionFlag(&p.doNotDetectDistro, cmd)
+	addAdvisoriesDirFlag(&p.advisoriesRepoDir, cmd)
+
+	cmd.Flags().StringVarP(&p.format, "format", "f", string(advisory.StatsFormatMarkdown), "output format, one of: "+strings.Join(statsFormats, ", "))
+	cmd.Flags().StringVarP(&p.output, "output", "o", "", "output location (default: stdout)")
+	cmd.Flags().IntVar(&p.top, "top", 10, "number of packages with the most open advisories to list, all if 0")
+	cmd.Flags().StringToIntVar(&p.slas, "sla", nil, "days within which open advisories of a severity are expected to be resolved, e.g. critical=7 (default critical=7,high=30,medium=90,low=180)")
+
+	p.enrichment.addFlagsTo(cmd)
+	cmd.Flags().BoolVar(&p.noEnrich, "no-enrich", false, "do not look up the severities of the vulnerabilities")
+}
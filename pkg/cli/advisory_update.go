@@ -1,15 +1,20 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"sort"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/samber/lo"
 	"github.com/spf13/cobra"
 	"github.com/wolfi-dev/wolfictl/pkg/advisory"
 	"github.com/wolfi-dev/wolfictl/pkg/cli/components/advisory/prompt"
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
 	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
 	buildconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/build"
 	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
@@ -21,8 +26,16 @@ import (
 func AdvisoryUpdate() *cobra.Command {
 	p := &updateParams{}
 	cmd := &cobra.Command{
-		Use:           "update",
-		Short:         "append an entry to an existing package advisory",
+		Use:   "update",
+		Short: "append an entry to an existing package advisory",
+		Long: `append an entry to an existing package advisory
+
+With --packages-matching, the entry is appended to the advisory for --vuln of
+every package whose name matches the glob pattern and has one, to record a
+determination that holds for many packages at once, e.g. a false positive
+across an ecosystem. Nothing is prompted for then.`,
+		Example: `  wolfictl advisory update -p curl -V CVE-2023-0001 --event fixed --fixed-version 8.1.0-r0
+  wolfictl advisory update -V CVE-2024-1234 --packages-matching 'py3-*' --event false-positive --justification vulnerable_code_not_present`,
 		SilenceErrors: true,
 		Args:          cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -70,9 +83,13 @@ func AdvisoryUpdate() *cobra.Command {
 				return err
 			}
 
+			if p.packagesMatching != "" {
+				return p.updateMatching(cmd.Context(), req, advisoryCfgs)
+			}
+
 			var apkindexes []*repository.ApkIndex
 			for _, arch := range archs {
-				idx, err := index.Index(arch, packageRepositoryURL)
+				idx, err := index.Index(cmd.Context(), arch, packageRepositoryURL)
 				if err != nil {
 					return fmt.Errorf("unable to load APKINDEX for %s: %w", arch, err)
 				}
@@ -134,6 +151,9 @@ func AdvisoryUpdate() *cobra.Command {
 			opts := advisory.UpdateOptions{
 				AdvisoryCfgs: advisoryCfgs,
 			}
+			if !p.noAliases {
+				opts.Aliases = p.aliases.resolveOrWarn(cmd.Context(), "", req.Vulnerability)
+			}
 
 			err = advisory.Update(req, opts)
 			if err != nil {
@@ -163,6 +183,9 @@ type updateParams struct {
 	distroRepoDir, advisoriesRepoDir string
 	archs                            []string
 	packageRepositoryURL             string
+	aliases                          aliasParams
+	noAliases                        bool
+	packagesMatching                 string
 }
 
 func (p *updateParams) addFlagsTo(cmd *cobra.Command) {
@@ -174,4 +197,40 @@ func (p *updateParams) addFlagsTo(cmd *cobra.Command) {
 	addAdvisoriesDirFlag(&p.advisoriesRepoDir, cmd)
 	cmd.Flags().StringSliceVar(&p.archs, "arch", []string{"x86_64", "aarch64"}, "package architectures to find published versions for")
 	cmd.Flags().StringVarP(&p.packageRepositoryURL, "package-repo-url", "r", "", "URL of the APK package repository")
+	p.aliases.addFlagsTo(cmd)
+	cmd.Flags().BoolVar(&p.noAliases, "no-aliases", false, "do not look for the advisory under the aliases of the vulnerability")
+	cmd.Flags().StringVar(&p.packagesMatching, "packages-matching", "", "glob pattern of the packages to update the advisory of, e.g. 'py3-*', instead of --package")
+}
+
+// updateMatching adds the entry of the request to the advisory of every package matching --packages-matching that has
+// one for the vulnerability, for determinations that hold for many packages at once. Nothing is prompted for.
+func (p *updateParams) updateMatching(ctx context.Context, req advisory.Request, advisoryCfgs *configs.Index[advisoryconfigs.Document]) error {
+	if req.Package != "" {
+		return errors.New("--package and --packages-matching can't both be given")
+	}
+	if req.Vulnerability == "" {
+		return errors.New("--packages-matching needs the vulnerability to update the advisories of, use --vuln")
+	}
+
+	opts := advisory.UpdateOptions{
+		AdvisoryCfgs: advisoryCfgs,
+	}
+	if !p.noAliases {
+		opts.Aliases = p.aliases.resolveOrWarn(ctx, "", req.Vulnerability)
+	}
+
+	packages, err := advisory.UpdateMatching(req, p.packagesMatching, opts)
+	if err != nil {
+		return err
+	}
+	log.Printf("updated the advisory for %s of %d package(s): %s", req.Vulnerability, len(packages), strings.Join(packages, ", "))
+
+	if p.requestParams.sync {
+		for _, name := range packages {
+			if err := doFollowupSync(advisoryCfgs.Select().WhereName(name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
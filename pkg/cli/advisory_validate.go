@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	buildconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/build"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/distro"
+	"github.com/wolfi-dev/wolfictl/pkg/index"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+)
+
+func AdvisoryValidate() *cobra.Command {
+	p := &validateParams{}
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "check the advisory documents for problems",
+		Long: `check the advisory documents for problems
+
+Every advisory document of the advisories repo is checked for:
+
+  - following the schema, without unknown fields, and being named after its
+    package
+  - being about a package one of the configs of the distro repo builds
+  - its vulnerabilities being CVE or GHSA IDs
+  - its entries being in chronological order, with a valid status and the
+    justification, action or fixed version the status requires
+  - fixed versions being package versions, not older than the version the
+    package was at when the vulnerability was detected
+
+The version at detection is the latest version of the package the package
+repository had built by the timestamp of the first entry. Without a package
+repository URL, fixed versions aren't checked against it.
+
+The problems are printed, and the command fails if there are any, so it can
+gate changes to the advisories repo in CI.`,
+		Example: `  wolfictl advisory validate
+  wolfictl advisory validate --advisories-repo-dir ../advisories --distro-repo-dir ../os --package-repo-url ""`,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			packageRepositoryURL := p.packageRepositoryURL
+			distroRepoDir := resolveDistroDir(p.distroRepoDir)
+			advisoriesRepoDir := resolveAdvisoriesDir(p.advisoriesRepoDir)
+			if distroRepoDir == "" || advisoriesRepoDir == "" {
+				if p.doNotDetectDistro {
+					return fmt.Errorf("distro repo dir and/or advisories repo dir was left unspecified")
+				}
+
+				d, err := distro.Detect()
+				if err != nil {
+					return fmt.Errorf("distro repo dir and/or advisories repo dir was left unspecified, and distro auto-detection failed: %w", err)
+				}
+
+				if !cmd.Flags().Changed("package-repo-url") {
+					packageRepositoryURL = d.APKRepositoryURL
+				}
+
+				distroRepoDir = d.DistroRepoDir
+				advisoriesRepoDir = d.AdvisoriesRepoDir
+				_, _ = fmt.Fprint(os.Stderr, renderDetectedDistro(d))
+			}
+
+			buildCfgs, err := buildconfigs.NewIndex(rwos.DirFS(distroRepoDir))
+			if err != nil {
+				return fmt.Errorf("unable to select packages: %w", err)
+			}
+
+			var apkindexes []*repository.ApkIndex
+			if packageRepositoryURL != "" {
+				for _, arch := range p.archs {
+					idx, err := index.Index(cmd.Context(), arch, packageRepositoryURL)
+					if err != nil {
+						return fmt.Errorf("unable to load APKINDEX for %s: %w", arch, err)
+					}
+					apkindexes = append(apkindexes, idx)
+				}
+			}
+
+			problems, err := advisory.Validate(advisory.ValidateOptions{
+				AdvisoryFsys: os.DirFS(advisoriesRepoDir),
+				BuildCfgs:    buildCfgs,
+				APKIndexes:   apkindexes,
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, problem := range problems {
+				fmt.Println(problem)
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("found %d problems in the advisory documents", len(problems))
+			}
+
+			_, _ = fmt.Fprintln(os.Stderr, "advisory documents are valid ✅")
+			return nil
+		},
+	}
+
+	p.addFlagsTo(cmd)
+	return cmd
+}
+
+type validateParams struct {
+	doNotDetectDistro bool
+
+	distroRepoDir, advisoriesRepoDir string
+	archs                            []string
+	packageRepositoryURL             string
+}
+
+func (p *validateParams) addFlagsTo(cmd *cobra.Command) {
+	addNoDistroDetectionFlag(&p.doNotDetectDistro, cmd)
+
+	addDistroDirFlag(&p.distroRepoDir, cmd)
+	addAdvisoriesDirFlag(&p.advisoriesRepoDir, cmd)
+	cmd.Flags().StringSliceVar(&p.archs, "arch", []string{"x86_64", "aarch64"}, "package architectures to find the versions at detection for")
+	cmd.Flags().StringVarP(&p.packageRepositoryURL, "package-repo-url", "r", "", "URL of the APK package repository, empty to not check fixed versions against it")
+}
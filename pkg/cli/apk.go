@@ -9,7 +9,6 @@ import (
 	"os"
 	"path"
 	"runtime"
-	"sort"
 	"strings"
 	"sync"
 
@@ -46,7 +45,7 @@ func Apk() *cobra.Command {
 
 			if len(args) == 0 {
 				// Get the index and present a searchable list to select.
-				idx, err := index.Index(arch, repo)
+				idx, err := index.Index(cmd.Context(), arch, repo)
 				if err != nil {
 					return err
 				}
@@ -111,7 +110,7 @@ func Index() *cobra.Command {
 				repo = got
 			}
 
-			idx, err := index.Index(arch, repo)
+			idx, err := index.Index(cmd.Context(), arch, repo)
 			if err != nil {
 				return err
 			}
@@ -123,6 +122,7 @@ func Index() *cobra.Command {
 	}
 	cmd.Flags().StringVar(&arch, "arch", "x86_64", "arch of package to get")
 	cmd.Flags().StringVar(&repo, "repo", "wolfi", "repo to get packages from")
+	cmd.AddCommand(cmdIndexGenerate(), cmdWhoProvides())
 	return cmd
 }
 
@@ -257,12 +257,7 @@ Otherwise it's written to APKINDEX.tar.gz.
 			if err := errg.Wait(); err != nil {
 				return err
 			}
-			sort.Slice(idx.Packages, func(i, j int) bool {
-				if idx.Packages[i].Name == idx.Packages[j].Name {
-					return idx.Packages[i].Version < idx.Packages[j].Version
-				}
-				return idx.Packages[i].Name < idx.Packages[j].Name
-			})
+			index.Sort(idx)
 
 			r, err := repository.ArchiveFromIndex(idx)
 			if err != nil {
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"chainguard.dev/apko/pkg/build/types"
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/index"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+)
+
+func cmdBootstrap() *cobra.Command {
+	p := &bootstrapParams{}
+	cmd := &cobra.Command{
+		Use:   "bootstrap <package>",
+		Short: "Build a package along with the dependencies missing from an upstream repository",
+		Long: `Build a package along with the dependencies missing from an upstream repository.
+
+Every local package the package depends on to build, directly or
+transitively, is looked up in the --upstream repository. The ones it already
+has at the same version are used from there, and the rest, missing or
+outdated, are built in dependency order along with the package itself, the
+same way as 'wolfictl build' does. With several --arch, a package missing from
+the index of any of them is built for all of them.
+
+The builds resolve their environments with the --upstream repository and the
+--upstream-keyring too, passed to melange through the MELANGE_EXTRA_OPTS of the
+Makefile, so the packages that aren't built are installed from there.
+
+This is handy to stand up a new overlay repository on top of an existing one.
+With --plan, the packages that would be built are printed without building
+them.
+
+All the flags of 'wolfictl build' apply, except --watch.`,
+		Example: `  wolfictl bootstrap my-app
+  wolfictl bootstrap my-app --upstream https://packages.example.com/os --upstream-keyring https://packages.example.com/os/example.rsa.pub --plan
+  wolfictl bootstrap my-app --keep-going --jobs 4`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if p.watch {
+				return errors.New("--watch can't be used with bootstrap")
+			}
+
+			pkgs, err := dag.NewPackages(os.DirFS(p.dir), p.dir)
+			if err != nil {
+				return err
+			}
+			g, err := dag.NewGraph(pkgs, dag.WithContext(cmd.Context()))
+			if err != nil {
+				return explainGraphError(err)
+			}
+			g, err = g.Dependencies(args[0])
+			if err != nil {
+				return err
+			}
+
+			upstream := p.upstream
+			// Map a friendly string like "wolfi" to its repo URL.
+			if got, found := repos[upstream]; found {
+				upstream = got
+			}
+			var indexes []*repository.ApkIndex
+			for _, a := range p.arches {
+				idx, err := index.Index(cmd.Context(), types.ParseArchitecture(a).ToAPK(), upstream)
+				if err != nil {
+					return fmt.Errorf("reading index of %s: %w", upstream, err)
+				}
+				indexes = append(indexes, idx)
+			}
+
+			waves, err := g.Waves()
+			if err != nil {
+				return err
+			}
+			build := make(map[string]bool)
+			for _, wave := range waves {
+				for _, c := range wave {
+					status, ok := upstreamStatus(c, indexes)
+					if !ok {
+						build[c.String()] = true
+					}
+					fmt.Fprintf(cmd.ErrOrStderr(), "%s: %s\n", c, status)
+				}
+			}
+			if len(build) == 0 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%s and its dependencies are up to date in %s\n", args[0], upstream)
+				return nil
+			}
+
+			p.repositories = []string{upstream}
+			p.keyring = p.upstreamKeyring
+			g, err = g.Filter(func(pkg dag.Package) bool {
+				c, ok := pkg.(*dag.Configuration)
+				if !ok {
+					return true
+				}
+				// subpackages and provides are built along with their origin
+				return build[fmt.Sprintf("%s-%s-r%d", c.Package.Name, c.Package.Version, c.Package.Epoch)]
+			})
+			if err != nil {
+				return err
+			}
+			return p.run(cmd, g, args)
+		},
+	}
+	p.addFlagsTo(cmd)
+	cmd.Flags().StringVar(&p.upstream, "upstream", "wolfi", "repository to use the packages that are up to date from")
+	cmd.Flags().StringSliceVar(&p.upstreamKeyring, "upstream-keyring", []string{"https://packages.wolfi.dev/os/wolfi-signing.rsa.pub"}, "paths or URLs of the public keys the packages of --upstream are signed with")
+	return cmd
+}
+
+type bootstrapParams struct {
+	buildParams
+	upstream        string
+	upstreamKeyring []string
+}
+
+// upstreamStatus describes whether the upstream indexes have the origin package c at its version, and reports whether
+// all of them do.
+func upstreamStatus(c *dag.Configuration, indexes []*repository.ApkIndex) (string, bool) {
+	var newest string
+	for _, idx := range indexes {
+		found := false
+		for _, pkg := range idx.Packages {
+			if pkg.Name != c.Package.Name {
+				continue
+			}
+			if pkg.Version == c.Version() {
+				found = true
+				break
+			}
+			if newest == "" || dag.CompareVersions(pkg.Version, newest) > 0 {
+				newest = pkg.Version
+			}
+		}
+		if !found {
+			if newest != "" {
+				return fmt.Sprintf("outdated upstream (%s), building", newest), false
+			}
+			return "missing upstream, building", false
+		}
+	}
+	return "up to date upstream", true
+}
@@ -0,0 +1,409 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"chainguard.dev/apko/pkg/build/types"
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/builder"
+	"github.com/wolfi-dev/wolfictl/pkg/checks"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/groups"
+)
+
+const (
+	executorLocal      = "local"
+	executorSSH        = "ssh"
+	executorKubernetes = "kubernetes"
+)
+
+func cmdBuild() *cobra.Command {
+	p := &buildParams{}
+	cmd := &cobra.Command{
+		Use:   "build [package...]",
+		Short: "Build packages in dependency order, in parallel where possible",
+		Long: `Build packages in dependency order, in parallel where possible.
+
+The packages are grouped into waves using the dependency graph: the packages
+of a wave only depend on packages of earlier waves, so they are built
+concurrently. The artifacts of each wave are collected into the local
+repository (--repo) before the next wave starts, and the repository's
+APKINDEX is regenerated, signed with --signing-key if given, so the next wave
+resolves the packages just built rather than stale upstream versions.
+
+If packages are given, only those packages are built. A package group defined
+in the .package-groups.yaml file of --dir, like @gnome-core, stands for all of
+its packages.
+
+By default the build stops at the first failure (--fail-fast), and the builds
+still running are canceled, which the --summary-file records as such. With
+--keep-going, the packages that don't depend on a failed build, directly or
+transitively, are still built, the ones that do are skipped, and the failed
+builds are reported along with the packages they held back. --max-failures
+keeps going until that many builds failed.
+
+With --watch, a single package is built along with the packages that depend
+on it, then built again every time its melange config, or anything under the
+--watch-source directories, changes, until interrupted. Failed builds are
+reported without ending the loop.
+
+The output of every build is streamed with a [package-version] prefix on each
+line, and also written to its own file with --log-dir. With --summary-file, the
+status, duration, exit code and artifacts of every package are written as JSON
+once the build finishes, whether it succeeded or not.
+
+With --record-env, the exact packages the build environment of every package
+resolved to, with their versions and repositories, are recorded in a .env.json
+file next to its apk. 'wolfictl build env-diff' compares them with the ones of
+a previous run, to explain why a rebuild produced different output.
+
+With --hermetic, packages whose pipelines reach the network outside of their
+fetch and git-checkout steps, as reported by 'wolfictl check hermetic', fail
+without being built.
+
+The duration of every build is recorded in a timings database (--timings-file).
+With --plan, nothing is built: the waves are printed along with the estimated
+duration of each package, the critical path time of the whole build, and the
+number of jobs needed to reach it.
+
+With --cache-dir or --cache-repo, the artifacts of every build are cached by a
+key derived from the package's melange config, the patches it applies, and the
+exact versions its build dependencies resolve to. Packages whose key is already
+in the cache are fetched instead of being rebuilt.
+
+With several --arch, every architecture is built at the same time, each with
+its own concurrency limit. Architectures that aren't native to the executor
+are built under QEMU emulation, which is far slower, so they're limited to
+--emulated-jobs builds at a time unless --arch-jobs says otherwise. ssh hosts
+can be tagged with the architecture they natively build, e.g.
+--ssh-host aarch64=arm-builder; untagged hosts are assumed to share the
+architecture of this machine.
+
+Builds are run by an executor:
+
+  local       runs make in --dir on this machine
+  ssh         runs make on the --ssh-host hosts, copying configs and packages with rsync
+  kubernetes  runs a Job per package in the current kubeconfig context, exchanging packages through --bucket`,
+		Example: `  wolfictl build
+  wolfictl build --jobs 4 curl openssl
+  wolfictl build @gnome-core
+  wolfictl build --keep-going --summary-file build-summary.json
+  wolfictl build --plan
+  wolfictl build --watch curl --watch-source ./curl
+  wolfictl build --cache-repo ghcr.io/my-org/build-cache
+  wolfictl build --executor ssh --ssh-host builder1 --ssh-host builder2
+  wolfictl build --arch x86_64,aarch64 --executor ssh --ssh-host builder1 --ssh-host aarch64=arm-builder1
+  wolfictl build --executor kubernetes --bundle-repo gcr.io/my-project/dag --bucket gs://my-bucket/builds/`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pkgs, err := dag.NewPackages(os.DirFS(p.dir), p.dir)
+			if err != nil {
+				return err
+			}
+			args, err = groups.ExpandDir(p.dir, args, inDag(pkgs))
+			if err != nil {
+				return err
+			}
+			if p.watch && len(args) != 1 {
+				return fmt.Errorf("--watch requires exactly one package")
+			}
+
+			g, err := dag.NewGraph(pkgs, dag.WithContext(cmd.Context()))
+			if err != nil {
+				return explainGraphError(err)
+			}
+			if len(args) > 0 {
+				g, err = g.SubgraphWithRoots(args)
+				if err != nil {
+					return err
+				}
+			}
+
+			return p.run(cmd, g, args)
+		},
+	}
+	p.addFlagsTo(cmd)
+	cmd.AddCommand(cmdBuildEnvDiff())
+	return cmd
+}
+
+// run builds the local packages of g, or prints the plan to build them with --plan.
+func (p *buildParams) run(cmd *cobra.Command, g *dag.Graph, args []string) error {
+	arches := make([]string, 0, len(p.arches))
+	for _, a := range p.arches {
+		arches = append(arches, types.ParseArchitecture(a).ToAPK())
+	}
+
+	repo := p.repo
+	if repo == "" {
+		repo = filepath.Join(p.dir, "packages")
+	}
+	timingsFile := p.timingsFile
+	if timingsFile == "" {
+		timingsFile = filepath.Join(repo, "build-timings.json")
+	}
+	timings, err := builder.LoadTimings(timingsFile)
+	if err != nil {
+		return err
+	}
+
+	if p.plan {
+		for _, arch := range arches {
+			if len(arches) > 1 {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s:\n", arch)
+			}
+			plan, err := builder.NewPlan(g, arch, timings)
+			if err != nil {
+				return err
+			}
+			if err := plan.Write(cmd.OutOrStdout()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	cache, err := p.cache()
+	if err != nil {
+		return err
+	}
+
+	m := &builder.MultiArch{}
+	for _, arch := range arches {
+		e, native, err := p.executor(arch, len(arches) > 1)
+		if err != nil {
+			return err
+		}
+
+		s := builder.NewScheduler(e, repo, arch)
+		s.Repositories = p.repositories
+		s.Keyring = p.keyring
+		s.Jobs = p.jobsFor(cmd, arch, native)
+		if !native {
+			s.Logger.Printf("building %s under emulation, %d builds at a time", arch, s.Jobs)
+		}
+		s.Timings = timings
+		s.Cache = cache
+		s.LogDir = p.logDir
+		s.KeepGoing = p.keepGoing
+		s.MaxFailures = p.maxFailures
+		s.Reindex = p.reindex
+		s.SigningKey = p.signingKey
+		s.RecordEnvironments = p.recordEnv
+		if p.hermetic {
+			s.Preflight = checkHermetic
+		}
+		m.Schedulers = append(m.Schedulers, s)
+	}
+	logger := m.Schedulers[0].Logger
+	if p.reindex && p.signingKey == "" {
+		logger.Printf("no --signing-key provided, the regenerated index won't be signed")
+	}
+
+	if p.watch {
+		return p.runWatch(cmd.Context(), args[0], m)
+	}
+
+	err = m.Run(cmd.Context(), g)
+	if p.summaryFile != "" {
+		if serr := m.Summary.WriteFile(p.summaryFile); serr != nil {
+			logger.Printf("failed to write summary: %v", serr)
+		}
+	}
+	return err
+}
+
+type buildParams struct {
+	dir, repo    string
+	arches       []string
+	jobs         int
+	archJobs     map[string]int
+	emulatedJobs int
+	executorName string
+
+	keepGoing, failFast bool
+	maxFailures         int
+
+	cacheDir, cacheRepo string
+
+	plan        bool
+	timingsFile string
+
+	logDir, summaryFile string
+	recordEnv           bool
+
+	hermetic bool
+
+	watch        bool
+	watchSources []string
+
+	reindex    bool
+	signingKey string
+
+	// repositories and keyring are added to the ones of the configs to build with, e.g. by bootstrap
+	repositories, keyring []string
+
+	sshHosts     []string
+	sshRemoteDir string
+
+	namespace, bundleRepo, bucket, sdkImage, gcloudImage, cpu, ram string
+	kubernetes                                                     *builder.Kubernetes
+}
+
+func (p *buildParams) addFlagsTo(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&p.dir, "dir", "d", ".", "directory to search for melange configs")
+	cmd.Flags().StringSliceVarP(&p.arches, "arch", "a", []string{"x86_64"}, "architectures to build for, can be repeated")
+	cmd.Flags().StringVar(&p.repo, "repo", "", "local repository to collect built packages into, defaults to the packages directory in --dir")
+	cmd.Flags().IntVarP(&p.jobs, "jobs", "j", 1, "maximum number of packages to build at once per architecture, 0 means no limit")
+	cmd.Flags().IntVar(&p.emulatedJobs, "emulated-jobs", 1, "maximum number of packages to build at once for architectures built under emulation")
+	cmd.Flags().StringToIntVar(&p.archJobs, "arch-jobs", map[string]int{}, "maximum number of packages to build at once for specific architectures, e.g. aarch64=2")
+	cmd.Flags().StringVar(&p.executorName, "executor", executorLocal, fmt.Sprintf("where to run builds, one of: %s, %s, %s", executorLocal, executorSSH, executorKubernetes))
+
+	cmd.Flags().BoolVarP(&p.keepGoing, "keep-going", "k", false, "keep building the packages that don't depend on a failed build")
+	cmd.Flags().BoolVar(&p.failFast, "fail-fast", false, "stop at the first failed build, the default")
+	cmd.Flags().IntVar(&p.maxFailures, "max-failures", 0, "keep going until this many builds failed, 0 means no limit with --keep-going")
+	cmd.MarkFlagsMutuallyExclusive("fail-fast", "keep-going")
+	cmd.MarkFlagsMutuallyExclusive("fail-fast", "max-failures")
+
+	cmd.Flags().BoolVar(&p.plan, "plan", false, "print the build waves with time estimates instead of building")
+	cmd.Flags().StringVar(&p.timingsFile, "timings-file", "", "database of previous build durations to estimate with, defaults to build-timings.json in --repo")
+
+	cmd.Flags().BoolVar(&p.watch, "watch", false, "build the package and its dependents again every time its config changes")
+	cmd.Flags().StringSliceVar(&p.watchSources, "watch-source", []string{}, "directory whose changes also trigger a build with --watch, can be repeated")
+	cmd.MarkFlagsMutuallyExclusive("watch", "plan")
+
+	cmd.Flags().StringVar(&p.logDir, "log-dir", "", "directory to write a log file of every build to")
+	cmd.Flags().StringVar(&p.summaryFile, "summary-file", "", "file to write a JSON summary of the build to, - for stdout")
+	cmd.Flags().BoolVar(&p.hermetic, "hermetic", false, "fail the builds of packages whose pipelines reach the network outside of fetch and git-checkout steps")
+	cmd.Flags().BoolVar(&p.recordEnv, "record-env", false, "record the resolved build environment of every package next to its apk")
+
+	cmd.Flags().BoolVar(&p.reindex, "reindex", true, "regenerate the APKINDEX of --repo after every wave")
+	cmd.Flags().StringVar(&p.signingKey, "signing-key", "", "key to sign the regenerated APKINDEX with")
+
+	cmd.Flags().StringVar(&p.cacheDir, "cache-dir", "", "local directory to cache built packages in")
+	cmd.Flags().StringVar(&p.cacheRepo, "cache-repo", "", "OCI repository to cache built packages in")
+	cmd.MarkFlagsMutuallyExclusive("cache-dir", "cache-repo")
+
+	cmd.Flags().StringSliceVar(&p.sshHosts, "ssh-host", []string{}, "host to build on with the ssh executor, as [arch=]host, can be repeated")
+	cmd.Flags().StringVar(&p.sshRemoteDir, "ssh-remote-dir", "wolfictl-build", "directory on the ssh hosts to copy melange configs to")
+
+	cmd.Flags().StringVarP(&p.namespace, "namespace", "n", "default", "namespace to create build jobs in")
+	cmd.Flags().StringVar(&p.bundleRepo, "bundle-repo", "", "OCI repository to push the bundle of melange configs to")
+	cmd.Flags().StringVar(&p.bucket, "bucket", "", "GCS location to exchange packages with build jobs through")
+	cmd.Flags().StringVar(&p.sdkImage, "sdk-image", "ghcr.io/wolfi-dev/sdk:latest", "sdk image to use")
+	cmd.Flags().StringVar(&p.gcloudImage, "gcloud-image", "gcr.io/google.com/cloudsdktool/google-cloud-cli:slim", "image to use for gcloud stuff")
+	cmd.Flags().StringVar(&p.cpu, "cpu", "1", "CPU request of each build job")
+	cmd.Flags().StringVar(&p.ram, "ram", "2Gi", "RAM request of each build job")
+}
+
+// executor returns the executor to build arch with, and whether it builds arch natively rather than under emulation.
+func (p *buildParams) executor(arch string, multiArch bool) (builder.Executor, bool, error) {
+	switch p.executorName {
+	case executorLocal:
+		return builder.Local{Dir: p.dir}, !builder.Emulated(arch), nil
+	case executorSSH:
+		hosts, native, err := p.sshHostsFor(arch)
+		if err != nil {
+			return nil, false, err
+		}
+		remoteDir := p.sshRemoteDir
+		if multiArch {
+			// hosts can be shared between architectures, and their builds must not run in the same directory
+			remoteDir = filepath.Join(remoteDir, arch)
+		}
+		e, err := builder.NewSSH(p.dir, remoteDir, hosts)
+		return e, native, err
+	case executorKubernetes:
+		if p.bundleRepo == "" || p.bucket == "" {
+			return nil, false, fmt.Errorf("--bundle-repo and --bucket are required with the %s executor", executorKubernetes)
+		}
+		if p.kubernetes == nil {
+			k, err := builder.NewKubernetes(&builder.Kubernetes{
+				Dir:         p.dir,
+				Namespace:   p.namespace,
+				BundleRepo:  p.bundleRepo,
+				Bucket:      p.bucket,
+				SDKImage:    p.sdkImage,
+				GCloudImage: p.gcloudImage,
+				CPU:         p.cpu,
+				RAM:         p.ram,
+			})
+			if err != nil {
+				return nil, false, err
+			}
+			p.kubernetes = k
+		}
+		// jobs are scheduled on nodes of their architecture
+		return p.kubernetes, true, nil
+	default:
+		return nil, false, fmt.Errorf("unknown executor %q", p.executorName)
+	}
+}
+
+// sshHostsFor returns the --ssh-host hosts to build arch on. Hosts tagged with arch build it natively, and are
+// preferred over untagged hosts, which are assumed to share the architecture of this machine.
+func (p *buildParams) sshHostsFor(arch string) ([]string, bool, error) {
+	var tagged, untagged []string
+	for _, h := range p.sshHosts {
+		a, host, ok := strings.Cut(h, "=")
+		if !ok {
+			untagged = append(untagged, h)
+			continue
+		}
+		if types.ParseArchitecture(a).ToAPK() == arch {
+			tagged = append(tagged, host)
+		}
+	}
+	switch {
+	case len(tagged) > 0:
+		return tagged, true, nil
+	case len(untagged) > 0:
+		return untagged, !builder.Emulated(arch), nil
+	default:
+		return nil, false, fmt.Errorf("no --ssh-host to build %s on", arch)
+	}
+}
+
+// jobsFor returns the maximum number of concurrent builds of arch.
+func (p *buildParams) jobsFor(cmd *cobra.Command, arch string, native bool) int {
+	for a, jobs := range p.archJobs {
+		if types.ParseArchitecture(a).ToAPK() == arch {
+			return jobs
+		}
+	}
+	if !native {
+		return p.emulatedJobs
+	}
+	if p.executorName == executorSSH && !cmd.Flags().Changed("jobs") {
+		// each host runs one build at a time
+		hosts, _, _ := p.sshHostsFor(arch)
+		return len(hosts)
+	}
+	return p.jobs
+}
+
+// checkHermetic is the preflight check of --hermetic.
+func checkHermetic(t builder.Task) error {
+	found := checks.FindNetworkAccess(t.Config.Configuration)
+	if len(found) == 0 {
+		return nil
+	}
+	steps := make([]string, 0, len(found))
+	for _, n := range found {
+		steps = append(steps, n.Step+" "+n.Reason)
+	}
+	return fmt.Errorf("not hermetic: %s", strings.Join(steps, "; "))
+}
+
+func (p *buildParams) cache() (builder.Cache, error) {
+	switch {
+	case p.cacheDir != "":
+		return builder.DirCache{Dir: p.cacheDir}, nil
+	case p.cacheRepo != "":
+		return builder.OCICache{Repo: p.cacheRepo}, nil
+	default:
+		return nil, nil
+	}
+}
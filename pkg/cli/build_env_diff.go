@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+
+	"chainguard.dev/apko/pkg/build/types"
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/builder"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+func cmdBuildEnvDiff() *cobra.Command {
+	p := &buildEnvDiffParams{}
+	cmd := &cobra.Command{
+		Use:   "env-diff <package>",
+		Short: "Explain why a rebuild differs by comparing the build environments recorded by two runs",
+		Long: `Explain why a rebuild differs by comparing the build environments recorded by two runs.
+
+'wolfictl build --record-env' records the exact packages, with their version
+and repository, that the build environment of every package resolved to, in a
+.env.json file next to its apk. This compares the environment recorded in
+--repo with the one of a previous run, given as the repository that run built
+into (e.g. the packages directory of a CI artifact) or as a .env.json file.
+
+Only the packages that were added, removed, or resolved to a different version
+or repository are printed.`,
+		Example: `  wolfictl build env-diff curl --against ../last-release/packages
+  wolfictl build env-diff curl --against curl-8.1.0-r0.env.json --json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			arch := types.ParseArchitecture(p.arch).ToAPK()
+
+			current, err := builder.FindEnvironment(p.repo, arch, args[0], p.version)
+			if err != nil {
+				return err
+			}
+
+			var previous *builder.Environment
+			if fi, err := os.Stat(p.against); err == nil && !fi.IsDir() {
+				previous, err = builder.LoadEnvironment(p.against)
+				if err != nil {
+					return err
+				}
+			} else {
+				previous, err = builder.FindEnvironment(p.against, arch, args[0], p.againstVersion)
+				if err != nil {
+					return err
+				}
+			}
+
+			return envDiff(dag.DiffBuildEnvironments(previous.Dependencies(), current.Dependencies()), p.outputJSON, cmd.OutOrStdout())
+		},
+	}
+	p.addFlagsTo(cmd)
+	return cmd
+}
+
+type buildEnvDiffParams struct {
+	repo, arch, against     string
+	version, againstVersion string
+	outputJSON              bool
+}
+
+func (p *buildEnvDiffParams) addFlagsTo(cmd *cobra.Command) {
+	cwd, _ := os.Getwd()
+	cmd.Flags().StringVar(&p.repo, "repo", filepath.Join(cwd, "packages"), "repository of the run to explain")
+	cmd.Flags().StringVarP(&p.arch, "arch", "a", "x86_64", "architecture of the builds to compare")
+	cmd.Flags().StringVar(&p.against, "against", "", "repository of the previous run, or a build environment file it recorded")
+	_ = cmd.MarkFlagRequired("against")
+	cmd.Flags().StringVar(&p.version, "version", "", "version of the package in --repo, defaults to the latest recorded")
+	cmd.Flags().StringVar(&p.againstVersion, "against-version", "", "version of the package in the previous run, defaults to the latest recorded")
+	cmd.Flags().BoolVar(&p.outputJSON, "json", false, "print the changes as JSON")
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/wolfi-dev/wolfictl/pkg/builder"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+// runWatch builds the package name and the packages that depend on it with m, then again every time its melange
+// config, or anything under the --watch-source directories, changes, until interrupted. The graph is rebuilt from
+// the configs on disk before every build, so changed dependencies are picked up, but the indexes of the upstream
+// repositories are only fetched once.
+func (p *buildParams) runWatch(ctx context.Context, name string, m *builder.MultiArch) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pkgs, err := dag.NewPackages(os.DirFS(p.dir), p.dir)
+	if err != nil {
+		return err
+	}
+	configs := pkgs.Config(name, true)
+	if len(configs) == 0 {
+		return fmt.Errorf("package %q not found in %s", name, p.dir)
+	}
+	files := make([]string, 0, len(configs))
+	for _, c := range configs {
+		files = append(files, c.Path)
+	}
+
+	w := builder.NewWatcher(files, p.watchSources)
+	w.Logger = m.Schedulers[0].Logger
+
+	indexes := dag.NewIndexCache()
+	build := func(ctx context.Context, changed []string) error {
+		if len(changed) > 0 {
+			rel := make([]string, 0, len(changed))
+			for _, c := range changed {
+				if r, err := filepath.Rel(p.dir, c); err == nil {
+					c = r
+				}
+				rel = append(rel, c)
+			}
+			w.Logger.Printf("changed: %s", strings.Join(rel, ", "))
+		}
+
+		pkgs, err := dag.NewPackages(os.DirFS(p.dir), p.dir)
+		if err != nil {
+			return err
+		}
+		g, err := dag.NewGraph(pkgs, dag.WithIndexCache(indexes), dag.WithContext(ctx))
+		if err != nil {
+			return explainGraphError(err)
+		}
+		g, err = g.Dependents(name)
+		if err != nil {
+			return err
+		}
+		if err := m.Run(ctx, g); err != nil {
+			return err
+		}
+		w.Logger.Printf("built %s and the packages that depend on it", name)
+		return nil
+	}
+
+	if err := build(ctx, nil); err != nil {
+		w.Logger.Printf("%v", err)
+	}
+	w.Logger.Printf("watching for changes")
+	return w.Run(ctx, build)
+}
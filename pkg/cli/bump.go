@@ -2,21 +2,29 @@ package cli
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"chainguard.dev/melange/pkg/build"
+	"chainguard.dev/melange/pkg/renovate"
 	"github.com/spf13/cobra"
-)
 
-const epochPattern = `epoch: %d`
+	"github.com/wolfi-dev/wolfictl/pkg/configs/yamledit"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/groups"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
 
 type bumpOptions struct {
-	repoDir string
-	epoch   bool
-	dryRun  bool
+	repoDir    string
+	epoch      bool
+	dryRun     bool
+	dependents bool
+	checksums  bool
 }
 
 // this feels very hacky but the Makefile is going away with help from Dag so plan to delete this func soon
@@ -64,24 +72,42 @@ The bump subcommand increments version numbers in package config files.
 For now it will only bump epoch numbers but a future version will
 allow users to control versions expressed in semver.
 
-wolfictl bump can take a filename, a package or a file glob, increasing
+wolfictl bump can take a filename, a package, a file glob or a package
+group defined in the .package-groups.yaml file of the repository, increasing
 the version in each matching configuration file:
 
     wolfictl bump zlib.yaml
     wolfictl bump openssl
     wolfictl bump lib*.yaml
+    wolfictl bump @gnome-core
 
 The command assumes it is being run from the top of the wolfi/os 
 repository. To look for files in another location use the --repo flag.
 You can use --dry-run to see which versions will be bumped without
 modifying anything in the filesystem.
 
+With --dependents, the packages that depend on the given ones to build,
+directly or transitively, are bumped too, to rebuild everything after a
+change of a toolchain or of a library like glibc:
+
+    wolfictl bump --dependents glibc
+    wolfictl bump --dependents --dry-run go-1.21
+
+The expected-sha256 and expected-sha512 of the fetch steps whose uri
+depends on the version, and the expected-commit of the git-checkout steps
+checking out a versioned tag, are refreshed for the version in the config,
+so a version changed by hand builds. Use --checksums=false to leave them.
+
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
 				cmd.Help() //nolint:errcheck
 				return fmt.Errorf("not enough arguments")
 			}
+			args, err := groups.ExpandDir(opts.repoDir, args, groups.ConfigExists(opts.repoDir))
+			if err != nil {
+				return err
+			}
 			files := []string{}
 			for _, fname := range args {
 				_, err := os.Stat(filepath.Join(opts.repoDir, fname+".yaml"))
@@ -102,6 +128,13 @@ modifying anything in the filesystem.
 				return fmt.Errorf("unable to find config files from: %s", fname)
 			}
 
+			if opts.dependents {
+				files, err = withDependents(cmd.Context(), opts.repoDir, files)
+				if err != nil {
+					return err
+				}
+			}
+
 			if opts.dryRun {
 				fmt.Fprint(os.Stderr, "dry-run: not writing data\n")
 			}
@@ -110,6 +143,11 @@ modifying anything in the filesystem.
 				if err := bumpEpoch(opts, f); err != nil {
 					return err
 				}
+				if opts.checksums && !opts.dryRun {
+					if err := refreshChecksums(cmd.Context(), f); err != nil {
+						return err
+					}
+				}
 			}
 			return nil
 		},
@@ -118,10 +156,58 @@ modifying anything in the filesystem.
 	cmd.Flags().BoolVar(&opts.epoch, "epoch", true, "bump the package epoch")
 	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "don't change anything, just print what would be done")
 	cmd.Flags().StringVar(&opts.repoDir, "repo", ".", "path to the wolfi/os repository")
+	cmd.Flags().BoolVar(&opts.dependents, "dependents", false, "also bump the packages that depend on the given ones to build, directly or transitively")
+	cmd.Flags().BoolVar(&opts.checksums, "checksums", true, "refresh the expected checksums of fetch steps and the expected commits of git-checkout steps")
 
 	return cmd
 }
 
+// withDependents adds the configs of the packages of repoDir that depend on the packages of files to build, directly
+// or transitively, in the same terms as the build waves, after them
+func withDependents(ctx context.Context, repoDir string, files []string) ([]string, error) {
+	pkgs, err := dag.NewPackages(os.DirFS(repoDir), repoDir)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string)
+	for _, c := range pkgs.Packages() {
+		names[filepath.Clean(c.Path)] = c.Package.Name
+	}
+
+	bumped := make(map[string]bool)
+	var roots []string
+	for _, f := range files {
+		bumped[filepath.Clean(f)] = true
+		name, ok := names[filepath.Clean(f)]
+		if !ok {
+			return nil, fmt.Errorf("%s is not a melange config of %s", f, repoDir)
+		}
+		roots = append(roots, name)
+	}
+
+	g, err := dag.NewGraph(pkgs, dag.WithContext(ctx), dag.WithAllowUnresolved())
+	if err != nil {
+		return nil, explainGraphError(err)
+	}
+	g, err = g.Dependents(roots...)
+	if err != nil {
+		return nil, err
+	}
+	sorted, err := g.ReverseSorted()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range sorted {
+		c, ok := p.(*dag.Configuration)
+		if !ok || bumped[filepath.Clean(c.Path)] {
+			continue
+		}
+		bumped[filepath.Clean(c.Path)] = true
+		files = append(files, c.Path)
+	}
+	return files, nil
+}
+
 func bumpEpoch(opts bumpOptions, path string) error {
 	cfg, err := build.ParseConfiguration(path)
 	if err != nil {
@@ -137,43 +223,52 @@ func bumpEpoch(opts bumpOptions, path string) error {
 		return nil
 	}
 
-	original, err := os.Open(path)
+	err = yamledit.EditFile(path, func(doc *yamledit.Document) error {
+		packageNode, err := renovate.NodeFromMapping(doc.Root.Content[0], "package")
+		if err != nil {
+			return err
+		}
+		epochNode, err := renovate.NodeFromMapping(packageNode, "epoch")
+		if err != nil {
+			return fmt.Errorf("unable to find epoch tag in yaml config: %w", err)
+		}
+		epochNode.Value = strconv.FormatUint(cfg.Package.Epoch+1, 10)
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("opening config file: %w", err)
+		return fmt.Errorf("writing %s: %w", path, err)
 	}
 
-	scanner := bufio.NewScanner(original)
-	scanner.Split(bufio.ScanLines)
-	newFile := []string{}
-	found := false
-	old := fmt.Sprintf(epochPattern, cfg.Package.Epoch)
-	for scanner.Scan() {
-		if strings.TrimSpace(scanner.Text()) == old {
-			found = true
-			newFile = append(
-				newFile, strings.ReplaceAll(
-					scanner.Text(), old, fmt.Sprintf(epochPattern, cfg.Package.Epoch+1),
-				),
-			)
-		} else {
-			newFile = append(newFile, scanner.Text())
-		}
+	if err := updateMakefile(opts.repoDir, cfg.Package.Name, cfg.Package.Version, cfg.Package.Epoch+1); err != nil {
+		return fmt.Errorf("updating makefile: %w", err)
 	}
-	original.Close()
 
-	if !found {
-		return fmt.Errorf("unable to find epoch tag in yaml config")
-	}
+	return nil
+}
 
-	if err := os.WriteFile(
-		path, []byte(strings.Join(newFile, "\n")+"\n"), os.FileMode(0o644),
-	); err != nil {
-		return fmt.Errorf("writing %s: %w", path, err)
+// refreshChecksums sets the expected checksums of the fetch steps and the expected commits of the git-checkout steps of
+// the config at path to the ones of the sources of its version.
+func refreshChecksums(ctx context.Context, path string) error {
+	checksums, err := melange.RefreshFetchChecksums(ctx, path, melange.ChecksumOptions{})
+	if err != nil {
+		return fmt.Errorf("refreshing the checksums of %s: %w", path, err)
 	}
-
-	if err := updateMakefile(opts.repoDir, cfg.Package.Name, cfg.Package.Version, cfg.Package.Epoch+1); err != nil {
-		return fmt.Errorf("updating makefile: %w", err)
+	for _, c := range checksums {
+		if c.Changed {
+			fmt.Fprintf(os.Stderr, "updated %s: %s\n", path, c)
+		}
 	}
 
+	commits, err := melange.RefreshExpectedCommits(path, func(repository, tag string) (string, error) {
+		return melange.LsRemoteTag(ctx, repository, tag)
+	})
+	if err != nil {
+		return fmt.Errorf("refreshing the expected commits of %s: %w", path, err)
+	}
+	for _, c := range commits {
+		if c.Changed {
+			fmt.Fprintf(os.Stderr, "updated %s: %s\n", path, c)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDependents(t *testing.T) {
+	testDir := filepath.Join("..", "dag", "testdata", "complex")
+	config := func(name string) string {
+		return filepath.Join(testDir, name+".yaml")
+	}
+
+	files, err := withDependents(context.Background(), testDir, []string{config("two")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{config("two"), config("three")}, files)
+
+	// both configs of one are the package one, whose dependents are two, and three, which depends on two too
+	files, err = withDependents(context.Background(), testDir, []string{config("one-dupl")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{config("one-dupl"), config("one"), config("two"), config("three")}, files, "dependents are added once, after what they depend on")
+
+	files, err = withDependents(context.Background(), testDir, []string{config("one"), config("two")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{config("one"), config("two"), config("one-dupl"), config("three")}, files, "the configs given aren't added again")
+
+	files, err = withDependents(context.Background(), testDir, []string{config("three")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{config("three")}, files)
+
+	_, err = withDependents(context.Background(), testDir, []string{filepath.Join(t.TempDir(), "one.yaml")})
+	assert.ErrorContains(t, err, "is not a melange config of")
+}
+
+func TestBumpEpoch(t *testing.T) {
+	config := `package:
+  name: foo
+  version: "1.10"
+  # rebuilt for the new openssl
+  epoch: 2 # bumped by hand
+  description: foo
+
+pipeline:
+  - runs: 'echo "epoch: 2"'
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "foo.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(config), 0o600))
+
+	require.NoError(t, bumpEpoch(bumpOptions{repoDir: dir}, path))
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Replace(config, "epoch: 2 #", "epoch: 3 #", 1), string(b), "only the epoch changes, comments and quoting stay")
+}
+
+func TestRefreshChecksums(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "sources of %s", r.URL.Path)
+	}))
+	defer srv.Close()
+
+	config := `package:
+  name: foo
+  version: 1.11.0
+  epoch: 0
+
+pipeline:
+  - uses: fetch
+    with:
+      uri: ` + srv.URL + `/foo-${{package.version}}.tar.gz
+      expected-sha256: 0000000000000000000000000000000000000000000000000000000000000000
+`
+	path := filepath.Join(t.TempDir(), "foo.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(config), 0o600))
+
+	require.NoError(t, refreshChecksums(context.Background(), path))
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	sum := sha256.Sum256([]byte("sources of /foo-1.11.0.tar.gz"))
+	assert.Contains(t, string(b), "expected-sha256: "+hex.EncodeToString(sum[:]), "the checksum is the one of the sources of the version in the config")
+}
@@ -16,6 +16,15 @@ func Check() *cobra.Command {
 		Diff(),
 		CheckUpdate(),
 		SoName(),
+		StaleSubpackages(),
+		Hermetic(),
+		Intake(),
+		PackageGroups(),
+		YAMLSchema(),
+		EOL(),
+		UnusedDeps(),
+		Splits(),
+		License(),
 	)
 	return cmd
 }
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
+
+	"github.com/wolfi-dev/wolfictl/pkg/checks"
+	"github.com/wolfi-dev/wolfictl/pkg/gh"
+)
+
+func EOL() *cobra.Command {
+	o := checks.EOLOptions{}
+	var overridesFile, format, repo string
+	var withinDays int
+	var createIssues bool
+	var labels []string
+	cmd := &cobra.Command{
+		Use:               "eol [package...]",
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		Short:             "Check for packages whose version stream is past its end-of-life, or whose project is unmaintained",
+		Long: `Check for packages whose version stream is past its end-of-life, or whose project is unmaintained
+
+Looks up the release cycle of every package on https://endoflife.date, and
+reports the packages whose cycle is past its end-of-life, so maintainers can
+plan migrations to a supported cycle. The product of a package is its name, or
+its name without the version stream suffix of packages like nodejs-18, and its
+cycle is the longest one its version starts with, like 3.11 for 3.11.4.
+Packages endoflife.date doesn't know are skipped. With --within-days, the
+packages reaching end-of-life within that many days are reported too.
+
+The overrides file (--overrides) completes and corrects endoflife.date:
+
+  packages:
+    openjdk-17:
+      product: eclipse-temurin
+      cycle: "17"
+    libfoo:
+      eol: 2024-06-30
+    libbar:
+      unmaintained: the upstream repository was archived
+    python-2.7:
+      ignore: true
+
+With --create-issues, an issue is opened in --repo for every package reported,
+unless one with the same title is already open. GITHUB_TOKEN needs access to
+the repository.
+
+If packages are given, only their configs are checked.`,
+		Example: `  wolfictl check eol
+  wolfictl check eol --within-days 90 --format json
+  wolfictl check eol --create-issues --repo wolfi-dev/os --github-labels eol`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != formatText && format != formatJSON {
+				return fmt.Errorf("unknown output format %q, must be one of: %s, %s", format, formatText, formatJSON)
+			}
+			if overridesFile == "" {
+				overridesFile = filepath.Join(o.Dir, checks.DefaultEOLOverridesFile)
+			}
+			overrides, err := checks.ReadEOLOverrides(overridesFile)
+			if err != nil {
+				return err
+			}
+			o.Overrides = overrides
+			o.Packages = args
+			o.Now = time.Now().UTC()
+			o.Within = time.Duration(withinDays) * 24 * time.Hour
+
+			var gitOpts gh.GitOptions
+			var owner, name string
+			if createIssues {
+				var ok bool
+				owner, name, ok = strings.Cut(repo, "/")
+				if !ok || owner == "" || name == "" {
+					return fmt.Errorf("repo %q isn't of the form owner/name", repo)
+				}
+				token := os.Getenv("GITHUB_TOKEN")
+				if token == "" {
+					return errors.New("no GITHUB_TOKEN token found")
+				}
+				ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+				gitOpts = gh.GitOptions{
+					GithubClient: github.NewClient(oauth2.NewClient(cmd.Context(), ts)),
+					MaxRetries:   10,
+					Logger:       log.New(log.Writer(), "wolfictl check eol: ", log.LstdFlags|log.Lmsgprefix),
+				}
+			}
+
+			found, err := o.CheckEOL(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if format == formatJSON {
+				if found == nil {
+					found = []checks.EOLPackage{}
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(found); err != nil {
+					return err
+				}
+			}
+			if len(found) == 0 {
+				return nil
+			}
+			if format == formatText {
+				for _, e := range found {
+					fmt.Fprintln(cmd.OutOrStdout(), e)
+				}
+			}
+
+			for _, e := range found {
+				if !createIssues {
+					break
+				}
+				issue := &gh.Issues{Owner: owner, RepoName: name, PackageName: e.Package, Title: e.IssueTitle(), Comment: e.IssueBody(), Labels: labels}
+				existing, err := gitOpts.CheckExistingIssue(cmd.Context(), issue)
+				if err != nil {
+					return err
+				}
+				if existing != 0 {
+					gitOpts.Logger.Printf("%s: issue #%d already open", e.Package, existing)
+					continue
+				}
+				url, err := gitOpts.OpenIssue(cmd.Context(), issue)
+				if err != nil {
+					return fmt.Errorf("failed to open the issue of %s: %w", e.Package, err)
+				}
+				gitOpts.Logger.Printf("%s: %s", e.Package, url)
+			}
+			return fmt.Errorf("found %d packages past their end-of-life or unmaintained", len(found))
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.Dir, "directory", "d", ".", "directory containing melange configs")
+	cmd.Flags().StringVar(&overridesFile, "overrides", "", fmt.Sprintf("end-of-life overrides, defaults to %s in --directory", checks.DefaultEOLOverridesFile))
+	cmd.Flags().IntVar(&withinDays, "within-days", 0, "also report the packages reaching end-of-life within this many days")
+	cmd.Flags().StringVar(&o.URL, "eol-url", checks.DefaultEOLURL, "endoflife.date API")
+	cmd.Flags().StringVar(&format, "format", formatText, fmt.Sprintf("output format, one of: %s, %s", formatText, formatJSON))
+	cmd.Flags().BoolVar(&createIssues, "create-issues", false, "open a GitHub issue in --repo for every package reported")
+	cmd.Flags().StringVar(&repo, "repo", "", "GitHub repository to open issues in, as owner/name")
+	cmd.Flags().StringArrayVar(&labels, "github-labels", []string{}, "labels of the issues opened")
+
+	return cmd
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/checks"
+)
+
+func Hermetic() *cobra.Command {
+	o := checks.HermeticOptions{}
+	var format string
+	cmd := &cobra.Command{
+		Use:               "hermetic [package...]",
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		Short:             "Check that melange pipelines only reach the network to fetch their sources",
+		Long: `Check that melange pipelines only reach the network to fetch their sources
+
+Reports the pipeline steps, of packages and of their subpackages, that reach
+the network outside of fetch and git-checkout steps: downloads with curl or
+wget, git clones, and package managers installing dependencies that aren't
+vendored or run offline. The builds of those packages depend on more than
+their declared sources, so rebuilding them can produce different output.
+
+Only the commands the scripts run are matched, not their arguments, e.g. the
+--with-curl of a configure script. Go builds, which only download the modules
+the sources don't vendor, are reported when they download modules explicitly,
+e.g. with go mod download.
+
+If packages are given, only their configs are checked.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != formatText && format != formatSARIF {
+				return fmt.Errorf("unknown output format %q, must be one of: %s, %s", format, formatText, formatSARIF)
+			}
+			o.Packages = args
+
+			found, err := o.CheckHermetic()
+			if err != nil {
+				return err
+			}
+			if format == formatSARIF {
+				if err := found.SARIF().Write(cmd.OutOrStdout()); err != nil {
+					return err
+				}
+			}
+			if len(found) == 0 {
+				return nil
+			}
+			if format == formatText {
+				for _, n := range found {
+					fmt.Fprintln(cmd.OutOrStdout(), n)
+				}
+			}
+			return fmt.Errorf("found %d pipeline steps reaching the network", len(found))
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.Dir, "directory", "d", ".", "directory containing melange configs")
+	cmd.Flags().StringVar(&format, "format", formatText, fmt.Sprintf("output format, one of: %s, %s (for GitHub code scanning)", formatText, formatSARIF))
+
+	return cmd
+}
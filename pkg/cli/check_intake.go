@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/checks"
+	"github.com/wolfi-dev/wolfictl/pkg/git"
+)
+
+func Intake() *cobra.Command {
+	o := checks.IntakeOptions{}
+	var policyFile, baseRef, format string
+	cmd := &cobra.Command{
+		Use:               "intake",
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		Short:             "Check that melange configs only use approved external repositories and keys",
+		Long: `Check that melange configs only use approved external repositories and keys
+
+Reports the repositories and keyring entries of the build environments of
+melange configs that the intake policy (--policy) doesn't approve, so the
+trusted supply chain can't grow without an explicit change to the policy.
+Local repositories and keys aren't checked.
+
+The policy is a YAML file listing the approved repositories and keys, which
+can be glob patterns:
+
+  repositories:
+    - https://packages.wolfi.dev/*
+  keyring:
+    - https://packages.wolfi.dev/os/wolfi-signing.rsa.pub
+
+With --base-ref, only the repositories and keys that aren't used by any config
+at that git revision are checked, e.g. the ones a pull request introduces.`,
+		Example: `  wolfictl check intake --base-ref origin/main
+  wolfictl check intake --policy .github/intake-policy.yaml --format sarif`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != formatText && format != formatSARIF {
+				return fmt.Errorf("unknown output format %q, must be one of: %s, %s", format, formatText, formatSARIF)
+			}
+			if policyFile == "" {
+				policyFile = filepath.Join(o.Dir, "intake-policy.yaml")
+			}
+			policy, err := checks.ReadIntakePolicy(policyFile)
+			if err != nil {
+				return err
+			}
+			o.Policy = policy
+
+			if baseRef != "" {
+				d, cleanup, err := git.CheckoutRevision(o.Dir, baseRef)
+				if err != nil {
+					return err
+				}
+				defer cleanup()
+				o.BaseDir = d
+			}
+
+			found, err := o.CheckIntake()
+			if err != nil {
+				return err
+			}
+			if format == formatSARIF {
+				if err := found.SARIF().Write(cmd.OutOrStdout()); err != nil {
+					return err
+				}
+			}
+			if len(found) == 0 {
+				return nil
+			}
+			if format == formatText {
+				for _, s := range found {
+					fmt.Fprintln(cmd.OutOrStdout(), s)
+				}
+			}
+			return fmt.Errorf("found %d repositories and keys that need approval in %s", len(found), policyFile)
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.Dir, "directory", "d", ".", "directory containing melange configs")
+	cmd.Flags().StringVar(&policyFile, "policy", "", "intake policy listing the approved repositories and keys, defaults to intake-policy.yaml in --directory")
+	cmd.Flags().StringVar(&baseRef, "base-ref", "", "git revision to only check the repositories and keys new since")
+	cmd.Flags().StringVar(&format, "format", formatText, fmt.Sprintf("output format, one of: %s, %s (for GitHub code scanning)", formatText, formatSARIF))
+
+	return cmd
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/wolfi-dev/wolfictl/pkg/checks"
+)
+
+func License() *cobra.Command {
+	o := checks.LicenseOptions{}
+	var format string
+	cmd := &cobra.Command{
+		Use:               "license [package...]",
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		Short:             "Check that the licenses of melange configs are SPDX expressions matching their sources",
+		Long: `Check that the licenses of melange configs are SPDX expressions matching their sources
+
+Reports the licenses of the copyright of packages that aren't SPDX license
+expressions of identifiers on the SPDX License List, or that aren't written in
+their normalized form, e.g. "gpl-2.0+ and mit" for "GPL-2.0-or-later AND MIT".
+
+The sources of the packages at their version, those of their first fetch or
+git-checkout step, are then downloaded, and the licenses of the license files
+at their root (LICENSE, COPYING, LICENSE-MIT, ...) are detected by the phrases
+of their texts and notices, or by their SPDX-License-Identifier tags. The
+licenses found in the sources that a package doesn't declare are reported, as
+are the declared licenses that could have been found but weren't. The -only
+and -or-later versions of GNU licenses match either way, as their texts don't
+tell them apart. Packages whose sources can't be downloaded are skipped.
+
+With --offline, the sources aren't downloaded.
+
+If packages are given, only their configs are checked.`,
+		Example: `  wolfictl check license zlib
+  wolfictl check license --offline --format sarif`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != formatText && format != formatSARIF {
+				return fmt.Errorf("unknown output format %q, must be one of: %s, %s", format, formatText, formatSARIF)
+			}
+			o.Logger = log.New(log.Writer(), "wolfictl check license: ", log.LstdFlags|log.Lmsgprefix)
+			o.Packages = args
+
+			found, err := o.CheckLicenses()
+			if err != nil {
+				return err
+			}
+			if format == formatSARIF {
+				if err := found.SARIF().Write(cmd.OutOrStdout()); err != nil {
+					return err
+				}
+			}
+			if len(found) == 0 {
+				return nil
+			}
+			if format == formatText {
+				for _, f := range found {
+					fmt.Fprintln(cmd.OutOrStdout(), f)
+				}
+			}
+			return fmt.Errorf("found %d problems with the licenses of packages", len(found))
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.Dir, "directory", "d", ".", "directory containing melange configs")
+	cmd.Flags().BoolVar(&o.Offline, "offline", false, "only check the declared licenses, without downloading the sources")
+	cmd.Flags().StringVar(&format, "format", formatText, fmt.Sprintf("output format, one of: %s, %s (for GitHub code scanning)", formatText, formatSARIF))
+
+	return cmd
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/groups"
+)
+
+func PackageGroups() *cobra.Command {
+	var dir string
+	cmd := &cobra.Command{
+		Use:               "package-groups",
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		Short:             "Check that the members of package groups exist in the dag",
+		Long: `Check that the members of package groups exist in the dag
+
+Package groups are named lists of packages defined in the .package-groups.yaml
+file of a repository of melange configs. Commands that take packages, like
+build, text, dot, pod, bump, update --package-name and report
+license-inventory, accept a group prefixed with @, e.g. @gnome-core, in place
+of its packages:
+
+  groups:
+    gnome-core:
+      description: The core GNOME libraries
+      packages:
+        - glib
+        - gtk-4
+        - "@gnome-fonts"
+    gnome-fonts:
+      packages:
+        - font-cantarell
+
+Reports groups with packages, subpackages or provides that aren't in the dag,
+groups that include unknown groups or themselves, and empty groups. Commands
+given a group check its members too, but only of the groups they're given.`,
+		Example: `  wolfictl check package-groups
+  wolfictl check package-groups -d ~/os`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			gs, err := groups.ReadDir(dir)
+			if err != nil {
+				return err
+			}
+			if len(gs) == 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "no package groups defined in %s\n", groups.DefaultFile)
+				return nil
+			}
+
+			pkgs, err := dag.NewPackages(os.DirFS(dir), dir)
+			if err != nil {
+				return err
+			}
+
+			if err := gs.Validate(inDag(pkgs)); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%d package groups are valid\n", len(gs))
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&dir, "dir", "d", ".", "directory to search for melange configs and package groups")
+	return cmd
+}
+
+// inDag reports whether a package is in the dag, which the members of groups used as roots of subgraphs can be as a
+// package, a subpackage or a provide.
+func inDag(pkgs *dag.Packages) func(name string) bool {
+	return func(name string) bool {
+		return len(pkgs.Config(name, false)) > 0
+	}
+}
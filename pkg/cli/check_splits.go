@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/wolfi-dev/wolfictl/pkg/checks"
+)
+
+func Splits() *cobra.Command {
+	o := checks.SplitsOptions{}
+	cmd := &cobra.Command{
+		Use:               "splits [package...]",
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		Short:             "Suggest splitting built packages into the standard subpackages",
+		Long: `Suggest splitting built packages into the standard subpackages
+
+Looks at the files of the apks of the last build in --packages-dir, and
+suggests the standard subpackages for the files that the split pipelines of
+melange would move out of them:
+
+  -static  static libraries                     split/static
+  -dev     headers, pkg-config files, .so links split/dev
+  -doc     man and info pages                   split/manpages, split/infodir
+  -lang    translations                         split/locales
+
+Subpackages that a config already has, by name or by split pipeline, aren't
+suggested, nor are the ones that would leave the package empty, like the -dev
+subpackage of a header-only library.
+
+The suggestions are printed as a patch adding the subpackages to the melange
+configs, which git apply applies in the directory of the configs.
+
+If packages are given, only they are checked.`,
+		Example: `  wolfictl check splits
+  wolfictl check splits zlib | git apply`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Logger = log.New(log.Writer(), "wolfictl check splits: ", log.LstdFlags|log.Lmsgprefix)
+			o.Packages = args
+
+			suggested, err := o.CheckSplits()
+			if err != nil {
+				return err
+			}
+			if len(suggested) == 0 {
+				return nil
+			}
+			for _, s := range suggested {
+				var names []string
+				for _, split := range s.Splits {
+					names = append(names, fmt.Sprintf("%s-%s (%d files)", s.Package, split.Suffix, len(split.Files)))
+				}
+				o.Logger.Printf("%s could be split into %s", s.Package, strings.Join(names, ", "))
+				fmt.Fprint(cmd.OutOrStdout(), s.Patch)
+			}
+			return fmt.Errorf("found %d packages that could be split into standard subpackages", len(suggested))
+		},
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		cwd = "."
+	}
+
+	cmd.Flags().StringVarP(&o.Dir, "directory", "d", ".", "directory containing melange configs")
+	cmd.Flags().StringVar(&o.PackagesDir, "packages-dir", filepath.Join(cwd, "packages"), "directory containing the packages of the last build")
+	cmd.Flags().StringVar(&o.Arch, "arch", "x86_64", "arch of the packages to look at")
+
+	return cmd
+}
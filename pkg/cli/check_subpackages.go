@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/checks"
+)
+
+func StaleSubpackages() *cobra.Command {
+	o := checks.NewSubpackages()
+	var format string
+	cmd := &cobra.Command{
+		Use:               "stale-subpackages",
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		Short:             "Check subpackages declared in melange configs against what's published and built",
+		Long: `Check subpackages declared in melange configs against what's published and built
+
+Reports subpackages that are declared for the current version of a package
+that was built, but that neither the index of --repo nor the last build in
+--packages-dir has, which usually means their split rules don't match
+anything anymore. Also reports subpackages in the index whose origin package
+doesn't declare them anymore.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if format != formatText && format != formatSARIF {
+				return fmt.Errorf("unknown output format %q, must be one of: %s, %s", format, formatText, formatSARIF)
+			}
+			// Map a friendly string like "wolfi" to its repo URL.
+			if got, found := repos[o.Repo]; found {
+				o.Repo = got
+			}
+			stale, err := o.CheckSubpackages(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if format == formatSARIF {
+				if err := stale.SARIF().Write(cmd.OutOrStdout()); err != nil {
+					return err
+				}
+			}
+			if stale.Empty() {
+				o.Logger.Printf("no stale subpackages found")
+				return nil
+			}
+			if format == formatText {
+				if err := stale.Write(cmd.OutOrStdout()); err != nil {
+					return err
+				}
+			}
+			return fmt.Errorf("found %d stale subpackages", len(stale.NeverProduced)+len(stale.Undeclared))
+		},
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		cwd = "."
+	}
+
+	cmd.Flags().StringVarP(&o.Dir, "directory", "d", ".", "directory containing melange configs")
+	cmd.Flags().StringVar(&o.PackagesDir, "packages-dir", filepath.Join(cwd, "packages"), "directory containing the packages of the last build")
+	cmd.Flags().StringVar(&o.Repo, "repo", "wolfi", "repo whose index to compare with")
+	cmd.Flags().StringVar(&o.Arch, "arch", "x86_64", "arch of packages to compare")
+	cmd.Flags().StringVar(&format, "format", formatText, fmt.Sprintf("output format, one of: %s, %s (for GitHub code scanning)", formatText, formatSARIF))
+
+	return cmd
+}
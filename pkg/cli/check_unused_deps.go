@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/wolfi-dev/wolfictl/pkg/checks"
+)
+
+func UnusedDeps() *cobra.Command {
+	o := checks.UnusedDepsOptions{}
+	cmd := &cobra.Command{
+		Use:               "unused-deps <package>",
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		Short:             "Check for build environment packages a package's build doesn't use",
+		Long: `Check for build environment packages a package's build doesn't use
+
+Builds the package with make under strace, tracing the files its build opens
+and runs in its build environment, and reports the packages of its
+environment.contents.packages that appear unnecessary: none of their files,
+nor those of the dependencies only they pull into the environment, were
+accessed, and none of the libraries they provide are linked by the packages
+built. Such packages only slow builds down, and can hide missing dependencies.
+
+A package can be needed without the build reading its files, like a package
+only there for its dependencies or its triggers; those can be left out of the
+report with --ignore.
+
+The build needs strace, and runs in bubblewrap like other melange builds. The
+packages built are written to the --repo repository.`,
+		Example: `  wolfictl check unused-deps zlib
+  wolfictl check unused-deps --ignore busybox --ignore ca-certificates-bundle zlib`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Logger = log.New(log.Writer(), "wolfictl check unused-deps: ", log.LstdFlags|log.Lmsgprefix)
+			o.Output = os.Stderr
+			if o.Repo == "" {
+				o.Repo = filepath.Join(o.Dir, "packages")
+			}
+
+			unused, err := o.CheckUnusedDeps(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			if len(unused) == 0 {
+				return nil
+			}
+			for _, p := range unused {
+				fmt.Fprintln(cmd.OutOrStdout(), p)
+			}
+			return fmt.Errorf("found %d environment packages of %s that appear unnecessary", len(unused), args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.Dir, "directory", "d", ".", "directory containing melange configs")
+	cmd.Flags().StringVar(&o.Repo, "repo", "", "repository the package is built into, the packages directory of --directory by default")
+	cmd.Flags().StringVar(&o.SigningKey, "signing-key", "", "key to sign the packages built with")
+	cmd.Flags().StringVarP(&o.Arch, "arch", "a", "x86_64", "architecture to build for")
+	cmd.Flags().StringSliceVar(&o.Ignore, "ignore", nil, "environment packages never to report")
+
+	return cmd
+}
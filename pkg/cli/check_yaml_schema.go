@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/checks"
+)
+
+func YAMLSchema() *cobra.Command {
+	o := checks.SchemaOptions{}
+	var format string
+	cmd := &cobra.Command{
+		Use:               "yaml-schema",
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		Short:             "Check melange configs against the melange JSON schema",
+		Long: `Check melange configs against the melange JSON schema
+
+Validates every melange config against the JSON schema melange publishes for
+the version of melange the repository declares, reporting fields the schema
+doesn't know, values of the wrong type, e.g. a version of 1.10 which YAML reads
+as the number 1.1, and missing required fields, with their file:line:column.
+Unlike wolfictl lint, which checks the conventions of Wolfi, this only checks
+the structure of the configs.
+
+The version of the schema is pinned in the .melange-schema.yaml file of the
+repository, so it's only changed on purpose:
+
+  version: v0.5.0
+
+The schema can also be read from another URL or a local file, e.g. to check
+configs offline:
+
+  schema: hack/melange-schema.json
+
+--schema-version and --schema override the pin.`,
+		Example: `  wolfictl check yaml-schema
+  wolfictl check yaml-schema --schema-version v0.5.0 --format sarif`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if format != formatText && format != formatSARIF {
+				return fmt.Errorf("unknown output format %q, must be one of: %s, %s", format, formatText, formatSARIF)
+			}
+
+			found, location, err := o.CheckSchema()
+			if err != nil {
+				return err
+			}
+			if format == formatSARIF {
+				if err := found.SARIF().Write(cmd.OutOrStdout()); err != nil {
+					return err
+				}
+			}
+			if len(found) == 0 {
+				return nil
+			}
+			if format == formatText {
+				for _, v := range found {
+					fmt.Fprintln(cmd.OutOrStdout(), v)
+				}
+			}
+			return fmt.Errorf("found %d schema violations in melange configs, checked against %s", len(found), location)
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.Dir, "directory", "d", ".", "directory containing melange configs")
+	cmd.Flags().StringVar(&o.Version, "schema-version", "", "melange release whose JSON schema to check against, overrides the version pinned in .melange-schema.yaml")
+	cmd.Flags().StringVar(&o.Schema, "schema", "", "URL or path of the JSON schema to check against, overrides .melange-schema.yaml")
+	cmd.Flags().StringVar(&format, "format", formatText, fmt.Sprintf("output format, one of: %s, %s (for GitHub code scanning)", formatText, formatSARIF))
+
+	return cmd
+}
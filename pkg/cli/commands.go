@@ -16,16 +16,30 @@ func New() *cobra.Command {
 	cmd.AddCommand(
 		Advisory(),
 		Bump(),
+		cmdBootstrap(),
+		cmdBuild(),
 		Gh(),
 		Apk(),
 		Index(),
 		GenerateIndex(),
 		cmdPod(),
+		cmdPromote(),
+		cmdWithdraw(),
+		cmdScan(),
 		cmdSVG(),
 		cmdText(),
+		cmdSubpackageOrigins(),
+		cmdSummary(),
 		cmdMake(),
+		cmdEnvDiff(),
+		cmdExplain(),
+		cmdNew(),
+		cmdConvert(),
+		cmdFmt(),
+		cmdCompareIndex(),
 		Check(),
 		Lint(),
+		Report(),
 		Update(),
 		VEX(),
 		version.Version(),
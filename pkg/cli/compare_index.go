@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/index"
+)
+
+func cmdCompareIndex() *cobra.Command {
+	var arch string
+	var outputJSON bool
+	cmd := &cobra.Command{
+		Use:   "compare-index <old> <new>",
+		Short: "Compare the packages of two APKINDEX files",
+		Long: `Compare the packages of two APKINDEX files.
+
+Each index can be an APKINDEX.tar.gz file or URL, a repository URL or local
+repository directory, whose --arch index is read, or one of the repositories
+known to 'wolfictl index' like wolfi.
+
+Packages that were added or removed are printed, along with packages whose
+versions changed, and the dependencies and provides that changed between the
+latest version of each package in the two indexes.`,
+		Example: `  wolfictl compare-index ./packages wolfi
+  wolfictl compare-index old/APKINDEX.tar.gz new/APKINDEX.tar.gz --json
+  wolfictl compare-index https://packages.wolfi.dev/os https://packages.wolfi.dev/bootstrap/stage3 --arch aarch64`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			old, err := index.Open(cmd.Context(), indexSource(args[0], arch))
+			if err != nil {
+				return fmt.Errorf("reading old index: %w", err)
+			}
+			current, err := index.Open(cmd.Context(), indexSource(args[1], arch))
+			if err != nil {
+				return fmt.Errorf("reading new index: %w", err)
+			}
+			return compareIndex(index.Diff(old, current), outputJSON, cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().StringVar(&arch, "arch", "x86_64", "arch of the indexes of repositories to compare")
+	cmd.Flags().BoolVar(&outputJSON, "json", false, "print the changes as JSON")
+	return cmd
+}
+
+// indexSource returns the location of the APKINDEX.tar.gz of src for arch, unless src is one already.
+func indexSource(src, arch string) string {
+	// Map a friendly string like "wolfi" to its repo URL.
+	if got, found := repos[src]; found {
+		src = got
+	}
+	if strings.HasSuffix(src, ".tar.gz") {
+		return src
+	}
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(src, "/"), arch, index.ArchiveName)
+	}
+	if fi, err := os.Stat(src); err == nil && fi.IsDir() {
+		return filepath.Join(src, arch, index.ArchiveName)
+	}
+	return src
+}
+
+func compareIndex(changes []index.PackageChange, outputJSON bool, w io.Writer) error {
+	if outputJSON {
+		if changes == nil {
+			changes = []index.PackageChange{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(changes)
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "no changes between the indexes")
+		return nil
+	}
+	for _, c := range changes {
+		fmt.Fprintln(w, c)
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package guide
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/cli/components/advisory/field"
+	"github.com/wolfi-dev/wolfictl/pkg/cli/styles"
+)
+
+// skip is the determination of leaving a vulnerability open, to triage it later.
+const skip = "skip for now"
+
+const descriptionWidth = 100
+
+var (
+	titleStyle = styles.Accented().Copy().Bold(true)
+	labelStyle = styles.Secondary().Copy()
+	faintStyle = styles.Faint().Copy()
+	errorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555"))
+)
+
+type Configuration struct {
+	// Vulnerabilities are the open vulnerabilities of the package to walk through.
+	Vulnerabilities []string
+
+	// TriageFunc returns the triage of a vulnerability. It's run in the background, as it may take a while.
+	TriageFunc func(vulnerability string) (advisory.Triage, error)
+
+	// AllowedFixedVersionsFunc returns the versions of the package a vulnerability can be fixed in.
+	AllowedFixedVersionsFunc func(packageName string) []string
+
+	// RecordFunc writes the determination of a vulnerability to its advisory.
+	RecordFunc func(advisory.Request) error
+
+	// Request is the base of the requests of the determinations, with the package and timestamp to record them with.
+	Request advisory.Request
+}
+
+type Model struct {
+	config Configuration
+
+	// internal data
+	index      int
+	triage     *advisory.Triage
+	triageErr  error
+	fields     []field.Field
+	focusIndex int
+	request    advisory.Request
+	recordErr  error
+
+	// Recorded are the determinations recorded so far, as "<vulnerability>: <event>".
+	Recorded []string
+
+	// EarlyExit is set to true if the user asks to exit the guide early.
+	EarlyExit bool
+}
+
+type triageMsg struct {
+	triage advisory.Triage
+	err    error
+}
+
+func New(config Configuration) Model {
+	return Model{config: config}
+}
+
+func (m Model) Init() tea.Cmd {
+	return m.loadTriage()
+}
+
+// loadTriage returns the command loading the triage of the current vulnerability.
+func (m Model) loadTriage() tea.Cmd {
+	if m.index >= len(m.config.Vulnerabilities) {
+		return tea.Quit
+	}
+	vulnerability := m.config.Vulnerabilities[m.index]
+	return func() tea.Msg {
+		t, err := m.config.TriageFunc(vulnerability)
+		return triageMsg{triage: t, err: err}
+	}
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case triageMsg:
+		m.triage = &msg.triage
+		m.triageErr = msg.err
+		m.request = m.config.Request
+		m.request.Vulnerability = m.config.Vulnerabilities[m.index]
+		m.fields = []field.Field{field.NewListField(m.newEventFieldConfig())}
+		m.focusIndex = 0
+		var cmd tea.Cmd
+		m.fields[0], cmd = m.fields[0].SetFocus()
+		return m, cmd
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			m.EarlyExit = true
+			return m, tea.Quit
+
+		case "enter":
+			if m.triage == nil || len(m.fields) == 0 {
+				// still loading
+				return m, nil
+			}
+			return m.submit()
+		}
+	}
+
+	if len(m.fields) == 0 {
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.fields[m.focusIndex], cmd = m.fields[m.focusIndex].Update(msg)
+	return m, cmd
+}
+
+// submit submits the focused field, and records the determination once it's complete.
+func (m Model) submit() (tea.Model, tea.Cmd) {
+	sel, err := m.fields[m.focusIndex].SubmitValue()
+	if err != nil {
+		var inner field.ErrValueNotAccepted
+		if errors.As(err, &inner) {
+			// Value isn't ready to be submitted; do nothing.
+			return m, nil
+		}
+	}
+	m.fields[m.focusIndex] = sel.SetBlur()
+
+	if m.focusIndex == 0 && sel.Value() == skip {
+		return m.next()
+	}
+	m.request = sel.UpdateRequest(m.request)
+
+	if f, ok := m.missingField(); ok {
+		m.fields = append(m.fields, f)
+		m.focusIndex++
+		var cmd tea.Cmd
+		m.fields[m.focusIndex], cmd = m.fields[m.focusIndex].SetFocus()
+		return m, cmd
+	}
+
+	if err := m.config.RecordFunc(m.request); err != nil {
+		m.recordErr = err
+		return m, tea.Quit
+	}
+	m.Recorded = append(m.Recorded, fmt.Sprintf("%s: %s", m.request.Vulnerability, m.fields[0].Value()))
+	return m.next()
+}
+
+// next moves on to the next vulnerability, if there's one.
+func (m Model) next() (tea.Model, tea.Cmd) {
+	m.index++
+	m.triage = nil
+	m.triageErr = nil
+	m.fields = nil
+	return m, m.loadTriage()
+}
+
+// missingField returns the field of what the determination still needs, and false if it needs nothing more.
+func (m Model) missingField() (field.Field, bool) {
+	switch m.request.Status {
+	case vex.StatusFixed:
+		if m.request.FixedVersion == "" {
+			return field.NewTextField(m.newFixedVersionFieldConfig()), true
+		}
+	case vex.StatusNotAffected:
+		if m.request.Justification == "" {
+			return field.NewListField(field.ListFieldConfiguration{
+				Prompt:  "Justification: ",
+				Options: vex.Justifications(),
+				RequestUpdater: func(value string, req advisory.Request) advisory.Request {
+					req.Justification = vex.Justification(value)
+					return req
+				},
+			}), true
+		}
+	}
+	return nil, false
+}
+
+func (m Model) newEventFieldConfig() field.ListFieldConfiguration {
+	// the suggested event comes first, so it's the one selected
+	var options []string
+	if m.triage.Suggested != "" {
+		options = append(options, string(m.triage.Suggested))
+	}
+	for _, e := range []advisory.Event{advisory.EventFixed, advisory.EventFalsePositive, advisory.EventFixNotPlanned} {
+		if e != m.triage.Suggested {
+			options = append(options, string(e))
+		}
+	}
+	options = append(options, skip)
+
+	return field.ListFieldConfiguration{
+		Prompt:  "Determination: ",
+		Options: options,
+		RequestUpdater: func(value string, req advisory.Request) advisory.Request {
+			if e, err := advisory.ParseEvent(value); err == nil {
+				e.Apply(&req)
+			}
+			return req
+		},
+	}
+}
+
+func (m Model) newFixedVersionFieldConfig() field.TextFieldConfiguration {
+	allowedVersions := m.config.AllowedFixedVersionsFunc(m.request.Package)
+
+	cfg := field.TextFieldConfiguration{
+		Prompt: "Fixed Version: ",
+		RequestUpdater: func(value string, req advisory.Request) advisory.Request {
+			req.FixedVersion = value
+			return req
+		},
+		AllowedValues:  allowedVersions,
+		NoMatchHelpMsg: "No matching version found.",
+		ValidationRules: []field.TextValidationRule{
+			field.NotEmpty,
+		},
+	}
+
+	switch {
+	case m.triage.Suggested == advisory.EventFixed && m.triage.PackageVersion != "":
+		cfg.DefaultSuggestion = m.triage.PackageVersion
+	case len(allowedVersions) >= 1:
+		cfg.DefaultSuggestion = allowedVersions[0]
+	}
+
+	return cfg
+}
+
+// Err returns the error recording a determination failed with, if it did.
+func (m Model) Err() error {
+	return m.recordErr
+}
+
+func (m Model) View() string {
+	if m.index >= len(m.config.Vulnerabilities) {
+		return ""
+	}
+
+	var lines []string
+	vulnerability := m.config.Vulnerabilities[m.index]
+	lines = append(lines, titleStyle.Render(fmt.Sprintf("%s %s", m.config.Request.Package, vulnerability))+
+		faintStyle.Render(fmt.Sprintf("  (%d of %d)", m.index+1, len(m.config.Vulnerabilities))), "")
+
+	if m.triage == nil {
+		lines = append(lines, faintStyle.Render("Looking up the vulnerability..."))
+		return strings.Join(lines, "\n") + "\n"
+	}
+
+	lines = append(lines, m.triageView()...)
+	lines = append(lines, "")
+	for _, f := range m.fields {
+		lines = append(lines, f.View())
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func (m Model) triageView() []string {
+	t := m.triage
+	var lines []string
+	if m.triageErr != nil {
+		lines = append(lines, errorStyle.Render(fmt.Sprintf("Unable to look up the vulnerability: %s", m.triageErr)))
+	}
+	if d := t.Details; d != nil {
+		if d.Severity != "" {
+			lines = append(lines, labelStyle.Render("Severity: ")+d.Severity)
+		}
+		if d.Description != "" {
+			lines = append(lines, lipgloss.NewStyle().Width(descriptionWidth).Render(d.Description))
+		}
+		if len(d.AffectedVersions) > 0 {
+			ranges := make([]string, 0, len(d.AffectedVersions))
+			for _, vr := range d.AffectedVersions {
+				ranges = append(ranges, vr.String())
+			}
+			lines = append(lines, labelStyle.Render("Affected versions: ")+strings.Join(ranges, "; "))
+		}
+		if len(d.CWEs) > 0 {
+			lines = append(lines, labelStyle.Render("CWEs: ")+strings.Join(d.CWEs, ", "))
+		}
+		if d.URL != "" {
+			lines = append(lines, faintStyle.Render(d.URL))
+		}
+	}
+	if t.PackageVersion != "" {
+		lines = append(lines, labelStyle.Render("Package version: ")+t.PackageVersion)
+	}
+	lines = append(lines, labelStyle.Render("Upstream fix: ")+t.UpstreamStatus)
+	return lines
+}
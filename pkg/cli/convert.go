@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/wolfi-dev/wolfictl/pkg/scaffold"
+)
+
+func cmdConvert() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "convert",
+		Short: "Convert the package definitions of other distributions to melange configs",
+	}
+	cmd.AddCommand(cmdConvertAPKBUILD())
+	return cmd
+}
+
+func cmdConvertAPKBUILD() *cobra.Command {
+	var dir string
+	var stdout, force bool
+	cmd := &cobra.Command{
+		Use:   "apkbuild <path|URL>",
+		Short: "Convert an Alpine APKBUILD to a melange config",
+		Long: `Convert an Alpine APKBUILD to a melange config
+
+The APKBUILD is read from a file, a directory of aports containing one, or a
+URL, like the raw file of a package on gitlab.alpinelinux.org. Its variables
+are expanded, without running any command, and converted to the equivalent
+melange config:
+
+  - pkgname, pkgver, pkgrel, pkgdesc, url, license, arch and depends become
+    the package block
+  - makedepends become the packages of the build environment
+  - remote sources are fetched with their sha512sums, and local patches are
+    applied with the patch pipeline
+  - prepare(), build() and package() become steps of the pipeline, with
+    $pkgdir, $pkgver, $CHOST and the like replaced by their melange
+    equivalents
+  - subpackages run their split function, or the split pipelines of melange
+    for abuild's dev, doc, static and lang
+  - sources downloaded from GitHub get an update block monitoring the
+    repository
+
+What can't be converted, like check(), local files other than patches or
+conditionals on the architecture, is listed on stderr for the maintainer to
+finish by hand.`,
+		Example: `  wolfictl convert apkbuild ../aports/main/zlib
+  wolfictl convert apkbuild https://gitlab.alpinelinux.org/alpine/aports/-/raw/master/main/zlib/APKBUILD --stdout`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := openAPKBUILD(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			a, err := scaffold.ParseAPKBUILD(r)
+			if err != nil {
+				return fmt.Errorf("failed to parse %s: %w", args[0], err)
+			}
+			c, warnings, err := a.Convert()
+			if err != nil {
+				return err
+			}
+			for _, w := range warnings {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%s: %s\n", c.Package.Name, w)
+			}
+			return writeConfig(cmd, c, dir, stdout, force)
+		},
+	}
+
+	cmd.Flags().StringVarP(&dir, "dir", "d", ".", "directory of melange configs to write the config in")
+	cmd.Flags().BoolVar(&stdout, "stdout", false, "print the melange config instead of writing it")
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite an existing melange config")
+	return cmd
+}
+
+// openAPKBUILD opens an APKBUILD from a URL, a file, or a directory containing one
+func openAPKBUILD(cmd *cobra.Command, location string) (io.ReadCloser, error) {
+	if strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://") {
+		req, err := http.NewRequestWithContext(cmd.Context(), http.MethodGet, location, http.NoBody)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", location, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to download %s: %s", location, resp.Status)
+		}
+		return resp.Body, nil
+	}
+	if info, err := os.Stat(location); err == nil && info.IsDir() {
+		location = filepath.Join(location, "APKBUILD")
+	}
+	return os.Open(location)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/tmc/dot"
 	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/groups"
 )
 
 func cmdSVG() *cobra.Command {
@@ -31,9 +32,9 @@ Generate .dot output and pipe it to dot to generate a PNG
 			if err != nil {
 				return err
 			}
-			g, err := dag.NewGraph(pkgs)
+			g, err := dag.NewGraph(pkgs, dag.WithContext(cmd.Context()))
 			if err != nil {
-				return err
+				return explainGraphError(err)
 			}
 
 			if len(args) == 0 {
@@ -41,6 +42,11 @@ Generate .dot output and pipe it to dot to generate a PNG
 					log.Print("warning: the 'show dependents' option has no effect without specifying one or more package names")
 				}
 			} else {
+				args, err = groups.ExpandDir(dir, args, inDag(pkgs))
+				if err != nil {
+					return err
+				}
+
 				// ensure all packages exist in the graph
 				for _, arg := range args {
 					if _, err := g.Graph.Vertex(arg); err == graph.ErrVertexNotFound {
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/git"
+)
+
+func cmdEnvDiff() *cobra.Command {
+	p := &envDiffParams{}
+	cmd := &cobra.Command{
+		Use:   "env-diff <package>",
+		Short: "Compare the resolved build environment of a package between two repo states or versions",
+		Long: `Compare the resolved build environment of a package between two repo states or versions.
+
+The build environment of the package is resolved against the configured
+repositories, the same way as for 'wolfictl text' and friends, and the exact
+dependency versions are compared. Only the packages that were added, removed,
+or resolved to a different version or repository are printed.
+
+The base state to compare against can be another directory of melange
+configs (--base-dir), or a git revision of --dir (--base-ref). When --dir
+contains more than one version of the package, --base-version and --version
+select the two versions to compare.`,
+		Example: `  wolfictl env-diff curl --base-ref HEAD~20
+  wolfictl env-diff curl --base-dir ../os-last-week
+  wolfictl env-diff openssl --base-version 3.1.0 --version 3.1.1`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if p.baseDir != "" && p.baseRef != "" {
+				return errors.New("only one of --base-dir and --base-ref can be used")
+			}
+
+			baseDir := p.baseDir
+			if p.baseRef != "" {
+				d, cleanup, err := git.CheckoutRevision(p.dir, p.baseRef)
+				if err != nil {
+					return err
+				}
+				defer cleanup()
+				baseDir = d
+			}
+			if baseDir == "" {
+				if p.baseVersion == "" && p.version == "" {
+					return errors.New("nothing to compare: specify --base-dir, --base-ref, or --base-version and --version")
+				}
+				baseDir = p.dir
+			}
+
+			// both states are resolved against the same repositories, so only fetch their indexes once
+			indexes := dag.NewIndexCache()
+			base, err := p.resolve(cmd.Context(), baseDir, args[0], p.baseVersion, indexes)
+			if err != nil {
+				return fmt.Errorf("resolving base build environment: %w", err)
+			}
+			current, err := p.resolve(cmd.Context(), p.dir, args[0], p.version, indexes)
+			if err != nil {
+				return fmt.Errorf("resolving build environment: %w", err)
+			}
+
+			return envDiff(dag.DiffBuildEnvironments(base, current), p.outputJSON, cmd.OutOrStdout())
+		},
+	}
+	p.addFlagsTo(cmd)
+	return cmd
+}
+
+type envDiffParams struct {
+	dir, baseDir, baseRef string
+	version, baseVersion  string
+	repos, keys           []string
+	outputJSON            bool
+}
+
+func (p *envDiffParams) addFlagsTo(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&p.dir, "dir", "d", ".", "directory to search for melange configs")
+	cmd.Flags().StringVar(&p.baseDir, "base-dir", "", "directory of melange configs to compare against")
+	cmd.Flags().StringVar(&p.baseRef, "base-ref", "", "git revision of --dir to compare against")
+	cmd.Flags().StringVar(&p.version, "version", "", "version of the package to use, when there is more than one")
+	cmd.Flags().StringVar(&p.baseVersion, "base-version", "", "version of the package to compare against, when there is more than one")
+	cmd.Flags().StringSliceVarP(&p.repos, "repository-append", "r", []string{}, "path to extra repositories to include in the build environment")
+	cmd.Flags().StringSliceVarP(&p.keys, "keyring-append", "k", []string{}, "path to extra keys to include in the build environment keyring")
+	cmd.Flags().BoolVar(&p.outputJSON, "json", false, "print the changes as JSON")
+}
+
+func (p *envDiffParams) resolve(ctx context.Context, dir, name, version string, indexes *dag.IndexCache) ([]dag.Package, error) {
+	pkgs, err := dag.NewPackages(os.DirFS(dir), dir)
+	if err != nil {
+		return nil, err
+	}
+	g, err := dag.NewGraph(pkgs, dag.WithRepos(p.repos...), dag.WithKeys(p.keys...), dag.WithAllowUnresolved(), dag.WithIndexCache(indexes), dag.WithContext(ctx))
+	if err != nil {
+		return nil, explainGraphError(err)
+	}
+	return g.BuildEnvironment(name, version)
+}
+
+func envDiff(changes []dag.EnvironmentChange, outputJSON bool, w io.Writer) error {
+	if outputJSON {
+		if changes == nil {
+			changes = []dag.EnvironmentChange{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(changes)
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "no changes to the resolved build environment")
+		return nil
+	}
+	for _, c := range changes {
+		fmt.Fprintln(w, c)
+	}
+	return nil
+}
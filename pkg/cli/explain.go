@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/explain"
+	"github.com/wolfi-dev/wolfictl/pkg/update"
+)
+
+func cmdExplain() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "explain",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Short:         "Subcommands that explain packages of a repository of melange configs",
+	}
+	cmd.AddCommand(cmdExplainConfig())
+	return cmd
+}
+
+type explainConfigParams struct {
+	dir               string
+	advisoriesRepoDir string
+	upstream          bool
+	lint              bool
+	deps              bool
+}
+
+func cmdExplainConfig() *cobra.Command {
+	p := &explainConfigParams{}
+	cmd := &cobra.Command{
+		Use:   "config <package>",
+		Short: "Render the melange config of a package annotated with what's known about it",
+		Long: `Render the melange config of a package annotated with what's known about it
+
+The config is printed with comments below its lines, indented like them so it
+stays valid YAML:
+
+  - the values that fields with variables, like ${{package.version}}, resolve
+    to, including the lines of the runs of pipelines
+  - the version and repository every package of the build environment
+    resolves to in the dependency graph of --dir
+  - the latest upstream version, looked up like 'wolfictl update' does, next
+    to the version of the package (GitHub is only queried with GITHUB_TOKEN)
+
+It starts with a summary of the package, with the lint findings of the config
+and the number of its advisories by their latest status, if an advisories
+repository is given with --advisories-repo-dir.`,
+		Example: `  wolfictl explain config curl
+  wolfictl explain config curl --advisories-repo-dir ../advisories
+  wolfictl explain config curl --upstream=false --lint=false`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.run(cmd.Context(), args[0], cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().StringVarP(&p.dir, "dir", "d", ".", "directory containing melange configs")
+	addAdvisoriesDirFlag(&p.advisoriesRepoDir, cmd)
+	cmd.Flags().BoolVar(&p.upstream, "upstream", true, "look up the latest upstream version")
+	cmd.Flags().BoolVar(&p.lint, "lint", true, "lint the config")
+	cmd.Flags().BoolVar(&p.deps, "deps", true, "resolve the build dependencies with the dependency graph of --dir")
+	return cmd
+}
+
+func (p *explainConfigParams) run(ctx context.Context, name string, w io.Writer) error {
+	o := explain.Options{
+		Dir:  p.dir,
+		Lint: p.lint,
+	}
+	if p.deps {
+		pkgs, err := dag.NewPackages(os.DirFS(p.dir), p.dir)
+		if err != nil {
+			return err
+		}
+		g, err := dag.NewGraph(pkgs, dag.WithContext(ctx), dag.WithAllowUnresolved())
+		if err != nil {
+			return explainGraphError(err)
+		}
+		o.Graph = g
+	}
+	if p.upstream {
+		o.Upstream = latestUpstreamVersion(p.dir)
+	}
+	if dir := resolveAdvisoriesDir(p.advisoriesRepoDir); dir != "" {
+		index, err := advisoryconfigs.NewIndex(rwos.DirFS(dir))
+		if err != nil {
+			return err
+		}
+		o.Advisories = index
+	}
+
+	e, err := explain.Config(ctx, name, o)
+	if err != nil {
+		return err
+	}
+	return e.Render(w)
+}
+
+// latestUpstreamVersion looks up the latest version of a package with the datasources of wolfictl update
+func latestUpstreamVersion(dir string) explain.UpstreamFunc {
+	return func(ctx context.Context, name string) (string, string, error) {
+		o := update.New()
+		o.Logger = log.New(io.Discard, "", 0)
+		// the GraphQL API of GitHub can't be queried anonymously
+		o.GithubReleaseQuery = os.Getenv("GITHUB_TOKEN") != ""
+		o.ReleaseMonitoringQuery = true
+		o.GitLabReleaseQuery = true
+		o.PyPIQuery = true
+		o.CratesQuery = true
+		o.GoModuleQuery = true
+		o.NpmQuery = true
+		o.RubyGemsQuery = true
+		o.ScrapeQuery = true
+
+		latest, err := o.LatestVersion(ctx, dir, name)
+		return latest.Version, o.Datasource(name), err
+	}
+}
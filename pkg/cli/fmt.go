@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+func cmdFmt() *cobra.Command {
+	var check, write bool
+	cmd := &cobra.Command{
+		Use:   "fmt [file|dir]...",
+		Short: "Format melange configs canonically",
+		Long: `Format melange configs canonically
+
+The sections of the configs and their keys are put in the usual order, the
+sections are separated by blank lines, and everything is indented by two
+spaces. The steps of the pipelines are normalized: an empty with is removed,
+multi-line commands are written as literal blocks without trailing whitespace,
+and long single line commands are folded.
+
+The comments of the configs are kept, as are the blank lines within their
+sections.
+
+Directories are formatted with the configs at their top level. Without
+--write, the changes formatting would make are printed as diffs; --check also
+fails if there are any, for CI.`,
+		Example: `  wolfictl fmt bash.yaml
+  wolfictl fmt --write bash.yaml
+  wolfictl fmt --check .`,
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{"."}
+			}
+			paths, err := configPaths(args)
+			if err != nil {
+				return err
+			}
+
+			var unformatted []string
+			for _, path := range paths {
+				src, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				formatted, err := melange.Format(src)
+				if err != nil {
+					return fmt.Errorf("unable to format %s: %w", path, err)
+				}
+				if bytes.Equal(src, formatted) {
+					continue
+				}
+				unformatted = append(unformatted, path)
+
+				if write {
+					if err := os.WriteFile(path, formatted, 0o600); err != nil {
+						return err
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "formatted %s\n", path)
+					continue
+				}
+				diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+					A:        difflib.SplitLines(string(src)),
+					B:        difflib.SplitLines(string(formatted)),
+					FromFile: path,
+					ToFile:   path,
+					Context:  3,
+				})
+				if err != nil {
+					return err
+				}
+				fmt.Fprint(cmd.OutOrStdout(), diff)
+			}
+
+			if check && len(unformatted) > 0 {
+				return fmt.Errorf("%d configs aren't formatted, run wolfictl fmt --write to format them: %s", len(unformatted), strings.Join(unformatted, ", "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&check, "check", false, "fail if any config isn't formatted")
+	cmd.Flags().BoolVarP(&write, "write", "w", false, "write the formatted configs in place")
+	cmd.MarkFlagsMutuallyExclusive("check", "write")
+	return cmd
+}
+
+// configPaths returns the files given, and the melange configs at the top level of the directories given
+func configPaths(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			return nil, err
+		}
+		var configs []string
+		for _, e := range entries {
+			if e.Type().IsRegular() && !strings.HasPrefix(e.Name(), ".") && strings.HasSuffix(e.Name(), ".yaml") {
+				configs = append(configs, filepath.Join(arg, e.Name()))
+			}
+		}
+		sort.Strings(configs)
+		paths = append(paths, configs...)
+	}
+	return paths, nil
+}
@@ -12,6 +12,7 @@ func Gh() *cobra.Command {
 
 	cmd.AddCommand(
 		Release(),
+		MirrorIssues(),
 	)
 
 	return cmd
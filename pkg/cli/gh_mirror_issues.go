@@ -0,0 +1,289 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/google/go-github/v50/github"
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/gh"
+	wolfigit "github.com/wolfi-dev/wolfictl/pkg/git"
+	"golang.org/x/oauth2"
+)
+
+func MirrorIssues() *cobra.Command {
+	p := &mirrorIssuesParams{}
+	cmd := &cobra.Command{
+		Use:               "mirror-issues",
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		Short:             "Publishes embargoed security work tracked in a private mirror once its embargo lifts",
+		Long: `Publishes embargoed security work tracked in a private mirror once its embargo lifts
+
+Embargoed security work is tracked in open issues of a private repository,
+labelled with --label, whose description declares the embargo:
+
+  package: curl
+  vulnerability: CVE-2023-38545
+  embargo-until: 2023-10-11T06:00:00Z
+
+and its advisory entries are recorded in a private mirror of the advisories
+repository, checked out at --private-advisories-repo-dir.
+
+For every issue whose embargo lifted, the advisory entries are migrated to the
+public advisories repository, committed on a new branch, and proposed in a pull
+request against --pull-request-base-branch. Entries recorded during the
+embargo are dated at the lift, since that's when they became public. The
+private issue is then closed with a link to the pull request.
+
+Issues still under embargo are left alone, so the command can run on a
+schedule. GITHUB_TOKEN needs access to both repositories.`,
+		Example: `  wolfictl gh mirror-issues --private-repo wolfi-dev/advisories-embargoed --private-advisories-repo-dir ../advisories-embargoed -a ../advisories
+  wolfictl gh mirror-issues --private-repo wolfi-dev/advisories-embargoed --private-advisories-repo-dir ../advisories-embargoed -a ../advisories --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			advisoriesRepoDir := resolveAdvisoriesDir(p.advisoriesRepoDir)
+			if advisoriesRepoDir == "" {
+				return fmt.Errorf("advisories repo dir was left unspecified")
+			}
+			owner, repo, ok := strings.Cut(p.privateRepo, "/")
+			if !ok || owner == "" || repo == "" {
+				return fmt.Errorf("private repo %q isn't of the form owner/name", p.privateRepo)
+			}
+			token := os.Getenv("GITHUB_TOKEN")
+			if token == "" {
+				return errors.New("no GITHUB_TOKEN token found")
+			}
+
+			ctx := cmd.Context()
+			logger := log.New(log.Writer(), "wolfictl gh mirror-issues: ", log.LstdFlags|log.Lmsgprefix)
+			ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+			gitOpts := gh.GitOptions{
+				GithubClient: github.NewClient(oauth2.NewClient(ctx, ts)),
+				MaxRetries:   10,
+				Logger:       logger,
+			}
+
+			privateCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(p.privateAdvisoriesRepoDir))
+			if err != nil {
+				return err
+			}
+
+			issues, err := gitOpts.ListIssues(ctx, owner, repo, "open")
+			if err != nil {
+				return fmt.Errorf("unable to list issues of %s: %w", p.privateRepo, err)
+			}
+
+			now := time.Now()
+			var errs []error
+			for _, issue := range issues {
+				if issue.IsPullRequest() || !hasIssueLabel(issue, p.label) {
+					continue
+				}
+				ref := fmt.Sprintf("%s#%d", p.privateRepo, issue.GetNumber())
+
+				e, err := advisory.ParseEmbargo(issue.GetBody())
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", ref, err))
+					continue
+				}
+				if !e.Lifted(now) {
+					logger.Printf("%s: %s in %s is embargoed until %s", ref, e.Vulnerability, e.Package, e.LiftsAt.Format(time.RFC3339))
+					continue
+				}
+
+				if p.dryRun {
+					if err := previewEmbargoLift(cmd, advisoriesRepoDir, e, privateCfgs); err != nil {
+						errs = append(errs, fmt.Errorf("%s: %w", ref, err))
+					}
+					continue
+				}
+
+				prURL, err := p.liftEmbargo(ctx, gitOpts, advisoriesRepoDir, e, privateCfgs)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", ref, err))
+					continue
+				}
+
+				comment := fmt.Sprintf("The embargo lifted, the advisory is proposed in %s", prURL)
+				if prURL == "" {
+					comment = "The embargo lifted, and the advisory is public already."
+				}
+				logger.Printf("%s: %s", ref, comment)
+				if _, err := gitOpts.CommentIssue(ctx, owner, repo, comment, issue.GetNumber()); err != nil {
+					errs = append(errs, fmt.Errorf("%s: unable to comment: %w", ref, err))
+					continue
+				}
+				if err := gitOpts.CloseIssue(ctx, owner, repo, issue.GetNumber()); err != nil {
+					errs = append(errs, fmt.Errorf("%s: unable to close: %w", ref, err))
+				}
+			}
+
+			return errors.Join(errs...)
+		},
+	}
+
+	p.addFlagsTo(cmd)
+	return cmd
+}
+
+type mirrorIssuesParams struct {
+	privateRepo, privateAdvisoriesRepoDir, advisoriesRepoDir string
+	label, pullRequestBaseBranch                             string
+	dryRun                                                   bool
+}
+
+func (p *mirrorIssuesParams) addFlagsTo(cmd *cobra.Command) {
+	addAdvisoriesDirFlag(&p.advisoriesRepoDir, cmd)
+
+	cmd.Flags().StringVar(&p.privateRepo, "private-repo", "", "private GitHub repository tracking the embargoed work, as owner/name")
+	_ = cmd.MarkFlagRequired("private-repo")
+	cmd.Flags().StringVar(&p.privateAdvisoriesRepoDir, "private-advisories-repo-dir", "", "directory containing the private mirror of the advisories repository")
+	_ = cmd.MarkFlagRequired("private-advisories-repo-dir")
+	cmd.Flags().StringVar(&p.label, "label", "embargo", "label of the private issues tracking embargoed work")
+	cmd.Flags().StringVar(&p.pullRequestBaseBranch, "pull-request-base-branch", "main", "base branch to create the pull requests against")
+	cmd.Flags().BoolVar(&p.dryRun, "dry-run", false, "print the diff of the advisory documents of lifted embargoes instead of proposing them")
+}
+
+func hasIssueLabel(issue *github.Issue, label string) bool {
+	for _, l := range issue.Labels {
+		if strings.EqualFold(l.GetName(), label) {
+			return true
+		}
+	}
+	return false
+}
+
+// previewEmbargoLift prints the changes migrating the embargoed advisory would make to the public advisories.
+func previewEmbargoLift(cmd *cobra.Command, advisoriesRepoDir string, e advisory.Embargo, privateCfgs *configs.Index[advisoryconfigs.Document]) error {
+	before, err := readAdvisoryDocuments(advisoriesRepoDir)
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "wolfictl-gh-mirror-issues-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	for name, b := range before {
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0o600); err != nil {
+			return err
+		}
+	}
+
+	publicCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	if err != nil {
+		return err
+	}
+	if _, err := advisory.MigrateEmbargoed(e, advisory.MigrateEmbargoedOptions{PrivateCfgs: privateCfgs, PublicCfgs: publicCfgs}); err != nil {
+		return err
+	}
+
+	after, err := readAdvisoryDocuments(dir)
+	if err != nil {
+		return err
+	}
+	return writeDocumentsDiff(cmd.OutOrStdout(), changedDocuments(before, after), before, after)
+}
+
+// liftEmbargo migrates the embargoed advisory to the public advisories on a new branch, pushes it and opens a pull
+// request, returning its URL. It returns an empty URL if the advisory is public already. The advisories repository is
+// left on the branch it was on.
+func (p *mirrorIssuesParams) liftEmbargo(ctx context.Context, gitOpts gh.GitOptions, dir string, e advisory.Embargo, privateCfgs *configs.Index[advisoryconfigs.Document]) (string, error) {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return "", fmt.Errorf("unable to open advisories repository %s: %w", dir, err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+
+	branch := plumbing.NewBranchReferenceName(fmt.Sprintf("embargo-lift-%s-%s", e.Package, strings.ToLower(e.Vulnerability)))
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(branch, head.Hash())); err != nil {
+		return "", fmt.Errorf("unable to create branch %s: %w", branch.Short(), err)
+	}
+	if err := wt.Checkout(&git.CheckoutOptions{Branch: branch}); err != nil {
+		return "", fmt.Errorf("unable to check out branch %s: %w", branch.Short(), err)
+	}
+	defer func() {
+		restore := &git.CheckoutOptions{Branch: head.Name()}
+		if !head.Name().IsBranch() {
+			restore = &git.CheckoutOptions{Hash: head.Hash()}
+		}
+		if err := wt.Checkout(restore); err != nil {
+			gitOpts.Logger.Printf("unable to check out %s again: %v", head.Name().Short(), err)
+		}
+	}()
+
+	before, err := readAdvisoryDocuments(dir)
+	if err != nil {
+		return "", err
+	}
+	publicCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	if err != nil {
+		return "", err
+	}
+	applied, err := advisory.MigrateEmbargoed(e, advisory.MigrateEmbargoedOptions{PrivateCfgs: privateCfgs, PublicCfgs: publicCfgs})
+	if err != nil {
+		return "", err
+	}
+	if len(applied) == 0 {
+		return "", nil
+	}
+	after, err := readAdvisoryDocuments(dir)
+	if err != nil {
+		return "", err
+	}
+
+	title := fmt.Sprintf("Publish %s for %s", e.Vulnerability, e.Package)
+	var body strings.Builder
+	fmt.Fprintf(&body, "The embargo on %s for %s lifted at %s.\n\n", e.Vulnerability, e.Package, e.LiftsAt.Format(time.RFC3339))
+	for _, req := range applied {
+		fmt.Fprintf(&body, "- %s: %s\n", req.Timestamp.Format(time.RFC3339), req.Status)
+	}
+	if err := commitDocuments(dir, changedDocuments(before, after), title+"\n\n"+body.String()); err != nil {
+		return "", err
+	}
+
+	refSpec := config.RefSpec(fmt.Sprintf("%s:%s", branch, branch))
+	if err := repo.PushContext(ctx, &git.PushOptions{RemoteName: "origin", RefSpecs: []config.RefSpec{refSpec}, Auth: wolfigit.GetGitAuth()}); err != nil {
+		return "", fmt.Errorf("failed to git push: %w", err)
+	}
+
+	gitURL, err := wolfigit.GetRemoteURL(repo)
+	if err != nil {
+		return "", fmt.Errorf("failed to find git origin URL: %w", err)
+	}
+	pr, err := gitOpts.OpenPullRequest(ctx, &gh.NewPullRequest{
+		BasePullRequest: gh.BasePullRequest{
+			Owner:                 gitURL.Organisation,
+			RepoName:              gitURL.Name,
+			Branch:                branch.Short(),
+			PullRequestBaseBranch: p.pullRequestBaseBranch,
+		},
+		Title: title,
+		Body:  body.String(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return pr.GetHTMLURL(), nil
+}
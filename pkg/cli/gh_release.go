@@ -32,7 +32,7 @@ wolfictl gh release --bump-prerelease-with-prefix rc
 				return errors.New("missing flag to bump release version")
 			}
 
-			return releaseOpts.Release()
+			return releaseOpts.Release(cmd.Context())
 		},
 	}
 
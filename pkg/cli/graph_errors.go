@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+// explainGraphError renders an error from dag.NewGraph grouped by kind, with unresolved dependencies listed once
+// along with every package that needs them, rather than once per package.
+func explainGraphError(err error) error {
+	// NewGraph joins the errors of all packages together
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		return err
+	}
+
+	var (
+		neededBy = make(map[string][]string)
+		others   []string
+	)
+	for _, e := range joined.Unwrap() {
+		var unresolved *dag.UnresolvedDependencyError
+		if errors.As(e, &unresolved) {
+			neededBy[unresolved.Dependency] = append(neededBy[unresolved.Dependency], unresolved.Package)
+			continue
+		}
+		others = append(others, e.Error())
+	}
+	if len(neededBy) == 0 {
+		return err
+	}
+
+	deps := make([]string, 0, len(neededBy))
+	for dep := range neededBy {
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "unable to build graph:\n%d unresolved dependencies:", len(deps))
+	for _, dep := range deps {
+		pkgs := neededBy[dep]
+		sort.Strings(pkgs)
+		fmt.Fprintf(&b, "\n  %s, needed by %s", dep, strings.Join(pkgs, ", "))
+	}
+	for _, o := range others {
+		fmt.Fprintf(&b, "\n%s", o)
+	}
+	return errors.New(b.String())
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"log"
+	"path/filepath"
+
+	"chainguard.dev/apko/pkg/build/types"
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/index"
+)
+
+func cmdIndexGenerate() *cobra.Command {
+	var arch, repo, signingKey string
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Regenerate the APKINDEX of a local repository",
+		Long: `Regenerate the APKINDEX of a local repository from the apks in it.
+
+The index of --repo/<arch>/ is replaced with one listing every apk in that
+directory, and signed with --signing-key if it's given. This is what
+wolfictl build does between waves.`,
+		Example: `  wolfictl index generate
+  wolfictl index generate --repo ./packages --arch aarch64 --signing-key local-melange.rsa`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if signingKey == "" {
+				log.Println("no --signing-key provided, not signing index")
+			}
+			dir := filepath.Join(repo, types.ParseArchitecture(arch).ToAPK())
+			if err := index.Generate(cmd.Context(), dir, signingKey); err != nil {
+				return err
+			}
+			log.Printf("wrote %s", filepath.Join(dir, index.ArchiveName))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&arch, "arch", "x86_64", "arch of packages to index")
+	cmd.Flags().StringVar(&repo, "repo", "./packages", "local repository to index")
+	cmd.Flags().StringVar(&signingKey, "signing-key", "", "if set, key to use to sign the index")
+	return cmd
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/index"
+)
+
+func cmdWhoProvides() *cobra.Command {
+	var arch, repo, dir string
+	cmd := &cobra.Command{
+		Use:   "who-provides <constraint>",
+		Short: "List the packages that satisfy a dependency constraint",
+		Long: `List the packages that satisfy a dependency constraint, like foo>=2.3 or so:libc.so.6.
+
+Packages match by name or through one of their provides, using apk version
+comparison. The APKINDEX of --repo is searched, and so are the melange configs
+in --dir if it's given.
+
+Virtual packages, like cmd:cc, so:libc.so.6, pc:libffi or py3dist(requests),
+are matched through provides only, and the provides that matched is printed
+along with each package. Most of them are generated when packages are built,
+so melange configs only provide those they declare explicitly.`,
+		Example: `  wolfictl index who-provides 'openssl>=3.1'
+  wolfictl index who-provides so:libc.so.6 --arch aarch64
+  wolfictl index who-provides cmd:cc
+  wolfictl index who-provides 'go~1.20' --dir .`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := dag.ParseConstraint(args[0])
+			if err != nil {
+				return err
+			}
+
+			if dir != "" {
+				pkgs, err := dag.NewPackages(os.DirFS(dir), dir)
+				if err != nil {
+					return err
+				}
+				for _, config := range pkgs.WhoProvides(c) {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s (%s)\n", config, dag.Local, config.Path)
+				}
+			}
+
+			// Map a friendly string like "wolfi" to its repo URL.
+			if got, found := repos[repo]; found {
+				repo = got
+			}
+			idx, err := index.Index(cmd.Context(), arch, repo)
+			if err != nil {
+				return err
+			}
+			for _, pkg := range dag.WhoProvidesInIndex(idx, c) {
+				if c.IsVirtual() {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s-%s\t%s (provides %s)\n", pkg.Name, pkg.Version, repo, dag.MatchingProvides(pkg, c))
+					continue
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s-%s\t%s\n", pkg.Name, pkg.Version, repo)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&arch, "arch", "x86_64", "arch of packages to search")
+	cmd.Flags().StringVar(&repo, "repo", "wolfi", "repo to search packages in")
+	cmd.Flags().StringVarP(&dir, "dir", "d", "", "directory of melange configs to search too")
+	return cmd
+}
@@ -1,10 +1,22 @@
 package cli
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/git"
 	"github.com/wolfi-dev/wolfictl/pkg/lint"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln/nvdapi"
+	"gopkg.in/yaml.v3"
 )
 
 type lintOptions struct {
@@ -12,8 +24,27 @@ type lintOptions struct {
 	verbose   bool
 	list      bool
 	skipRules []string
+	format    string
+	profile   string
+	jobs      int
+	failFast  bool
+
+	providerIndexes []string
+	pipelineDir     string
+	config          string
+	fix             bool
+	suggestCPEs     bool
+	nvdAPIKey       string
+	changed         bool
+	base            string
 }
 
+const (
+	formatText  = "text"
+	formatSARIF = "sarif"
+	formatJSON  = "json"
+)
+
 func Lint() *cobra.Command {
 	o := &lintOptions{}
 	cmd := &cobra.Command{
@@ -22,52 +53,260 @@ func Lint() *cobra.Command {
 		SilenceUsage:      true,
 		SilenceErrors:     true,
 		Short:             "Lint the code",
+		Long: `Lint the code
+
+Packages are linted concurrently, --jobs at a time. Rules that run external
+commands or touch the network are evaluated last, once the other rules have
+been evaluated for every package. What they fetch, like the Makefile, the
+provider indexes and the CPE dictionary entries of a product, is fetched once
+for all packages.
+
+With --profile, how long each rule takes and how often it finds an issue are
+recorded in a JSON file, which is read back on the next run to evaluate the
+cheapest rules that find the most issues first. Along with --fail-fast, which
+stops linting a package at its first issue, this keeps CI runs short.
+
+With --provider-index, the environment packages and runtime dependencies of
+configs are checked to resolve to a linted package, or a package of one of the
+given APKINDEXes, so typos like openssl-dv are caught before a build fails on
+them, with the package they were likely meant to be. Dependencies on virtual
+packages, like cmd:cc, so:libc.so.6, pc:libffi or py3dist(requests), are
+checked to have a provider among them. Configs building or providing what
+another config does, which dependencies resolve to either of, are reported
+whatever the flags.
+
+Steps that use a pipeline are checked against the pipelines melange has built
+in and the repo-local ones of --pipeline-dir, the pipelines directory by
+default: the pipeline has to exist, its required inputs have to be given, and
+inputs it doesn't take are reported. Repo-local pipelines can be deprecated
+with a top level deprecated key telling what to use instead, which steps
+using them are warned about.
+
+Repositories whose conventions differ from Wolfi's configure the rules in a
+.wolfictl-lint.yaml at their root, or the file given with --config: rules can
+be enabled or disabled, have their severity overridden, or exclude packages.
+
+With --fix, the issues of the rules that can fix them, like sources downloaded
+over http or configs not indented by two spaces, are fixed in the configs,
+keeping their comments, and the fixes applied are reported.
+
+The package.cpe of configs, the vendor and product vulnerabilities of the
+package are matched by in the NVD, is checked to be well formed. The
+missing-cpe rule, disabled by default, reports packages that have none and
+that the vulnerability detector knows no CPE of; with --suggest-cpes, it
+suggests candidates from the NVD CPE dictionary, named like the package and
+preferably on the host of its homepage.
+
+With --changed, only the configs changed in the git worktree are linted,
+staged or not, which keeps pre-commit hooks fast; with --base, the configs
+changed since the merge base of the given revision and HEAD are too, as in a
+pull request. Renamed configs are linted by their new name, and deleted ones
+aren't. Changes to the lint config or to a repo-local pipeline lint every
+config.
+
+--format sarif writes the issues as SARIF, to upload to GitHub code scanning
+so they're annotated on pull requests, and --format json as a list of issues
+for other tools. Both locate issues at the line of the key of the config the
+rule is about, and give the rule and its severity.`,
+		Example: `  wolfictl lint
+  wolfictl lint --profile .lint-profile.json --fail-fast
+  wolfictl lint --provider-index https://packages.wolfi.dev/os/x86_64/APKINDEX.tar.gz
+  wolfictl lint --config lint.yaml
+  wolfictl lint --fix
+  wolfictl lint --changed --base origin/main
+  wolfictl lint --suggest-cpes
+  wolfictl lint --format sarif > lint.sarif`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// args[0] can be used to get the path to the file to lint or `.` to lint the current directory
 			// what if given yaml is not Melange yaml?
 			o.args = args
-			return o.LintCmd()
+			return o.LintCmd(cmd.Context())
 		},
 	}
 	cmd.Flags().BoolVarP(&o.verbose, "verbose", "v", false, "verbose output")
 	cmd.Flags().BoolVarP(&o.list, "list", "l", false, "prints the all of available rules and exits")
 	cmd.Flags().StringArrayVarP(&o.skipRules, "skip-rule", "", []string{}, "list of rules to skip")
+	cmd.Flags().StringVar(&o.profile, "profile", "", "JSON file to order rules by and record their cost in")
+	cmd.Flags().IntVarP(&o.jobs, "jobs", "j", runtime.NumCPU(), "number of packages to lint concurrently")
+	cmd.Flags().BoolVar(&o.failFast, "fail-fast", false, "stop linting a package at its first issue")
+	cmd.Flags().StringArrayVar(&o.providerIndexes, "provider-index", []string{}, "APKINDEX, as a URL or path, to resolve dependencies against")
+	cmd.Flags().StringVar(&o.pipelineDir, "pipeline-dir", "", "directory of the repo-local pipelines, the pipelines directory of the linted one by default")
+	cmd.Flags().BoolVar(&o.fix, "fix", false, "fix the issues of the rules that can fix them in the configs")
+	cmd.Flags().StringVar(&o.config, "config", "", fmt.Sprintf("lint config, defaults to %s in the linted directory", lint.DefaultConfigFile))
+	cmd.Flags().BoolVar(&o.changed, "changed", false, "only lint the configs changed in the git worktree, and since --base if set")
+	cmd.Flags().StringVar(&o.base, "base", "", "with --changed, also lint the configs changed since the merge base of this revision and HEAD, e.g. origin/main")
+	cmd.Flags().BoolVar(&o.suggestCPEs, "suggest-cpes", false, "suggest CPEs from the NVD CPE dictionary for the packages missing-cpe reports")
+	addNVDAPIKeyFlag(&o.nvdAPIKey, cmd)
+	cmd.Flags().StringVar(&o.format, "format", formatText, fmt.Sprintf("output format, one of: %s, %s (for GitHub code scanning), %s", formatText, formatSARIF, formatJSON))
 
 	cmd.AddCommand(LintYam())
 
 	return cmd
 }
 
-func (o lintOptions) LintCmd() error {
-	linter := lint.New(o.makeLintOptions()...)
+func (o lintOptions) LintCmd(ctx context.Context) error {
+	opts := append(o.makeLintOptions(), lint.WithContext(ctx))
+	config, err := o.readConfig()
+	if err != nil {
+		return err
+	}
+	opts = append(opts, lint.WithConfig(config))
+	// with no changed configs, the result is empty, written in the output format like any other
+	unchanged := false
+	if o.changed {
+		packages, all, err := o.changedPackages(ctx)
+		if err != nil {
+			return err
+		}
+		unchanged = !all && len(packages) == 0
+		if unchanged {
+			fmt.Fprintln(os.Stderr, "No changed configs to lint")
+		}
+		if !all {
+			opts = append(opts, lint.WithPackages(packages...))
+		}
+	}
+	var profile *lint.Profile
+	if o.profile != "" {
+		var err error
+		profile, err = lint.ReadProfile(o.profile)
+		if err != nil {
+			return fmt.Errorf("reading lint profile: %w", err)
+		}
+		opts = append(opts, lint.WithProfile(profile))
+	}
+	linter := lint.New(opts...)
 
 	// If the list flag is set, print the list of available rules and exit.
 	if o.list {
-		linter.PrintRules()
-		return nil
+		return linter.PrintRules()
+	}
+
+	if o.format != formatText && o.format != formatSARIF && o.format != formatJSON {
+		return fmt.Errorf("unknown output format %q, must be one of: %s, %s, %s", o.format, formatText, formatSARIF, formatJSON)
 	}
 
 	// Run the linter.
-	result, err := linter.Lint()
-	if err != nil {
-		return err
+	var result lint.Result
+	if !unchanged {
+		result, err = linter.Lint()
+		if err != nil {
+			return err
+		}
 	}
-	if result.HasErrors() {
+	if profile != nil && !unchanged {
+		if err := profile.Write(o.profile); err != nil {
+			return fmt.Errorf("writing lint profile: %w", err)
+		}
+	}
+	switch o.format {
+	case formatSARIF:
+		if err := linter.SARIF(result).Write(os.Stdout); err != nil {
+			return err
+		}
+		if result.HasErrors() {
+			return errors.New("linting failed")
+		}
+		return nil
+	case formatJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(linter.Issues(result)); err != nil {
+			return err
+		}
+		if result.HasErrors() {
+			return errors.New("linting failed")
+		}
+		return nil
+	}
+	if result.HasErrors() || o.fix {
 		linter.Print(result)
+	}
+	if result.HasErrors() {
 		return errors.New("linting failed")
 	}
 	return nil
 }
 
+// readConfig reads the lint config of --config, or the one at the root of the linted directory.
+func (o lintOptions) readConfig() (*lint.Config, error) {
+	path := o.config
+	if path == "" {
+		dir := "."
+		if len(o.args) > 0 {
+			dir = o.args[0]
+		}
+		if fi, err := os.Stat(dir); err == nil && !fi.IsDir() {
+			dir = filepath.Dir(dir)
+		}
+		path = filepath.Join(dir, lint.DefaultConfigFile)
+	}
+	return lint.ReadConfig(path)
+}
+
+// changedPackages returns the packages of the configs changed in the linted directory, or all of them if the lint
+// config or a repo-local pipeline, which can change the issues of any config, changed.
+func (o lintOptions) changedPackages(ctx context.Context) (packages []string, all bool, err error) {
+	dir := "."
+	if len(o.args) > 0 {
+		dir = o.args[0]
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return nil, false, fmt.Errorf("--changed lints the changed configs of a directory, %s isn't one", dir)
+	}
+	files, err := git.ChangedFiles(ctx, dir, o.base)
+	if err != nil {
+		return nil, false, err
+	}
+
+	pipelineDir := o.pipelineDir
+	if pipelineDir == "" {
+		pipelineDir = filepath.Join(dir, "pipelines")
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+		if path == filepath.Clean(o.config) || file == lint.DefaultConfigFile {
+			return nil, true, nil
+		}
+		if rel, err := filepath.Rel(pipelineDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return nil, true, nil
+		}
+		// Packages are only read from the top level of the directory.
+		if filepath.Dir(file) != "." || filepath.Ext(file) != ".yaml" {
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, false, err
+		}
+		var check melange.ConfigCheck
+		if err := yaml.Unmarshal(b, &check); err != nil || check.Package.Name == "" || check.Package.Version == "" {
+			// not a melange config
+			continue
+		}
+		packages = append(packages, check.Package.Name)
+	}
+	return packages, false, nil
+}
+
 func (o lintOptions) makeLintOptions() []lint.Option {
 	if len(o.args) == 0 {
 		// Lint the current directory by default.
 		o.args = []string{"."}
 	}
 
-	return []lint.Option{
+	opts := []lint.Option{
 		lint.WithPath(o.args[0]),
 		lint.WithVerbose(o.verbose),
 		lint.WithSkipRules(o.skipRules),
+		lint.WithJobs(o.jobs),
+		lint.WithFailFast(o.failFast),
+		lint.WithProviderIndexes(o.providerIndexes...),
+		lint.WithPipelineDir(o.pipelineDir),
+		lint.WithFix(o.fix),
+	}
+	if o.suggestCPEs {
+		opts = append(opts, lint.WithCPESuggester(nvdapi.NewDetector(http.DefaultClient, nvdapi.DefaultHost, resolveNVDAPIKey(o.nvdAPIKey))))
 	}
+	return opts
 }
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintCmd_noChangedConfigs(t *testing.T) {
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "foo.yaml"), []byte("package:\n  name: foo\n  version: 1.2.3\n  epoch: 0\n"), 0o600))
+	_, err = wt.Add("foo.yaml")
+	require.NoError(t, err)
+	_, err = wt.Commit("add foo", &git.CommitOptions{Author: &object.Signature{Name: "John Doe", Email: "john@doe.org", When: time.Now()}})
+	require.NoError(t, err)
+
+	for format, want := range map[string]string{
+		formatJSON:  "[]\n",
+		formatSARIF: `"results": []`,
+	} {
+		stdout := os.Stdout
+		read, write, err := os.Pipe()
+		require.NoError(t, err)
+		os.Stdout = write
+		err = lintOptions{args: []string{dir}, changed: true, format: format}.LintCmd(context.Background())
+		os.Stdout = stdout
+		require.NoError(t, write.Close())
+		require.NoError(t, err)
+		written, err := io.ReadAll(read)
+		require.NoError(t, err)
+		assert.Contains(t, string(written), want, "an empty %s result is written", format)
+		assert.NotContains(t, string(written), "No changed configs")
+	}
+}
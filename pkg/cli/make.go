@@ -23,9 +23,9 @@ func cmdMake() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			g, err := dag.NewGraph(pkgs)
+			g, err := dag.NewGraph(pkgs, dag.WithContext(cmd.Context()))
 			if err != nil {
-				return err
+				return explainGraphError(err)
 			}
 
 			filtered, err := g.Filter(dag.FilterLocal())
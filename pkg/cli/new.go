@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
+
+	"github.com/wolfi-dev/wolfictl/pkg/scaffold"
+)
+
+func cmdNew() *cobra.Command {
+	o := scaffold.Options{}
+	var dir string
+	var stdout, force bool
+	cmd := &cobra.Command{
+		Use:   "new <pypi|go|gem|crate|github> <identifier>",
+		Short: "Generate the melange config of a new package from an upstream project",
+		Long: `Generate the melange config of a new package from an upstream project
+
+The config is generated from the metadata of a PyPI project, a Go module, a
+gem, a crate or a GitHub repository, and pre-filled with its name, version,
+description, homepage and license, the sources of the version and their
+checksum or commit, a skeleton of the usual build pipeline of its ecosystem,
+and an update block monitoring the project for new versions. It's meant to be
+edited before it's built: the build dependencies and the pipeline rarely fit
+as they are.
+
+The latest release is packaged, unless --version is given. Licenses that aren't
+valid SPDX expressions are written as upstream declares them, for 'wolfictl
+lint' to flag.
+
+GitHub is looked up with GITHUB_TOKEN when it's set, for higher rate limits.`,
+		Example: `  wolfictl new pypi requests
+  wolfictl new go github.com/sigstore/cosign/v2 --version 2.2.0
+  wolfictl new gem nokogiri
+  wolfictl new crate ripgrep --stdout
+  wolfictl new github https://github.com/jqlang/jq --name jq`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Source, o.Identifier = args[0], args[1]
+			if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+				ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+				o.GitHubClient = github.NewClient(oauth2.NewClient(cmd.Context(), ts))
+			}
+
+			c, err := o.Generate(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return writeConfig(cmd, c, dir, stdout, force)
+		},
+	}
+
+	cmd.Flags().StringVarP(&dir, "dir", "d", ".", "directory of melange configs to write the config in")
+	cmd.Flags().StringVar(&o.Version, "version", "", "version to package, the latest release if empty")
+	cmd.Flags().StringVar(&o.Name, "name", "", "name of the package, derived from the identifier if empty")
+	cmd.Flags().BoolVar(&stdout, "stdout", false, "print the melange config instead of writing it")
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite an existing melange config")
+	cmd.ValidArgs = scaffold.Sources
+	return cmd
+}
+
+// writeConfig writes a generated config to <name>.yaml in dir, unless it already exists and force isn't set, or to
+// the output of the command with stdout
+func writeConfig(cmd *cobra.Command, c *scaffold.Config, dir string, stdout, force bool) error {
+	b, err := c.YAML()
+	if err != nil {
+		return err
+	}
+	if stdout {
+		_, err := cmd.OutOrStdout().Write(b)
+		return err
+	}
+
+	path := filepath.Join(dir, c.Package.Name+".yaml")
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "wrote %s\n", path)
+	return nil
+}
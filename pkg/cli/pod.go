@@ -20,6 +20,7 @@ import (
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/spf13/cobra"
 	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/groups"
 	"golang.org/x/sync/errgroup"
 	"gopkg.in/yaml.v3"
 	corev1 "k8s.io/api/core/v1"
@@ -76,10 +77,14 @@ func cmdPod() *cobra.Command {
 				if err != nil {
 					return err
 				}
-				g, err := dag.NewGraph(pkgs)
+				args, err := groups.ExpandDir(dir, args, inDag(pkgs))
 				if err != nil {
 					return err
 				}
+				g, err := dag.NewGraph(pkgs)
+				if err != nil {
+					return explainGraphError(err)
+				}
 
 				subgraph, err := g.SubgraphWithRoots(args)
 				if err != nil {
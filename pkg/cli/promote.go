@@ -0,0 +1,204 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/index"
+	"github.com/wolfi-dev/wolfictl/pkg/promote"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+	"google.golang.org/api/googleapi"
+)
+
+func cmdPromote() *cobra.Command {
+	p := &promoteParams{}
+	cmd := &cobra.Command{
+		Use:   "promote [package...]",
+		Short: "Copy packages from a staging repository bucket to a production repository bucket",
+		Long: `Copy packages from a staging repository bucket to a production repository bucket.
+
+Each package is given as name-version, like curl-8.1.0-r0, or as a name, which
+promotes its latest version in staging. Without packages, every package of
+staging that production doesn't have at the same version is promoted.
+
+Before anything is copied, the promoted packages are checked to be
+dependency-closed against production: every dependency of a promoted package
+must be provided by production or by another promoted package. Then the apks,
+and their .sig signatures if staging has them, are copied, and the APKINDEX of
+production is regenerated with the promoted packages and signed with
+--signing-key. The APKINDEX is only replaced if production's hasn't changed
+since it was read, so a concurrent promotion or build isn't overwritten; run
+the promotion again if it was.
+
+--from and --to take a bucket location with the gs:// prefix, or one of
+"wolfi", "stage1", "stage2" and "stage3" like 'wolfictl generate-index'.
+With --dry-run, the packages that would be promoted are printed and checked,
+but nothing is copied.`,
+		Example: `  wolfictl promote --from gs://my-staging/os --to wolfi --signing-key wolfi-signing.rsa
+  wolfictl promote curl libcurl-8.1.0-r0 --from gs://my-staging/os --to gs://my-production/os --dry-run`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !p.dryRun && p.signingKey == "" {
+				return errors.New("cowardly refusing to publish APKINDEX without signing; --signing-key must be passed unless --dry-run")
+			}
+			return p.promote(cmd.Context(), args)
+		},
+	}
+	cmd.Flags().StringVar(&p.from, "from", "", "staging bucket to promote packages from")
+	cmd.Flags().StringVar(&p.to, "to", "wolfi", "production bucket to promote packages to")
+	cmd.Flags().StringVar(&p.arch, "arch", "x86_64", "arch of packages to promote")
+	cmd.Flags().StringVar(&p.signingKey, "signing-key", "", "key to sign the regenerated APKINDEX of production with")
+	cmd.Flags().BoolVar(&p.dryRun, "dry-run", false, "check and print the packages to promote without copying them")
+	_ = cmd.MarkFlagRequired("from")
+	return cmd
+}
+
+type promoteParams struct {
+	from, to, arch string
+	signingKey     string
+	dryRun         bool
+}
+
+func (p *promoteParams) promote(ctx context.Context, names []string) error {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	from, err := bucketLocation(p.from)
+	if err != nil {
+		return fmt.Errorf("--from: %w", err)
+	}
+	to, err := bucketLocation(p.to)
+	if err != nil {
+		return fmt.Errorf("--to: %w", err)
+	}
+	src := client.Bucket(from.bucket)
+	dst := client.Bucket(to.bucket)
+
+	staging, _, err := readBucketIndex(ctx, src, from.object(p.arch, index.ArchiveName))
+	if err != nil {
+		return fmt.Errorf("reading staging index: %w", err)
+	}
+	production, generation, err := readBucketIndex(ctx, dst, to.object(p.arch, index.ArchiveName))
+	if err != nil {
+		return fmt.Errorf("reading production index: %w", err)
+	}
+
+	promoted, err := promote.Select(staging, production, names)
+	if err != nil {
+		return err
+	}
+	if len(promoted) == 0 {
+		log.Println("nothing to promote, production is up to date with staging")
+		return nil
+	}
+	if err := promote.Verify(promoted, production); err != nil {
+		return err
+	}
+
+	for _, pkg := range promoted {
+		log.Printf("- %s", promote.Filename(pkg))
+	}
+	if p.dryRun {
+		log.Printf("would promote %d packages", len(promoted))
+		return nil
+	}
+
+	for _, pkg := range promoted {
+		name := promote.Filename(pkg)
+		if err := copyObject(ctx, dst.Object(to.object(p.arch, name)), src.Object(from.object(p.arch, name))); err != nil {
+			return fmt.Errorf("copying %s: %w", name, err)
+		}
+		sig := name + ".sig"
+		err := copyObject(ctx, dst.Object(to.object(p.arch, sig)), src.Object(from.object(p.arch, sig)))
+		if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			return fmt.Errorf("copying %s: %w", sig, err)
+		}
+	}
+
+	// the index is only published once every promoted package is in production
+	tmp, err := os.MkdirTemp("", "wolfictl-promote")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	archive := filepath.Join(tmp, index.ArchiveName)
+	if err := index.Write(ctx, promote.Merge(production, promoted), archive, p.signingKey); err != nil {
+		return err
+	}
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	log.Printf("publishing APKINDEX with %d promoted packages", len(promoted))
+	// the index is replaced only if it's still the one the promoted packages were merged into
+	obj := dst.Object(to.object(p.arch, index.ArchiveName)).If(storage.Conditions{GenerationMatch: generation})
+	w := obj.NewWriter(ctx)
+	w.CacheControl = "no-cache"
+	if _, err := io.Copy(w, f); err != nil {
+		w.Close()
+		return preconditionError(err)
+	}
+	// Closing the GCS object also flushes remaining data, and so it can fail.
+	return preconditionError(w.Close())
+}
+
+// preconditionError explains the failure of the generation precondition of the production index.
+func preconditionError(err error) error {
+	var e *googleapi.Error
+	if errors.As(err, &e) && e.Code == http.StatusPreconditionFailed {
+		return fmt.Errorf("production index changed while promoting, run the promotion again: %w", err)
+	}
+	return err
+}
+
+// gcsLocation is a directory in a GCS bucket.
+type gcsLocation struct {
+	bucket, prefix string
+}
+
+func (l gcsLocation) object(elem ...string) string {
+	return path.Join(append([]string{l.prefix}, elem...)...)
+}
+
+// bucketLocation parses a gs:// location, or one of the known buckets.
+func bucketLocation(s string) (gcsLocation, error) {
+	// Map a friendly string like "wolfi" to its bucket.
+	if got, found := buckets[s]; found {
+		s = got
+	}
+	if !strings.HasPrefix(s, "gs://") {
+		return gcsLocation{}, errors.New("bucket must have gs:// prefix")
+	}
+	bkt, prefix, _ := strings.Cut(strings.TrimPrefix(s, "gs://"), "/")
+	return gcsLocation{bucket: bkt, prefix: prefix}, nil
+}
+
+// readBucketIndex reads an APKINDEX from a bucket, returning the generation of the object it was read from.
+func readBucketIndex(ctx context.Context, b *storage.BucketHandle, name string) (*repository.ApkIndex, int64, error) {
+	r, err := b.Object(name).NewReader(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer r.Close()
+	idx, err := repository.IndexFromArchive(r)
+	return idx, r.Attrs.Generation, err
+}
+
+func copyObject(ctx context.Context, dst, src *storage.ObjectHandle) error {
+	_, err := dst.CopierFrom(src).Run(ctx)
+	return err
+}
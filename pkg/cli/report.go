@@ -0,0 +1,20 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func Report() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "report",
+		Aliases:       []string{"reports"},
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Short:         "Subcommands that report on the packages of a repository of melange configs",
+	}
+	cmd.AddCommand(
+		LicenseInventory(),
+		Risk(),
+	)
+	return cmd
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/spf13/cobra"
+
+	buildconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/build"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/groups"
+	"github.com/wolfi-dev/wolfictl/pkg/license"
+)
+
+func LicenseInventory() *cobra.Command {
+	var dir, format, output string
+	cmd := &cobra.Command{
+		Use:               "license-inventory [package...]",
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		Short:             "Report the licenses of all the packages of a repository of melange configs",
+		Long: `Report the licenses of all the packages of a repository of melange configs
+
+Lists the license of every copyright statement of every package, normalized as
+an SPDX license expression: identifiers get the case of the SPDX License List,
+deprecated GNU identifiers like GPL-2.0 and GPL-2.0+ are replaced by
+GPL-2.0-only and GPL-2.0-or-later, and redundant parentheses are dropped. Each
+license has a status:
+
+  valid    an expression of SPDX or LicenseRef- licenses
+  unknown  an expression using identifiers that aren't on the SPDX License List
+  invalid  not an SPDX license expression
+  missing  no license, or no copyright statement at all
+
+The inventory also counts the packages using each license. It's written as a
+CSV with a row per license (--format csv), as JSON with the entries and the
+counts (--format json), or as Markdown with the counts and the licenses that
+need attention (--format markdown).
+
+If packages are given, only the configs of those packages, or of their origin
+packages if they're subpackages, are inventoried. A package group defined in
+the .package-groups.yaml file of --directory, like @gnome-core, stands for all
+of its packages.`,
+		Example: `  wolfictl report license-inventory
+  wolfictl report license-inventory --format csv -o licenses.csv
+  wolfictl report license-inventory --format json @gnome-core`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f := license.Format(format)
+			switch f {
+			case license.FormatCSV, license.FormatJSON, license.FormatMarkdown:
+			default:
+				return fmt.Errorf("unknown output format %q, must be one of: %s, %s, %s", format, license.FormatCSV, license.FormatJSON, license.FormatMarkdown)
+			}
+
+			index, err := buildconfigs.NewIndex(rwos.DirFS(dir))
+			if err != nil {
+				return fmt.Errorf("unable to index melange configs in %q: %w", dir, err)
+			}
+			cfgs := index.Select().Configurations()
+			if len(args) > 0 {
+				names, err := groups.ExpandDir(dir, args, func(name string) bool {
+					_, err := selectConfigurations(cfgs, []string{name})
+					return err == nil
+				})
+				if err != nil {
+					return err
+				}
+				cfgs, err = selectConfigurations(cfgs, names)
+				if err != nil {
+					return err
+				}
+			}
+			inventory := license.NewInventory(cfgs)
+
+			var w io.Writer = cmd.OutOrStdout()
+			if output != "" {
+				file, err := os.Create(output)
+				if err != nil {
+					return fmt.Errorf("unable to open output file: %w", err)
+				}
+				defer file.Close()
+				w = file
+			}
+			return inventory.Write(w, f)
+		},
+	}
+
+	cmd.Flags().StringVarP(&dir, "directory", "d", ".", "directory containing melange configs")
+	cmd.Flags().StringVar(&format, "format", string(license.FormatMarkdown), fmt.Sprintf("output format, one of: %s, %s, %s", license.FormatCSV, license.FormatJSON, license.FormatMarkdown))
+	cmd.Flags().StringVarP(&output, "output", "o", "", "output location (default: stdout)")
+	return cmd
+}
+
+// selectConfigurations returns the configs of the named packages or subpackages, in the order of cfgs.
+func selectConfigurations(cfgs []build.Configuration, names []string) ([]build.Configuration, error) {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+	var selected []build.Configuration
+	for i := range cfgs {
+		matched := false
+		if wanted[cfgs[i].Package.Name] {
+			matched = true
+			delete(wanted, cfgs[i].Package.Name)
+		}
+		for _, sp := range cfgs[i].Subpackages {
+			if wanted[sp.Name] {
+				matched = true
+				delete(wanted, sp.Name)
+			}
+		}
+		if matched {
+			selected = append(selected, cfgs[i])
+		}
+	}
+	for _, name := range names {
+		if wanted[name] {
+			return nil, fmt.Errorf("no melange config found for package %q", name)
+		}
+	}
+	return selected, nil
+}
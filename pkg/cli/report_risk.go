@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/risk"
+)
+
+func Risk() *cobra.Command {
+	var dir, format, output string
+	var months, top int
+	cmd := &cobra.Command{
+		Use:               "risk",
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		Short:             "Report the packages that are both highly depended upon and frequently changing",
+		Long: `Report the packages that are both highly depended upon and frequently changing
+
+Every package gets the number of packages of the repository that depend on it,
+directly or transitively, and the number of commits per month that changed its
+melange config over the last --months, from the git history of --directory.
+Its score is the dependents times the changes per month. Hotspots are the
+packages in the top quarter by both dependents and changes per month, where
+changes need the most scrutiny and test coverage.
+
+The report is written as Markdown with the hotspots and the --top packages by
+score (--format markdown), as JSON with all the packages (--format json), or as
+a graphviz graph of the dependencies (--format dot) whose packages are labelled
+with their changes per month and dependents, hotspots filled, and whose edges
+are as wide as the dependency changes often.`,
+		Example: `  wolfictl report risk
+  wolfictl report risk --months 6 --format json -o risk.json
+  wolfictl report risk --format dot | dot -Tsvg > risk.svg`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f := risk.Format(format)
+			switch f {
+			case risk.FormatMarkdown, risk.FormatJSON, risk.FormatDot:
+			default:
+				return fmt.Errorf("unknown output format %q, must be one of: %s, %s, %s", format, risk.FormatMarkdown, risk.FormatJSON, risk.FormatDot)
+			}
+			if months <= 0 {
+				return fmt.Errorf("--months must be positive")
+			}
+
+			pkgs, err := dag.NewPackages(os.DirFS(dir), dir)
+			if err != nil {
+				return err
+			}
+			g, err := dag.NewGraph(pkgs, dag.WithContext(cmd.Context()))
+			if err != nil {
+				return explainGraphError(err)
+			}
+
+			until := time.Now().UTC()
+			since := until.AddDate(0, -months, 0)
+			changes, err := risk.ChangeCounts(dir, since)
+			if err != nil {
+				return err
+			}
+			report, err := risk.NewReport(g, dir, changes, since, until)
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = cmd.OutOrStdout()
+			if output != "" {
+				file, err := os.Create(output)
+				if err != nil {
+					return fmt.Errorf("unable to open output file: %w", err)
+				}
+				defer file.Close()
+				w = file
+			}
+			return report.Write(w, f, top)
+		},
+	}
+
+	cmd.Flags().StringVarP(&dir, "directory", "d", ".", "directory containing melange configs, in a git repository")
+	cmd.Flags().IntVar(&months, "months", 12, "number of months of history to count changes over")
+	cmd.Flags().IntVar(&top, "top", 25, "number of packages to list by score in the markdown report, all if 0")
+	cmd.Flags().StringVar(&format, "format", string(risk.FormatMarkdown), fmt.Sprintf("output format, one of: %s, %s, %s", risk.FormatMarkdown, risk.FormatJSON, risk.FormatDot))
+	cmd.Flags().StringVarP(&output, "output", "o", "", "output location (default: stdout)")
+	return cmd
+}
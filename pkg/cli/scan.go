@@ -0,0 +1,444 @@
+package cli
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/apk"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/distro"
+	"github.com/wolfi-dev/wolfictl/pkg/index"
+	"github.com/wolfi-dev/wolfictl/pkg/scan"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln/osv"
+	"golang.org/x/exp/slices"
+)
+
+// Scanners match components against vulnerability data.
+const (
+	scannerOSV   = "osv"
+	scannerGrype = "grype"
+)
+
+var scanners = []string{scannerOSV, scannerGrype}
+
+func cmdScan() *cobra.Command {
+	p := &scanParams{}
+	cmd := &cobra.Command{
+		Use:   "scan <apk or directory>...",
+		Short: "Scan built apks for vulnerabilities",
+		Long: `Scan built apks for vulnerabilities.
+
+The software every apk contains is cataloged: the Go modules and standard
+library of its Go binaries, its Python distributions and its npm packages.
+The apks themselves and their components are then matched against the
+vulnerability data of OSV.dev, and the vulnerabilities found are reported with
+the package and the origin package they belong to, so they can be triaged in
+the advisories of the origin. Their severities and the versions of the
+components that fix them are looked up too, unless --no-details is given.
+
+Vulnerabilities the advisories of the origin already settle for the version of
+the apk, under their ID or one of its aliases, are left out: false positives,
+fixes that aren't planned, and fixes in that version or an earlier one. What's
+reported is what still needs triage. Use --show-triaged to report them too,
+with the event that settled them.
+
+With --create-pending, the vulnerabilities that still need triage and have no
+advisory yet are recorded as detected in the advisories of their origins, once
+per origin, closing the loop between finding them and triaging them. With
+--guide too, they're walked through in the guide first, like 'wolfictl advisory
+guide' does, to record a determination right away, and only the ones skipped
+there are recorded as detected.
+
+Directories, like the packages directory of a build, are searched for apks.
+
+Published packages are scanned with --remote, by name and optionally version,
+like curl or curl@8.1.0-r0: the package, in its latest version unless one is
+given, is downloaded from the --repository, its signature is checked against
+the --keyring, and it's scanned like a local apk. With --subpackages, the
+packages built from the same origin are scanned too.
+
+The findings are written as a table, as JSON, or as SARIF to upload to GitHub
+code scanning, where they're located in the build configurations of their
+origins. Every finding has an ID that stays the same across scans of later
+versions of the package, to diff the findings of two scans by.
+
+The vulnerability data is the one of the --scanner: osv queries the OSV.dev
+API, and grype runs the grype executable, which has to be installed, on an
+SBOM of the components, to compare the scanners or to keep triaging when one
+isn't available. Grype isn't built into wolfictl as a library: the scan uses
+the version of grype that's installed, which --grype-path selects.
+
+With --offline, the osv scanner matches against a local snapshot of the OSV
+records instead, managed with 'wolfictl scan db', e.g. in air-gapped build
+environments.`,
+		Example: `  wolfictl scan packages/x86_64/curl-8.1.0-r0.apk
+  wolfictl scan packages/ --advisories-repo-dir ../advisories --format json -o findings.json
+  wolfictl scan packages/ --show-triaged
+  wolfictl scan packages/ --advisories-repo-dir ../advisories --create-pending --guide
+  wolfictl scan packages/ --format sarif -o scan.sarif
+  wolfictl scan --remote curl@8.1.0-r0 --subpackages --arch aarch64
+  wolfictl scan packages/ --scanner grype
+  wolfictl scan packages/ --offline --db-version 3f2a9c1e8b7d`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f := scan.Format(p.format)
+			if !slices.Contains(scan.Formats, f) {
+				return fmt.Errorf("unknown format %q, must be one of: %s", p.format, formatNames(scan.Formats))
+			}
+			if p.guide && !p.createPending {
+				return errors.New("--guide is only supported with --create-pending")
+			}
+			opts, err := p.scanOptions()
+			if err != nil {
+				return err
+			}
+			advisoriesRepoDir := p.advisoriesRepoDir()
+			if p.createPending && advisoriesRepoDir == "" {
+				return errors.New("--create-pending needs the advisories repo dir to record the vulnerabilities in, use --advisories-repo-dir")
+			}
+			if len(args) == 0 && len(p.remote) == 0 {
+				return errors.New("no apks or directories given, and no --remote packages")
+			}
+
+			var sboms []*scan.SBOM
+			if len(args) > 0 {
+				local, err := catalog(args)
+				if err != nil {
+					return err
+				}
+				sboms = append(sboms, local...)
+			}
+			if len(p.remote) > 0 {
+				remote, err := p.fetchRemote(cmd.Context(), p.remote)
+				if err != nil {
+					return err
+				}
+				sboms = append(sboms, remote...)
+			}
+			for _, sbom := range sboms {
+				log.Printf("cataloged %d component(s) of %s-%s", len(sbom.Components), sbom.Name, sbom.Version)
+			}
+
+			findings, err := scan.Scan(cmd.Context(), sboms, opts)
+			if err != nil {
+				return err
+			}
+			log.Printf("found %d vulnerabilit(y/ies) in %d apk(s)", len(findings), len(sboms))
+
+			if advisoriesRepoDir != "" {
+				advisoryCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(advisoriesRepoDir))
+				if err != nil {
+					return err
+				}
+				var aliases vuln.Aliases
+				if !p.noAliases {
+					ids := make([]string, 0, len(findings))
+					for _, finding := range findings {
+						ids = append(ids, finding.Vulnerability)
+					}
+					aliases = p.aliases.resolveOrWarn(cmd.Context(), "", ids...)
+				}
+				untriaged := len(findings)
+				findings = scan.Triage(findings, advisoryCfgs, aliases, p.showTriaged)
+				if !p.showTriaged {
+					log.Printf("left out %d vulnerabilit(y/ies) settled by advisories", untriaged-len(findings))
+				}
+				if p.createPending {
+					if err := p.recordPending(cmd.Context(), advisoryCfgs, aliases, findings); err != nil {
+						return err
+					}
+				}
+			}
+
+			w, closeOutput, err := p.openOutput(cmd)
+			if err != nil {
+				return err
+			}
+			defer closeOutput()
+			return scan.Write(w, findings, f)
+		},
+	}
+
+	p.addFlagsTo(cmd)
+	cmd.AddCommand(cmdScanDiff(), cmdScanImage(), cmdScanDB())
+	return cmd
+}
+
+type scanParams struct {
+	doNotDetectDistro bool
+	advisoriesDir     string
+
+	format, output     string
+	ecosystem          string
+	scanner            string
+	osvHost, grypePath string
+	noDetails          bool
+
+	offline          bool
+	dbDir, dbVersion string
+	dbMaxAge         time.Duration
+
+	showTriaged bool
+	aliases     aliasParams
+	noAliases   bool
+
+	createPending bool
+	guide         bool
+	enrichment    enrichmentParams
+
+	remote         []string
+	subpackages    bool
+	repositoryURL  string
+	arch           string
+	keyringEntries []string
+}
+
+func (p *scanParams) addFlagsTo(cmd *cobra.Command) {
+	addNoDistroDetectionFlag(&p.doNotDetectDistro, cmd)
+	addAdvisoriesDirFlag(&p.advisoriesDir, cmd)
+
+	p.addMatchFlagsTo(cmd, scan.Formats)
+
+	cmd.Flags().BoolVar(&p.showTriaged, "show-triaged", false, "also report the vulnerabilities the advisories settle, with the event that settled them")
+	p.aliases.addFlagsTo(cmd)
+	cmd.Flags().BoolVar(&p.noAliases, "no-aliases", false, "do not look for vulnerabilities in the advisories under their aliases")
+
+	cmd.Flags().BoolVar(&p.createPending, "create-pending", false, "record the vulnerabilities without an advisory as detected in the advisories of their origins")
+	cmd.Flags().BoolVar(&p.guide, "guide", false, "with --create-pending, walk through the vulnerabilities in the guide to record determinations right away")
+	p.enrichment.addFlagsTo(cmd)
+
+	cmd.Flags().StringSliceVar(&p.remote, "remote", nil, "published packages to scan, as name or name@version")
+	p.addRemoteFlagsTo(cmd)
+}
+
+// addMatchFlagsTo adds the flags of matching apks against vulnerability data, and of writing the results in one of
+// the formats.
+func (p *scanParams) addMatchFlagsTo(cmd *cobra.Command, formats []scan.Format) {
+	cmd.Flags().StringVarP(&p.format, "format", "f", string(scan.FormatTable), fmt.Sprintf("output format, one of: %s", formatNames(formats)))
+	cmd.Flags().StringVarP(&p.output, "output", "o", "", "output location (default: stdout)")
+	cmd.Flags().StringVar(&p.ecosystem, "ecosystem", "Wolfi", "OSV ecosystem to match the apks themselves in, or empty to only match their components")
+	cmd.Flags().StringVar(&p.scanner, "scanner", scannerOSV, fmt.Sprintf("scanner to match with, one of: %s", strings.Join(scanners, ", ")))
+	cmd.Flags().StringVar(&p.osvHost, "osv-host", osv.DefaultHost, "host of the OSV API, with the osv scanner")
+	cmd.Flags().StringVar(&p.grypePath, "grype-path", "grype", "grype executable, with the grype scanner")
+	cmd.Flags().BoolVar(&p.noDetails, "no-details", false, "do not look up the severities and fixed versions of the vulnerabilities found")
+	cmd.Flags().BoolVar(&p.offline, "offline", false, "match against the local vulnerability database instead of the OSV API, with the osv scanner")
+	addScanDBDirFlag(&p.dbDir, cmd)
+	cmd.Flags().StringVar(&p.dbVersion, "db-version", "", "version the local vulnerability database has to be, to pin offline scans to")
+	cmd.Flags().DurationVar(&p.dbMaxAge, "db-max-age", defaultDBMaxAge, "age of the local vulnerability database to warn about")
+}
+
+// addRemoteFlagsTo adds the flags of downloading published packages.
+func (p *scanParams) addRemoteFlagsTo(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&p.subpackages, "subpackages", false, "also scan the packages built from the same origin as the published packages")
+	cmd.Flags().StringVar(&p.repositoryURL, "repository", "https://packages.wolfi.dev/os", "URL of the repository to download published packages from")
+	cmd.Flags().StringVar(&p.arch, "arch", "x86_64", "architecture of the published packages")
+	cmd.Flags().StringSliceVar(&p.keyringEntries, "keyring", []string{"https://packages.wolfi.dev/os/wolfi-signing.rsa.pub"}, "paths or URLs of the public keys published packages have to be signed with")
+}
+
+// scanOptions returns the options of matching with the --scanner.
+func (p *scanParams) scanOptions() (scan.Options, error) {
+	var matcher scan.VulnScanner
+	switch p.scanner {
+	case scannerOSV:
+		if !p.offline {
+			matcher = &scan.OSVMatcher{Client: osv.NewClient(http.DefaultClient, p.osvHost)}
+			break
+		}
+		db, err := openScanDB(p.dbDir, p.dbVersion, p.dbMaxAge)
+		if err != nil {
+			return scan.Options{}, err
+		}
+		matcher = &scan.OSVMatcher{DB: db}
+	case scannerGrype:
+		if p.offline {
+			return scan.Options{}, errors.New("--offline is only supported by the osv scanner")
+		}
+		matcher = &scan.GrypeMatcher{Path: p.grypePath, Distro: p.ecosystem}
+	default:
+		return scan.Options{}, fmt.Errorf("unknown scanner %q, must be one of: %s", p.scanner, strings.Join(scanners, ", "))
+	}
+
+	opts := scan.Options{
+		Matcher:   matcher,
+		Ecosystem: p.ecosystem,
+	}
+	if !p.noDetails {
+		opts.Detailer = matcher
+	}
+	return opts, nil
+}
+
+// openOutput returns where to write the results to, and how to close it when they're written.
+func (p *scanParams) openOutput(cmd *cobra.Command) (io.Writer, func(), error) {
+	if p.output == "" {
+		return cmd.OutOrStdout(), func() {}, nil
+	}
+	file, err := os.Create(p.output)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to open output file: %w", err)
+	}
+	return file, func() { file.Close() }, nil
+}
+
+// catalog returns the SBOMs of the apks given, and of the ones in the directories given.
+func catalog(args []string) ([]*scan.SBOM, error) {
+	apks, err := findAPKs(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(apks) == 0 {
+		return nil, fmt.Errorf("no apks found in %s", strings.Join(args, ", "))
+	}
+	sboms := make([]*scan.SBOM, 0, len(apks))
+	for _, file := range apks {
+		sbom, err := catalogAPK(file)
+		if err != nil {
+			return nil, err
+		}
+		sboms = append(sboms, sbom)
+	}
+	return sboms, nil
+}
+
+// fetchRemote downloads the published packages, as name or name@version, checks their signatures, and returns their
+// SBOMs.
+func (p *scanParams) fetchRemote(ctx context.Context, refs []string) ([]*scan.SBOM, error) {
+	keys := make(map[string]*rsa.PublicKey)
+	for _, entry := range p.keyringEntries {
+		b, err := readLocation(ctx, entry)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read key: %w", err)
+		}
+		key, err := apk.ParsePublicKey(b)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse key %s: %w", entry, err)
+		}
+		keys[path.Base(entry)] = key
+	}
+
+	idx, err := index.Index(ctx, p.arch, p.repositoryURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the index of %s: %w", p.repositoryURL, err)
+	}
+
+	opts := scan.RemoteOptions{
+		Client:        http.DefaultClient,
+		RepositoryURL: p.repositoryURL,
+		Arch:          p.arch,
+		Keys:          keys,
+	}
+	var sboms []*scan.SBOM
+	for _, ref := range refs {
+		pkgs, err := scan.ResolveRemote(idx, ref, p.subpackages)
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range pkgs {
+			log.Printf("downloading %s-%s", pkg.Name, pkg.Version)
+			sbom, err := scan.FetchRemote(ctx, pkg, opts)
+			if err != nil {
+				return nil, err
+			}
+			sboms = append(sboms, sbom)
+		}
+	}
+	return sboms, nil
+}
+
+// readLocation reads a file, or downloads it if location is a URL.
+func readLocation(ctx context.Context, location string) ([]byte, error) {
+	if !strings.HasPrefix(location, "https://") && !strings.HasPrefix(location, "http://") {
+		return os.ReadFile(location)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch %s: %w", location, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch %s: got response status %d", location, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// advisoriesRepoDir returns the directory of the advisories to leave out the settled vulnerabilities with, or "" if
+// there's none, in which case every vulnerability is reported.
+func (p *scanParams) advisoriesRepoDir() string {
+	if dir := resolveAdvisoriesDir(p.advisoriesDir); dir != "" || p.doNotDetectDistro {
+		return dir
+	}
+	d, err := distro.Detect()
+	if err != nil {
+		log.Printf("no advisories repo dir specified, and distro auto-detection failed, reporting every vulnerability: %s", err)
+		return ""
+	}
+	_, _ = fmt.Fprint(os.Stderr, renderDetectedDistro(d))
+	return d.AdvisoriesRepoDir
+}
+
+func formatNames(formats []scan.Format) string {
+	names := make([]string, 0, len(formats))
+	for _, f := range formats {
+		names = append(names, string(f))
+	}
+	return strings.Join(names, ", ")
+}
+
+// findAPKs returns the apks given, and the ones in the directories given.
+func findAPKs(args []string) ([]string, error) {
+	var apks []string
+	for _, arg := range args {
+		fi, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			apks = append(apks, arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(file, ".apk") {
+				apks = append(apks, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return apks, nil
+}
+
+func catalogAPK(file string) (*scan.SBOM, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sbom, err := scan.Catalog(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to catalog %s: %w", file, err)
+	}
+	return sbom, nil
+}
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/scan"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln/osv"
+)
+
+// defaultDBMaxAge is the age of the database after which it's warned about, as it's missing recent vulnerabilities.
+const defaultDBMaxAge = 7 * 24 * time.Hour
+
+func cmdScanDB() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Manage the local vulnerability database of offline scans",
+		Long: `Manage the local vulnerability database of offline scans.
+
+'wolfictl scan --offline' matches against a local snapshot of the OSV records
+of the ecosystems of apks and their components, instead of querying the OSV.dev
+API, for scanning in air-gapped build environments. The snapshot is
+downloaded with 'wolfictl scan db download', or downloaded on another host and
+brought over with 'wolfictl scan db import'. Every archive of the snapshot is
+checked against the digest it was downloaded with before it's used.
+
+Every snapshot has a version, derived from its digests, that scans can be
+pinned to with --db-version, so that CI scans of the same apks with the same
+snapshot have the same results.`,
+	}
+	cmd.AddCommand(cmdScanDBDownload(), cmdScanDBStatus(), cmdScanDBImport())
+	return cmd
+}
+
+func cmdScanDBDownload() *cobra.Command {
+	var dir, baseURL string
+	var ecosystems []string
+	cmd := &cobra.Command{
+		Use:   "download",
+		Short: "Download a snapshot of the vulnerability database",
+		Example: `  wolfictl scan db download
+  wolfictl scan db download --dir ./osv-db --ecosystem Go,Wolfi`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := resolveScanDBDir(dir)
+			if err != nil {
+				return err
+			}
+			m, err := osv.DownloadDB(cmd.Context(), http.DefaultClient, baseURL, dir, ecosystems)
+			if err != nil {
+				return err
+			}
+			log.Printf("downloaded version %s of the database to %s", m.Version(), dir)
+			return nil
+		},
+	}
+	addScanDBDirFlag(&dir, cmd)
+	cmd.Flags().StringVar(&baseURL, "url", osv.DefaultDBURL, "URL the OSV records of the ecosystems are published under")
+	cmd.Flags().StringSliceVar(&ecosystems, "ecosystem", []string{"Wolfi", scan.EcosystemGo, scan.EcosystemPyPI, scan.EcosystemNPM}, "OSV ecosystems to download the records of")
+	return cmd
+}
+
+func cmdScanDBStatus() *cobra.Command {
+	var dir string
+	var maxAge time.Duration
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the version and age of the vulnerability database, and check its integrity",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := resolveScanDBDir(dir)
+			if err != nil {
+				return err
+			}
+			m, err := osv.VerifyDB(dir)
+			if err != nil {
+				return err
+			}
+
+			age := m.Age(time.Now())
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintf(tw, "Directory:\t%s\n", dir)
+			fmt.Fprintf(tw, "Version:\t%s\n", m.Version())
+			fmt.Fprintf(tw, "Downloaded:\t%s (%s ago)\n", m.Downloaded.Format(time.RFC3339), age.Round(time.Minute))
+			fmt.Fprintf(tw, "Source:\t%s\n", m.Source)
+			for _, e := range m.Ecosystems {
+				fmt.Fprintf(tw, "Ecosystem:\t%s (%d records)\n", e.Name, e.Records)
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+			warnScanDBAge(m, maxAge)
+			return nil
+		},
+	}
+	addScanDBDirFlag(&dir, cmd)
+	cmd.Flags().DurationVar(&maxAge, "max-age", defaultDBMaxAge, "age of the database to warn about")
+	return cmd
+}
+
+func cmdScanDBImport() *cobra.Command {
+	var dir string
+	cmd := &cobra.Command{
+		Use:   "import <database directory>",
+		Short: "Import a snapshot of the vulnerability database downloaded elsewhere",
+		Example: `  wolfictl scan db download --dir ./osv-db   # on a connected host
+  wolfictl scan db import ./osv-db           # on the air-gapped one`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := resolveScanDBDir(dir)
+			if err != nil {
+				return err
+			}
+			m, err := osv.ImportDB(args[0], dir)
+			if err != nil {
+				return err
+			}
+			log.Printf("imported version %s of the database to %s", m.Version(), dir)
+			return nil
+		},
+	}
+	addScanDBDirFlag(&dir, cmd)
+	return cmd
+}
+
+func addScanDBDirFlag(dir *string, cmd *cobra.Command) {
+	cmd.Flags().StringVar(dir, "db-dir", "", "directory of the vulnerability database (default is wolfictl/osv-db in the user cache directory)")
+}
+
+func resolveScanDBDir(dir string) (string, error) {
+	if dir != "" {
+		return dir, nil
+	}
+	dir, err := osv.DefaultDBDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to determine the database dir, use --db-dir: %w", err)
+	}
+	return dir, nil
+}
+
+// openScanDB opens the vulnerability database, which has to be the version given, if one is.
+func openScanDB(dir, version string, maxAge time.Duration) (*osv.DB, error) {
+	dir, err := resolveScanDBDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	db, err := osv.OpenDB(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the vulnerability database, see 'wolfictl scan db': %w", err)
+	}
+	if version != "" && !strings.EqualFold(db.Metadata.Version(), version) {
+		return nil, fmt.Errorf("the vulnerability database %s is version %s, not the pinned version %s", dir, db.Metadata.Version(), version)
+	}
+	log.Printf("matching against version %s of the vulnerability database", db.Metadata.Version())
+	warnScanDBAge(db.Metadata, maxAge)
+	return db, nil
+}
+
+func warnScanDBAge(m *osv.DBMetadata, maxAge time.Duration) {
+	if age := m.Age(time.Now()); maxAge > 0 && age > maxAge {
+		log.Printf("warning: the vulnerability database is %s old, older than %s, and misses recent vulnerabilities", age.Round(time.Hour), maxAge)
+	}
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/scan"
+	"golang.org/x/exp/slices"
+)
+
+// scanReportFormats are the formats the reports of scan diff and scan image can be written in.
+var scanReportFormats = []scan.Format{scan.FormatTable, scan.FormatJSON}
+
+func cmdScanDiff() *cobra.Command {
+	p := &scanParams{}
+	var remote bool
+	cmd := &cobra.Command{
+		Use:   "diff <old> <new>",
+		Short: "Compare the vulnerabilities of two versions of packages",
+		Long: `Compare the vulnerabilities of two versions of packages.
+
+The old and new versions, each an apk or a directory of apks, are scanned like
+'wolfictl scan' scans them, and the vulnerabilities are reported by how they
+changed: introduced by the new version, fixed by it, or persisted from the old
+one. Vulnerabilities are compared by package, component and ID, regardless of
+the versions of the components.
+
+With --remote, the old and new versions are published packages, as
+name@version, downloaded from the --repository and checked against the
+--keyring.`,
+		Example: `  wolfictl scan diff curl-8.1.0-r0.apk packages/x86_64/curl-8.4.0-r0.apk
+  wolfictl scan diff --remote curl@8.1.0-r0 curl@8.4.0-r0 --subpackages
+  wolfictl scan diff --remote --format json go-1.21@1.21.1-r0 go-1.21@1.21.3-r0`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f := scan.Format(p.format)
+			if !slices.Contains(scanReportFormats, f) {
+				return fmt.Errorf("unknown format %q, must be one of: %s", p.format, formatNames(scanReportFormats))
+			}
+
+			// both versions share the matcher, to look up the details of the vulnerabilities they have in common once
+			opts, err := p.scanOptions()
+			if err != nil {
+				return err
+			}
+			var findings [2][]scan.Finding
+			for i, arg := range args {
+				var sboms []*scan.SBOM
+				if remote {
+					sboms, err = p.fetchRemote(cmd.Context(), []string{arg})
+				} else {
+					sboms, err = catalog([]string{arg})
+				}
+				if err != nil {
+					return err
+				}
+				findings[i], err = scan.Scan(cmd.Context(), sboms, opts)
+				if err != nil {
+					return err
+				}
+				log.Printf("found %d vulnerabilit(y/ies) in %s", len(findings[i]), arg)
+			}
+
+			changes := scan.Diff(findings[0], findings[1])
+
+			w, closeOutput, err := p.openOutput(cmd)
+			if err != nil {
+				return err
+			}
+			defer closeOutput()
+			return scan.WriteDiff(w, changes, f)
+		},
+	}
+
+	p.addMatchFlagsTo(cmd, scanReportFormats)
+	cmd.Flags().BoolVar(&remote, "remote", false, "compare published packages, given as name@version")
+	p.addRemoteFlagsTo(cmd)
+	return cmd
+}
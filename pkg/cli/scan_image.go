@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/index"
+	"github.com/wolfi-dev/wolfictl/pkg/scan"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+	"golang.org/x/exp/slices"
+)
+
+func cmdScanImage() *cobra.Command {
+	p := &scanParams{}
+	var platform string
+	var noPublished bool
+	cmd := &cobra.Command{
+		Use:   "image <ref>",
+		Short: "Scan the packages installed in a container image for vulnerabilities",
+		Long: `Scan the packages installed in a container image for vulnerabilities.
+
+The image is pulled, and the apk packages installed in it are read from its apk
+database, along with the components found in the files each package installed.
+They're scanned like 'wolfictl scan' scans apks, and the vulnerabilities are
+reported grouped by the origin of the packages they were found in, i.e. by the
+build configuration that fixing them changes.
+
+Unless --no-published is given, the installed versions are compared with the
+ones published in the --repository: vulnerabilities of the packages themselves
+whose fix is published are marked, as rebuilding the image fixes them.`,
+		Example: `  wolfictl scan image cgr.dev/chainguard/curl:latest
+  wolfictl scan image --platform linux/arm64 --format json cgr.dev/chainguard/go:latest`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f := scan.Format(p.format)
+			if !slices.Contains(scanReportFormats, f) {
+				return fmt.Errorf("unknown format %q, must be one of: %s", p.format, formatNames(scanReportFormats))
+			}
+
+			opts, err := p.scanOptions()
+			if err != nil {
+				return err
+			}
+
+			ref, err := name.ParseReference(args[0])
+			if err != nil {
+				return fmt.Errorf("unable to parse image reference: %w", err)
+			}
+			targetPlatform, err := v1.ParsePlatform(platform)
+			if err != nil {
+				return fmt.Errorf("unable to parse platform: %w", err)
+			}
+			img, err := remote.Image(ref,
+				remote.WithContext(cmd.Context()),
+				remote.WithAuthFromKeychain(authn.DefaultKeychain),
+				remote.WithPlatform(*targetPlatform),
+			)
+			if err != nil {
+				return fmt.Errorf("unable to pull %s: %w", ref, err)
+			}
+
+			log.Printf("cataloging the packages of %s", ref)
+			sboms, err := scan.CatalogImage(img)
+			if err != nil {
+				return err
+			}
+			log.Printf("found %d installed package(s)", len(sboms))
+
+			findings, err := scan.Scan(cmd.Context(), sboms, opts)
+			if err != nil {
+				return err
+			}
+			log.Printf("found %d vulnerabilit(y/ies) in %s", len(findings), ref)
+
+			var published *repository.ApkIndex
+			if !noPublished && len(sboms) > 0 {
+				// the packages of an image are of the same architecture
+				published, err = index.Index(cmd.Context(), sboms[0].Arch, p.repositoryURL)
+				if err != nil {
+					log.Printf("unable to read the index of %s, not checking for published fixes: %s", p.repositoryURL, err)
+				}
+			}
+
+			w, closeOutput, err := p.openOutput(cmd)
+			if err != nil {
+				return err
+			}
+			defer closeOutput()
+			return scan.WriteImageReport(w, scan.GroupByOrigin(findings, published), f)
+		},
+	}
+
+	p.addMatchFlagsTo(cmd, scanReportFormats)
+	cmd.Flags().StringVar(&platform, "platform", "linux/amd64", "platform of the image to scan, for multi-platform images")
+	cmd.Flags().StringVar(&p.repositoryURL, "repository", "https://packages.wolfi.dev/os", "URL of the repository the packages of the image are installed from")
+	cmd.Flags().BoolVar(&noPublished, "no-published", false, "do not check the repository for published fixes")
+	return cmd
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/cli/components/advisory/guide"
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/scan"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+	"golang.org/x/exp/slices"
+)
+
+// recordPending records the vulnerabilities of the findings that have no advisory yet as detected in the advisories
+// of their origins. With --guide, they're walked through in the guide first, to record a determination right away,
+// and only the ones skipped there are recorded as detected.
+func (p *scanParams) recordPending(ctx context.Context, advisoryCfgs *configs.Index[advisoryconfigs.Document], aliases vuln.Aliases, findings []scan.Finding) error {
+	// advisories are kept under CVE or GHSA IDs, so vulnerabilities without one, like some Go ones, can't be recorded
+	var pending []advisory.Request
+	for _, req := range scan.Pending(findings, aliases, time.Now()) {
+		if !advisory.ValidVulnerabilityID(req.Vulnerability) {
+			log.Printf("unable to record %s of %s, it has no CVE or GHSA alias", req.Vulnerability, req.Package)
+			continue
+		}
+		pending = append(pending, req)
+	}
+	opts := advisory.CreateOptions{AdvisoryCfgs: advisoryCfgs, Aliases: aliases}
+
+	if p.guide && len(pending) > 0 {
+		determined, err := p.guidePending(ctx, pending, findings, opts)
+		if err != nil {
+			return err
+		}
+		var skipped []advisory.Request
+		for _, req := range pending {
+			if !determined[req.Package+" "+req.Vulnerability] {
+				skipped = append(skipped, req)
+			}
+		}
+		pending = skipped
+	}
+
+	for _, req := range pending {
+		if err := advisory.Create(req, opts); err != nil {
+			return fmt.Errorf("unable to record %s of %s as detected: %w", req.Vulnerability, req.Package, err)
+		}
+		log.Printf("recorded %s of %s as detected", req.Vulnerability, req.Package)
+	}
+	return nil
+}
+
+// guidePending walks through the pending vulnerabilities in the guide, an origin at a time, and returns the ones a
+// determination was recorded for, as "<origin> <vulnerability>".
+func (p *scanParams) guidePending(ctx context.Context, pending []advisory.Request, findings []scan.Finding, opts advisory.CreateOptions) (map[string]bool, error) {
+	detailer, err := p.enrichment.enricher()
+	if err != nil {
+		return nil, err
+	}
+
+	// the versions of the packages of each origin that were scanned, which are the versions fixes can be recorded in
+	versions := make(map[string][]string)
+	for _, f := range findings {
+		if !slices.Contains(versions[f.Origin], f.Version) {
+			versions[f.Origin] = append(versions[f.Origin], f.Version)
+		}
+	}
+
+	var origins []string
+	vulnerabilities := make(map[string][]string)
+	for _, req := range pending {
+		if _, ok := vulnerabilities[req.Package]; !ok {
+			origins = append(origins, req.Package)
+		}
+		vulnerabilities[req.Package] = append(vulnerabilities[req.Package], req.Vulnerability)
+	}
+
+	determined := make(map[string]bool)
+	for _, origin := range origins {
+		origin := origin
+		packageVersion := versions[origin][0]
+		m := guide.New(guide.Configuration{
+			Vulnerabilities: vulnerabilities[origin],
+			TriageFunc: func(vulnerability string) (advisory.Triage, error) {
+				details, err := detailer.VulnerabilityDetails(ctx, origin, vulnerability)
+				return advisory.NewTriage(origin, packageVersion, vulnerability, details), err
+			},
+			AllowedFixedVersionsFunc: func(string) []string {
+				return versions[origin]
+			},
+			RecordFunc: func(req advisory.Request) error {
+				req.Timestamp = time.Now()
+				if err := req.Validate(); err != nil {
+					return err
+				}
+				if err := advisory.Create(req, opts); err != nil {
+					return err
+				}
+				determined[origin+" "+req.Vulnerability] = true
+				return nil
+			},
+			Request: advisory.Request{Package: origin},
+		})
+
+		returnedModel, err := tea.NewProgram(m).Run()
+		if err != nil {
+			return nil, err
+		}
+		m, ok := returnedModel.(guide.Model)
+		if !ok {
+			return nil, fmt.Errorf("unexpected model type: %T", returnedModel)
+		}
+		if err := m.Err(); err != nil {
+			return nil, fmt.Errorf("unable to record the determination: %w", err)
+		}
+		for _, r := range m.Recorded {
+			log.Printf("recorded %s of %s", r, origin)
+		}
+	}
+	return determined, nil
+}
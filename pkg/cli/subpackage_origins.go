@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/git"
+)
+
+func cmdSubpackageOrigins() *cobra.Command {
+	var dir, baseRef string
+	var orphansOnly, outputJSON bool
+	cmd := &cobra.Command{
+		Use:   "subpackage-origins",
+		Short: "List the origin package of each subpackage and flag orphan subpackages",
+		Long: `List the origin package of each subpackage and flag orphan subpackages.
+
+Every subpackage declared by the melange configs in --dir is printed along
+with the origin package that builds it. Subpackages that packages depend on,
+but whose origin package is missing from the graph, are flagged as orphans.
+
+With --base-ref, subpackages that the configs at that git revision declared,
+and that no config declares anymore because their origin was removed or
+dropped them, are flagged too if packages still depend on them. Without this
+check, such dependencies quietly resolve to a stale package upstream, or fail
+to resolve much later.
+
+The command fails if any orphan subpackage is found.`,
+		Example: `  wolfictl subpackage-origins
+  wolfictl subpackage-origins --base-ref origin/main --orphans-only`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			pkgs, err := dag.NewPackages(os.DirFS(dir), dir)
+			if err != nil {
+				return err
+			}
+			// unresolved dependencies are kept as vertices, as they may be on subpackages that are gone
+			g, err := dag.NewGraph(pkgs, dag.WithAllowUnresolved(), dag.WithContext(cmd.Context()))
+			if err != nil {
+				return explainGraphError(err)
+			}
+
+			var base *dag.Packages
+			if baseRef != "" {
+				d, cleanup, err := git.CheckoutRevision(dir, baseRef)
+				if err != nil {
+					return err
+				}
+				defer cleanup()
+				base, err = dag.NewPackages(os.DirFS(d), d)
+				if err != nil {
+					return fmt.Errorf("reading packages at %s: %w", baseRef, err)
+				}
+			}
+
+			orphans, err := g.OrphanSubpackages(base)
+			if err != nil {
+				return err
+			}
+
+			w := cmd.OutOrStdout()
+			if outputJSON {
+				out := struct {
+					Origins []dag.SubpackageOrigin `json:"origins,omitempty"`
+					Orphans []dag.OrphanSubpackage `json:"orphans"`
+				}{Orphans: orphans}
+				if !orphansOnly {
+					out.Origins = g.SubpackageOrigins()
+				}
+				if out.Orphans == nil {
+					out.Orphans = []dag.OrphanSubpackage{}
+				}
+				enc := json.NewEncoder(w)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(out); err != nil {
+					return err
+				}
+			} else {
+				if !orphansOnly {
+					for _, s := range g.SubpackageOrigins() {
+						fmt.Fprintln(w, s)
+					}
+				}
+				for _, o := range orphans {
+					fmt.Fprintf(w, "orphan %s\n", o)
+				}
+			}
+
+			if len(orphans) > 0 {
+				return fmt.Errorf("found %d orphan subpackages", len(orphans))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&dir, "dir", "d", ".", "directory to search for melange configs")
+	cmd.Flags().StringVar(&baseRef, "base-ref", "", "git revision to also flag the subpackages removed since")
+	cmd.Flags().BoolVar(&orphansOnly, "orphans-only", false, "only print orphan subpackages")
+	cmd.Flags().BoolVar(&outputJSON, "json", false, "print the subpackages and orphans as JSON")
+	return cmd
+}
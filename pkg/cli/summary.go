@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/wolfi-dev/wolfictl/pkg/builder"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/history"
+)
+
+type summaryParams struct {
+	dir               string
+	advisoriesRepoDir string
+	buildSummaries    []string
+	deps              bool
+	format            string
+	output            string
+}
+
+func cmdSummary() *cobra.Command {
+	p := &summaryParams{}
+	cmd := &cobra.Command{
+		Use:   "summary <package>",
+		Short: "Summarize the track record of a package for reviewers",
+		Long: `Summarize the track record of a package for reviewers
+
+Gives the context of a change to a package at a glance:
+
+  - the commits that changed its melange config in the git history of --dir,
+    how often they bumped its version or epoch and how many were reverts
+  - the last new version proposed by 'wolfictl update', from the titles of
+    its commits
+  - its builds and failed builds in the --build-summary files written by
+    'wolfictl build --summary-file', e.g. the ones kept by CI
+  - its open advisories, whose latest status is affected or under
+    investigation, if an advisories repository is given with
+    --advisories-repo-dir
+  - the number of packages that depend on it to build, directly or
+    transitively, from the dependency graph of --dir`,
+		Example: `  wolfictl summary openssl
+  wolfictl summary openssl --build-summary ci/build-summary.json --advisories-repo-dir ../advisories
+  wolfictl summary openssl --format json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f := history.Format(p.format)
+			switch f {
+			case history.FormatText, history.FormatJSON:
+			default:
+				return fmt.Errorf("unknown output format %q, must be one of: %s, %s", p.format, history.FormatText, history.FormatJSON)
+			}
+
+			o := history.Options{Dir: p.dir}
+			for _, path := range p.buildSummaries {
+				s, err := builder.ReadSummary(path)
+				if err != nil {
+					return err
+				}
+				o.BuildSummaries = append(o.BuildSummaries, s)
+			}
+			if dir := resolveAdvisoriesDir(p.advisoriesRepoDir); dir != "" {
+				index, err := advisoryconfigs.NewIndex(rwos.DirFS(dir))
+				if err != nil {
+					return err
+				}
+				o.Advisories = index
+			}
+			if p.deps {
+				pkgs, err := dag.NewPackages(os.DirFS(p.dir), p.dir)
+				if err != nil {
+					return err
+				}
+				g, err := dag.NewGraph(pkgs, dag.WithContext(cmd.Context()), dag.WithAllowUnresolved())
+				if err != nil {
+					return explainGraphError(err)
+				}
+				o.Graph = g
+			}
+
+			s, err := history.New(args[0], o)
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = cmd.OutOrStdout()
+			if p.output != "" {
+				file, err := os.Create(p.output)
+				if err != nil {
+					return fmt.Errorf("unable to open output file: %w", err)
+				}
+				defer file.Close()
+				w = file
+			}
+			return s.Write(w, f)
+		},
+	}
+	cmd.Flags().StringVarP(&p.dir, "dir", "d", ".", "directory containing melange configs, in a git repository")
+	addAdvisoriesDirFlag(&p.advisoriesRepoDir, cmd)
+	cmd.Flags().StringArrayVar(&p.buildSummaries, "build-summary", nil, "build summary written by wolfictl build --summary-file to count builds from, oldest first, can be given several times")
+	cmd.Flags().BoolVar(&p.deps, "deps", true, "count the dependents of the package with the dependency graph of --dir")
+	cmd.Flags().StringVar(&p.format, "format", string(history.FormatText), fmt.Sprintf("output format, one of: %s, %s", history.FormatText, history.FormatJSON))
+	cmd.Flags().StringVarP(&p.output, "output", "o", "", "output location (default: stdout)")
+	return cmd
+}
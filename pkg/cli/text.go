@@ -11,6 +11,7 @@ import (
 	"github.com/dominikbraun/graph"
 	"github.com/spf13/cobra"
 	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/groups"
 )
 
 func cmdText() *cobra.Command {
@@ -26,9 +27,9 @@ func cmdText() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			g, err := dag.NewGraph(pkgs)
+			g, err := dag.NewGraph(pkgs, dag.WithContext(cmd.Context()))
 			if err != nil {
-				return err
+				return explainGraphError(err)
 			}
 
 			if len(args) == 0 {
@@ -36,6 +37,11 @@ func cmdText() *cobra.Command {
 					log.Print("warning: the 'show dependents' option has no effect without specifying one or more package names")
 				}
 			} else {
+				args, err = groups.ExpandDir(dir, args, inDag(pkgs))
+				if err != nil {
+					return err
+				}
+
 				// ensure all packages exist in the graph
 				for _, arg := range args {
 					if _, err := g.Graph.Vertex(arg); err == graph.ErrVertexNotFound {
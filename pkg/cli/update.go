@@ -8,9 +8,13 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
 	"github.com/wolfi-dev/wolfictl/pkg/update"
 )
 
+// the pull requests an update run couldn't create are saved here, in the working directory, by default
+const defaultFailureQueueFile = "wolfictl-update-failures.json"
+
 type options struct {
 	packageNames           []string
 	pullRequestBaseBranch  string
@@ -18,19 +22,143 @@ type options struct {
 	dryRun                 bool
 	githubReleaseQuery     bool
 	releaseMonitoringQuery bool
+	gitlabReleaseQuery     bool
+	pypiQuery              bool
+	cratesQuery            bool
+	goModuleQuery          bool
+	npmQuery               bool
+	rubyGemsQuery          bool
+	scrapeQuery            bool
 	useGitSign             bool
 	createIssues           bool
 	issueLabels            []string
+	summaryFile            string
+	pushgatewayURL         string
+	shard                  string
+	failureQueueFile       string
+	maxSourceSize          int64
+	concurrency            int
+	force                  bool
+	releaseNotes           bool
+	advisoriesRepoDir      string
+	securityLabel          string
+	validate               string
+	historyDepth           int
+	all                    bool
+	format                 string
 }
 
 func Update() *cobra.Command {
 	o := &options{}
 	cmd := &cobra.Command{
-		Use:     "update",
-		Short:   "Proposes melange package update(s) via a pull request",
-		Long:    `"Proposes melange package update(s) via a pull request".`,
-		Example: `  wolfictl update https://github.com/wolfi-dev/os`,
-		Args:    cobra.RangeArgs(1, 1),
+		Use:   "update",
+		Short: "Proposes melange package update(s) via a pull request",
+		Long: `"Proposes melange package update(s) via a pull request".
+
+With --shard index/total, only the packages of that shard are checked, so a
+large repository can be scanned by several jobs at once. Packages are assigned
+to shards by a hash of their name, so every package is checked by exactly one
+shard and no pull request is opened twice. Combine the --summary-file of every
+shard with 'wolfictl update merge-summaries'.
+
+Pushing the branch of a pull request and opening it are retried with
+exponential backoff when GitHub fails transiently, and a pull request that
+still fails doesn't stop the run. It's saved to --failure-queue-file, and
+'wolfictl update retry-failed' replays only the saved pull requests. A failure
+queue holds the pull requests of a single repository and base branch.
+
+Packages whose upstream is hosted on gitlab.com or a self-hosted GitLab are
+checked with an update.gitlab block in their melange config, which takes the
+same identifier, strip-prefix, strip-suffix, tag-filter and use-tag keys as
+update.github, and an optional host. Set GITLAB_TOKEN to check private
+projects or raise the GitLab rate limit.
+
+Packages released on PyPI are checked with an update.pypi block, whose
+identifier is the name of the project. Yanked releases are skipped, and so are
+pre-releases unless include-prereleases is set. The fetch step of the config
+is pointed at the sdist of the new release.
+
+Rust crates released on crates.io are checked with an update.crates block,
+whose identifier is the name of the crate and whose optional constraint, e.g.
+">= 0.9, < 1.0", restricts the versions to update to. Yanked versions and
+pre-releases are skipped.
+
+Go modules are checked against the Go module proxy with an update.go block,
+whose identifier is the path of the module. Later major versions published
+under a major version suffix of the path, e.g. /v2, are followed too.
+Pre-releases are skipped, and a module with no tagged versions, only
+pseudo-versions, is reported as a failure.
+
+Packages published on the npm registry are checked with an update.npm block,
+whose identifier is the name of the package. The version the latest dist-tag
+points at is used, skipping deprecated versions, unless include-prereleases is
+set. The fetch step of the config is pointed at the tarball of the new version.
+
+Gems released on rubygems.org are checked with an update.rubygems block, whose
+identifier is the name of the gem and whose optional platform, e.g.
+x86_64-linux, selects a gem with precompiled native extensions rather than
+the default ruby one. Pre-releases are skipped unless include-prereleases is
+set. The fetch step of the config is pointed at the .gem of the new version.
+
+Packages whose upstream publishes releases on a plain web page, like the
+directory listings of GNU mirrors, kernel.org or Apache dist, are checked with
+an update.scrape block, whose url is the page and whose pattern is a regular
+expression capturing the version in a group named version, or in its only
+group. The matched versions are filtered and sorted like GitHub tags.
+
+The expected-sha256 and expected-sha512 of the fetch steps of an updated
+package are recomputed by downloading the sources of the new version, up to
+--max-source-size, and checked against the digests the datasource publishes,
+e.g. PyPI or npm. The checksums are reported in the pull request.
+
+With --dry-run --all, every package is checked with its datasource, but
+nothing is updated. Instead a report of the current and latest version of
+every package, the datasource used, and for how many days outdated packages
+have been on their version, is written to stdout as JSON (--format json) or as
+Markdown with the outdated packages and the failed lookups (--format md), e.g.
+for a weekly dashboard of stale packages.
+
+The datasources are queried at once, each with a rate limit of its own, and
+every datasource checks --concurrency packages at once. Responses are reused
+within a run, so packages asking about the same upstream project, like the
+version streams of a library, only query it once.
+
+Some versions found upstream aren't proposed: versions lower than the current
+one, versions whose update was reverted, found in the subjects of the last
+--history-depth commits like 'Revert "curl/8.1.2 package update"', and
+versions that look like pre-releases, unless the update config includes
+pre-releases. Datasources that know about yanked releases already skip them.
+The skipped updates are listed at the end of the run with the reason, and in
+the skipped field of the --summary-file. --force proposes them anyway.
+
+The .update-policy.yaml file of the repository can set a weekly cadence,
+freeze windows, a maximum version jump, like no new major versions, and the
+reviewers of the pull requests of packages and package groups. The updates the
+policy refuses are skipped too, even with --force.
+
+The pull request of a package released on GitHub, found with its update.github
+config or the tag of its git-checkout step, links to the changes between the
+tags of the current and the new version, and includes the notes of the GitHub
+releases in between, or else the section about the new version of the
+CHANGELOG.md, CHANGES.md or NEWS.md of the repository. Disable it with
+--release-notes=false.
+
+With the advisories repository given with --advisories-repo-dir, the
+vulnerabilities of a package with a pending advisory, under investigation or
+affected, are looked up on https://osv.dev. When the new version is at or past
+a version OSV says fixes one of them, the pull request of the update is opened
+before the others, gets the --security-label label, and lists the
+vulnerabilities fixed.
+
+With --validate lint, the bumped config of a package is linted before its pull
+request is opened, and with --validate build it's also built for the
+architecture of this machine with make, like 'make packages/x86_64/...'. A
+bump that fails is reported like other failures rather than proposed, and the
+pull request of one that passes says what was checked.`,
+		Example: `  wolfictl update https://github.com/wolfi-dev/os
+  wolfictl update https://github.com/wolfi-dev/os --shard 3/10 --summary-file summary-3.json
+  wolfictl update https://github.com/wolfi-dev/os --dry-run --all --format md > stale.md`,
+		Args: cobra.RangeArgs(1, 1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return o.UpdateCmd(cmd.Context(), args[0])
 		},
@@ -39,23 +167,60 @@ func Update() *cobra.Command {
 	cmd.Flags().BoolVar(&o.dryRun, "dry-run", false, "prints proposed package updates rather than creating a pull request")
 	cmd.Flags().BoolVar(&o.githubReleaseQuery, "github-release-query", true, "query the GitHub graphql API for latest releases")
 	cmd.Flags().BoolVar(&o.releaseMonitoringQuery, "release-monitoring-query", true, "query https://release-monitoring.org/ API for latest releases")
-	cmd.Flags().StringArrayVar(&o.packageNames, "package-name", []string{}, "Optional: provide a specific package name to check for updates rather than searching all packages in a repo URI")
+	cmd.Flags().BoolVar(&o.gitlabReleaseQuery, "gitlab-release-query", true, "query the GitLab API for latest releases of packages with an update.gitlab config")
+	cmd.Flags().BoolVar(&o.pypiQuery, "pypi-query", true, "query https://pypi.org/ for latest releases of packages with an update.pypi config")
+	cmd.Flags().BoolVar(&o.cratesQuery, "crates-query", true, "query https://crates.io/ for latest versions of packages with an update.crates config")
+	cmd.Flags().BoolVar(&o.goModuleQuery, "go-module-query", true, "query https://proxy.golang.org/ for latest versions of packages with an update.go config")
+	cmd.Flags().BoolVar(&o.npmQuery, "npm-query", true, "query https://registry.npmjs.org/ for latest versions of packages with an update.npm config")
+	cmd.Flags().BoolVar(&o.rubyGemsQuery, "rubygems-query", true, "query https://rubygems.org/ for latest versions of packages with an update.rubygems config")
+	cmd.Flags().BoolVar(&o.scrapeQuery, "scrape-query", true, "scrape the pages of packages with an update.scrape config for latest versions")
+	cmd.Flags().StringArrayVar(&o.packageNames, "package-name", []string{}, "Optional: provide a specific package name, or a package group like @gnome-core, to check for updates rather than searching all packages in a repo URI")
 	cmd.Flags().StringVar(&o.pullRequestBaseBranch, "pull-request-base-branch", "main", "base branch to create a pull request against")
 	cmd.Flags().StringVar(&o.pullRequestTitle, "pull-request-title", "%s/%s package update", "the title to use when creating a pull request")
 	cmd.Flags().BoolVar(&o.useGitSign, "use-gitsign", false, "enable gitsign to sign the git commits")
 	cmd.Flags().BoolVar(&o.createIssues, "create-issues", true, "creates GitHub Issues for failed package updates")
 	cmd.Flags().StringArrayVar(&o.issueLabels, "github-labels", []string{}, "Optional: provide a list of labels to apply to updater generated issues and pull requests")
+	cmd.Flags().StringVar(&o.summaryFile, "summary-file", "", "Optional: write a JSON summary of the run to this file, use - for stdout")
+	cmd.Flags().StringVar(&o.pushgatewayURL, "pushgateway-url", "", "Optional: push run metrics to this Prometheus pushgateway")
+	cmd.Flags().StringVar(&o.shard, "shard", "", "Optional: only check the packages of this shard, as index/total, e.g. 3/10")
+	cmd.Flags().StringVar(&o.failureQueueFile, "failure-queue-file", defaultFailureQueueFile, "file to save the pull requests that couldn't be created to, for 'wolfictl update retry-failed'")
+	cmd.Flags().BoolVar(&o.all, "all", false, "with --dry-run, report the latest versions of all the packages rather than updating them")
+	cmd.Flags().StringVar(&o.format, "format", string(update.ReportFormatMarkdown), fmt.Sprintf("format of the --all report, one of: %s, %s", update.ReportFormatJSON, update.ReportFormatMarkdown))
+	cmd.Flags().Int64Var(&o.maxSourceSize, "max-source-size", melange.DefaultMaxSourceSize>>20, "limit in MiB of the size of the sources downloaded to recompute the checksums of fetch steps")
+	cmd.Flags().IntVar(&o.concurrency, "concurrency", update.DefaultLookupConcurrency, "number of packages every datasource checks at once")
+	cmd.Flags().BoolVar(&o.releaseNotes, "release-notes", true, "add the upstream release notes since the current version to the pull requests of packages released on GitHub")
+	addAdvisoriesDirFlag(&o.advisoriesRepoDir, cmd)
+	cmd.Flags().StringVar(&o.securityLabel, "security-label", update.DefaultSecurityLabel, "label of the pull requests of updates that fix vulnerabilities with pending advisories")
+	cmd.Flags().StringVar(&o.validate, "validate", update.ValidateNone, fmt.Sprintf("check bumped packages before opening their pull request, one of: %s, %s, %s", update.ValidateNone, update.ValidateLint, update.ValidateBuild))
+	cmd.Flags().BoolVar(&o.force, "force", false, "propose updates to lower, reverted and pre-release versions too")
+	cmd.Flags().IntVar(&o.historyDepth, "history-depth", update.DefaultHistoryDepth, "number of commits of history to clone to find the updates that were reverted, all of them if 0")
 
 	cmd.AddCommand(
 		Package(),
+		cmdUpdateMergeSummaries(),
+		cmdUpdateRetryFailed(),
 	)
 
 	return cmd
 }
 
-func (o options) UpdateCmd(_ context.Context, repoURI string) error {
+func (o options) UpdateCmd(ctx context.Context, repoURI string) error {
 	updateContext := update.New()
 
+	if o.all {
+		if !o.dryRun {
+			return errors.New("--all only reports the latest versions, and needs --dry-run")
+		}
+		if len(o.packageNames) > 0 {
+			return errors.New("--all can't be combined with --package-name")
+		}
+		switch update.ReportFormat(o.format) {
+		case update.ReportFormatJSON, update.ReportFormatMarkdown:
+		default:
+			return fmt.Errorf("unknown report format %q, must be one of: %s, %s", o.format, update.ReportFormatJSON, update.ReportFormatMarkdown)
+		}
+	}
+
 	if !o.dryRun && os.Getenv("GITHUB_TOKEN") == "" {
 		return errors.New("no GITHUB_TOKEN token found")
 	}
@@ -70,12 +235,127 @@ func (o options) UpdateCmd(_ context.Context, repoURI string) error {
 	updateContext.PullRequestTitle = o.pullRequestTitle
 	updateContext.ReleaseMonitoringQuery = o.releaseMonitoringQuery
 	updateContext.GithubReleaseQuery = o.githubReleaseQuery
+	updateContext.GitLabReleaseQuery = o.gitlabReleaseQuery
+	updateContext.PyPIQuery = o.pypiQuery
+	updateContext.CratesQuery = o.cratesQuery
+	updateContext.GoModuleQuery = o.goModuleQuery
+	updateContext.NpmQuery = o.npmQuery
+	updateContext.RubyGemsQuery = o.rubyGemsQuery
+	updateContext.ScrapeQuery = o.scrapeQuery
 	updateContext.UseGitSign = o.useGitSign
 	updateContext.CreateIssues = o.createIssues
 	updateContext.IssueLabels = o.issueLabels
-	if err := updateContext.Update(); err != nil {
+	updateContext.SummaryFile = o.summaryFile
+	updateContext.PushgatewayURL = o.pushgatewayURL
+	updateContext.FailureQueueFile = o.failureQueueFile
+	updateContext.MaxSourceSize = o.maxSourceSize << 20
+	if o.concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1, got %d", o.concurrency)
+	}
+	updateContext.Concurrency = o.concurrency
+	if o.historyDepth < 0 {
+		return fmt.Errorf("--history-depth must not be negative, got %d", o.historyDepth)
+	}
+	updateContext.Force = o.force
+	updateContext.ReleaseNotes = o.releaseNotes
+	updateContext.AdvisoriesDir = resolveAdvisoriesDir(o.advisoriesRepoDir)
+	updateContext.SecurityLabel = o.securityLabel
+	switch o.validate {
+	case update.ValidateNone, update.ValidateLint, update.ValidateBuild:
+	default:
+		return fmt.Errorf("unknown validation %q, must be one of: %s, %s, %s", o.validate, update.ValidateNone, update.ValidateLint, update.ValidateBuild)
+	}
+	updateContext.Validate = o.validate
+	updateContext.HistoryDepth = o.historyDepth
+	if o.shard != "" {
+		shard, err := update.ParseShard(o.shard)
+		if err != nil {
+			return err
+		}
+		updateContext.Shard = shard
+	}
+	if o.all {
+		report, err := updateContext.Report(ctx)
+		if err != nil {
+			return fmt.Errorf("reporting stale packages: %w", err)
+		}
+		return report.Write(os.Stdout, update.ReportFormat(o.format))
+	}
+	if err := updateContext.Update(ctx); err != nil {
 		return fmt.Errorf("creating updates: %w", err)
 	}
 
 	return nil
 }
+
+func cmdUpdateMergeSummaries() *cobra.Command {
+	var summaryFile, pushgatewayURL string
+	cmd := &cobra.Command{
+		Use:   "merge-summaries <summary-file>...",
+		Short: "Merge the run summaries of the shards of an update run",
+		Long: `Merge the run summaries of the shards of an update run.
+
+The --summary-file of every shard of a run started with --shard are combined
+into a summary of the whole run, which is printed, written to --summary-file,
+and pushed to --pushgateway-url. Its metrics are grouped by shard "all", so
+they don't replace the ones of the shards or of unsharded runs. It fails if the
+summary of any shard is missing.`,
+		Example: `  wolfictl update merge-summaries summary-*.json --summary-file summary.json`,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			summaries := make([]*update.RunSummary, 0, len(args))
+			for _, path := range args {
+				s, err := update.ReadRunSummary(path)
+				if err != nil {
+					return err
+				}
+				summaries = append(summaries, s)
+			}
+			merged, err := update.MergeRunSummaries(summaries...)
+			if err != nil {
+				return err
+			}
+
+			o := update.New()
+			o.Summary = merged
+			o.SummaryFile = summaryFile
+			o.PushgatewayURL = pushgatewayURL
+			return o.ReportSummary()
+		},
+	}
+	cmd.Flags().StringVar(&summaryFile, "summary-file", "", "Optional: write the merged JSON summary to this file, use - for stdout")
+	cmd.Flags().StringVar(&pushgatewayURL, "pushgateway-url", "", "Optional: push the merged run metrics to this Prometheus pushgateway")
+	return cmd
+}
+
+func cmdUpdateRetryFailed() *cobra.Command {
+	var failureQueueFile string
+	cmd := &cobra.Command{
+		Use:   "retry-failed",
+		Short: "Retry the pull requests that an update run failed to create",
+		Long: `Retry the pull requests that an update run failed to create.
+
+Replays only the pull requests saved to the --failure-queue-file of earlier
+update runs, with the repository, labels and pull request settings of those
+runs. If the branch of a pull request was pushed, only the pull request is
+opened, otherwise the package is updated again with the version found by the
+run. The pull requests that fail again stay in the queue, and the file is
+removed once it's empty.`,
+		Example: `  wolfictl update retry-failed
+  wolfictl update retry-failed --failure-queue-file failures-3.json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if os.Getenv("GITHUB_TOKEN") == "" {
+				return errors.New("no GITHUB_TOKEN token found")
+			}
+			o := update.New()
+			o.FailureQueueFile = failureQueueFile
+			if err := o.RetryFailed(cmd.Context()); err != nil {
+				return fmt.Errorf("retrying failed pull requests: %w", err)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&failureQueueFile, "failure-queue-file", defaultFailureQueueFile, "file the update runs saved the pull requests that couldn't be created to")
+	return cmd
+}
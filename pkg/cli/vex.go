@@ -1,12 +1,17 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 
 	"chainguard.dev/melange/pkg/build"
+	govex "github.com/openvex/go-vex/pkg/vex"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/distro"
 	"github.com/wolfi-dev/wolfictl/pkg/vex"
 )
 
@@ -23,12 +28,15 @@ that use them.
 
 wolfictl can generate VEX data by reading the melange configuration files
 of each package and additional information coming from external documents.
-There are currently two VEX subcommands:
+There are currently three VEX subcommands:
 
  wolfictl vex package: Generates VEX documents from a list of melange configs
 
  wolfictl vex sbom: Generates a VEX document by reading an image SBOM
 
+ wolfictl vex advisories: Generates an OpenVEX or CSAF VEX document from the
+ advisories repository
+
 For more information please see the help sections if these subcommands. To know
 more about the VEX tooling powering wolfictl see: https://openvex.dev/
 
@@ -39,6 +47,7 @@ more about the VEX tooling powering wolfictl see: https://openvex.dev/
 
 	addPackage(cmd)
 	addSBOM(cmd)
+	addAdvisories(cmd)
 	return cmd
 }
 
@@ -47,8 +56,14 @@ var vexCfg = vex.Config{
 	DistroRepo: "",
 	Author:     "",
 	AuthorRole: "",
+	Namespace:  "https://wolfi.dev",
 }
 
+const (
+	vexFormatOpenVEX = "openvex"
+	vexFormatCSAF    = "csaf"
+)
+
 func addPackage(parent *cobra.Command) {
 	cmd := &cobra.Command{
 		Use:           "package [flags] CONFIG [CONFIG]...",
@@ -136,3 +151,103 @@ func addCommonVexFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&vexCfg.Author, "author", "", "author of the VEX document")
 	cmd.Flags().StringVar(&vexCfg.AuthorRole, "role", "", "role of the author of the VEX document")
 }
+
+func addAdvisories(parent *cobra.Command) {
+	var advisoriesRepoDir, packageName, image, format string
+	var doNotDetectDistro bool
+	cmd := &cobra.Command{
+		Use: "advisories [flags]",
+		Example: `wolfictl vex advisories --package curl
+wolfictl vex advisories --image cgr.dev/chainguard/git@sha256:... --format csaf`,
+		Short: "Generate a VEX document from the advisories repository",
+		Long: `wolfictl vex advisories: Generate a VEX document from the advisories repository
+
+The vex advisories subcommand generates an OpenVEX document, or with
+--format csaf a CSAF VEX document, from the advisories of the packages of the
+distro, so that consumers can attach VEX attestations to published images.
+The triage events the advisories record become the VEX statuses they amount
+to: a false positive is not_affected with its justification, a fix is fixed,
+and so on.
+
+The document is about all packages with advisories, the one given with
+--package, or, with --image, the distro packages an image contains. The
+image is given as a path to its SPDX SBOM or as a reference, ideally by
+digest, of an image with an SBOM attached, and the statements are about the
+image, with the packages as subcomponents.
+
+Unlike OpenVEX, CSAF only has the current status of every product, which is
+the one of the latest advisory entry.
+`,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != vexFormatOpenVEX && format != vexFormatCSAF {
+				return fmt.Errorf("unknown format %q, must be one of: %s, %s", format, vexFormatOpenVEX, vexFormatCSAF)
+			}
+			if packageName != "" && image != "" {
+				return errors.New("--package and --image can't both be given")
+			}
+
+			advisoriesRepoDir = resolveAdvisoriesDir(advisoriesRepoDir)
+			if advisoriesRepoDir == "" {
+				if doNotDetectDistro {
+					return fmt.Errorf("no advisories repo dir specified")
+				}
+
+				d, err := distro.Detect()
+				if err != nil {
+					return fmt.Errorf("no advisories repo dir specified, and distro auto-detection failed: %w", err)
+				}
+
+				advisoriesRepoDir = d.AdvisoriesRepoDir
+				_, _ = fmt.Fprint(os.Stderr, renderDetectedDistro(d))
+			}
+
+			index, err := advisoryconfigs.NewIndex(rwos.DirFS(advisoriesRepoDir))
+			if err != nil {
+				return fmt.Errorf("unable to index advisory configs for directory %q: %w", advisoriesRepoDir, err)
+			}
+			selection := index.Select()
+			if packageName != "" {
+				selection = selection.WhereName(packageName)
+				if selection.Len() == 0 {
+					return fmt.Errorf("no advisories found for package %q", packageName)
+				}
+			}
+			docs := selection.Configurations()
+
+			var doc *govex.VEX
+			if image != "" {
+				doc, err = vex.FromImageAdvisories(cmd.Context(), vexCfg, image, docs...)
+			} else {
+				doc, err = vex.FromAdvisories(vexCfg, docs...)
+			}
+			if err != nil {
+				return fmt.Errorf("creating VEX document from advisories: %w", err)
+			}
+
+			if format == vexFormatCSAF {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(vex.ToCSAF(vexCfg, doc)); err != nil {
+					return fmt.Errorf("marshaling CSAF document: %w", err)
+				}
+				return nil
+			}
+
+			if err := doc.ToJSON(os.Stdout); err != nil {
+				return fmt.Errorf("marshaling VEX document: %w", err)
+			}
+
+			return nil
+		},
+	}
+	addCommonVexFlags(cmd)
+	addAdvisoriesDirFlag(&advisoriesRepoDir, cmd)
+	addNoDistroDetectionFlag(&doNotDetectDistro, cmd)
+	addPackageFlag(&packageName, cmd)
+	cmd.Flags().StringVar(&image, "image", "", "SPDX SBOM, or reference of an image with an SBOM attached, to generate the document for the packages of")
+	cmd.Flags().StringVarP(&format, "format", "f", vexFormatOpenVEX, fmt.Sprintf("format of the document, %s or %s", vexFormatOpenVEX, vexFormatCSAF))
+	cmd.Flags().StringVar(&vexCfg.Namespace, "namespace", vexCfg.Namespace, "URL of the publisher of CSAF documents")
+	parent.AddCommand(cmd)
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wolfi-dev/wolfictl/pkg/index"
+	"github.com/wolfi-dev/wolfictl/pkg/withdraw"
+)
+
+func cmdWithdraw() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "withdraw",
+		Short: "Record withdrawn package versions and strip them from indexes",
+		Long: `Record withdrawn package versions and strip them from indexes.
+
+Package versions whose artifacts are broken or vulnerable are withdrawn by
+recording them in withdrawn-packages.txt of the distro repository, one
+name-version per line, with the reason after a #. When the repository is
+published, 'wolfictl withdraw filter' strips them from its APKINDEX, so that
+nothing installs them anymore.`,
+	}
+	cmd.AddCommand(cmdWithdrawAdd(), cmdWithdrawFilter())
+	return cmd
+}
+
+func cmdWithdrawAdd() *cobra.Command {
+	var file, reason string
+	cmd := &cobra.Command{
+		Use:   "add <package-version>...",
+		Short: "Record package versions as withdrawn",
+		Example: `  wolfictl withdraw add curl-8.1.0-r0 libcurl4-8.1.0-r0 --reason "broken TLS"
+  wolfictl withdraw add --file ../os/withdrawn-packages.txt openssl-3.1.0-r0`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var added []withdraw.Withdrawal
+			for _, arg := range args {
+				w, err := withdraw.ParsePackage(arg, reason)
+				if err != nil {
+					return err
+				}
+				added = append(added, w)
+			}
+
+			withdrawals, err := readWithdrawals(file)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			withdrawals = withdraw.Add(withdrawals, added...)
+
+			var buf bytes.Buffer
+			if err := withdraw.Write(&buf, withdrawals); err != nil {
+				return err
+			}
+			if err := os.WriteFile(file, buf.Bytes(), 0o644); err != nil { //nolint:gosec // the list is public
+				return err
+			}
+			log.Printf("recorded %d withdrawn package version(s) in %s", len(added), file)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&file, "file", withdraw.DefaultFileName, "withdrawn packages file to record the package versions in")
+	cmd.Flags().StringVar(&reason, "reason", "", "why the package versions are withdrawn")
+	return cmd
+}
+
+func cmdWithdrawFilter() *cobra.Command {
+	var file, src, output, signingKey string
+	cmd := &cobra.Command{
+		Use:   "filter",
+		Short: "Strip the withdrawn package versions from an APKINDEX",
+		Long: `Strip the withdrawn package versions from an APKINDEX.
+
+The index at --index, a path or URL, is written to --output without the
+package versions of the withdrawn packages file, and signed with
+--signing-key. Before anything is written, the remaining packages are checked
+not to depend on withdrawn ones: every dependency that a withdrawn package
+provided must still be provided by another package.`,
+		Example: `  wolfictl withdraw filter --index ./packages/x86_64/APKINDEX.tar.gz --signing-key wolfi-signing.rsa
+  wolfictl withdraw filter --index https://packages.wolfi.dev/os/x86_64/APKINDEX.tar.gz --output APKINDEX.tar.gz`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output == "" {
+				if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+					return errors.New("the index is remote, --output is required")
+				}
+				output = src
+			}
+			if signingKey == "" {
+				log.Println("no --signing-key provided, not signing index")
+			}
+
+			withdrawals, err := readWithdrawals(file)
+			if err != nil {
+				return err
+			}
+			idx, err := index.Open(cmd.Context(), src)
+			if err != nil {
+				return err
+			}
+
+			filtered, removed := withdraw.Filter(idx, withdrawals)
+			if err := withdraw.Verify(filtered, removed); err != nil {
+				return err
+			}
+			for _, pkg := range removed {
+				log.Printf("withdrawing %s-%s", pkg.Name, pkg.Version)
+			}
+
+			if err := index.Write(cmd.Context(), filtered, output, signingKey); err != nil {
+				return err
+			}
+			log.Printf("wrote %s without %d withdrawn package(s)", output, len(removed))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&file, "file", withdraw.DefaultFileName, "withdrawn packages file")
+	cmd.Flags().StringVar(&src, "index", "", "path or URL of the APKINDEX.tar.gz to filter")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "path to write the filtered index to (default: the --index path)")
+	cmd.Flags().StringVar(&signingKey, "signing-key", "", "if set, key to sign the filtered index with")
+	_ = cmd.MarkFlagRequired("index")
+	return cmd
+}
+
+func readWithdrawals(path string) ([]withdraw.Withdrawal, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	withdrawals, err := withdraw.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", path, err)
+	}
+	return withdrawals, nil
+}
@@ -2,8 +2,10 @@ package build
 
 import (
 	"testing"
+	"time"
 
 	"chainguard.dev/melange/pkg/build"
+	"github.com/openvex/go-vex/pkg/vex"
 	"github.com/stretchr/testify/require"
 	"github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os/tester"
 )
@@ -39,3 +41,36 @@ func TestBuildConfigsIndex(t *testing.T) {
 		t.Errorf("unexpected file modification results (-want, +got):\n%s", diff)
 	}
 }
+
+func TestBuildConfigsIndex_keepsFormatting(t *testing.T) {
+	testfiles := []string{
+		"config-3.yaml",
+	}
+
+	fsys, err := tester.NewFSWithRoot(
+		"testdata/rwfs-index",
+		testfiles...,
+	)
+	require.NoError(t, err)
+
+	index, err := NewIndexFromPaths(fsys, testfiles...)
+	require.NoError(t, err)
+
+	advisoriesSectionUpdater := NewAdvisoriesSectionUpdater(func(cfg build.Configuration) (build.Advisories, error) {
+		advisories := cfg.Advisories
+		advisories["CVE-2023-0001"] = append(advisories["CVE-2023-0001"], build.AdvisoryContent{
+			Timestamp:    time.Date(2023, 6, 2, 12, 0, 0, 0, time.UTC),
+			Status:       vex.StatusFixed,
+			FixedVersion: "1.4-r2",
+		})
+		return advisories, nil
+	})
+
+	s := index.Select().WhereName("gouda")
+	err = s.Update(advisoriesSectionUpdater)
+	require.NoError(t, err)
+
+	if diff := fsys.DiffAll(); diff != "" {
+		t.Errorf("unexpected file modification results (-want, +got):\n%s", diff)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/wolfi-dev/wolfictl/pkg/configs/yamledit"
 )
 
 // An EntryUpdater is a function that takes a configuration Entry and modifies a
@@ -47,10 +49,14 @@ func NewTargetedYAMLASTMutater[K any, T Configuration](
 			return err
 		}
 
-		err = sectionNode.Encode(updatedSectionData)
+		// The updated section is merged into the existing one rather than
+		// replacing it, to keep its comments, key order and quoting.
+		updatedNode := &yaml.Node{}
+		err = updatedNode.Encode(updatedSectionData)
 		if err != nil {
 			return err
 		}
+		yamledit.Merge(sectionNode, updatedNode)
 
 		return nil
 	}
@@ -2,11 +2,13 @@ package configs
 
 import (
 	"fmt"
+	"io/fs"
 
-	"github.com/chainguard-dev/yam/pkg/yam/formatted"
 	"github.com/dprotaso/go-yit"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
+
+	"github.com/wolfi-dev/wolfictl/pkg/configs/yamledit"
 )
 
 // A YAMLASTMutater is a function that mutates a YAML AST. The function is also
@@ -18,18 +20,34 @@ type YAMLASTMutater[T any] func(T, *yaml.Node) error
 // YAMLASTMutater provided to operate on a given Entry.
 func NewYAMLUpdateFunc[T Configuration](yamlASTMutater YAMLASTMutater[T]) EntryUpdater[T] {
 	return func(i *Index[T], e Entry[T]) error {
-		root := e.yamlASTRoot()
-
 		cfg := e.Configuration()
 		if cfg == nil {
 			return errors.New("nil configuration")
 		}
 
-		err := yamlASTMutater(*cfg, root)
+		// The file is edited from its current bytes rather than re-encoded from
+		// the AST, so that the keys, comments and quoting the mutater doesn't
+		// touch stay as they are.
+		src, err := fs.ReadFile(i.fsys, e.getPath())
+		if err != nil {
+			return fmt.Errorf("unable to update %q: %w", e.getPath(), err)
+		}
+
+		doc, err := yamledit.Parse(src)
+		if err != nil {
+			return fmt.Errorf("unable to update %q: %w", e.getPath(), err)
+		}
+
+		err = yamlASTMutater(*cfg, doc.Root)
 		if err != nil {
 			return err
 		}
 
+		updated, err := doc.Bytes()
+		if err != nil {
+			return fmt.Errorf("unable to encode updated YAML: %w", err)
+		}
+
 		file, err := i.fsys.OpenAsWritable(e.getPath())
 		if err != nil {
 			return fmt.Errorf("unable to update %q: %w", e.getPath(), err)
@@ -41,11 +59,9 @@ func NewYAMLUpdateFunc[T Configuration](yamlASTMutater YAMLASTMutater[T]) EntryU
 			return fmt.Errorf("unable to update %q: %w", e.getPath(), err)
 		}
 
-		encoder := formatted.NewEncoder(file).AutomaticConfig()
-
-		err = encoder.Encode(root)
+		_, err = file.Write(updated)
 		if err != nil {
-			return fmt.Errorf("unable to encode updated YAML: %w", err)
+			return fmt.Errorf("unable to update %q: %w", e.getPath(), err)
 		}
 
 		return nil
@@ -0,0 +1,85 @@
+package yamledit
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Merge updates dst to the content of src, typically a node just encoded from updated data, keeping what src doesn't
+// express: the order of the keys both have, the comments of dst, and the style of its scalars. The keys src doesn't
+// have are removed, and the ones dst doesn't have are appended, as are the items past the end of a sequence.
+func Merge(dst, src *yaml.Node) {
+	if dst.Kind != src.Kind || dst.Kind == yaml.AliasNode || !scalarKeys(dst) || !scalarKeys(src) {
+		head, line, foot := dst.HeadComment, dst.LineComment, dst.FootComment
+		*dst = *src
+		dst.HeadComment, dst.LineComment, dst.FootComment = head, line, foot
+		return
+	}
+
+	switch dst.Kind {
+	case yaml.DocumentNode:
+		if len(dst.Content) == 1 && len(src.Content) == 1 {
+			Merge(dst.Content[0], src.Content[0])
+		} else {
+			dst.Content = src.Content
+		}
+
+	case yaml.ScalarNode:
+		if dst.Value == src.Value && dst.ShortTag() == src.ShortTag() {
+			return
+		}
+		// a value that gained lines takes the style it was encoded in, the encoder quotes the others as they need
+		if strings.Contains(src.Value, "\n") && dst.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 ||
+			dst.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 && !strings.Contains(src.Value, "\n") {
+			dst.Style = src.Style
+		}
+		dst.Value, dst.Tag = src.Value, src.Tag
+
+	case yaml.MappingNode:
+		values := make(map[string]*yaml.Node)
+		for i := 0; i < len(src.Content); i += 2 {
+			values[src.Content[i].Value] = src.Content[i+1]
+		}
+		content := make([]*yaml.Node, 0, len(src.Content))
+		kept := make(map[string]bool)
+		for i := 0; i < len(dst.Content); i += 2 {
+			v, ok := values[dst.Content[i].Value]
+			if !ok {
+				continue
+			}
+			Merge(dst.Content[i+1], v)
+			content = append(content, dst.Content[i], dst.Content[i+1])
+			kept[dst.Content[i].Value] = true
+		}
+		for i := 0; i < len(src.Content); i += 2 {
+			if !kept[src.Content[i].Value] {
+				content = append(content, src.Content[i], src.Content[i+1])
+			}
+		}
+		dst.Content = content
+
+	case yaml.SequenceNode:
+		for i := 0; i < len(dst.Content) && i < len(src.Content); i++ {
+			Merge(dst.Content[i], src.Content[i])
+		}
+		if len(src.Content) < len(dst.Content) {
+			dst.Content = dst.Content[:len(src.Content)]
+		} else {
+			dst.Content = append(dst.Content, src.Content[len(dst.Content):]...)
+		}
+	}
+}
+
+// scalarKeys reports whether a node isn't a mapping with keys that aren't scalars
+func scalarKeys(n *yaml.Node) bool {
+	if n.Kind != yaml.MappingNode {
+		return true
+	}
+	for i := 0; i < len(n.Content); i += 2 {
+		if n.Content[i].Kind != yaml.ScalarNode {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,191 @@
+package yamledit
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// foldWidth is the width folded scalars are wrapped at
+const foldWidth = 80
+
+// Render renders the edited document anew, indented by two spaces, with the comments of its nodes and the blank lines
+// that separated the entries of its collections in the source. Unlike Bytes, it normalizes the layout of the whole
+// document: the indentation, the spacing of the comments, and the lines of folded scalars, wrapped at 80 columns.
+func (d *Document) Render() ([]byte, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(d.Root); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	o, err := Parse(fold(b.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	var gaps []int
+	d.gaps(o, o.Root, d.Root, &gaps)
+	sort.Ints(gaps)
+	var out bytes.Buffer
+	at := 0
+	for _, g := range gaps {
+		out.Write(o.src[at:g])
+		out.WriteString("\n")
+		at = g
+	}
+	out.Write(o.src[at:])
+
+	if !d.readsBackAs(out.Bytes()) {
+		return nil, errors.New("the rendered document doesn't keep all its comments")
+	}
+	return out.Bytes(), nil
+}
+
+// SeparateEntries has Render separate the entries of a collection by blank lines.
+func (d *Document) SeparateEntries(n *yaml.Node) {
+	for i, e := range entries(n) {
+		if i > 0 {
+			d.blank[e] = true
+		}
+	}
+}
+
+// gaps adds the offsets of the entries of o, the rendering of the node tree of ed, that have a blank line before them
+// in ed
+func (d *Document) gaps(o *Document, rendered, ed *yaml.Node, offsets *[]int) {
+	if rendered.Kind != ed.Kind || len(rendered.Content) != len(ed.Content) {
+		return
+	}
+	if block(rendered) {
+		renderedEntries, edEntries := entries(rendered), entries(ed)
+		starts := o.starts(renderedEntries, indentOf(rendered))
+		for i := range renderedEntries {
+			if i > 0 && d.blank[edEntries[i]] && strings.TrimSpace(o.line(starts[i]-1)) != "" {
+				*offsets = append(*offsets, o.lines[starts[i]])
+			}
+		}
+	}
+	for i := range rendered.Content {
+		d.gaps(o, rendered.Content[i], ed.Content[i], offsets)
+	}
+}
+
+// markBlankLines records the entries of the block collections of n that have a blank line before them
+func (d *Document) markBlankLines(n *yaml.Node) {
+	if block(n) {
+		es := entries(n)
+		starts := d.starts(es, indentOf(n))
+		for i, e := range es {
+			if i > 0 && strings.TrimSpace(d.line(starts[i]-1)) == "" {
+				d.blank[e] = true
+			}
+		}
+	}
+	for _, c := range n.Content {
+		d.markBlankLines(c)
+	}
+}
+
+// block reports whether n is a block collection with entries
+func block(n *yaml.Node) bool {
+	return (n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode) && n.Style&yaml.FlowStyle == 0 && len(n.Content) > 0
+}
+
+// entries returns the keys of a mapping, or the items of a sequence
+func entries(n *yaml.Node) []*yaml.Node {
+	switch n.Kind {
+	case yaml.MappingNode:
+		var keys []*yaml.Node
+		for i := 0; i < len(n.Content); i += 2 {
+			keys = append(keys, n.Content[i])
+		}
+		return keys
+	case yaml.SequenceNode:
+		return n.Content
+	}
+	return nil
+}
+
+// indentOf returns the indentation of the entries of a parsed block collection
+func indentOf(n *yaml.Node) int {
+	if n.Kind == yaml.MappingNode {
+		return n.Content[0].Column - 1
+	}
+	return n.Column - 1
+}
+
+// fold wraps the lines of the folded scalars of a rendered document that are longer than foldWidth, at single spaces,
+// which folding reads back as they were
+func fold(src []byte) []byte {
+	root := &yaml.Node{}
+	if yaml.Unmarshal(src, root) != nil {
+		return src
+	}
+	var folded []*yaml.Node
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Kind == yaml.ScalarNode && n.Style&yaml.FoldedStyle != 0 {
+			folded = append(folded, n)
+		}
+		for _, c := range n.Content {
+			walk(c)
+		}
+	}
+	walk(root)
+	if len(folded) == 0 {
+		return src
+	}
+
+	lines := strings.SplitAfter(string(src), "\n")
+	for _, n := range folded {
+		indent := -1
+		for l := n.Line; l < len(lines); l++ {
+			text := strings.TrimSuffix(lines[l], "\n")
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			i := indentation(text)
+			if indent < 0 {
+				indent = i
+			}
+			if i < indent {
+				break
+			}
+			if i == indent && len(text) > foldWidth {
+				lines[l] = wrap(text[i:], indent) + strings.TrimPrefix(lines[l], text)
+			}
+		}
+	}
+	return []byte(strings.Join(lines, ""))
+}
+
+// wrap breaks a line of a folded scalar into lines of at most foldWidth columns where it can
+func wrap(text string, indent int) string {
+	prefix := strings.Repeat(" ", indent)
+	var b strings.Builder
+	for {
+		cut := -1
+		for i := 1; i < len(text)-1; i++ {
+			if text[i] != ' ' || text[i-1] == ' ' || text[i+1] == ' ' {
+				continue
+			}
+			if cut >= 0 && indent+i > foldWidth {
+				break
+			}
+			cut = i
+		}
+		if cut < 0 || indent+len(text) <= foldWidth {
+			b.WriteString(prefix + text)
+			return b.String()
+		}
+		b.WriteString(prefix + text[:cut] + "\n")
+		text = text[cut+1:]
+	}
+}
@@ -0,0 +1,700 @@
+// Package yamledit edits YAML files through their yaml.v3 node tree, and writes them back changed only where the nodes
+// were edited: the order of the keys, the comments, the blank lines and the quoting of everything else stay as they
+// are, so automated edits of configs make the diffs reviewers expect.
+package yamledit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chainguard-dev/yam/pkg/yam/formatted"
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a YAML document being edited. Edits change the nodes of Root, its document node, in place, or replace
+// them.
+type Document struct {
+	Root *yaml.Node
+
+	src []byte
+	// orig is the node tree of src, which Root is compared to
+	orig *yaml.Node
+	// lines are the offsets of the lines of src, from 1: lines[1] is 0, and the last one is len(src)+1
+	lines []int
+	// blank are the entries of the collections of Root that have a blank line before them, which Render keeps
+	blank map[*yaml.Node]bool
+}
+
+// Parse parses a YAML document for editing.
+func Parse(src []byte) (*Document, error) {
+	d := &Document{Root: &yaml.Node{}, src: src, orig: &yaml.Node{}}
+	if err := yaml.Unmarshal(src, d.Root); err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(src, d.orig); err != nil {
+		return nil, err
+	}
+	d.lines = []int{0, 0}
+	for i, c := range src {
+		if c == '\n' {
+			d.lines = append(d.lines, i+1)
+		}
+	}
+	d.lines = append(d.lines, len(src)+1)
+	d.blank = make(map[*yaml.Node]bool)
+	d.markBlankLines(d.Root)
+	return d, nil
+}
+
+// Bytes returns the edited document. Edited scalars are replaced where they are, in the style they had, entries
+// added to mappings and sequences are inserted after the entries they follow, and removed ones are deleted along with
+// their comments. The entries whose structure changed otherwise are rendered again, and if the result somehow doesn't
+// read back as the edited document, the whole document is.
+func (d *Document) Bytes() ([]byte, error) {
+	if d.orig.Kind == yaml.DocumentNode && d.Root.Kind == yaml.DocumentNode && len(d.orig.Content) == 1 && len(d.Root.Content) == 1 &&
+		d.orig.HeadComment == d.Root.HeadComment && d.orig.FootComment == d.Root.FootComment {
+		p := &patcher{d: d}
+		if err := p.node(d.orig.Content[0], d.Root.Content[0], d.lastLine()); err == nil {
+			if out, ok := p.apply(); ok && d.readsBackAs(out) {
+				return out, nil
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	if err := formatted.NewEncoder(&b).AutomaticConfig().Encode(d.Root); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// readsBackAs reports whether out parses to the edited document, with the same comments
+func (d *Document) readsBackAs(out []byte) bool {
+	n := &yaml.Node{}
+	if err := yaml.Unmarshal(out, n); err != nil {
+		return false
+	}
+	return sameValue(n, d.Root) && equalStrings(comments(n), comments(d.Root))
+}
+
+// EditFile applies edit to the YAML file at path, and writes it back if it changed.
+func EditFile(path string, edit func(*Document) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	d, err := Parse(src)
+	if err != nil {
+		return fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+	if err := edit(d); err != nil {
+		return err
+	}
+	out, err := d.Bytes()
+	if err != nil {
+		return fmt.Errorf("unable to encode %s: %w", path, err)
+	}
+	if bytes.Equal(out, src) {
+		return nil
+	}
+	return os.WriteFile(path, out, info.Mode())
+}
+
+// errFallback means a node can't be edited where it is, and needs to be rendered again with its key or dash
+var errFallback = errors.New("can't be edited in place")
+
+type edit struct {
+	start, end int
+	text       string
+}
+
+type patcher struct {
+	d     *Document
+	edits []edit
+}
+
+// node edits the text of orig, whose lines end at end, into ed
+func (p *patcher) node(orig, ed *yaml.Node, end int) error {
+	if orig.Kind != ed.Kind || orig.Anchor != ed.Anchor || orig.Kind == yaml.AliasNode ||
+		orig.HeadComment != ed.HeadComment || orig.LineComment != ed.LineComment || orig.FootComment != ed.FootComment {
+		if same(orig, ed) {
+			return nil
+		}
+		return errFallback
+	}
+	if orig.Kind != yaml.ScalarNode && (orig.Style&yaml.FlowStyle != 0 || len(orig.Content) == 0) {
+		// flow and empty collections are edited as a whole
+		if same(orig, ed) {
+			return nil
+		}
+		return p.inline(orig, ed)
+	}
+	switch orig.Kind {
+	case yaml.ScalarNode:
+		return p.scalar(orig, ed)
+	case yaml.MappingNode:
+		return p.mapping(orig, ed, end)
+	case yaml.SequenceNode:
+		return p.sequence(orig, ed, end)
+	}
+	return errFallback
+}
+
+// scalar replaces the text of a single line scalar by its new value, in the same style
+func (p *patcher) scalar(orig, ed *yaml.Node) error {
+	if orig.Value == ed.Value && orig.ShortTag() == ed.ShortTag() && orig.Style == ed.Style {
+		return nil
+	}
+	if orig.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 || ed.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return errFallback
+	}
+	start := p.d.offset(orig.Line, orig.Column)
+	end := p.d.scalarEnd(start, orig.Style)
+	// the text must be what the scalar renders to, which rules out multi-line scalars, tags and anchors
+	if was, err := renderScalar(orig); err != nil || string(p.d.src[start:end]) != was {
+		return errFallback
+	}
+	text, err := renderScalar(ed)
+	if err != nil || strings.Contains(text, "\n") {
+		return errFallback
+	}
+	p.edits = append(p.edits, edit{start, end, text})
+	return nil
+}
+
+// inline replaces a flow or empty collection that's on a single line by its new value in flow style
+func (p *patcher) inline(orig, ed *yaml.Node) error {
+	start := p.d.offset(orig.Line, orig.Column)
+	end, ok := p.d.flowEnd(start)
+	if !ok {
+		return errFallback
+	}
+	n := *ed
+	n.Style |= yaml.FlowStyle
+	out, err := yaml.Marshal(&n)
+	if err != nil {
+		return errFallback
+	}
+	text := strings.TrimSuffix(string(out), "\n")
+	if strings.Contains(text, "\n") {
+		return errFallback
+	}
+	p.edits = append(p.edits, edit{start, end, text})
+	return nil
+}
+
+// mapping edits the pairs of a block mapping whose keys are in the same order, and inserts the new ones after the
+// keys they follow
+func (p *patcher) mapping(orig, ed *yaml.Node, end int) error {
+	edIndex := make(map[string]int)
+	for k := 0; k < len(ed.Content); k += 2 {
+		if ed.Content[k].Kind != yaml.ScalarNode {
+			return errFallback
+		}
+		edIndex[ed.Content[k].Value] = k
+	}
+	origIndex := make(map[string]int)
+	last := -1
+	for i := 0; i < len(orig.Content); i += 2 {
+		key := orig.Content[i]
+		if key.Kind != yaml.ScalarNode {
+			return errFallback
+		}
+		origIndex[key.Value] = i
+		if k, ok := edIndex[key.Value]; ok {
+			if k < last {
+				return errFallback
+			}
+			last = k
+		}
+	}
+	// the pairs added after each entry of orig, and before the first one at -1
+	added := make(map[int][]*yaml.Node)
+	prev := -1
+	for k := 0; k < len(ed.Content); k += 2 {
+		if i, ok := origIndex[ed.Content[k].Value]; ok {
+			prev = i / 2
+			continue
+		}
+		added[prev] = append(added[prev], &yaml.Node{Kind: yaml.MappingNode, Content: ed.Content[k : k+2]})
+	}
+
+	indent := orig.Content[0].Column - 1
+	entries := make([]*yaml.Node, 0, len(orig.Content)/2)
+	for i := 0; i < len(orig.Content); i += 2 {
+		entries = append(entries, orig.Content[i])
+	}
+	starts, ends := p.d.regions(entries, indent, end)
+	var removed []int
+	for i := 0; i < len(orig.Content); i += 2 {
+		n := len(p.edits)
+		r := i / 2
+		k, ok := edIndex[orig.Content[i].Value]
+		if !ok {
+			if p.d.afterDash(starts[r], indent) {
+				return errFallback
+			}
+			removed = append(removed, r)
+			continue
+		}
+		err := p.node(orig.Content[i+1], ed.Content[k+1], ends[r])
+		if err == nil && !same(orig.Content[i], ed.Content[k]) {
+			err = errFallback
+		}
+		if errors.Is(err, errFallback) {
+			p.edits = p.edits[:n]
+			if p.d.afterDash(starts[r], indent) {
+				return errFallback
+			}
+			err = p.replace(starts[r], ends[r], orig.Content[i], &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{ed.Content[k], ed.Content[k+1]}}, indent)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	p.remove(starts, ends, removed)
+	gap := p.d.gap(starts)
+	for r := -1; r < len(entries); r++ {
+		pairs := added[r]
+		if len(pairs) == 0 {
+			continue
+		}
+		if r < 0 {
+			if p.d.afterDash(starts[0], indent) {
+				return errFallback
+			}
+			if err := p.insert(p.d.lines[starts[0]], false, gap, pairs, indent); err != nil {
+				return err
+			}
+		} else if err := p.insert(p.d.lineEnd(ends[r]), true, gap, pairs, indent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sequence edits the items of a block sequence: the items that stay as they are are kept, the others in between
+// them are edited in order, and the rest deleted or inserted
+func (p *patcher) sequence(orig, ed *yaml.Node, end int) error {
+	indent := orig.Column - 1
+	starts, ends := p.d.regions(orig.Content, indent, end)
+	gap := p.d.gap(starts)
+	kept := append(unchanged(orig.Content, ed.Content), [2]int{len(orig.Content), len(ed.Content)})
+	var removed []int
+	i, j := 0, 0
+	for _, k := range kept {
+		for ; i < k[0] && j < k[1]; i, j = i+1, j+1 {
+			n := len(p.edits)
+			err := p.node(orig.Content[i], ed.Content[j], ends[i])
+			if errors.Is(err, errFallback) {
+				p.edits = p.edits[:n]
+				if p.d.afterDash(starts[i], indent) {
+					return errFallback
+				}
+				err = p.replace(starts[i], ends[i], orig.Content[i], &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{ed.Content[j]}}, indent)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		for ; i < k[0]; i++ {
+			if p.d.afterDash(starts[i], indent) {
+				return errFallback
+			}
+			removed = append(removed, i)
+		}
+		if j < k[1] {
+			var items []*yaml.Node
+			for _, item := range ed.Content[j:k[1]] {
+				items = append(items, &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{item}})
+			}
+			if i == 0 {
+				if p.d.afterDash(starts[0], indent) {
+					return errFallback
+				}
+				if err := p.insert(p.d.lines[starts[0]], false, gap, items, indent); err != nil {
+					return err
+				}
+			} else if err := p.insert(p.d.lineEnd(ends[i-1]), true, gap, items, indent); err != nil {
+				return err
+			}
+			j = k[1]
+		}
+		i, j = i+1, j+1
+	}
+	p.remove(starts, ends, removed)
+	return nil
+}
+
+// unchanged returns the indexes of the longest run of items that are the same in orig and ed, in order
+func unchanged(orig, ed []*yaml.Node) [][2]int {
+	lengths := make([][]int, len(orig)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(ed)+1)
+	}
+	for i := len(orig) - 1; i >= 0; i-- {
+		for j := len(ed) - 1; j >= 0; j-- {
+			switch {
+			case same(orig[i], ed[j]):
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			case lengths[i+1][j] >= lengths[i][j+1]:
+				lengths[i][j] = lengths[i+1][j]
+			default:
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+	var kept [][2]int
+	for i, j := 0, 0; i < len(orig) && j < len(ed); {
+		switch {
+		case same(orig[i], ed[j]):
+			kept = append(kept, [2]int{i, j})
+			i, j = i+1, j+1
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return kept
+}
+
+// remove deletes entries of a collection, given in order, with the blank lines that separate them from the next
+// ones, or from the previous ones for the entries at the end
+func (p *patcher) remove(starts, ends, removed []int) {
+	for n, i := range removed {
+		start, end := starts[i], ends[i]
+		if len(removed)-n < len(starts)-i {
+			for end+1 < starts[i+1] && strings.TrimSpace(p.d.line(end+1)) == "" {
+				end++
+			}
+		} else if i > 0 {
+			for start-1 > ends[i-1] && strings.TrimSpace(p.d.line(start-1)) == "" {
+				start--
+			}
+		}
+		p.edits = append(p.edits, edit{p.d.lines[start], p.d.lineEnd(end), ""})
+	}
+}
+
+// replace renders the entry whose lines are start to end again, with the head comment of its first node only if the
+// comment is in these lines
+func (p *patcher) replace(start, end int, first, n *yaml.Node, indent int) error {
+	head := n.Content[0].HeadComment
+	if head != "" && start+strings.Count(head, "\n")+1 > first.Line {
+		n.Content[0].HeadComment = ""
+		defer func() { n.Content[0].HeadComment = head }()
+	}
+	text, err := render(n, indent)
+	if err != nil {
+		return err
+	}
+	p.edits = append(p.edits, edit{p.d.lines[start], p.d.lineEnd(end), text})
+	return nil
+}
+
+// insert renders new entries of a collection at an offset, after the entry that ends there or before the one that
+// starts there, separated from it by a blank line with gap
+func (p *patcher) insert(at int, after, gap bool, entries []*yaml.Node, indent int) error {
+	var b strings.Builder
+	if at > len(p.d.src) {
+		at = len(p.d.src)
+		b.WriteString("\n")
+	}
+	for _, e := range entries {
+		text, err := render(e, indent)
+		if err != nil {
+			return err
+		}
+		if gap && after {
+			b.WriteString("\n")
+		}
+		b.WriteString(text)
+		if gap && !after {
+			b.WriteString("\n")
+		}
+	}
+	p.edits = append(p.edits, edit{at, at, b.String()})
+	return nil
+}
+
+// gap reports whether the entries of a collection are separated by blank lines
+func (d *Document) gap(starts []int) bool {
+	return len(starts) > 1 && strings.TrimSpace(d.line(starts[1]-1)) == ""
+}
+
+// afterDash reports whether the entry of a collection whose lines start at start is on the line of the dash of the
+// sequence item the collection is, which is then edited as a whole
+func (d *Document) afterDash(start, indent int) bool {
+	return indentation(d.line(start)) != indent
+}
+
+// regions returns the lines of the entries of a block collection: from the comment right above each one, to the
+// line before the next one, or to end, without the blank lines and the comments of the outer collections at the end
+func (d *Document) regions(entries []*yaml.Node, indent, end int) (starts, ends []int) {
+	starts = d.starts(entries, indent)
+	for i := range entries {
+		last := end
+		if i+1 < len(entries) {
+			last = starts[i+1] - 1
+		}
+		for last > starts[i] {
+			l := d.line(last)
+			if strings.TrimSpace(l) != "" && (!strings.HasPrefix(strings.TrimSpace(l), "#") || indentation(l) >= indent) {
+				break
+			}
+			last--
+		}
+		ends = append(ends, last)
+	}
+	return starts, ends
+}
+
+// starts returns the first lines of the entries of a block collection, which are those of the comments right above
+// them
+func (d *Document) starts(entries []*yaml.Node, indent int) []int {
+	var starts []int
+	for _, e := range entries {
+		start := e.Line
+		for start > 1 && !d.afterDash(start, indent) {
+			l := d.line(start - 1)
+			if !strings.HasPrefix(strings.TrimSpace(l), "#") || indentation(l) != indent {
+				break
+			}
+			start--
+		}
+		starts = append(starts, start)
+	}
+	return starts
+}
+
+// apply returns the document with the edits, false if any overlap
+func (p *patcher) apply() ([]byte, bool) {
+	sort.SliceStable(p.edits, func(i, j int) bool { return p.edits[i].start < p.edits[j].start })
+	var b bytes.Buffer
+	at := 0
+	for _, e := range p.edits {
+		if e.start < at && e.text == "" {
+			// removals of consecutive entries can share the blank lines between them
+			e.start = at
+			if e.end < at {
+				e.end = at
+			}
+		}
+		if e.start < at {
+			return nil, false
+		}
+		b.Write(p.d.src[at:e.start])
+		b.WriteString(e.text)
+		at = e.end
+		if at > len(p.d.src) {
+			at = len(p.d.src)
+		}
+	}
+	b.Write(p.d.src[at:])
+	return b.Bytes(), true
+}
+
+// render renders a collection of new entries in block style, indented by indent spaces
+func render(n *yaml.Node, indent int) (string, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	lines := strings.SplitAfter(string(fold(b.Bytes())), "\n")
+	prefix := strings.Repeat(" ", indent)
+	for i, l := range lines {
+		if strings.TrimSpace(l) != "" {
+			lines[i] = prefix + l
+		}
+	}
+	return strings.Join(lines, ""), nil
+}
+
+func renderScalar(n *yaml.Node) (string, error) {
+	out, err := yaml.Marshal(&yaml.Node{Kind: yaml.ScalarNode, Tag: n.Tag, Value: n.Value, Style: n.Style})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// line returns the text of a line, without its newline
+func (d *Document) line(n int) string {
+	if n < 1 || n >= len(d.lines)-1 {
+		return ""
+	}
+	return string(d.src[d.lines[n]:d.lineEnd(n)])
+}
+
+// lineEnd returns the offset after the newline of a line, or len(src)+1 for a last line without one
+func (d *Document) lineEnd(n int) int {
+	if n+1 >= len(d.lines) {
+		return len(d.src)
+	}
+	return d.lines[n+1]
+}
+
+// lastLine returns the number of the last line that isn't empty
+func (d *Document) lastLine() int {
+	n := len(d.lines) - 2
+	for n > 1 && d.lines[n] >= len(d.src) {
+		n--
+	}
+	return n
+}
+
+// offset returns the offset of a position of the parser, whose columns count runes from 1
+func (d *Document) offset(line, column int) int {
+	o := d.lines[line]
+	for i := 1; i < column && o < len(d.src); i++ {
+		_, size := utf8.DecodeRune(d.src[o:])
+		o += size
+	}
+	return o
+}
+
+// scalarEnd returns the offset after the scalar of a block at start
+func (d *Document) scalarEnd(start int, style yaml.Style) int {
+	src := d.src
+	switch {
+	case style&yaml.DoubleQuotedStyle != 0:
+		for i := start + 1; i < len(src); i++ {
+			switch src[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1
+			}
+		}
+	case style&yaml.SingleQuotedStyle != 0:
+		for i := start + 1; i < len(src); i++ {
+			if src[i] == '\'' {
+				if i+1 < len(src) && src[i+1] == '\'' {
+					i++
+					continue
+				}
+				return i + 1
+			}
+		}
+	default:
+		end := bytes.IndexByte(src[start:], '\n')
+		if end < 0 {
+			end = len(src) - start
+		}
+		text := src[start : start+end]
+		if i := bytes.Index(text, []byte(" #")); i >= 0 {
+			text = text[:i]
+		}
+		return start + len(bytes.TrimRight(text, " \t\r"))
+	}
+	return len(src)
+}
+
+// flowEnd returns the offset after the flow collection at start, false if it isn't on a single line
+func (d *Document) flowEnd(start int) (int, bool) {
+	depth := 0
+	var quote byte
+	for i := start; i < len(d.src); i++ {
+		c := d.src[i]
+		switch {
+		case c == '\n':
+			return 0, false
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+			if depth == 0 {
+				return i + 1, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func indentation(l string) int {
+	return len(l) - len(strings.TrimLeft(l, " "))
+}
+
+// same reports whether two nodes have the same content, comments and style, wherever they are
+func same(a, b *yaml.Node) bool {
+	if a.Kind != b.Kind || a.Value != b.Value || a.ShortTag() != b.ShortTag() || a.Style != b.Style || a.Anchor != b.Anchor ||
+		a.HeadComment != b.HeadComment || a.LineComment != b.LineComment || a.FootComment != b.FootComment ||
+		len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if !same(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameValue reports whether two nodes have the same content, whatever their style and comments
+func sameValue(a, b *yaml.Node) bool {
+	if a.Kind == yaml.DocumentNode && b.Kind == yaml.DocumentNode {
+		return len(a.Content) == len(b.Content) && (len(a.Content) == 0 || sameValue(a.Content[0], b.Content[0]))
+	}
+	if a.Kind != b.Kind || len(a.Content) != len(b.Content) {
+		return false
+	}
+	if a.Kind == yaml.ScalarNode && (a.Value != b.Value || a.ShortTag() != b.ShortTag()) {
+		return false
+	}
+	for i := range a.Content {
+		if !sameValue(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// comments returns the lines of the comments of a node tree, sorted, wherever the parser attached them
+func comments(n *yaml.Node) []string {
+	var lines []string
+	for _, c := range []string{n.HeadComment, n.LineComment, n.FootComment} {
+		for _, l := range strings.Split(c, "\n") {
+			if l = strings.TrimSpace(l); l != "" {
+				lines = append(lines, l)
+			}
+		}
+	}
+	for _, c := range n.Content {
+		lines = append(lines, comments(c)...)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,438 @@
+package yamledit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const config = `# Copyright 2023 Example
+# header comment
+
+package:
+  name: foo   # the name
+  version: 1.2.3
+  epoch: 4
+  description: 'single quoted'
+  dependencies:
+    runtime: [bar, baz]
+
+# build it
+pipeline:
+  - uses: git-checkout
+    with:
+      repository: https://github.com/example/foo
+      tag: v${{package.version}}
+      expected-commit: "0123abcd"
+
+  - runs: |
+      make
+      make install
+
+  # strip last
+  - uses: strip
+
+update:
+  enabled: true
+`
+
+func value(t *testing.T, n *yaml.Node, path ...string) *yaml.Node {
+	t.Helper()
+	if n.Kind == yaml.DocumentNode {
+		n = n.Content[0]
+	}
+	for _, key := range path {
+		found := false
+		for i := 0; i < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				n, found = n.Content[i+1], true
+				break
+			}
+		}
+		require.True(t, found, key)
+	}
+	return n
+}
+
+func TestDocument_Bytes(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(t *testing.T, root *yaml.Node)
+		want string
+	}{
+		{
+			name: "unchanged",
+			edit: func(t *testing.T, root *yaml.Node) {},
+			want: config,
+		},
+		{
+			name: "scalars",
+			edit: func(t *testing.T, root *yaml.Node) {
+				value(t, root, "package", "version").Value = "1.10"
+				value(t, root, "package", "version").Tag = "!!str"
+				value(t, root, "package", "epoch").Value = "0"
+				value(t, root, "package", "description").Value = "it's quoted"
+				value(t, root, "package", "name").Value = "foo-bar"
+				value(t, root, "pipeline").Content[0].Content[3].Content[5].Value = "4567ef"
+			},
+			want: `# Copyright 2023 Example
+# header comment
+
+package:
+  name: foo-bar   # the name
+  version: "1.10"
+  epoch: 0
+  description: 'it''s quoted'
+  dependencies:
+    runtime: [bar, baz]
+
+# build it
+pipeline:
+  - uses: git-checkout
+    with:
+      repository: https://github.com/example/foo
+      tag: v${{package.version}}
+      expected-commit: "4567ef"
+
+  - runs: |
+      make
+      make install
+
+  # strip last
+  - uses: strip
+
+update:
+  enabled: true
+`,
+		},
+		{
+			name: "added and removed keys",
+			edit: func(t *testing.T, root *yaml.Node) {
+				pkg := value(t, root, "package")
+				pkg.Content = append(pkg.Content[:4], pkg.Content[6:]...)
+				pkg.Content = append(pkg.Content,
+					&yaml.Node{Kind: yaml.ScalarNode, Value: "url"},
+					&yaml.Node{Kind: yaml.ScalarNode, Value: "https://example.com"})
+				with := value(t, root, "pipeline").Content[0].Content[3]
+				with.Content = with.Content[:4]
+				update := value(t, root, "update")
+				update.Content = append(update.Content,
+					&yaml.Node{Kind: yaml.ScalarNode, Value: "github"},
+					&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+						{Kind: yaml.ScalarNode, Value: "identifier"},
+						{Kind: yaml.ScalarNode, Value: "example/foo"},
+					}})
+			},
+			want: `# Copyright 2023 Example
+# header comment
+
+package:
+  name: foo   # the name
+  version: 1.2.3
+  description: 'single quoted'
+  dependencies:
+    runtime: [bar, baz]
+  url: https://example.com
+
+# build it
+pipeline:
+  - uses: git-checkout
+    with:
+      repository: https://github.com/example/foo
+      tag: v${{package.version}}
+
+  - runs: |
+      make
+      make install
+
+  # strip last
+  - uses: strip
+
+update:
+  enabled: true
+  github:
+    identifier: example/foo
+`,
+		},
+		{
+			name: "inserted keys",
+			edit: func(t *testing.T, root *yaml.Node) {
+				m := root.Content[0]
+				m.Content = append(m.Content[:4:4], append([]*yaml.Node{
+					{Kind: yaml.ScalarNode, Value: "subpackages"},
+					{Kind: yaml.SequenceNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Content: []*yaml.Node{
+						{Kind: yaml.ScalarNode, Value: "name"},
+						{Kind: yaml.ScalarNode, Value: "foo-doc"},
+					}}}},
+				}, m.Content[4:]...)...)
+				update := value(t, root, "update")
+				update.Content = append([]*yaml.Node{
+					{Kind: yaml.ScalarNode, Value: "manual"},
+					{Kind: yaml.ScalarNode, Value: "false"},
+				}, update.Content...)
+			},
+			want: `# Copyright 2023 Example
+# header comment
+
+package:
+  name: foo   # the name
+  version: 1.2.3
+  epoch: 4
+  description: 'single quoted'
+  dependencies:
+    runtime: [bar, baz]
+
+# build it
+pipeline:
+  - uses: git-checkout
+    with:
+      repository: https://github.com/example/foo
+      tag: v${{package.version}}
+      expected-commit: "0123abcd"
+
+  - runs: |
+      make
+      make install
+
+  # strip last
+  - uses: strip
+
+subpackages:
+  - name: foo-doc
+
+update:
+  manual: false
+  enabled: true
+`,
+		},
+		{
+			name: "sequences",
+			edit: func(t *testing.T, root *yaml.Node) {
+				pipeline := value(t, root, "pipeline")
+				pipeline.Content = append(pipeline.Content[:1], pipeline.Content[2:]...)
+				pipeline.Content = append(pipeline.Content, &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+					{Kind: yaml.ScalarNode, Value: "runs"},
+					{Kind: yaml.ScalarNode, Value: "echo done\n", Style: yaml.LiteralStyle},
+				}})
+				runtime := value(t, root, "package", "dependencies", "runtime")
+				runtime.Content = append(runtime.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "qux"})
+			},
+			want: `# Copyright 2023 Example
+# header comment
+
+package:
+  name: foo   # the name
+  version: 1.2.3
+  epoch: 4
+  description: 'single quoted'
+  dependencies:
+    runtime: [bar, baz, qux]
+
+# build it
+pipeline:
+  - uses: git-checkout
+    with:
+      repository: https://github.com/example/foo
+      tag: v${{package.version}}
+      expected-commit: "0123abcd"
+
+  # strip last
+  - uses: strip
+
+  - runs: |
+      echo done
+
+update:
+  enabled: true
+`,
+		},
+		{
+			name: "changed structure",
+			edit: func(t *testing.T, root *yaml.Node) {
+				pipeline := value(t, root, "pipeline")
+				pipeline.Content[1] = &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+					{Kind: yaml.ScalarNode, Value: "uses"},
+					{Kind: yaml.ScalarNode, Value: "autoconf/make"},
+				}}
+				// keys that swap places are rendered again with their parent
+				update := value(t, root, "update")
+				update.Content = append(update.Content,
+					&yaml.Node{Kind: yaml.ScalarNode, Value: "manual"},
+					&yaml.Node{Kind: yaml.ScalarNode, Value: "false"})
+				update.Content[0], update.Content[2] = update.Content[2], update.Content[0]
+				update.Content[1], update.Content[3] = update.Content[3], update.Content[1]
+			},
+			want: `# Copyright 2023 Example
+# header comment
+
+package:
+  name: foo   # the name
+  version: 1.2.3
+  epoch: 4
+  description: 'single quoted'
+  dependencies:
+    runtime: [bar, baz]
+
+# build it
+pipeline:
+  - uses: git-checkout
+    with:
+      repository: https://github.com/example/foo
+      tag: v${{package.version}}
+      expected-commit: "0123abcd"
+
+  - uses: autoconf/make
+
+  # strip last
+  - uses: strip
+
+update:
+  manual: false
+  enabled: true
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Parse([]byte(config))
+			require.NoError(t, err)
+			tt.edit(t, d.Root)
+			got, err := d.Bytes()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	type event struct {
+		Timestamp time.Time `yaml:"timestamp"`
+		Type      string    `yaml:"type"`
+		Note      string    `yaml:"note,omitempty"`
+	}
+	type advisory struct {
+		ID      string   `yaml:"id"`
+		Aliases []string `yaml:"aliases,omitempty"`
+		Events  []event  `yaml:"events"`
+	}
+	src := `schema-version: 2.0.1
+
+package:
+  name: foo
+
+advisories:
+  # the first one
+  - id: CVE-2023-0001
+    events:
+      - timestamp: 2023-05-01T10:00:00Z
+        type: detection
+        note: 'found by a scanner'  # really
+`
+	d, err := Parse([]byte(src))
+	require.NoError(t, err)
+	section := value(t, d.Root, "advisories")
+	var advisories []advisory
+	require.NoError(t, section.Decode(&advisories))
+
+	advisories[0].Aliases = []string{"GHSA-xxxx-xxxx-xxxx"}
+	advisories[0].Events = append(advisories[0].Events, event{
+		Timestamp: time.Date(2023, 6, 2, 12, 0, 0, 0, time.UTC),
+		Type:      "fixed",
+	})
+	advisories = append(advisories, advisory{ID: "CVE-2023-0002", Events: []event{{
+		Timestamp: time.Date(2023, 6, 3, 12, 0, 0, 0, time.UTC),
+		Type:      "false-positive-determination",
+		Note:      "not used",
+	}}})
+	updated := &yaml.Node{}
+	require.NoError(t, updated.Encode(advisories))
+	Merge(section, updated)
+
+	got, err := d.Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, `schema-version: 2.0.1
+
+package:
+  name: foo
+
+advisories:
+  # the first one
+  - id: CVE-2023-0001
+    events:
+      - timestamp: 2023-05-01T10:00:00Z
+        type: detection
+        note: 'found by a scanner'  # really
+      - timestamp: 2023-06-02T12:00:00Z
+        type: fixed
+    aliases:
+      - GHSA-xxxx-xxxx-xxxx
+  - id: CVE-2023-0002
+    events:
+      - timestamp: 2023-06-03T12:00:00Z
+        type: false-positive-determination
+        note: not used
+`, string(got))
+}
+
+func TestEditFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(config), 0o600))
+
+	require.NoError(t, EditFile(path, func(d *Document) error {
+		value(t, d.Root, "package", "epoch").Value = "5"
+		return nil
+	}))
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(got), "# header comment\n\npackage:\n  name: foo   # the name\n  version: 1.2.3\n  epoch: 5\n")
+}
+
+func TestDocument_Bytes_removeLastEntries(t *testing.T) {
+	d, err := Parse([]byte(config))
+	require.NoError(t, err)
+	pipeline := value(t, d.Root, "pipeline")
+	pipeline.Content = pipeline.Content[:1]
+	got, err := d.Bytes()
+	require.NoError(t, err)
+	assert.Contains(t, string(got), "      expected-commit: \"0123abcd\"\n\nupdate:\n")
+}
+
+func TestDocument_Render(t *testing.T) {
+	d, err := Parse([]byte(`# header
+
+package:
+    name: foo   # the name
+    version: 1.2.3
+pipeline:
+- uses: fetch
+
+# make it
+- runs: make
+`))
+	require.NoError(t, err)
+	value(t, d.Root, "pipeline").Content[1].Content[1].Value = "./configure --prefix=/usr --sysconfdir=/etc --mandir=/usr/share/man --localstatedir=/var && make"
+	value(t, d.Root, "pipeline").Content[1].Content[1].Style = yaml.FoldedStyle
+	d.SeparateEntries(d.Root.Content[0])
+
+	got, err := d.Render()
+	require.NoError(t, err)
+	assert.Equal(t, `# header
+
+package:
+  name: foo # the name
+  version: 1.2.3
+
+pipeline:
+  - uses: fetch
+
+  # make it
+  - runs: >-
+      ./configure --prefix=/usr --sysconfdir=/etc --mandir=/usr/share/man
+      --localstatedir=/var && make
+`, string(got))
+}
@@ -0,0 +1,96 @@
+package dag
+
+import (
+	"io"
+	"log"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// These tests are meant to be run with -race, which make test does.
+
+func TestNewGraph_Concurrent(t *testing.T) {
+	testDir := "testdata/basic"
+	pkgs, err := NewPackages(os.DirFS(testDir), testDir)
+	require.NoError(t, err)
+
+	cache := NewIndexCache()
+	logger := log.New(io.Discard, "", 0)
+
+	const n = 8
+	graphs := make([]*Graph, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			graphs[i], errs[i] = NewGraph(pkgs, WithRepos(packageRepo), WithKeys(key), WithIndexCache(cache), WithLogger(logger))
+		}(i)
+	}
+	wg.Wait()
+
+	want, err := NewGraph(pkgs, WithRepos(packageRepo), WithKeys(key))
+	require.NoError(t, err)
+	wantSorted, err := want.Sorted()
+	require.NoError(t, err)
+	for i := 0; i < n; i++ {
+		require.NoError(t, errs[i])
+		got, err := graphs[i].Sorted()
+		require.NoError(t, err)
+		assert.Equal(t, len(wantSorted), len(got))
+	}
+}
+
+func TestGraph_ConcurrentReads(t *testing.T) {
+	testDir := "testdata/complex"
+	pkgs, err := NewPackages(os.DirFS(testDir), testDir)
+	require.NoError(t, err)
+	graph, err := NewGraph(pkgs, WithRepos(packageRepo), WithKeys(key), WithLogger(log.New(io.Discard, "", 0)))
+	require.NoError(t, err)
+
+	wantWaves, err := graph.Waves()
+	require.NoError(t, err)
+	wantDeps, err := graph.BuildDependencies()
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			waves, err := graph.Waves()
+			assert.NoError(t, err)
+			assert.Equal(t, len(wantWaves), len(waves))
+
+			deps, err := graph.BuildDependencies()
+			assert.NoError(t, err)
+			assert.Equal(t, wantDeps, deps)
+
+			for _, c := range pkgs.Packages() {
+				_, err := graph.BuildEnvironment(c.Package.Name, c.Version())
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestIndexCache(t *testing.T) {
+	testDir := "testdata/basic"
+	pkgs, err := NewPackages(os.DirFS(testDir), testDir)
+	require.NoError(t, err)
+
+	cache := NewIndexCache()
+	_, err = NewGraph(pkgs, WithRepos(packageRepo), WithKeys(key), WithIndexCache(cache))
+	require.NoError(t, err)
+
+	_, ok := cache.get("x86_64", packageRepo+"/x86_64")
+	assert.True(t, ok, "index of %s should be cached", packageRepo)
+	_, ok = cache.get("aarch64", packageRepo+"/x86_64")
+	assert.False(t, ok)
+}
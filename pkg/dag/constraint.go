@@ -0,0 +1,176 @@
+package dag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	apkversion "github.com/knqyf263/go-apk-version"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+)
+
+// Operator is the comparison of a version Constraint.
+type Operator string
+
+const (
+	OpAny          Operator = ""
+	OpEqual        Operator = "="
+	OpGreater      Operator = ">"
+	OpGreaterEqual Operator = ">="
+	OpLess         Operator = "<"
+	OpLessEqual    Operator = "<="
+	// OpFuzzy matches every version that starts with the given one, e.g. foo~1.2 matches foo-1.2.3-r0.
+	OpFuzzy Operator = "~"
+)
+
+// operators are ordered so that the two character operators are tried first.
+var operators = []Operator{OpGreaterEqual, OpLessEqual, OpFuzzy, OpEqual, OpGreater, OpLess}
+
+// Constraint is a package dependency as written in melange configs and APKINDEXes, e.g. "foo", "foo>=2.3" or
+// "so:libc.so.6".
+type Constraint struct {
+	Name     string
+	Operator Operator
+	Version  string
+}
+
+// ParseConstraint parses a dependency like "foo>=2.3".
+func ParseConstraint(s string) (Constraint, error) {
+	s = strings.TrimSpace(s)
+	for _, op := range operators {
+		if name, version, ok := strings.Cut(s, string(op)); ok {
+			if name == "" || version == "" {
+				return Constraint{}, fmt.Errorf("invalid constraint %q", s)
+			}
+			if !apkversion.Valid(version) {
+				return Constraint{}, fmt.Errorf("invalid constraint %q: invalid version %q", s, version)
+			}
+			return Constraint{Name: name, Operator: op, Version: version}, nil
+		}
+	}
+	if s == "" {
+		return Constraint{}, fmt.Errorf("empty constraint")
+	}
+	return Constraint{Name: s}, nil
+}
+
+func (c Constraint) String() string {
+	return c.Name + string(c.Operator) + c.Version
+}
+
+// Matches reports whether a package, subpackage or provides with the given name and version satisfies the
+// constraint. An empty version, as of a provides without one, only satisfies constraints without a version.
+func (c Constraint) Matches(name, version string) bool {
+	if name != c.Name {
+		return false
+	}
+	if c.Operator == OpAny {
+		return true
+	}
+	if version == "" {
+		return false
+	}
+
+	switch c.Operator {
+	case OpFuzzy:
+		if !strings.HasPrefix(version, c.Version) {
+			return false
+		}
+		next := strings.TrimPrefix(version, c.Version)
+		return next == "" || next[0] < '0' || next[0] > '9'
+	case OpEqual:
+		return CompareVersions(version, c.Version) == 0
+	case OpGreater:
+		return CompareVersions(version, c.Version) > 0
+	case OpGreaterEqual:
+		return CompareVersions(version, c.Version) >= 0
+	case OpLess:
+		return CompareVersions(version, c.Version) < 0
+	case OpLessEqual:
+		return CompareVersions(version, c.Version) <= 0
+	default:
+		return false
+	}
+}
+
+// WhoProvides returns the local packages, subpackages and provides that satisfy the constraint, newest first.
+func (p Packages) WhoProvides(c Constraint) []*Configuration {
+	var matches []*Configuration
+	for _, config := range p.configs[c.Name] {
+		if c.Matches(config.name, config.version) {
+			matches = append(matches, config)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return CompareVersions(matches[i].version, matches[j].version) > 0
+	})
+	return matches
+}
+
+// WhoProvides returns the packages in the graph, local or from upstream repositories, that satisfy the
+// constraint, newest first. Local packages also match through their subpackages and provides.
+func (g Graph) WhoProvides(c Constraint) ([]Package, error) {
+	pkgs, err := g.NodesByName(c.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		matches []Package
+		seen    = make(map[string]bool)
+	)
+	for _, pkg := range pkgs {
+		if c.Matches(pkg.Name(), pkg.Version()) {
+			matches = append(matches, pkg)
+			seen[packageHash(pkg)] = true
+		}
+	}
+	for _, config := range g.packages.WhoProvides(c) {
+		if !seen[packageHash(config)] {
+			matches = append(matches, config)
+			seen[packageHash(config)] = true
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if v := CompareVersions(matches[i].Version(), matches[j].Version()); v != 0 {
+			return v > 0
+		}
+		return matches[i].Source() < matches[j].Source()
+	})
+	return matches, nil
+}
+
+// WhoProvidesInIndex returns the packages of an APKINDEX that satisfy the constraint, either by name or through
+// one of their provides, newest first.
+func WhoProvidesInIndex(idx *repository.ApkIndex, c Constraint) []*repository.Package {
+	var matches []*repository.Package
+	for _, pkg := range idx.Packages {
+		if c.Matches(pkg.Name, pkg.Version) {
+			matches = append(matches, pkg)
+			continue
+		}
+		if MatchingProvides(pkg, c) != "" {
+			matches = append(matches, pkg)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return CompareVersions(matches[i].Version, matches[j].Version) > 0
+	})
+	return matches
+}
+
+// MatchingProvides returns the first provides of an APKINDEX package that satisfies the constraint, e.g.
+// "cmd:cc=13.2.0-r0" for cmd:cc, or an empty string if none does.
+func MatchingProvides(pkg *repository.Package, c Constraint) string {
+	for _, prov := range pkg.Provides {
+		if name, version := ParseProvides(prov); c.Matches(name, version) {
+			return prov
+		}
+	}
+	return ""
+}
+
+// ParseProvides splits a provides like "so:libc.so.6=6" into its name and version, which is empty if it has none.
+func ParseProvides(prov string) (name, version string) {
+	return packageNameFromProvides(prov)
+}
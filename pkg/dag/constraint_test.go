@@ -0,0 +1,140 @@
+package dag
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+)
+
+func TestCompareVersions(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.3-r0", "1.2.3", 1},
+		{"1.2.3-r1", "1.2.3-r0", 1},
+		{"1.2.10", "1.2.9", 1},
+		{"1.2", "1.2.1", -1},
+		{"1.2a", "1.2", 1},
+		{"1.2b", "1.2a", 1},
+		{"1.2_rc1", "1.2", -1},
+		{"1.2_alpha", "1.2_beta", -1},
+		{"1.2_p1", "1.2", 1},
+		{"1.2_git20230101", "1.2_p1", -1},
+		{"10.10.11", "2.3", 1},
+	} {
+		assert.Equal(t, tt.want, CompareVersions(tt.a, tt.b), "%s vs %s", tt.a, tt.b)
+		assert.Equal(t, -tt.want, CompareVersions(tt.b, tt.a), "%s vs %s", tt.b, tt.a)
+	}
+}
+
+func TestParseConstraint(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want Constraint
+	}{
+		{"foo", Constraint{Name: "foo"}},
+		{"so:libc.so.6", Constraint{Name: "so:libc.so.6"}},
+		{"foo>=2.3", Constraint{Name: "foo", Operator: OpGreaterEqual, Version: "2.3"}},
+		{"foo<=2.3-r1", Constraint{Name: "foo", Operator: OpLessEqual, Version: "2.3-r1"}},
+		{"foo>2.3", Constraint{Name: "foo", Operator: OpGreater, Version: "2.3"}},
+		{"foo<2.3", Constraint{Name: "foo", Operator: OpLess, Version: "2.3"}},
+		{"foo=2.3", Constraint{Name: "foo", Operator: OpEqual, Version: "2.3"}},
+		{"foo~2.3", Constraint{Name: "foo", Operator: OpFuzzy, Version: "2.3"}},
+	} {
+		got, err := ParseConstraint(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+		assert.Equal(t, tt.in, got.String())
+	}
+
+	for _, in := range []string{"", ">=2.3", "foo>=", "foo>=not-a-version"} {
+		_, err := ParseConstraint(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestConstraint_Matches(t *testing.T) {
+	for _, tt := range []struct {
+		constraint, name, version string
+		want                      bool
+	}{
+		{"foo", "foo", "1.0-r0", true},
+		{"foo", "bar", "1.0-r0", false},
+		{"foo>=2.3", "foo", "2.3-r0", true},
+		{"foo>=2.3", "foo", "2.10-r0", true},
+		{"foo>=2.3", "foo", "2.2.9-r5", false},
+		{"foo>2.3-r0", "foo", "2.3-r1", true},
+		{"foo<2.3", "foo", "2.3_rc1-r0", true},
+		{"foo=2.3-r0", "foo", "2.3-r0", true},
+		{"foo=2.3", "foo", "2.3-r0", false},
+		{"foo~2.3", "foo", "2.3.4-r0", true},
+		{"foo~2.3", "foo", "2.30-r0", false},
+		{"foo>=2.3", "foo", "", false},
+	} {
+		c, err := ParseConstraint(tt.constraint)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, c.Matches(tt.name, tt.version), "%s matches %s-%s", tt.constraint, tt.name, tt.version)
+	}
+}
+
+func TestPackages_WhoProvides(t *testing.T) {
+	testDir := "testdata/complex"
+	pkgs, err := NewPackages(os.DirFS(testDir), testDir)
+	require.NoError(t, err)
+
+	versions := func(configs []*Configuration) []string {
+		var vs []string
+		for _, c := range configs {
+			vs = append(vs, c.String())
+		}
+		return vs
+	}
+
+	c, err := ParseConstraint("one>1.2.3-r1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"one-1.2.8-r1"}, versions(pkgs.WhoProvides(c)))
+
+	c, err = ParseConstraint("one")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"one-1.2.8-r1", "one-1.2.3-r1"}, versions(pkgs.WhoProvides(c)))
+
+	c, err = ParseConstraint("two-provides-explicit>=10")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"two-provides-explicit-10.11.12"}, versions(pkgs.WhoProvides(c)))
+
+	c, err = ParseConstraint("two-provides-explicit>=11")
+	require.NoError(t, err)
+	assert.Empty(t, pkgs.WhoProvides(c))
+}
+
+func TestWhoProvidesInIndex(t *testing.T) {
+	idx := &repository.ApkIndex{
+		Packages: []*repository.Package{
+			{Name: "glibc", Version: "2.37-r1", Provides: []string{"so:libc.so.6=6"}},
+			{Name: "glibc", Version: "2.38-r0", Provides: []string{"so:libc.so.6=6"}},
+			{Name: "musl", Version: "1.2.4-r0", Provides: []string{"libc"}},
+		},
+	}
+
+	c, err := ParseConstraint("so:libc.so.6")
+	require.NoError(t, err)
+	got := WhoProvidesInIndex(idx, c)
+	require.Len(t, got, 2)
+	assert.Equal(t, "2.38-r0", got[0].Version)
+
+	c, err = ParseConstraint("glibc<2.38")
+	require.NoError(t, err)
+	got = WhoProvidesInIndex(idx, c)
+	require.Len(t, got, 1)
+	assert.Equal(t, "2.37-r1", got[0].Version)
+
+	// an unversioned provides doesn't satisfy a versioned constraint
+	c, err = ParseConstraint("libc>=1")
+	require.NoError(t, err)
+	assert.Empty(t, WhoProvidesInIndex(idx, c))
+}
@@ -0,0 +1,160 @@
+package dag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BuildEnvironment returns the packages that the build environment of the named origin package resolved to,
+// sorted by name. If the Packages contain more than one version of the package, version selects which one,
+// and may be given either with or without the epoch (e.g. "1.2.3" or "1.2.3-r1").
+func (g Graph) BuildEnvironment(name, version string) ([]Package, error) {
+	c, err := g.origin(name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var env []Package
+	for _, dep := range g.DependenciesOf(packageHash(c)) {
+		p, err := g.Graph.Vertex(dep)
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, p)
+	}
+
+	sort.Slice(env, func(i, j int) bool {
+		if env[i].Name() == env[j].Name() {
+			return env[i].Version() < env[j].Version()
+		}
+		return env[i].Name() < env[j].Name()
+	})
+	return env, nil
+}
+
+// ResolvedDependencies returns the packages that the dependencies of the build environment of the named origin
+// package resolved to, by dependency as written in its config, e.g. "so:libc.so.6" or "busybox". The version selects
+// the package like for BuildEnvironment.
+func (g Graph) ResolvedDependencies(name, version string) (map[string]Package, error) {
+	c, err := g.origin(name, version)
+	if err != nil {
+		return nil, err
+	}
+	source := packageHash(c)
+	resolved := make(map[string]Package)
+	for _, target := range g.DependenciesOf(source) {
+		edge, err := g.Graph.Edge(source, target)
+		if err != nil {
+			return nil, err
+		}
+		dep, ok := edge.Properties.Attributes[edgeAttributeTargetOrigin]
+		if !ok {
+			continue
+		}
+		p, err := g.Graph.Vertex(target)
+		if err != nil {
+			return nil, err
+		}
+		resolved[dep] = p
+	}
+	return resolved, nil
+}
+
+// origin returns the config of the named origin package, selected by version if there's more than one.
+func (g Graph) origin(name, version string) (*Configuration, error) {
+	var candidates []*Configuration
+	for _, c := range g.packages.Config(name, true) {
+		if c.Package.Name != name {
+			// a subpackage or provides of the same name, we only want origin packages
+			continue
+		}
+		if version == "" || version == c.Package.Version || version == c.Version() {
+			candidates = append(candidates, c)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		if version != "" {
+			return nil, fmt.Errorf("package %s-%s not found", name, version)
+		}
+		return nil, fmt.Errorf("package %s not found", name)
+	case 1:
+		return candidates[0], nil
+	default:
+		versions := make([]string, 0, len(candidates))
+		for _, c := range candidates {
+			versions = append(versions, c.Version())
+		}
+		return nil, fmt.Errorf("multiple versions of package %s found, choose one of: %s", name, strings.Join(versions, ", "))
+	}
+}
+
+// EnvironmentChange describes how a single package in a build environment differs between two resolutions.
+// An empty Old or New side means the package was added or removed respectively.
+type EnvironmentChange struct {
+	Name       string `json:"name"`
+	OldVersion string `json:"oldVersion,omitempty"`
+	NewVersion string `json:"newVersion,omitempty"`
+	OldSource  string `json:"oldSource,omitempty"`
+	NewSource  string `json:"newSource,omitempty"`
+}
+
+func (c EnvironmentChange) String() string {
+	switch {
+	case c.OldVersion == "" && c.OldSource == "":
+		return fmt.Sprintf("+ %s %s (%s)", c.Name, c.NewVersion, c.NewSource)
+	case c.NewVersion == "" && c.NewSource == "":
+		return fmt.Sprintf("- %s %s (%s)", c.Name, c.OldVersion, c.OldSource)
+	case c.OldSource != c.NewSource:
+		return fmt.Sprintf("~ %s %s (%s) -> %s (%s)", c.Name, c.OldVersion, c.OldSource, c.NewVersion, c.NewSource)
+	default:
+		return fmt.Sprintf("~ %s %s -> %s", c.Name, c.OldVersion, c.NewVersion)
+	}
+}
+
+// DiffBuildEnvironments compares two resolved build environments, as returned by BuildEnvironment, and returns
+// the packages that were added, removed, or resolved to a different version or source, sorted by name.
+// Packages in both environments that resolved identically are omitted.
+func DiffBuildEnvironments(old, current []Package) []EnvironmentChange {
+	oldByName := environmentByName(old)
+	newByName := environmentByName(current)
+
+	var changes []EnvironmentChange
+	for name, o := range oldByName {
+		n, ok := newByName[name]
+		if !ok {
+			changes = append(changes, EnvironmentChange{Name: name, OldVersion: o.Version(), OldSource: o.Source()})
+			continue
+		}
+		if o.Version() != n.Version() || o.Source() != n.Source() {
+			changes = append(changes, EnvironmentChange{
+				Name:       name,
+				OldVersion: o.Version(),
+				NewVersion: n.Version(),
+				OldSource:  o.Source(),
+				NewSource:  n.Source(),
+			})
+		}
+	}
+	for name, n := range newByName {
+		if _, ok := oldByName[name]; !ok {
+			changes = append(changes, EnvironmentChange{Name: name, NewVersion: n.Version(), NewSource: n.Source()})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// environmentByName indexes an environment by package name, if a name appears more than once the last entry wins
+func environmentByName(env []Package) map[string]Package {
+	m := make(map[string]Package, len(env))
+	for _, p := range env {
+		m[p.Name()] = p
+	}
+	return m
+}
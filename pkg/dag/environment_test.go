@@ -0,0 +1,96 @@
+package dag
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildEnvironment(t *testing.T) {
+	testDir := "testdata/basic"
+	pkgs, err := NewPackages(os.DirFS(testDir), testDir)
+	require.NoError(t, err)
+	graph, err := NewGraph(pkgs, WithRepos(packageRepo), WithKeys(key))
+	require.NoError(t, err)
+
+	env, err := graph.BuildEnvironment("busybox", "")
+	require.NoError(t, err)
+
+	var got []string
+	for _, p := range env {
+		got = append(got, packageHash(p))
+	}
+	assert.Equal(t, []string{
+		"binutils:2.39-r1@testdata/packages/x86_64",
+		"build-base:1-r2@testdata/packages/x86_64",
+		"busybox:1.35.0-r2@testdata/packages/x86_64",
+		"ca-certificates-bundle:20220614-r1@testdata/packages/x86_64",
+		"patch:2.7.6-r1@testdata/packages/x86_64",
+		"scanelf:1.3.4-r1@testdata/packages/x86_64",
+		"wget:1.21.3-r1@testdata/packages/x86_64",
+	}, got)
+
+	_, err = graph.BuildEnvironment("busybox", "0.0.1")
+	assert.Error(t, err)
+	_, err = graph.BuildEnvironment("missing", "")
+	assert.Error(t, err)
+}
+
+func TestResolvedDependencies(t *testing.T) {
+	testDir := "testdata/basic"
+	pkgs, err := NewPackages(os.DirFS(testDir), testDir)
+	require.NoError(t, err)
+	graph, err := NewGraph(pkgs, WithRepos(packageRepo), WithKeys(key))
+	require.NoError(t, err)
+
+	resolved, err := graph.ResolvedDependencies("busybox", "")
+	require.NoError(t, err)
+
+	got := make(map[string]string)
+	for dep, p := range resolved {
+		got[dep] = packageHash(p)
+	}
+	assert.Equal(t, map[string]string{
+		"binutils":               "binutils:2.39-r1@testdata/packages/x86_64",
+		"build-base":             "build-base:1-r2@testdata/packages/x86_64",
+		"busybox":                "busybox:1.35.0-r2@testdata/packages/x86_64",
+		"ca-certificates-bundle": "ca-certificates-bundle:20220614-r1@testdata/packages/x86_64",
+		"patch":                  "patch:2.7.6-r1@testdata/packages/x86_64",
+		"scanelf":                "scanelf:1.3.4-r1@testdata/packages/x86_64",
+		"wget":                   "wget:1.21.3-r1@testdata/packages/x86_64",
+	}, got)
+
+	_, err = graph.ResolvedDependencies("missing", "")
+	assert.Error(t, err)
+}
+
+func TestDiffBuildEnvironments(t *testing.T) {
+	old := []Package{
+		externalPackage{"binutils", "2.39-r1", "https://packages.wolfi.dev/os"},
+		externalPackage{"busybox", "1.35.0-r2", "https://packages.wolfi.dev/os"},
+		externalPackage{"gcc", "12.2.0-r1", "https://packages.wolfi.dev/os"},
+		externalPackage{"patch", "2.7.6-r1", "https://packages.wolfi.dev/os"},
+	}
+	current := []Package{
+		externalPackage{"binutils", "2.40-r0", "https://packages.wolfi.dev/os"},
+		externalPackage{"busybox", "1.35.0-r2", "https://packages.wolfi.dev/os"},
+		externalPackage{"gcc", "12.2.0-r1", Local},
+		externalPackage{"wget", "1.21.3-r1", "https://packages.wolfi.dev/os"},
+	}
+
+	changes := DiffBuildEnvironments(old, current)
+	assert.Equal(t, []EnvironmentChange{
+		{Name: "binutils", OldVersion: "2.39-r1", NewVersion: "2.40-r0", OldSource: "https://packages.wolfi.dev/os", NewSource: "https://packages.wolfi.dev/os"},
+		{Name: "gcc", OldVersion: "12.2.0-r1", NewVersion: "12.2.0-r1", OldSource: "https://packages.wolfi.dev/os", NewSource: Local},
+		{Name: "patch", OldVersion: "2.7.6-r1", OldSource: "https://packages.wolfi.dev/os"},
+		{Name: "wget", NewVersion: "1.21.3-r1", NewSource: "https://packages.wolfi.dev/os"},
+	}, changes)
+
+	assert.Equal(t, "~ binutils 2.39-r1 -> 2.40-r0", changes[0].String())
+	assert.Equal(t, "- patch 2.7.6-r1 (https://packages.wolfi.dev/os)", changes[2].String())
+	assert.Equal(t, "+ wget 1.21.3-r1 (https://packages.wolfi.dev/os)", changes[3].String())
+
+	assert.Empty(t, DiffBuildEnvironments(old, old))
+}
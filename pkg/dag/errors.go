@@ -0,0 +1,93 @@
+package dag
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnresolvedDependencyError is returned when a build dependency of a package can't be resolved in any of the
+// repositories available to it, nor locally.
+type UnresolvedDependencyError struct {
+	// Package is the package with the dependency, e.g. "curl-8.1.0-r0".
+	Package string
+	// Dependency is the dependency as written in the config, e.g. "openssl-dev>=3".
+	Dependency string
+	// Err is the error from the resolver, if any. It's nil when the dependency only resolved to the package
+	// itself.
+	Err error
+}
+
+func (e *UnresolvedDependencyError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s: unfulfilled dependency %s", e.Package, e.Dependency)
+	}
+	return fmt.Sprintf("%s: unable to resolve dependency %s: %v", e.Package, e.Dependency, e.Err)
+}
+
+func (e *UnresolvedDependencyError) Unwrap() error {
+	return e.Err
+}
+
+// CycleError is returned when packages depend on each other in a way that can't be resolved.
+type CycleError struct {
+	// Path is the cycle, starting and ending with the same package.
+	Path []string
+	// Err is the reason the cycle couldn't be broken, if any.
+	Err error
+}
+
+func (e *CycleError) Error() string {
+	msg := fmt.Sprintf("unresolvable cycle: %s", strings.Join(e.Path, " -> "))
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *CycleError) Unwrap() error {
+	return e.Err
+}
+
+// IndexFetchError is returned when the APKINDEXes of the repositories referenced by a package can't be loaded.
+type IndexFetchError struct {
+	// Package is the package whose config references the repositories.
+	Package string
+	Repos   []string
+	Arch    string
+	Err     error
+}
+
+func (e *IndexFetchError) Error() string {
+	return fmt.Sprintf("unable to load repositories %s for %s (%s): %v", strings.Join(e.Repos, ", "), e.Package, e.Arch, e.Err)
+}
+
+func (e *IndexFetchError) Unwrap() error {
+	return e.Err
+}
+
+// FindErrors returns every error of type T in the tree of err, including all of the errors joined together by
+// NewGraph, so they can be grouped or rendered by kind.
+func FindErrors[T error](err error) []T {
+	var found []T
+	var walk func(error)
+	walk = func(err error) {
+		if err == nil {
+			return
+		}
+		// errors.As would stop at the first match, we want them all
+		if t, ok := err.(T); ok {
+			found = append(found, t)
+			return
+		}
+		switch u := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, e := range u.Unwrap() {
+				walk(e)
+			}
+		case interface{ Unwrap() error }:
+			walk(u.Unwrap())
+		}
+	}
+	walk(err)
+	return found
+}
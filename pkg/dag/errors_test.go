@@ -0,0 +1,44 @@
+package dag
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGraph_UnresolvedDependencyErrors(t *testing.T) {
+	testDir := "testdata/complex"
+	pkgs, err := NewPackages(os.DirFS(testDir), testDir)
+	require.NoError(t, err)
+
+	_, err = NewGraph(pkgs)
+	require.Error(t, err)
+
+	unresolved := FindErrors[*UnresolvedDependencyError](err)
+	require.NotEmpty(t, unresolved)
+
+	byPackage := make(map[string][]string)
+	for _, e := range unresolved {
+		byPackage[e.Package] = append(byPackage[e.Package], e.Dependency)
+	}
+	assert.Contains(t, byPackage["three-other-7.8.9-r1"], "busybox")
+	assert.Contains(t, byPackage["two-4.5.6-r1"], "build-base")
+	assert.Empty(t, FindErrors[*IndexFetchError](err))
+}
+
+func TestFindErrors(t *testing.T) {
+	cycle := &CycleError{Path: []string{"a", "b", "a"}, Err: errors.New("boom")}
+	unresolved := &UnresolvedDependencyError{Package: "a-1-r0", Dependency: "c"}
+	err := fmt.Errorf("wrapped: %w", errors.Join(unresolved, fmt.Errorf("more: %w", cycle), errors.New("other")))
+
+	assert.Equal(t, []*CycleError{cycle}, FindErrors[*CycleError](err))
+	assert.Equal(t, []*UnresolvedDependencyError{unresolved}, FindErrors[*UnresolvedDependencyError](err))
+	assert.Empty(t, FindErrors[*IndexFetchError](err))
+
+	assert.Equal(t, "unresolvable cycle: a -> b -> a: boom", cycle.Error())
+	assert.Equal(t, "a-1-r0: unfulfilled dependency c", unresolved.Error())
+}
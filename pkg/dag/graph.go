@@ -2,10 +2,12 @@ package dag
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"log"
 	"net/http"
 	"net/url"
 	"os"
@@ -13,7 +15,6 @@ import (
 	"strings"
 
 	"github.com/dominikbraun/graph"
-	log "github.com/sirupsen/logrus"
 	"go.lsp.dev/uri"
 
 	apko "chainguard.dev/apko/pkg/apk/impl"
@@ -22,6 +23,9 @@ import (
 // Graph represents an interdependent set of packages defined in one or more Melange configurations,
 // as defined in Packages, as well as upstream repositories and their package indexes,
 // as declared in those configurations files. The graph is directed and acyclic.
+//
+// A Graph isn't modified once it's built, so its methods are safe for concurrent use. Graphs can be built
+// concurrently too, from the same Packages.
 type Graph struct {
 	Graph    graph.Graph[string, Package]
 	packages *Packages
@@ -55,6 +59,15 @@ func NewGraph(pkgs *Packages, options ...GraphOptions) (*Graph, error) {
 			return nil, err
 		}
 	}
+	if opts.logger == nil {
+		opts.logger = log.New(log.Writer(), "", log.LstdFlags)
+	}
+	if opts.indexes == nil {
+		opts.indexes = NewIndexCache()
+	}
+	if opts.ctx == nil {
+		opts.ctx = context.Background()
+	}
 	g := &Graph{
 		Graph:    newGraph(),
 		packages: pkgs,
@@ -62,16 +75,16 @@ func NewGraph(pkgs *Packages, options ...GraphOptions) (*Graph, error) {
 		byName:   map[string][]string{},
 	}
 
-	// indexes is a cache of all repositories. Only some might be used for each package.
-	var (
-		indexes = make(map[string]apko.NamedIndex)
-		errs    []error
-	)
+	// opts.indexes is a cache of all repositories. Only some might be used for each package.
+	var errs []error
 
 	// 1. go through each known origin package, add it as a vertex
 	// 2. go through each of its subpackages, add them as vertices, with the sub dependent on the origin
 	// 3. go through each of its dependencies, add them as vertices, with the origin dependent on the dependency
 	for _, c := range pkgs.Packages() {
+		if err := opts.ctx.Err(); err != nil {
+			return nil, err
+		}
 		version := fullVersion(&c.Package)
 		if err := g.addVertex(c); err != nil && !errors.Is(err, graph.ErrVertexAlreadyExists) {
 			errs = append(errs, err)
@@ -105,7 +118,7 @@ func NewGraph(pkgs *Packages, options ...GraphOptions) (*Graph, error) {
 			lookupRepos = []apko.NamedIndex{}
 		)
 		for _, repo := range append(origRepos, opts.repos...) {
-			if index, ok := indexes[repo]; !ok {
+			if index, ok := opts.indexes.get(arch, repo); !ok {
 				repos = append(repos, repo)
 			} else {
 				lookupRepos = append(lookupRepos, index)
@@ -113,7 +126,7 @@ func NewGraph(pkgs *Packages, options ...GraphOptions) (*Graph, error) {
 		}
 		keyMap := make(map[string][]byte)
 		for _, key := range append(origKeys, opts.keys...) {
-			b, err := getKeyMaterial(key)
+			b, err := getKeyMaterial(opts.ctx, key)
 			if err != nil {
 				return nil, fmt.Errorf("failed to get key material for %s: %w", key, err)
 			}
@@ -125,10 +138,10 @@ func NewGraph(pkgs *Packages, options ...GraphOptions) (*Graph, error) {
 		if len(repos) > 0 {
 			loadedRepos, err := apko.GetRepositoryIndexes(repos, keyMap, arch)
 			if err != nil {
-				return nil, fmt.Errorf("unable to load repositories for %s: %w", c.String(), err)
+				return nil, &IndexFetchError{Package: c.String(), Repos: repos, Arch: arch, Err: err}
 			}
 			for _, repo := range loadedRepos {
-				indexes[repo.Source()] = repo
+				opts.indexes.add(arch, repo)
 				lookupRepos = append(lookupRepos, repo)
 			}
 		}
@@ -157,8 +170,9 @@ func NewGraph(pkgs *Packages, options ...GraphOptions) (*Graph, error) {
 			if cycle != nil {
 				if err := g.resolveCycle(cycle, buildDep, resolver, localRepoSource); err != nil {
 					sp, _ := graph.ShortestPath(g.Graph, cycle.target, cycle.src) //nolint:errcheck // we do not need to check for an error, as we have an error
-					log.Errorf("unresolvable cycle: %s -> %s, caused by: %s", cycle.src, cycle.target, strings.Join(sp, " -> "))
-					errs = append(errs, err)
+					cerr := &CycleError{Path: append([]string{cycle.src}, sp...), Err: err}
+					opts.logger.Print(cerr)
+					errs = append(errs, cerr)
 					continue
 				}
 			}
@@ -184,7 +198,7 @@ func (g *Graph) addAppropriatePackage(resolver *apko.PkgResolver, c Package, dep
 			return nil, fmt.Errorf("%s: unable to add dangling package %s: %w", c, dep, err)
 		}
 	case (err != nil || len(resolved) == 0):
-		return nil, fmt.Errorf("%s: unable to resolve dependency %s: %w", c, dep, err)
+		return nil, &UnresolvedDependencyError{Package: c.String(), Dependency: dep, Err: err}
 	default:
 		// no error and we had at least one package listed in `resolved`
 		for _, r := range resolved {
@@ -224,7 +238,7 @@ func (g *Graph) addAppropriatePackage(resolver *apko.PkgResolver, c Package, dep
 				}
 				continue
 			}
-			err := g.Graph.AddEdge(packageHash(c), target, graph.EdgeAttribute("target-origin", dep))
+			err := g.Graph.AddEdge(packageHash(c), target, dependencyEdge(dep))
 			switch {
 			case err == nil || errors.Is(err, graph.ErrEdgeAlreadyExists):
 				// no error, so we can keep the vertex and we have our match
@@ -239,7 +253,7 @@ func (g *Graph) addAppropriatePackage(resolver *apko.PkgResolver, c Package, dep
 				return &cycle{src: packageHash(c), target: cycleTarget}, nil
 			}
 			if !g.opts.allowUnresolved {
-				return nil, fmt.Errorf("%s: unfulfilled dependency %s", c, dep)
+				return nil, &UnresolvedDependencyError{Package: c.String(), Dependency: dep}
 			}
 			if err := g.addDanglingPackage(dep, c); err != nil {
 				return nil, fmt.Errorf("%s: unable to add dangling package %s: %w", c, dep, err)
@@ -270,13 +284,13 @@ func (g *Graph) resolveCycle(c *cycle, dep string, resolver *apko.PkgResolver, l
 	if edge.Properties.Attributes == nil {
 		return fmt.Errorf("original edge %s -> %s has no attributes", removeSrc, removeTarget)
 	}
-	origDep := edge.Properties.Attributes["target-origin"]
+	origDep := edge.Properties.Attributes[edgeAttributeTargetOrigin]
 	// try to reverse the direction of the edge
 	if err := g.Graph.RemoveEdge(removeSrc, removeTarget); err != nil {
 		return fmt.Errorf("unable to remove original edge %s -> %s: %w", removeSrc, removeTarget, err)
 	}
 	// add in our new edge
-	if err := g.Graph.AddEdge(c.src, c.target, graph.EdgeAttribute("target-origin", dep)); err != nil {
+	if err := g.Graph.AddEdge(c.src, c.target, dependencyEdge(dep)); err != nil {
 		return fmt.Errorf("unable to add replacement edge %s -> %s: %w", c.src, c.target, err)
 	}
 	// now we need to re-add the edge that was removed, but with a different target
@@ -308,7 +322,7 @@ func (g *Graph) addDanglingPackage(name string, parent Package) error {
 	if err := g.addVertex(pkg); err != nil && !errors.Is(err, graph.ErrVertexAlreadyExists) {
 		return err
 	}
-	if err := g.Graph.AddEdge(packageHash(parent), packageHash(pkg)); err != nil && !errors.Is(err, graph.ErrEdgeAlreadyExists) {
+	if err := g.Graph.AddEdge(packageHash(parent), packageHash(pkg), dependencyEdge(name)); err != nil && !errors.Is(err, graph.ErrEdgeAlreadyExists) {
 		return err
 	}
 	return nil
@@ -391,7 +405,7 @@ func (g Graph) SubgraphWithLeaves(leaves []string) (*Graph, error) {
 		}
 		names = append(names, key)
 
-		for dependent := range predecessorMap[key] {
+		for dependent, edge := range predecessorMap[key] {
 			c := g.packages.ConfigByKey(dependent)
 			if c == nil {
 				return fmt.Errorf("unable to find package %q", dependent)
@@ -399,7 +413,7 @@ func (g Graph) SubgraphWithLeaves(leaves []string) (*Graph, error) {
 			if err := subgraph.addVertex(c); err != nil && !errors.Is(err, graph.ErrVertexAlreadyExists) {
 				return err
 			}
-			if err := subgraph.Graph.AddEdge(dependent, key); err != nil && !errors.Is(err, graph.ErrEdgeAlreadyExists) {
+			if err := subgraph.Graph.AddEdge(dependent, key, graph.EdgeAttributes(edge.Properties.Attributes)); err != nil && !errors.Is(err, graph.ErrEdgeAlreadyExists) {
 				return err
 			}
 
@@ -511,7 +525,7 @@ func (g Graph) Filter(filter Filter) (*Graph, error) {
 				continue
 			}
 			// both the node and the dependency are in the new graph, so keep the edge
-			if err := subgraph.Graph.AddEdge(edge.Source, edge.Target); err != nil && !errors.Is(err, graph.ErrEdgeAlreadyExists) {
+			if err := subgraph.Graph.AddEdge(edge.Source, edge.Target, graph.EdgeAttributes(edge.Properties.Attributes)); err != nil && !errors.Is(err, graph.ErrEdgeAlreadyExists) {
 				return nil, err
 			}
 		}
@@ -573,7 +587,7 @@ func (g Graph) NodesByName(name string) (pkgs []Package, err error) {
 	return
 }
 
-func getKeyMaterial(key string) ([]byte, error) {
+func getKeyMaterial(ctx context.Context, key string) ([]byte, error) {
 	var (
 		b     []byte
 		asURI uri.URI
@@ -602,8 +616,11 @@ func getKeyMaterial(key string) ([]byte, error) {
 			return nil, nil
 		}
 	case "https":
-		client := &http.Client{}
-		res, err := client.Get(asURL.String())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, asURL.String(), http.NoBody)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get key at %s: %w", key, err)
+		}
+		res, err := http.DefaultClient.Do(req)
 		if err != nil {
 			return nil, fmt.Errorf("unable to get key at %s: %w", key, err)
 		}
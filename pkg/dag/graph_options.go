@@ -1,9 +1,17 @@
 package dag
 
+import (
+	"context"
+	"log"
+)
+
 type graphOptions struct {
 	allowUnresolved bool
 	repos           []string
 	keys            []string
+	logger          *log.Logger
+	indexes         *IndexCache
+	ctx             context.Context
 }
 
 type GraphOptions func(*graphOptions) error
@@ -28,3 +36,30 @@ func WithKeys(keys ...string) GraphOptions {
 		return nil
 	}
 }
+
+// WithLogger sets the logger the graph reports what it works around while resolving dependencies to. By default,
+// it's a logger of its own writing to the output of the standard logger.
+func WithLogger(logger *log.Logger) GraphOptions {
+	return func(o *graphOptions) error {
+		o.logger = logger
+		return nil
+	}
+}
+
+// WithIndexCache shares the indexes of the repositories fetched for the graph with other graphs using the same
+// cache. By default, every graph fetches the repositories it needs itself.
+func WithIndexCache(c *IndexCache) GraphOptions {
+	return func(o *graphOptions) error {
+		o.indexes = c
+		return nil
+	}
+}
+
+// WithContext stops building the graph once ctx is canceled, including fetching the keys of the repositories it
+// needs. Indexes being fetched are only checked for in between packages.
+func WithContext(ctx context.Context) GraphOptions {
+	return func(o *graphOptions) error {
+		o.ctx = ctx
+		return nil
+	}
+}
@@ -1,6 +1,7 @@
 package dag
 
 import (
+	"context"
 	"os"
 	"testing"
 
@@ -13,6 +14,16 @@ const (
 	key         = "testdata/packages/key.rsa.pub"
 )
 
+func TestNewGraph_canceled(t *testing.T) {
+	testDir := "testdata/basic"
+	pkgs, err := NewPackages(os.DirFS(testDir), testDir)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = NewGraph(pkgs, WithAllowUnresolved(), WithContext(ctx))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
 func TestNewGraph(t *testing.T) {
 	t.Run("basic", func(t *testing.T) {
 		var (
@@ -0,0 +1,35 @@
+package dag
+
+import (
+	"sync"
+
+	apko "chainguard.dev/apko/pkg/apk/impl"
+)
+
+// IndexCache holds the indexes of the repositories fetched while building graphs, so graphs of configs that
+// share repositories only fetch each of them once. It's safe for concurrent use by graphs built at the same time.
+type IndexCache struct {
+	mu      sync.Mutex
+	indexes map[indexKey]apko.NamedIndex
+}
+
+type indexKey struct {
+	arch, repo string
+}
+
+func NewIndexCache() *IndexCache {
+	return &IndexCache{indexes: make(map[indexKey]apko.NamedIndex)}
+}
+
+func (c *IndexCache) get(arch, repo string) (apko.NamedIndex, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	idx, ok := c.indexes[indexKey{arch: arch, repo: repo}]
+	return idx, ok
+}
+
+func (c *IndexCache) add(arch string, idx apko.NamedIndex) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.indexes[indexKey{arch: arch, repo: idx.Source()}] = idx
+}
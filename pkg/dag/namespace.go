@@ -0,0 +1,78 @@
+package dag
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dominikbraun/graph"
+)
+
+// Namespace is the kind of virtual package a dependency is on. Virtual packages are provided by packages rather than
+// being packages themselves, and most are generated when a package is built, e.g. a cmd: provides for every
+// executable in its PATH. Dependencies on packages have no namespace.
+type Namespace string
+
+const (
+	NamespaceNone Namespace = ""
+	// NamespaceCmd is an executable, e.g. cmd:cc.
+	NamespaceCmd Namespace = "cmd"
+	// NamespaceSo is a shared library, e.g. so:libc.so.6.
+	NamespaceSo Namespace = "so"
+	// NamespacePc is a pkg-config module, e.g. pc:libffi.
+	NamespacePc Namespace = "pc"
+	// NamespacePy3Dist is a Python distribution, e.g. py3dist(requests).
+	NamespacePy3Dist Namespace = "py3dist"
+)
+
+// edge attributes of dependencies in a Graph
+const (
+	edgeAttributeTargetOrigin = "target-origin"
+	edgeAttributeNamespace    = "namespace"
+)
+
+// NamespaceOf returns the namespace of the name of a dependency or provides, without its version.
+func NamespaceOf(name string) Namespace {
+	for _, ns := range []Namespace{NamespaceCmd, NamespaceSo, NamespacePc} {
+		if strings.HasPrefix(name, string(ns)+":") && len(name) > len(ns)+1 {
+			return ns
+		}
+	}
+	if strings.HasPrefix(name, string(NamespacePy3Dist)+"(") && strings.HasSuffix(name, ")") && len(name) > len(NamespacePy3Dist)+2 {
+		return NamespacePy3Dist
+	}
+	return NamespaceNone
+}
+
+// Namespace returns the namespace of the constraint, NamespaceNone if it's on a package.
+func (c Constraint) Namespace() Namespace {
+	return NamespaceOf(c.Name)
+}
+
+// IsVirtual reports whether the constraint is on a virtual package, which only the provides of packages satisfy.
+func (c Constraint) IsVirtual() bool {
+	return c.Namespace() != NamespaceNone
+}
+
+// dependencyEdge returns the attributes of the edge for a dependency as written in a config: the dependency itself,
+// to resolve it again if needed, and its namespace if it's on a virtual package.
+func dependencyEdge(dep string) func(*graph.EdgeProperties) {
+	attributes := map[string]string{edgeAttributeTargetOrigin: dep}
+	name := dep
+	if c, err := ParseConstraint(dep); err == nil {
+		name = c.Name
+	}
+	if ns := NamespaceOf(name); ns != NamespaceNone {
+		attributes[edgeAttributeNamespace] = string(ns)
+	}
+	return graph.EdgeAttributes(attributes)
+}
+
+// DependencyNamespace returns the namespace of the dependency the edge from source to target was added for,
+// NamespaceNone if it's on a package, or the edge is between a subpackage and its origin.
+func (g Graph) DependencyNamespace(source, target string) (Namespace, error) {
+	edge, err := g.Graph.Edge(source, target)
+	if err != nil {
+		return NamespaceNone, fmt.Errorf("unable to find edge %s -> %s: %w", source, target, err)
+	}
+	return Namespace(edge.Properties.Attributes[edgeAttributeNamespace]), nil
+}
@@ -0,0 +1,69 @@
+package dag
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaceOf(t *testing.T) {
+	for name, want := range map[string]Namespace{
+		"cmd:cc":            NamespaceCmd,
+		"so:libc.so.6":      NamespaceSo,
+		"pc:libffi":         NamespacePc,
+		"py3dist(requests)": NamespacePy3Dist,
+		"busybox":           NamespaceNone,
+		"cmd:":              NamespaceNone,
+		"py3dist()":         NamespaceNone,
+		"py3-requests":      NamespaceNone,
+	} {
+		assert.Equalf(t, want, NamespaceOf(name), "NamespaceOf(%s)", name)
+	}
+
+	c, err := ParseConstraint("pc:libffi>=3.4")
+	require.NoError(t, err)
+	assert.Equal(t, NamespacePc, c.Namespace())
+	assert.True(t, c.IsVirtual())
+
+	c, err = ParseConstraint("glibc>=2.38")
+	require.NoError(t, err)
+	assert.False(t, c.IsVirtual())
+}
+
+func TestGraph_DependencyNamespace(t *testing.T) {
+	testDir := "testdata/virtual"
+	pkgs, err := NewPackages(os.DirFS(testDir), testDir)
+	require.NoError(t, err)
+	graph, err := NewGraph(pkgs, WithAllowUnresolved())
+	require.NoError(t, err)
+
+	configs := pkgs.Config("py3-foo", false)
+	require.Len(t, configs, 1)
+	src := packageHash(configs[0])
+
+	for target, want := range map[string]Namespace{
+		"busybox:@unknown":             NamespaceNone,
+		"cmd:cc:@unknown":              NamespaceCmd,
+		"so:libffi.so.8:@unknown":      NamespaceSo,
+		"pc:libffi>=3.4:@unknown":      NamespacePc,
+		"py3dist(setuptools):@unknown": NamespacePy3Dist,
+	} {
+		got, err := graph.DependencyNamespace(src, target)
+		if !assert.NoError(t, err, target) {
+			continue
+		}
+		assert.Equal(t, want, got, target)
+	}
+
+	// subgraphs keep the namespaces
+	sub, err := graph.Filter(func(Package) bool { return true })
+	require.NoError(t, err)
+	got, err := sub.DependencyNamespace(src, "cmd:cc:@unknown")
+	require.NoError(t, err)
+	assert.Equal(t, NamespaceCmd, got)
+
+	_, err = graph.DependencyNamespace(src, "nope:@unknown")
+	assert.Error(t, err)
+}
@@ -3,7 +3,6 @@ package dag
 import (
 	"fmt"
 	"io/fs"
-	"log"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -57,6 +56,8 @@ func (c Configuration) Resolved() bool {
 //
 // It does not try to determine relationships and dependencies between packages. For that,
 // pass a Packages to NewGraph.
+//
+// Packages aren't modified once NewPackages returns, so they're safe for concurrent use.
 type Packages struct {
 	configs  map[string][]*Configuration
 	packages map[string][]*Configuration
@@ -165,7 +166,7 @@ func NewPackages(fsys fs.FS, dirPath string) (*Packages, error) {
 
 			name := c.name
 			if name == "" {
-				log.Fatalf("no package name in %q", path)
+				return fmt.Errorf("no package name in %q", path)
 			}
 			if err := pkgs.addConfiguration(name, c); err != nil {
 				return err
@@ -179,7 +180,7 @@ func NewPackages(fsys fs.FS, dirPath string) (*Packages, error) {
 				subpkg := c.Subpackages[i]
 				name := subpkg.Name
 				if name == "" {
-					log.Fatalf("empty subpackage name at index %d for package %q", i, c.Package.Name)
+					return fmt.Errorf("empty subpackage name at index %d for package %q", i, c.Package.Name)
 				}
 				c := &Configuration{
 					Configuration: buildc,
@@ -247,9 +248,8 @@ func (p Packages) Config(name string, packageOnly bool) []*Configuration {
 	list = append(list, c...)
 
 	// sort the list by increasing version
-	// this should be better about this, perhaps we will use the apko version sorting library in a future revision
 	sort.Slice(list, func(i, j int) bool {
-		return fullVersion(&list[i].Package) < fullVersion(&list[j].Package)
+		return CompareVersions(fullVersion(&list[i].Package), fullVersion(&list[j].Package)) < 0
 	})
 	return list
 }
@@ -0,0 +1,175 @@
+package dag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SubpackageOrigin relates a subpackage to the origin package whose configuration declares it.
+type SubpackageOrigin struct {
+	Subpackage string `json:"subpackage"`
+	// Origin is the origin package as name-version.
+	Origin string `json:"origin"`
+	Path   string `json:"path"`
+	// InGraph is whether the origin package is a vertex of the graph.
+	InGraph bool `json:"inGraph"`
+}
+
+func (s SubpackageOrigin) String() string {
+	return fmt.Sprintf("%s -> %s", s.Subpackage, s.Origin)
+}
+
+// SubpackageOrigins returns every subpackage declared by the local packages of the Graph along with its origin,
+// sorted by subpackage and then origin.
+func (g Graph) SubpackageOrigins() []SubpackageOrigin {
+	var out []SubpackageOrigin
+	for _, c := range g.packages.Packages() {
+		_, err := g.Graph.Vertex(packageHash(c))
+		for i := range c.Subpackages {
+			out = append(out, SubpackageOrigin{
+				Subpackage: c.Subpackages[i].Name,
+				Origin:     c.String(),
+				Path:       c.Path,
+				InGraph:    err == nil,
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Subpackage != out[j].Subpackage {
+			return out[i].Subpackage < out[j].Subpackage
+		}
+		return out[i].Origin < out[j].Origin
+	})
+	return out
+}
+
+// OrphanSubpackage is a subpackage that packages of the Graph depend on, but that no origin package in the Graph
+// builds anymore.
+type OrphanSubpackage struct {
+	Subpackage string `json:"subpackage"`
+	// Origin is the name of the origin package the subpackage belongs, or belonged, to.
+	Origin string `json:"origin"`
+	Reason string `json:"reason"`
+	// Dependents are the origin packages that depend on the subpackage.
+	Dependents []string `json:"dependents"`
+}
+
+func (o OrphanSubpackage) String() string {
+	return fmt.Sprintf("%s: %s, needed by %s", o.Subpackage, o.Reason, strings.Join(o.Dependents, ", "))
+}
+
+// OrphanSubpackages returns the subpackages that are vertices of the Graph but whose origin package vertex is
+// missing from it, sorted by subpackage.
+//
+// If base is not nil, it's the same set of packages before a change, e.g. the target branch of a pull request. The
+// subpackages that base declares and that no package of the Graph declares anymore, because their origin was removed
+// or doesn't list them anymore, are returned too if packages of the Graph still depend on them. Such dependencies
+// would otherwise quietly resolve to a stale package in an upstream repository, or not resolve at all.
+func (g Graph) OrphanSubpackages(base *Packages) ([]OrphanSubpackage, error) {
+	adjacencyMap, err := g.Graph.AdjacencyMap()
+	if err != nil {
+		return nil, err
+	}
+	predecessors, err := g.Graph.PredecessorMap()
+	if err != nil {
+		return nil, err
+	}
+
+	dependents := func(hashes ...string) ([]string, error) {
+		seen := make(map[string]bool)
+		var names []string
+		for _, hash := range hashes {
+			for p := range predecessors[hash] {
+				vertex, err := g.Graph.Vertex(p)
+				if err != nil {
+					return nil, err
+				}
+				name := vertex.Name()
+				if c, ok := vertex.(*Configuration); ok {
+					name = c.Package.Name
+				}
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+		}
+		sort.Strings(names)
+		return names, nil
+	}
+
+	var orphans []OrphanSubpackage
+	for node := range adjacencyMap {
+		vertex, err := g.Graph.Vertex(node)
+		if err != nil {
+			return nil, err
+		}
+		c, ok := vertex.(*Configuration)
+		if !ok || !c.isSubpackage() {
+			continue
+		}
+		origin := originOf(c)
+		if _, err := g.Graph.Vertex(packageHash(origin)); err == nil {
+			continue
+		}
+		deps, err := dependents(node)
+		if err != nil {
+			return nil, err
+		}
+		orphans = append(orphans, OrphanSubpackage{
+			Subpackage: c.name,
+			Origin:     c.Package.Name,
+			Reason:     fmt.Sprintf("origin %s is missing from the graph", origin),
+			Dependents: deps,
+		})
+	}
+
+	if base != nil {
+		seen := make(map[string]bool)
+		for _, c := range base.Packages() {
+			for i := range c.Subpackages {
+				name := c.Subpackages[i].Name
+				if seen[name] || len(g.packages.Config(name, false)) > 0 {
+					continue
+				}
+				seen[name] = true
+				hashes := g.byName[name]
+				if len(hashes) == 0 {
+					// nothing depends on it anymore
+					continue
+				}
+				deps, err := dependents(hashes...)
+				if err != nil {
+					return nil, err
+				}
+				reason := fmt.Sprintf("origin %s was removed", c.Package.Name)
+				if len(g.packages.Config(c.Package.Name, true)) > 0 {
+					reason = fmt.Sprintf("origin %s doesn't declare it anymore", c.Package.Name)
+				}
+				orphans = append(orphans, OrphanSubpackage{
+					Subpackage: name,
+					Origin:     c.Package.Name,
+					Reason:     reason,
+					Dependents: deps,
+				})
+			}
+		}
+	}
+
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].Subpackage < orphans[j].Subpackage
+	})
+	return orphans, nil
+}
+
+// isSubpackage reports whether c is one of the subpackages of its configuration, rather than the origin package or
+// something it provides.
+func (c Configuration) isSubpackage() bool {
+	for i := range c.Subpackages {
+		if c.Subpackages[i].Name == c.name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package dag
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubpackageOrigins(t *testing.T) {
+	testDir := "testdata/subpackages/base"
+	pkgs, err := NewPackages(os.DirFS(testDir), testDir)
+	require.NoError(t, err)
+	graph, err := NewGraph(pkgs, WithAllowUnresolved())
+	require.NoError(t, err)
+
+	assert.Equal(t, []SubpackageOrigin{
+		{Subpackage: "lib-dev", Origin: "lib-1.0.0-r0", Path: "testdata/subpackages/base/lib.yaml", InGraph: true},
+		{Subpackage: "lib-doc", Origin: "lib-1.0.0-r0", Path: "testdata/subpackages/base/lib.yaml", InGraph: true},
+		{Subpackage: "tool-extra", Origin: "tool-1.0.0-r0", Path: "testdata/subpackages/base/tool.yaml", InGraph: true},
+	}, graph.SubpackageOrigins())
+}
+
+func TestOrphanSubpackages(t *testing.T) {
+	baseDir := "testdata/subpackages/base"
+	base, err := NewPackages(os.DirFS(baseDir), baseDir)
+	require.NoError(t, err)
+
+	t.Run("origin removed from the graph", func(t *testing.T) {
+		graph, err := NewGraph(base, WithAllowUnresolved())
+		require.NoError(t, err)
+
+		orphans, err := graph.OrphanSubpackages(nil)
+		require.NoError(t, err)
+		assert.Empty(t, orphans)
+
+		filtered, err := graph.Filter(func(p Package) bool { return p.Name() != "lib" })
+		require.NoError(t, err)
+		orphans, err = filtered.OrphanSubpackages(nil)
+		require.NoError(t, err)
+		assert.Equal(t, []OrphanSubpackage{{
+			Subpackage: "lib-dev",
+			Origin:     "lib",
+			Reason:     "origin lib-1.0.0-r0 is missing from the graph",
+			Dependents: []string{"app"},
+		}}, orphans)
+	})
+
+	t.Run("origin changed since base", func(t *testing.T) {
+		headDir := "testdata/subpackages/head"
+		head, err := NewPackages(os.DirFS(headDir), headDir)
+		require.NoError(t, err)
+		graph, err := NewGraph(head, WithAllowUnresolved())
+		require.NoError(t, err)
+
+		orphans, err := graph.OrphanSubpackages(base)
+		require.NoError(t, err)
+		assert.Equal(t, []OrphanSubpackage{{
+			Subpackage: "lib-dev",
+			Origin:     "lib",
+			Reason:     "origin lib doesn't declare it anymore",
+			Dependents: []string{"app"},
+		}, {
+			Subpackage: "tool-extra",
+			Origin:     "tool",
+			Reason:     "origin tool was removed",
+			Dependents: []string{"app"},
+		}}, orphans)
+	})
+}
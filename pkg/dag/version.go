@@ -0,0 +1,18 @@
+package dag
+
+import (
+	"strings"
+
+	apkversion "github.com/knqyf263/go-apk-version"
+)
+
+// CompareVersions compares two apk versions the way apk does, returning -1 if a is older than b, 1 if it's newer,
+// and 0 if they are the same. Versions that aren't valid apk versions are compared as strings.
+func CompareVersions(a, b string) int {
+	va, erra := apkversion.NewVersion(a)
+	vb, errb := apkversion.NewVersion(b)
+	if erra != nil || errb != nil {
+		return strings.Compare(a, b)
+	}
+	return va.Compare(vb)
+}
@@ -0,0 +1,233 @@
+package dag
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Waves groups the local origin packages in the Graph into build waves. Every package in a wave only depends on
+// packages from earlier waves, or on packages that are not built locally, so the packages within a single wave
+// can be built independently of, and in parallel with, each other.
+//
+// Dependencies on local subpackages or provides count as dependencies on the origin package that builds them.
+// Within each wave, packages are sorted by name.
+func (g Graph) Waves() ([][]*Configuration, error) {
+	origins, deps, err := g.originDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	levels := make(map[string]int)
+	visiting := make(map[string]bool)
+	var stack []string
+
+	var level func(origin string) (int, error)
+	level = func(origin string) (int, error) {
+		if l, ok := levels[origin]; ok {
+			return l, nil
+		}
+		if visiting[origin] {
+			path := []string{origin}
+			for i := len(stack) - 1; i >= 0 && stack[i] != origin; i-- {
+				path = append([]string{stack[i]}, path...)
+			}
+			return 0, &CycleError{
+				Path: append([]string{origin}, path...),
+				Err:  errors.New("packages depend on each other through their subpackages"),
+			}
+		}
+		visiting[origin] = true
+		stack = append(stack, origin)
+		defer func() {
+			delete(visiting, origin)
+			stack = stack[:len(stack)-1]
+		}()
+
+		l := 0
+		for _, depHash := range deps[origin] {
+			dl, err := level(depHash)
+			if err != nil {
+				return 0, err
+			}
+			if dl+1 > l {
+				l = dl + 1
+			}
+		}
+		levels[origin] = l
+		return l, nil
+	}
+
+	var waves [][]*Configuration
+	for originHash, c := range origins {
+		l, err := level(originHash)
+		if err != nil {
+			return nil, err
+		}
+		for len(waves) <= l {
+			waves = append(waves, nil)
+		}
+		waves[l] = append(waves[l], c)
+	}
+
+	for _, wave := range waves {
+		sort.Slice(wave, func(i, j int) bool {
+			if wave[i].name == wave[j].name {
+				return wave[i].version < wave[j].version
+			}
+			return wave[i].name < wave[j].name
+		})
+	}
+	return waves, nil
+}
+
+// BuildDependencies returns the local origin packages every local origin package in the Graph directly depends on,
+// keyed by package, in the same terms as Waves: dependencies on subpackages or provides count as dependencies on the
+// origin package that builds them, and packages that are not built locally are left out.
+func (g Graph) BuildDependencies() (map[string][]string, error) {
+	origins, deps, err := g.originDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string][]string, len(origins))
+	for originHash, c := range origins {
+		names := make([]string, 0, len(deps[originHash]))
+		for _, depHash := range deps[originHash] {
+			names = append(names, origins[depHash].String())
+		}
+		sort.Strings(names)
+		out[c.String()] = names
+	}
+	return out, nil
+}
+
+// Dependents returns a subgraph of g with the local origin packages of the given names, and every local origin
+// package that depends on them, directly or transitively, in the same terms as Waves. It's the smallest set of
+// packages to rebuild after the given ones changed. Vertices of packages that are not built locally are kept, so
+// build environments still resolve.
+func (g Graph) Dependents(names ...string) (*Graph, error) {
+	return g.closure(names, true)
+}
+
+// Dependencies returns a subgraph of g with the local origin packages of the given names, and every local origin
+// package they depend on, directly or transitively, in the same terms as Waves. It's the set of packages to build
+// before the given ones can be built. Vertices of packages that are not built locally are kept, so build
+// environments still resolve.
+func (g Graph) Dependencies(names ...string) (*Graph, error) {
+	return g.closure(names, false)
+}
+
+// closure returns a subgraph of g with the local origin packages of the given names, and the local origin packages
+// that depend on them if dependents is set, or the ones they depend on otherwise.
+func (g Graph) closure(names []string, dependents bool) (*Graph, error) {
+	origins, deps, err := g.originDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	next := deps
+	if dependents {
+		next = make(map[string][]string)
+		for origin, originDeps := range deps {
+			for _, dep := range originDeps {
+				next[dep] = append(next[dep], origin)
+			}
+		}
+	}
+
+	keep := make(map[string]bool)
+	var walk func(origin string)
+	walk = func(origin string) {
+		if keep[origin] {
+			return
+		}
+		keep[origin] = true
+		for _, o := range next[origin] {
+			walk(o)
+		}
+	}
+	for _, name := range names {
+		found := false
+		for originHash, c := range origins {
+			if c.name == name {
+				found = true
+				walk(originHash)
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("package %q not found", name)
+		}
+	}
+
+	return g.Filter(func(p Package) bool {
+		c, ok := p.(*Configuration)
+		if !ok {
+			return true
+		}
+		return keep[packageHash(originOf(c))]
+	})
+}
+
+// originDependencies returns the origin packages of the local vertices in the Graph, keyed by hash, along with the
+// hashes of the origin packages each of them depends on.
+func (g Graph) originDependencies() (map[string]*Configuration, map[string][]string, error) {
+	adjacencyMap, err := g.Graph.AdjacencyMap()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the origin package vertex of every local vertex, keyed by hash
+	vertexOrigins := make(map[string]*Configuration)
+	for node := range adjacencyMap {
+		vertex, err := g.Graph.Vertex(node)
+		if err != nil {
+			return nil, nil, err
+		}
+		c, ok := vertex.(*Configuration)
+		if !ok {
+			continue
+		}
+		vertexOrigins[node] = originOf(c)
+	}
+
+	origins := make(map[string]*Configuration)
+	for node, c := range vertexOrigins {
+		if originHash := packageHash(c); node == originHash {
+			origins[originHash] = c
+		}
+	}
+
+	deps := make(map[string][]string)
+	for origin := range origins {
+		seen := make(map[string]bool)
+		for dep := range adjacencyMap[origin] {
+			depOrigin, ok := vertexOrigins[dep]
+			if !ok {
+				// not built locally
+				continue
+			}
+			depHash := packageHash(depOrigin)
+			if depHash == origin || seen[depHash] {
+				continue
+			}
+			if _, ok := origins[depHash]; !ok {
+				// the origin isn't part of this graph, e.g. in a subgraph
+				continue
+			}
+			seen[depHash] = true
+			deps[origin] = append(deps[origin], depHash)
+		}
+	}
+	return origins, deps, nil
+}
+
+// originOf returns the origin package of c, which is c itself unless c is a subpackage or provides.
+func originOf(c *Configuration) *Configuration {
+	return &Configuration{
+		Configuration: c.Configuration,
+		Path:          c.Path,
+		name:          c.Package.Name,
+		version:       fullVersion(&c.Package),
+	}
+}
@@ -0,0 +1,114 @@
+package dag
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaves(t *testing.T) {
+	testDir := "testdata/complex"
+	pkgs, err := NewPackages(os.DirFS(testDir), testDir)
+	require.NoError(t, err)
+	graph, err := NewGraph(pkgs, WithAllowUnresolved())
+	require.NoError(t, err)
+
+	waves, err := graph.Waves()
+	require.NoError(t, err)
+
+	var got [][]string
+	for _, wave := range waves {
+		var names []string
+		for _, c := range wave {
+			names = append(names, c.String())
+		}
+		got = append(got, names)
+	}
+	assert.Equal(t, [][]string{
+		{"one-1.2.3-r1", "one-1.2.8-r1"},
+		{"two-4.5.6-r1"},
+		{"three-other-7.8.9-r1"},
+	}, got)
+}
+
+func TestBuildDependencies(t *testing.T) {
+	testDir := "testdata/complex"
+	pkgs, err := NewPackages(os.DirFS(testDir), testDir)
+	require.NoError(t, err)
+	graph, err := NewGraph(pkgs, WithAllowUnresolved())
+	require.NoError(t, err)
+
+	deps, err := graph.BuildDependencies()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"one-1.2.3-r1":         {},
+		"one-1.2.8-r1":         {},
+		"two-4.5.6-r1":         {"one-1.2.3-r1"},
+		"three-other-7.8.9-r1": {"one-1.2.8-r1", "two-4.5.6-r1"},
+	}, deps)
+}
+
+func TestDependents(t *testing.T) {
+	testDir := "testdata/complex"
+	pkgs, err := NewPackages(os.DirFS(testDir), testDir)
+	require.NoError(t, err)
+	graph, err := NewGraph(pkgs, WithAllowUnresolved())
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		names []string
+		want  []string
+	}{
+		{names: []string{"two"}, want: []string{"two-4.5.6-r1", "three-other-7.8.9-r1"}},
+		{names: []string{"three-other"}, want: []string{"three-other-7.8.9-r1"}},
+		{names: []string{"one"}, want: []string{"one-1.2.3-r1", "one-1.2.8-r1", "two-4.5.6-r1", "three-other-7.8.9-r1"}},
+	} {
+		sub, err := graph.Dependents(tt.names...)
+		require.NoError(t, err)
+		waves, err := sub.Waves()
+		require.NoError(t, err)
+
+		var got []string
+		for _, wave := range waves {
+			for _, c := range wave {
+				got = append(got, c.String())
+			}
+		}
+		assert.Equal(t, tt.want, got, "dependents of %v", tt.names)
+	}
+
+	_, err = graph.Dependents("missing")
+	assert.Error(t, err)
+}
+
+func TestDependencies(t *testing.T) {
+	testDir := "testdata/complex"
+	pkgs, err := NewPackages(os.DirFS(testDir), testDir)
+	require.NoError(t, err)
+	graph, err := NewGraph(pkgs, WithAllowUnresolved())
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		names []string
+		want  []string
+	}{
+		{names: []string{"two"}, want: []string{"one-1.2.3-r1", "two-4.5.6-r1"}},
+		{names: []string{"three-other"}, want: []string{"one-1.2.3-r1", "one-1.2.8-r1", "two-4.5.6-r1", "three-other-7.8.9-r1"}},
+		{names: []string{"one"}, want: []string{"one-1.2.3-r1", "one-1.2.8-r1"}},
+	} {
+		sub, err := graph.Dependencies(tt.names...)
+		require.NoError(t, err)
+		waves, err := sub.Waves()
+		require.NoError(t, err)
+
+		var got []string
+		for _, wave := range waves {
+			for _, c := range wave {
+				got = append(got, c.String())
+			}
+		}
+		assert.Equal(t, tt.want, got, "dependencies of %v", tt.names)
+	}
+}
@@ -0,0 +1,313 @@
+// Package explain renders a melange config with what wolfictl knows about it inline: the values of its variables, the
+// packages its build dependencies resolve to, its latest upstream version, its lint findings and its advisories, for
+// a single screen overview of a package.
+package explain
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/openvex/go-vex/pkg/vex"
+	"gopkg.in/yaml.v3"
+
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/lint"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+	wolfiversions "github.com/wolfi-dev/wolfictl/pkg/versions"
+)
+
+// UpstreamFunc looks up the latest upstream version of a package, and the datasource it was found with.
+type UpstreamFunc func(ctx context.Context, name string) (version, datasource string, err error)
+
+// Options configure what Config gathers about a package. The sources left unset aren't consulted.
+type Options struct {
+	// Dir is the directory of melange configs the package is in.
+	Dir string
+	// Graph resolves the build dependencies of the package.
+	Graph *dag.Graph
+	// Upstream looks up the latest upstream version of the package.
+	Upstream UpstreamFunc
+	// Lint evaluates the rules of wolfictl lint for the package.
+	Lint bool
+	// Advisories are the advisories of the repository, counted by the status of their latest entry.
+	Advisories *configs.Index[advisoryconfigs.Document]
+}
+
+// Upstream is the latest upstream version of a package.
+type Upstream struct {
+	Version    string
+	Datasource string
+	// Outdated is set if Version is newer than the version of the config.
+	Outdated bool
+	Err      error
+}
+
+// Explanation is what's known about the config of a package.
+type Explanation struct {
+	Package string
+	Version string
+	// File is the path of the config, relative to the directory of configs.
+	File string
+
+	// Lines are the lines of the config, Annotations the notes on them by index.
+	Lines       []string
+	Annotations map[int][]string
+
+	Upstream *Upstream
+	// Lint are the findings of the lint rules, LintErr set if linting failed.
+	Lint    lint.EvalRuleErrors
+	LintErr error
+	// Advisories are the number of advisories of the package by the status of their latest entry, nil if they
+	// weren't counted.
+	Advisories map[vex.Status]int
+	// Dependencies is the number of build dependencies resolved, Unresolved the ones that couldn't be, and
+	// DependenciesErr set if they couldn't be resolved at all.
+	Dependencies    int
+	Unresolved      []string
+	DependenciesErr error
+}
+
+// Config explains the config of the named package.
+func Config(ctx context.Context, name string, o Options) (*Explanation, error) {
+	pkgs, err := melange.ReadPackageConfigs([]string{name}, o.Dir)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := pkgs[name]
+	if !ok {
+		return nil, fmt.Errorf("package %s not found in %s", name, o.Dir)
+	}
+	b, err := os.ReadFile(filepath.Join(p.Dir, p.Filename))
+	if err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", p.Filename, err)
+	}
+
+	e := &Explanation{
+		Package:     name,
+		Version:     fmt.Sprintf("%s-r%d", p.Config.Package.Version, p.Config.Package.Epoch),
+		File:        p.Filename,
+		Annotations: make(map[int][]string),
+	}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(nil, len(b)+1)
+	for sc.Scan() {
+		e.Lines = append(e.Lines, sc.Text())
+	}
+
+	vars, err := melange.Variables(&p.Config)
+	if err != nil {
+		return nil, err
+	}
+	e.annotateVariables(&root, vars)
+
+	if o.Graph != nil {
+		e.annotateDependencies(&root, o.Graph)
+	}
+	if o.Upstream != nil {
+		e.annotateUpstream(ctx, &root, p.Config, o.Upstream)
+	}
+	if o.Lint {
+		result, err := lint.New(lint.WithPath(o.Dir), lint.WithPackages(name), lint.WithContext(ctx)).Lint()
+		e.LintErr = err
+		for _, r := range result {
+			e.Lint = append(e.Lint, r.Errors...)
+		}
+	}
+	if o.Advisories != nil {
+		e.Advisories = make(map[vex.Status]int)
+		for _, doc := range o.Advisories.Select().WhereName(name).Configurations() {
+			for _, entries := range doc.Advisories {
+				if latest := advisory.Latest(entries); latest != nil {
+					e.Advisories[latest.Status]++
+				}
+			}
+		}
+	}
+	return e, nil
+}
+
+func (e *Explanation) annotate(line int, format string, args ...interface{}) {
+	// yaml.v3 counts lines from 1
+	e.Annotations[line-1] = append(e.Annotations[line-1], fmt.Sprintf(format, args...))
+}
+
+// annotateVariables notes the values of the scalars with variables, line by line for block scalars like the runs of
+// pipelines. Variables only defined in a pipeline, like ${{inputs.foo}}, or in a subpackage are left alone.
+func (e *Explanation) annotateVariables(n *yaml.Node, vars map[string]string) {
+	if n.Kind != yaml.ScalarNode {
+		for _, c := range n.Content {
+			e.annotateVariables(c, vars)
+		}
+		return
+	}
+	if !strings.Contains(n.Value, "${{") {
+		return
+	}
+	if n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+		if v, err := build.MutateStringFromMap(vars, n.Value); err == nil {
+			e.annotate(n.Line, "%s", v)
+		}
+		return
+	}
+	// the lines of a block scalar start below its indicator
+	for i, l := range strings.Split(n.Value, "\n") {
+		if !strings.Contains(l, "${{") {
+			continue
+		}
+		if v, err := build.MutateStringFromMap(vars, l); err == nil {
+			e.annotate(n.Line+1+i, "%s", strings.TrimSpace(v))
+		}
+	}
+}
+
+// annotateDependencies notes the package and repository every package of the build environment resolved to.
+func (e *Explanation) annotateDependencies(root *yaml.Node, g *dag.Graph) {
+	packages := mappingPath(root, "environment", "contents", "packages")
+	if packages == nil {
+		return
+	}
+	resolved, err := g.ResolvedDependencies(e.Package, e.Version)
+	if err != nil {
+		e.DependenciesErr = err
+		return
+	}
+	for _, dep := range packages.Content {
+		p, ok := resolved[dep.Value]
+		switch {
+		case !ok, !p.Resolved():
+			e.Unresolved = append(e.Unresolved, dep.Value)
+			e.annotate(dep.Line, "unresolved")
+		case p.Name() != dep.Value:
+			e.Dependencies++
+			e.annotate(dep.Line, "%s-%s from %s", p.Name(), p.Version(), p.Source())
+		default:
+			e.Dependencies++
+			e.annotate(dep.Line, "%s from %s", p.Version(), p.Source())
+		}
+	}
+}
+
+// annotateUpstream notes the latest upstream version next to the version of the package.
+func (e *Explanation) annotateUpstream(ctx context.Context, root *yaml.Node, cfg build.Configuration, upstream UpstreamFunc) {
+	e.Upstream = &Upstream{}
+	e.Upstream.Version, e.Upstream.Datasource, e.Upstream.Err = upstream(ctx, e.Package)
+	if e.Upstream.Err == nil && e.Upstream.Version != "" {
+		latest, lerr := wolfiversions.NewVersion(e.Upstream.Version)
+		current, cerr := wolfiversions.NewVersion(cfg.Package.Version)
+		e.Upstream.Outdated = lerr == nil && cerr == nil && latest.GreaterThan(current)
+	}
+	if n := mappingPath(root, "package", "version"); n != nil {
+		e.annotate(n.Line, "%s", e.Upstream)
+	}
+}
+
+func (u Upstream) String() string {
+	switch {
+	case u.Err != nil:
+		return fmt.Sprintf("upstream lookup failed: %s", u.Err)
+	case u.Version == "":
+		return "no upstream version found"
+	case u.Outdated:
+		return fmt.Sprintf("outdated, upstream has %s (%s)", u.Version, u.Datasource)
+	default:
+		return fmt.Sprintf("up to date with upstream %s (%s)", u.Version, u.Datasource)
+	}
+}
+
+// mappingPath returns the node at the given keys of nested mappings of a document, or nil if there's none.
+func mappingPath(n *yaml.Node, keys ...string) *yaml.Node {
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	for _, key := range keys {
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				next = n.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return n
+}
+
+// Render writes a summary of the explanation, followed by the config with the annotations below the lines they're
+// on, as comments indented like the lines so the config stays valid YAML.
+func (e *Explanation) Render(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s %s (%s)\n", e.Package, e.Version, e.File)
+	if e.Upstream != nil {
+		fmt.Fprintf(&b, "#   upstream:     %s\n", e.Upstream)
+	}
+	switch {
+	case e.DependenciesErr != nil:
+		fmt.Fprintf(&b, "#   build deps:   unable to resolve: %s\n", e.DependenciesErr)
+	case len(e.Unresolved) > 0:
+		fmt.Fprintf(&b, "#   build deps:   %d resolved, %d unresolved: %s\n", e.Dependencies, len(e.Unresolved), strings.Join(e.Unresolved, ", "))
+	case e.Dependencies > 0:
+		fmt.Fprintf(&b, "#   build deps:   %d resolved\n", e.Dependencies)
+	}
+	switch {
+	case e.LintErr != nil:
+		fmt.Fprintf(&b, "#   lint:         failed: %s\n", e.LintErr)
+	case len(e.Lint) > 0:
+		fmt.Fprintf(&b, "#   lint:         %d findings\n", len(e.Lint))
+		for _, f := range e.Lint {
+			fmt.Fprintf(&b, "#     - %s\n", f.Error)
+		}
+	}
+	if e.Advisories != nil {
+		fmt.Fprintf(&b, "#   advisories:   %s\n", advisoryCounts(e.Advisories))
+	}
+	b.WriteString("\n")
+
+	for i, l := range e.Lines {
+		b.WriteString(l)
+		b.WriteString("\n")
+		indent := l[:len(l)-len(strings.TrimLeft(l, " -"))]
+		indent = strings.ReplaceAll(indent, "-", " ")
+		for _, a := range e.Annotations[i] {
+			fmt.Fprintf(&b, "%s# => %s\n", indent, a)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func advisoryCounts(counts map[vex.Status]int) string {
+	if len(counts) == 0 {
+		return "none"
+	}
+	statuses := make([]string, 0, len(counts))
+	for s := range counts {
+		statuses = append(statuses, string(s))
+	}
+	sort.Strings(statuses)
+	parts := make([]string, 0, len(statuses))
+	for _, s := range statuses {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[vex.Status(s)], s))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,82 @@
+package explain
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const config = `package:
+  name: hello
+  version: 1.2.3
+  epoch: 1
+  description: hello world
+  copyright:
+    - license: Apache-2.0
+
+pipeline:
+  - uses: fetch
+    with:
+      uri: https://example.com/hello-${{package.version}}.tar.gz
+      expected-sha256: 0000000000000000000000000000000000000000000000000000000000000000
+  - runs: |
+      echo building
+      make DESTDIR=${{targets.destdir}} install
+`
+
+func writeConfig(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hello.yaml"), []byte(config), 0o600))
+	return dir
+}
+
+func TestConfig(t *testing.T) {
+	dir := writeConfig(t)
+	upstream := func(_ context.Context, name string) (string, string, error) {
+		assert.Equal(t, "hello", name)
+		return "1.3.0", "release-monitor", nil
+	}
+
+	e, err := Config(context.Background(), "hello", Options{Dir: dir, Upstream: upstream})
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3-r1", e.Version)
+	assert.Equal(t, "hello.yaml", e.File)
+
+	assert.Equal(t, []string{"outdated, upstream has 1.3.0 (release-monitor)"}, e.Annotations[2])
+	assert.Equal(t, []string{"https://example.com/hello-1.2.3.tar.gz"}, e.Annotations[11])
+	assert.Equal(t, []string{"make DESTDIR=/home/build/melange-out/hello install"}, e.Annotations[15])
+	assert.Empty(t, e.Annotations[14], "lines without variables aren't annotated")
+
+	var b strings.Builder
+	require.NoError(t, e.Render(&b))
+	out := b.String()
+	assert.Contains(t, out, "# hello 1.2.3-r1 (hello.yaml)\n")
+	assert.Contains(t, out, "  version: 1.2.3\n  # => outdated, upstream has 1.3.0 (release-monitor)\n")
+	assert.Contains(t, out, "      uri: https://example.com/hello-${{package.version}}.tar.gz\n      # => https://example.com/hello-1.2.3.tar.gz\n")
+
+	var v interface{}
+	assert.NoError(t, yaml.Unmarshal([]byte(out), &v), "the rendered config should stay valid YAML")
+}
+
+func TestConfig_upstreamError(t *testing.T) {
+	dir := writeConfig(t)
+	upstream := func(context.Context, string) (string, string, error) {
+		return "", "github", errors.New("rate limited")
+	}
+
+	e, err := Config(context.Background(), "hello", Options{Dir: dir, Upstream: upstream})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"upstream lookup failed: rate limited"}, e.Annotations[2])
+}
+
+func TestConfig_notFound(t *testing.T) {
+	_, err := Config(context.Background(), "missing", Options{Dir: writeConfig(t)})
+	assert.Error(t, err)
+}
@@ -72,6 +72,10 @@ func (o GitOptions) handleRateLimitList(action func(opt *github.ListOptions) (*g
 }
 
 func (o GitOptions) handleGitHubResponse(resp *github.Response, err error, action func() error) error {
+	if resp == nil {
+		// the request didn't get a response, e.g. the connection dropped
+		return err
+	}
 	if resp.StatusCode == http.StatusUnauthorized {
 		return fmt.Errorf("failed to auth with GitHub, does your personal access token have the repo scope? https://github.com/settings/tokens/new?scopes=repo. status code: %d", resp.StatusCode)
 	}
@@ -80,7 +84,13 @@ func (o GitOptions) handleGitHubResponse(resp *github.Response, err error, actio
 			isRateLimited, delay := o.checkRateLimiting(githubErr)
 			if isRateLimited {
 				o.Logger.Printf("retrying again later with %v second delay due to secondary rate limiting.", delay.Seconds())
-				time.Sleep(delay)
+				ctx := context.Background()
+				if resp.Request != nil {
+					ctx = resp.Request.Context()
+				}
+				if err := sleep(ctx, delay); err != nil {
+					return err
+				}
 				return action()
 			}
 			return githubErr
@@ -114,3 +124,15 @@ func (o GitOptions) checkRateLimiting(githubErr error) (bool, time.Duration) {
 	}
 	return isRateLimited, delay
 }
+
+// sleep waits for d, or until the context of the request being retried is canceled
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
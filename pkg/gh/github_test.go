@@ -1 +1,41 @@
 package gh
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenPullRequest_noResponse(t *testing.T) {
+	// a server that drops the connection, so the client gets an error without a response
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		conn.Close()
+	}))
+	defer testServer.Close()
+
+	client := github.NewClient(testServer.Client())
+	var err error
+	client.BaseURL, err = url.Parse(testServer.URL + "/")
+	require.NoError(t, err)
+
+	gitOptions := GitOptions{
+		GithubClient: client,
+		MaxRetries:   3,
+	}
+	_, err = gitOptions.OpenPullRequest(context.Background(), &NewPullRequest{
+		BasePullRequest: BasePullRequest{Owner: "cheese", RepoName: "crisps", Branch: "wolfictl-foo", PullRequestBaseBranch: "main"},
+		Title:           "foo/1.2.3 package update",
+	})
+	assert.Error(t, err)
+	var responseErr *github.ErrorResponse
+	assert.False(t, errors.As(err, &responseErr))
+}
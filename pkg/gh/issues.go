@@ -127,6 +127,18 @@ func (o GitOptions) LabelIssue(ctx context.Context, owner, repo string, number i
 	return err
 }
 
+// CloseIssue closes an issue, e.g. once the work it tracks moved elsewhere
+func (o GitOptions) CloseIssue(ctx context.Context, owner, repo string, number int) error {
+	ir := &github.IssueRequest{
+		State: github.String("closed"),
+	}
+	err := o.handleRateLimit(func() (*github.Response, error) {
+		_, resp, err := o.GithubClient.Issues.Edit(ctx, owner, repo, number, ir)
+		return resp, err
+	})
+	return err
+}
+
 func (o GitOptions) AddReactionIssue(ctx context.Context, i *Issues, number int, reaction string) error {
 	err := o.handleRateLimit(func() (*github.Response, error) {
 		_, resp, err := o.GithubClient.Reactions.CreateIssueReaction(ctx, i.Owner, i.RepoName, number, reaction)
@@ -2,6 +2,7 @@ package gh
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -105,3 +106,36 @@ func TestOpenIssue(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "https://github.com/cheese/crisps/issues/1", htmlURL)
 }
+
+func TestCloseIssue(t *testing.T) {
+	// Create a test server that simulates the GitHub API, recording the requested state
+	var method, path string
+	var body github.IssueRequest
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte(`{"number": 7, "state": "closed"}`))
+		assert.NoError(t, err)
+	}))
+	defer testServer.Close()
+
+	// Create a mock GitHub client
+	client := github.NewClient(testServer.Client())
+	var err error
+	client.BaseURL, err = url.Parse(testServer.URL + "/")
+	assert.NoError(t, err)
+
+	gitOptions := GitOptions{
+		GithubClient: client,
+		MaxRetries:   3,
+	}
+
+	err = gitOptions.CloseIssue(context.Background(), "cheese", "crisps", 7)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodPatch, method)
+	assert.Equal(t, "/repos/cheese/crisps/issues/7", path)
+	assert.Equal(t, "closed", body.GetState())
+}
@@ -2,6 +2,8 @@ package gh
 
 import (
 	"context"
+	"net/http"
+	"strings"
 
 	"github.com/google/go-github/v50/github"
 
@@ -21,7 +23,7 @@ type GetPullRequest struct {
 }
 
 // OpenPullRequest opens a pull request on GitHub
-func (o GitOptions) OpenPullRequest(pr *NewPullRequest) (*github.PullRequest, error) {
+func (o GitOptions) OpenPullRequest(ctx context.Context, pr *NewPullRequest) (*github.PullRequest, error) {
 	// Configure pull request options that the GitHub client accepts when making calls to open new pull requests
 	newPR := &github.NewPullRequest{
 		Title: github.String(pr.Title),
@@ -32,7 +34,7 @@ func (o GitOptions) OpenPullRequest(pr *NewPullRequest) (*github.PullRequest, er
 
 	var githubPR *github.PullRequest
 	err := o.handleRateLimit(func() (*github.Response, error) {
-		createdPR, resp, err := o.GithubClient.PullRequests.Create(context.Background(), pr.Owner, pr.RepoName, newPR)
+		createdPR, resp, err := o.GithubClient.PullRequests.Create(ctx, pr.Owner, pr.RepoName, newPR)
 		githubPR = createdPR
 		return resp, err
 	})
@@ -44,6 +46,40 @@ func (o GitOptions) OpenPullRequest(pr *NewPullRequest) (*github.PullRequest, er
 	return githubPR, nil
 }
 
+// EditPullRequest replaces the title and body of a pull request
+func (o GitOptions) EditPullRequest(ctx context.Context, owner, repo string, number int, title, body string) (*github.PullRequest, error) {
+	var githubPR *github.PullRequest
+	err := o.handleRateLimit(func() (*github.Response, error) {
+		editedPR, resp, err := o.GithubClient.PullRequests.Edit(ctx, owner, repo, number, &github.PullRequest{
+			Title: github.String(title),
+			Body:  github.String(body),
+		})
+		githubPR = editedPR
+		return resp, err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed editing pull request %d", number)
+	}
+
+	return githubPR, nil
+}
+
+// RequestReviewers requests the review of a pull request from GitHub users, and teams given as org/team
+func (o GitOptions) RequestReviewers(ctx context.Context, owner, repo string, number int, reviewers []string) error {
+	req := github.ReviewersRequest{}
+	for _, r := range reviewers {
+		if _, team, ok := strings.Cut(r, "/"); ok {
+			req.TeamReviewers = append(req.TeamReviewers, team)
+		} else {
+			req.Reviewers = append(req.Reviewers, r)
+		}
+	}
+	return o.handleRateLimit(func() (*github.Response, error) {
+		_, resp, err := o.GithubClient.PullRequests.RequestReviewers(ctx, owner, repo, number, req)
+		return resp, err
+	})
+}
+
 // ListPullRequests returns a list of pull requests for a given state using pagination
 func (o GitOptions) ListPullRequests(ctx context.Context, owner, repo, state string) ([]*github.PullRequest, error) {
 	openPullRequests := []*github.PullRequest{}
@@ -74,3 +110,57 @@ func (o GitOptions) ClosePullRequest(ctx context.Context, owner, repo string, nu
 
 	return err
 }
+
+// FetchPullRequest returns a single pull request by number
+func (o GitOptions) FetchPullRequest(ctx context.Context, owner, repo string, number int) (*github.PullRequest, error) {
+	var pr *github.PullRequest
+	err := o.handleRateLimit(func() (*github.Response, error) {
+		p, resp, err := o.GithubClient.PullRequests.Get(ctx, owner, repo, number)
+		pr = p
+		return resp, err
+	})
+
+	return pr, err
+}
+
+// ListPullRequestFiles returns the files changed by a pull request using pagination
+func (o GitOptions) ListPullRequestFiles(ctx context.Context, owner, repo string, number int) ([]*github.CommitFile, error) {
+	files := []*github.CommitFile{}
+
+	err := o.handleRateLimitList(func(opt *github.ListOptions) (*github.Response, error) {
+		fs, resp, err := o.GithubClient.PullRequests.ListFiles(ctx, owner, repo, number, opt)
+		files = append(files, fs...)
+		return resp, err
+	})
+
+	return files, err
+}
+
+// FetchFileContent returns the content of a file of a repository at ref, e.g. the base commit of a pull request, or
+// nil if the file doesn't exist there.
+func (o GitOptions) FetchFileContent(ctx context.Context, owner, repo, path, ref string) ([]byte, error) {
+	var file *github.RepositoryContent
+	var status int
+	err := o.handleRateLimit(func() (*github.Response, error) {
+		f, _, resp, err := o.GithubClient.Repositories.GetContents(ctx, owner, repo, path, &github.RepositoryContentGetOptions{Ref: ref})
+		file = f
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		return resp, err
+	})
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, errors.Errorf("%s is a directory", path)
+	}
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
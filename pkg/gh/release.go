@@ -53,7 +53,7 @@ func NewReleaseOptions() ReleaseOptions {
 const defaultStartVersion = "v0.0.0"
 
 // Release will create a new GitHub release
-func (o ReleaseOptions) Release() error {
+func (o ReleaseOptions) Release(ctx context.Context) error {
 	// get the latest git tag
 	current, err := wolfigit.GetVersionFromTag(o.Dir, 1)
 	if current == nil || err != nil {
@@ -84,7 +84,7 @@ func (o ReleaseOptions) Release() error {
 	}
 
 	// create the GitHub release
-	err = o.createGitHubRelease(next.Original())
+	err = o.createGitHubRelease(ctx, next.Original())
 	if err != nil {
 		return err
 	}
@@ -146,7 +146,7 @@ func (o ReleaseOptions) bumpReleaseVersion(current *version.Version) (*version.V
 }
 
 // createGitHubRelease creates a new release on GitHub
-func (o ReleaseOptions) createGitHubRelease(v string) error {
+func (o ReleaseOptions) createGitHubRelease(ctx context.Context, v string) error {
 	repo, err := git.PlainOpen(o.Dir)
 	if err != nil {
 		return err
@@ -162,7 +162,6 @@ func (o ReleaseOptions) createGitHubRelease(v string) error {
 		TagName: github.String(v),
 	}
 
-	ctx := context.Background()
 	release, _, err := o.GithubClient.Repositories.CreateRelease(ctx, gitURL.Organisation, gitURL.Name, input)
 	if err != nil {
 		return err
@@ -172,8 +171,7 @@ func (o ReleaseOptions) createGitHubRelease(v string) error {
 	return nil
 }
 
-func (o ReleaseOptions) GetReleaseURL(owner, repoName, v string) (string, error) {
-	ctx := context.Background()
+func (o ReleaseOptions) GetReleaseURL(ctx context.Context, owner, repoName, v string) (string, error) {
 	release, _, err := o.GithubClient.Repositories.GetReleaseByTag(ctx, owner, repoName, v)
 	if err != nil {
 		return "", errors.Wrapf(err, "failed to get github release for %s/%s tag %s", owner, repoName, v)
@@ -0,0 +1,121 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ChangedFiles returns the files under dir that changed in the git repository containing it: the ones changed in the
+// worktree, staged or not and untracked ones included, and, if base is set, the ones committed since the merge base of
+// base and HEAD, as a pull request against base would show them. Renamed files are returned by their new name, and
+// deleted files aren't. Paths are relative to dir.
+func ChangedFiles(ctx context.Context, dir, base string) ([]string, error) {
+	r, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository %s: %w", dir, err)
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git worktree: %w", err)
+	}
+	root := wt.Filesystem.Root()
+
+	var changed []string
+	if base != "" {
+		changed, err = committedChanges(ctx, r, base)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	status, err := wt.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git status: %w", err)
+	}
+	for path, s := range status {
+		if s.Staging != git.Unmodified || s.Worktree != git.Unmodified {
+			changed = append(changed, path)
+		}
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var files []string
+	for _, path := range changed {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		rel, err := filepath.Rel(abs, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || seen[rel] {
+			continue
+		}
+		seen[rel] = true
+		// Deleted files, and the old names of renamed ones, have nothing left to look at.
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		files = append(files, rel)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// committedChanges returns the paths of the files committed since the merge base of base and HEAD, by their name at
+// HEAD.
+func committedChanges(ctx context.Context, r *git.Repository, base string) ([]string, error) {
+	baseHash, err := r.ResolveRevision(plumbing.Revision(base))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve revision %s: %w", base, err)
+	}
+	baseCommit, err := r.CommitObject(*baseHash)
+	if err != nil {
+		return nil, err
+	}
+	head, err := r.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	headCommit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	mergeBases, err := headCommit.MergeBase(baseCommit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the merge base of %s and HEAD: %w", base, err)
+	}
+	if len(mergeBases) == 0 {
+		return nil, fmt.Errorf("%s and HEAD have no common ancestor", base)
+	}
+
+	from, err := mergeBases[0].Tree()
+	if err != nil {
+		return nil, err
+	}
+	to, err := headCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := object.DiffTreeWithOptions(ctx, from, to, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s and HEAD: %w", base, err)
+	}
+
+	var paths []string
+	for _, c := range changes {
+		// Deletions have no name at HEAD.
+		if c.To.Name != "" {
+			paths = append(paths, c.To.Name)
+		}
+	}
+	return paths, nil
+}
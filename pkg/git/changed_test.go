@@ -0,0 +1,72 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	commit := func(msg string) {
+		_, err := wt.Add(".")
+		require.NoError(t, err)
+		_, err = wt.Commit(msg, &git.CommitOptions{
+			All:    true,
+			Author: &object.Signature{Name: "John Doe", Email: "john@doe.org", When: time.Now()},
+		})
+		require.NoError(t, err)
+	}
+
+	write("modified.yaml", "package:\n  name: modified\n")
+	write("renamed.yaml", "package:\n  name: renamed\n  version: 1.0.0\n  description: a config that gets renamed\n")
+	write("deleted.yaml", "package:\n  name: deleted\n")
+	write("untouched.yaml", "package:\n  name: untouched\n")
+	write("sub/untouched.yaml", "package:\n  name: sub\n")
+	commit("initial")
+	base, err := r.Head()
+	require.NoError(t, err)
+
+	write("modified.yaml", "package:\n  name: modified\n  version: 2.0.0\n")
+	require.NoError(t, os.Rename(filepath.Join(dir, "renamed.yaml"), filepath.Join(dir, "new-name.yaml")))
+	require.NoError(t, os.Remove(filepath.Join(dir, "deleted.yaml")))
+	write("sub/changed.yaml", "package:\n  name: sub\n  version: 2.0.0\n")
+	commit("change configs")
+
+	write("untracked.yaml", "package:\n  name: untracked\n")
+	write("untouched.yaml", "package:\n  name: untouched\n  version: 1.0.0\n")
+
+	ctx := context.Background()
+
+	files, err := ChangedFiles(ctx, dir, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"untouched.yaml", "untracked.yaml"}, files, "the worktree changes")
+
+	files, err = ChangedFiles(ctx, dir, base.Hash().String())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"modified.yaml", "new-name.yaml", filepath.Join("sub", "changed.yaml"), "untouched.yaml", "untracked.yaml"}, files)
+
+	files, err = ChangedFiles(ctx, filepath.Join(dir, "sub"), base.Hash().String())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"changed.yaml"}, files, "paths are relative to the directory, and limited to it")
+
+	_, err = ChangedFiles(ctx, dir, "no-such-branch")
+	assert.Error(t, err)
+}
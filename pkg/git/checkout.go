@@ -0,0 +1,67 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// CheckoutRevision clones the git repository containing dir into a new temporary directory and checks out the
+// given revision there, leaving dir untouched. It returns the path of dir within the checkout, and a func that
+// removes the checkout once the caller is done with it.
+func CheckoutRevision(dir, revision string) (string, func(), error) {
+	r, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open git repository %s: %w", dir, err)
+	}
+
+	wt, err := r.Worktree()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get git worktree: %w", err)
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	rel, err := filepath.Rel(wt.Filesystem.Root(), abs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	hash, err := r.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to resolve revision %s: %w", revision, err)
+	}
+
+	tempDir, err := os.MkdirTemp("", "wolfictl-checkout")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary folder to check out %s into: %w", revision, err)
+	}
+
+	cleanup := func() { os.RemoveAll(tempDir) }
+
+	clone, err := git.PlainClone(tempDir, false, &git.CloneOptions{
+		URL:        wt.Filesystem.Root(),
+		NoCheckout: true,
+	})
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to clone %s into %s: %w", dir, tempDir, err)
+	}
+
+	cloneWt, err := clone.Worktree()
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to get git worktree: %w", err)
+	}
+
+	if err := cloneWt.Checkout(&git.CheckoutOptions{Hash: *hash}); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to check out %s: %w", revision, err)
+	}
+
+	return filepath.Join(tempDir, rel), cleanup, nil
+}
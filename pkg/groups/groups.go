@@ -0,0 +1,187 @@
+// Package groups reads named groups of packages, like gnome-core or k8s-controllers, so commands that take a list of
+// packages can be given a whole group at once.
+package groups
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile is where the groups of a repository of melange configs are defined. It's a dotfile so it isn't mistaken
+// for a melange config.
+const DefaultFile = ".package-groups.yaml"
+
+// Prefix marks a group where a package is expected, e.g. @gnome-core.
+const Prefix = "@"
+
+// Group is a named list of packages, which can include other groups.
+type Group struct {
+	Description string `yaml:"description,omitempty"`
+	// Packages are the names of the members, or groups prefixed with @ whose members are included.
+	Packages []string `yaml:"packages"`
+	// UpdateTogether makes wolfictl update bump the members of the group in a single pull request, for packages that
+	// can't be updated independently, e.g. texlive and its collections.
+	UpdateTogether bool `yaml:"update-together,omitempty"`
+}
+
+// Groups are the package groups of a repository by name.
+type Groups map[string]Group
+
+type file struct {
+	Groups Groups `yaml:"groups"`
+}
+
+// Read reads the groups defined in path. A missing file has no groups, so repositories that don't use groups don't
+// need one.
+func Read(path string) (Groups, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Groups{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	f := file{}
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("unable to parse package groups %s: %w", path, err)
+	}
+	if f.Groups == nil {
+		return Groups{}, nil
+	}
+	return f.Groups, nil
+}
+
+// ReadDir reads the groups defined in the DefaultFile of a repository of melange configs.
+func ReadDir(dir string) (Groups, error) {
+	return Read(filepath.Join(dir, DefaultFile))
+}
+
+// Names returns the names of the groups, sorted alphabetically.
+func (g Groups) Names() []string {
+	names := make([]string, 0, len(g))
+	for name := range g {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Expand replaces the groups in a list of packages, like @gnome-core, with their members, recursively. Packages keep
+// the order they're first given in, and appear once even if several groups include them.
+func (g Groups) Expand(packages []string) ([]string, error) {
+	var expanded []string
+	seen := make(map[string]bool)
+	var expand func(packages []string, path []string) error
+	expand = func(packages []string, path []string) error {
+		for _, p := range packages {
+			name, isGroup := strings.CutPrefix(p, Prefix)
+			if !isGroup {
+				if !seen[p] {
+					seen[p] = true
+					expanded = append(expanded, p)
+				}
+				continue
+			}
+			for _, parent := range path {
+				if parent == name {
+					return fmt.Errorf("package group %s includes itself: %s", name, strings.Join(append(path, name), " -> "))
+				}
+			}
+			group, ok := g[name]
+			if !ok {
+				return fmt.Errorf("unknown package group %q", name)
+			}
+			if err := expand(group.Packages, append(path[:len(path):len(path)], name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := expand(packages, nil); err != nil {
+		return nil, err
+	}
+	return expanded, nil
+}
+
+// Validate checks that every group can be expanded and that all of its members exist, according to exists, e.g. are
+// packages in the dag.
+func (g Groups) Validate(exists func(name string) bool) error {
+	var errs []error
+	for _, name := range g.Names() {
+		members, err := g.Expand([]string{Prefix + name})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(members) == 0 {
+			errs = append(errs, fmt.Errorf("package group %s has no packages", name))
+			continue
+		}
+		var missing []string
+		for _, m := range members {
+			if !exists(m) {
+				missing = append(missing, m)
+			}
+		}
+		if len(missing) > 0 {
+			errs = append(errs, fmt.Errorf("package group %s has packages that don't exist: %s", name, strings.Join(missing, ", ")))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ExpandExisting is like Expand, but fails if the groups have members that don't exist according to exists, e.g.
+// aren't packages in the dag, like Validate reports them. Packages given directly are left to the caller to check.
+func (g Groups) ExpandExisting(packages []string, exists func(name string) bool) ([]string, error) {
+	expanded, err := g.Expand(packages)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, p := range expanded {
+		if !slices.Contains(packages, p) && !exists(p) {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("package groups have packages that don't exist: %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// ExpandDir replaces the groups in a list of packages, e.g. given on the command line, with their members as defined
+// in the DefaultFile of dir, which must exist according to exists. The file is only read if the list has groups.
+func ExpandDir(dir string, packages []string, exists func(name string) bool) ([]string, error) {
+	hasGroup := false
+	for _, p := range packages {
+		if strings.HasPrefix(p, Prefix) {
+			hasGroup = true
+			break
+		}
+	}
+	if !hasGroup {
+		return packages, nil
+	}
+	g, err := ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	return g.ExpandExisting(packages, exists)
+}
+
+// ConfigExists reports whether a package has a melange config in dir, for the commands that work on the configs of
+// the members of groups rather than on the dag, e.g. bump.
+func ConfigExists(dir string) func(name string) bool {
+	return func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name+".yaml"))
+		return err == nil
+	}
+}
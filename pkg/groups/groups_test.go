@@ -0,0 +1,96 @@
+package groups
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		packages []string
+		// missing is a member of the groups that doesn't exist
+		missing string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:     "no groups",
+			packages: []string{"curl", "openssl"},
+			want:     []string{"curl", "openssl"},
+		},
+		{
+			name:     "nested groups keep the order and drop duplicates",
+			packages: []string{"curl", "@gnome-core", "glib"},
+			want:     []string{"curl", "glib", "gtk-4", "font-cantarell"},
+		},
+		{
+			name:     "several groups",
+			packages: []string{"@k8s-controllers", "@gnome-fonts"},
+			want:     []string{"cert-manager", "ingress-nginx", "font-cantarell", "glib"},
+		},
+		{
+			name:     "unknown group",
+			packages: []string{"@gnome-extra"},
+			wantErr:  `unknown package group "gnome-extra"`,
+		},
+		{
+			name:     "members that don't exist",
+			packages: []string{"font-noto", "@gnome-core"},
+			missing:  "gtk-4",
+			wantErr:  "package groups have packages that don't exist: gtk-4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandDir("testdata", tt.packages, func(name string) bool { return name != tt.missing })
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReadDir_updateTogether(t *testing.T) {
+	g, err := ReadDir("testdata")
+	require.NoError(t, err)
+	assert.True(t, g["k8s-controllers"].UpdateTogether)
+	assert.False(t, g["gnome-core"].UpdateTogether)
+}
+
+func TestReadDir_missing(t *testing.T) {
+	g, err := ReadDir(t.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, g)
+}
+
+func TestGroups_Validate(t *testing.T) {
+	g := Groups{
+		"gnome-core":  {Packages: []string{"glib", "gtk-4", "@gnome-fonts"}},
+		"gnome-fonts": {Packages: []string{"font-cantarell"}},
+		"loop-a":      {Packages: []string{"glib", "@loop-b"}},
+		"loop-b":      {Packages: []string{"@loop-a"}},
+		"empty":       {},
+	}
+	exists := func(name string) bool {
+		return name == "glib" || name == "font-cantarell"
+	}
+
+	err := g.Validate(exists)
+	require.Error(t, err)
+	assert.Equal(t, `package group empty has no packages
+package group gnome-core has packages that don't exist: gtk-4
+package group loop-a includes itself: loop-a -> loop-b -> loop-a
+package group loop-b includes itself: loop-b -> loop-a -> loop-b`, err.Error())
+
+	delete(g, "empty")
+	delete(g, "loop-a")
+	delete(g, "loop-b")
+	g["gnome-core"] = Group{Packages: []string{"glib", "@gnome-fonts"}}
+	assert.NoError(t, g.Validate(exists))
+}
@@ -0,0 +1,325 @@
+// Package history summarizes the track record of a package of a repository of melange configs: how often it's bumped
+// and reverted, how its builds went, its open advisories, how many packages depend on it and when the update bot last
+// proposed a new version of it, so reviewers get the context of a change to it at a glance.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/builder"
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+// bumpPattern matches the lines of the version and epoch of the package of a melange config, for git log -G
+const bumpPattern = `^  (version|epoch): `
+
+// Options configure what New gathers about a package. The sources left unset aren't consulted.
+type Options struct {
+	// Dir is the directory of melange configs the package is in, in a git repository.
+	Dir string
+	// Graph counts the packages that depend on the package.
+	Graph *dag.Graph
+	// Advisories are the advisories of the repository.
+	Advisories *configs.Index[advisoryconfigs.Document]
+	// BuildSummaries are the summaries of past builds, e.g. from the --summary-file of wolfictl build in CI, oldest
+	// first.
+	BuildSummaries []*builder.Summary
+}
+
+// Commit is a commit that changed the config of a package.
+type Commit struct {
+	Hash    string    `json:"hash"`
+	Date    time.Time `json:"date"`
+	Author  string    `json:"author"`
+	Subject string    `json:"subject"`
+}
+
+func (c Commit) String() string {
+	hash := c.Hash
+	if len(hash) > 7 {
+		hash = hash[:7]
+	}
+	return fmt.Sprintf("%s %s %s", c.Date.Format("2006-01-02"), hash, c.Subject)
+}
+
+// Summary is the track record of a package.
+type Summary struct {
+	Package string `json:"package"`
+	Version string `json:"version"`
+	// File is the path of the config, relative to the directory of configs.
+	File string `json:"file"`
+
+	// Commits is the number of commits that changed the config, Bumps the ones that changed its version or epoch,
+	// and Reverts the ones that reverted an earlier commit.
+	Commits int `json:"commits"`
+	Bumps   int `json:"bumps"`
+	Reverts int `json:"reverts"`
+	// BumpIntervalDays is the mean number of days between bumps, 0 with less than two of them.
+	BumpIntervalDays float64 `json:"bumpIntervalDays,omitempty"`
+	LastBump         *Commit `json:"lastBump,omitempty"`
+	// LastBotUpdate is the latest commit of wolfictl update, titled like "curl/8.1.2 package update".
+	LastBotUpdate *Commit `json:"lastBotUpdate,omitempty"`
+
+	// Builds and BuildFailures are the number of builds and failed builds of the package in the build summaries, and
+	// LastFailure the latest failed one.
+	Builds        int                  `json:"builds"`
+	BuildFailures int                  `json:"buildFailures"`
+	LastFailure   *builder.BuildResult `json:"lastFailure,omitempty"`
+
+	// OpenAdvisories are the IDs of the advisories whose latest status is affected or under investigation, nil if
+	// advisories weren't checked.
+	OpenAdvisories []string `json:"openAdvisories"`
+
+	// DirectDependents is the number of local packages that depend on the package to build, and Dependents the ones
+	// that do directly or transitively, which a change to it rebuilds. Both are -1 without a graph.
+	DirectDependents int `json:"directDependents"`
+	Dependents       int `json:"dependents"`
+}
+
+// New summarizes the track record of the named package.
+func New(name string, o Options) (*Summary, error) {
+	pkgs, err := melange.ReadPackageConfigs([]string{name}, o.Dir)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := pkgs[name]
+	if !ok {
+		return nil, fmt.Errorf("package %s not found in %s", name, o.Dir)
+	}
+	s := &Summary{
+		Package:          name,
+		Version:          fmt.Sprintf("%s-r%d", p.Config.Package.Version, p.Config.Package.Epoch),
+		File:             p.Filename,
+		DirectDependents: -1,
+		Dependents:       -1,
+	}
+
+	if err := s.addGitHistory(o.Dir); err != nil {
+		return nil, err
+	}
+	for _, summary := range o.BuildSummaries {
+		s.addBuilds(summary)
+	}
+	if o.Advisories != nil {
+		s.OpenAdvisories = []string{}
+		for _, doc := range o.Advisories.Select().WhereName(name).Configurations() {
+			for id, entries := range doc.Advisories {
+				if latest := advisory.Latest(entries); latest != nil && isOpen(latest.Status) {
+					s.OpenAdvisories = append(s.OpenAdvisories, id)
+				}
+			}
+		}
+		sort.Strings(s.OpenAdvisories)
+	}
+	if o.Graph != nil {
+		if err := s.countDependents(o.Graph); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func isOpen(status vex.Status) bool {
+	return status == vex.StatusUnderInvestigation || status == vex.StatusAffected
+}
+
+// addGitHistory counts the commits that changed the config of the package, excluding merge commits, from the output
+// of git log.
+func (s *Summary) addGitHistory(dir string) error {
+	commits, err := gitLog(dir, s.File)
+	if err != nil {
+		return err
+	}
+	bumps, err := gitLog(dir, s.File, "-G", bumpPattern)
+	if err != nil {
+		return err
+	}
+	bumped := make(map[string]bool, len(bumps))
+	for _, c := range bumps {
+		bumped[c.Hash] = true
+	}
+
+	// commits are newest first
+	var first, last *Commit
+	for i := range commits {
+		c := &commits[i]
+		s.Commits++
+		if strings.HasPrefix(c.Subject, `Revert "`) {
+			s.Reverts++
+		}
+		if bumped[c.Hash] {
+			s.Bumps++
+			if last == nil {
+				last = c
+			}
+			first = c
+		}
+		if s.LastBotUpdate == nil && isBotUpdate(s.Package, c.Subject) {
+			s.LastBotUpdate = c
+		}
+	}
+	s.LastBump = last
+	if s.Bumps > 1 {
+		s.BumpIntervalDays = last.Date.Sub(first.Date).Hours() / 24 / float64(s.Bumps-1)
+	}
+	return nil
+}
+
+// isBotUpdate tells if subject is the title of a commit of wolfictl update for the package, which is also the title of
+// its pull request, so squash merges add the number of the pull request to it
+func isBotUpdate(name, subject string) bool {
+	return strings.HasPrefix(subject, name+"/") && strings.Contains(subject, " package update")
+}
+
+// gitLog returns the non-merge commits that changed file, relative to dir, newest first.
+func gitLog(dir, file string, args ...string) ([]Commit, error) {
+	args = append([]string{"log", "--no-merges", "--format=%H%x1f%ct%x1f%an%x1f%s"}, args...)
+	cmd := exec.Command("git", append(args, "--", file)...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the history of %s from git log: %w", filepath.Join(dir, file), err)
+	}
+
+	var commits []Commit
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		fields := strings.SplitN(sc.Text(), "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		ts, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected commit time %q in git log: %w", fields[1], err)
+		}
+		commits = append(commits, Commit{Hash: fields[0], Date: time.Unix(ts, 0).UTC(), Author: fields[2], Subject: fields[3]})
+	}
+	return commits, sc.Err()
+}
+
+// addBuilds counts the builds of the package in a build summary. Skipped builds didn't run, and canceled ones didn't
+// finish, so aren't counted.
+func (s *Summary) addBuilds(summary *builder.Summary) {
+	for i := range summary.Results {
+		r := summary.Results[i]
+		if r.Package != s.Package || r.Status == builder.StatusSkipped || r.Status == builder.StatusCanceled {
+			continue
+		}
+		s.Builds++
+		if r.Status == builder.StatusFailed {
+			s.BuildFailures++
+			s.LastFailure = &r
+		}
+	}
+}
+
+// countDependents counts the local packages that depend on the package to build, in the same terms as the build
+// waves of the graph.
+func (s *Summary) countDependents(g *dag.Graph) error {
+	deps, err := g.BuildDependencies()
+	if err != nil {
+		return err
+	}
+	dependents := make(map[string][]string)
+	for pkg, pkgDeps := range deps {
+		for _, d := range pkgDeps {
+			dependents[d] = append(dependents[d], pkg)
+		}
+	}
+
+	origin := s.Package + "-" + s.Version
+	if _, ok := deps[origin]; !ok {
+		return fmt.Errorf("package %s not found in the graph", origin)
+	}
+	s.DirectDependents = len(dependents[origin])
+	seen := map[string]bool{origin: true}
+	stack := []string{origin}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, m := range dependents[n] {
+			if !seen[m] {
+				seen[m] = true
+				stack = append(stack, m)
+			}
+		}
+	}
+	s.Dependents = len(seen) - 1
+	return nil
+}
+
+// Format is the encoding of a summary.
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// Write writes the summary to w in the given format.
+func (s *Summary) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	case FormatText:
+		return s.writeText(w)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func (s *Summary) writeText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s (%s)\n", s.Package, s.Version, s.File)
+	fmt.Fprintf(&b, "  history:      %d commits, %d bumps, %d reverts\n", s.Commits, s.Bumps, s.Reverts)
+	switch {
+	case s.LastBump == nil:
+		b.WriteString("  bumps:        never bumped\n")
+	case s.BumpIntervalDays > 0:
+		fmt.Fprintf(&b, "  bumps:        every %.1f days, last %s\n", s.BumpIntervalDays, s.LastBump)
+	default:
+		fmt.Fprintf(&b, "  bumps:        last %s\n", s.LastBump)
+	}
+	if s.LastBotUpdate != nil {
+		fmt.Fprintf(&b, "  update bot:   last %s\n", s.LastBotUpdate)
+	} else {
+		b.WriteString("  update bot:   never updated\n")
+	}
+	switch {
+	case s.Builds == 0:
+	case s.LastFailure != nil:
+		fmt.Fprintf(&b, "  builds:       %d of %d failed, last %s on %s: %s\n", s.BuildFailures, s.Builds, s.LastFailure, s.LastFailure.Arch, s.LastFailure.Error)
+	default:
+		fmt.Fprintf(&b, "  builds:       %d, none failed\n", s.Builds)
+	}
+	if s.OpenAdvisories != nil {
+		if len(s.OpenAdvisories) == 0 {
+			b.WriteString("  advisories:   none open\n")
+		} else {
+			fmt.Fprintf(&b, "  advisories:   %d open: %s\n", len(s.OpenAdvisories), strings.Join(s.OpenAdvisories, ", "))
+		}
+	}
+	if s.Dependents >= 0 {
+		fmt.Fprintf(&b, "  dependents:   %d direct, %d in total\n", s.DirectDependents, s.Dependents)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
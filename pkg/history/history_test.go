@@ -0,0 +1,126 @@
+package history
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wolfi-dev/wolfictl/pkg/builder"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+const configTemplate = `package:
+  name: %s
+  version: %s
+  epoch: %d
+  description: %s
+
+environment:
+  contents:
+    packages:
+%s
+pipeline:
+  - runs: echo
+`
+
+func writeConfig(t *testing.T, dir, name, version string, epoch int, description string, deps ...string) {
+	var contents string
+	for _, d := range deps {
+		contents += "      - " + d + "\n"
+	}
+	b := fmt.Sprintf(configTemplate, name, version, epoch, description, contents)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(b), 0o600))
+}
+
+func TestNew(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 0
+	commit := func(subject string) {
+		_, err := wt.Add(".")
+		require.NoError(t, err)
+		_, err = wt.Commit(subject, &git.CommitOptions{
+			Author: &object.Signature{Name: "John Doe", Email: "john@doe.org", When: start.AddDate(0, 0, day)},
+		})
+		require.NoError(t, err)
+	}
+
+	writeConfig(t, dir, "lib", "1.0.0", 0, "a library")
+	writeConfig(t, dir, "app", "1.0.0", 0, "an app", "lib")
+	writeConfig(t, dir, "tool", "1.0.0", 0, "a tool", "app")
+	commit("add lib, app and tool")
+	day += 10
+	writeConfig(t, dir, "lib", "1.1.0", 0, "a library")
+	commit("lib/1.1.0 package update (#12)")
+	day += 10
+	writeConfig(t, dir, "lib", "1.1.0", 0, "a shared library")
+	commit("describe lib")
+	day += 10
+	writeConfig(t, dir, "lib", "1.1.0", 1, "a shared library")
+	commit("rebuild lib")
+	day += 10
+	writeConfig(t, dir, "lib", "1.1.0", 0, "a shared library")
+	commit(`Revert "rebuild lib"`)
+
+	pkgs, err := dag.NewPackages(os.DirFS(dir), dir)
+	require.NoError(t, err)
+	g, err := dag.NewGraph(pkgs, dag.WithAllowUnresolved())
+	require.NoError(t, err)
+	builds := &builder.Summary{Results: []builder.BuildResult{
+		{Package: "lib", Version: "1.1.0-r0", Arch: "x86_64", Status: builder.StatusFailed, ExitCode: 2, Error: "make failed"},
+		{Package: "lib", Version: "1.1.0-r0", Arch: "aarch64", Status: builder.StatusBuilt},
+		{Package: "app", Version: "1.0.0-r0", Arch: "x86_64", Status: builder.StatusSkipped},
+	}}
+
+	s, err := New("lib", Options{Dir: dir, Graph: g, BuildSummaries: []*builder.Summary{builds}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.1.0-r0", s.Version)
+	assert.Equal(t, 5, s.Commits)
+	assert.Equal(t, 4, s.Bumps, "the description change isn't a bump")
+	assert.Equal(t, 1, s.Reverts)
+	assert.InDelta(t, 40.0/3, s.BumpIntervalDays, 0.01)
+	require.NotNil(t, s.LastBump)
+	assert.Equal(t, `Revert "rebuild lib"`, s.LastBump.Subject)
+	require.NotNil(t, s.LastBotUpdate)
+	assert.Equal(t, "lib/1.1.0 package update (#12)", s.LastBotUpdate.Subject)
+
+	assert.Equal(t, 2, s.Builds, "skipped builds aren't counted")
+	assert.Equal(t, 1, s.BuildFailures)
+	require.NotNil(t, s.LastFailure)
+	assert.Equal(t, "x86_64", s.LastFailure.Arch)
+
+	assert.Nil(t, s.OpenAdvisories, "advisories weren't checked")
+	assert.Equal(t, 1, s.DirectDependents)
+	assert.Equal(t, 2, s.Dependents)
+
+	var b bytes.Buffer
+	require.NoError(t, s.Write(&b, FormatText))
+	assert.Contains(t, b.String(), "history:      5 commits, 4 bumps, 1 reverts")
+	assert.Contains(t, b.String(), "update bot:   last 2023-01-11")
+	assert.Contains(t, b.String(), "builds:       1 of 2 failed, last lib-1.1.0-r0 on x86_64: make failed")
+	assert.Contains(t, b.String(), "dependents:   1 direct, 2 in total")
+	assert.NotContains(t, b.String(), "advisories:")
+}
+
+func TestNew_notFound(t *testing.T) {
+	_, err := New("missing", Options{Dir: t.TempDir()})
+	assert.Error(t, err)
+}
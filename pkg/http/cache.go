@@ -0,0 +1,86 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ResponseCache keeps the successful responses of GET requests by URL, for clients that ask the same API about the
+// same thing several times in a run, e.g. for every version stream of a package. Requests for a URL being fetched
+// wait for it rather than sending their own, and responses are kept in memory, so it's meant for short lived runs
+// querying APIs, not for downloads.
+type ResponseCache struct {
+	mu        sync.Mutex
+	responses map[string]cachedResponse
+	group     singleflight.Group
+}
+
+type cachedResponse struct {
+	status     string
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
+// NewResponseCache returns an empty ResponseCache
+func NewResponseCache() *ResponseCache {
+	return &ResponseCache{responses: make(map[string]cachedResponse)}
+}
+
+// do returns the cached response of req, or sends it with fetch and caches the response if it's successful
+func (c *ResponseCache) do(req *http.Request, fetch func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	key := req.URL.String()
+	c.mu.Lock()
+	cached, ok := c.responses[key]
+	c.mu.Unlock()
+	if ok {
+		return cached.response(req), nil
+	}
+
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		resp, err := fetch(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		r := cachedResponse{
+			status:     resp.Status,
+			statusCode: resp.StatusCode,
+			header:     resp.Header,
+			body:       body,
+		}
+		if resp.StatusCode == http.StatusOK {
+			c.mu.Lock()
+			c.responses[key] = r
+			c.mu.Unlock()
+		}
+		return r, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(cachedResponse).response(req), nil
+}
+
+// response returns a copy of the cached response for req, with a body of its own
+func (r cachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        r.status,
+		StatusCode:    r.statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.body)),
+		ContentLength: int64(len(r.body)),
+		Request:       req,
+	}
+}
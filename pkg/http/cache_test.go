@@ -0,0 +1,55 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestRLHTTPClient_Cache(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, "hello "+r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewClient(rate.NewLimiter(rate.Inf, 1))
+	c.Cache = NewResponseCache()
+	get := func(path string) (int, string) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+path, http.NoBody)
+		require.NoError(t, err)
+		resp, err := c.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(b)
+	}
+
+	for i := 0; i < 3; i++ {
+		code, body := get("/a")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "hello /a", body)
+	}
+	assert.Equal(t, int32(1), requests.Load(), "repeated requests should be answered from the cache")
+
+	get("/b")
+	assert.Equal(t, int32(2), requests.Load())
+
+	for i := 0; i < 2; i++ {
+		code, _ := get("/missing")
+		assert.Equal(t, http.StatusNotFound, code)
+	}
+	assert.Equal(t, int32(4), requests.Load(), "failed requests shouldn't be cached")
+}
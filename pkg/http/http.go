@@ -3,6 +3,7 @@ package http
 import (
 	"context"
 	"net/http"
+	"sync/atomic"
 
 	"golang.org/x/time/rate"
 )
@@ -11,12 +12,43 @@ import (
 type RLHTTPClient struct {
 	Client      *http.Client
 	Ratelimiter *rate.Limiter
+	// Cache, if set, answers GET requests that were already sent through the client without waiting for the rate
+	// limit
+	Cache *ResponseCache
+
+	ctx context.Context
+}
+
+// WithContext returns a copy of the client whose requests are canceled along with ctx, unless they carry a context
+// of their own, including the ones waiting for the rate limit
+func (c *RLHTTPClient) WithContext(ctx context.Context) *RLHTTPClient {
+	cc := *c
+	cc.ctx = ctx
+	return &cc
+}
+
+// WithRateLimit returns a copy of the client with a rate limiter of its own, so its requests don't wait for the ones
+// of the client it was copied from
+func (c *RLHTTPClient) WithRateLimit(rl *rate.Limiter) *RLHTTPClient {
+	cc := *c
+	cc.Ratelimiter = rl
+	return &cc
 }
 
 // Do dispatches the HTTP request to the network
 func (c *RLHTTPClient) Do(req *http.Request) (*http.Response, error) {
-	// Comment out the below 5 lines to turn off ratelimiting
-	ctx := context.Background()
+	if c.ctx != nil && req.Context() == context.Background() {
+		req = req.WithContext(c.ctx)
+	}
+	if c.Cache != nil && req.Method == http.MethodGet {
+		return c.Cache.do(req, c.do)
+	}
+	return c.do(req)
+}
+
+func (c *RLHTTPClient) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	// Comment out the below 4 lines to turn off ratelimiting
 	err := c.Ratelimiter.Wait(ctx) // This is a blocking call. Honors the rate limit
 	if err != nil {
 		return nil, err
@@ -36,3 +68,33 @@ func NewClient(rl *rate.Limiter) *RLHTTPClient {
 	}
 	return c
 }
+
+// CountingTransport is an http.RoundTripper that counts the requests sent
+// through it, so callers can report how many API calls a run used.
+type CountingTransport struct {
+	Base  http.RoundTripper
+	count atomic.Int64
+}
+
+// NewCountingClient returns a copy of client whose transport counts requests
+func NewCountingClient(client *http.Client) (*http.Client, *CountingTransport) {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &CountingTransport{Base: base}
+	c := *client
+	c.Transport = t
+	return &c, t
+}
+
+// RoundTrip records the request and hands it to the base transport
+func (t *CountingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.count.Add(1)
+	return t.Base.RoundTrip(req)
+}
+
+// Count returns the number of requests sent so far
+func (t *CountingTransport) Count() int64 {
+	return t.count.Load()
+}
@@ -0,0 +1,156 @@
+package index
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gitlab.alpinelinux.org/alpine/go/repository"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+// PackageChange describes how a package differs between two indexes. An empty OldVersion or NewVersion means the
+// package was added or removed respectively. Otherwise, the dependencies and provides of the latest version of the
+// package in each index are compared, and any other versions that were added or removed are listed.
+type PackageChange struct {
+	Name       string `json:"name"`
+	OldVersion string `json:"oldVersion,omitempty"`
+	NewVersion string `json:"newVersion,omitempty"`
+
+	AddedVersions   []string `json:"addedVersions,omitempty"`
+	RemovedVersions []string `json:"removedVersions,omitempty"`
+
+	AddedDependencies   []string `json:"addedDependencies,omitempty"`
+	RemovedDependencies []string `json:"removedDependencies,omitempty"`
+	AddedProvides       []string `json:"addedProvides,omitempty"`
+	RemovedProvides     []string `json:"removedProvides,omitempty"`
+}
+
+func (c PackageChange) String() string {
+	var b strings.Builder
+	switch {
+	case c.OldVersion == "":
+		fmt.Fprintf(&b, "+ %s %s", c.Name, c.NewVersion)
+	case c.NewVersion == "":
+		fmt.Fprintf(&b, "- %s %s", c.Name, c.OldVersion)
+	case c.OldVersion != c.NewVersion:
+		fmt.Fprintf(&b, "~ %s %s -> %s", c.Name, c.OldVersion, c.NewVersion)
+	default:
+		fmt.Fprintf(&b, "~ %s %s", c.Name, c.NewVersion)
+	}
+	for _, l := range []struct {
+		sign, kind string
+		items      []string
+	}{
+		{"+", "version", c.AddedVersions},
+		{"-", "version", c.RemovedVersions},
+		{"+", "depends", c.AddedDependencies},
+		{"-", "depends", c.RemovedDependencies},
+		{"+", "provides", c.AddedProvides},
+		{"-", "provides", c.RemovedProvides},
+	} {
+		for _, item := range l.items {
+			fmt.Fprintf(&b, "\n    %s %s %s", l.sign, l.kind, item)
+		}
+	}
+	return b.String()
+}
+
+// Diff compares two indexes and returns the packages that were added, removed, or changed, sorted by name. Packages
+// whose versions, and latest version's dependencies and provides, are the same in both indexes are omitted.
+func Diff(old, current *repository.ApkIndex) []PackageChange {
+	oldByName := packagesByName(old)
+	newByName := packagesByName(current)
+
+	var changes []PackageChange
+	for name, o := range oldByName {
+		n, ok := newByName[name]
+		if !ok {
+			changes = append(changes, PackageChange{Name: name, OldVersion: latest(o).Version})
+			continue
+		}
+		if c, changed := diffPackage(name, o, n); changed {
+			changes = append(changes, c)
+		}
+	}
+	for name, n := range newByName {
+		if _, ok := oldByName[name]; !ok {
+			changes = append(changes, PackageChange{Name: name, NewVersion: latest(n).Version})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// diffPackage compares the versions of a package in two indexes, and reports whether they differ.
+func diffPackage(name string, old, current []*repository.Package) (PackageChange, bool) {
+	o, n := latest(old), latest(current)
+	c := PackageChange{
+		Name:       name,
+		OldVersion: o.Version,
+		NewVersion: n.Version,
+	}
+	c.AddedVersions, c.RemovedVersions = diffStrings(versions(old), versions(current))
+	c.AddedDependencies, c.RemovedDependencies = diffStrings(o.Dependencies, n.Dependencies)
+	c.AddedProvides, c.RemovedProvides = diffStrings(o.Provides, n.Provides)
+
+	changed := c.OldVersion != c.NewVersion ||
+		len(c.AddedVersions)+len(c.RemovedVersions)+
+			len(c.AddedDependencies)+len(c.RemovedDependencies)+
+			len(c.AddedProvides)+len(c.RemovedProvides) > 0
+	return c, changed
+}
+
+func packagesByName(idx *repository.ApkIndex) map[string][]*repository.Package {
+	m := make(map[string][]*repository.Package)
+	for _, p := range idx.Packages {
+		m[p.Name] = append(m[p.Name], p)
+	}
+	return m
+}
+
+// latest returns the highest version of pkgs, which must not be empty.
+func latest(pkgs []*repository.Package) *repository.Package {
+	l := pkgs[0]
+	for _, p := range pkgs[1:] {
+		if dag.CompareVersions(p.Version, l.Version) > 0 {
+			l = p
+		}
+	}
+	return l
+}
+
+func versions(pkgs []*repository.Package) []string {
+	v := make([]string, 0, len(pkgs))
+	for _, p := range pkgs {
+		v = append(v, p.Version)
+	}
+	return v
+}
+
+// diffStrings returns the strings only in current, and the ones only in old, each sorted.
+func diffStrings(old, current []string) (added, removed []string) {
+	inOld := make(map[string]bool, len(old))
+	for _, s := range old {
+		inOld[s] = true
+	}
+	inNew := make(map[string]bool, len(current))
+	for _, s := range current {
+		inNew[s] = true
+		if !inOld[s] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range old {
+		if !inNew[s] {
+			removed = append(removed, s)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
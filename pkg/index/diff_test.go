@@ -0,0 +1,67 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+)
+
+func TestDiff(t *testing.T) {
+	old := &repository.ApkIndex{
+		Packages: []*repository.Package{
+			{Name: "curl", Version: "8.0.1-r0", Dependencies: []string{"so:libssl.so.3"}},
+			{Name: "curl", Version: "8.1.0-r0", Dependencies: []string{"so:libssl.so.3"}, Provides: []string{"cmd:curl=8.1.0-r0"}},
+			{Name: "glibc", Version: "2.37-r1", Provides: []string{"so:libc.so.6=6"}},
+			{Name: "libfoo", Version: "1.0-r0"},
+			{Name: "unchanged", Version: "1.0-r0", Dependencies: []string{"a", "b"}},
+		},
+	}
+	current := &repository.ApkIndex{
+		Packages: []*repository.Package{
+			{Name: "curl", Version: "8.1.0-r0", Dependencies: []string{"so:libssl.so.3"}, Provides: []string{"cmd:curl=8.1.0-r0"}},
+			{Name: "curl", Version: "8.1.1-r0", Dependencies: []string{"so:libssl.so.3", "so:libz.so.1"}, Provides: []string{"cmd:curl=8.1.1-r0"}},
+			{Name: "glibc", Version: "2.37-r1", Provides: []string{"so:libc.so.6=6", "ld-linux"}},
+			{Name: "libbar", Version: "2.0-r0"},
+			{Name: "unchanged", Version: "1.0-r0", Dependencies: []string{"b", "a"}},
+		},
+	}
+
+	assert.Equal(t, []PackageChange{{
+		Name:              "curl",
+		OldVersion:        "8.1.0-r0",
+		NewVersion:        "8.1.1-r0",
+		AddedVersions:     []string{"8.1.1-r0"},
+		RemovedVersions:   []string{"8.0.1-r0"},
+		AddedDependencies: []string{"so:libz.so.1"},
+		AddedProvides:     []string{"cmd:curl=8.1.1-r0"},
+		RemovedProvides:   []string{"cmd:curl=8.1.0-r0"},
+	}, {
+		Name:          "glibc",
+		OldVersion:    "2.37-r1",
+		NewVersion:    "2.37-r1",
+		AddedProvides: []string{"ld-linux"},
+	}, {
+		Name:       "libbar",
+		NewVersion: "2.0-r0",
+	}, {
+		Name:       "libfoo",
+		OldVersion: "1.0-r0",
+	}}, Diff(old, current))
+
+	assert.Empty(t, Diff(old, old))
+}
+
+func TestPackageChange_String(t *testing.T) {
+	assert.Equal(t, "+ libbar 2.0-r0", PackageChange{Name: "libbar", NewVersion: "2.0-r0"}.String())
+	assert.Equal(t, "- libfoo 1.0-r0", PackageChange{Name: "libfoo", OldVersion: "1.0-r0"}.String())
+	assert.Equal(t, `~ curl 8.1.0-r0 -> 8.1.1-r0
+    + version 8.1.1-r0
+    - depends so:libssl.so.1.1`, PackageChange{
+		Name:                "curl",
+		OldVersion:          "8.1.0-r0",
+		NewVersion:          "8.1.1-r0",
+		AddedVersions:       []string{"8.1.1-r0"},
+		RemovedDependencies: []string{"so:libssl.so.1.1"},
+	}.String())
+}
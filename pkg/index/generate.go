@@ -0,0 +1,110 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	melange "chainguard.dev/melange/pkg/cli"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+)
+
+// ArchiveName is the name of the index in each architecture directory of a repository.
+const ArchiveName = "APKINDEX.tar.gz"
+
+// FromDirectory returns an index of the apks in dir, e.g. ./packages/x86_64.
+func FromDirectory(dir string) (*repository.ApkIndex, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &repository.ApkIndex{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".apk") {
+			continue
+		}
+		apk, err := parsePackage(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", e.Name(), err)
+		}
+		idx.Packages = append(idx.Packages, apk)
+	}
+	Sort(idx)
+	return idx, nil
+}
+
+func parsePackage(path string) (*repository.Package, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	apk, err := repository.ParsePackage(f)
+	if err != nil {
+		return nil, err
+	}
+	apk.Size = uint64(fi.Size())
+	return apk, nil
+}
+
+// Sort orders the packages of idx by name, then version.
+func Sort(idx *repository.ApkIndex) {
+	sort.Slice(idx.Packages, func(i, j int) bool {
+		if idx.Packages[i].Name == idx.Packages[j].Name {
+			return idx.Packages[i].Version < idx.Packages[j].Version
+		}
+		return idx.Packages[i].Name < idx.Packages[j].Name
+	})
+}
+
+// Write archives idx to path, and signs it with signingKey unless that's empty. The index at path is only replaced
+// once the new one is complete, so a failed or canceled write leaves the previous one in place.
+func Write(ctx context.Context, idx *repository.ApkIndex, path, signingKey string) error {
+	r, err := repository.ArchiveFromIndex(idx)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if signingKey != "" {
+		if err := melange.SignIndexCmd(ctx, signingKey, tmp); err != nil {
+			return fmt.Errorf("error signing index: %w", err)
+		}
+	}
+	if err := os.Chmod(tmp, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Generate writes the index of the apks in dir to its APKINDEX.tar.gz, replacing whatever was there.
+func Generate(ctx context.Context, dir, signingKey string) error {
+	idx, err := FromDirectory(dir)
+	if err != nil {
+		return err
+	}
+	return Write(ctx, idx, filepath.Join(dir, ArchiveName), signingKey)
+}
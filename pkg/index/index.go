@@ -1,6 +1,7 @@
 package index
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,11 +11,22 @@ import (
 	"gitlab.alpinelinux.org/alpine/go/repository"
 )
 
-func Index(arch, repo string) (*repository.ApkIndex, error) {
-	var rc io.ReadCloser
+func Index(ctx context.Context, arch, repo string) (*repository.ApkIndex, error) {
 	if strings.HasPrefix(repo, "http://") || strings.HasPrefix(repo, "https://") {
-		url := fmt.Sprintf("%s/%s/APKINDEX.tar.gz", repo, arch)
-		resp, err := http.Get(url) //nolint:gosec
+		return Open(ctx, fmt.Sprintf("%s/%s/APKINDEX.tar.gz", repo, arch))
+	}
+	return Open(ctx, repo)
+}
+
+// Open reads the APKINDEX.tar.gz at src, a URL or a local path. Downloads are canceled along with ctx.
+func Open(ctx context.Context, src string) (*repository.ApkIndex, error) {
+	var rc io.ReadCloser
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, http.NoBody)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
 			return nil, err
 		}
@@ -24,13 +36,13 @@ func Index(arch, repo string) (*repository.ApkIndex, error) {
 			if err != nil {
 				return nil, err
 			}
-			return nil, fmt.Errorf("GET %s (%d): %s", url, resp.StatusCode, b)
+			return nil, fmt.Errorf("GET %s (%d): %s", src, resp.StatusCode, b)
 		}
 		rc = resp.Body
 	} else {
-		f, err := os.Open(repo)
+		f, err := os.Open(src)
 		if err != nil {
-			return nil, fmt.Errorf("opening %q: %w", repo, err)
+			return nil, fmt.Errorf("opening %q: %w", src, err)
 		}
 		defer f.Close()
 		rc = f
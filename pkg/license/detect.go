@@ -0,0 +1,202 @@
+package license
+
+import (
+	"errors"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Detection is a license found in a license file of a source tree.
+type Detection struct {
+	// License is an SPDX license identifier. The texts and notices of GNU licenses don't tell apart the -only and
+	// -or-later licenses, so they're found as the deprecated identifiers without either, e.g. GPL-2.0.
+	License string
+	// File is the path of the license file in the source tree.
+	File string
+}
+
+// signature is a license, and phrases of its text or of its notices that tell it apart from the others.
+type signature struct {
+	license string
+	phrases []string
+}
+
+// signatures are matched in order, so the BSD licenses with more clauses come before the ones with less, see
+// supersedes
+var signatures = []signature{
+	{"AGPL-3.0", []string{"gnu affero general public license version 3 19 november 2007"}},
+	{"AGPL-3.0", []string{"under the terms of the gnu affero general public license as published by the free software foundation either version 3"}},
+	{"GPL-2.0", []string{"gnu general public license version 2 june 1991"}},
+	{"GPL-2.0", []string{"under the terms of the gnu general public license as published by the free software foundation either version 2"}},
+	{"GPL-3.0", []string{"gnu general public license version 3 29 june 2007"}},
+	{"GPL-3.0", []string{"under the terms of the gnu general public license as published by the free software foundation either version 3"}},
+	{"LGPL-2.0", []string{"gnu library general public license version 2 june 1991"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license version 2 1 february 1999"}},
+	{"LGPL-2.1", []string{"under the terms of the gnu lesser general public license as published by the free software foundation either version 2 1"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license version 3 29 june 2007"}},
+	{"LGPL-3.0", []string{"under the terms of the gnu lesser general public license as published by the free software foundation either version 3"}},
+	{"Apache-2.0", []string{"apache license version 2 0"}},
+	{"MPL-2.0", []string{"mozilla public license version 2 0"}},
+	{"EPL-1.0", []string{"eclipse public license v 1 0"}},
+	{"EPL-2.0", []string{"eclipse public license v 2 0"}},
+	{"BSL-1.0", []string{"boost software license version 1 0"}},
+	{"Artistic-2.0", []string{"the artistic license 2 0"}},
+	{"CC0-1.0", []string{"cc0 1 0 universal"}},
+	{"PSF-2.0", []string{"python software foundation license version 2"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+	{"MIT", []string{"permission is hereby granted free of charge to any person obtaining a copy", "the above copyright notice and this permission notice shall be included"}},
+	{"ISC", []string{"permission to use copy modify and or distribute this software for any purpose with or without fee is hereby granted"}},
+	{"Zlib", []string{"in no event will the authors be held liable for any damages arising from the use of this software", "altered source versions must be plainly marked as such"}},
+	{"BSD-4-Clause", []string{"redistribution and use in source and binary forms with or without modification are permitted", "all advertising materials mentioning features or use of this software must display"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms with or without modification are permitted", "may be used to endorse or promote products derived from this software without specific prior written permission"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms with or without modification are permitted"}},
+}
+
+// supersedes are the licenses whose clauses are all in the text of a license, so its signature matching stands for
+// theirs too
+var supersedes = map[string][]string{
+	"BSD-4-Clause": {"BSD-3-Clause", "BSD-2-Clause"},
+	"BSD-3-Clause": {"BSD-2-Clause"},
+}
+
+var (
+	// licenseFile matches the names of the files licenses are usually in, e.g. LICENSE, COPYING.LIB or LICENSE-MIT
+	licenseFile = regexp.MustCompile(`(?i)^(un)?(licen[cs]e|copying|copyright)([-._].*)?$`)
+	// spdxTag matches the SPDX license identifiers some license files have instead of, or on top of, a license text
+	spdxTag = regexp.MustCompile(`SPDX-License-Identifier:\s*(.+)`)
+	// nonWords are what normalized texts don't keep between words: punctuation, comment markers and whitespace
+	nonWords = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Detect returns the licenses of the license files at the root of a source tree, sorted by file and license. The
+// licenses are found by the phrases that tell them apart in their texts and notices, compared once case, punctuation
+// and line breaks are normalized, or by the SPDX license identifiers the files have.
+func Detect(fsys fs.FS) ([]Detection, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	var detected []Detection
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !licenseFile.MatchString(e.Name()) {
+			continue
+		}
+		text, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range detectText(string(text)) {
+			detected = append(detected, Detection{License: l, File: e.Name()})
+		}
+	}
+	return detected, nil
+}
+
+// detectText returns the licenses of the text of a license file, sorted.
+func detectText(text string) []string {
+	found := make(map[string]bool)
+	for _, m := range spdxTag.FindAllStringSubmatch(text, -1) {
+		ids, err := Licenses(m[1])
+		if err != nil {
+			continue
+		}
+		for _, id := range ids {
+			found[id] = true
+		}
+	}
+
+	normalized := " " + strings.TrimSpace(nonWords.ReplaceAllString(strings.ToLower(text), " ")) + " "
+	superseded := make(map[string]bool)
+	for _, s := range signatures {
+		if superseded[s.license] {
+			continue
+		}
+		matches := true
+		for _, p := range s.phrases {
+			if !strings.Contains(normalized, " "+p+" ") {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+		found[s.license] = true
+		for _, l := range supersedes[s.license] {
+			superseded[l] = true
+		}
+	}
+
+	licenses := make([]string, 0, len(found))
+	for l := range found {
+		licenses = append(licenses, l)
+	}
+	sort.Strings(licenses)
+	return licenses
+}
+
+// Licenses returns the license identifiers of an SPDX license expression, normalized, in order of appearance and
+// without their exceptions.
+func Licenses(expression string) ([]string, error) {
+	n, _, err := parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, l := range n.licenses() {
+		id, _, _ := strings.Cut(l, " WITH ")
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Mismatches compares the licenses of the declared SPDX license expressions with the ones detected in the sources.
+// It returns the detected licenses that aren't declared, and the declared licenses that Detect can find but didn't.
+// Licenses match regardless of -only, -or-later and +, which the texts of GNU licenses don't tell.
+func Mismatches(declared []string, detected []Detection) (undeclared []Detection, undetected []string, err error) {
+	declaredFamilies := make(map[string]bool)
+	var declaredIDs []string
+	for _, expression := range declared {
+		ids, err := Licenses(expression)
+		if errors.Is(err, ErrEmptyExpression) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, id := range ids {
+			declaredFamilies[family(id)] = true
+			declaredIDs = append(declaredIDs, id)
+		}
+	}
+
+	detectedFamilies := make(map[string]bool)
+	for _, d := range detected {
+		detectedFamilies[family(d.License)] = true
+		if !declaredFamilies[family(d.License)] {
+			undeclared = append(undeclared, d)
+		}
+	}
+	detectable := make(map[string]bool)
+	for _, s := range signatures {
+		detectable[family(s.license)] = true
+	}
+	seen := make(map[string]bool)
+	for _, id := range declaredIDs {
+		f := family(id)
+		if detectable[f] && !detectedFamilies[f] && !seen[id] {
+			undetected = append(undetected, id)
+		}
+		seen[id] = true
+	}
+	return undeclared, undetected, nil
+}
+
+// family returns a license identifier without the -only, -or-later or + that tell which versions of the license apply
+func family(id string) string {
+	id = strings.TrimSuffix(id, "+")
+	id = strings.TrimSuffix(id, "-only")
+	return strings.TrimSuffix(id, "-or-later")
+}
@@ -0,0 +1,115 @@
+package license
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	mitText = `MIT License
+
+Copyright (c) 2023 Example
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+`
+	bsd3Text = `Copyright (c) 2023, Example
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice, this
+   list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its
+   contributors may be used to endorse or promote products derived from
+   this software without specific prior written permission.
+`
+	gplNotice = `/*
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * (at your option) any later version.
+ */
+`
+)
+
+func TestDetect(t *testing.T) {
+	fsys := fstest.MapFS{
+		"LICENSE-MIT":    {Data: []byte(mitText)},
+		"LICENSE.apache": {Data: []byte("                                 Apache License\n                           Version 2.0, January 2004\n")},
+		"COPYING":        {Data: []byte(gplNotice)},
+		"copyright":      {Data: []byte(bsd3Text)},
+		"UNLICENSE":      {Data: []byte("SPDX-License-Identifier: Unlicense OR mit\n")},
+		"README.md":      {Data: []byte(mitText)},
+		"docs/LICENSE":   {Data: []byte(bsd3Text)},
+	}
+
+	detected, err := Detect(fsys)
+	require.NoError(t, err)
+	assert.Equal(t, []Detection{
+		{License: "GPL-2.0", File: "COPYING"},
+		{License: "MIT", File: "LICENSE-MIT"},
+		{License: "Apache-2.0", File: "LICENSE.apache"},
+		{License: "MIT", File: "UNLICENSE"},
+		{License: "Unlicense", File: "UNLICENSE"},
+		{License: "BSD-3-Clause", File: "copyright"},
+	}, detected)
+}
+
+func TestMismatches(t *testing.T) {
+	detected := []Detection{
+		{License: "GPL-2.0", File: "COPYING"},
+		{License: "MIT", File: "LICENSE-MIT"},
+	}
+	tests := []struct {
+		name       string
+		declared   []string
+		undeclared []Detection
+		undetected []string
+	}{
+		{
+			name:     "match",
+			declared: []string{"GPL-2.0-or-later AND MIT"},
+		},
+		{
+			name:     "versions of GNU licenses",
+			declared: []string{"GPL-2.0-only", "MIT"},
+		},
+		{
+			name:       "undeclared",
+			declared:   []string{"MIT"},
+			undeclared: []Detection{{License: "GPL-2.0", File: "COPYING"}},
+		},
+		{
+			name:       "undetected",
+			declared:   []string{"GPL-2.0-or-later AND MIT AND Apache-2.0 AND LicenseRef-Custom"},
+			undetected: []string{"Apache-2.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			undeclared, undetected, err := Mismatches(tt.declared, detected)
+			require.NoError(t, err)
+			assert.Equal(t, tt.undeclared, undeclared)
+			assert.Equal(t, tt.undetected, undetected)
+		})
+	}
+
+	_, _, err := Mismatches([]string{"MIT AND"}, detected)
+	assert.Error(t, err)
+}
@@ -0,0 +1,183 @@
+package license
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+)
+
+// Status is how usable the license of a copyright statement is for compliance.
+type Status string
+
+const (
+	// StatusValid is an expression of licenses on the SPDX License List, or user defined ones.
+	StatusValid Status = "valid"
+	// StatusUnknown is an expression using identifiers that aren't on the SPDX License List.
+	StatusUnknown Status = "unknown"
+	// StatusInvalid is a license that isn't an SPDX license expression.
+	StatusInvalid Status = "invalid"
+	// StatusMissing is a package, or a copyright statement of it, without a license.
+	StatusMissing Status = "missing"
+)
+
+// Entry is the license of a copyright statement of a package.
+type Entry struct {
+	Package  string `json:"package"`
+	Version  string `json:"version"`
+	Declared string `json:"declared"`
+	// Normalized is the canonical form of the declared license, see Normalize. It's empty unless the declared
+	// license is an SPDX license expression.
+	Normalized string   `json:"normalized,omitempty"`
+	Status     Status   `json:"status"`
+	Unknown    []string `json:"unknown,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Inventory is the licenses of the packages of a repository of melange configs.
+type Inventory struct {
+	// Entries are sorted by package, and a package has an entry per copyright statement.
+	Entries []Entry `json:"packages"`
+	// Counts are the number of packages using each license, with its exception if it has one.
+	Counts map[string]int `json:"counts"`
+}
+
+// NewInventory returns the inventory of the licenses of cfgs.
+func NewInventory(cfgs []build.Configuration) Inventory {
+	inv := Inventory{Counts: make(map[string]int)}
+	for i := range cfgs {
+		pkg := cfgs[i].Package
+		version := fmt.Sprintf("%s-r%d", pkg.Version, pkg.Epoch)
+		if len(pkg.Copyright) == 0 {
+			inv.Entries = append(inv.Entries, Entry{Package: pkg.Name, Version: version, Status: StatusMissing})
+			continue
+		}
+
+		used := make(map[string]bool)
+		for _, c := range pkg.Copyright {
+			e := Entry{Package: pkg.Name, Version: version, Declared: c.License}
+			n, unknown, err := parse(c.License)
+			switch {
+			case err == ErrEmptyExpression:
+				e.Status = StatusMissing
+			case err != nil:
+				e.Status, e.Error = StatusInvalid, err.Error()
+			default:
+				e.Normalized, e.Unknown = n.String(), unknown
+				e.Status = StatusValid
+				if len(unknown) > 0 {
+					e.Status = StatusUnknown
+				}
+				for _, l := range n.licenses() {
+					used[l] = true
+				}
+			}
+			inv.Entries = append(inv.Entries, e)
+		}
+		for l := range used {
+			inv.Counts[l]++
+		}
+	}
+	sort.SliceStable(inv.Entries, func(i, j int) bool {
+		return inv.Entries[i].Package < inv.Entries[j].Package
+	})
+	return inv
+}
+
+// Problems returns the entries whose license isn't valid.
+func (inv Inventory) Problems() []Entry {
+	var problems []Entry
+	for _, e := range inv.Entries {
+		if e.Status != StatusValid {
+			problems = append(problems, e)
+		}
+	}
+	return problems
+}
+
+// Format is the encoding of an inventory.
+type Format string
+
+const (
+	FormatCSV      Format = "csv"
+	FormatJSON     Format = "json"
+	FormatMarkdown Format = "markdown"
+)
+
+// Write encodes the inventory to w. CSV has a row per entry, Markdown the counts and the entries with problems.
+func (inv Inventory) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatCSV:
+		return inv.writeCSV(w)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(inv)
+	case FormatMarkdown:
+		return inv.writeMarkdown(w)
+	default:
+		return fmt.Errorf("unknown inventory format %q", format)
+	}
+}
+
+func (inv Inventory) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"package", "version", "declared", "normalized", "status", "unknown", "error"}); err != nil {
+		return err
+	}
+	for _, e := range inv.Entries {
+		if err := cw.Write([]string{e.Package, e.Version, e.Declared, e.Normalized, string(e.Status), strings.Join(e.Unknown, " "), e.Error}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (inv Inventory) writeMarkdown(w io.Writer) error {
+	var b strings.Builder
+	packages := make(map[string]bool)
+	for _, e := range inv.Entries {
+		packages[e.Package] = true
+	}
+	problems := inv.Problems()
+	fmt.Fprintf(&b, "# License inventory\n\n%d packages, %d licenses with problems.\n\n", len(packages), len(problems))
+
+	licenses := make([]string, 0, len(inv.Counts))
+	for l := range inv.Counts {
+		licenses = append(licenses, l)
+	}
+	// most used first
+	sort.Slice(licenses, func(i, j int) bool {
+		if inv.Counts[licenses[i]] != inv.Counts[licenses[j]] {
+			return inv.Counts[licenses[i]] > inv.Counts[licenses[j]]
+		}
+		return licenses[i] < licenses[j]
+	})
+	b.WriteString("## Licenses\n\n| License | Packages |\n| --- | --- |\n")
+	for _, l := range licenses {
+		fmt.Fprintf(&b, "| %s | %d |\n", markdownCell(l), inv.Counts[l])
+	}
+
+	if len(problems) > 0 {
+		b.WriteString("\n## Problems\n\n| Package | Version | License | Status | Details |\n| --- | --- | --- | --- | --- |\n")
+		for _, e := range problems {
+			details := e.Error
+			if len(e.Unknown) > 0 {
+				details = "not on the SPDX License List: " + strings.Join(e.Unknown, ", ")
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", e.Package, e.Version, markdownCell(e.Declared), e.Status, markdownCell(details))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,49 @@
+package license
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	buildconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/build"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+)
+
+func TestInventory(t *testing.T) {
+	index, err := buildconfigs.NewIndex(rwos.DirFS(filepath.Join("testdata", "inventory")))
+	require.NoError(t, err)
+	inv := NewInventory(index.Select().Configurations())
+
+	assert.Equal(t, []Entry{
+		{Package: "bar", Version: "2.0.0-r1", Declared: "gpl-2.0+", Normalized: "GPL-2.0-or-later", Status: StatusValid},
+		{Package: "bar", Version: "2.0.0-r1", Declared: "MIT AND Foo-1.0", Normalized: "MIT AND Foo-1.0", Status: StatusUnknown, Unknown: []string{"Foo-1.0"}},
+		{Package: "baz", Version: "0.1.0-r0", Status: StatusMissing},
+		{Package: "foo", Version: "1.2.3-r0", Declared: "MIT OR Apache-2.0", Normalized: "MIT OR Apache-2.0", Status: StatusValid},
+		{Package: "qux", Version: "3.0.0-r2", Declared: "MIT | BSD", Status: StatusInvalid, Error: `unexpected "|" in license expression "MIT | BSD"`},
+	}, inv.Entries)
+	assert.Equal(t, map[string]int{"MIT": 2, "Apache-2.0": 1, "Foo-1.0": 1, "GPL-2.0-or-later": 1}, inv.Counts)
+
+	t.Run("markdown", func(t *testing.T) {
+		want, err := os.ReadFile(filepath.Join("testdata", "inventory.md"))
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, inv.Write(&buf, FormatMarkdown))
+		assert.Equal(t, string(want), buf.String())
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, inv.Write(&buf, FormatCSV))
+		assert.Equal(t, `package,version,declared,normalized,status,unknown,error
+bar,2.0.0-r1,gpl-2.0+,GPL-2.0-or-later,valid,,
+bar,2.0.0-r1,MIT AND Foo-1.0,MIT AND Foo-1.0,unknown,Foo-1.0,
+baz,0.1.0-r0,,,missing,,
+foo,1.2.3-r0,MIT OR Apache-2.0,MIT OR Apache-2.0,valid,,
+qux,3.0.0-r2,MIT | BSD,,invalid,,"unexpected ""|"" in license expression ""MIT | BSD"""
+`, buf.String())
+	})
+}
@@ -0,0 +1,243 @@
+// Package license works with the SPDX license expressions of the copyright of melange configs.
+package license
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	//go:embed licenses.txt
+	licensesTxt string
+	//go:embed exceptions.txt
+	exceptionsTxt string
+
+	// canonical identifiers by their lower case, as identifiers are case-insensitive
+	licenseIDs   = readIDs(licensesTxt)
+	exceptionIDs = readIDs(exceptionsTxt)
+
+	// versions of GNU licenses that were deprecated in favor of an -only and an -or-later identifier
+	deprecatedGNU = regexp.MustCompile(`^(AGPL|GPL|LGPL|GFDL)-\d\.\d$`)
+)
+
+func readIDs(txt string) map[string]string {
+	ids := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(txt))
+	for scanner.Scan() {
+		id := strings.TrimSpace(scanner.Text())
+		if id == "" || strings.HasPrefix(id, "#") {
+			continue
+		}
+		ids[strings.ToLower(id)] = id
+	}
+	return ids
+}
+
+// ErrEmptyExpression is returned by Normalize for an expression without any license.
+var ErrEmptyExpression = errors.New("empty license expression")
+
+// Normalize returns the canonical form of an SPDX license expression: identifiers are in the case of the SPDX License
+// List, deprecated GNU identifiers are replaced, e.g. GPL-2.0 by GPL-2.0-only and GPL-2.0+ by GPL-2.0-or-later,
+// operators are upper case and separated by single spaces, and only the parentheses that are needed are kept. It also
+// returns the license and exception identifiers that aren't on the SPDX License List, which are left as they are.
+// LicenseRef- and DocumentRef- identifiers are user defined, so they are never unknown. It returns an error if the
+// expression isn't valid.
+func Normalize(expression string) (normalized string, unknown []string, err error) {
+	n, unknown, err := parse(expression)
+	if err != nil {
+		return "", nil, err
+	}
+	return n.String(), unknown, nil
+}
+
+func parse(expression string) (*node, []string, error) {
+	tokens := tokenize(expression)
+	if len(tokens) == 0 {
+		return nil, nil, ErrEmptyExpression
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, nil, fmt.Errorf("unexpected %q in license expression %q", p.tokens[p.pos], expression)
+	}
+	return n, p.unknown, nil
+}
+
+// tokenize splits an expression into parentheses and words.
+func tokenize(expression string) []string {
+	expression = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expression)
+	return strings.Fields(expression)
+}
+
+// node is a license, a license with an exception or a conjunction or disjunction of two nodes.
+type node struct {
+	// set for licenses
+	license   string
+	exception string
+
+	// set for conjunctions and disjunctions
+	operator    string
+	left, right *node
+}
+
+func (n *node) String() string {
+	if n.operator == "" {
+		if n.exception != "" {
+			return n.license + " WITH " + n.exception
+		}
+		return n.license
+	}
+	// AND binds tighter than OR, so only a disjunction in a conjunction needs parentheses
+	operand := func(o *node) string {
+		if n.operator == "AND" && o.operator == "OR" {
+			return "(" + o.String() + ")"
+		}
+		return o.String()
+	}
+	return operand(n.left) + " " + n.operator + " " + operand(n.right)
+}
+
+// licenses returns the licenses of the expression, with their exceptions, in order of appearance.
+func (n *node) licenses() []string {
+	if n.operator == "" {
+		return []string{n.String()}
+	}
+	return append(n.left.licenses(), n.right.licenses()...)
+}
+
+type parser struct {
+	tokens  []string
+	pos     int
+	unknown []string
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) parseOr() (*node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "OR") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &node{operator: "OR", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (*node, error) {
+	left, err := p.parseWith()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "AND") {
+		p.pos++
+		right, err := p.parseWith()
+		if err != nil {
+			return nil, err
+		}
+		left = &node{operator: "AND", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseWith() (*node, error) {
+	n, err := p.parseAtom()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(p.peek(), "WITH") {
+		return n, nil
+	}
+	p.pos++
+	if n.operator != "" || n.exception != "" {
+		return nil, errors.New("WITH must follow a license")
+	}
+	exception := p.peek()
+	if exception == "" || isReserved(exception) {
+		return nil, errors.New("expected a license exception after WITH")
+	}
+	p.pos++
+	if id, ok := exceptionIDs[strings.ToLower(exception)]; ok {
+		exception = id
+	} else {
+		p.unknown = append(p.unknown, exception)
+	}
+	n.exception = exception
+	return n, nil
+}
+
+func (p *parser) parseAtom() (*node, error) {
+	token := p.peek()
+	switch {
+	case token == "":
+		return nil, errors.New("unexpected end of license expression")
+	case token == "(":
+		p.pos++
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing closing parenthesis")
+		}
+		p.pos++
+		return n, nil
+	case isReserved(token):
+		return nil, fmt.Errorf("expected a license, got %q", token)
+	}
+	p.pos++
+	return &node{license: p.normalizeLicense(token)}, nil
+}
+
+// normalizeLicense returns the canonical form of a license identifier, optionally followed by +, and records it if it's
+// unknown.
+func (p *parser) normalizeLicense(token string) string {
+	if isUserDefined(token) {
+		return token
+	}
+	id, orLater := strings.CutSuffix(token, "+")
+	canonical, ok := licenseIDs[strings.ToLower(id)]
+	if !ok {
+		p.unknown = append(p.unknown, token)
+		return token
+	}
+	if deprecatedGNU.MatchString(canonical) {
+		if orLater {
+			return canonical + "-or-later"
+		}
+		return canonical + "-only"
+	}
+	if orLater {
+		return canonical + "+"
+	}
+	return canonical
+}
+
+func isReserved(token string) bool {
+	switch strings.ToUpper(token) {
+	case "AND", "OR", "WITH", "(", ")":
+		return true
+	}
+	return false
+}
+
+func isUserDefined(token string) bool {
+	return strings.HasPrefix(token, "LicenseRef-") || strings.HasPrefix(token, "DocumentRef-")
+}
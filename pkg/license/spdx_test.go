@@ -0,0 +1,47 @@
+package license
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+		unknown    []string
+		wantErr    bool
+	}{
+		{expression: "MIT", want: "MIT"},
+		{expression: "apache-2.0", want: "Apache-2.0"},
+		{expression: "GPL-2.0", want: "GPL-2.0-only"},
+		{expression: "LGPL-2.1+", want: "LGPL-2.1-or-later"},
+		{expression: "GPL-3.0-or-later", want: "GPL-3.0-or-later"},
+		{expression: "mit or  Apache-2.0", want: "MIT OR Apache-2.0"},
+		{expression: "((MIT) AND (BSD-3-Clause OR Zlib))", want: "MIT AND (BSD-3-Clause OR Zlib)"},
+		{expression: "(MIT AND Zlib) OR ISC", want: "MIT AND Zlib OR ISC"},
+		{expression: "GPL-2.0-or-later with classpath-exception-2.0", want: "GPL-2.0-or-later WITH Classpath-exception-2.0"},
+		{expression: "LicenseRef-wolfi-Custom AND MIT", want: "LicenseRef-wolfi-Custom AND MIT"},
+		{expression: "MIT AND Foo-1.0", want: "MIT AND Foo-1.0", unknown: []string{"Foo-1.0"}},
+		{expression: "MIT WITH Foo-exception", want: "MIT WITH Foo-exception", unknown: []string{"Foo-exception"}},
+		{expression: "MIT AND", wantErr: true},
+		{expression: "(MIT OR Zlib", wantErr: true},
+		{expression: "MIT Zlib", wantErr: true},
+		{expression: "(MIT OR Zlib) WITH LLVM-exception", wantErr: true},
+		{expression: "  ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			got, unknown, err := Normalize(tt.expression)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.unknown, unknown)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFile is where the rule configuration is read from, at the root of the linted repository.
+const DefaultConfigFile = ".wolfictl-lint.yaml"
+
+// Config configures the rules for repositories whose conventions differ from Wolfi's.
+type Config struct {
+	// Rules configures rules by name, the rules it doesn't name are left as they are.
+	Rules map[string]RuleConfig `yaml:"rules"`
+}
+
+// RuleConfig configures a rule.
+type RuleConfig struct {
+	// Enabled turns the rule on or off, rules are on unless they're Disabled by default.
+	Enabled *bool `yaml:"enabled"`
+
+	// Severity overrides the severity of the rule.
+	Severity Severity `yaml:"severity"`
+
+	// Exclude are the packages the rule isn't evaluated for, as names or path.Match patterns like py3-*.
+	Exclude []string `yaml:"exclude"`
+}
+
+// ReadConfig reads a Config from a YAML file, a file that doesn't exist configures nothing.
+func ReadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("failed to parse lint config %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// apply returns the rules as configured, without the disabled ones. Rules it doesn't know, severities that aren't
+// ERROR, WARNING or INFO and invalid exclusion patterns are errors, so typos don't go unnoticed.
+func (c *Config) apply(rules Rules) (Rules, error) {
+	if c == nil {
+		c = &Config{}
+	}
+	known := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		known[rule.Name] = true
+	}
+	for name, rc := range c.Rules {
+		if !known[name] {
+			return nil, fmt.Errorf("lint config configures unknown rule %s", name)
+		}
+		switch Severity(strings.ToUpper(string(rc.Severity))) {
+		case "", SeverityError, SeverityWarning, SeverityInfo:
+		default:
+			return nil, fmt.Errorf("lint config gives rule %s unknown severity %s, must be one of: %s, %s, %s", name, rc.Severity, SeverityError, SeverityWarning, SeverityInfo)
+		}
+		for _, pattern := range rc.Exclude {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("lint config excludes packages from rule %s by invalid pattern %q: %w", name, pattern, err)
+			}
+		}
+	}
+
+	configured := make(Rules, 0, len(rules))
+	for _, rule := range rules {
+		rc := c.Rules[rule.Name]
+		if rc.Enabled != nil {
+			rule.Disabled = !*rc.Enabled
+		}
+		if rule.Disabled {
+			continue
+		}
+		if rc.Severity != "" {
+			rule.Severity = Severity(strings.ToUpper(string(rc.Severity)))
+		}
+		rule.Exclude = append(rule.Exclude, rc.Exclude...)
+		configured = append(configured, rule)
+	}
+	return configured, nil
+}
+
+// excludes returns true if the rule isn't evaluated for the package.
+func (r Definition) excludes(name string) bool {
+	for _, pattern := range r.Exclude {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package lint
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadConfig(t *testing.T) {
+	config, err := ReadConfig("testdata/config/.wolfictl-lint.yaml")
+	require.NoError(t, err)
+	require.Len(t, config.Rules, 3)
+	require.NotNil(t, config.Rules["forbidden-repository-used"].Enabled)
+	assert.False(t, *config.Rules["forbidden-repository-used"].Enabled)
+	assert.Equal(t, []string{"missing-*"}, config.Rules["valid-copyright-header"].Exclude)
+
+	config, err = ReadConfig("testdata/config/missing.yaml")
+	require.NoError(t, err)
+	assert.Empty(t, config.Rules)
+}
+
+func TestLinter_Config(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"forbidden-repository.yaml", "missing-copyright.yaml", "duplicated-package.yaml"} {
+		b, err := os.ReadFile(filepath.Join("testdata/files", f))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, f), b, 0o600))
+	}
+	config, err := ReadConfig("testdata/config/.wolfictl-lint.yaml")
+	require.NoError(t, err)
+
+	got, err := New(WithPath(dir), WithConfig(config)).Lint()
+	require.NoError(t, err)
+	require.Len(t, got, 1, "the forbidden repository rule is disabled, and missing-copyright is excluded")
+	assert.Equal(t, "duplicated-package", got[0].File)
+	require.Len(t, got[0].Errors, 1)
+	assert.Equal(t, SeverityWarning, got[0].Errors[0].Rule.Severity)
+	assert.EqualError(t, got[0].Errors[0].Error, "[no-repeated-deps]: package foo is duplicated in environment (WARNING)")
+
+	for name, config := range map[string]*Config{
+		"unknown rule":     {Rules: map[string]RuleConfig{"no-such-rule": {}}},
+		"unknown severity": {Rules: map[string]RuleConfig{"no-repeated-deps": {Severity: "fatal"}}},
+		"invalid pattern":  {Rules: map[string]RuleConfig{"no-repeated-deps": {Exclude: []string{"["}}}},
+	} {
+		_, err := New(WithPath(dir), WithConfig(config)).Lint()
+		assert.Error(t, err, name)
+	}
+}
+
+// noFooDependency is a Rule of a fork, which isn't a Definition.
+type noFooDependency struct{}
+
+func (noFooDependency) ID() string { return "no-foo-dependency" }
+
+func (noFooDependency) DefaultSeverity() Severity { return SeverityWarning }
+
+func (noFooDependency) Lint(config build.Configuration) error {
+	for _, p := range config.Environment.Contents.Packages {
+		if p == "foo" {
+			return errors.New("foo is used")
+		}
+	}
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	defer func(registered []Rule) { registeredRules = registered }(registeredRules)
+	Register(Definition{
+		Name:        "no-foo-dependency",
+		Description: "packages should not depend on foo",
+		Severity:    SeverityError,
+		LintFunc:    noFooDependency{}.Lint,
+		Disabled:    true,
+	})
+
+	l := newTestLinterWithDir("dir/")
+	got, err := l.Lint()
+	require.NoError(t, err)
+	assert.Empty(t, got, "registered rules disabled by default aren't evaluated")
+
+	enabled := true
+	l = New(WithPath("testdata/dir/"), WithConfig(&Config{Rules: map[string]RuleConfig{"no-foo-dependency": {Enabled: &enabled}}}))
+	got, err = l.Lint()
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.EqualError(t, got[0].Errors.WrapErrors(), "1 error occurred:\n\t* [no-foo-dependency]: foo is used (ERROR)\n\n")
+
+	Register(noFooDependency{})
+	_, err = l.Lint()
+	assert.ErrorContains(t, err, "rule no-foo-dependency is registered more than once")
+
+	registeredRules = []Rule{noFooDependency{}}
+	l = New(WithPath("testdata/dir/"), WithConfig(&Config{Rules: map[string]RuleConfig{"no-foo-dependency": {Severity: SeverityError}}}))
+	got, err = l.Lint()
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.EqualError(t, got[0].Errors.WrapErrors(), "1 error occurred:\n\t* [no-foo-dependency]: foo is used (ERROR)\n\n", "the lint config configures rules that aren't definitions too")
+}
@@ -0,0 +1,105 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/facebookincubator/nvdtools/wfn"
+	"github.com/pkg/errors"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln/nvdapi"
+)
+
+// CPESuggester suggests candidate CPEs of a package from its name and homepage, like nvdapi.Detector does from the
+// NVD CPE dictionary.
+type CPESuggester interface {
+	SuggestCPEs(ctx context.Context, packageName, homepage string) ([]wfn.Attributes, error)
+}
+
+// maxCPESuggestions is how many of the candidate CPEs of a package are suggested.
+const maxCPESuggestions = 3
+
+// reCPEAttribute matches the values of the attributes of a CPE as the NVD dictionary names them: lowercase, without
+// spaces or wildcards.
+var reCPEAttribute = regexp.MustCompile(`^[a-z0-9][a-z0-9._~+!-]*$`)
+
+// checkCPE returns the problems with the package.cpe of a config: a CPE that doesn't parse, isn't an application's
+// or has a version, and missing or malformed attributes.
+func (l *Linter) checkCPE(config build.Configuration) []string {
+	p, ok := l.localPackages[config.Package.Name]
+	if !ok || p.CPE == nil {
+		return nil
+	}
+	cpe := p.CPE
+
+	if cpe.Raw != "" {
+		attrs, err := wfn.Parse(cpe.Raw)
+		if err != nil {
+			return []string{fmt.Sprintf("package.cpe %s is not a CPE", cpe.Raw)}
+		}
+		var problems []string
+		if attrs.Part != "a" {
+			problems = append(problems, fmt.Sprintf("package.cpe %s is not an application's, whose part is a", cpe.Raw))
+		}
+		if attrs.Version != wfn.Any {
+			problems = append(problems, fmt.Sprintf("package.cpe %s has a version, vulnerabilities are matched against the package's", cpe.Raw))
+		}
+		if len(problems) > 0 {
+			return problems
+		}
+	}
+
+	var problems []string
+	for _, attr := range []struct {
+		name, value string
+		required    bool
+	}{
+		{"vendor", cpe.Vendor, true},
+		{"product", cpe.Product, true},
+		{"target_sw", cpe.TargetSW, false},
+	} {
+		switch {
+		case attr.value == "" && attr.required:
+			problems = append(problems, fmt.Sprintf("package.cpe has no %s", attr.name))
+		case attr.value != "" && !reCPEAttribute.MatchString(attr.value):
+			problems = append(problems, fmt.Sprintf("package.cpe %s %q is not lowercase letters, digits and ._~+!- only", attr.name, attr.value))
+		}
+	}
+	return problems
+}
+
+// checkMissingCPE returns an error for a config without a package.cpe whose package the vulnerability detector has no
+// CPE for either, so it matches vulnerabilities of products named like the package of any vendor. Candidate CPEs are
+// suggested if the CPESuggester option is set.
+func (l *Linter) checkMissingCPE(config build.Configuration) error {
+	name := config.Package.Name
+	if p, ok := l.localPackages[name]; ok && p.CPE != nil {
+		return nil
+	}
+	if nvdapi.HasCPEMapping(name) {
+		return nil
+	}
+
+	msg := fmt.Sprintf("package.cpe is missing, vulnerabilities of any vendor's product named %s are matched", name)
+	if l.options.CPESuggester == nil {
+		return errors.New(msg)
+	}
+
+	cpes, err := l.options.CPESuggester.SuggestCPEs(l.context(), name, config.Package.URL)
+	if err != nil {
+		return errors.Wrapf(err, "failed to suggest a CPE for %s", name)
+	}
+	if len(cpes) == 0 {
+		return fmt.Errorf("%s, and no product of the NVD CPE dictionary is named like it", msg)
+	}
+	if len(cpes) > maxCPESuggestions {
+		cpes = cpes[:maxCPESuggestions]
+	}
+	candidates := make([]string, 0, len(cpes))
+	for _, cpe := range cpes {
+		candidates = append(candidates, cpe.BindToFmtString())
+	}
+	return fmt.Errorf("%s, candidates: %s", msg, strings.Join(candidates, ", "))
+}
@@ -0,0 +1,114 @@
+package lint
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/facebookincubator/nvdtools/wfn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+	"gopkg.in/yaml.v3"
+)
+
+func TestLinter_CheckCPE(t *testing.T) {
+	tests := []struct {
+		cpe  string
+		want []string
+	}{
+		{cpe: "{vendor: haxx, product: curl}"},
+		{cpe: "{vendor: nodejs, product: node.js}"},
+		{cpe: "{vendor: python, product: requests, target_sw: python}"},
+		{cpe: "cpe:2.3:a:haxx:curl:*:*:*:*:*:*:*:*"},
+		{cpe: "cpe:/a:haxx:curl"},
+		{cpe: "{product: curl}", want: []string{"package.cpe has no vendor"}},
+		{cpe: "{vendor: haxx, product: '*'}", want: []string{`package.cpe product "*" is not lowercase letters, digits and ._~+!- only`}},
+		{cpe: "{vendor: Haxx, product: curl}", want: []string{`package.cpe vendor "Haxx" is not lowercase letters, digits and ._~+!- only`}},
+		{cpe: "haxx:curl", want: []string{"package.cpe haxx:curl is not a CPE"}},
+		{cpe: "cpe:2.3:o:haxx:curl:*:*:*:*:*:*:*:*", want: []string{"package.cpe cpe:2.3:o:haxx:curl:*:*:*:*:*:*:*:* is not an application's, whose part is a"}},
+		{cpe: "cpe:2.3:a:haxx:curl:8.0.1:*:*:*:*:*:*:*", want: []string{"package.cpe cpe:2.3:a:haxx:curl:8.0.1:*:*:*:*:*:*:* has a version, vulnerabilities are matched against the package's"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cpe, func(t *testing.T) {
+			var cpe melange.CPE
+			require.NoError(t, yaml.Unmarshal([]byte(tt.cpe), &cpe))
+
+			l := &Linter{localPackages: map[string]*melange.Packages{"curl": {CPE: &cpe}}}
+			config := build.Configuration{Package: build.Package{Name: "curl"}}
+			assert.Equal(t, tt.want, l.checkCPE(config))
+		})
+	}
+}
+
+// suggester suggests the cpes it's given, or fails with err.
+type suggester struct {
+	cpes []wfn.Attributes
+	err  error
+}
+
+func (s suggester) SuggestCPEs(context.Context, string, string) ([]wfn.Attributes, error) {
+	return s.cpes, s.err
+}
+
+func TestLinter_CheckMissingCPE(t *testing.T) {
+	candidates := []wfn.Attributes{
+		{Part: "a", Vendor: "libexpat_project", Product: "libexpat"},
+		{Part: "a", Vendor: "expat", Product: "libexpat"},
+		{Part: "a", Vendor: "example", Product: "libexpat"},
+		{Part: "a", Vendor: "other", Product: "libexpat"},
+	}
+	tests := []struct {
+		name      string
+		pkg       string
+		cpe       *melange.CPE
+		suggester CPESuggester
+		want      string
+	}{
+		{
+			name: "with a cpe",
+			pkg:  "libexpat",
+			cpe:  &melange.CPE{Vendor: "libexpat_project", Product: "libexpat"},
+		},
+		{
+			name: "mapped by the vulnerability detector",
+			pkg:  "curl",
+		},
+		{
+			name: "without a suggester",
+			pkg:  "libexpat",
+			want: "package.cpe is missing, vulnerabilities of any vendor's product named libexpat are matched",
+		},
+		{
+			name:      "with candidates",
+			pkg:       "libexpat",
+			suggester: suggester{cpes: candidates},
+			want:      "package.cpe is missing, vulnerabilities of any vendor's product named libexpat are matched, candidates: cpe:2.3:a:libexpat_project:libexpat:*:*:*:*:*:*:*:*, cpe:2.3:a:expat:libexpat:*:*:*:*:*:*:*:*, cpe:2.3:a:example:libexpat:*:*:*:*:*:*:*:*",
+		},
+		{
+			name:      "without candidates",
+			pkg:       "libexpat",
+			suggester: suggester{},
+			want:      "package.cpe is missing, vulnerabilities of any vendor's product named libexpat are matched, and no product of the NVD CPE dictionary is named like it",
+		},
+		{
+			name:      "failing to suggest",
+			pkg:       "libexpat",
+			suggester: suggester{err: errors.New("rate limited")},
+			want:      "failed to suggest a CPE for libexpat: rate limited",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(WithCPESuggester(tt.suggester))
+			l.localPackages = map[string]*melange.Packages{tt.pkg: {CPE: tt.cpe}}
+			err := l.checkMissingCPE(build.Configuration{Package: build.Package{Name: tt.pkg, URL: "https://libexpat.github.io"}})
+			if tt.want == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package lint
+
+import (
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"golang.org/x/exp/slices"
+)
+
+// maxTypoDistance is how many characters a dependency can differ from a package name by to be taken for a typo of it.
+const maxTypoDistance = 2
+
+// dependencies returns the environment packages of a config and the runtime dependencies of its package and
+// subpackages.
+func dependencies(config build.Configuration) []string {
+	deps := append([]string{}, config.Environment.Contents.Packages...)
+	deps = append(deps, config.Package.Dependencies.Runtime...)
+	for i := range config.Subpackages {
+		deps = append(deps, config.Subpackages[i].Dependencies.Runtime...)
+	}
+	return deps
+}
+
+// dependenciesWithoutProvider returns the dependencies of a config, the virtual ones or the others, that neither a
+// linted package nor a package of the provider indexes provides.
+func (l *Linter) dependenciesWithoutProvider(config build.Configuration, virtual bool) ([]string, error) {
+	var missing []string
+	for _, dep := range dependencies(config) {
+		// Packages pinned to a repository, like foo@local, are provided by a package named without the tag.
+		name, _, _ := strings.Cut(dep, "@")
+		c, err := dag.ParseConstraint(name)
+		if err != nil || c.IsVirtual() != virtual || slices.Contains(missing, dep) {
+			continue
+		}
+		ok, err := l.hasProvider(c)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			missing = append(missing, dep)
+		}
+	}
+	return missing, nil
+}
+
+// closestPackageName returns the name of the linted package, subpackage or package of the provider indexes that a
+// dependency is most likely a typo of, or "" if none is close enough.
+func (l *Linter) closestPackageName(dep string) string {
+	name, _, _ := strings.Cut(dep, "@")
+	if c, err := dag.ParseConstraint(name); err == nil {
+		name = c.Name
+	}
+
+	closest, best := "", maxTypoDistance+1
+	consider := func(candidate string) {
+		if d := editDistance(name, candidate); d < best || d == best && candidate < closest {
+			closest, best = candidate, d
+		}
+	}
+	for _, p := range l.localPackages {
+		consider(p.Config.Package.Name)
+		for i := range p.Config.Subpackages {
+			consider(p.Config.Subpackages[i].Name)
+		}
+	}
+	for _, idx := range l.providerIndexes {
+		for _, p := range idx.Packages {
+			consider(p.Name)
+		}
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance of a and b: how many characters have to be inserted, deleted or
+// substituted to turn one into the other.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,246 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+	"chainguard.dev/melange/pkg/renovate"
+	"gopkg.in/yaml.v3"
+
+	"github.com/wolfi-dev/wolfictl/pkg/configs/yamledit"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+	"github.com/wolfi-dev/wolfictl/pkg/scaffold"
+)
+
+// indentWidth is how many spaces configs are indented by
+const indentWidth = 2
+
+// source returns the source of the config of a linted package.
+func (l *Linter) source(config build.Configuration) ([]byte, error) {
+	p, ok := l.localPackages[config.Package.Name]
+	if !ok {
+		return nil, fmt.Errorf("no config for package %s", config.Package.Name)
+	}
+	return os.ReadFile(filepath.Join(p.Dir, p.Filename))
+}
+
+// editSource applies edit to the root mapping of the source of a config, changing it only where the nodes were edited.
+func editSource(src []byte, edit func(root *yaml.Node) error) ([]byte, error) {
+	doc, err := yamledit.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Root.Kind != yaml.DocumentNode || len(doc.Root.Content) != 1 || doc.Root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("not a melange config")
+	}
+	if err := edit(doc.Root.Content[0]); err != nil {
+		return nil, err
+	}
+	return doc.Bytes()
+}
+
+// misindented returns the lines of the keys and sequence items of a YAML node tree that aren't indented by two spaces
+// more than what they're nested in, block scalars and flow collections aside.
+func misindented(n *yaml.Node, column int) []int {
+	if n.Style&yaml.FlowStyle != 0 {
+		return nil
+	}
+	var lines []int
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			lines = append(lines, misindented(c, 1)...)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Column != column {
+				lines = append(lines, n.Content[i].Line)
+			}
+			if v := n.Content[i+1]; v.Kind == yaml.MappingNode || v.Kind == yaml.SequenceNode {
+				lines = append(lines, misindented(v, column+indentWidth)...)
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			// items start after their dash and a space
+			if item.Column != column+indentWidth && (item.Kind == yaml.MappingNode || item.Kind == yaml.ScalarNode) {
+				lines = append(lines, item.Line)
+			}
+			if item.Kind == yaml.MappingNode || item.Kind == yaml.SequenceNode {
+				lines = append(lines, misindented(item, column+indentWidth)...)
+			}
+		}
+	}
+	return lines
+}
+
+// fixIndentation renders a config anew, indented by two spaces.
+func fixIndentation(_ build.Configuration, src []byte) ([]byte, error) {
+	doc, err := yamledit.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Render()
+}
+
+// sortEnvironmentPackages sorts the packages of the build environment of a config, along with their comments.
+func sortEnvironmentPackages(_ build.Configuration, src []byte) ([]byte, error) {
+	return editSource(src, func(root *yaml.Node) error {
+		env, err := renovate.NodeFromMapping(root, "environment")
+		if err != nil {
+			return err
+		}
+		contents, err := renovate.NodeFromMapping(env, "contents")
+		if err != nil {
+			return err
+		}
+		packages, err := renovate.NodeFromMapping(contents, "packages")
+		if err != nil {
+			return err
+		}
+		sort.SliceStable(packages.Content, func(i, j int) bool { return packages.Content[i].Value < packages.Content[j].Value })
+		return nil
+	})
+}
+
+// insecureURIs returns the package url, the uris of the fetch steps and the repositories of the git-checkout steps of
+// a config that are downloaded over plain http.
+func insecureURIs(config build.Configuration) []string {
+	var uris []string
+	if strings.HasPrefix(config.Package.URL, "http://") {
+		uris = append(uris, config.Package.URL)
+	}
+	for _, step := range pipelineSteps(config) {
+		if uri := step.With[sourceInput(step.Uses)]; strings.HasPrefix(uri, "http://") {
+			uris = append(uris, uri)
+		}
+	}
+	return uris
+}
+
+// sourceInput returns the input of a pipeline that says where sources are downloaded from, if it downloads some
+func sourceInput(uses string) string {
+	switch uses {
+	case "fetch":
+		return "uri"
+	case "git-checkout":
+		return "repository"
+	}
+	return ""
+}
+
+// fixInsecureURIs has the package url and the sources of the steps of a config downloaded over https.
+func fixInsecureURIs(_ build.Configuration, src []byte) ([]byte, error) {
+	return editSource(src, func(root *yaml.Node) error {
+		https := func(n *yaml.Node) {
+			if n.Kind == yaml.ScalarNode && strings.HasPrefix(n.Value, "http://") {
+				n.Value = "https://" + strings.TrimPrefix(n.Value, "http://")
+			}
+		}
+		if pkg, err := renovate.NodeFromMapping(root, "package"); err == nil {
+			if url, err := renovate.NodeFromMapping(pkg, "url"); err == nil {
+				https(url)
+			}
+		}
+		var walk func(pipeline *yaml.Node)
+		walk = func(pipeline *yaml.Node) {
+			for _, step := range pipeline.Content {
+				if step.Kind != yaml.MappingNode {
+					continue
+				}
+				if uses, err := renovate.NodeFromMapping(step, "uses"); err == nil && sourceInput(uses.Value) != "" {
+					if with, err := renovate.NodeFromMapping(step, "with"); err == nil {
+						if uri, err := renovate.NodeFromMapping(with, sourceInput(uses.Value)); err == nil {
+							https(uri)
+						}
+					}
+				}
+				if p, err := renovate.NodeFromMapping(step, "pipeline"); err == nil {
+					walk(p)
+				}
+			}
+		}
+		if p, err := renovate.NodeFromMapping(root, "pipeline"); err == nil {
+			walk(p)
+		}
+		if subpackages, err := renovate.NodeFromMapping(root, "subpackages"); err == nil {
+			for _, sub := range subpackages.Content {
+				if p, err := renovate.NodeFromMapping(sub, "pipeline"); err == nil {
+					walk(p)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// hasUpdateConfig returns true if the source of a config has an update block.
+func hasUpdateConfig(src []byte) (bool, error) {
+	var c map[string]yaml.Node
+	if err := yaml.Unmarshal(src, &c); err != nil {
+		return false, err
+	}
+	_, ok := c["update"]
+	return ok, nil
+}
+
+// addUpdateConfig adds an update block to a config whose sources are downloaded from GitHub, monitoring the
+// repository they're downloaded from.
+func addUpdateConfig(config build.Configuration, src []byte) ([]byte, error) {
+	m := sourceMonitor(config)
+	if m == nil {
+		return nil, fmt.Errorf("the sources of %s aren't downloaded from GitHub, so there's no monitor to default to", config.Package.Name)
+	}
+	return editSource(src, func(root *yaml.Node) error {
+		update := &yaml.Node{}
+		if err := update.Encode(scaffold.Update{Enabled: true, GitHub: m}); err != nil {
+			return err
+		}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "update"}, update)
+		return nil
+	})
+}
+
+// sourceMonitor returns the GitHub monitor of the first step of a config downloading sources, if it downloads them
+// from GitHub: the archive or a release asset of a fetch step, or the tag of a git-checkout step. Sources downloaded
+// over http count, as https-uri fixes them.
+func sourceMonitor(config build.Configuration) *scaffold.Monitor {
+	https := func(uri string) string {
+		if rest, ok := strings.CutPrefix(uri, "http://"); ok {
+			return "https://" + rest
+		}
+		return uri
+	}
+	for _, step := range pipelineSteps(config) {
+		switch step.Uses {
+		case "fetch":
+			return scaffold.GitHubMonitor(https(step.With["uri"]))
+		case "git-checkout":
+			rest, ok := strings.CutPrefix(https(step.With["repository"]), "https://github.com/")
+			if !ok {
+				return nil
+			}
+			prefix, _, ok := strings.Cut(step.With["tag"], "${{package.version}}")
+			if !ok {
+				return nil
+			}
+			return &scaffold.Monitor{Identifier: strings.TrimSuffix(rest, ".git"), StripPrefix: prefix, UseTags: true}
+		}
+	}
+	return nil
+}
+
+// readFixed reads the config of a package again once it's fixed
+func readFixed(pkg *melange.Packages) (*melange.Packages, error) {
+	config, err := melange.ReadMelangeConfig(filepath.Join(pkg.Dir, pkg.Filename))
+	if err != nil {
+		return nil, err
+	}
+	fixed := *pkg
+	fixed.Config = config
+	return &fixed, nil
+}
@@ -0,0 +1,87 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestLinter_Fix(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "unfixed.yaml")
+	b, err := os.ReadFile("testdata/fix/unfixed.yaml")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, b, 0o600))
+	enabled := true
+	config := &Config{Rules: map[string]RuleConfig{
+		"sorted-environment-packages": {Enabled: &enabled},
+		"missing-update-config":       {Enabled: &enabled},
+	}}
+
+	got, err := New(WithPath(dir), WithConfig(config)).Lint()
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Len(t, got[0].Errors, 4)
+	assert.Empty(t, got[0].Fixed, "nothing is fixed without the fix option")
+
+	got, err = New(WithPath(dir), WithConfig(config), WithFix(true)).Lint()
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Empty(t, got[0].Errors)
+	assert.Equal(t, []string{"https-uri", "sorted-environment-packages", "missing-update-config", "valid-indentation"}, got[0].Fixed)
+
+	fixed, err := os.ReadFile(path)
+	require.NoError(t, err)
+	want, err := os.ReadFile("testdata/fix/fixed.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(fixed))
+
+	got, err = New(WithPath(dir), WithConfig(config)).Lint()
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestLinter_FixUnfixable(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "missing-copyright.yaml")
+	b, err := os.ReadFile("testdata/files/missing-copyright.yaml")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, b, 0o600))
+	enabled := true
+
+	// the sources aren't downloaded from GitHub, so there's no update monitor to add
+	got, err := New(WithPath(dir), WithFix(true), WithConfig(&Config{Rules: map[string]RuleConfig{"missing-update-config": {Enabled: &enabled}}})).Lint()
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Empty(t, got[0].Fixed)
+	require.Len(t, got[0].Errors, 2)
+	assert.Equal(t, "missing-update-config", got[0].Errors[1].Rule.Name)
+
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(b), string(after))
+}
+
+func TestMisindented(t *testing.T) {
+	src := `package:
+  name: foo
+   # a comment is not a key
+environment:
+  contents:
+    packages:
+    - busybox
+pipeline:
+  - uses: fetch
+    with:
+       uri: https://example.com/foo.tar.gz
+  - runs: |
+        make
+`
+	var n yaml.Node
+	require.NoError(t, yaml.Unmarshal([]byte(src), &n))
+	assert.Equal(t, []int{7, 11}, misindented(&n, 1))
+}
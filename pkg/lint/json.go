@@ -0,0 +1,33 @@
+package lint
+
+import "path/filepath"
+
+// Issue is a rule a config failed, as lint --format json reports it.
+type Issue struct {
+	Package string `json:"package"`
+	// Path is the path of the config, within the linted path.
+	Path string `json:"path"`
+	// Line is the line of the key of the config the rule is about.
+	Line     int      `json:"line"`
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// Issues returns the rules the configs of the result failed, in the order of the result.
+func (l *Linter) Issues(result Result) []Issue {
+	issues := []Issue{}
+	for _, res := range result {
+		for _, e := range res.Errors {
+			issues = append(issues, Issue{
+				Package:  res.File,
+				Path:     filepath.ToSlash(filepath.Join(l.options.Path, res.Path)),
+				Line:     e.Line,
+				Rule:     e.Rule.Name,
+				Severity: e.Rule.Severity,
+				Message:  e.Message,
+			})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,40 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinter_Issues(t *testing.T) {
+	l := newTestLinterWithFile("duplicated-package.yaml")
+	result, err := l.Lint()
+	require.NoError(t, err)
+
+	assert.Equal(t, []Issue{{
+		Package:  "duplicated-package",
+		Path:     "testdata/files/duplicated-package.yaml",
+		Line:     13,
+		Rule:     "no-repeated-deps",
+		Severity: SeverityError,
+		Message:  "package foo is duplicated in environment",
+	}}, l.Issues(result))
+
+	assert.Equal(t, []Issue{}, l.Issues(nil), "no issues are an empty list")
+}
+
+func TestKeyLine(t *testing.T) {
+	path := "testdata/files/duplicated-package.yaml"
+	for key, want := range map[string]int{
+		"":                              1,
+		"package.version":               3,
+		"environment.contents.packages": 13,
+		// the deepest key the config has
+		"environment.contents.repositories": 12,
+		"pipeline":                          1,
+	} {
+		assert.Equal(t, want, keyLine(path, key), key)
+	}
+	assert.Equal(t, 1, keyLine("testdata/files/missing.yaml", "package"))
+}
@@ -3,20 +3,30 @@ package lint
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/exp/slices"
+	"golang.org/x/sync/errgroup"
 
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
 
+	"chainguard.dev/melange/pkg/build"
 	"github.com/pkg/errors"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/index"
 	"github.com/wolfi-dev/wolfictl/pkg/melange"
+	"gitlab.alpinelinux.org/alpine/go/repository"
 )
 
 // Linter represents a linter instance.
@@ -27,6 +37,28 @@ type Linter struct {
 	// makefileBytes is storing the cached bytes of the Makefile
 	// to avoid reading it multiple times.
 	makefileBytes []byte
+	makefileErr   error
+	makefileOnce  sync.Once
+
+	// providerIndexes are the loaded APKINDEXes of the ProviderIndexes option, and localPackages the packages being
+	// linted, which virtual dependencies are resolved against.
+	providerIndexes     []*repository.ApkIndex
+	providerIndexesErr  error
+	providerIndexesOnce sync.Once
+	localPackages       map[string]*melange.Packages
+
+	// providers are what the linted configs provide by name, and packageFiles the configs by the name of their
+	// package, which different configs providing the same name are found by.
+	providers    map[string][]provider
+	packageFiles map[string][]string
+
+	// pipelines are the repo-local pipelines, which steps are checked against along with the built-in ones.
+	pipelines     map[string]pipelineDefinition
+	pipelinesErr  error
+	pipelinesOnce sync.Once
+
+	// rules are the rules Lint evaluated, as the lint config configures them.
+	rules Rules
 
 	// logger is the logger to use.
 	logger *log.Logger
@@ -45,81 +77,258 @@ func New(opts ...Option) *Linter {
 }
 
 // Lint evaluates all rules and returns the result.
+//
+// Packages are linted concurrently, rules in the order of the profile if there's one. Expensive rules are evaluated
+// once the others have been for every package, sharing what they fetch across packages.
 func (l *Linter) Lint() (Result, error) {
-	rules := AllRules(l)
+	configured, err := l.Rules()
+	if err != nil {
+		return Result{}, err
+	}
+	l.rules = configured
+	rules := orderRules(configured, l.options.Profile)
+	var cheap, expensive Rules
+	for _, rule := range rules {
+		if rule.Expensive {
+			expensive = append(expensive, rule)
+		} else {
+			cheap = append(cheap, rule)
+		}
+	}
+	// errors are reported in the order the rules are declared in, whatever order they ran in
+	declared := make(map[string]int)
+	for i, rule := range configured {
+		declared[rule.Name] = i
+	}
 
 	filesToLint, err := melange.ReadAllPackagesFromRepo(l.options.Path)
 	if err != nil {
 		return Result{}, err
 	}
-
-	results := make(Result, 0)
+	l.localPackages = filesToLint
+	l.providers = indexProviders(filesToLint)
+	l.packageFiles, err = packageFiles(l.options.Path)
+	if err != nil {
+		return Result{}, err
+	}
+	names := make([]string, 0, len(filesToLint))
 	for name := range filesToLint {
-		failedRules := make(EvalRuleErrors, 0)
-		for _, rule := range rules {
-			// Check if we should skip this rule.
-			shouldEvaluate := true
-			if len(rule.ConditionFuncs) > 0 {
-				for _, cond := range rule.ConditionFuncs {
-					if !cond() {
-						shouldEvaluate = false
-						break
-					}
-				}
+		names = append(names, name)
+	}
+	if len(l.options.Packages) > 0 {
+		names = names[:0]
+		for _, name := range l.options.Packages {
+			if _, ok := filesToLint[name]; !ok {
+				return Result{}, fmt.Errorf("package %s not found in %s", name, l.options.Path)
 			}
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
 
-			// If one of the conditions is not met we skip the evaluation process.
-			if !shouldEvaluate {
-				if l.options.Verbose {
-					l.logger.Printf("%s: skipping rule %s because condition is not met\n", name, rule.Name)
-				}
+	jobs := l.options.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	ctx := l.context()
+	failed := make([]EvalRuleErrors, len(names))
+	for _, phase := range []Rules{cheap, expensive} {
+		var g errgroup.Group
+		g.SetLimit(jobs)
+		for i := range names {
+			i := i
+			if l.options.FailFast && len(failed[i]) > 0 {
 				continue
 			}
-
-			// Allow users to override rules when running lint command
-			if slices.Contains(l.options.SkipRules, rule.Name) {
-				if l.options.Verbose {
-					l.logger.Printf("%s: skipping rule %s because --skip-rule flag set\n", name, rule.Name)
+			g.Go(func() error {
+				if ctx.Err() != nil {
+					return nil
 				}
-				continue
+				failed[i] = append(failed[i], l.evalRules(names[i], filesToLint[names[i]], phase)...)
+				return nil
+			})
+		}
+		_ = g.Wait()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make(Result, 0)
+	for i, name := range names {
+		failedRules := failed[i]
+		var fixed []string
+		if l.options.Fix {
+			failedRules, fixed, err = l.fix(name, filesToLint[name], failedRules)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fix %s: %w", name, err)
+			}
+		}
+		// If we have errors or fixes we append them to the result.
+		if failedRules.WrapErrors() == nil && len(fixed) == 0 {
+			continue
+		}
+		sort.SliceStable(failedRules, func(a, b int) bool {
+			return declared[failedRules[a].Rule.Name] < declared[failedRules[b].Rule.Name]
+		})
+		results = append(results, EvalResult{
+			File:   name,
+			Path:   filesToLint[name].Filename,
+			Errors: failedRules,
+			Fixed:  fixed,
+		})
+	}
+
+	return results, nil
+}
+
+// fix applies the fixes of the failed rules that have one to the config of a package, and evaluates these rules again
+// on the fixed config. It returns the rules that still fail, and the names of the ones that were fixed.
+func (l *Linter) fix(name string, pkg *melange.Packages, failed EvalRuleErrors) (EvalRuleErrors, []string, error) {
+	path := filepath.Join(pkg.Dir, pkg.Filename)
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	orig := src
+	var remaining EvalRuleErrors
+	var attempted Rules
+	for _, e := range failed {
+		if e.Rule.Fix == nil {
+			remaining = append(remaining, e)
+			continue
+		}
+		out, err := e.Rule.Fix(pkg.Config, src)
+		if err != nil {
+			if l.options.Verbose {
+				l.logger.Printf("%s: can't fix rule %s: %v\n", name, e.Rule.Name, err)
 			}
+			remaining = append(remaining, e)
+			continue
+		}
+		src = out
+		attempted = append(attempted, e.Rule)
+	}
+	if len(attempted) == 0 || bytes.Equal(src, orig) {
+		return failed, nil, nil
+	}
 
-			if slices.Contains(filesToLint[name].NoLint, rule.Name) {
-				if l.options.Verbose {
-					l.logger.Printf("%s: skipping rule %s because file contains #nolint:%s\n", name, rule.Name, rule.Name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := os.WriteFile(path, src, info.Mode()); err != nil {
+		return nil, nil, err
+	}
+	fixedPkg, err := readFixed(pkg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("the fixed config doesn't read back: %w", err)
+	}
+	still := l.evalRules(name, fixedPkg, attempted)
+	failing := make(map[string]bool, len(still))
+	for _, e := range still {
+		failing[e.Rule.Name] = true
+	}
+	var fixed []string
+	for _, rule := range attempted {
+		if !failing[rule.Name] {
+			fixed = append(fixed, rule.Name)
+		}
+	}
+	return append(remaining, still...), fixed, nil
+}
+
+func (l *Linter) context() context.Context {
+	if l.options.Context == nil {
+		return context.Background()
+	}
+	return l.options.Context
+}
+
+// evalRules evaluates the rules against a package, recording their cost in the profile if there's one.
+func (l *Linter) evalRules(name string, pkg *melange.Packages, rules Rules) EvalRuleErrors {
+	failedRules := make(EvalRuleErrors, 0)
+	for _, rule := range rules {
+		// Check if we should skip this rule.
+		shouldEvaluate := true
+		if len(rule.ConditionFuncs) > 0 {
+			for _, cond := range rule.ConditionFuncs {
+				if !cond() {
+					shouldEvaluate = false
+					break
 				}
-				continue
 			}
+		}
 
-			// Evaluate the rule.
-			if err := rule.LintFunc(filesToLint[name].Config); err != nil {
-				msg := fmt.Sprintf("[%s]: %s (%s)", rule.Name, err.Error(), rule.Severity)
-				if l.options.Verbose {
-					msg += fmt.Sprintf(" - (%s)", rule.Description)
-				}
+		// If one of the conditions is not met we skip the evaluation process.
+		if !shouldEvaluate {
+			if l.options.Verbose {
+				l.logger.Printf("%s: skipping rule %s because condition is not met\n", name, rule.Name)
+			}
+			continue
+		}
 
-				failedRules = append(failedRules, EvalRuleError{
-					Rule:  rule,
-					Error: fmt.Errorf(msg),
-				})
+		// Allow users to override rules when running lint command
+		if slices.Contains(l.options.SkipRules, rule.Name) {
+			if l.options.Verbose {
+				l.logger.Printf("%s: skipping rule %s because --skip-rule flag set\n", name, rule.Name)
 			}
+			continue
 		}
-		// If we have errors we append them to the result.
-		if failedRules.WrapErrors() != nil {
-			results = append(results, EvalResult{
-				File:   name,
-				Errors: failedRules,
+
+		if slices.Contains(pkg.NoLint, rule.Name) {
+			if l.options.Verbose {
+				l.logger.Printf("%s: skipping rule %s because file contains #nolint:%s\n", name, rule.Name, rule.Name)
+			}
+			continue
+		}
+
+		if rule.excludes(name) {
+			if l.options.Verbose {
+				l.logger.Printf("%s: skipping rule %s because the lint config excludes the package\n", name, rule.Name)
+			}
+			continue
+		}
+
+		// Evaluate the rule.
+		start := time.Now()
+		err := rule.LintFunc(pkg.Config)
+		if l.options.Profile != nil {
+			l.options.Profile.record(rule.Name, time.Since(start), err != nil)
+		}
+		if err != nil {
+			msg := fmt.Sprintf("[%s]: %s (%s)", rule.Name, err.Error(), rule.Severity)
+			if l.options.Verbose {
+				msg += fmt.Sprintf(" - (%s)", rule.Description)
+			}
+
+			line := keyLine(filepath.Join(pkg.Dir, pkg.Filename), rule.Key)
+			var lineErr *LineError
+			if errors.As(err, &lineErr) {
+				line = lineErr.Line
+			}
+
+			failedRules = append(failedRules, EvalRuleError{
+				Rule:    rule,
+				Error:   fmt.Errorf(msg),
+				Message: err.Error(),
+				Line:    line,
 			})
+			if l.options.FailFast {
+				break
+			}
 		}
 	}
-
-	return results, nil
+	return failedRules
 }
 
 // Print prints the result to stdout.
 func (l *Linter) Print(result Result) {
 	foundAny := false
 	for _, res := range result {
+		if len(res.Fixed) > 0 {
+			l.logger.Printf("Package: %s: fixed %s\n", res.File, strings.Join(res.Fixed, ", "))
+		}
 		if res.Errors.WrapErrors() != nil {
 			foundAny = true
 			l.logger.Printf("Package: %s: %s\n", res.File, res.Errors.WrapErrors())
@@ -130,12 +339,48 @@ func (l *Linter) Print(result Result) {
 	}
 }
 
-// PrintRules prints the rules to stdout.
-func (l *Linter) PrintRules() {
+// PrintRules prints the rules to stdout, as the lint config configures them.
+func (l *Linter) PrintRules() error {
+	configured, err := l.Rules()
+	if err != nil {
+		return err
+	}
+	enabled := make(map[string]Definition, len(configured))
+	for _, rule := range configured {
+		enabled[rule.Name] = rule
+	}
 	l.logger.Println("Available rules:")
-	for _, rule := range AllRules(l) {
-		l.logger.Printf("* %s: %s\n", rule.Name, cases.Title(language.Und).String(rule.Description))
+	for _, rule := range l.allRules() {
+		status := "disabled"
+		if r, ok := enabled[rule.Name]; ok {
+			status = string(r.Severity)
+		}
+		l.logger.Printf("* %s: %s (%s)\n", rule.Name, cases.Title(language.Und).String(rule.Description), status)
 	}
+	return nil
+}
+
+// allRules returns the built-in rules and the registered ones, whatever the lint config says.
+func (l *Linter) allRules() Rules {
+	rules := AllRules(l)
+	for _, registered := range registeredRules {
+		rules = append(rules, definitionOf(registered))
+	}
+	return rules
+}
+
+// Rules returns the rules to evaluate: the built-in and registered ones, as the lint config configures them, without
+// the disabled ones.
+func (l *Linter) Rules() (Rules, error) {
+	rules := l.allRules()
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if seen[rule.Name] {
+			return nil, fmt.Errorf("rule %s is registered more than once", rule.Name)
+		}
+		seen[rule.Name] = true
+	}
+	return l.options.Config.apply(rules)
 }
 
 // checkIfMakefileExists returns a ConditionFunc that checks if the Makefile exists.
@@ -148,6 +393,74 @@ func (l *Linter) checkIfMakefileExists() ConditionFunc {
 	}
 }
 
+// checkIfProviderIndexesSet returns a ConditionFunc that checks if APKINDEXes to search for providers were given.
+func (l *Linter) checkIfProviderIndexesSet() ConditionFunc {
+	return func() bool {
+		return len(l.options.ProviderIndexes) > 0
+	}
+}
+
+// hasProvider returns true if a linted package, or a package of the provider indexes, satisfies the constraint.
+func (l *Linter) hasProvider(c dag.Constraint) (bool, error) {
+	for _, p := range l.localPackages {
+		if providesLocally(p.Config, c) {
+			return true, nil
+		}
+	}
+
+	// Lazy load the indexes, once for all packages linted concurrently.
+	l.providerIndexesOnce.Do(func() {
+		for _, src := range l.options.ProviderIndexes {
+			idx, err := index.Open(l.context(), src)
+			if err != nil {
+				l.providerIndexesErr = errors.Wrapf(err, "failed to load provider index %s", src)
+				return
+			}
+			l.providerIndexes = append(l.providerIndexes, idx)
+		}
+	})
+	if l.providerIndexesErr != nil {
+		return false, l.providerIndexesErr
+	}
+	for _, idx := range l.providerIndexes {
+		if len(dag.WhoProvidesInIndex(idx, c)) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// providesLocally returns true if the package or one of the subpackages of config, or what they declare to provide,
+// satisfies the constraint.
+func providesLocally(config build.Configuration, c dag.Constraint) bool {
+	version := fmt.Sprintf("%s-r%d", config.Package.Version, config.Package.Epoch)
+	provides := func(name string, deps build.Dependencies) bool {
+		if c.Matches(name, version) {
+			return true
+		}
+		for _, prov := range deps.Provides {
+			name, v := dag.ParseProvides(prov)
+			if v == "" || strings.Contains(v, "${{") {
+				v = version
+			}
+			if c.Matches(name, v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if provides(config.Package.Name, config.Package.Dependencies) {
+		return true
+	}
+	for i := range config.Subpackages {
+		if provides(config.Subpackages[i].Name, config.Subpackages[i].Dependencies) {
+			return true
+		}
+	}
+	return false
+}
+
 // readMakefile reads the Makefile from the file.
 func (l *Linter) readMakefile() error {
 	cmd := exec.Command("make", "-C", l.options.Path, "list") //nolint: gosec
@@ -164,11 +477,12 @@ func (l *Linter) readMakefile() error {
 
 // checkMakefile checks if the given package name is exists in the Makefile.
 func (l *Linter) checkMakefile(packageName string) (bool, error) {
-	// Lazy load the Makefile.
-	if l.makefileBytes == nil {
-		if err := l.readMakefile(); err != nil {
-			return false, err
-		}
+	// Lazy load the Makefile, once for all packages linted concurrently.
+	l.makefileOnce.Do(func() {
+		l.makefileErr = l.readMakefile()
+	})
+	if l.makefileErr != nil {
+		return false, l.makefileErr
 	}
 
 	scanner := bufio.NewScanner(bytes.NewReader(l.makefileBytes))
@@ -184,3 +498,34 @@ func (l *Linter) checkMakefile(packageName string) (bool, error) {
 	// If we didn't find the package in the Makefile we return false.
 	return false, nil
 }
+
+// keyLine returns the line of a key of a config, given as a dotted path like environment.contents.packages, or of the
+// deepest of its parents the config has. It returns the line of the package key for an empty path, and the first line
+// if the config can't be read.
+func keyLine(path, key string) int {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 1
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil || len(doc.Content) == 0 {
+		return 1
+	}
+	if key == "" {
+		key = "package"
+	}
+	n, line := doc.Content[0], 1
+	for _, k := range strings.Split(key, ".") {
+		found := false
+		for i := 0; n.Kind == yaml.MappingNode && i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == k {
+				n, line, found = n.Content[i+1], n.Content[i].Line, true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return line
+}
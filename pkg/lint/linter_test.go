@@ -1,10 +1,12 @@
 package lint
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func newTestLinterWithDir(path string) *Linter {
@@ -41,3 +43,24 @@ func TestLinter_Dir(t *testing.T) {
 		})
 	}
 }
+
+func TestLinter_Packages(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"files/forbidden-repository.yaml", "files/missing-copyright.yaml"} {
+		b, err := os.ReadFile(filepath.Join("testdata", f))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, filepath.Base(f)), b, 0o600))
+	}
+
+	got, err := New(WithPath(dir)).Lint()
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+
+	got, err = New(WithPath(dir), WithPackages("missing-copyright")).Lint()
+	require.NoError(t, err)
+	require.Len(t, got, 1, "only the given package should be linted")
+	assert.Equal(t, "missing-copyright", got[0].File)
+
+	_, err = New(WithPath(dir), WithPackages("missing")).Lint()
+	assert.Error(t, err)
+}
@@ -1,5 +1,7 @@
 package lint
 
+import "context"
+
 // Options represents the options to configure the linter.
 type Options struct {
 	// Path is the path to the file or directory to lint
@@ -10,6 +12,40 @@ type Options struct {
 
 	// Skip rules removes the given slice of rules to be checked
 	SkipRules []string
+
+	// Profile records the cost of every rule evaluation, and orders the rules by the cost it recorded before.
+	Profile *Profile
+
+	// Jobs is how many packages are linted concurrently.
+	Jobs int
+
+	// FailFast stops evaluating the rules of a package once one of them fails.
+	FailFast bool
+
+	// ProviderIndexes are the APKINDEXes, as URLs or paths, searched for the dependencies, and providers of virtual
+	// dependencies like cmd:cc, that no linted package provides.
+	ProviderIndexes []string
+
+	// Context stops linting once it's canceled, the packages not linted yet aren't.
+	Context context.Context
+
+	// Packages restricts linting to the named packages of Path, all of them if empty. The other packages are still
+	// read, to resolve dependencies against.
+	Packages []string
+
+	// PipelineDir is the directory of the repo-local pipelines steps can use besides the built-in ones, the pipelines
+	// directory of Path if empty.
+	PipelineDir string
+
+	// Config configures the rules, enabling or disabling them, overriding their severity or excluding packages from
+	// them.
+	Config *Config
+
+	// Fix applies the fixes of the rules that have one to the configs that fail them.
+	Fix bool
+
+	// CPESuggester suggests the CPEs of the packages missing a package.cpe, none are if it's nil.
+	CPESuggester CPESuggester
 }
 
 // Option represents a linter option.
@@ -35,3 +71,73 @@ func WithSkipRules(skipRules []string) Option {
 		o.SkipRules = skipRules
 	}
 }
+
+// WithProfile sets the profile to order rules by and record their cost in.
+func WithProfile(profile *Profile) Option {
+	return func(o *Options) {
+		o.Profile = profile
+	}
+}
+
+// WithJobs sets how many packages are linted concurrently.
+func WithJobs(jobs int) Option {
+	return func(o *Options) {
+		o.Jobs = jobs
+	}
+}
+
+// WithFailFast sets the fail fast option.
+func WithFailFast(failFast bool) Option {
+	return func(o *Options) {
+		o.FailFast = failFast
+	}
+}
+
+// WithProviderIndexes sets the APKINDEXes to search for dependencies and providers of virtual dependencies.
+func WithProviderIndexes(indexes ...string) Option {
+	return func(o *Options) {
+		o.ProviderIndexes = indexes
+	}
+}
+
+// WithContext sets the context that stops linting once canceled.
+func WithContext(ctx context.Context) Option {
+	return func(o *Options) {
+		o.Context = ctx
+	}
+}
+
+// WithPackages sets the names of the packages to lint, rather than all of them.
+func WithPackages(names ...string) Option {
+	return func(o *Options) {
+		o.Packages = names
+	}
+}
+
+// WithPipelineDir sets the directory of the repo-local pipelines.
+func WithPipelineDir(dir string) Option {
+	return func(o *Options) {
+		o.PipelineDir = dir
+	}
+}
+
+// WithConfig sets the configuration of the rules.
+func WithConfig(config *Config) Option {
+	return func(o *Options) {
+		o.Config = config
+	}
+}
+
+// WithFix sets the fix option.
+func WithFix(fix bool) Option {
+	return func(o *Options) {
+		o.Fix = fix
+	}
+}
+
+// WithCPESuggester sets what suggests the CPEs of packages missing one.
+func WithCPESuggester(suggester CPESuggester) Option {
+	return func(o *Options) {
+		o.CPESuggester = suggester
+	}
+}
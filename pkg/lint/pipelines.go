@@ -0,0 +1,206 @@
+package lint
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// pipelineDefinition is what a step that uses a pipeline is checked against: the inputs it takes, and, if it's
+// deprecated, what to use instead.
+type pipelineDefinition struct {
+	Inputs map[string]build.Input `yaml:"inputs"`
+	// Deprecated is what to use instead of the pipeline, which is deprecated if it's set.
+	Deprecated string `yaml:"deprecated"`
+}
+
+// builtinPipelines are the pipelines melange has built in, with their inputs.
+var builtinPipelines = map[string]pipelineDefinition{
+	"autoconf/configure":    {Inputs: inputs("dir", "host", "build")},
+	"autoconf/make":         {Inputs: inputs("dir", "opts")},
+	"autoconf/make-install": {Inputs: inputs("dir")},
+	"cmake/build":           {Inputs: inputs("output-dir")},
+	"cmake/configure":       {Inputs: inputs("output-dir", "opts")},
+	"cmake/install":         {Inputs: inputs("output-dir")},
+	"fetch":                 {Inputs: inputs("strip-components", "extract", "expected-sha256", "expected-sha512", "uri!", "timeout", "dns-timeout", "retry-limit")},
+	"git-checkout":          {Inputs: inputs("repository!", "destination", "depth", "branch", "tag", "expected-commit")},
+	"go/build":              {Inputs: inputs("packages!", "tags", "output!", "modroot", "prefix", "ldflags", "install-dir")},
+	"go/install":            {Inputs: inputs("package!", "version", "prefix", "install-dir", "ldflags", "tags")},
+	"meson/compile":         {Inputs: inputs("output-dir")},
+	"meson/configure":       {Inputs: inputs("output-dir", "opts")},
+	"meson/install":         {Inputs: inputs("output-dir")},
+	"patch":                 {Inputs: inputs("strip-components", "patches!")},
+	"ruby/build":            {Inputs: inputs("gem!", "output", "opts")},
+	"ruby/clean":            {},
+	"ruby/install":          {Inputs: inputs("gem", "gem-file", "version!", "opts")},
+	"split/dev":             {},
+	"split/infodir":         {},
+	"split/locales":         {},
+	"split/manpages":        {},
+	"split/static":          {},
+	"strip":                 {},
+}
+
+// inputs returns the inputs of the given names, the ones ending with ! being required.
+func inputs(names ...string) map[string]build.Input {
+	m := make(map[string]build.Input, len(names))
+	for _, name := range names {
+		name, required := strings.CutSuffix(name, "!")
+		m[name] = build.Input{Required: required}
+	}
+	return m
+}
+
+// pipelineDir returns the directory of the repo-local pipelines, the pipelines directory next to the linted configs
+// unless the PipelineDir option is set.
+func (l *Linter) pipelineDir() string {
+	if l.options.PipelineDir != "" {
+		return l.options.PipelineDir
+	}
+	dir := l.options.Path
+	if fi, err := os.Stat(dir); err == nil && !fi.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	return filepath.Join(dir, "pipelines")
+}
+
+// readPipelines reads the repo-local pipelines, named after their path in the pipeline directory without the .yaml
+// extension, as melange does.
+func (l *Linter) readPipelines() error {
+	dir := l.pipelineDir()
+	l.pipelines = make(map[string]pipelineDefinition)
+	if _, err := os.Stat(dir); os.IsNotExist(err) && l.options.PipelineDir == "" {
+		return nil
+	}
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".yaml" {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var p pipelineDefinition
+		if err := yaml.Unmarshal(b, &p); err != nil {
+			return errors.Wrapf(err, "failed to parse pipeline %s", path)
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		l.pipelines[strings.TrimSuffix(filepath.ToSlash(rel), ".yaml")] = p
+		return nil
+	})
+}
+
+// pipeline returns the definition of the pipeline a step uses, the repo-local one taking precedence over the built-in
+// one like it does for melange.
+func (l *Linter) pipeline(uses string) (pipelineDefinition, bool, error) {
+	// Lazy load the repo-local pipelines, once for all packages linted concurrently.
+	l.pipelinesOnce.Do(func() {
+		l.pipelinesErr = l.readPipelines()
+	})
+	if l.pipelinesErr != nil {
+		return pipelineDefinition{}, false, errors.Wrapf(l.pipelinesErr, "failed to read pipelines from %s", l.pipelineDir())
+	}
+	if p, ok := l.pipelines[uses]; ok {
+		return p, true, nil
+	}
+	p, ok := builtinPipelines[uses]
+	return p, ok, nil
+}
+
+// pipelineStep is a step of a config, and where it is in the config, e.g. pipeline[1] or subpackages[0].pipeline[2].
+type pipelineStep struct {
+	build.Pipeline
+	location string
+}
+
+// allSteps returns the steps of the pipelines of the package and its subpackages, nested ones included.
+func allSteps(config build.Configuration) []pipelineStep {
+	var steps []pipelineStep
+	var walk func(prefix string, pipeline []build.Pipeline)
+	walk = func(prefix string, pipeline []build.Pipeline) {
+		for i, p := range pipeline {
+			location := fmt.Sprintf("%spipeline[%d]", prefix, i)
+			steps = append(steps, pipelineStep{Pipeline: p, location: location})
+			walk(location+".", p.Pipeline)
+		}
+	}
+	walk("", config.Pipeline)
+	for i := range config.Subpackages {
+		walk(fmt.Sprintf("subpackages[%d].", i), config.Subpackages[i].Pipeline)
+	}
+	return steps
+}
+
+// pipelineSteps returns the steps of the pipelines of the package and its subpackages that use a pipeline, nested
+// ones included.
+func pipelineSteps(config build.Configuration) []pipelineStep {
+	var steps []pipelineStep
+	for _, step := range allSteps(config) {
+		if step.Uses != "" {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// checkPipelineUses returns the problems with the pipelines the steps of config use: pipelines that are neither
+// built in nor repo-local, required inputs without a value and inputs the pipelines don't take.
+func (l *Linter) checkPipelineUses(config build.Configuration) ([]string, error) {
+	var problems []string
+	for _, step := range pipelineSteps(config) {
+		p, ok, err := l.pipeline(step.Uses)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s uses unknown pipeline %s", step.location, step.Uses))
+			continue
+		}
+
+		var missing, unknown []string
+		for name, input := range p.Inputs {
+			if input.Required && input.Default == "" && step.With[name] == "" {
+				missing = append(missing, name)
+			}
+		}
+		for name := range step.With {
+			if _, ok := p.Inputs[name]; !ok {
+				unknown = append(unknown, name)
+			}
+		}
+		sort.Strings(missing)
+		sort.Strings(unknown)
+		if len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("%s (%s) is missing required input %s", step.location, step.Uses, strings.Join(missing, ", ")))
+		}
+		if len(unknown) > 0 {
+			problems = append(problems, fmt.Sprintf("%s (%s) has unknown input %s", step.location, step.Uses, strings.Join(unknown, ", ")))
+		}
+	}
+	return problems, nil
+}
+
+// checkDeprecatedPipelines returns the steps of config that use deprecated pipelines, with what to use instead.
+func (l *Linter) checkDeprecatedPipelines(config build.Configuration) ([]string, error) {
+	var deprecated []string
+	for _, step := range pipelineSteps(config) {
+		p, ok, err := l.pipeline(step.Uses)
+		if err != nil {
+			return nil, err
+		}
+		if ok && p.Deprecated != "" {
+			deprecated = append(deprecated, fmt.Sprintf("%s uses deprecated pipeline %s, %s", step.location, step.Uses, p.Deprecated))
+		}
+	}
+	return deprecated, nil
+}
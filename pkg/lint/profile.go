@@ -0,0 +1,117 @@
+package lint
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RuleProfile is the accumulated cost and signal of a rule across lint runs.
+type RuleProfile struct {
+	// Runs is how many times the rule was evaluated.
+	Runs int `json:"runs"`
+
+	// Failures is how many of those evaluations found an issue.
+	Failures int `json:"failures"`
+
+	// Duration is the total time spent evaluating the rule.
+	Duration time.Duration `json:"duration"`
+}
+
+// costPerFailure is the expected time spent evaluating the rule for every issue it finds. Counts are smoothed, so
+// rules that never failed yet aren't infinitely expensive.
+func (r RuleProfile) costPerFailure() float64 {
+	if r.Runs == 0 {
+		return 0
+	}
+	mean := float64(r.Duration) / float64(r.Runs)
+	return mean * float64(r.Runs+1) / float64(r.Failures+1)
+}
+
+// Profile records how long each rule takes and how often it fails, so later runs can evaluate the cheapest rules
+// that fail the most first. It's safe for concurrent use.
+type Profile struct {
+	mu    sync.Mutex
+	Rules map[string]*RuleProfile `json:"rules"`
+}
+
+// NewProfile returns an empty Profile.
+func NewProfile() *Profile {
+	return &Profile{Rules: make(map[string]*RuleProfile)}
+}
+
+// ReadProfile reads a Profile written by Write. A missing file is an empty Profile, so the first run can create it.
+func ReadProfile(path string) (*Profile, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewProfile(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p := NewProfile()
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, err
+	}
+	if p.Rules == nil {
+		p.Rules = make(map[string]*RuleProfile)
+	}
+	return p, nil
+}
+
+// Write writes the Profile to path as JSON.
+func (p *Profile) Write(path string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+func (p *Profile) record(rule string, d time.Duration, failed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r, ok := p.Rules[rule]
+	if !ok {
+		r = &RuleProfile{}
+		p.Rules[rule] = r
+	}
+	r.Runs++
+	r.Duration += d
+	if failed {
+		r.Failures++
+	}
+}
+
+func (p *Profile) rule(name string) RuleProfile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if r, ok := p.Rules[name]; ok {
+		return *r
+	}
+	return RuleProfile{}
+}
+
+// orderRules returns the rules in the order to evaluate them: the ones with the lowest cost per failure in the profile
+// first, and the expensive ones last. Rules the profile doesn't know yet keep their order, ahead of the others, so
+// they get profiled. The profile can be nil.
+func orderRules(rules Rules, profile *Profile) Rules {
+	ordered := make(Rules, len(rules))
+	copy(ordered, rules)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Expensive != ordered[j].Expensive {
+			return !ordered[i].Expensive
+		}
+		if profile == nil {
+			return false
+		}
+		return profile.rule(ordered[i].Name).costPerFailure() < profile.rule(ordered[j].Name).costPerFailure()
+	})
+	return ordered
+}
@@ -0,0 +1,91 @@
+package lint
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderRules(t *testing.T) {
+	rules := Rules{
+		{Name: "makefile", Expensive: true},
+		{Name: "slow"},
+		{Name: "fast-rare"},
+		{Name: "fast-frequent"},
+		{Name: "unknown"},
+	}
+
+	names := func(rules Rules) []string {
+		var out []string
+		for _, r := range rules {
+			out = append(out, r.Name)
+		}
+		return out
+	}
+
+	// without a profile, only expensive rules move
+	assert.Equal(t, []string{"slow", "fast-rare", "fast-frequent", "unknown", "makefile"}, names(orderRules(rules, nil)))
+
+	profile := NewProfile()
+	for i := 0; i < 10; i++ {
+		profile.record("slow", 10*time.Millisecond, i%2 == 0)
+		profile.record("fast-rare", time.Millisecond, false)
+		profile.record("fast-frequent", time.Millisecond, i%2 == 0)
+		profile.record("makefile", time.Microsecond, true)
+	}
+	assert.Equal(t, []string{"unknown", "fast-frequent", "fast-rare", "slow", "makefile"}, names(orderRules(rules, profile)))
+}
+
+func TestProfile_ReadWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profile.json")
+
+	// a missing profile is an empty one
+	profile, err := ReadProfile(path)
+	require.NoError(t, err)
+	assert.Empty(t, profile.Rules)
+
+	profile.record("bad-version", 2*time.Millisecond, true)
+	profile.record("bad-version", time.Millisecond, false)
+	require.NoError(t, profile.Write(path))
+
+	got, err := ReadProfile(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*RuleProfile{
+		"bad-version": {Runs: 2, Failures: 1, Duration: 3 * time.Millisecond},
+	}, got.Rules)
+}
+
+func TestLinter_Profile(t *testing.T) {
+	sequential, err := New(WithPath(filepath.Join("testdata", "files"))).Lint()
+	require.NoError(t, err)
+
+	profile := NewProfile()
+	concurrent, err := New(WithPath(filepath.Join("testdata", "files")), WithJobs(4), WithProfile(profile)).Lint()
+	require.NoError(t, err)
+
+	// the same issues are found, in the same order
+	require.Len(t, concurrent, len(sequential))
+	for i := range sequential {
+		assert.Equal(t, sequential[i].File, concurrent[i].File)
+		require.Len(t, concurrent[i].Errors, len(sequential[i].Errors))
+		for j := range sequential[i].Errors {
+			assert.Equal(t, sequential[i].Errors[j].Error, concurrent[i].Errors[j].Error)
+		}
+	}
+
+	require.Contains(t, profile.Rules, "bad-version")
+	assert.Positive(t, profile.Rules["bad-version"].Runs)
+	assert.Positive(t, profile.Rules["bad-version"].Failures)
+}
+
+func TestLinter_FailFast(t *testing.T) {
+	result, err := New(WithPath(filepath.Join("testdata", "files")), WithFailFast(true)).Lint()
+	require.NoError(t, err)
+	require.NotEmpty(t, result)
+	for _, r := range result {
+		assert.Len(t, r.Errors, 1, r.File)
+	}
+}
@@ -0,0 +1,143 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+// provider is a package, subpackage or provides of a linted config, which dependencies on its name resolve to.
+type provider struct {
+	// config is the name of the package of the config.
+	config string
+	// kind is package, subpackage or provides.
+	kind string
+	// version is the full version of the package, or the version of the provides, which is empty if it has none.
+	version  string
+	priority int
+}
+
+func (p provider) String() string {
+	switch p.kind {
+	case "package":
+		return fmt.Sprintf("built by %s", p.config)
+	case "subpackage":
+		return fmt.Sprintf("a subpackage of %s", p.config)
+	}
+	if p.version == "" {
+		return fmt.Sprintf("provided by %s", p.config)
+	}
+	return fmt.Sprintf("provided by %s at %s", p.config, p.version)
+}
+
+// conflicts returns true if dependencies on the name both providers provide don't resolve to one of them
+// deterministically: package names have to be unique, and provides are only told apart by their version or their
+// provider-priority.
+func (p provider) conflicts(other provider) bool {
+	if p.config == other.config {
+		return false
+	}
+	if p.kind != "provides" || other.kind != "provides" {
+		return true
+	}
+	versionsOverlap := p.version == "" || other.version == "" || p.version == other.version
+	return versionsOverlap && p.priority == other.priority
+}
+
+// indexProviders returns the packages, subpackages and provides of the configs by the name they provide.
+func indexProviders(packages map[string]*melange.Packages) map[string][]provider {
+	providers := make(map[string][]provider)
+	for _, p := range packages {
+		config := p.Config
+		version := fmt.Sprintf("%s-r%d", config.Package.Version, config.Package.Epoch)
+		add := func(kind, name string, deps build.Dependencies) {
+			providers[name] = append(providers[name], provider{config: config.Package.Name, kind: kind, version: version})
+			for _, prov := range deps.Provides {
+				name, v := dag.ParseProvides(prov)
+				if strings.Contains(v, "${{") {
+					v = version
+				}
+				providers[name] = append(providers[name], provider{config: config.Package.Name, kind: "provides", version: v, priority: deps.ProviderPriority})
+			}
+		}
+		add("package", config.Package.Name, config.Package.Dependencies)
+		for i := range config.Subpackages {
+			add("subpackage", config.Subpackages[i].Name, config.Subpackages[i].Dependencies)
+		}
+	}
+	return providers
+}
+
+// packageFiles returns the configs of the directory by the name of their package, which several of them may have
+// though only one of them is read.
+func packageFiles(dir string) (map[string][]string, error) {
+	files := make(map[string][]string)
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var check melange.ConfigCheck
+		if err := yaml.Unmarshal(b, &check); err != nil || check.Package.Name == "" || check.Package.Version == "" {
+			// not a melange config
+			continue
+		}
+		files[check.Package.Name] = append(files[check.Package.Name], filepath.Base(path))
+	}
+	return files, nil
+}
+
+// checkDuplicateProviders returns what a config provides that another config provides too: its package name, the
+// names of its subpackages, and its provides at overlapping versions.
+func (l *Linter) checkDuplicateProviders(config build.Configuration) []string {
+	var problems []string
+	if files := l.packageFiles[config.Package.Name]; len(files) > 1 {
+		problems = append(problems, fmt.Sprintf("package %s is built by %s", config.Package.Name, strings.Join(files, ", ")))
+	}
+
+	names := []string{config.Package.Name}
+	provides := append([]string{}, config.Package.Dependencies.Provides...)
+	for i := range config.Subpackages {
+		names = append(names, config.Subpackages[i].Name)
+		provides = append(provides, config.Subpackages[i].Dependencies.Provides...)
+	}
+	for _, prov := range provides {
+		name, _ := dag.ParseProvides(prov)
+		names = append(names, name)
+	}
+
+	var reported []string
+	for _, name := range names {
+		if slices.Contains(reported, name) {
+			continue
+		}
+		for _, mine := range l.providers[name] {
+			if mine.config != config.Package.Name {
+				continue
+			}
+			var others []string
+			for _, other := range l.providers[name] {
+				if mine.conflicts(other) && !slices.Contains(others, other.String()) {
+					others = append(others, other.String())
+				}
+			}
+			if len(others) > 0 {
+				problems = append(problems, fmt.Sprintf("%s is %s, but also %s", name, mine, strings.Join(others, " and ")))
+				reported = append(reported, name)
+				break
+			}
+		}
+	}
+	return problems
+}
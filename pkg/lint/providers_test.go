@@ -0,0 +1,79 @@
+package lint
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinter_DuplicateProviders(t *testing.T) {
+	l := New(WithPath(filepath.Join("testdata", "providers")))
+	got, err := l.Lint()
+	require.NoError(t, err)
+
+	duplicates := make(map[string]string)
+	for _, r := range got {
+		for _, e := range r.Errors {
+			if e.Rule.Name == "duplicate-provider" {
+				duplicates[r.File] = e.Error.Error()
+			}
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"foo": "[duplicate-provider]: foo-dev is a subpackage of foo, but also a subpackage of bar, " +
+			"cmd:foo is provided by foo, but also provided by bar at 2.0.0-r0 (ERROR)",
+		"bar": "[duplicate-provider]: foo-dev is a subpackage of bar, but also a subpackage of foo, " +
+			"cmd:foo is provided by bar at 2.0.0-r0, but also provided by foo (ERROR)",
+		"baz": "[duplicate-provider]: package baz is built by baz-1.1.yaml, baz.yaml (ERROR)",
+	}, duplicates, "provides told apart by their version or provider-priority don't conflict")
+}
+
+func TestProvider_Conflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b provider
+		want bool
+	}{
+		{
+			name: "same config",
+			a:    provider{config: "foo", kind: "package"},
+			b:    provider{config: "foo", kind: "provides"},
+		},
+		{
+			name: "package names",
+			a:    provider{config: "foo", kind: "subpackage", version: "1.0.0-r0"},
+			b:    provider{config: "bar", kind: "provides", version: "2.0.0-r0"},
+			want: true,
+		},
+		{
+			name: "unversioned provides",
+			a:    provider{config: "foo", kind: "provides"},
+			b:    provider{config: "bar", kind: "provides", version: "2.0.0-r0"},
+			want: true,
+		},
+		{
+			name: "provides at the same version",
+			a:    provider{config: "foo", kind: "provides", version: "1.0.0-r0"},
+			b:    provider{config: "bar", kind: "provides", version: "1.0.0-r0"},
+			want: true,
+		},
+		{
+			name: "provides at different versions",
+			a:    provider{config: "foo", kind: "provides", version: "1.0.0-r0"},
+			b:    provider{config: "bar", kind: "provides", version: "2.0.0-r0"},
+		},
+		{
+			name: "provides with different priorities",
+			a:    provider{config: "foo", kind: "provides", priority: 1},
+			b:    provider{config: "bar", kind: "provides", priority: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.a.conflicts(tt.b))
+			assert.Equal(t, tt.want, tt.b.conflicts(tt.a))
+		})
+	}
+}
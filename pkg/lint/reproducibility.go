@@ -0,0 +1,82 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+)
+
+var (
+	// reMovingURI matches the uris of sources that change without their url changing, like the latest release or the
+	// tip of a branch
+	reMovingURI = regexp.MustCompile(`(?i)(^|[/._=-])(latest|nightly|snapshot|current|master|main|head)([/._-]|$)`)
+	// reDate matches commands that run date, which embeds the time of the build unless it's SOURCE_DATE_EPOCH
+	reDate = regexp.MustCompile("(^|[;&|(`]|\\$\\()\\s*date\\b")
+	// reDownload matches commands that download what the build uses, without a checksum to verify it by
+	reDownload = regexp.MustCompile(`(^|[;&|(]|\$\()\s*(curl|wget|git\s+clone)\b`)
+	// reLatestVersion matches the latest version of tools and images, like go install foo@latest
+	reLatestVersion = regexp.MustCompile(`[@:]latest\b`)
+)
+
+// unpinnedCheckouts returns the git-checkout steps of a config that check out neither a tag nor an expected-commit,
+// but the tip of a branch.
+func unpinnedCheckouts(config build.Configuration) []string {
+	var unpinned []string
+	for _, step := range pipelineSteps(config) {
+		if step.Uses != "git-checkout" || step.With["tag"] != "" || step.With["expected-commit"] != "" {
+			continue
+		}
+		branch := step.With["branch"]
+		if branch == "" {
+			branch = "the default branch"
+		}
+		unpinned = append(unpinned, fmt.Sprintf("%s checks out %s of %s, not a tag or an expected-commit", step.location, branch, step.With["repository"]))
+	}
+	return unpinned
+}
+
+// unverifiedFetches returns the fetch steps of a config without the checksum their sources are verified by.
+func unverifiedFetches(config build.Configuration) []string {
+	var unverified []string
+	for _, step := range pipelineSteps(config) {
+		if step.Uses == "fetch" && step.With["expected-sha256"] == "" && step.With["expected-sha512"] == "" {
+			unverified = append(unverified, fmt.Sprintf("%s fetches %s without expected-sha256 or expected-sha512", step.location, step.With["uri"]))
+		}
+	}
+	return unverified
+}
+
+// movingSourceURIs returns the fetch steps of a config whose uri points at sources that change without it changing,
+// like the latest release or the tip of a branch.
+func movingSourceURIs(config build.Configuration) []string {
+	var moving []string
+	for _, step := range pipelineSteps(config) {
+		if step.Uses == "fetch" && reMovingURI.MatchString(step.With["uri"]) {
+			moving = append(moving, fmt.Sprintf("%s fetches %s, which changes without its url changing", step.location, step.With["uri"]))
+		}
+	}
+	return moving
+}
+
+// runsMatching returns the commands of the steps of a config that match re, except the ones that also contain one of
+// the exceptions, described by what.
+func runsMatching(config build.Configuration, re *regexp.Regexp, what string, exceptions ...string) []string {
+	var found []string
+	for _, step := range allSteps(config) {
+	lines:
+		for _, line := range strings.Split(step.Runs, "\n") {
+			if !re.MatchString(line) {
+				continue
+			}
+			for _, e := range exceptions {
+				if strings.Contains(line, e) {
+					continue lines
+				}
+			}
+			found = append(found, fmt.Sprintf("%s %s: %s", step.location, what, strings.TrimSpace(line)))
+		}
+	}
+	return found
+}
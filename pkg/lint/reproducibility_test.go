@@ -0,0 +1,39 @@
+package lint
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReproducibilityPatterns(t *testing.T) {
+	tests := []struct {
+		re    *regexp.Regexp
+		line  string
+		match bool
+	}{
+		{reDate, `echo "built on $(date)"`, true},
+		{reDate, "date +%s > BUILD_TIME", true},
+		{reDate, "VERSION=`date +%Y%m%d`", true},
+		{reDate, "./configure && date", true},
+		{reDate, "apk update", false},
+		{reDate, "git log --date=iso", false},
+		{reDate, "echo the update date", false},
+		{reDownload, "curl -sL https://example.com/x -o x", true},
+		{reDownload, "  wget https://example.com/x", true},
+		{reDownload, "cd src && git clone https://example.com/x", true},
+		{reDownload, "make curl-config", false},
+		{reLatestVersion, "go install golang.org/x/tools/cmd/stringer@latest", true},
+		{reLatestVersion, "docker pull alpine:latest", true},
+		{reLatestVersion, "install -m644 latest.txt", false},
+		{reMovingURI, "https://example.com/releases/latest/download/x.tar.gz", true},
+		{reMovingURI, "https://github.com/example/x/archive/refs/heads/main.tar.gz", true},
+		{reMovingURI, "https://example.com/x-nightly.tar.gz", true},
+		{reMovingURI, "https://example.com/domain-${{package.version}}.tar.gz", false},
+		{reMovingURI, "https://github.com/example/x/archive/v${{package.version}}.tar.gz", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.match, tt.re.MatchString(tt.line), "%s on %q", tt.re, tt.line)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"chainguard.dev/melange/pkg/renovate"
@@ -15,6 +17,8 @@ import (
 	"golang.org/x/exp/slices"
 
 	"chainguard.dev/melange/pkg/build"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
 )
 
 var (
@@ -37,6 +41,15 @@ var (
 
 func init() { versionRegex.Longest() }
 
+// registeredRules are the rules registered besides the built-in ones.
+var registeredRules []Rule
+
+// Register adds rules to evaluate besides the built-in ones of AllRules, for forks to lint their own conventions. It
+// isn't safe to call concurrently with linting, so it's meant to be called from init functions.
+func Register(rules ...Rule) {
+	registeredRules = append(registeredRules, rules...)
+}
+
 // AllRules is a list of all available rules to evaluate.
 var AllRules = func(l *Linter) Rules { //nolint:gocyclo
 	return Rules{
@@ -44,6 +57,7 @@ var AllRules = func(l *Linter) Rules { //nolint:gocyclo
 			Name:        "no-makefile-entry-for-package",
 			Description: "every package should have a corresponding entry in Makefile",
 			Severity:    SeverityError,
+			Key:         "package.name",
 			LintFunc: func(config build.Configuration) error {
 				exist, err := l.checkMakefile(config.Package.Name)
 				if err != nil {
@@ -57,11 +71,13 @@ var AllRules = func(l *Linter) Rules { //nolint:gocyclo
 			ConditionFuncs: []ConditionFunc{
 				l.checkIfMakefileExists(),
 			},
+			Expensive: true,
 		},
 		{
 			Name:        "forbidden-repository-used",
 			Description: "do not specify a forbidden repository",
 			Severity:    SeverityError,
+			Key:         "environment.contents.repositories",
 			LintFunc: func(config build.Configuration) error {
 				for _, repo := range config.Environment.Contents.Repositories {
 					if slices.Contains(forbiddenRepositories, repo) {
@@ -75,6 +91,7 @@ var AllRules = func(l *Linter) Rules { //nolint:gocyclo
 			Name:        "forbidden-keyring-used",
 			Description: "do not specify a forbidden keyring",
 			Severity:    SeverityError,
+			Key:         "environment.contents.keyring",
 			LintFunc: func(config build.Configuration) error {
 				for _, keyring := range config.Environment.Contents.Keyring {
 					if slices.Contains(forbiddenKeyrings, keyring) {
@@ -88,6 +105,7 @@ var AllRules = func(l *Linter) Rules { //nolint:gocyclo
 			Name:        "valid-copyright-header",
 			Description: "every package should have a valid copyright header",
 			Severity:    SeverityInfo,
+			Key:         "package.copyright",
 			LintFunc: func(config build.Configuration) error {
 				if len(config.Package.Copyright) == 0 {
 					return fmt.Errorf("copyright header is missing")
@@ -104,6 +122,7 @@ var AllRules = func(l *Linter) Rules { //nolint:gocyclo
 			Name:        "contains-epoch",
 			Description: "every package should have an epoch",
 			Severity:    SeverityError,
+			Key:         "package.epoch",
 			LintFunc: func(_ build.Configuration) error {
 				var node yaml.Node
 				fileInfo, err := os.Stat(l.options.Path)
@@ -151,6 +170,7 @@ var AllRules = func(l *Linter) Rules { //nolint:gocyclo
 			Name:        "valid-pipeline-fetch-uri",
 			Description: "every fetch pipeline should have a valid uri",
 			Severity:    SeverityError,
+			Key:         "pipeline",
 			LintFunc: func(config build.Configuration) error {
 				for _, p := range config.Pipeline {
 					if p.Uses == "fetch" {
@@ -170,6 +190,7 @@ var AllRules = func(l *Linter) Rules { //nolint:gocyclo
 			Name:        "valid-pipeline-fetch-digest",
 			Description: "every fetch pipeline should have a valid digest",
 			Severity:    SeverityError,
+			Key:         "pipeline",
 			LintFunc: func(config build.Configuration) error {
 				for _, p := range config.Pipeline {
 					if p.Uses == "fetch" {
@@ -194,25 +215,146 @@ var AllRules = func(l *Linter) Rules { //nolint:gocyclo
 				return nil
 			},
 		},
+		{
+			Name:        "valid-pipeline-uses",
+			Description: "every step should use a built-in or repo-local pipeline, with its required inputs and no unknown ones",
+			Severity:    SeverityError,
+			Key:         "pipeline",
+			LintFunc: func(config build.Configuration) error {
+				problems, err := l.checkPipelineUses(config)
+				if err != nil {
+					return err
+				}
+				if len(problems) > 0 {
+					return fmt.Errorf("%s", strings.Join(problems, ", "))
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "deprecated-pipeline",
+			Description: "steps should not use deprecated pipelines",
+			Severity:    SeverityWarning,
+			Key:         "pipeline",
+			LintFunc: func(config build.Configuration) error {
+				deprecated, err := l.checkDeprecatedPipelines(config)
+				if err != nil {
+					return err
+				}
+				if len(deprecated) > 0 {
+					return fmt.Errorf("%s", strings.Join(deprecated, ", "))
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "https-uri",
+			Description: "the package url and the sources of fetch and git-checkout steps should be downloaded over https",
+			Severity:    SeverityWarning,
+			Key:         "pipeline",
+			LintFunc: func(config build.Configuration) error {
+				if uris := insecureURIs(config); len(uris) > 0 {
+					return fmt.Errorf("%s should use https", strings.Join(uris, ", "))
+				}
+				return nil
+			},
+			Fix: fixInsecureURIs,
+		},
 		{
 			Name:        "no-repeated-deps",
 			Description: "no repeated dependencies",
 			Severity:    SeverityError,
+			Key:         "environment.contents.packages",
 			LintFunc: func(config build.Configuration) error {
 				seen := map[string]struct{}{}
 				for _, p := range config.Environment.Contents.Packages {
-					if _, ok := seen[p]; ok {
-						return fmt.Errorf("package %s is duplicated in environment", p)
+					// foo and foo>=1.2 are the same dependency
+					name := p
+					if c, err := dag.ParseConstraint(p); err == nil {
+						name = c.Name
 					}
-					seen[p] = struct{}{}
+					if _, ok := seen[name]; ok {
+						return fmt.Errorf("package %s is duplicated in environment", name)
+					}
+					seen[name] = struct{}{}
 				}
 				return nil
 			},
 		},
+		{
+			Name:        "sorted-environment-packages",
+			Description: "the packages of the build environment should be sorted",
+			Severity:    SeverityInfo,
+			Key:         "environment.contents.packages",
+			LintFunc: func(config build.Configuration) error {
+				if !sort.StringsAreSorted(config.Environment.Contents.Packages) {
+					return fmt.Errorf("environment packages are not sorted")
+				}
+				return nil
+			},
+			Fix: sortEnvironmentPackages,
+			// Wolfi doesn't sort them, the packages it needs the most usually come first
+			Disabled: true,
+		},
+		{
+			Name:        "virtual-dependency-without-provider",
+			Description: "virtual dependencies like cmd:, so:, pc: and py3dist() should have a provider",
+			Severity:    SeverityError,
+			Key:         "environment.contents.packages",
+			LintFunc: func(config build.Configuration) error {
+				missing, err := l.dependenciesWithoutProvider(config, true)
+				if err != nil {
+					return err
+				}
+				if len(missing) > 0 {
+					return fmt.Errorf("no package provides %s", strings.Join(missing, ", "))
+				}
+				return nil
+			},
+			ConditionFuncs: []ConditionFunc{
+				l.checkIfProviderIndexesSet(),
+			},
+			Expensive: true,
+		},
+		{
+			Name:        "duplicate-provider",
+			Description: "configs should not build or provide what other configs do, which dependencies resolve to either of",
+			Severity:    SeverityError,
+			Key:         "package",
+			LintFunc: func(config build.Configuration) error {
+				return joinProblems(l.checkDuplicateProviders(config))
+			},
+		},
+		{
+			Name:        "unknown-dependency",
+			Description: "environment packages and runtime dependencies should be packages of the repository or of the provider indexes",
+			Severity:    SeverityError,
+			Key:         "environment.contents.packages",
+			LintFunc: func(config build.Configuration) error {
+				missing, err := l.dependenciesWithoutProvider(config, false)
+				if err != nil {
+					return err
+				}
+				for i, dep := range missing {
+					if name := l.closestPackageName(dep); name != "" {
+						missing[i] = fmt.Sprintf("%s (did you mean %s?)", dep, name)
+					}
+				}
+				if len(missing) > 0 {
+					return fmt.Errorf("no package is named %s", strings.Join(missing, ", "))
+				}
+				return nil
+			},
+			ConditionFuncs: []ConditionFunc{
+				l.checkIfProviderIndexesSet(),
+			},
+			Expensive: true,
+		},
 		{
 			Name:        "bad-template-var",
 			Description: "bad template variable",
 			Severity:    SeverityError,
+			Key:         "pipeline",
 			LintFunc: func(config build.Configuration) error {
 				badTemplateVars := []string{
 					"$pkgdir",
@@ -250,6 +392,7 @@ var AllRules = func(l *Linter) Rules { //nolint:gocyclo
 			Name:        "bad-version",
 			Description: "version is malformed",
 			Severity:    SeverityError,
+			Key:         "package.version",
 			LintFunc: func(config build.Configuration) error {
 				version := config.Package.Version
 				if len(versionRegex.FindAllStringSubmatch(version, -1)) == 0 {
@@ -262,6 +405,7 @@ var AllRules = func(l *Linter) Rules { //nolint:gocyclo
 			Name:        "valid-pipeline-git-checkout-commit",
 			Description: "every git-checkout pipeline should have a valid expected-commit",
 			Severity:    SeverityError,
+			Key:         "pipeline",
 			LintFunc: func(config build.Configuration) error {
 				for _, p := range config.Pipeline {
 					if p.Uses == "git-checkout" {
@@ -281,6 +425,7 @@ var AllRules = func(l *Linter) Rules { //nolint:gocyclo
 			Name:        "valid-pipeline-git-checkout-tag",
 			Description: "every git-checkout pipeline should have a tag",
 			Severity:    SeverityError,
+			Key:         "pipeline",
 			LintFunc: func(config build.Configuration) error {
 				for _, p := range config.Pipeline {
 					if p.Uses == "git-checkout" {
@@ -296,6 +441,7 @@ var AllRules = func(l *Linter) Rules { //nolint:gocyclo
 			Name:        "check-when-version-changes",
 			Description: "check comments to make sure they are updated when version changes",
 			Severity:    SeverityError,
+			Key:         "pipeline",
 			LintFunc: func(config build.Configuration) error {
 				re := regexp.MustCompile(`# CHECK-WHEN-VERSION-CHANGES: (.+)`)
 				var checkString = func(s string) error {
@@ -325,7 +471,152 @@ var AllRules = func(l *Linter) Rules { //nolint:gocyclo
 				return nil
 			},
 		},
+		{
+			Name:        "missing-update-config",
+			Description: "every package should have an update config",
+			Severity:    SeverityWarning,
+			LintFunc: func(config build.Configuration) error {
+				src, err := l.source(config)
+				if err != nil {
+					return err
+				}
+				ok, err := hasUpdateConfig(src)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return fmt.Errorf("update config is missing")
+				}
+				return nil
+			},
+			Fix: addUpdateConfig,
+			// wolfictl check update checks the update configs of Wolfi
+			Disabled: true,
+		},
+		{
+			Name:        "valid-indentation",
+			Description: "configs should be indented by two spaces",
+			Severity:    SeverityWarning,
+			LintFunc: func(config build.Configuration) error {
+				src, err := l.source(config)
+				if err != nil {
+					return err
+				}
+				var n yaml.Node
+				if err := yaml.Unmarshal(src, &n); err != nil {
+					return err
+				}
+				lines := misindented(&n, 1)
+				if len(lines) == 0 {
+					return nil
+				}
+				sort.Ints(lines)
+				lines = slices.Compact(lines)
+				var numbers []string
+				for _, line := range lines {
+					numbers = append(numbers, strconv.Itoa(line))
+				}
+				return fmt.Errorf("line %s is not indented by two spaces", strings.Join(numbers, ", "))
+			},
+			Fix: fixIndentation,
+		},
+		{
+			Name:        "unpinned-git-checkout",
+			Description: "git-checkout steps should check out a tag or an expected-commit, not the tip of a branch",
+			Severity:    SeverityWarning,
+			Key:         "pipeline",
+			LintFunc: func(config build.Configuration) error {
+				return joinProblems(unpinnedCheckouts(config))
+			},
+		},
+		{
+			Name:        "unverified-fetch",
+			Description: "fetch steps should verify their sources by an expected checksum",
+			Severity:    SeverityWarning,
+			Key:         "pipeline",
+			LintFunc: func(config build.Configuration) error {
+				return joinProblems(unverifiedFetches(config))
+			},
+		},
+		{
+			Name:        "moving-source-uri",
+			Description: "fetch steps should not fetch sources that change without their url changing, like the latest release",
+			Severity:    SeverityWarning,
+			Key:         "pipeline",
+			LintFunc: func(config build.Configuration) error {
+				return joinProblems(movingSourceURIs(config))
+			},
+		},
+		{
+			Name:        "build-timestamp",
+			Description: "builds should not embed the time they run at, but SOURCE_DATE_EPOCH",
+			Severity:    SeverityWarning,
+			Key:         "pipeline",
+			LintFunc: func(config build.Configuration) error {
+				return joinProblems(runsMatching(config, reDate, "embeds the build time", "SOURCE_DATE_EPOCH"))
+			},
+		},
+		{
+			Name:        "unverified-download",
+			Description: "steps should not download what builds use without verifying it, fetch and git-checkout do",
+			Severity:    SeverityWarning,
+			Key:         "pipeline",
+			LintFunc: func(config build.Configuration) error {
+				return joinProblems(runsMatching(config, reDownload, "downloads without a checksum"))
+			},
+		},
+		{
+			Name:        "latest-version",
+			Description: "steps should not install the latest version of tools or images, which changes between builds",
+			Severity:    SeverityWarning,
+			Key:         "pipeline",
+			LintFunc: func(config build.Configuration) error {
+				return joinProblems(runsMatching(config, reLatestVersion, "installs the latest version"))
+			},
+		},
+		{
+			Name:        "embedded-secret",
+			Description: "configs should not embed tokens, private keys or passwords, which leak once they're published",
+			Severity:    SeverityError,
+			LintFunc: func(config build.Configuration) error {
+				src, err := l.source(config)
+				if err != nil {
+					return err
+				}
+				secrets, line := findSecrets(src)
+				if len(secrets) == 0 {
+					return nil
+				}
+				return &LineError{Line: line, Err: joinProblems(secrets)}
+			},
+		},
+		{
+			Name:        "valid-cpe",
+			Description: "package.cpe should be an application's CPE, without a version, with a lowercase vendor and product",
+			Severity:    SeverityError,
+			Key:         "package.cpe",
+			LintFunc: func(config build.Configuration) error {
+				return joinProblems(l.checkCPE(config))
+			},
+		},
+		{
+			Name:        "missing-cpe",
+			Description: "packages whose vulnerabilities can't be matched by their name alone should have a package.cpe",
+			Severity:    SeverityWarning,
+			Key:         "package",
+			LintFunc:    l.checkMissingCPE,
+			Disabled:    true,
+			Expensive:   true,
+		},
+	}
+}
+
+// joinProblems returns the problems a rule found as an error, nil if there are none
+func joinProblems(problems []string) error {
+	if len(problems) == 0 {
+		return nil
 	}
+	return fmt.Errorf("%s", strings.Join(problems, ", "))
 }
 
 func containsKey(parentNode *yaml.Node, key string) error {
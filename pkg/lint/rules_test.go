@@ -1,12 +1,16 @@
 package lint
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/wolfi-dev/wolfictl/pkg/index"
+	"gitlab.alpinelinux.org/alpine/go/repository"
 )
 
 func TestLinter_Rules(t *testing.T) {
@@ -22,7 +26,7 @@ func TestLinter_Rules(t *testing.T) {
 				File: "missing-copyright",
 				Errors: EvalRuleErrors{
 					{
-						Rule: Rule{
+						Rule: Definition{
 							Name:     "valid-copyright-header",
 							Severity: SeverityInfo,
 						},
@@ -38,7 +42,7 @@ func TestLinter_Rules(t *testing.T) {
 				File: "forbidden-repository",
 				Errors: EvalRuleErrors{
 					{
-						Rule: Rule{
+						Rule: Definition{
 							Name:     "forbidden-repository-used",
 							Severity: SeverityError,
 						},
@@ -54,7 +58,7 @@ func TestLinter_Rules(t *testing.T) {
 				File: "forbidden-keyring",
 				Errors: EvalRuleErrors{
 					{
-						Rule: Rule{
+						Rule: Definition{
 							Name:     "forbidden-keyring-used",
 							Severity: SeverityError,
 						},
@@ -70,7 +74,7 @@ func TestLinter_Rules(t *testing.T) {
 				File: "wrong-pipeline-fetch-uri",
 				Errors: EvalRuleErrors{
 					{
-						Rule: Rule{
+						Rule: Definition{
 							Name:     "valid-pipeline-fetch-uri",
 							Severity: SeverityError,
 						},
@@ -86,7 +90,7 @@ func TestLinter_Rules(t *testing.T) {
 				File: "wrong-pipeline-fetch-digest",
 				Errors: EvalRuleErrors{
 					{
-						Rule: Rule{
+						Rule: Definition{
 							Name:     "valid-pipeline-fetch-digest",
 							Severity: SeverityError,
 						},
@@ -102,7 +106,23 @@ func TestLinter_Rules(t *testing.T) {
 				File: "duplicated-package",
 				Errors: EvalRuleErrors{
 					{
-						Rule: Rule{
+						Rule: Definition{
+							Name:     "no-repeated-deps",
+							Severity: SeverityError,
+						},
+						Error: fmt.Errorf("[no-repeated-deps]: package foo is duplicated in environment (ERROR)"),
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			file: "duplicated-versioned-package.yaml",
+			want: EvalResult{
+				File: "duplicated-versioned-package",
+				Errors: EvalRuleErrors{
+					{
+						Rule: Definition{
 							Name:     "no-repeated-deps",
 							Severity: SeverityError,
 						},
@@ -118,7 +138,7 @@ func TestLinter_Rules(t *testing.T) {
 				File: "bad-template-var",
 				Errors: EvalRuleErrors{
 					{
-						Rule: Rule{
+						Rule: Definition{
 							Name:     "bad-template-var",
 							Severity: SeverityError,
 						},
@@ -134,7 +154,7 @@ func TestLinter_Rules(t *testing.T) {
 				File: "bad-version",
 				Errors: EvalRuleErrors{
 					{
-						Rule: Rule{
+						Rule: Definition{
 							Name:     "bad-version",
 							Severity: SeverityError,
 						},
@@ -149,7 +169,7 @@ func TestLinter_Rules(t *testing.T) {
 				File: "wrong-pipeline-git-checkout-commit",
 				Errors: EvalRuleErrors{
 					{
-						Rule: Rule{
+						Rule: Definition{
 							Name:     "valid-pipeline-git-checkout-commit",
 							Severity: SeverityError,
 						},
@@ -165,7 +185,7 @@ func TestLinter_Rules(t *testing.T) {
 				File: "missing-pipeline-git-checkout-commit",
 				Errors: EvalRuleErrors{
 					{
-						Rule: Rule{
+						Rule: Definition{
 							Name:     "valid-pipeline-git-checkout-commit",
 							Severity: SeverityError,
 						},
@@ -181,7 +201,7 @@ func TestLinter_Rules(t *testing.T) {
 				File: "wrong-pipeline-git-checkout-tag",
 				Errors: EvalRuleErrors{
 					{
-						Rule: Rule{
+						Rule: Definition{
 							Name:     "valid-pipeline-git-checkout-tag",
 							Severity: SeverityError,
 						},
@@ -197,7 +217,7 @@ func TestLinter_Rules(t *testing.T) {
 				File: "nolint",
 				Errors: EvalRuleErrors{
 					{
-						Rule: Rule{
+						Rule: Definition{
 							Name:     "valid-copyright-header",
 							Severity: SeverityInfo,
 						},
@@ -213,7 +233,7 @@ func TestLinter_Rules(t *testing.T) {
 				File: "no-epoch",
 				Errors: EvalRuleErrors{
 					{
-						Rule: Rule{
+						Rule: Definition{
 							Name:     "contains-epoch",
 							Severity: SeverityError,
 						},
@@ -229,7 +249,7 @@ func TestLinter_Rules(t *testing.T) {
 				File: "check-version-matches",
 				Errors: EvalRuleErrors{
 					{
-						Rule: Rule{
+						Rule: Definition{
 							Name:     "check-when-version-changes",
 							Severity: SeverityError,
 						},
@@ -245,7 +265,7 @@ func TestLinter_Rules(t *testing.T) {
 				File: "check-version-matches",
 				Errors: EvalRuleErrors{
 					{
-						Rule: Rule{
+						Rule: Definition{
 							Name:     "check-when-version-changes",
 							Severity: SeverityError,
 						},
@@ -255,6 +275,142 @@ func TestLinter_Rules(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			file: "wrong-pipeline-uses.yaml",
+			want: EvalResult{
+				File: "wrong-pipeline-uses",
+				Errors: EvalRuleErrors{
+					{
+						Rule: Definition{
+							Name:     "valid-pipeline-fetch-uri",
+							Severity: SeverityError,
+						},
+						Error: errors.New("[valid-pipeline-fetch-uri]: uri is missing in fetch pipeline (ERROR)"),
+					},
+					{
+						Rule: Definition{
+							Name:     "valid-pipeline-uses",
+							Severity: SeverityError,
+						},
+						Error: errors.New("[valid-pipeline-uses]: pipeline[0] (fetch) is missing required input uri, pipeline[0] (fetch) has unknown input sha, pipeline[1] uses unknown pipeline autoconf/build, subpackages[0].pipeline[0].pipeline[0] (local/build) is missing required input target (ERROR)"),
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			file: "not-reproducible.yaml",
+			want: EvalResult{
+				File: "not-reproducible",
+				Errors: EvalRuleErrors{
+					{
+						Rule: Definition{
+							Name:     "valid-pipeline-git-checkout-commit",
+							Severity: SeverityError,
+						},
+						Error: errors.New("[valid-pipeline-git-checkout-commit]: expected-commit is missing (ERROR)"),
+					},
+					{
+						Rule: Definition{
+							Name:     "valid-pipeline-git-checkout-tag",
+							Severity: SeverityError,
+						},
+						Error: errors.New("[valid-pipeline-git-checkout-tag]: tag is missing (ERROR)"),
+					},
+					{
+						Rule: Definition{
+							Name:     "unpinned-git-checkout",
+							Severity: SeverityWarning,
+						},
+						Error: errors.New("[unpinned-git-checkout]: pipeline[0] checks out main of https://github.com/example/not-reproducible, not a tag or an expected-commit (WARNING)"),
+					},
+					{
+						Rule: Definition{
+							Name:     "unverified-fetch",
+							Severity: SeverityWarning,
+						},
+						Error: errors.New("[unverified-fetch]: subpackages[0].pipeline[0].pipeline[0] fetches https://example.com/data-${{package.version}}.tar.gz without expected-sha256 or expected-sha512 (WARNING)"),
+					},
+					{
+						Rule: Definition{
+							Name:     "moving-source-uri",
+							Severity: SeverityWarning,
+						},
+						Error: errors.New("[moving-source-uri]: pipeline[1] fetches https://example.com/releases/latest/download/extra.tar.gz, which changes without its url changing (WARNING)"),
+					},
+					{
+						Rule: Definition{
+							Name:     "build-timestamp",
+							Severity: SeverityWarning,
+						},
+						Error: errors.New(`[build-timestamp]: pipeline[2] embeds the build time: echo "built on $(date)" > BUILD_INFO (WARNING)`),
+					},
+					{
+						Rule: Definition{
+							Name:     "unverified-download",
+							Severity: SeverityWarning,
+						},
+						Error: errors.New("[unverified-download]: subpackages[0].pipeline[0].pipeline[1] downloads without a checksum: curl -sL https://example.com/data.json -o data.json (WARNING)"),
+					},
+					{
+						Rule: Definition{
+							Name:     "latest-version",
+							Severity: SeverityWarning,
+						},
+						Error: errors.New("[latest-version]: pipeline[2] installs the latest version: go install golang.org/x/tools/cmd/stringer@latest (WARNING)"),
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			file: "deprecated-pipeline.yaml",
+			want: EvalResult{
+				File: "deprecated-pipeline",
+				Errors: EvalRuleErrors{
+					{
+						Rule: Definition{
+							Name:     "deprecated-pipeline",
+							Severity: SeverityWarning,
+						},
+						Error: errors.New("[deprecated-pipeline]: pipeline[0] uses deprecated pipeline local/make, use local/build instead (WARNING)"),
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			file: "embedded-secret.yaml",
+			want: EvalResult{
+				File: "embedded-secret",
+				Errors: EvalRuleErrors{
+					{
+						Rule: Definition{
+							Name:     "embedded-secret",
+							Severity: SeverityError,
+						},
+						Error: errors.New("[embedded-secret]: line 15 has a GitHub token ghp_**** (ERROR)"),
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			file: "wrong-cpe.yaml",
+			want: EvalResult{
+				File: "wrong-cpe",
+				Errors: EvalRuleErrors{
+					{
+						Rule: Definition{
+							Name:     "valid-cpe",
+							Severity: SeverityError,
+						},
+						Error: errors.New(`[valid-cpe]: package.cpe has no vendor, package.cpe product "Wrong CPE" is not lowercase letters, digits and ._~+!- only (ERROR)`),
+					},
+				},
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -284,3 +440,63 @@ func TestLinter_Rules(t *testing.T) {
 		})
 	}
 }
+
+func TestLinter_VirtualDependencies(t *testing.T) {
+	idx := &repository.ApkIndex{
+		Packages: []*repository.Package{
+			{Name: "busybox", Version: "1.36.0-r0"},
+			{Name: "gcc", Version: "13.1.0-r0", Provides: []string{"cmd:cc=13.1.0-r0", "cmd:gcc=13.1.0-r0"}},
+			{Name: "libffi-dev", Version: "3.4.4-r0", Provides: []string{"pc:libffi=3.4.4"}},
+			{Name: "libfoo", Version: "1.0.0-r0", Provides: []string{"so:libfoo.so.1=1"}},
+		},
+	}
+	indexPath := filepath.Join(t.TempDir(), "APKINDEX.tar.gz")
+	require.NoError(t, index.Write(context.Background(), idx, indexPath, ""))
+
+	l := New(WithPath(filepath.Join("testdata", "virtual")), WithProviderIndexes(indexPath))
+	got, err := l.Lint()
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Len(t, got[0].Errors, 1)
+
+	e := got[0].Errors[0]
+	assert.Equal(t, "virtual-dependency-without-provider", e.Rule.Name)
+	assert.Equal(t, errors.New("[virtual-dependency-without-provider]: no package provides cmd:bar, pc:libbaz (ERROR)"), e.Error)
+}
+
+func TestLinter_UnknownDependencies(t *testing.T) {
+	idx := &repository.ApkIndex{
+		Packages: []*repository.Package{
+			{Name: "busybox", Version: "1.36.0-r0"},
+			{Name: "gcc", Version: "13.1.0-r0", Provides: []string{"cmd:cc=13.1.0-r0"}},
+			{Name: "libfoo", Version: "1.0.0-r0"},
+			{Name: "openssl-dev", Version: "3.1.0-r0"},
+		},
+	}
+	indexPath := filepath.Join(t.TempDir(), "APKINDEX.tar.gz")
+	require.NoError(t, index.Write(context.Background(), idx, indexPath, ""))
+
+	l := New(WithPath(filepath.Join("testdata", "unknown")), WithProviderIndexes(indexPath))
+	got, err := l.Lint()
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Len(t, got[0].Errors, 1)
+
+	e := got[0].Errors[0]
+	assert.Equal(t, "unknown-dependency", e.Rule.Name)
+	assert.Equal(t, errors.New("[unknown-dependency]: no package is named openssl-dv (did you mean openssl-dev?), left-pad (ERROR)"), e.Error)
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"openssl-dv", "openssl-dev", 1},
+		{"libfoo", "libfoo", 0},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+	} {
+		assert.Equal(t, tt.want, editDistance(tt.a, tt.b), "%s, %s", tt.a, tt.b)
+	}
+}
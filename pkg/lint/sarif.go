@@ -0,0 +1,37 @@
+package lint
+
+import (
+	"path/filepath"
+
+	"github.com/wolfi-dev/wolfictl/pkg/sarif"
+)
+
+// sarifLevels maps rule severities to SARIF levels.
+var sarifLevels = map[Severity]sarif.Level{
+	SeverityError:   sarif.LevelError,
+	SeverityWarning: sarif.LevelWarning,
+	SeverityInfo:    sarif.LevelNote,
+}
+
+// SARIF returns the result of Lint as a SARIF log, with a rule for every rule it evaluated, identified by its name,
+// and a result for every rule a config failed, located at the line of the key of the rule in the config, relative to
+// the linted path.
+func (l *Linter) SARIF(result Result) *sarif.Log {
+	var rules []sarif.Rule
+	for _, rule := range l.rules {
+		rules = append(rules, sarif.Rule{
+			ID:                   rule.Name,
+			ShortDescription:     sarif.Message{Text: rule.Description},
+			DefaultConfiguration: sarif.Configuration{Level: sarifLevels[rule.Severity]},
+		})
+	}
+
+	log := sarif.New(rules...)
+	for _, res := range result {
+		for _, e := range res.Errors {
+			path := filepath.Join(l.options.Path, res.Path)
+			log.Add(e.Rule.Name, e.Message, filepath.ToSlash(path), e.Line)
+		}
+	}
+	return log
+}
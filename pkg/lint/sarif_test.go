@@ -0,0 +1,40 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wolfi-dev/wolfictl/pkg/sarif"
+)
+
+func TestLinter_SARIF(t *testing.T) {
+	l := newTestLinterWithFile("missing-copyright.yaml")
+	result, err := l.Lint()
+	require.NoError(t, err)
+
+	log := l.SARIF(result)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	rules, err := l.Rules()
+	require.NoError(t, err)
+	assert.Len(t, run.Tool.Driver.Rules, len(rules), "every rule evaluated, the disabled ones aside")
+	require.Len(t, run.Results, 1)
+
+	r := run.Results[0]
+	assert.Equal(t, "valid-copyright-header", r.RuleID)
+	assert.Equal(t, "valid-copyright-header", run.Tool.Driver.Rules[r.RuleIndex].ID)
+	assert.Equal(t, sarif.LevelNote, r.Level)
+	assert.Equal(t, "copyright header is missing", r.Message.Text)
+	assert.Equal(t, "testdata/files/missing-copyright.yaml", r.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 1, r.Locations[0].PhysicalLocation.Region.StartLine)
+
+	var b bytes.Buffer
+	require.NoError(t, log.Write(&b))
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(b.Bytes(), &decoded))
+	assert.Equal(t, "2.1.0", decoded["version"])
+}
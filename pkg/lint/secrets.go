@@ -0,0 +1,65 @@
+package lint
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+// secretPattern matches a kind of secret, the secret itself being the submatch named secret if there's one.
+type secretPattern struct {
+	what string
+	re   *regexp.Regexp
+}
+
+// secretPatterns are the secrets configs shouldn't embed. Values that are variables, like ${{secrets.TOKEN}} or
+// $TOKEN, aren't secrets, only where they come from could be.
+var secretPatterns = []secretPattern{
+	{"private key", regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY(?: BLOCK)?-----`)},
+	{"GitHub token", regexp.MustCompile(`\b(?P<secret>gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{"GitLab token", regexp.MustCompile(`\b(?P<secret>glpat-[A-Za-z0-9_-]{20,})`)},
+	{"AWS access key", regexp.MustCompile(`\b(?P<secret>(?:AKIA|ASIA)[0-9A-Z]{16})\b`)},
+	{"Slack token", regexp.MustCompile(`\b(?P<secret>xox[abposr]-[A-Za-z0-9-]{10,})`)},
+	{"Google API key", regexp.MustCompile(`\b(?P<secret>AIza[0-9A-Za-z_-]{35})`)},
+	{"authorization header", regexp.MustCompile(`(?i)authorization:\s*(?:bearer|basic|token)\s+(?P<secret>[A-Za-z0-9._~+/=-]{8,})`)},
+	{"password in a url", regexp.MustCompile(`://[^/\s:@$]+:(?P<secret>[^/\s@$]+)@`)},
+	{"curl password", regexp.MustCompile(`\scurl\s.*(?:-u|--user)\s+["']?[^\s:$"']+:(?P<secret>[^\s$"']+)`)},
+	{"password", regexp.MustCompile(`(?i)\b(?:password|passwd|secret|token|api[_-]?key)\s*[=:]\s*["']?(?P<secret>[^\s"'$]{6,})`)},
+}
+
+// findSecrets returns the secrets the source of a config embeds, redacted, by line, and the first line one is on.
+func findSecrets(src []byte) (secrets []string, firstLine int) {
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	// a line can be as long as the whole source, e.g. an embedded blob, which a secret can hide in
+	scanner.Buffer(nil, len(src)+1)
+	for line := 1; scanner.Scan(); line++ {
+		text := " " + scanner.Text()
+		for _, p := range secretPatterns {
+			m := p.re.FindStringSubmatch(text)
+			if m == nil {
+				continue
+			}
+			found := p.what
+			if i := p.re.SubexpIndex("secret"); i > 0 {
+				found = fmt.Sprintf("%s %s", p.what, redact(m[i]))
+			}
+			secrets = append(secrets, fmt.Sprintf("line %d has a %s", line, found))
+			if firstLine == 0 {
+				firstLine = line
+			}
+			// one secret per line is enough to look at it
+			break
+		}
+	}
+	return secrets, firstLine
+}
+
+// redact returns the first characters of a secret, enough to recognize it but not to use it.
+func redact(secret string) string {
+	const shown = 4
+	if len(secret) <= shown*2 {
+		return "****"
+	}
+	return secret[:shown] + "****"
+}
@@ -8,6 +8,9 @@ import (
 // Function is a function that lints a single configuration.
 type Function func(build.Configuration) error
 
+// FixFunc fixes what a rule reports about a configuration in its source, returning the fixed source.
+type FixFunc func(config build.Configuration, src []byte) ([]byte, error)
+
 // ConditionFunc is a function that checks if a rule should be executed.
 type ConditionFunc func() bool
 
@@ -20,8 +23,22 @@ const (
 	SeverityInfo    Severity = "INFO"
 )
 
-// Rule represents a linter rule.
-type Rule struct {
+// Rule is a lint rule. Whatever implements it, the lint config configures it like the built-in rules: it can enable or
+// disable it, exclude packages from it and override its severity.
+type Rule interface {
+	// ID identifies the rule in lint configs, nolint comments and reports.
+	ID() string
+
+	// DefaultSeverity is the severity of the issues the rule finds, unless the lint config overrides it.
+	DefaultSeverity() Severity
+
+	// Lint returns the issues of a config, nil if it has none.
+	Lint(config build.Configuration) error
+}
+
+// Definition is the Rule the built-in rules are, which registered rules can be too, to locate their issues at a key,
+// be evaluated under conditions or fix what they report.
+type Definition struct {
 	// Name is the name of the rule.
 	Name string
 
@@ -31,23 +48,91 @@ type Rule struct {
 	// Severity is the severity of the rule.
 	Severity Severity
 
+	// Key is the dotted path of the key of configs the rule is about, like environment.contents.packages, whose line
+	// locates the issues of the rule. The package key locates them if it's empty.
+	Key string
+
 	// LintFunc is the function that lints a single configuration.
 	LintFunc Function
 
 	// ConditionFuncs is a list of and-conditioned functions that check if the rule should be executed.
 	ConditionFuncs []ConditionFunc
+
+	// Expensive is set for rules that touch the network or run external commands. They're evaluated after every
+	// other rule has been evaluated for every package. What they fetch, like the Makefile, the provider indexes and
+	// the CPE dictionary entries of a product, is fetched once and shared across packages.
+	Expensive bool
+
+	// Disabled rules aren't evaluated unless the Config enables them, for conventions only some repositories follow.
+	Disabled bool
+
+	// Exclude are the packages the rule isn't evaluated for, as names or path.Match patterns.
+	Exclude []string
+
+	// Fix, if set, fixes what the rule reports, for lint --fix.
+	Fix FixFunc
+}
+
+// Rules is a list of rule definitions.
+type Rules []Definition
+
+func (r Definition) ID() string {
+	return r.Name
+}
+
+func (r Definition) DefaultSeverity() Severity {
+	return r.Severity
 }
 
-// Rules is a list of Rule.
-type Rules []Rule
+func (r Definition) Lint(config build.Configuration) error {
+	return r.LintFunc(config)
+}
+
+// definitionOf returns the definition of a rule, the one of a Rule that isn't a Definition being its ID, severity and
+// Lint.
+func definitionOf(rule Rule) Definition {
+	switch r := rule.(type) {
+	case Definition:
+		return r
+	case *Definition:
+		return *r
+	}
+	return Definition{
+		Name:        rule.ID(),
+		Description: rule.ID(),
+		Severity:    rule.DefaultSeverity(),
+		LintFunc:    rule.Lint,
+	}
+}
 
 // EvalRuleError represents an error that occurred during single rule evaluation.
 type EvalRuleError struct {
 	// Rule is the rule that caused the error.
-	Rule Rule
+	Rule Definition
 
 	// Error is the error that occurred.
 	Error error
+
+	// Message is the message of the error returned by the rule, without the rule name and severity.
+	Message string
+
+	// Line is the line of the config the error is located at, the line of the Key of the rule.
+	Line int
+}
+
+// LineError is an error of a rule located at a line of the config, rather than at the Key of the rule, like the line
+// a secret is embedded at.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
 }
 
 // EvalRuleErrors returns a list of EvalError.
@@ -58,8 +143,14 @@ type EvalResult struct {
 	// File is the name of the file that was evaluated against.
 	File string
 
+	// Path is the path of the file that was evaluated against, relative to the linted directory.
+	Path string
+
 	// Errors is a list of validation errors for each rule.
 	Errors EvalRuleErrors
+
+	// Fixed are the names of the rules whose issues were fixed in the file.
+	Fixed []string
 }
 
 // Result is a list of RuleResult.
@@ -0,0 +1,243 @@
+package melange
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+	"chainguard.dev/melange/pkg/renovate"
+	"gopkg.in/yaml.v3"
+
+	"github.com/wolfi-dev/wolfictl/pkg/configs/yamledit"
+)
+
+// DefaultMaxSourceSize is the default limit of the size of a source archive downloaded to compute its checksums, well
+// above the size of the biggest sources, so a misconfigured uri can't download forever.
+const DefaultMaxSourceSize = 4 << 30
+
+// Digests are the digests of a source archive, either can be empty.
+type Digests struct {
+	SHA256 string
+	SHA512 string
+}
+
+// SourceChecksum is the checksum of the source archive of a fetch step, downloaded for the version a config was bumped
+// to.
+type SourceChecksum struct {
+	URI  string
+	Size int64
+	Digests
+	// Changed is set when the expected-sha256 or expected-sha512 of the fetch step were updated.
+	Changed bool
+	// Verified is set when the digests match the ones upstream published for the archive.
+	Verified bool
+}
+
+func (c SourceChecksum) String() string {
+	s := fmt.Sprintf("sha256 %s of %s (%d bytes)", c.SHA256, c.URI, c.Size)
+	if c.Verified {
+		s += ", verified against the published digest"
+	}
+	return s
+}
+
+// ChecksumOptions configures how RefreshFetchChecksums downloads source archives.
+type ChecksumOptions struct {
+	Client *http.Client
+	// MaxSize is the limit of the size of a source archive in bytes, DefaultMaxSourceSize if unset.
+	MaxSize int64
+	// Published are the digests upstream published for source archives by uri, e.g. by PyPI for an sdist. An archive
+	// whose digests don't match them isn't used.
+	Published map[string]Digests
+}
+
+// RefreshFetchChecksums downloads the source archives of the fetch steps of configFile whose uri depends on variables
+// like ${{package.version}}, and sets their expected-sha256 and expected-sha512 to the digests of the archives, so
+// it's meant to run after Bump. Fetch steps with a fixed uri are left alone, as their archive doesn't change with the
+// version. Archives are hashed as they're downloaded, so big ones don't need to fit on disk, and downloads stop once
+// ctx is canceled.
+func RefreshFetchChecksums(ctx context.Context, configFile string, o ChecksumOptions) ([]SourceChecksum, error) {
+	cfg, err := build.ParseConfiguration(configFile)
+	if err != nil {
+		return nil, err
+	}
+	if !hasVersionedStep(cfg.Pipeline, "fetch", "uri") {
+		return nil, nil
+	}
+	mutations, err := Variables(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var checksums []SourceChecksum
+	err = yamledit.EditFile(configFile, func(doc *yamledit.Document) error {
+		pipelineNode, err := renovate.NodeFromMapping(doc.Root.Content[0], "pipeline")
+		if err != nil {
+			return err
+		}
+		for _, step := range pipelineSteps(pipelineNode, "fetch") {
+			withNode, err := renovate.NodeFromMapping(step, "with")
+			if err != nil {
+				continue
+			}
+			uriNode, err := renovate.NodeFromMapping(withNode, "uri")
+			if err != nil || !strings.Contains(uriNode.Value, "${{") {
+				continue
+			}
+			uri, err := build.MutateStringFromMap(mutations, uriNode.Value)
+			if err != nil {
+				return err
+			}
+
+			c, err := o.checksum(ctx, uri)
+			if err != nil {
+				return err
+			}
+			if published, ok := o.Published[uri]; ok {
+				if err := verifyDigests(c, published); err != nil {
+					return err
+				}
+				c.Verified = true
+			}
+			c.Changed = updateDigests(withNode, c.Digests)
+			checksums = append(checksums, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return checksums, nil
+}
+
+// hasVersionedStep reports whether any step of a pipeline that uses the given pipeline has a with value for key
+// with variables, like ${{package.version}}.
+func hasVersionedStep(pipeline []build.Pipeline, uses, key string) bool {
+	for i := range pipeline {
+		if pipeline[i].Uses == uses && strings.Contains(pipeline[i].With[key], "${{") {
+			return true
+		}
+		if hasVersionedStep(pipeline[i].Pipeline, uses, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Variables returns the values of the variables of a config, like ${{package.version}} or ${{vars.foo}}, to evaluate
+// the values of its pipelines with build.MutateStringFromMap.
+func Variables(cfg *build.Configuration) (map[string]string, error) {
+	pctx := &build.PipelineContext{
+		Context: &build.Context{
+			Configuration: *cfg,
+		},
+		Package: &cfg.Package,
+	}
+	return build.MutateWith(pctx, map[string]string{})
+}
+
+// pipelineSteps returns the steps of a pipeline node, including nested pipelines, that use the given pipeline.
+func pipelineSteps(pipelineNode *yaml.Node, uses string) []*yaml.Node {
+	var steps []*yaml.Node
+	for _, step := range pipelineNode.Content {
+		if u, err := renovate.NodeFromMapping(step, "uses"); err == nil && u.Value == uses {
+			steps = append(steps, step)
+		}
+		if nested, err := renovate.NodeFromMapping(step, "pipeline"); err == nil {
+			steps = append(steps, pipelineSteps(nested, uses)...)
+		}
+	}
+	return steps
+}
+
+// checksum downloads the archive at uri and returns its size and digests.
+func (o ChecksumOptions) checksum(ctx context.Context, uri string) (SourceChecksum, error) {
+	maxSize := o.MaxSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxSourceSize
+	}
+	client := o.Client
+	if client == nil {
+		client = &http.Client{
+			CheckRedirect: func(req *http.Request, _ []*http.Request) error {
+				// like melange, as redirects of sourceforge don't work with the referer
+				req.Header.Del("Referer")
+				return nil
+			},
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, http.NoBody)
+	if err != nil {
+		return SourceChecksum{}, err
+	}
+	// like melange, as some servers, e.g. www.netfilter.org, refuse other MIME types
+	req.Header.Set("Accept", "text/html")
+	resp, err := client.Do(req)
+	if err != nil {
+		return SourceChecksum{}, fmt.Errorf("failed to download %s: %w", uri, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return SourceChecksum{}, fmt.Errorf("failed to download %s: %s", uri, resp.Status)
+	}
+	if resp.ContentLength > maxSize {
+		return SourceChecksum{}, fmt.Errorf("%s is %d bytes, more than the limit of %d bytes", uri, resp.ContentLength, maxSize)
+	}
+
+	h256, h512 := sha256.New(), sha512.New()
+	size, err := io.Copy(io.MultiWriter(h256, h512), io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return SourceChecksum{}, fmt.Errorf("failed to download %s: %w", uri, err)
+	}
+	if size > maxSize {
+		return SourceChecksum{}, fmt.Errorf("%s is more than the limit of %d bytes", uri, maxSize)
+	}
+	return SourceChecksum{
+		URI:  uri,
+		Size: size,
+		Digests: Digests{
+			SHA256: hex.EncodeToString(h256.Sum(nil)),
+			SHA512: hex.EncodeToString(h512.Sum(nil)),
+		},
+	}, nil
+}
+
+func verifyDigests(c SourceChecksum, published Digests) error {
+	if published.SHA256 != "" && !strings.EqualFold(published.SHA256, c.SHA256) {
+		return fmt.Errorf("sha256 of %s is %s, but %s was published", c.URI, c.SHA256, published.SHA256)
+	}
+	if published.SHA512 != "" && !strings.EqualFold(published.SHA512, c.SHA512) {
+		return fmt.Errorf("sha512 of %s is %s, but %s was published", c.URI, c.SHA512, published.SHA512)
+	}
+	return nil
+}
+
+// updateDigests sets the expected-sha256 and expected-sha512 of the with mapping of a fetch step that it has, or
+// expected-sha256 if it has neither, and reports whether they changed.
+func updateDigests(withNode *yaml.Node, d Digests) bool {
+	changed := false
+	found := false
+	for key, digest := range map[string]string{"expected-sha256": d.SHA256, "expected-sha512": d.SHA512} {
+		n, err := renovate.NodeFromMapping(withNode, key)
+		if err != nil {
+			continue
+		}
+		found = true
+		if n.Value != digest {
+			changed = true
+			setDigest(withNode, key, digest)
+		}
+	}
+	if !found {
+		setDigest(withNode, "expected-sha256", d.SHA256)
+		changed = true
+	}
+	return changed
+}
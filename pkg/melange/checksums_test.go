@@ -0,0 +1,151 @@
+package melange
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func sha512Hex(s string) string {
+	sum := sha512.Sum512([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// checksumsConfig writes the checksums test config, with its sources served by server, to a temp dir.
+func checksumsConfig(t *testing.T, server *httptest.Server) string {
+	b, err := os.ReadFile(filepath.Join("testdata", "checksums", "foo.yaml"))
+	require.NoError(t, err)
+	configFile := filepath.Join(t.TempDir(), "foo.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(strings.ReplaceAll(string(b), "SERVER", server.URL)), 0o644))
+	return configFile
+}
+
+func TestRefreshFetchChecksums(t *testing.T) {
+	sources := map[string]string{
+		"/foo-1.3.0.tar.gz":           "foo 1.3.0",
+		"/docs/foo-docs-1_3_0.tar.gz": "docs of foo 1.3.0",
+		"/patches/fix.patch":          "a patch that doesn't change",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		source, ok := sources[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(source))
+	}))
+	defer server.Close()
+
+	configFile := checksumsConfig(t, server)
+	require.NoError(t, Bump(configFile, "1.3.0", "4444444444444444444444444444444444444444"))
+
+	checksums, err := RefreshFetchChecksums(context.Background(), configFile, ChecksumOptions{
+		Client: server.Client(),
+		Published: map[string]Digests{
+			server.URL + "/foo-1.3.0.tar.gz": {SHA256: sha256Hex("foo 1.3.0")},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []SourceChecksum{
+		{
+			URI:      server.URL + "/foo-1.3.0.tar.gz",
+			Size:     9,
+			Digests:  Digests{SHA256: sha256Hex("foo 1.3.0"), SHA512: sha512Hex("foo 1.3.0")},
+			Changed:  true,
+			Verified: true,
+		},
+		{
+			URI:     server.URL + "/docs/foo-docs-1_3_0.tar.gz",
+			Size:    17,
+			Digests: Digests{SHA256: sha256Hex("docs of foo 1.3.0"), SHA512: sha512Hex("docs of foo 1.3.0")},
+			Changed: true,
+		},
+	}, checksums)
+
+	cfg, err := build.ParseConfiguration(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, "1.3.0", cfg.Package.Version)
+	assert.Equal(t, uint64(0), cfg.Package.Epoch)
+	assert.Equal(t, sha256Hex("foo 1.3.0"), cfg.Pipeline[0].With["expected-sha256"])
+	assert.NotContains(t, cfg.Pipeline[0].With, "expected-sha512")
+	// the uri of the patch doesn't change with the version, so it isn't downloaded
+	assert.Equal(t, "1111111111111111111111111111111111111111111111111111111111111111", cfg.Pipeline[1].With["expected-sha256"])
+	assert.Equal(t, "4444444444444444444444444444444444444444", cfg.Pipeline[2].With["expected-commit"])
+	assert.Equal(t, sha512Hex("docs of foo 1.3.0"), cfg.Pipeline[3].Pipeline[0].With["expected-sha512"])
+
+	// running again changes nothing
+	checksums, err = RefreshFetchChecksums(context.Background(), configFile, ChecksumOptions{Client: server.Client()})
+	require.NoError(t, err)
+	require.Len(t, checksums, 2)
+	assert.False(t, checksums[0].Changed)
+	assert.False(t, checksums[1].Changed)
+}
+
+func TestRefreshFetchChecksums_errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/foo-2.0.0.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(strings.Repeat("x", 1024)))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		version string
+		options ChecksumOptions
+		wantErr string
+	}{
+		{
+			name:    "missing sources",
+			version: "2.0.0",
+			wantErr: "404 Not Found",
+		},
+		{
+			name:    "too big",
+			version: "1.3.0",
+			options: ChecksumOptions{MaxSize: 1000},
+			wantErr: "more than the limit of 1000 bytes",
+		},
+		{
+			name:    "published digest mismatch",
+			version: "1.3.0",
+			options: ChecksumOptions{Published: map[string]Digests{server.URL + "/foo-1.3.0.tar.gz": {SHA512: "abcd"}}},
+			wantErr: "but abcd was published",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile := checksumsConfig(t, server)
+			require.NoError(t, Bump(configFile, tt.version, ""))
+			before, err := os.ReadFile(configFile)
+			require.NoError(t, err)
+
+			tt.options.Client = server.Client()
+			_, err = RefreshFetchChecksums(context.Background(), configFile, tt.options)
+			assert.ErrorContains(t, err, tt.wantErr)
+
+			// the config is left as it was
+			after, err := os.ReadFile(configFile)
+			require.NoError(t, err)
+			assert.Equal(t, string(before), string(after))
+		})
+	}
+}
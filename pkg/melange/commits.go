@@ -0,0 +1,120 @@
+package melange
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+	"chainguard.dev/melange/pkg/renovate"
+
+	"github.com/wolfi-dev/wolfictl/pkg/configs/yamledit"
+)
+
+// ExpectedCommit is the commit the tag checked out by a git-checkout step was resolved to.
+type ExpectedCommit struct {
+	Repository string
+	Tag        string
+	Commit     string
+	// Changed is set when the expected-commit of the step was updated.
+	Changed bool
+}
+
+func (c ExpectedCommit) String() string {
+	return fmt.Sprintf("tag %s of %s is commit %s", c.Tag, c.Repository, c.Commit)
+}
+
+// RefreshExpectedCommits sets the expected-commit of the git-checkout steps of configFile that pin one, and check out
+// a tag that depends on variables like ${{package.version}}, to the commit resolve returns for the repository and the
+// tag. It's meant to run after Bump, when the datasource of the new version doesn't know its commit.
+func RefreshExpectedCommits(configFile string, resolve func(repository, tag string) (string, error)) ([]ExpectedCommit, error) {
+	cfg, err := build.ParseConfiguration(configFile)
+	if err != nil {
+		return nil, err
+	}
+	if !hasVersionedStep(cfg.Pipeline, "git-checkout", "tag") {
+		return nil, nil
+	}
+	mutations, err := Variables(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []ExpectedCommit
+	err = yamledit.EditFile(configFile, func(doc *yamledit.Document) error {
+		pipelineNode, err := renovate.NodeFromMapping(doc.Root.Content[0], "pipeline")
+		if err != nil {
+			return err
+		}
+		for _, step := range pipelineSteps(pipelineNode, "git-checkout") {
+			withNode, err := renovate.NodeFromMapping(step, "with")
+			if err != nil {
+				continue
+			}
+			commitNode, err := renovate.NodeFromMapping(withNode, "expected-commit")
+			if err != nil {
+				continue
+			}
+			tagNode, err := renovate.NodeFromMapping(withNode, "tag")
+			if err != nil || !strings.Contains(tagNode.Value, "${{") {
+				continue
+			}
+			repositoryNode, err := renovate.NodeFromMapping(withNode, "repository")
+			if err != nil {
+				return fmt.Errorf("git-checkout of tag %s has no repository", tagNode.Value)
+			}
+
+			repository, err := build.MutateStringFromMap(mutations, repositoryNode.Value)
+			if err != nil {
+				return err
+			}
+			tag, err := build.MutateStringFromMap(mutations, tagNode.Value)
+			if err != nil {
+				return err
+			}
+			commit, err := resolve(repository, tag)
+			if err != nil {
+				return fmt.Errorf("failed to resolve tag %s of %s: %w", tag, repository, err)
+			}
+
+			c := ExpectedCommit{Repository: repository, Tag: tag, Commit: commit, Changed: commitNode.Value != commit}
+			commitNode.Value = commit
+			// a commit can be all digits, which would be read back as a number
+			commitNode.Tag = "!!str"
+			commits = append(commits, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// LsRemoteTag returns the commit a tag of a git repository points to with git ls-remote, which lists annotated tags
+// twice, as the tag object and peeled to the commit with a ^{} suffix
+func LsRemoteTag(ctx context.Context, repository, tag string) (string, error) {
+	ref := "refs/tags/" + tag
+	out, err := exec.CommandContext(ctx, "git", "ls-remote", "--", repository, ref, ref+"^{}").Output()
+	if err != nil {
+		return "", fmt.Errorf("git ls-remote %s failed: %w", repository, err)
+	}
+
+	commit := ""
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		hash, name, ok := strings.Cut(line, "\t")
+		switch {
+		case !ok:
+			continue
+		case name == ref+"^{}":
+			return hash, nil
+		case name == ref:
+			commit = hash
+		}
+	}
+	if commit == "" {
+		return "", fmt.Errorf("tag %s not found in %s", tag, repository)
+	}
+	return commit, nil
+}
@@ -0,0 +1,63 @@
+package melange
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func commitsConfig(t *testing.T) string {
+	b, err := os.ReadFile(filepath.Join("testdata", "commits", "bar.yaml"))
+	require.NoError(t, err)
+	configFile := filepath.Join(t.TempDir(), "bar.yaml")
+	require.NoError(t, os.WriteFile(configFile, b, 0o644))
+	return configFile
+}
+
+func TestRefreshExpectedCommits(t *testing.T) {
+	configFile := commitsConfig(t)
+	require.NoError(t, Bump(configFile, "2.1.0", ""))
+
+	var resolved []string
+	commits, err := RefreshExpectedCommits(configFile, func(repository, tag string) (string, error) {
+		resolved = append(resolved, repository+"@"+tag)
+		return "3333333333333333333333333333333333333333", nil
+	})
+	require.NoError(t, err)
+
+	// only the pinned checkout of a versioned tag is resolved
+	assert.Equal(t, []string{"https://github.com/example/bar@v2.1.0"}, resolved)
+	assert.Equal(t, []ExpectedCommit{{
+		Repository: "https://github.com/example/bar",
+		Tag:        "v2.1.0",
+		Commit:     "3333333333333333333333333333333333333333",
+		Changed:    true,
+	}}, commits)
+
+	cfg, err := build.ParseConfiguration(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, "3333333333333333333333333333333333333333", cfg.Pipeline[0].With["expected-commit"])
+	assert.Equal(t, "2222222222222222222222222222222222222222", cfg.Pipeline[1].With["expected-commit"])
+	assert.NotContains(t, cfg.Pipeline[2].With, "expected-commit")
+}
+
+func TestRefreshExpectedCommitsFails(t *testing.T) {
+	configFile := commitsConfig(t)
+	before, err := os.ReadFile(configFile)
+	require.NoError(t, err)
+
+	_, err = RefreshExpectedCommits(configFile, func(repository, tag string) (string, error) {
+		return "", errors.New("tag not found")
+	})
+	assert.ErrorContains(t, err, "failed to resolve tag v2.0.0 of https://github.com/example/bar: tag not found")
+
+	// the config is left alone
+	after, err := os.ReadFile(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, string(before), string(after))
+}
@@ -0,0 +1,34 @@
+package melange
+
+import (
+	"github.com/facebookincubator/nvdtools/wfn"
+	"gopkg.in/yaml.v3"
+)
+
+// CPE is the package.cpe block of a config, which melange doesn't know about: the vendor and product, and the target
+// software for language ecosystem packages, that vulnerabilities of the package are matched by in the NVD. They're not
+// derived from the package name for packages that vulnerability databases know by another name, e.g. haxx curl.
+type CPE struct {
+	Vendor   string `yaml:"vendor"`
+	Product  string `yaml:"product"`
+	TargetSW string `yaml:"target_sw"`
+
+	// Raw is the CPE as given when it's a formatted string, e.g. cpe:2.3:a:haxx:curl:*:*:*:*:*:*:*:*, rather than a
+	// mapping. Vendor, Product and TargetSW are parsed from it, and left empty if it doesn't parse.
+	Raw string `yaml:"-"`
+}
+
+// UnmarshalYAML reads a CPE given either as a mapping or as a formatted string.
+func (c *CPE) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*c = CPE{Raw: value.Value}
+		if attrs, err := wfn.Parse(value.Value); err == nil {
+			c.Vendor = wfn.StripSlashes(attrs.Vendor)
+			c.Product = wfn.StripSlashes(attrs.Product)
+			c.TargetSW = wfn.StripSlashes(attrs.TargetSW)
+		}
+		return nil
+	}
+	type plain CPE
+	return value.Decode((*plain)(c))
+}
@@ -0,0 +1,88 @@
+package melange
+
+import (
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+	"chainguard.dev/melange/pkg/renovate"
+	"gopkg.in/yaml.v3"
+
+	"github.com/wolfi-dev/wolfictl/pkg/configs/yamledit"
+)
+
+// RewriteFetch points the first fetch step of configFile at uri, the source archive of version, and sets its
+// expected-sha256 and expected-sha512 to the digests that are given, removing the other, which was of the previous
+// archive. The version in uri is replaced by ${{package.version}}, so later bumps keep working, which makes it meant to
+// run before Bump, e.g. when the archive of the new version moved to a different path. It returns false and leaves
+// configFile untouched if the config has no fetch step.
+func RewriteFetch(configFile, version, uri, expectedSHA256, expectedSHA512 string) (bool, error) {
+	cfg, err := build.ParseConfiguration(configFile)
+	if err != nil {
+		return false, err
+	}
+	found := false
+	for i := range cfg.Pipeline {
+		if cfg.Pipeline[i].Uses == "fetch" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false, nil
+	}
+
+	err = yamledit.EditFile(configFile, func(doc *yamledit.Document) error {
+		pipelineNode, err := renovate.NodeFromMapping(doc.Root.Content[0], "pipeline")
+		if err != nil {
+			return err
+		}
+		for _, step := range pipelineNode.Content {
+			uses, err := renovate.NodeFromMapping(step, "uses")
+			if err != nil || uses.Value != "fetch" {
+				continue
+			}
+			withNode, err := renovate.NodeFromMapping(step, "with")
+			if err != nil {
+				return err
+			}
+			uriNode, err := renovate.NodeFromMapping(withNode, "uri")
+			if err != nil {
+				return err
+			}
+			uriNode.Value = strings.ReplaceAll(uri, version, "${{package.version}}")
+
+			for key, digest := range map[string]string{"expected-sha256": expectedSHA256, "expected-sha512": expectedSHA512} {
+				setDigest(withNode, key, digest)
+			}
+			return nil
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// setDigest sets the value of key in the with mapping of a fetch step to digest, or removes key if digest is empty.
+func setDigest(withNode *yaml.Node, key, digest string) {
+	for i := 0; i+1 < len(withNode.Content); i += 2 {
+		if withNode.Content[i].Value != key {
+			continue
+		}
+		if digest == "" {
+			withNode.Content = append(withNode.Content[:i], withNode.Content[i+2:]...)
+			return
+		}
+		withNode.Content[i+1].Value = digest
+		// a digest can be all digits, which would be read back as a number
+		withNode.Content[i+1].Tag = "!!str"
+		return
+	}
+	if digest != "" {
+		withNode.Content = append(withNode.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: digest},
+		)
+	}
+}
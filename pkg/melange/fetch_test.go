@@ -0,0 +1,65 @@
+package melange
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewriteFetch(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "fetch", "py3-requests.yaml"))
+	require.NoError(t, err)
+	configFile := filepath.Join(t.TempDir(), "py3-requests.yaml")
+	require.NoError(t, os.WriteFile(configFile, b, 0o644))
+
+	rewritten, err := RewriteFetch(configFile, "2.31.0",
+		"https://files.pythonhosted.org/packages/9d/be/10918a2eac4ae9f02f6cfe6414b7a155ccd8f7f9d4380d62fd5b955065c3/requests-2.31.0.tar.gz",
+		"942c5a758f98d790eaed1a29cb6eefc7ffb0d1cf7af05c3d2791656dbd6ad1e1", "")
+	require.NoError(t, err)
+	assert.True(t, rewritten)
+
+	cfg, err := build.ParseConfiguration(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, "https://files.pythonhosted.org/packages/9d/be/10918a2eac4ae9f02f6cfe6414b7a155ccd8f7f9d4380d62fd5b955065c3/requests-${{package.version}}.tar.gz", cfg.Pipeline[0].With["uri"])
+	assert.Equal(t, "942c5a758f98d790eaed1a29cb6eefc7ffb0d1cf7af05c3d2791656dbd6ad1e1", cfg.Pipeline[0].With["expected-sha256"])
+	// the version is left for Bump to change
+	assert.Equal(t, "2.30.0", cfg.Package.Version)
+}
+
+func TestRewriteFetch_sha512(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "fetch", "py3-requests.yaml"))
+	require.NoError(t, err)
+	configFile := filepath.Join(t.TempDir(), "py3-requests.yaml")
+	require.NoError(t, os.WriteFile(configFile, b, 0o644))
+
+	sha512 := strings.Repeat("0f", 64)
+	rewritten, err := RewriteFetch(configFile, "2.31.0", "https://example.com/requests-2.31.0.tgz", "", sha512)
+	require.NoError(t, err)
+	assert.True(t, rewritten)
+
+	cfg, err := build.ParseConfiguration(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/requests-${{package.version}}.tgz", cfg.Pipeline[0].With["uri"])
+	assert.Equal(t, sha512, cfg.Pipeline[0].With["expected-sha512"])
+	// the sha256 was of the previous archive
+	assert.NotContains(t, cfg.Pipeline[0].With, "expected-sha256")
+}
+
+func TestRewriteFetch_noFetch(t *testing.T) {
+	configFile := filepath.Join("testdata", "melange_dir", "foo.yaml")
+	before, err := os.ReadFile(configFile)
+	require.NoError(t, err)
+
+	rewritten, err := RewriteFetch(configFile, "1.2.3", "https://example.com/foo-1.2.3.tar.gz", "0000", "")
+	require.NoError(t, err)
+	assert.False(t, rewritten)
+
+	after, err := os.ReadFile(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+}
@@ -0,0 +1,145 @@
+package melange
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"chainguard.dev/melange/pkg/renovate"
+	"gopkg.in/yaml.v3"
+
+	"github.com/wolfi-dev/wolfictl/pkg/configs/yamledit"
+)
+
+// The orders the keys of the sections of a config are formatted in. The keys that aren't listed keep their order,
+// after the listed ones.
+var (
+	rootKeys        = []string{"package", "environment", "vars", "var-transforms", "data", "pipeline", "subpackages", "options", "update", "test", "advisories", "secfixes"}
+	packageKeys     = []string{"name", "version", "epoch", "description", "url", "commit", "copyright", "target-architecture", "dependencies", "options", "scriptlets", "checks"}
+	environmentKeys = []string{"contents", "environment", "accounts"}
+	contentsKeys    = []string{"repositories", "keyring", "packages"}
+	subpackageKeys  = []string{"name", "range", "description", "url", "commit", "dependencies", "options", "scriptlets", "pipeline", "checks", "test"}
+	stepKeys        = []string{"name", "if", "uses", "with", "working-directory", "runs", "pipeline", "needs", "label", "assertions"}
+	updateKeys      = []string{"enabled", "manual", "require-sequential", "shared", "exclude-reason", "version-separator", "ignore-regex-patterns", "release-monitor", "github", "git"}
+)
+
+// foldLength is the length of the single line commands that are formatted folded
+const foldLength = 80
+
+// Format formats a melange config canonically: its sections and their keys in the usual order and separated by blank
+// lines, the steps of its pipelines normalized, and the whole config indented by two spaces. Comments, and the blank
+// lines within sections, are kept.
+//
+// Steps are normalized by removing their empty with, writing their multi-line commands as literal blocks without
+// trailing whitespace, and their long single line commands folded.
+func Format(src []byte) ([]byte, error) {
+	doc, err := yamledit.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Root.Kind != yaml.DocumentNode || len(doc.Root.Content) != 1 || doc.Root.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("not a melange config")
+	}
+
+	root := doc.Root.Content[0]
+	sortKeys(root, rootKeys)
+	doc.SeparateEntries(root)
+	if n, err := renovate.NodeFromMapping(root, "package"); err == nil {
+		sortKeys(n, packageKeys)
+	}
+	if n, err := renovate.NodeFromMapping(root, "environment"); err == nil {
+		sortKeys(n, environmentKeys)
+		if contents, err := renovate.NodeFromMapping(n, "contents"); err == nil {
+			sortKeys(contents, contentsKeys)
+		}
+	}
+	if n, err := renovate.NodeFromMapping(root, "pipeline"); err == nil {
+		formatPipeline(n)
+	}
+	if n, err := renovate.NodeFromMapping(root, "subpackages"); err == nil && n.Kind == yaml.SequenceNode {
+		for _, sub := range n.Content {
+			sortKeys(sub, subpackageKeys)
+			if p, err := renovate.NodeFromMapping(sub, "pipeline"); err == nil {
+				formatPipeline(p)
+			}
+		}
+	}
+	if n, err := renovate.NodeFromMapping(root, "update"); err == nil {
+		sortKeys(n, updateKeys)
+	}
+	if n, err := renovate.NodeFromMapping(root, "test"); err == nil {
+		if p, err := renovate.NodeFromMapping(n, "pipeline"); err == nil {
+			formatPipeline(p)
+		}
+	}
+
+	return doc.Render()
+}
+
+// formatPipeline normalizes the steps of a pipeline, and of the pipelines they run
+func formatPipeline(pipeline *yaml.Node) {
+	if pipeline.Kind != yaml.SequenceNode {
+		return
+	}
+	for _, step := range pipeline.Content {
+		if step.Kind != yaml.MappingNode {
+			continue
+		}
+		sortKeys(step, stepKeys)
+		for i := 0; i < len(step.Content); i += 2 {
+			if v := step.Content[i+1]; step.Content[i].Value == "with" && (v.ShortTag() == "!!null" || v.Kind == yaml.MappingNode && len(v.Content) == 0) {
+				step.Content = append(step.Content[:i], step.Content[i+2:]...)
+				break
+			}
+		}
+		if runs, err := renovate.NodeFromMapping(step, "runs"); err == nil && runs.Kind == yaml.ScalarNode {
+			formatRuns(runs)
+		}
+		if p, err := renovate.NodeFromMapping(step, "pipeline"); err == nil {
+			formatPipeline(p)
+		}
+	}
+}
+
+// formatRuns writes multi-line commands as literal blocks that end with a single newline and have no trailing
+// whitespace, except before a backslash that continues a line, and folds long single line commands
+func formatRuns(runs *yaml.Node) {
+	if !strings.Contains(runs.Value, "\n") {
+		if len(runs.Value) > foldLength {
+			runs.Style = yaml.FoldedStyle
+		}
+		return
+	}
+	lines := strings.Split(strings.TrimRight(runs.Value, " \t\n"), "\n")
+	for i, l := range lines {
+		if trimmed := strings.TrimRight(l, " \t"); !strings.HasSuffix(trimmed, "\\") {
+			lines[i] = trimmed
+		}
+	}
+	runs.Value = strings.Join(lines, "\n") + "\n"
+	runs.Style = yaml.LiteralStyle
+}
+
+// sortKeys sorts the keys of a mapping in order, the keys that aren't in it after the others
+func sortKeys(n *yaml.Node, order []string) {
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	rank := func(key string) int {
+		for i, k := range order {
+			if k == key {
+				return i
+			}
+		}
+		return len(order)
+	}
+	pairs := make([][2]*yaml.Node, 0, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{n.Content[i], n.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return rank(pairs[i][0].Value) < rank(pairs[j][0].Value) })
+	n.Content = n.Content[:0]
+	for _, p := range pairs {
+		n.Content = append(n.Content, p[0], p[1])
+	}
+}
@@ -0,0 +1,31 @@
+package melange
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("testdata", "format", "bash.yaml"))
+	require.NoError(t, err)
+	want, err := os.ReadFile(filepath.Join("testdata", "format", "bash_expected.yaml"))
+	require.NoError(t, err)
+
+	got, err := Format(src)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got))
+
+	// formatted configs stay as they are
+	again, err := Format(got)
+	require.NoError(t, err)
+	assert.Equal(t, string(got), string(again))
+}
+
+func TestFormat_notAConfig(t *testing.T) {
+	_, err := Format([]byte("- a\n- b\n"))
+	assert.Error(t, err)
+}
@@ -2,17 +2,20 @@ package melange
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 
 	"chainguard.dev/melange/pkg/renovate"
-	"chainguard.dev/melange/pkg/renovate/bump"
 
 	"chainguard.dev/melange/pkg/build"
 	"github.com/pkg/errors"
+
+	"github.com/wolfi-dev/wolfictl/pkg/configs/yamledit"
 )
 
 type Packages struct {
@@ -21,6 +24,119 @@ type Packages struct {
 	Dir      string
 	NoLint   []string
 	Hash     string
+
+	// GitLabMonitor, PyPIMonitor, CratesMonitor, GoModuleMonitor, NpmMonitor, RubyGemsMonitor and ScrapeMonitor are
+	// the update.gitlab, update.pypi, update.crates, update.go, update.npm, update.rubygems and update.scrape blocks of
+	// the config, which melange doesn't know about
+	GitLabMonitor   *GitLabMonitor
+	PyPIMonitor     *PyPIMonitor
+	CratesMonitor   *CratesMonitor
+	GoModuleMonitor *GoModuleMonitor
+	NpmMonitor      *NpmMonitor
+	RubyGemsMonitor *RubyGemsMonitor
+	ScrapeMonitor   *ScrapeMonitor
+	// ReleaseMonitor are the settings of the update.release-monitor block that melange doesn't know about
+	ReleaseMonitor *ReleaseMonitor
+	// CPE is the package.cpe block of the config, nil if it has none
+	CPE *CPE
+}
+
+// GitLabMonitor configures update checks of packages whose upstream is hosted on gitlab.com or a self-hosted GitLab,
+// mirroring the github block of melange update configs
+type GitLabMonitor struct {
+	// Identifier is the path of the project, e.g. gitlab-org/gitlab-runner
+	Identifier string `yaml:"identifier"`
+	// Host of the GitLab instance, defaults to gitlab.com
+	Host        string `yaml:"host"`
+	StripPrefix string `yaml:"strip-prefix"`
+	StripSuffix string `yaml:"strip-suffix"`
+	TagFilter   string `yaml:"tag-filter"`
+	UseTags     bool   `yaml:"use-tag"`
+}
+
+// PyPIMonitor configures update checks of packages released on the Python Package Index
+type PyPIMonitor struct {
+	// Identifier is the name of the project on PyPI, e.g. requests
+	Identifier string `yaml:"identifier"`
+	// PreReleases allows updating to alpha, beta, release candidate and development releases
+	PreReleases bool `yaml:"include-prereleases"`
+}
+
+// CratesMonitor configures update checks of Rust crates released on crates.io
+type CratesMonitor struct {
+	// Identifier is the name of the crate, e.g. ripgrep
+	Identifier string `yaml:"identifier"`
+	// Constraint restricts updates to the versions satisfying it, e.g. ">= 0.9, < 1.0"
+	Constraint string `yaml:"constraint"`
+}
+
+// GoModuleMonitor configures update checks of Go modules published on the Go module proxy
+type GoModuleMonitor struct {
+	// Identifier is the path of the module, e.g. github.com/sigstore/cosign/v2
+	Identifier string `yaml:"identifier"`
+}
+
+// NpmMonitor configures update checks of packages published on the npm registry
+type NpmMonitor struct {
+	// Identifier is the name of the package, e.g. typescript or @angular/cli
+	Identifier string `yaml:"identifier"`
+	// PreReleases allows updating to pre-releases, and to versions published under other dist-tags than latest
+	PreReleases bool `yaml:"include-prereleases"`
+}
+
+// RubyGemsMonitor configures update checks of gems released on rubygems.org
+type RubyGemsMonitor struct {
+	// Identifier is the name of the gem, e.g. nokogiri
+	Identifier string `yaml:"identifier"`
+	// Platform of the gem, e.g. x86_64-linux for precompiled native extensions, defaults to ruby
+	Platform string `yaml:"platform"`
+	// PreReleases allows updating to pre-releases, e.g. 1.16.0.rc1
+	PreReleases bool `yaml:"include-prereleases"`
+}
+
+// ScrapeMonitor configures update checks of packages whose upstream publishes releases on a plain web page, e.g. the
+// directory listing of a GNU mirror
+type ScrapeMonitor struct {
+	// URL of the page, e.g. https://ftp.gnu.org/gnu/make/
+	URL string `yaml:"url"`
+	// Pattern matching the versions on the page, capturing the version in a group named version or in its only group,
+	// e.g. make-(\d+\.\d+(?:\.\d+)?)\.tar\.gz
+	Pattern string `yaml:"pattern"`
+}
+
+// ReleaseMonitor configures how the versions of the projects on release-monitoring.org are filtered, in addition to
+// the identifier melange reads from the release-monitor block
+type ReleaseMonitor struct {
+	// Identifiers of the projects on release-monitoring.org, replacing identifier, for packages bundling the sources of
+	// several projects released together. The first project is the package's own, and the package is only updated to
+	// the versions that all of them have released.
+	Identifiers []int `yaml:"identifiers"`
+	// Include only allows updating to the versions matching this regex, e.g. ^3\. to stay on a major version
+	Include string `yaml:"include-regex"`
+	// Exclude skips the versions matching this regex, like the ignore-regex-patterns of the update config
+	Exclude string `yaml:"exclude-regex"`
+	// StableOnly restricts updates to the versions release-monitoring.org considers stable, the default. Projects whose
+	// versions are all flagged as pre-releases, e.g. because of a misleading version scheme, can turn it off.
+	StableOnly *bool `yaml:"stable-only"`
+}
+
+// IsStableOnly reports whether only stable versions are allowed, which is the default
+func (m *ReleaseMonitor) IsStableOnly() bool {
+	return m == nil || m.StableOnly == nil || *m.StableOnly
+}
+
+// updateMonitors are the update monitors of a melange config that wolfictl supports but melange doesn't
+type updateMonitors struct {
+	GitLab   *GitLabMonitor   `yaml:"gitlab"`
+	PyPI     *PyPIMonitor     `yaml:"pypi"`
+	Crates   *CratesMonitor   `yaml:"crates"`
+	Go       *GoModuleMonitor `yaml:"go"`
+	Npm      *NpmMonitor      `yaml:"npm"`
+	RubyGems *RubyGemsMonitor `yaml:"rubygems"`
+	Scrape   *ScrapeMonitor   `yaml:"scrape"`
+
+	// melange reads the identifier of the release-monitor block, and ignores the rest
+	ReleaseMonitor *ReleaseMonitor `yaml:"release-monitor"`
 }
 
 type ConfigCheck struct {
@@ -60,11 +176,25 @@ func ReadPackageConfigs(packageNames []string, dir string) (map[string]*Packages
 				return p, fmt.Errorf("failed to read package config %s: %w", fullPath, err)
 			}
 
+			ext, err := readExtensions(fullPath)
+			if err != nil {
+				return p, fmt.Errorf("failed to read package config %s: %w", fullPath, err)
+			}
+
 			p[config.Package.Name] = &Packages{
-				Config:   config,
-				Filename: filename,
-				Dir:      dir,
-				NoLint:   nolint,
+				Config:          config,
+				Filename:        filename,
+				Dir:             dir,
+				NoLint:          nolint,
+				GitLabMonitor:   ext.Update.GitLab,
+				PyPIMonitor:     ext.Update.PyPI,
+				CratesMonitor:   ext.Update.Crates,
+				GoModuleMonitor: ext.Update.Go,
+				NpmMonitor:      ext.Update.Npm,
+				RubyGemsMonitor: ext.Update.RubyGems,
+				ScrapeMonitor:   ext.Update.Scrape,
+				ReleaseMonitor:  ext.Update.ReleaseMonitor,
+				CPE:             ext.Package.CPE,
 			}
 		}
 		return p, nil
@@ -87,6 +217,48 @@ func findNoLint(filename string) ([]string, error) {
 	return nil, nil
 }
 
+// extensions are the blocks of a melange config that wolfictl supports but melange doesn't parse
+type extensions struct {
+	Package struct {
+		CPE *CPE `yaml:"cpe"`
+	} `yaml:"package"`
+	Update updateMonitors `yaml:"update"`
+}
+
+// ExtensionFields returns the fields wolfictl supports in the blocks of melange configs that melange doesn't know, by
+// the path of the block, e.g. update.
+func ExtensionFields() map[string][]string {
+	return map[string][]string{
+		"package":                yamlFields(reflect.TypeOf(extensions{}.Package)),
+		"update":                 yamlFields(reflect.TypeOf(updateMonitors{})),
+		"update.release-monitor": yamlFields(reflect.TypeOf(ReleaseMonitor{})),
+	}
+}
+
+func yamlFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// readExtensions reads the blocks of a melange config that melange doesn't parse
+func readExtensions(filename string) (extensions, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return extensions{}, err
+	}
+	var ext extensions
+	if err := yaml.Unmarshal(b, &ext); err != nil {
+		return extensions{}, err
+	}
+	return ext, nil
+}
+
 func ReadAllPackagesFromRepo(dir string) (map[string]*Packages, error) {
 	p := make(map[string]*Packages)
 
@@ -135,14 +307,28 @@ func ReadAllPackagesFromRepo(dir string) (map[string]*Packages, error) {
 			return p, fmt.Errorf("failed to read package config %s: %w", fi, err)
 		}
 
+		ext, err := readExtensions(fi)
+		if err != nil {
+			return p, fmt.Errorf("failed to read package config %s: %w", fi, err)
+		}
+
 		p[packageConfig.Package.Name] = &Packages{
-			Config:   packageConfig,
-			Filename: relativeFilename,
-			Dir:      dir,
-			NoLint:   nolint,
+			Config:          packageConfig,
+			Filename:        relativeFilename,
+			Dir:             dir,
+			NoLint:          nolint,
+			GitLabMonitor:   ext.Update.GitLab,
+			PyPIMonitor:     ext.Update.PyPI,
+			CratesMonitor:   ext.Update.Crates,
+			GoModuleMonitor: ext.Update.Go,
+			NpmMonitor:      ext.Update.Npm,
+			RubyGemsMonitor: ext.Update.RubyGems,
+			ScrapeMonitor:   ext.Update.Scrape,
+			ReleaseMonitor:  ext.Update.ReleaseMonitor,
+			CPE:             ext.Package.CPE,
 		}
 	}
-	fmt.Printf("found %[1]d packages\n", len(p))
+	log.Printf("found %d packages", len(p))
 	return p, nil
 }
 
@@ -155,16 +341,45 @@ func ReadMelangeConfig(filename string) (build.Configuration, error) {
 	return *packageConfig, err
 }
 
+// Bump sets the version of configFile, resets its epoch and sets the expected-commit of its git-checkout steps. Unlike
+// melange bump, it doesn't download the sources of fetch steps, RefreshFetchChecksums does.
 func Bump(configFile, version, expectedCommit string) error {
-	ctx, err := renovate.New(renovate.WithConfig(configFile))
-	if err != nil {
-		return err
-	}
 
-	bumpRenovator := bump.New(
-		bump.WithTargetVersion(version),
-		bump.WithExpectedCommit(expectedCommit),
-	)
+	return yamledit.EditFile(configFile, func(doc *yamledit.Document) error {
+		packageNode, err := renovate.NodeFromMapping(doc.Root.Content[0], "package")
+		if err != nil {
+			return err
+		}
+		versionNode, err := renovate.NodeFromMapping(packageNode, "version")
+		if err != nil {
+			return err
+		}
+		versionNode.Value = version
+		// a version like 1.10 would be read back as a number
+		versionNode.Tag = "!!str"
 
-	return ctx.Renovate(bumpRenovator)
+		epochNode, err := renovate.NodeFromMapping(packageNode, "epoch")
+		if err != nil {
+			return err
+		}
+		epochNode.Value = "0"
+
+		if expectedCommit == "" {
+			return nil
+		}
+		pipelineNode, err := renovate.NodeFromMapping(doc.Root.Content[0], "pipeline")
+		if err != nil {
+			return err
+		}
+		for _, step := range pipelineSteps(pipelineNode, "git-checkout") {
+			withNode, err := renovate.NodeFromMapping(step, "with")
+			if err != nil {
+				continue
+			}
+			if commitNode, err := renovate.NodeFromMapping(withNode, "expected-commit"); err == nil {
+				commitNode.Value = expectedCommit
+			}
+		}
+		return nil
+	})
 }
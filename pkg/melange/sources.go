@@ -0,0 +1,65 @@
+package melange
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"chainguard.dev/melange/pkg/build"
+	"chainguard.dev/melange/pkg/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// FetchSources puts the sources of the first fetch or git-checkout step of cfg into dir.
+func FetchSources(cfg *build.Configuration, dir string) error {
+	mutations, err := Variables(cfg)
+	if err != nil {
+		return err
+	}
+
+	for i := range cfg.Pipeline {
+		p := cfg.Pipeline[i]
+		switch p.Uses {
+		case "fetch":
+			uri, err := build.MutateStringFromMap(mutations, p.With["uri"])
+			if err != nil {
+				return err
+			}
+			return fetchTarball(uri, p.With["strip-components"], dir)
+
+		case "git-checkout":
+			tag, err := build.MutateStringFromMap(mutations, p.With["tag"])
+			if err != nil {
+				return err
+			}
+			_, err = git.PlainClone(dir, false, &git.CloneOptions{
+				URL:               p.With["repository"],
+				ReferenceName:     plumbing.NewTagReferenceName(tag),
+				RecurseSubmodules: git.NoRecurseSubmodules,
+				Depth:             1,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to clone %s at %s: %w", p.With["repository"], tag, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("no fetch or git-checkout step")
+}
+
+func fetchTarball(uri, strip, dir string) error {
+	filename, err := util.DownloadFile(uri)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", uri, err)
+	}
+	defer os.Remove(filename)
+
+	if strip == "" {
+		strip = "1"
+	}
+	if b, err := exec.Command("tar", "-xf", filename, "-C", dir, "--strip-components="+strip).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to extract %s: %w: %s", uri, err, b)
+	}
+	return nil
+}
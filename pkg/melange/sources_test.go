@@ -0,0 +1,48 @@
+package melange
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchSources(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ripgrep-14.0.0.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		gw := gzip.NewWriter(w)
+		tw := tar.NewWriter(gw)
+		content := "[package]\nname = \"ripgrep\"\n"
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "ripgrep-14.0.0/Cargo.toml", Mode: 0o644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, tw.Close())
+		require.NoError(t, gw.Close())
+	}))
+	defer srv.Close()
+
+	cfg := &build.Configuration{
+		Package: build.Package{Name: "ripgrep", Version: "14.0.0"},
+		Pipeline: []build.Pipeline{
+			{Uses: "fetch", With: map[string]string{"uri": srv.URL + "/ripgrep-${{package.version}}.tar.gz"}},
+			{Runs: "cargo build --release"},
+		},
+	}
+	dir := t.TempDir()
+	require.NoError(t, FetchSources(cfg, dir))
+	b, err := os.ReadFile(filepath.Join(dir, "Cargo.toml"))
+	require.NoError(t, err)
+	assert.Equal(t, "[package]\nname = \"ripgrep\"\n", string(b), "the sources are extracted without their top directory")
+
+	assert.Error(t, FetchSources(&build.Configuration{Pipeline: []build.Pipeline{{Runs: "make"}}}, t.TempDir()))
+}
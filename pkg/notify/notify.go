@@ -0,0 +1,69 @@
+// Package notify posts messages about things that need a maintainer's attention, like advisories that drifted from
+// the published packages, to a chat or alerting webhook.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Message is a notification, its Text can use markdown.
+type Message struct {
+	Title string
+	Text  string
+}
+
+// Notifier delivers messages.
+type Notifier interface {
+	Notify(ctx context.Context, m Message) error
+}
+
+// Webhook posts messages as JSON to an incoming webhook. The payload has a text field with the title and text of the
+// message, which Slack and Mattermost incoming webhooks understand, and the title and text on their own for other
+// receivers.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+type webhookPayload struct {
+	Text  string `json:"text"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// Notify posts the message to the webhook.
+func (w Webhook) Notify(ctx context.Context, m Message) error {
+	text := m.Text
+	if m.Title != "" {
+		text = fmt.Sprintf("*%s*\n%s", m.Title, m.Text)
+	}
+	b, err := json.Marshal(webhookPayload{Text: text, Title: m.Title, Body: m.Text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post notification: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024)) //nolint:errcheck // only used for the error message
+		return fmt.Errorf("failed to post notification: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_Notify(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&got))
+	}))
+	defer server.Close()
+
+	err := Webhook{URL: server.URL}.Notify(context.Background(), Message{Title: "drift", Text: "- curl CVE-2023-0001"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"text":  "*drift*\n- curl CVE-2023-0001",
+		"title": "drift",
+		"body":  "- curl CVE-2023-0001",
+	}, got)
+}
+
+func TestWebhook_NotifyFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		http.Error(rw, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := Webhook{URL: server.URL}.Notify(context.Background(), Message{Text: "hello"})
+	assert.ErrorContains(t, err, "403 Forbidden: invalid_token")
+}
@@ -0,0 +1,132 @@
+// Package promote selects packages to copy from a staging repository to a production repository, and checks that
+// they can be installed from production once they are.
+package promote
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gitlab.alpinelinux.org/alpine/go/repository"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/index"
+)
+
+// Select returns the packages of staging to promote to production. Each name selects either a package at an exact
+// version, like foo-1.2.3-r0, or the latest version of a package, like foo. Without names, every package of staging
+// that production doesn't have at the same version is selected.
+func Select(staging, production *repository.ApkIndex, names []string) ([]*repository.Package, error) {
+	var selected []*repository.Package
+	if len(names) == 0 {
+		inProduction := make(map[string]bool, len(production.Packages))
+		for _, pkg := range production.Packages {
+			inProduction[key(pkg)] = true
+		}
+		for _, pkg := range staging.Packages {
+			if !inProduction[key(pkg)] {
+				selected = append(selected, pkg)
+			}
+		}
+		return selected, nil
+	}
+
+	for _, name := range names {
+		var match *repository.Package
+		for _, pkg := range staging.Packages {
+			if key(pkg) == name {
+				match = pkg
+				break
+			}
+			if pkg.Name == name && (match == nil || dag.CompareVersions(pkg.Version, match.Version) > 0) {
+				match = pkg
+			}
+		}
+		if match == nil {
+			return nil, fmt.Errorf("package %q not found in staging", name)
+		}
+		selected = append(selected, match)
+	}
+	return selected, nil
+}
+
+// UnsatisfiedDependency is a dependency of a promoted package that neither production nor the other promoted
+// packages provide.
+type UnsatisfiedDependency struct {
+	Package    string
+	Dependency string
+}
+
+// ClosureError is returned by Verify when promoted packages would be uninstallable from production.
+type ClosureError struct {
+	Unsatisfied []UnsatisfiedDependency
+}
+
+func (e *ClosureError) Error() string {
+	deps := make([]string, 0, len(e.Unsatisfied))
+	for _, u := range e.Unsatisfied {
+		deps = append(deps, fmt.Sprintf("%s depends on %s", u.Package, u.Dependency))
+	}
+	return fmt.Sprintf("promoted packages have %d dependencies missing from production: %s", len(e.Unsatisfied), strings.Join(deps, ", "))
+}
+
+// Verify checks that every dependency of the promoted packages is satisfied by production or another promoted
+// package, so the promoted set is dependency-closed against production. It returns a *ClosureError otherwise.
+func Verify(promoted []*repository.Package, production *repository.ApkIndex) error {
+	available := Merge(production, promoted)
+
+	var unsatisfied []UnsatisfiedDependency
+	for _, pkg := range promoted {
+		for _, dep := range pkg.Dependencies {
+			if strings.HasPrefix(dep, "!") {
+				// a conflict, not a dependency
+				continue
+			}
+			c, err := dag.ParseConstraint(dep)
+			if err != nil {
+				return fmt.Errorf("parsing dependency %q of %s: %w", dep, key(pkg), err)
+			}
+			if len(dag.WhoProvidesInIndex(available, c)) == 0 {
+				unsatisfied = append(unsatisfied, UnsatisfiedDependency{Package: key(pkg), Dependency: dep})
+			}
+		}
+	}
+	if len(unsatisfied) > 0 {
+		sort.Slice(unsatisfied, func(i, j int) bool {
+			if unsatisfied[i].Package == unsatisfied[j].Package {
+				return unsatisfied[i].Dependency < unsatisfied[j].Dependency
+			}
+			return unsatisfied[i].Package < unsatisfied[j].Package
+		})
+		return &ClosureError{Unsatisfied: unsatisfied}
+	}
+	return nil
+}
+
+// Merge returns an index of the packages of production and the promoted packages, which replace the packages of
+// production at the same version.
+func Merge(production *repository.ApkIndex, promoted []*repository.Package) *repository.ApkIndex {
+	replaced := make(map[string]bool, len(promoted))
+	for _, pkg := range promoted {
+		replaced[key(pkg)] = true
+	}
+	// the signature of production doesn't cover the promoted packages, the merged index has to be signed again
+	merged := &repository.ApkIndex{Description: production.Description}
+	for _, pkg := range production.Packages {
+		if !replaced[key(pkg)] {
+			merged.Packages = append(merged.Packages, pkg)
+		}
+	}
+	merged.Packages = append(merged.Packages, promoted...)
+	index.Sort(merged)
+	return merged
+}
+
+// Filename returns the name of the apk of pkg in a repository.
+func Filename(pkg *repository.Package) string {
+	return key(pkg) + ".apk"
+}
+
+func key(pkg *repository.Package) string {
+	return fmt.Sprintf("%s-%s", pkg.Name, pkg.Version)
+}
@@ -0,0 +1,63 @@
+package promote
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+)
+
+var (
+	production = &repository.ApkIndex{Packages: []*repository.Package{
+		{Name: "glibc", Version: "2.37-r1", Provides: []string{"so:libc.so.6=6"}},
+		{Name: "openssl", Version: "3.1.0-r0", Provides: []string{"so:libssl.so.3=3"}},
+	}}
+	staging = &repository.ApkIndex{Packages: []*repository.Package{
+		{Name: "curl", Version: "8.0.1-r0", Dependencies: []string{"so:libc.so.6", "so:libcurl.so.4"}},
+		{Name: "curl", Version: "8.1.0-r0", Dependencies: []string{"so:libc.so.6", "so:libcurl.so.4", "!curl-minimal"}},
+		{Name: "libcurl", Version: "8.1.0-r0", Dependencies: []string{"so:libssl.so.3", "glibc>=2.37"}, Provides: []string{"so:libcurl.so.4=4"}},
+		{Name: "openssl", Version: "3.1.0-r0", Provides: []string{"so:libssl.so.3=3"}},
+	}}
+)
+
+func names(pkgs []*repository.Package) []string {
+	out := make([]string, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		out = append(out, key(pkg))
+	}
+	return out
+}
+
+func TestSelect(t *testing.T) {
+	selected, err := Select(staging, production, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"curl-8.0.1-r0", "curl-8.1.0-r0", "libcurl-8.1.0-r0"}, names(selected))
+
+	selected, err = Select(staging, production, []string{"curl", "libcurl-8.1.0-r0", "curl-8.0.1-r0"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"curl-8.1.0-r0", "libcurl-8.1.0-r0", "curl-8.0.1-r0"}, names(selected))
+
+	_, err = Select(staging, production, []string{"wget"})
+	assert.Error(t, err)
+}
+
+func TestVerify(t *testing.T) {
+	curl, err := Select(staging, production, []string{"curl"})
+	require.NoError(t, err)
+	err = Verify(curl, production)
+	var closure *ClosureError
+	require.ErrorAs(t, err, &closure)
+	assert.Equal(t, []UnsatisfiedDependency{{Package: "curl-8.1.0-r0", Dependency: "so:libcurl.so.4"}}, closure.Unsatisfied)
+
+	both, err := Select(staging, production, []string{"curl", "libcurl"})
+	require.NoError(t, err)
+	assert.NoError(t, Verify(both, production))
+}
+
+func TestMerge(t *testing.T) {
+	promoted, err := Select(staging, production, []string{"openssl", "libcurl"})
+	require.NoError(t, err)
+	merged := Merge(production, promoted)
+	assert.Equal(t, []string{"glibc-2.37-r1", "libcurl-8.1.0-r0", "openssl-3.1.0-r0"}, names(merged.Packages))
+}
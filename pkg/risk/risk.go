@@ -0,0 +1,286 @@
+// Package risk ranks the packages of a repository of melange configs by how many packages depend on them and how
+// often they change, so reviewers know where a change is both likely and far-reaching.
+package risk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tmc/dot"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+// Package is how risky changes to a package are.
+type Package struct {
+	Name    string `json:"package"`
+	Version string `json:"version"`
+	// DirectDependents is the number of local packages that depend on the package.
+	DirectDependents int `json:"directDependents"`
+	// Dependents is the number of local packages that depend on the package, directly or transitively, so need to be
+	// rebuilt when it changes.
+	Dependents int `json:"dependents"`
+	// Changes is the number of commits that changed the melange config of the package in the period of the report.
+	Changes         int     `json:"changes"`
+	ChangesPerMonth float64 `json:"changesPerMonth"`
+	// Score is the dependents times the changes per month.
+	Score float64 `json:"score"`
+	// Hotspot is set for packages in the top quarter of the packages by both dependents and changes per month.
+	Hotspot bool `json:"hotspot"`
+}
+
+// Report is the risk of the packages of a repository of melange configs.
+type Report struct {
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+	// Packages are sorted by score, highest first.
+	Packages []Package `json:"packages"`
+
+	// the local packages each package depends on directly, by name
+	dependencies map[string][]string
+}
+
+// ChangeCounts returns the number of commits since the given time that changed each file under dir, which must be in
+// a git repository, keyed by path relative to dir. Merge commits aren't counted.
+func ChangeCounts(dir string, since time.Time) (map[string]int, error) {
+	cmd := exec.Command("git", "log", "--no-merges", "--relative", "--name-only", "--format=",
+		"--since="+since.Format(time.RFC3339), "--", ".")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the history of %s from git log: %w", dir, err)
+	}
+
+	counts := make(map[string]int)
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		if path := strings.TrimSpace(s.Text()); path != "" {
+			counts[path]++
+		}
+	}
+	return counts, s.Err()
+}
+
+// NewReport returns the risk of the local packages of g, whose melange configs are in dir, given the changes to the
+// files in dir between since and until, e.g. from ChangeCounts.
+func NewReport(g *dag.Graph, dir string, changes map[string]int, since, until time.Time) (*Report, error) {
+	waves, err := g.Waves()
+	if err != nil {
+		return nil, err
+	}
+	buildDeps, err := g.BuildDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string)
+	var packages []Package
+	for _, wave := range waves {
+		for _, c := range wave {
+			names[c.String()] = c.Name()
+			path := c.Path
+			if rel, err := filepath.Rel(dir, c.Path); err == nil {
+				path = rel
+			}
+			packages = append(packages, Package{Name: c.Name(), Version: c.Version(), Changes: changes[filepath.ToSlash(path)]})
+		}
+	}
+	deps := make(map[string][]string, len(buildDeps))
+	for pkg, pkgDeps := range buildDeps {
+		for _, d := range pkgDeps {
+			deps[names[pkg]] = append(deps[names[pkg]], names[d])
+		}
+	}
+
+	return newReport(packages, deps, since, until), nil
+}
+
+func newReport(packages []Package, deps map[string][]string, since, until time.Time) *Report {
+	dependents := make(map[string][]string)
+	for pkg, pkgDeps := range deps {
+		for _, d := range pkgDeps {
+			dependents[d] = append(dependents[d], pkg)
+		}
+	}
+
+	months := until.Sub(since).Hours() / 24 / 30
+	if months <= 0 {
+		months = 1
+	}
+	for i := range packages {
+		p := &packages[i]
+		p.DirectDependents = len(dependents[p.Name])
+		p.Dependents = countTransitive(p.Name, dependents)
+		p.ChangesPerMonth = float64(p.Changes) / months
+		p.Score = float64(p.Dependents) * p.ChangesPerMonth
+	}
+	markHotspots(packages)
+
+	sort.SliceStable(packages, func(i, j int) bool {
+		if packages[i].Score != packages[j].Score {
+			return packages[i].Score > packages[j].Score
+		}
+		if packages[i].Dependents != packages[j].Dependents {
+			return packages[i].Dependents > packages[j].Dependents
+		}
+		return packages[i].Name < packages[j].Name
+	})
+	return &Report{Since: since, Until: until, Packages: packages, dependencies: deps}
+}
+
+// countTransitive returns the number of packages reachable from name, not counting itself.
+func countTransitive(name string, next map[string][]string) int {
+	seen := map[string]bool{name: true}
+	stack := []string{name}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, m := range next[n] {
+			if !seen[m] {
+				seen[m] = true
+				stack = append(stack, m)
+			}
+		}
+	}
+	return len(seen) - 1
+}
+
+// markHotspots marks the packages that are in the top quarter of the packages by both dependents and changes per
+// month, and have some of both.
+func markHotspots(packages []Package) {
+	if len(packages) == 0 {
+		return
+	}
+	dependents := make([]float64, 0, len(packages))
+	changes := make([]float64, 0, len(packages))
+	for _, p := range packages {
+		dependents = append(dependents, float64(p.Dependents))
+		changes = append(changes, p.ChangesPerMonth)
+	}
+	minDependents, minChanges := upperQuartile(dependents), upperQuartile(changes)
+	for i := range packages {
+		p := &packages[i]
+		p.Hotspot = p.Dependents > 0 && p.ChangesPerMonth > 0 &&
+			float64(p.Dependents) >= minDependents && p.ChangesPerMonth >= minChanges
+	}
+}
+
+func upperQuartile(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return sorted[len(sorted)*3/4]
+}
+
+// Hotspots returns the packages that are both highly depended upon and frequently changing.
+func (r Report) Hotspots() []Package {
+	var hotspots []Package
+	for _, p := range r.Packages {
+		if p.Hotspot {
+			hotspots = append(hotspots, p)
+		}
+	}
+	return hotspots
+}
+
+// Format is the encoding of a report.
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatJSON     Format = "json"
+	FormatDot      Format = "dot"
+)
+
+// Write encodes the report to w. Markdown has the hotspots and the top packages by score, JSON all the packages, and
+// dot the dependency graph annotated with the risk of every package.
+func (r Report) Write(w io.Writer, format Format, top int) error {
+	switch format {
+	case FormatMarkdown:
+		return r.writeMarkdown(w, top)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case FormatDot:
+		_, err := fmt.Fprintln(w, r.dot().String())
+		return err
+	default:
+		return fmt.Errorf("unknown risk report format %q", format)
+	}
+}
+
+func (r Report) writeMarkdown(w io.Writer, top int) error {
+	var b strings.Builder
+	hotspots := r.Hotspots()
+	fmt.Fprintf(&b, "# Dependency risk\n\nChanges from %s to %s, %d packages, %d of them hotspots.\n\n",
+		r.Since.Format(time.DateOnly), r.Until.Format(time.DateOnly), len(r.Packages), len(hotspots))
+
+	if len(hotspots) > 0 {
+		b.WriteString("## Hotspots\n\nPackages in the top quarter by both dependents and changes per month, where changes need the most scrutiny and test coverage.\n\n")
+		writeMarkdownTable(&b, hotspots)
+		b.WriteString("\n")
+	}
+
+	packages := r.Packages
+	if top > 0 && len(packages) > top {
+		packages = packages[:top]
+	}
+	fmt.Fprintf(&b, "## Top %d packages by score\n\nThe score is the number of dependents times the changes per month.\n\n", len(packages))
+	writeMarkdownTable(&b, packages)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMarkdownTable(b *strings.Builder, packages []Package) {
+	b.WriteString("| Package | Dependents | Direct dependents | Changes | Changes per month | Score |\n| --- | --- | --- | --- | --- | --- |\n")
+	for _, p := range packages {
+		fmt.Fprintf(b, "| %s | %d | %d | %d | %.2f | %.2f |\n", p.Name, p.Dependents, p.DirectDependents, p.Changes, p.ChangesPerMonth, p.Score)
+	}
+}
+
+// dot returns the dependency graph, with every package labelled with its changes per month and dependents, hotspots
+// filled, and the edges to a dependency as wide as the dependency changes often.
+func (r Report) dot() *dot.Graph {
+	out := dot.NewGraph("risk")
+	out.SetType(dot.DIGRAPH)
+
+	byName := make(map[string]Package, len(r.Packages))
+	nodes := make(map[string]*dot.Node, len(r.Packages))
+	names := make([]string, 0, len(r.Packages))
+	for _, p := range r.Packages {
+		byName[p.Name] = p
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := byName[name]
+		n := dot.NewNode(name)
+		_ = n.Set("label", fmt.Sprintf("%s\n%.2f changes/month\n%d dependents", name, p.ChangesPerMonth, p.Dependents))
+		if p.Hotspot {
+			_ = n.Set("style", "filled")
+			_ = n.Set("fillcolor", "salmon")
+		}
+		out.AddNode(n)
+		nodes[name] = n
+	}
+	for _, name := range names {
+		deps := append([]string(nil), r.dependencies[name]...)
+		sort.Strings(deps)
+		for _, d := range deps {
+			e := dot.NewEdge(nodes[name], nodes[d])
+			_ = e.Set("penwidth", strconv.FormatFloat(1+byName[d].ChangesPerMonth, 'f', 2, 64))
+			out.AddEdge(e)
+		}
+	}
+	return out
+}
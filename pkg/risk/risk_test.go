@@ -0,0 +1,121 @@
+package risk
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+var (
+	since = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	until = time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func testReport(t *testing.T) *Report {
+	testDir := "testdata"
+	pkgs, err := dag.NewPackages(os.DirFS(testDir), testDir)
+	require.NoError(t, err)
+	g, err := dag.NewGraph(pkgs, dag.WithAllowUnresolved())
+	require.NoError(t, err)
+
+	// six months
+	r, err := NewReport(g, testDir, map[string]int{
+		"glibc.yaml":   3,
+		"openssl.yaml": 12,
+		"curl.yaml":    6,
+		"git.yaml":     30,
+	}, since, until)
+	require.NoError(t, err)
+	return r
+}
+
+func TestNewReport(t *testing.T) {
+	r := testReport(t)
+
+	byName := make(map[string]Package)
+	var order []string
+	for _, p := range r.Packages {
+		byName[p.Name] = p
+		order = append(order, p.Name)
+	}
+	assert.Equal(t, []string{"openssl", "glibc", "curl", "git", "jq"}, order)
+
+	openssl := byName["openssl"]
+	assert.Equal(t, 1, openssl.DirectDependents)
+	assert.Equal(t, 2, openssl.Dependents)
+	assert.InDelta(t, 12/6.03, openssl.ChangesPerMonth, 0.01)
+	assert.InDelta(t, 2*12/6.03, openssl.Score, 0.01)
+
+	glibc := byName["glibc"]
+	assert.Equal(t, 3, glibc.DirectDependents)
+	assert.Equal(t, 4, glibc.Dependents)
+
+	// git changes the most, but nothing depends on it
+	assert.Zero(t, byName["git"].Score)
+
+	var hotspots []string
+	for _, p := range r.Hotspots() {
+		hotspots = append(hotspots, p.Name)
+	}
+	// glibc has the most dependents, but changes less often than most
+	assert.Equal(t, []string{"openssl"}, hotspots)
+}
+
+func TestReport_Write(t *testing.T) {
+	r := testReport(t)
+
+	var b bytes.Buffer
+	require.NoError(t, r.Write(&b, FormatMarkdown, 2))
+	assert.Contains(t, b.String(), "Changes from 2023-01-01 to 2023-07-01, 5 packages, 1 of them hotspots.")
+	assert.Contains(t, b.String(), "## Top 2 packages by score")
+	assert.Contains(t, b.String(), "| openssl | 2 | 1 | 12 | 1.99 | 3.98 |")
+	assert.NotContains(t, b.String(), "| jq |")
+
+	b.Reset()
+	require.NoError(t, r.Write(&b, FormatDot, 0))
+	assert.Contains(t, b.String(), `curl -> openssl  [ penwidth="2.99" ]`)
+	assert.Contains(t, b.String(), `fillcolor=salmon`)
+}
+
+func TestChangeCounts(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+
+	commit := func(when time.Time, files ...string) {
+		for _, f := range files {
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(f)), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, f), []byte(when.String()), 0o600))
+			_, err := wt.Add(f)
+			require.NoError(t, err)
+		}
+		_, err := wt.Commit("change "+strings.Join(files, ", "), &git.CommitOptions{
+			Author: &object.Signature{Name: "John Doe", Email: "john@doe.org", When: when},
+		})
+		require.NoError(t, err)
+	}
+	commit(since.AddDate(0, -1, 0), "configs/curl.yaml", "configs/git.yaml")
+	commit(since.AddDate(0, 1, 0), "configs/curl.yaml", "configs/git.yaml", "README.md")
+	commit(since.AddDate(0, 2, 0), "configs/curl.yaml")
+
+	counts, err := ChangeCounts(filepath.Join(dir, "configs"), since)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"curl.yaml": 2, "git.yaml": 1}, counts)
+}
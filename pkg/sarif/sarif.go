@@ -0,0 +1,217 @@
+// Package sarif writes results in the Static Analysis Results Interchange Format, which GitHub code scanning
+// accepts uploads of. Only the subset of SARIF 2.1.0 wolfictl needs is modeled.
+package sarif
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/release-utils/version"
+)
+
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// Level is the severity of a result.
+type Level string
+
+const (
+	LevelError   Level = "error"
+	LevelWarning Level = "warning"
+	LevelNote    Level = "note"
+)
+
+type Log struct {
+	Version string `json:"version"`
+	Schema  string `json:"$schema"`
+	Runs    []Run  `json:"runs"`
+}
+
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name           string `json:"name"`
+	Version        string `json:"version,omitempty"`
+	InformationURI string `json:"informationUri"`
+	Rules          []Rule `json:"rules"`
+}
+
+type Rule struct {
+	ID                   string        `json:"id"`
+	ShortDescription     Message       `json:"shortDescription"`
+	HelpURI              string        `json:"helpUri,omitempty"`
+	DefaultConfiguration Configuration `json:"defaultConfiguration"`
+	// Properties of rules about vulnerabilities have their security-severity, see SecuritySeverity.
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type Configuration struct {
+	Level Level `json:"level"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Result struct {
+	RuleID    string     `json:"ruleId"`
+	RuleIndex int        `json:"ruleIndex"`
+	Level     Level      `json:"level"`
+	Message   Message    `json:"message"`
+	Locations []Location `json:"locations"`
+	// PartialFingerprints identify a result across runs, e.g. after the lines of its file moved, by a key naming the
+	// scheme of the fingerprint.
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+	// Suppressions dismiss a result, e.g. a finding an advisory determined to be a false positive.
+	Suppressions []Suppression     `json:"suppressions,omitempty"`
+	Properties   map[string]string `json:"properties,omitempty"`
+}
+
+type Suppression struct {
+	// Kind is "external" for results dismissed outside of the analyzed files, "inSource" otherwise.
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	// Region is nil for results about a whole file.
+	Region *Region `json:"region,omitempty"`
+}
+
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type Region struct {
+	StartLine int `json:"startLine"`
+}
+
+// New returns a Log with a single run of wolfictl, knowing the given rules.
+func New(rules ...Rule) *Log {
+	return &Log{
+		Version: Version,
+		Schema:  Schema,
+		Runs: []Run{{
+			Tool: Tool{
+				Driver: Driver{
+					Name:           "wolfictl",
+					Version:        version.GetVersionInfo().GitVersion,
+					InformationURI: "https://github.com/wolfi-dev/wolfictl",
+					Rules:          rules,
+				},
+			},
+			Results: []Result{},
+		}},
+	}
+}
+
+// Add records a result of the rule with the given ID, located at line of the file at uri, or at the whole file if line
+// is 0, and returns it to set more of it, e.g. its fingerprints. The rule must have been passed to New or AddRule.
+func (l *Log) Add(ruleID, message, uri string, line int) *Result {
+	run := &l.Runs[0]
+	r := Result{
+		RuleID:    ruleID,
+		RuleIndex: -1,
+		Level:     LevelWarning,
+		Message:   Message{Text: message},
+		Locations: []Location{{
+			PhysicalLocation: PhysicalLocation{
+				ArtifactLocation: ArtifactLocation{URI: uri},
+			},
+		}},
+	}
+	if line > 0 {
+		r.Locations[0].PhysicalLocation.Region = &Region{StartLine: line}
+	}
+	for i := range run.Tool.Driver.Rules {
+		if run.Tool.Driver.Rules[i].ID == ruleID {
+			r.RuleIndex = i
+			r.Level = run.Tool.Driver.Rules[i].DefaultConfiguration.Level
+			break
+		}
+	}
+	run.Results = append(run.Results, r)
+	return &run.Results[len(run.Results)-1]
+}
+
+// AddRule adds a rule to the ones the log knows, unless it knows one with its ID already, for logs whose rules are
+// only known along with their results, like the vulnerabilities of a scan.
+func (l *Log) AddRule(rule Rule) {
+	run := &l.Runs[0]
+	for _, r := range run.Tool.Driver.Rules {
+		if r.ID == rule.ID {
+			return
+		}
+	}
+	run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+}
+
+// securitySeverities are the scores GitHub ranks the severities of vulnerabilities by, the lowest of each.
+var securitySeverities = map[string]string{
+	"CRITICAL": "9.0",
+	"HIGH":     "7.0",
+	"MEDIUM":   "4.0",
+	"LOW":      "0.1",
+}
+
+// SecuritySeverity returns the security-severity property of the rule of a vulnerability of the given severity, e.g.
+// "HIGH", which GitHub ranks the results of vulnerabilities by, and false for unknown severities.
+func SecuritySeverity(severity string) (string, bool) {
+	score, ok := securitySeverities[strings.ToUpper(severity)]
+	return score, ok
+}
+
+// SeverityLevel returns the level of the results of a vulnerability of the given severity, warnings for unknown
+// severities.
+func SeverityLevel(severity string) Level {
+	switch strings.ToUpper(severity) {
+	case "CRITICAL", "HIGH":
+		return LevelError
+	case "LOW":
+		return LevelNote
+	default:
+		return LevelWarning
+	}
+}
+
+// Write writes the log to w as indented JSON.
+func (l *Log) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(l)
+}
+
+// LineOf returns the first line of the file at path that contains s, or 1 if there's none, so results can point at
+// the most relevant line of a config they're about.
+func LineOf(path, s string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		return 1
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		if strings.Contains(scanner.Text(), s) {
+			return n
+		}
+	}
+	return 1
+}
@@ -0,0 +1,493 @@
+package scaffold
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// APKBUILD is what converting an Alpine APKBUILD needs from it: its variables, expanded like the shell would, and the
+// bodies of its functions. Lines that are neither, like conditionals on the architecture, are kept in Skipped.
+type APKBUILD struct {
+	Variables map[string]string
+	Functions map[string]string
+	Skipped   []string
+}
+
+var (
+	apkbuildFunction   = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_-]*)\(\)\s*\{\s*$`)
+	apkbuildAssignment = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+	// apkbuildReference matches the references to variables in the scripts of an APKBUILD, like $pkgver or ${pkgver}
+	apkbuildReference = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+// ParseAPKBUILD reads the variables and functions of an APKBUILD. Variables are expanded as they're assigned, with the
+// ${var%pattern}, ${var#pattern} and ${var/pattern/replacement} forms too. Commands are never run, so variables
+// assigned from command substitutions are left as they are.
+func ParseAPKBUILD(r io.Reader) (*APKBUILD, error) {
+	a := &APKBUILD{Variables: make(map[string]string), Functions: make(map[string]string)}
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if m := apkbuildFunction.FindStringSubmatch(line); m != nil {
+			start := i
+			var body []string
+			for i++; i < len(lines) && strings.TrimRight(lines[i], " \t") != "}"; i++ {
+				body = append(body, lines[i])
+			}
+			if i == len(lines) {
+				return nil, fmt.Errorf("line %d: function %s isn't closed", start+1, m[1])
+			}
+			a.Functions[m[1]] = dedent(body)
+			continue
+		}
+		if m := apkbuildAssignment.FindStringSubmatch(line); m != nil {
+			start := i
+			value := m[2]
+			for !quotesClosed(value) {
+				if i++; i == len(lines) {
+					return nil, fmt.Errorf("line %d: quotes of %s aren't closed", start+1, m[1])
+				}
+				value += "\n" + lines[i]
+			}
+			a.Variables[m[1]] = a.word(value)
+			continue
+		}
+		a.Skipped = append(a.Skipped, trimmed)
+	}
+	return a, nil
+}
+
+// quotesClosed reports whether all the quotes of a shell word are closed
+func quotesClosed(s string) bool {
+	var quote rune
+	escaped := false
+	for _, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case c == quote:
+			quote = 0
+		}
+	}
+	return quote == 0
+}
+
+// word returns the value of the shell word at the start of s, unquoted and expanded
+func (a *APKBUILD) word(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			b.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			b.WriteString(a.expand(strings.ReplaceAll(s[i+1:j], "\\\n", "")))
+			i = j
+		case c == ' ' || c == '\t':
+			// the rest is a comment
+			return b.String()
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t'\"", rune(s[j])) {
+				j++
+			}
+			b.WriteString(a.expand(s[i:j]))
+			i = j - 1
+		}
+	}
+	return b.String()
+}
+
+// expand replaces the references to variables in s by their values
+func (a *APKBUILD) expand(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			b.WriteByte(s[i+1])
+			i++
+			continue
+		}
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		if s[i+1] == '{' {
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				b.WriteString(s[i:])
+				break
+			}
+			b.WriteString(a.parameter(s[i+2 : i+end]))
+			i += end
+			continue
+		}
+		m := apkbuildReference.FindStringSubmatch(s[i:])
+		if m == nil {
+			b.WriteByte(s[i])
+			continue
+		}
+		b.WriteString(a.Variables[m[1]])
+		i += len(m[0]) - 1
+	}
+	return b.String()
+}
+
+// parameter returns the value of a parameter expansion, the part between ${ and }
+func (a *APKBUILD) parameter(p string) string {
+	i := strings.IndexAny(p, "%#/")
+	if i < 0 {
+		return a.Variables[p]
+	}
+	v, op := a.Variables[p[:i]], p[i:]
+	match := func(pattern, s string) bool {
+		ok, err := path.Match(pattern, s)
+		return err == nil && ok
+	}
+	switch {
+	case strings.HasPrefix(op, "%%"):
+		for j := 0; j <= len(v); j++ {
+			if match(op[2:], v[j:]) {
+				return v[:j]
+			}
+		}
+	case strings.HasPrefix(op, "%"):
+		for j := len(v); j >= 0; j-- {
+			if match(op[1:], v[j:]) {
+				return v[:j]
+			}
+		}
+	case strings.HasPrefix(op, "##"):
+		for j := len(v); j >= 0; j-- {
+			if match(op[2:], v[:j]) {
+				return v[j:]
+			}
+		}
+	case strings.HasPrefix(op, "#"):
+		for j := 0; j <= len(v); j++ {
+			if match(op[1:], v[:j]) {
+				return v[j:]
+			}
+		}
+	case strings.HasPrefix(op, "//"):
+		old, replacement, _ := strings.Cut(op[2:], "/")
+		return strings.ReplaceAll(v, old, replacement)
+	case strings.HasPrefix(op, "/"):
+		old, replacement, _ := strings.Cut(op[1:], "/")
+		return strings.Replace(v, old, replacement, 1)
+	}
+	return v
+}
+
+// dedent removes the indentation the lines of a function body have in common
+func dedent(lines []string) string {
+	prefix := ""
+	first := true
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		indent := l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+		switch {
+		case first:
+			prefix, first = indent, false
+		case !strings.HasPrefix(indent, prefix):
+			for !strings.HasPrefix(indent, prefix) {
+				prefix = prefix[:len(prefix)-1]
+			}
+		}
+	}
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = strings.TrimPrefix(l, prefix)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// architectures are the architectures packages are built for, which target-architecture restricts
+var architectures = []string{"x86_64", "aarch64"}
+
+// apkbuildScript replaces the variables of the scripts of APKBUILDs by their melange equivalents
+var apkbuildScript = strings.NewReplacer(
+	"${pkgdir}", "${{targets.destdir}}", "$pkgdir", "${{targets.destdir}}",
+	"${subpkgdir}", "${{targets.subpkgdir}}", "$subpkgdir", "${{targets.subpkgdir}}",
+	"${pkgver}", "${{package.version}}", "$pkgver", "${{package.version}}",
+	"${pkgname}", "${{package.name}}", "$pkgname", "${{package.name}}",
+	"${CHOST}", "${{host.triplet.gnu}}", "$CHOST", "${{host.triplet.gnu}}",
+	"${CBUILD}", "${{host.triplet.gnu}}", "$CBUILD", "${{host.triplet.gnu}}",
+	"${CTARGET}", "${{host.triplet.gnu}}", "$CTARGET", "${{host.triplet.gnu}}",
+)
+
+// splitPipelines are the melange pipelines of the split functions abuild provides for subpackages
+var splitPipelines = map[string][]string{
+	"dev":    {"split/dev"},
+	"doc":    {"split/manpages", "split/infodir"},
+	"static": {"split/static"},
+	"lang":   {"split/locales"},
+}
+
+// Convert returns the melange config equivalent to the APKBUILD, and what couldn't be converted for the maintainer to
+// finish by hand. Remote sources are fetched with their sha512sums, local patches applied, and the prepare, build and
+// package functions become steps of the pipeline, with their variables replaced by melange's. Subpackages run their
+// split function, or the split pipelines of melange equivalent to abuild's.
+func (a *APKBUILD) Convert() (*Config, []string, error) {
+	v := a.Variables
+	if v["pkgname"] == "" || v["pkgver"] == "" {
+		return nil, nil, fmt.Errorf("APKBUILD has no pkgname or pkgver")
+	}
+	var warnings []string
+	for _, s := range a.Skipped {
+		warnings = append(warnings, fmt.Sprintf("line %q isn't converted", s))
+	}
+
+	c := &Config{Package: Package{
+		Name:         v["pkgname"],
+		Version:      v["pkgver"],
+		Description:  v["pkgdesc"],
+		URL:          v["url"],
+		Copyright:    copyright(v["license"]),
+		Dependencies: Dependencies{Runtime: positive(strings.Fields(v["depends"]))},
+	}}
+	if v["pkgrel"] != "" {
+		epoch, err := strconv.Atoi(v["pkgrel"])
+		if err != nil {
+			return nil, nil, fmt.Errorf("pkgrel %q isn't a number", v["pkgrel"])
+		}
+		c.Package.Epoch = epoch
+	}
+	c.Package.TargetArchitecture = targetArchitecture(strings.Fields(v["arch"]))
+
+	c.Environment.Contents.Packages = []string{"build-base", "busybox", "ca-certificates-bundle"}
+	for _, d := range positive(strings.Fields(v["makedepends"] + " " + v["makedepends_build"] + " " + v["makedepends_host"])) {
+		if !contains(c.Environment.Contents.Packages, d) {
+			c.Environment.Contents.Packages = append(c.Environment.Contents.Packages, d)
+		}
+	}
+
+	checksums := make(map[string]string)
+	for _, line := range strings.Split(v["sha512sums"], "\n") {
+		if f := strings.Fields(line); len(f) == 2 {
+			checksums[f[1]] = f[0]
+		}
+	}
+	var patches []string
+	for _, source := range strings.Fields(v["source"]) {
+		filename, uri, remote := strings.Cut(source, "::")
+		if !remote {
+			uri = filename
+			filename = path.Base(uri)
+			remote = strings.Contains(uri, "://")
+		}
+		switch {
+		case remote:
+			step := Step{Uses: "fetch", With: map[string]string{
+				"uri":             strings.ReplaceAll(uri, v["pkgver"], "${{package.version}}"),
+				"expected-sha512": checksums[filename],
+			}}
+			if len(c.Pipeline) > 0 {
+				step.With["extract"] = "false"
+			}
+			if checksums[filename] == "" {
+				warnings = append(warnings, fmt.Sprintf("source %s has no sha512sum", filename))
+			}
+			c.Pipeline = append(c.Pipeline, step)
+		case strings.HasSuffix(filename, ".patch"):
+			patches = append(patches, filename)
+		default:
+			warnings = append(warnings, fmt.Sprintf("local source %s isn't converted", filename))
+		}
+	}
+	if len(patches) > 0 {
+		c.Pipeline = append(c.Pipeline, Step{Uses: "patch", With: map[string]string{"patches": strings.Join(patches, " ")}})
+		warnings = append(warnings, fmt.Sprintf("copy the patches %s next to the config", strings.Join(patches, ", ")))
+	}
+
+	for _, f := range []struct{ function, name string }{{"prepare", "Prepare"}, {"build", "Build"}, {"package", "Package"}} {
+		if s := a.script(f.function, &warnings); s != "" {
+			c.Pipeline = append(c.Pipeline, Step{Name: f.name, Runs: s})
+		}
+	}
+	if a.Functions["check"] != "" {
+		warnings = append(warnings, "check() isn't converted")
+	}
+	if !contains(strings.Fields(v["options"]), "!strip") {
+		c.Pipeline = append(c.Pipeline, Step{Uses: "strip"})
+	}
+
+	for _, entry := range strings.Fields(v["subpackages"]) {
+		parts := strings.Split(entry, ":")
+		name, function := parts[0], parts[0][strings.LastIndex(parts[0], "-")+1:]
+		if len(parts) > 1 && parts[1] != "" {
+			function = parts[1]
+		}
+		sp := Subpackage{Name: name, Description: fmt.Sprintf("%s (%s)", v["pkgdesc"], strings.TrimPrefix(name, c.Package.Name+"-"))}
+		switch s := a.script(function, &warnings); {
+		case s != "":
+			sp.Pipeline = []Step{{Runs: s}}
+		case splitPipelines[function] != nil:
+			for _, p := range splitPipelines[function] {
+				sp.Pipeline = append(sp.Pipeline, Step{Uses: p})
+			}
+		default:
+			warnings = append(warnings, fmt.Sprintf("subpackage %s has no pipeline, as abuild's %s() has no melange equivalent", name, function))
+		}
+		if function == "dev" {
+			sp.Dependencies.Runtime = []string{c.Package.Name}
+		}
+		c.Subpackages = append(c.Subpackages, sp)
+	}
+
+	if m := GitHubMonitor(fetchURI(c)); m != nil {
+		c.Update = Update{Enabled: true, GitHub: m}
+	} else {
+		warnings = append(warnings, "add an update monitor and enable updates")
+	}
+	return c, warnings, nil
+}
+
+// script returns the body of a function converted to a melange script, without the calls to the defaults of abuild
+// melange doesn't need, and warns about the variables of the APKBUILD it still uses
+func (a *APKBUILD) script(function string, warnings *[]string) string {
+	body, ok := a.Functions[function]
+	if !ok {
+		return ""
+	}
+	var lines []string
+	for _, l := range strings.Split(body, "\n") {
+		switch strings.TrimSpace(l) {
+		case "default_prepare", `cd "$builddir"`, "cd $builddir", `cd "$srcdir"/$pkgname-$pkgver`:
+			continue
+		}
+		lines = append(lines, l)
+	}
+	s := apkbuildScript.Replace(strings.TrimSpace(strings.Join(lines, "\n")))
+
+	var used []string
+	for _, m := range apkbuildReference.FindAllStringSubmatch(s, -1) {
+		if _, ok := a.Variables[m[1]]; (ok || m[1] == "srcdir" || m[1] == "builddir") && !contains(used, m[1]) {
+			used = append(used, m[1])
+		}
+	}
+	if strings.Contains(s, "amove ") {
+		*warnings = append(*warnings, fmt.Sprintf("%s() uses amove, move the files to ${{targets.subpkgdir}} instead", function))
+	}
+	if len(used) > 0 {
+		sort.Strings(used)
+		*warnings = append(*warnings, fmt.Sprintf("%s() uses the APKBUILD variables %s", function, strings.Join(used, ", ")))
+	}
+	return s
+}
+
+// targetArchitecture returns the architectures an APKBUILD arch restricts a package to, none for all of them
+func targetArchitecture(arch []string) []string {
+	var include []string
+	exclude := make(map[string]bool)
+	for _, a := range arch {
+		switch {
+		case a == "all" || a == "noarch":
+			include = append(include, architectures...)
+		case strings.HasPrefix(a, "!"):
+			exclude[a[1:]] = true
+		case contains(architectures, a):
+			include = append(include, a)
+		}
+	}
+	var target []string
+	for _, a := range architectures {
+		if contains(include, a) && !exclude[a] && !contains(target, a) {
+			target = append(target, a)
+		}
+	}
+	if len(target) == len(architectures) {
+		return nil
+	}
+	return target
+}
+
+// GitHubMonitor returns the update monitor of sources downloaded from GitHub, the archive of a tag or the asset of a
+// release, nil for sources downloaded from elsewhere
+func GitHubMonitor(uri string) *Monitor {
+	rest, ok := strings.CutPrefix(uri, "https://github.com/")
+	if !ok {
+		return nil
+	}
+	parts := strings.SplitN(rest, "/", 4)
+	if len(parts) < 4 {
+		return nil
+	}
+	m := &Monitor{Identifier: parts[0] + "/" + parts[1]}
+	switch parts[2] {
+	case "archive":
+		m.UseTags = true
+		tag := strings.TrimPrefix(parts[3], "refs/tags/")
+		m.StripPrefix, _, ok = strings.Cut(tag, "${{package.version}}")
+	case "releases":
+		tag := strings.TrimPrefix(parts[3], "download/")
+		m.StripPrefix, _, ok = strings.Cut(tag, "${{package.version}}")
+		ok = ok && !strings.Contains(m.StripPrefix, "/")
+	default:
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	return m
+}
+
+// fetchURI returns the uri of the first fetch step of the config, if it has one
+func fetchURI(c *Config) string {
+	for _, s := range c.Pipeline {
+		if s.Uses == "fetch" {
+			return s.With["uri"]
+		}
+	}
+	return ""
+}
+
+// positive returns the dependencies of an APKBUILD without the conflicts, like !foo
+func positive(deps []string) []string {
+	var out []string
+	for _, d := range deps {
+		if !strings.HasPrefix(d, "!") {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+func TestParseAPKBUILD(t *testing.T) {
+	a, err := ParseAPKBUILD(strings.NewReader(`
+pkgname=foo
+pkgver=1.2.3_rc1
+_ver=${pkgver/_/-}
+_major=${pkgver%%.*}
+_minor=${pkgver%.*}
+_noprefix=${pkgver#*.}
+_file='$pkgname-'"$pkgver".tar.gz # a comment
+pkgdesc="a description
+on two lines"
+package() {
+	if true; then
+		echo "$pkgdir"
+	fi
+}
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3-rc1", a.Variables["_ver"])
+	assert.Equal(t, "1", a.Variables["_major"])
+	assert.Equal(t, "1.2", a.Variables["_minor"])
+	assert.Equal(t, "2.3_rc1", a.Variables["_noprefix"])
+	assert.Equal(t, "$pkgname-1.2.3_rc1.tar.gz", a.Variables["_file"])
+	assert.Equal(t, "a description\non two lines", a.Variables["pkgdesc"])
+	assert.Equal(t, "if true; then\n\techo \"$pkgdir\"\nfi", a.Functions["package"])
+
+	_, err = ParseAPKBUILD(strings.NewReader("build() {\n\tmake\n"))
+	assert.Error(t, err, "functions should be closed")
+	_, err = ParseAPKBUILD(strings.NewReader("pkgdesc=\"unclosed\n"))
+	assert.Error(t, err, "quotes should be closed")
+}
+
+func TestAPKBUILD_Convert(t *testing.T) {
+	f, err := os.Open("testdata/apkbuild/APKBUILD")
+	require.NoError(t, err)
+	defer f.Close()
+	a, err := ParseAPKBUILD(f)
+	require.NoError(t, err)
+	c, warnings, err := a.Convert()
+	require.NoError(t, err)
+
+	assert.Equal(t, Package{
+		Name:               "libfoo",
+		Version:            "1.4.2",
+		Epoch:              3,
+		Description:        "A library for doing foo",
+		URL:                "https://foo.example.com",
+		TargetArchitecture: []string{"aarch64"},
+		Copyright:          []Copyright{{License: "MIT"}},
+		Dependencies:       Dependencies{Runtime: []string{"ca-certificates"}},
+	}, c.Package)
+	assert.Equal(t, []string{"build-base", "busybox", "ca-certificates-bundle", "openssl-dev", "zlib-dev", "perl"}, c.Environment.Contents.Packages)
+	assert.Equal(t, []Step{
+		{Uses: "fetch", With: map[string]string{
+			"uri":             "https://github.com/example/libfoo/archive/v${{package.version}}.tar.gz",
+			"expected-sha512": "0123abcd",
+		}},
+		{Uses: "patch", With: map[string]string{"patches": "fix-build.patch"}},
+		{Name: "Prepare", Runs: "sed -i 's/-Werror//' Makefile"},
+		{Name: "Build", Runs: "./configure \\\n\t--build=${{host.triplet.gnu}} \\\n\t--host=${{host.triplet.gnu}} \\\n\t--prefix=/usr \\\n\t--with-major=$_majorver\nmake"},
+		{Name: "Package", Runs: `make DESTDIR="${{targets.destdir}}" install`},
+		{Uses: "strip"},
+	}, c.Pipeline)
+	assert.Equal(t, []Subpackage{
+		{Name: "libfoo-dev", Description: "A library for doing foo (dev)", Pipeline: []Step{{Uses: "split/dev"}}, Dependencies: Dependencies{Runtime: []string{"libfoo"}}},
+		{Name: "libfoo-doc", Description: "A library for doing foo (doc)", Pipeline: []Step{{Uses: "split/manpages"}, {Uses: "split/infodir"}}},
+		{Name: "libfoo-tools", Description: "A library for doing foo (tools)", Pipeline: []Step{{Runs: "pkgdesc=\"$pkgdesc (command line tools)\"\namove usr/bin"}}},
+		{Name: "libfoo-openrc", Description: "A library for doing foo (openrc)"},
+	}, c.Subpackages)
+	assert.Equal(t, Update{Enabled: true, GitHub: &Monitor{Identifier: "example/libfoo", StripPrefix: "v", UseTags: true}}, c.Update)
+
+	assert.Equal(t, []string{
+		`line "case \"$CARCH\" in" isn't converted`,
+		`line "armhf) options=\"$options textrels\" ;;" isn't converted`,
+		`line "esac" isn't converted`,
+		"local source libfoo.initd isn't converted",
+		"copy the patches fix-build.patch next to the config",
+		"build() uses the APKBUILD variables _majorver",
+		"check() isn't converted",
+		"_tools() uses amove, move the files to ${{targets.subpkgdir}} instead",
+		"_tools() uses the APKBUILD variables pkgdesc",
+		"subpackage libfoo-openrc has no pipeline, as abuild's openrc() has no melange equivalent",
+	}, warnings)
+
+	// the config is one melange can read
+	b, err := c.YAML()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "libfoo.yaml"), b, 0o600))
+	packages, err := melange.ReadAllPackagesFromRepo(dir)
+	require.NoError(t, err)
+	require.Contains(t, packages, "libfoo")
+	assert.Len(t, packages["libfoo"].Config.Subpackages, 4)
+}
+
+func TestAPKBUILD_Convert_noGitHub(t *testing.T) {
+	a, err := ParseAPKBUILD(strings.NewReader(`pkgname=bar
+pkgver=2.0
+source="https://example.com/bar-$pkgver.tar.xz"
+sha512sums="ffff  bar-2.0.tar.xz"
+`))
+	require.NoError(t, err)
+	c, warnings, err := a.Convert()
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/bar-${{package.version}}.tar.xz", c.Pipeline[0].With["uri"])
+	assert.Equal(t, "ffff", c.Pipeline[0].With["expected-sha512"])
+	assert.Equal(t, Update{}, c.Update)
+	assert.Contains(t, warnings, "add an update monitor and enable updates")
+
+	_, _, err = (&APKBUILD{Variables: map[string]string{"pkgname": "bar"}}).Convert()
+	assert.Error(t, err)
+}
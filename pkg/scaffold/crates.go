@@ -0,0 +1,70 @@
+package scaffold
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type crate struct {
+	Crate struct {
+		Name             string `json:"name"`
+		Description      string `json:"description"`
+		Homepage         string `json:"homepage"`
+		Repository       string `json:"repository"`
+		MaxStableVersion string `json:"max_stable_version"`
+	} `json:"crate"`
+	Versions []struct {
+		Num      string `json:"num"`
+		Yanked   bool   `json:"yanked"`
+		Checksum string `json:"checksum"`
+		License  string `json:"license"`
+	} `json:"versions"`
+}
+
+func (o Options) crate(ctx context.Context) (*Config, error) {
+	c := &crate{}
+	found, err := o.getJSON(ctx, fmt.Sprintf("%s/api/v1/crates/%s", baseURL(o.CratesURL, defaultCratesURL), url.PathEscape(o.Identifier)), c)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no crate %s", o.Identifier)
+	}
+	want := o.Version
+	if want == "" {
+		want = c.Crate.MaxStableVersion
+	}
+	for _, v := range c.Versions {
+		if v.Num != want || v.Yanked {
+			continue
+		}
+		homepage := c.Crate.Homepage
+		if homepage == "" {
+			homepage = c.Crate.Repository
+		}
+		cfg := &Config{
+			Package: Package{
+				Name:        c.Crate.Name,
+				Version:     v.Num,
+				Description: c.Crate.Description,
+				URL:         homepage,
+				// older crates separate alternative licenses with a slash
+				Copyright: copyright(strings.ReplaceAll(v.License, "/", " OR ")),
+			},
+			Pipeline: []Step{
+				{Uses: "fetch", With: map[string]string{
+					"uri":             fmt.Sprintf("https://crates.io/api/v1/crates/%s/${{package.version}}/download", c.Crate.Name),
+					"expected-sha256": v.Checksum,
+				}},
+				{Name: "Cargo Build", Runs: fmt.Sprintf("cargo build --release --locked\ninstall -Dm755 target/release/%s ${{targets.destdir}}/usr/bin/%s", c.Crate.Name, c.Crate.Name)},
+				{Uses: "strip"},
+			},
+			Update: Update{Crates: &Monitor{Identifier: c.Crate.Name}},
+		}
+		cfg.Environment.Contents.Packages = []string{"build-base", "busybox", "ca-certificates-bundle", "rust"}
+		return cfg, nil
+	}
+	return nil, fmt.Errorf("no version %s of crate %s that isn't yanked", o.versionOrLatest(), o.Identifier)
+}
@@ -0,0 +1,150 @@
+package scaffold
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+
+	wolfiversions "github.com/wolfi-dev/wolfictl/pkg/versions"
+)
+
+// parseGitHubRepository returns the owner and name of a repository given as a URL, like
+// https://github.com/sigstore/cosign, or as owner/name
+func parseGitHubRepository(s string) (owner, repo string, err error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "https://"), "http://")
+	s = strings.TrimPrefix(s, "github.com/")
+	s = strings.TrimSuffix(strings.Trim(s, "/"), ".git")
+	owner, repo, ok := strings.Cut(s, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", "", fmt.Errorf("%q isn't a GitHub repository, like https://github.com/owner/name or owner/name", s)
+	}
+	return owner, repo, nil
+}
+
+func (o Options) gitHub(ctx context.Context) (*Config, error) {
+	owner, repo, err := parseGitHubRepository(o.Identifier)
+	if err != nil {
+		return nil, err
+	}
+	r, _, err := o.GitHubClient.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitHub repository %s/%s: %w", owner, repo, err)
+	}
+
+	tag, useTags, err := o.gitHubTag(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	// the version is the tag without what comes before its first digit, like the v of v1.2.3
+	i := strings.IndexAny(tag, "0123456789")
+	if i < 0 {
+		return nil, fmt.Errorf("tag %s of %s/%s isn't a version", tag, owner, repo)
+	}
+	prefix, v := tag[:i], tag[i:]
+	commit, err := o.tagCommit(ctx, owner, repo, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	homepage := r.GetHomepage()
+	if homepage == "" {
+		homepage = r.GetHTMLURL()
+	}
+	c := &Config{
+		Package: Package{
+			Name:        strings.ToLower(r.GetName()),
+			Version:     v,
+			Description: r.GetDescription(),
+			URL:         homepage,
+			Copyright:   copyright(r.GetLicense().GetSPDXID()),
+		},
+		Pipeline: []Step{
+			{Uses: "git-checkout", With: map[string]string{
+				"repository":      fmt.Sprintf("https://github.com/%s/%s", owner, repo),
+				"tag":             prefix + "${{package.version}}",
+				"expected-commit": commit,
+			}},
+			{Uses: "autoconf/configure"},
+			{Uses: "autoconf/make"},
+			{Uses: "autoconf/make-install"},
+			{Uses: "strip"},
+		},
+		Update: Update{GitHub: &Monitor{Identifier: owner + "/" + repo, StripPrefix: prefix, UseTags: useTags}},
+	}
+	c.Environment.Contents.Packages = []string{"autoconf", "automake", "build-base", "busybox", "ca-certificates-bundle"}
+	return c, nil
+}
+
+// gitHubTag returns the tag of the version to package: the tag of the version of the options, or else of the latest
+// release, or else the tag of the highest version if the repository doesn't publish releases, in which case the update
+// checks need to use tags too
+func (o Options) gitHubTag(ctx context.Context, owner, repo string) (tag string, useTags bool, err error) {
+	if o.Version != "" {
+		for _, t := range []string{"v" + o.Version, o.Version} {
+			_, resp, err := o.GitHubClient.Git.GetRef(ctx, owner, repo, "tags/"+t)
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				continue
+			}
+			if err != nil {
+				return "", false, fmt.Errorf("failed to get tag %s of %s/%s: %w", t, owner, repo, err)
+			}
+			releases, _, err := o.GitHubClient.Repositories.ListReleases(ctx, owner, repo, &github.ListOptions{PerPage: 1})
+			return t, err == nil && len(releases) == 0, nil
+		}
+		return "", false, fmt.Errorf("no tag of version %s in %s/%s", o.Version, owner, repo)
+	}
+
+	release, resp, err := o.GitHubClient.Repositories.GetLatestRelease(ctx, owner, repo)
+	if err == nil {
+		return release.GetTagName(), false, nil
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		return "", false, fmt.Errorf("failed to get the latest release of %s/%s: %w", owner, repo, err)
+	}
+
+	tags, _, err := o.GitHubClient.Repositories.ListTags(ctx, owner, repo, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return "", false, fmt.Errorf("failed to list the tags of %s/%s: %w", owner, repo, err)
+	}
+	var latest string
+	for _, t := range tags {
+		i := strings.IndexAny(t.GetName(), "0123456789")
+		if i < 0 {
+			continue
+		}
+		v, err := wolfiversions.NewVersion(t.GetName()[i:])
+		if err != nil || v.Prerelease() != "" {
+			continue
+		}
+		if latest != "" {
+			previous, err := wolfiversions.NewVersion(latest[strings.IndexAny(latest, "0123456789"):])
+			if err == nil && !v.GreaterThan(previous) {
+				continue
+			}
+		}
+		latest = t.GetName()
+	}
+	if latest == "" {
+		return "", false, fmt.Errorf("%s/%s has no releases nor version tags", owner, repo)
+	}
+	return latest, true, nil
+}
+
+// tagCommit returns the commit a tag points to, following annotated tags
+func (o Options) tagCommit(ctx context.Context, owner, repo, tag string) (string, error) {
+	ref, _, err := o.GitHubClient.Git.GetRef(ctx, owner, repo, "tags/"+tag)
+	if err != nil {
+		return "", fmt.Errorf("failed to get tag %s of %s/%s: %w", tag, owner, repo, err)
+	}
+	if ref.GetObject().GetType() != "tag" {
+		return ref.GetObject().GetSHA(), nil
+	}
+	t, _, err := o.GitHubClient.Git.GetTag(ctx, owner, repo, ref.GetObject().GetSHA())
+	if err != nil {
+		return "", fmt.Errorf("failed to get annotated tag %s of %s/%s: %w", tag, owner, repo, err)
+	}
+	return t.GetObject().GetSHA(), nil
+}
@@ -0,0 +1,111 @@
+package scaffold
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// goModuleInfo is the .info of a version of a module on the Go module proxy, with where it comes from for versions
+// the proxy fetched recently enough to record it
+type goModuleInfo struct {
+	Version string `json:"Version"`
+	Origin  *struct {
+		VCS    string `json:"VCS"`
+		URL    string `json:"URL"`
+		Subdir string `json:"Subdir"`
+		Ref    string `json:"Ref"`
+		Hash   string `json:"Hash"`
+	} `json:"Origin"`
+}
+
+func (o Options) goModule(ctx context.Context) (*Config, error) {
+	escaped, err := module.EscapePath(o.Identifier)
+	if err != nil {
+		return nil, fmt.Errorf("invalid go module path %q: %w", o.Identifier, err)
+	}
+	endpoint := "@latest"
+	if o.Version != "" {
+		endpoint = "@v/v" + strings.TrimPrefix(o.Version, "v") + ".info"
+	}
+	info := &goModuleInfo{}
+	found, err := o.getJSON(ctx, fmt.Sprintf("%s/%s/%s", baseURL(o.GoProxyURL, defaultGoProxyURL), escaped, endpoint), info)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no version %s of go module %s", o.versionOrLatest(), o.Identifier)
+	}
+	if module.IsPseudoVersion(info.Version) {
+		return nil, fmt.Errorf("go module %s has no tagged versions, only %s", o.Identifier, info.Version)
+	}
+	v := strings.TrimPrefix(info.Version, "v")
+
+	// the tag of the version, which is prefixed by the directory of modules in subdirectories of their repository
+	repository, tag, commit, modroot := "", info.Version, "", "."
+	if info.Origin != nil && info.Origin.VCS == "git" {
+		repository, commit = info.Origin.URL, info.Origin.Hash
+		tag = strings.TrimPrefix(info.Origin.Ref, "refs/tags/")
+		if info.Origin.Subdir != "" {
+			modroot = info.Origin.Subdir
+		}
+	}
+	root, _, _ := module.SplitPathVersion(o.Identifier)
+	var owner, repo string
+	if strings.HasPrefix(root, "github.com/") {
+		parts := strings.Split(root, "/")
+		if len(parts) >= 3 {
+			owner, repo = parts[1], parts[2]
+			if repository == "" {
+				repository = fmt.Sprintf("https://github.com/%s/%s", owner, repo)
+			}
+		}
+	}
+	if repository == "" {
+		return nil, fmt.Errorf("can't tell the repository of go module %s, try its source repository instead", o.Identifier)
+	}
+
+	c := &Config{
+		Package: Package{
+			Name:    path.Base(root),
+			Version: v,
+			URL:     "https://pkg.go.dev/" + o.Identifier,
+		},
+		Update: Update{Go: &Monitor{Identifier: o.Identifier}},
+	}
+	if owner != "" {
+		r, _, err := o.GitHubClient.Repositories.Get(ctx, owner, repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get GitHub repository %s/%s: %w", owner, repo, err)
+		}
+		c.Package.Description = r.GetDescription()
+		c.Package.Copyright = copyright(r.GetLicense().GetSPDXID())
+		if commit == "" {
+			if commit, err = o.tagCommit(ctx, owner, repo, tag); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if commit == "" {
+		return nil, fmt.Errorf("can't tell the commit of go module %s %s, try its source repository instead", o.Identifier, info.Version)
+	}
+
+	c.Pipeline = []Step{
+		{Uses: "git-checkout", With: map[string]string{
+			"repository":      repository,
+			"tag":             strings.TrimSuffix(tag, v) + "${{package.version}}",
+			"expected-commit": commit,
+		}},
+		{Uses: "go/build", With: map[string]string{
+			"packages": ".",
+			"modroot":  modroot,
+			"output":   c.Package.Name,
+		}},
+		{Uses: "strip"},
+	}
+	c.Environment.Contents.Packages = []string{"busybox", "ca-certificates-bundle", "go"}
+	return c, nil
+}
@@ -0,0 +1,131 @@
+package scaffold
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/wolfi-dev/wolfictl/pkg/license"
+)
+
+type pypiRelease struct {
+	Info struct {
+		Name              string            `json:"name"`
+		Version           string            `json:"version"`
+		Summary           string            `json:"summary"`
+		HomePage          string            `json:"home_page"`
+		ProjectURLs       map[string]string `json:"project_urls"`
+		License           string            `json:"license"`
+		LicenseExpression string            `json:"license_expression"`
+		Classifiers       []string          `json:"classifiers"`
+	} `json:"info"`
+	URLs []struct {
+		PackageType string `json:"packagetype"`
+		Filename    string `json:"filename"`
+		Digests     struct {
+			SHA256 string `json:"sha256"`
+		} `json:"digests"`
+	} `json:"urls"`
+}
+
+// pypiClassifierLicenses are the licenses of the trove classifiers that name a single SPDX license
+var pypiClassifierLicenses = map[string]string{
+	"License :: OSI Approved :: Apache Software License":                             "Apache-2.0",
+	"License :: OSI Approved :: MIT License":                                         "MIT",
+	"License :: OSI Approved :: ISC License (ISCL)":                                  "ISC",
+	"License :: OSI Approved :: Mozilla Public License 2.0 (MPL 2.0)":                "MPL-2.0",
+	"License :: OSI Approved :: Python Software Foundation License":                  "PSF-2.0",
+	"License :: OSI Approved :: GNU General Public License v2 (GPLv2)":               "GPL-2.0-only",
+	"License :: OSI Approved :: GNU General Public License v3 (GPLv3)":               "GPL-3.0-only",
+	"License :: OSI Approved :: GNU Lesser General Public License v3 (LGPLv3)":       "LGPL-3.0-only",
+	"License :: OSI Approved :: GNU Library or Lesser General Public License (LGPL)": "LGPL-2.0-or-later",
+}
+
+// pypiNameSeparators are replaced by hyphens in normalized project names, https://peps.python.org/pep-0503/#normalized-names
+var pypiNameSeparators = regexp.MustCompile(`[-_.]+`)
+
+func (o Options) pypi(ctx context.Context) (*Config, error) {
+	u := fmt.Sprintf("%s/pypi/%s/json", baseURL(o.PyPIURL, defaultPyPIURL), url.PathEscape(o.Identifier))
+	if o.Version != "" {
+		u = fmt.Sprintf("%s/pypi/%s/%s/json", baseURL(o.PyPIURL, defaultPyPIURL), url.PathEscape(o.Identifier), url.PathEscape(o.Version))
+	}
+	r := &pypiRelease{}
+	found, err := o.getJSON(ctx, u, r)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no release %s of pypi project %s", o.versionOrLatest(), o.Identifier)
+	}
+	info := r.Info
+
+	var filename, sha256 string
+	for _, f := range r.URLs {
+		if f.PackageType == "sdist" {
+			filename, sha256 = f.Filename, f.Digests.SHA256
+			break
+		}
+	}
+	if filename == "" || !strings.Contains(filename, info.Version) {
+		return nil, fmt.Errorf("pypi project %s %s has no source distribution, try its source repository instead", info.Name, info.Version)
+	}
+	i := strings.LastIndex(filename, info.Version)
+	filename = filename[:i] + "${{package.version}}" + filename[i+len(info.Version):]
+
+	homepage := info.ProjectURLs["Homepage"]
+	if homepage == "" {
+		homepage = info.HomePage
+	}
+
+	c := &Config{
+		Package: Package{
+			Name:        "py3-" + strings.ToLower(pypiNameSeparators.ReplaceAllString(info.Name, "-")),
+			Version:     info.Version,
+			Description: info.Summary,
+			URL:         homepage,
+			Copyright:   copyright(pypiLicense(info.LicenseExpression, info.License, info.Classifiers)),
+		},
+		Pipeline: []Step{
+			{Uses: "fetch", With: map[string]string{
+				"uri":             fmt.Sprintf("https://files.pythonhosted.org/packages/source/%s/%s/%s", info.Name[:1], info.Name, filename),
+				"expected-sha256": sha256,
+			}},
+			{Name: "Python Build", Runs: "python3 -m build --wheel --no-isolation"},
+			{Name: "Python Install", Runs: "python3 -m installer --destdir=${{targets.destdir}} dist/*.whl"},
+			{Uses: "strip"},
+		},
+		Update: Update{PyPI: &Monitor{Identifier: info.Name}},
+	}
+	c.Environment.Contents.Packages = []string{"build-base", "busybox", "ca-certificates-bundle", "py3-build", "py3-installer", "py3-setuptools", "py3-wheel", "python-3"}
+	return c, nil
+}
+
+// pypiLicense returns the license of a project: its license expression, or else its license if it's a valid SPDX
+// expression, or else the license of its classifiers, or else its license as it is if it's short enough not to be the
+// text of the license
+func pypiLicense(expression, projectLicense string, classifiers []string) string {
+	if expression != "" {
+		return expression
+	}
+	if _, unknown, err := license.Normalize(projectLicense); err == nil && len(unknown) == 0 {
+		return projectLicense
+	}
+	for _, c := range classifiers {
+		if l, ok := pypiClassifierLicenses[c]; ok {
+			return l
+		}
+	}
+	if len(projectLicense) <= 64 && !strings.Contains(projectLicense, "\n") {
+		return projectLicense
+	}
+	return ""
+}
+
+func (o Options) versionOrLatest() string {
+	if o.Version == "" {
+		return "latest"
+	}
+	return o.Version
+}
@@ -0,0 +1,62 @@
+package scaffold
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// rubyVersion is the version of Ruby gems are packaged for, in the names of the packages and of their build
+// dependencies
+const rubyVersion = "3.2"
+
+type gem struct {
+	Name        string   `json:"name"`
+	Version     string   `json:"version"`
+	Info        string   `json:"info"`
+	Licenses    []string `json:"licenses"`
+	HomepageURI string   `json:"homepage_uri"`
+	// SHA is the sha256 of the .gem file
+	SHA string `json:"sha"`
+}
+
+func (o Options) gem(ctx context.Context) (*Config, error) {
+	u := fmt.Sprintf("%s/api/v1/gems/%s.json", baseURL(o.RubyGemsURL, defaultRubyGemsURL), url.PathEscape(o.Identifier))
+	if o.Version != "" {
+		u = fmt.Sprintf("%s/api/v2/rubygems/%s/versions/%s.json", baseURL(o.RubyGemsURL, defaultRubyGemsURL), url.PathEscape(o.Identifier), url.PathEscape(o.Version))
+	}
+	g := &gem{}
+	found, err := o.getJSON(ctx, u, g)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no version %s of gem %s", o.versionOrLatest(), o.Identifier)
+	}
+
+	c := &Config{
+		Package: Package{
+			Name:        fmt.Sprintf("ruby%s-%s", rubyVersion, g.Name),
+			Version:     g.Version,
+			Description: strings.TrimSpace(g.Info),
+			URL:         g.HomepageURI,
+			Copyright:   copyright(strings.Join(g.Licenses, " OR ")),
+		},
+		Pipeline: []Step{
+			{Uses: "fetch", With: map[string]string{
+				"uri":             fmt.Sprintf("https://rubygems.org/downloads/%s-${{package.version}}.gem", g.Name),
+				"expected-sha256": g.SHA,
+				"extract":         "false",
+			}},
+			{Uses: "ruby/install", With: map[string]string{
+				"gem":     g.Name,
+				"version": "${{package.version}}",
+			}},
+			{Uses: "ruby/clean"},
+		},
+		Update: Update{RubyGems: &Monitor{Identifier: g.Name}},
+	}
+	c.Environment.Contents.Packages = []string{"build-base", "busybox", "ca-certificates-bundle", "ruby-" + rubyVersion, "ruby-" + rubyVersion + "-dev"}
+	return c, nil
+}
@@ -0,0 +1,229 @@
+// Package scaffold generates the melange configs of new packages from the metadata of their upstream projects on PyPI,
+// the Go module proxy, rubygems.org, crates.io or GitHub.
+package scaffold
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+	"gopkg.in/yaml.v3"
+
+	"github.com/wolfi-dev/wolfictl/pkg/license"
+)
+
+// the kinds of upstream projects a config can be generated from
+const (
+	SourcePyPI   = "pypi"
+	SourceGo     = "go"
+	SourceGem    = "gem"
+	SourceCrate  = "crate"
+	SourceGitHub = "github"
+)
+
+// Sources are the kinds of upstream projects a config can be generated from.
+var Sources = []string{SourcePyPI, SourceGo, SourceGem, SourceCrate, SourceGitHub}
+
+const (
+	defaultPyPIURL     = "https://pypi.org"
+	defaultGoProxyURL  = "https://proxy.golang.org"
+	defaultRubyGemsURL = "https://rubygems.org"
+	defaultCratesURL   = "https://crates.io"
+
+	// crates.io rejects requests without a user agent identifying the client
+	userAgent = "wolfictl (https://github.com/wolfi-dev/wolfictl)"
+)
+
+// Options configures how a config is generated.
+type Options struct {
+	// Source is the kind of upstream project, one of Sources.
+	Source string
+	// Identifier of the upstream project: the name of a PyPI project, a gem or a crate, the path of a Go module, or
+	// the URL or owner/name of a GitHub repository.
+	Identifier string
+	// Version to package, the latest release if empty.
+	Version string
+	// Name of the package, derived from the identifier if empty.
+	Name string
+
+	Client *http.Client
+	// GitHubClient looks up the repositories of GitHub projects and Go modules hosted on GitHub.
+	GitHubClient *github.Client
+
+	// base URLs of the registries, default to the public ones
+	PyPIURL, GoProxyURL, RubyGemsURL, CratesURL string
+}
+
+// Config is a melange config generated for an upstream project, with just what's needed to build it.
+type Config struct {
+	Package     Package      `yaml:"package"`
+	Environment Environment  `yaml:"environment"`
+	Pipeline    []Step       `yaml:"pipeline"`
+	Subpackages []Subpackage `yaml:"subpackages,omitempty"`
+	Update      Update       `yaml:"update"`
+}
+
+// Package is the package block of a config.
+type Package struct {
+	Name        string `yaml:"name"`
+	Version     string `yaml:"version"`
+	Epoch       int    `yaml:"epoch"`
+	Description string `yaml:"description,omitempty"`
+	URL         string `yaml:"url,omitempty"`
+	// TargetArchitecture restricts the architectures the package is built for, all of them if empty.
+	TargetArchitecture []string     `yaml:"target-architecture,omitempty"`
+	Copyright          []Copyright  `yaml:"copyright,omitempty"`
+	Dependencies       Dependencies `yaml:"dependencies,omitempty"`
+}
+
+// Dependencies are the packages a package needs at runtime.
+type Dependencies struct {
+	Runtime []string `yaml:"runtime,omitempty"`
+}
+
+// Subpackage is a package split from the files the pipeline installs.
+type Subpackage struct {
+	Name         string       `yaml:"name"`
+	Description  string       `yaml:"description,omitempty"`
+	Pipeline     []Step       `yaml:"pipeline,omitempty"`
+	Dependencies Dependencies `yaml:"dependencies,omitempty"`
+}
+
+// Copyright is the license of a package.
+type Copyright struct {
+	License string `yaml:"license"`
+}
+
+// Environment lists the packages the build needs.
+type Environment struct {
+	Contents struct {
+		Packages []string `yaml:"packages"`
+	} `yaml:"contents"`
+}
+
+// Step is a step of the pipeline of a config.
+type Step struct {
+	Name string            `yaml:"name,omitempty"`
+	Uses string            `yaml:"uses,omitempty"`
+	With map[string]string `yaml:"with,omitempty"`
+	Runs string            `yaml:"runs,omitempty"`
+}
+
+// Update is the update block of a config, with the monitor of the upstream project.
+type Update struct {
+	Enabled  bool     `yaml:"enabled"`
+	GitHub   *Monitor `yaml:"github,omitempty"`
+	PyPI     *Monitor `yaml:"pypi,omitempty"`
+	Go       *Monitor `yaml:"go,omitempty"`
+	RubyGems *Monitor `yaml:"rubygems,omitempty"`
+	Crates   *Monitor `yaml:"crates,omitempty"`
+}
+
+// Monitor is how the update checks find the versions of the upstream project.
+type Monitor struct {
+	Identifier  string `yaml:"identifier"`
+	StripPrefix string `yaml:"strip-prefix,omitempty"`
+	UseTags     bool   `yaml:"use-tag,omitempty"`
+}
+
+// YAML returns the config as melange YAML.
+func (c *Config) YAML() ([]byte, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Generate looks up the upstream project and returns a config building the version of the options, pre-filled with
+// what its metadata says: the description, homepage, license, sources and their checksum or commit, and an update
+// block monitoring it. The pipeline is a skeleton of the usual build of its ecosystem, meant to be edited.
+func (o Options) Generate(ctx context.Context) (*Config, error) {
+	if o.Client == nil {
+		o.Client = http.DefaultClient
+	}
+	if o.GitHubClient == nil {
+		o.GitHubClient = github.NewClient(o.Client)
+	}
+
+	var c *Config
+	var err error
+	switch o.Source {
+	case SourcePyPI:
+		c, err = o.pypi(ctx)
+	case SourceGo:
+		c, err = o.goModule(ctx)
+	case SourceGem:
+		c, err = o.gem(ctx)
+	case SourceCrate:
+		c, err = o.crate(ctx)
+	case SourceGitHub:
+		c, err = o.gitHub(ctx)
+	default:
+		return nil, fmt.Errorf("unknown source %q, must be one of: %s", o.Source, strings.Join(Sources, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if o.Name != "" {
+		c.Package.Name = o.Name
+	}
+	c.Update.Enabled = true
+	return c, nil
+}
+
+// copyright returns the copyright of a package under the given license, normalized when it's a valid SPDX expression
+// and left for the maintainer to fix otherwise, none if the license isn't known
+func copyright(expression string) []Copyright {
+	expression = strings.TrimSpace(expression)
+	if expression == "" || expression == "NOASSERTION" {
+		return nil
+	}
+	if normalized, _, err := license.Normalize(expression); err == nil {
+		expression = normalized
+	}
+	return []Copyright{{License: expression}}
+}
+
+// getJSON decodes the JSON of a GET request into v, and returns false if there's nothing at the URL
+func (o Options) getJSON(ctx context.Context, u string, v any) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return false, fmt.Errorf("failed creating GET request %s: %w", u, err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed getting URI %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return false, nil
+	default:
+		b, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("non ok http response for URI %s code: %v: %s", u, resp.StatusCode, b)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", u, err)
+	}
+	return true, nil
+}
+
+func baseURL(u, fallback string) string {
+	if u == "" {
+		u = fallback
+	}
+	return strings.TrimSuffix(u, "/")
+}
@@ -0,0 +1,194 @@
+package scaffold
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+var upstreamResponses = map[string]string{
+	"/pypi/requests/json": `{
+		"info": {"name": "requests", "version": "2.31.0", "summary": "Python HTTP for Humans.", "license": "Apache 2.0",
+			"project_urls": {"Homepage": "https://requests.readthedocs.io"},
+			"classifiers": ["License :: OSI Approved :: Apache Software License"]},
+		"urls": [
+			{"packagetype": "bdist_wheel", "filename": "requests-2.31.0-py3-none-any.whl", "digests": {"sha256": "aaaa"}},
+			{"packagetype": "sdist", "filename": "requests-2.31.0.tar.gz", "digests": {"sha256": "bbbb"}}
+		]
+	}`,
+	"/github.com/sigstore/cosign/v2/@v/v2.2.0.info": `{"Version": "v2.2.0",
+		"Origin": {"VCS": "git", "URL": "https://github.com/sigstore/cosign", "Ref": "refs/tags/v2.2.0", "Hash": "cccc"}}`,
+	"/api/v1/gems/nokogiri.json": `{"name": "nokogiri", "version": "1.15.4", "info": "Nokogiri makes it easy to deal with XML and HTML.",
+		"licenses": ["MIT"], "homepage_uri": "https://nokogiri.org", "sha": "dddd"}`,
+	"/api/v1/crates/ripgrep": `{
+		"crate": {"name": "ripgrep", "description": "ripgrep is a line-oriented search tool", "repository": "https://github.com/BurntSushi/ripgrep", "max_stable_version": "13.0.0"},
+		"versions": [
+			{"num": "14.0.0-beta", "checksum": "ffff", "license": "Unlicense OR MIT"},
+			{"num": "13.0.0", "checksum": "eeee", "license": "Unlicense/MIT"}
+		]
+	}`,
+	"/repos/sigstore/cosign":                     `{"name": "cosign", "description": "Code signing and transparency for containers and binaries", "license": {"spdx_id": "Apache-2.0"}}`,
+	"/repos/jqlang/jq":                           `{"name": "jq", "description": "Command-line JSON processor", "homepage": "https://jqlang.github.io/jq/", "license": {"spdx_id": "NOASSERTION"}}`,
+	"/repos/jqlang/jq/releases/latest":           `{"tag_name": "jq-1.7"}`,
+	"/repos/jqlang/jq/git/ref/tags/jq-1.7":       `{"ref": "refs/tags/jq-1.7", "object": {"type": "tag", "sha": "1111"}}`,
+	"/repos/jqlang/jq/git/tags/1111":             `{"object": {"type": "commit", "sha": "2222"}}`,
+	"/repos/example/tagged":                      `{"name": "Tagged", "html_url": "https://github.com/example/tagged"}`,
+	"/repos/example/tagged/tags":                 `[{"name": "v1.10.0"}, {"name": "v2.0.0-rc1"}, {"name": "v1.9.0"}, {"name": "nightly"}]`,
+	"/repos/example/tagged/git/ref/tags/v1.10.0": `{"ref": "refs/tags/v1.10.0", "object": {"type": "commit", "sha": "3333"}}`,
+}
+
+func testOptions(t *testing.T) Options {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := upstreamResponses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(body))
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(server.Client())
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	return Options{
+		Client:       server.Client(),
+		GitHubClient: client,
+		PyPIURL:      server.URL,
+		GoProxyURL:   server.URL,
+		RubyGemsURL:  server.URL,
+		CratesURL:    server.URL,
+	}
+}
+
+func TestOptions_Generate(t *testing.T) {
+	tests := []struct {
+		source, identifier, version string
+		wantPackage                 Package
+		wantSource                  Step
+		wantUpdate                  Update
+	}{
+		{
+			source:      SourcePyPI,
+			identifier:  "requests",
+			wantPackage: Package{Name: "py3-requests", Version: "2.31.0", Description: "Python HTTP for Humans.", URL: "https://requests.readthedocs.io", Copyright: []Copyright{{License: "Apache-2.0"}}},
+			wantSource: Step{Uses: "fetch", With: map[string]string{
+				"uri":             "https://files.pythonhosted.org/packages/source/r/requests/requests-${{package.version}}.tar.gz",
+				"expected-sha256": "bbbb",
+			}},
+			wantUpdate: Update{Enabled: true, PyPI: &Monitor{Identifier: "requests"}},
+		},
+		{
+			source:      SourceGo,
+			identifier:  "github.com/sigstore/cosign/v2",
+			version:     "2.2.0",
+			wantPackage: Package{Name: "cosign", Version: "2.2.0", Description: "Code signing and transparency for containers and binaries", URL: "https://pkg.go.dev/github.com/sigstore/cosign/v2", Copyright: []Copyright{{License: "Apache-2.0"}}},
+			wantSource: Step{Uses: "git-checkout", With: map[string]string{
+				"repository":      "https://github.com/sigstore/cosign",
+				"tag":             "v${{package.version}}",
+				"expected-commit": "cccc",
+			}},
+			wantUpdate: Update{Enabled: true, Go: &Monitor{Identifier: "github.com/sigstore/cosign/v2"}},
+		},
+		{
+			source:      SourceGem,
+			identifier:  "nokogiri",
+			wantPackage: Package{Name: "ruby3.2-nokogiri", Version: "1.15.4", Description: "Nokogiri makes it easy to deal with XML and HTML.", URL: "https://nokogiri.org", Copyright: []Copyright{{License: "MIT"}}},
+			wantSource: Step{Uses: "fetch", With: map[string]string{
+				"uri":             "https://rubygems.org/downloads/nokogiri-${{package.version}}.gem",
+				"expected-sha256": "dddd",
+				"extract":         "false",
+			}},
+			wantUpdate: Update{Enabled: true, RubyGems: &Monitor{Identifier: "nokogiri"}},
+		},
+		{
+			source:      SourceCrate,
+			identifier:  "ripgrep",
+			wantPackage: Package{Name: "ripgrep", Version: "13.0.0", Description: "ripgrep is a line-oriented search tool", URL: "https://github.com/BurntSushi/ripgrep", Copyright: []Copyright{{License: "Unlicense OR MIT"}}},
+			wantSource: Step{Uses: "fetch", With: map[string]string{
+				"uri":             "https://crates.io/api/v1/crates/ripgrep/${{package.version}}/download",
+				"expected-sha256": "eeee",
+			}},
+			wantUpdate: Update{Enabled: true, Crates: &Monitor{Identifier: "ripgrep"}},
+		},
+		{
+			source:      SourceGitHub,
+			identifier:  "https://github.com/jqlang/jq",
+			wantPackage: Package{Name: "jq", Version: "1.7", Description: "Command-line JSON processor", URL: "https://jqlang.github.io/jq/"},
+			wantSource: Step{Uses: "git-checkout", With: map[string]string{
+				"repository":      "https://github.com/jqlang/jq",
+				"tag":             "jq-${{package.version}}",
+				"expected-commit": "2222",
+			}},
+			wantUpdate: Update{Enabled: true, GitHub: &Monitor{Identifier: "jqlang/jq", StripPrefix: "jq-"}},
+		},
+		{
+			source:      SourceGitHub,
+			identifier:  "example/tagged",
+			wantPackage: Package{Name: "tagged", Version: "1.10.0", URL: "https://github.com/example/tagged"},
+			wantSource: Step{Uses: "git-checkout", With: map[string]string{
+				"repository":      "https://github.com/example/tagged",
+				"tag":             "v${{package.version}}",
+				"expected-commit": "3333",
+			}},
+			wantUpdate: Update{Enabled: true, GitHub: &Monitor{Identifier: "example/tagged", StripPrefix: "v", UseTags: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source+" "+tt.identifier, func(t *testing.T) {
+			o := testOptions(t)
+			o.Source, o.Identifier, o.Version = tt.source, tt.identifier, tt.version
+			c, err := o.Generate(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPackage, c.Package)
+			assert.Equal(t, tt.wantSource, c.Pipeline[0])
+			assert.Equal(t, tt.wantUpdate, c.Update)
+			assert.NotEmpty(t, c.Environment.Contents.Packages)
+
+			// the config is one melange and the update checks can read
+			b, err := c.YAML()
+			require.NoError(t, err)
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, c.Package.Name+".yaml"), b, 0o600))
+			packages, err := melange.ReadAllPackagesFromRepo(dir)
+			require.NoError(t, err)
+			require.Contains(t, packages, c.Package.Name)
+			assert.Equal(t, c.Package.Version, packages[c.Package.Name].Config.Package.Version)
+			assert.True(t, packages[c.Package.Name].Config.Update.Enabled)
+		})
+	}
+}
+
+func TestOptions_Generate_errors(t *testing.T) {
+	for _, o := range []Options{
+		{Source: "cpan", Identifier: "Moose"},
+		{Source: SourcePyPI, Identifier: "missing"},
+		{Source: SourceCrate, Identifier: "ripgrep", Version: "14.0.0"},
+		{Source: SourceGitHub, Identifier: "https://gitlab.com/example"},
+	} {
+		opts := testOptions(t)
+		opts.Source, opts.Identifier, opts.Version = o.Source, o.Identifier, o.Version
+		_, err := opts.Generate(context.Background())
+		assert.Error(t, err, "%s %s", o.Source, o.Identifier)
+	}
+}
+
+func TestParseGitHubRepository(t *testing.T) {
+	for _, s := range []string{"jqlang/jq", "https://github.com/jqlang/jq", "github.com/jqlang/jq.git", "https://github.com/jqlang/jq/"} {
+		owner, repo, err := parseGitHubRepository(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, "jqlang", owner, s)
+		assert.Equal(t, "jq", repo, s)
+	}
+}
@@ -0,0 +1,137 @@
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// ChangeKind is how a vulnerability changed from one version of a package to the next.
+type ChangeKind string
+
+const (
+	ChangeIntroduced ChangeKind = "introduced"
+	ChangeFixed      ChangeKind = "fixed"
+	ChangePersisted  ChangeKind = "persisted"
+)
+
+// Change is a vulnerability of a component of a package, and how it changed between the old and new versions of the
+// package.
+type Change struct {
+	Kind          ChangeKind `json:"kind"`
+	Package       string     `json:"package"`
+	Vulnerability string     `json:"vulnerability"`
+	Severity      string     `json:"severity,omitempty"`
+
+	// Component is the component with the vulnerability, in the new version unless it's fixed.
+	Component Component `json:"component"`
+
+	// Old and New are the findings of the vulnerability in the old and new versions, if it was found in them.
+	Old *Finding `json:"old,omitempty"`
+	New *Finding `json:"new,omitempty"`
+}
+
+// Diff returns how the vulnerabilities found in the old versions of packages changed in the new ones: the ones only
+// found in the new versions are introduced, the ones only found in the old ones are fixed, and the others persisted.
+// Findings are compared by package, component and vulnerability, regardless of the versions and files the components
+// are in. The changes are sorted by kind, then package and vulnerability.
+func Diff(old, current []Finding) []Change {
+	oldByKey, oldKeys := findingsByKey(old)
+	currentByKey, currentKeys := findingsByKey(current)
+
+	var changes []Change
+	for _, k := range currentKeys {
+		c := currentByKey[k]
+		change := Change{
+			Kind:          ChangeIntroduced,
+			Package:       c.Package,
+			Vulnerability: c.Vulnerability,
+			Severity:      c.Severity,
+			Component:     c.Component,
+			New:           c,
+		}
+		if o, ok := oldByKey[k]; ok {
+			change.Kind, change.Old = ChangePersisted, o
+		}
+		changes = append(changes, change)
+	}
+	for _, k := range oldKeys {
+		if _, ok := currentByKey[k]; ok {
+			continue
+		}
+		o := oldByKey[k]
+		changes = append(changes, Change{
+			Kind:          ChangeFixed,
+			Package:       o.Package,
+			Vulnerability: o.Vulnerability,
+			Severity:      o.Severity,
+			Component:     o.Component,
+			Old:           o,
+		})
+	}
+
+	kindOrder := map[ChangeKind]int{ChangeIntroduced: 0, ChangeFixed: 1, ChangePersisted: 2}
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Kind != b.Kind {
+			return kindOrder[a.Kind] < kindOrder[b.Kind]
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Vulnerability < b.Vulnerability
+	})
+	return changes
+}
+
+type findingKey struct {
+	pkg, ecosystem, component, vulnerability string
+}
+
+// findingsByKey returns the findings by what they're compared by, the first one of findings of the same component in
+// several files, and the keys in the order of the findings.
+func findingsByKey(findings []Finding) (map[findingKey]*Finding, []findingKey) {
+	byKey := make(map[findingKey]*Finding)
+	var keys []findingKey
+	for i := range findings {
+		f := &findings[i]
+		k := findingKey{f.Package, f.Component.Ecosystem, f.Component.Name, f.Vulnerability}
+		if _, ok := byKey[k]; ok {
+			continue
+		}
+		byKey[k] = f
+		keys = append(keys, k)
+	}
+	return byKey, keys
+}
+
+// WriteDiff encodes the changes to w, as a table or JSON.
+func WriteDiff(w io.Writer, changes []Change, format Format) error {
+	switch format {
+	case FormatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "CHANGE\tPACKAGE\tVULNERABILITY\tSEVERITY\tCOMPONENT\tOLD VERSION\tNEW VERSION")
+		for _, c := range changes {
+			oldVersion, newVersion := "-", "-"
+			if c.Old != nil {
+				oldVersion = c.Old.Component.Version
+			}
+			if c.New != nil {
+				newVersion = c.New.Component.Version
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s (%s)\t%s\t%s\n", c.Kind, c.Package, c.Vulnerability, orDash(c.Severity), c.Component.Name, c.Component.Ecosystem, oldVersion, newVersion)
+		}
+		return tw.Flush()
+	case FormatJSON:
+		if changes == nil {
+			changes = []Change{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(changes)
+	default:
+		return fmt.Errorf("unknown scan diff format %q", format)
+	}
+}
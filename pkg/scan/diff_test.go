@@ -0,0 +1,65 @@
+package scan
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	finding := func(pkg, name, version, path, vulnerability string) Finding {
+		return Finding{
+			Package:       pkg,
+			Component:     Component{Name: name, Version: version, Ecosystem: EcosystemGo, Path: path},
+			Vulnerability: vulnerability,
+		}
+	}
+	old := []Finding{
+		finding("crane", "stdlib", "1.20.1", "usr/bin/crane", "GO-2023-1621"),
+		finding("crane", "stdlib", "1.20.1", "usr/bin/crane", "GO-2023-1704"),
+		finding("crane", "stdlib", "1.20.1", "usr/bin/gcrane", "GO-2023-1704"),
+		finding("crane", "golang.org/x/net", "0.7.0", "usr/bin/crane", "GO-2023-1737"),
+	}
+	current := []Finding{
+		finding("crane", "stdlib", "1.20.4", "usr/bin/crane", "GO-2023-1704"),
+		finding("crane", "golang.org/x/net", "0.10.0", "usr/bin/crane", "GO-2023-1988"),
+	}
+
+	changes := Diff(old, current)
+
+	var got []string
+	for _, c := range changes {
+		got = append(got, string(c.Kind)+" "+c.Vulnerability+" "+c.Component.Name+" "+c.Component.Version)
+	}
+	assert.Equal(t, []string{
+		"introduced GO-2023-1988 golang.org/x/net 0.10.0",
+		"fixed GO-2023-1621 stdlib 1.20.1",
+		"fixed GO-2023-1737 golang.org/x/net 0.7.0",
+		"persisted GO-2023-1704 stdlib 1.20.4",
+	}, got, "findings are compared regardless of the versions of the components, once per component")
+
+	persisted := changes[3]
+	require.NotNil(t, persisted.Old)
+	require.NotNil(t, persisted.New)
+	assert.Equal(t, "1.20.1", persisted.Old.Component.Version)
+
+	assert.Empty(t, Diff(nil, nil))
+}
+
+func TestWriteDiff(t *testing.T) {
+	changes := Diff(
+		[]Finding{{Package: "curl", Component: Component{Name: "curl", Version: "8.1.0-r0", Ecosystem: "Wolfi"}, Vulnerability: "CVE-2023-38545", Severity: "CRITICAL"}},
+		[]Finding{{Package: "curl", Component: Component{Name: "curl", Version: "8.4.0-r0", Ecosystem: "Wolfi"}, Vulnerability: "CVE-2023-46218", Severity: "MEDIUM"}},
+	)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteDiff(&buf, changes, FormatTable))
+	assert.Equal(t, `CHANGE      PACKAGE  VULNERABILITY   SEVERITY  COMPONENT     OLD VERSION  NEW VERSION
+introduced  curl     CVE-2023-46218  MEDIUM    curl (Wolfi)  -            8.4.0-r0
+fixed       curl     CVE-2023-38545  CRITICAL  curl (Wolfi)  8.1.0-r0     -
+`, buf.String())
+
+	assert.Error(t, WriteDiff(&buf, changes, FormatSARIF))
+}
@@ -0,0 +1,207 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+// GrypeMatcher matches components against the vulnerability database of Grype, by running the grype executable on a
+// CycloneDX SBOM of the components, and takes the details of the vulnerabilities from its matches too. Grype isn't used
+// as a library: the grype module isn't a dependency of wolfictl, and matching only relies on its JSON output, which is
+// stable across the versions of the executable.
+type GrypeMatcher struct {
+	// Path is the grype executable, looked up in PATH if it's just a name.
+	Path string
+	// Distro is the distro the components of other ecosystems than the ones of the contents of apks are packages of,
+	// i.e. the apks themselves, e.g. "Wolfi".
+	Distro string
+
+	details map[grypeKey]Details
+}
+
+var _ VulnScanner = (*GrypeMatcher)(nil)
+
+type grypeKey struct {
+	id                       string
+	name, version, ecosystem string
+}
+
+func (m *GrypeMatcher) Match(ctx context.Context, components []Component) ([][]string, error) {
+	sbom, err := json.Marshal(cycloneDXOf(components, m.Distro))
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "wolfictl-grype-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	sbomPath := filepath.Join(dir, "sbom.cdx.json")
+	if err := os.WriteFile(sbomPath, sbom, 0o600); err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, m.Path, "sbom:"+sbomPath, "--output", "json", "--quiet") //nolint:gosec // running grype is the point
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running grype: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	vulns, details, err := parseGrypeMatches(&stdout, components)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the matches of grype: %w", err)
+	}
+	if m.details == nil {
+		m.details = make(map[grypeKey]Details)
+	}
+	for k, d := range details {
+		m.details[k] = d
+	}
+	return vulns, nil
+}
+
+// Details returns the severity and fix grype matched the vulnerability of the component with.
+func (m *GrypeMatcher) Details(_ context.Context, id string, component Component) (Details, error) {
+	d, ok := m.details[grypeKey{id, component.Name, component.Version, component.Ecosystem}]
+	if !ok {
+		return Details{Severity: advisory.SeverityUnknown}, nil
+	}
+	return d, nil
+}
+
+// cycloneDX is the part of a CycloneDX SBOM grype needs to match components, https://cyclonedx.org/docs/1.4/json/.
+type cycloneDX struct {
+	BOMFormat   string               `json:"bomFormat"`
+	SpecVersion string               `json:"specVersion"`
+	Version     int                  `json:"version"`
+	Components  []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXComponent struct {
+	BOMRef  string `json:"bom-ref"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	PURL    string `json:"purl,omitempty"`
+}
+
+// cycloneDXOf returns the CycloneDX SBOM of the components, which refers to each by its index, along with the distro
+// the apks are packages of, for grype to match them against its vulnerability data.
+func cycloneDXOf(components []Component, distro string) cycloneDX {
+	bom := cycloneDX{BOMFormat: "CycloneDX", SpecVersion: "1.4", Version: 1}
+	for i, c := range components {
+		bom.Components = append(bom.Components, cycloneDXComponent{
+			BOMRef:  fmt.Sprint(i),
+			Type:    "library",
+			Name:    c.Name,
+			Version: grypeVersion(c),
+			PURL:    purlOf(c),
+		})
+	}
+	if distro != "" {
+		bom.Components = append(bom.Components, cycloneDXComponent{
+			BOMRef: "distro",
+			Type:   "operating-system",
+			Name:   strings.ToLower(distro),
+		})
+	}
+	return bom
+}
+
+// grypeVersion returns the version of a component the way grype has it: Go modules and the standard library with
+// the prefixes of their versions, which components don't have.
+func grypeVersion(c Component) string {
+	switch {
+	case c.Ecosystem == EcosystemGo && c.Name == "stdlib":
+		return "go" + c.Version
+	case c.Ecosystem == EcosystemGo:
+		return "v" + c.Version
+	default:
+		return c.Version
+	}
+}
+
+// purlOf returns the package URL of a component, which grype matches it by, https://github.com/package-url/purl-spec.
+// Components of other ecosystems than the ones of the contents of apks are the apks themselves.
+func purlOf(c Component) string {
+	switch c.Ecosystem {
+	case EcosystemGo:
+		if c.Name == "stdlib" {
+			return "pkg:golang/stdlib@" + c.Version
+		}
+		return fmt.Sprintf("pkg:golang/%s@%s", c.Name, grypeVersion(c))
+	case EcosystemPyPI:
+		return fmt.Sprintf("pkg:pypi/%s@%s", strings.ToLower(c.Name), c.Version)
+	case EcosystemNPM:
+		return fmt.Sprintf("pkg:npm/%s@%s", strings.Replace(c.Name, "@", "%40", 1), c.Version)
+	default:
+		return fmt.Sprintf("pkg:apk/%s/%s@%s", strings.ToLower(c.Ecosystem), c.Name, c.Version)
+	}
+}
+
+// grypeOutput is the part of the JSON output of grype about its matches.
+type grypeOutput struct {
+	Matches []struct {
+		Vulnerability struct {
+			ID       string `json:"id"`
+			Severity string `json:"severity"`
+			Fix      struct {
+				Versions []string `json:"versions"`
+			} `json:"fix"`
+		} `json:"vulnerability"`
+		Artifact struct {
+			PURL string `json:"purl"`
+		} `json:"artifact"`
+	} `json:"matches"`
+}
+
+// parseGrypeMatches returns the IDs of the vulnerabilities grype matched each component with, and their details.
+func parseGrypeMatches(r io.Reader, components []Component) ([][]string, map[grypeKey]Details, error) {
+	var out grypeOutput
+	if err := json.NewDecoder(r).Decode(&out); err != nil {
+		return nil, nil, err
+	}
+
+	indexOf := make(map[string]int)
+	for i, c := range components {
+		indexOf[purlOf(c)] = i
+	}
+
+	vulns := make([][]string, len(components))
+	details := make(map[grypeKey]Details)
+	for _, m := range out.Matches {
+		// grype may add qualifiers, like the distro of apks
+		purl, _, _ := strings.Cut(m.Artifact.PURL, "?")
+		i, ok := indexOf[purl]
+		if !ok {
+			continue
+		}
+		c := components[i]
+		k := grypeKey{m.Vulnerability.ID, c.Name, c.Version, c.Ecosystem}
+		if _, ok := details[k]; ok {
+			continue
+		}
+		vulns[i] = append(vulns[i], m.Vulnerability.ID)
+
+		d := Details{Severity: advisory.SeverityLevel(m.Vulnerability.Severity)}
+		for _, fixed := range m.Vulnerability.Fix.Versions {
+			if dag.CompareVersions(fixed, c.Version) > 0 && (d.FixedVersion == "" || dag.CompareVersions(fixed, d.FixedVersion) < 0) {
+				d.FixedVersion = fixed
+			}
+		}
+		details[k] = d
+	}
+	return vulns, details, nil
+}
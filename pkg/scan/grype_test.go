@@ -0,0 +1,71 @@
+package scan
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCycloneDXOf(t *testing.T) {
+	bom := cycloneDXOf([]Component{
+		{Name: "curl", Version: "8.1.0-r0", Ecosystem: "Wolfi"},
+		{Name: "stdlib", Version: "1.20.4", Ecosystem: EcosystemGo},
+		{Name: "golang.org/x/net", Version: "0.7.0", Ecosystem: EcosystemGo},
+		{Name: "Requests", Version: "2.31.0", Ecosystem: EcosystemPyPI},
+		{Name: "@babel/core", Version: "7.22.5", Ecosystem: EcosystemNPM},
+	}, "Wolfi")
+
+	var got []string
+	for _, c := range bom.Components {
+		got = append(got, c.BOMRef+" "+c.Type+" "+c.Version+" "+c.PURL)
+	}
+	assert.Equal(t, []string{
+		"0 library 8.1.0-r0 pkg:apk/wolfi/curl@8.1.0-r0",
+		"1 library go1.20.4 pkg:golang/stdlib@1.20.4",
+		"2 library v0.7.0 pkg:golang/golang.org/x/net@v0.7.0",
+		"3 library 2.31.0 pkg:pypi/requests@2.31.0",
+		"4 library 7.22.5 pkg:npm/%40babel/core@7.22.5",
+		"distro operating-system  ",
+	}, got)
+	assert.Equal(t, "wolfi", bom.Components[5].Name)
+}
+
+func TestParseGrypeMatches(t *testing.T) {
+	components := []Component{
+		{Name: "curl", Version: "8.1.0-r0", Ecosystem: "Wolfi"},
+		{Name: "golang.org/x/net", Version: "0.7.0", Ecosystem: EcosystemGo},
+		{Name: "semver", Version: "7.5.4", Ecosystem: EcosystemNPM},
+	}
+	output := `{
+  "matches": [
+    {
+      "vulnerability": {"id": "CVE-2023-38545", "severity": "Critical", "fix": {"versions": ["8.4.0-r0"], "state": "fixed"}},
+      "artifact": {"name": "curl", "version": "8.1.0-r0", "purl": "pkg:apk/wolfi/curl@8.1.0-r0?distro=wolfi"}
+    },
+    {
+      "vulnerability": {"id": "GHSA-qppj-fm5r-hxr3", "severity": "Medium", "fix": {"versions": ["0.17.0", "0.7.0"], "state": "fixed"}},
+      "artifact": {"name": "golang.org/x/net", "version": "v0.7.0", "purl": "pkg:golang/golang.org/x/net@v0.7.0"}
+    },
+    {
+      "vulnerability": {"id": "GHSA-qppj-fm5r-hxr3", "severity": "Medium", "fix": {"versions": ["0.17.0"], "state": "fixed"}},
+      "artifact": {"name": "golang.org/x/net", "version": "v0.7.0", "purl": "pkg:golang/golang.org/x/net@v0.7.0"}
+    },
+    {
+      "vulnerability": {"id": "CVE-2000-0001", "severity": "Low", "fix": {"state": "not-fixed"}},
+      "artifact": {"name": "unknown", "version": "1.0.0", "purl": "pkg:generic/unknown@1.0.0"}
+    }
+  ]
+}`
+
+	vulns, details, err := parseGrypeMatches(strings.NewReader(output), components)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"CVE-2023-38545"}, {"GHSA-qppj-fm5r-hxr3"}, nil}, vulns, "matches are attributed by package URL, once each, and matches of other artifacts are ignored")
+
+	assert.Equal(t, Details{Severity: "CRITICAL", FixedVersion: "8.4.0-r0"}, details[grypeKey{"CVE-2023-38545", "curl", "8.1.0-r0", "Wolfi"}])
+	assert.Equal(t, Details{Severity: "MEDIUM", FixedVersion: "0.17.0"}, details[grypeKey{"GHSA-qppj-fm5r-hxr3", "golang.org/x/net", "0.7.0", EcosystemGo}], "fixes in the version or an earlier one are ignored")
+
+	_, _, err = parseGrypeMatches(strings.NewReader("not json"), components)
+	assert.Error(t, err)
+}
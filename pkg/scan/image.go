@@ -0,0 +1,243 @@
+package scan
+
+import (
+	"archive/tar"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+	"golang.org/x/exp/slices"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+// installedDB is the database of the packages apk installed, in the filesystem of an image.
+const installedDB = "lib/apk/db/installed"
+
+// InstalledPackage is a package installed in an image, and the files it installed.
+type InstalledPackage struct {
+	Name, Version, Origin, Arch string
+	Files                       []string
+}
+
+// ParseInstalled reads the packages of the database of installed packages of apk, which has a paragraph per package
+// with a line per field, like P:curl, and the files the package installed as a directory line (F:usr/bin) followed by
+// a line per file of it (R:curl).
+func ParseInstalled(r io.Reader) ([]InstalledPackage, error) {
+	var pkgs []InstalledPackage
+	var pkg *InstalledPackage
+	var dir string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if line == "" {
+			pkg = nil
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		if pkg == nil {
+			pkgs = append(pkgs, InstalledPackage{})
+			pkg = &pkgs[len(pkgs)-1]
+			dir = ""
+		}
+		switch key {
+		case "P":
+			pkg.Name = value
+		case "V":
+			pkg.Version = value
+		case "o":
+			pkg.Origin = value
+		case "A":
+			pkg.Arch = value
+		case "F":
+			dir = value
+		case "R":
+			pkg.Files = append(pkg.Files, path.Join(dir, value))
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return pkgs, nil
+}
+
+// CatalogImage returns the SBOMs of the packages installed in an image, with the components found in the files each
+// package installed. Components in files no package installed, e.g. copied into the image, aren't attributed to any
+// package and are left out.
+func CatalogImage(img v1.Image) ([]*SBOM, error) {
+	rc := mutate.Extract(img)
+	defer rc.Close()
+
+	var installed []InstalledPackage
+	var components []Component
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the filesystem of the image: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+		if name == installedDB {
+			if installed, err = ParseInstalled(tr); err != nil {
+				return nil, fmt.Errorf("unable to read %s: %w", installedDB, err)
+			}
+			continue
+		}
+		found, err := catalogFile(tr, hdr, name)
+		if err != nil {
+			return nil, err
+		}
+		components = append(components, found...)
+	}
+	if installed == nil {
+		return nil, fmt.Errorf("image has no %s, it has no apk packages", installedDB)
+	}
+
+	owners := make(map[string]*SBOM)
+	sboms := make([]*SBOM, 0, len(installed))
+	for _, pkg := range installed {
+		sbom := &SBOM{Name: pkg.Name, Version: pkg.Version, Origin: pkg.Origin, Arch: pkg.Arch}
+		if sbom.Origin == "" {
+			sbom.Origin = sbom.Name
+		}
+		for _, f := range pkg.Files {
+			owners[f] = sbom
+		}
+		sboms = append(sboms, sbom)
+	}
+	for _, c := range components {
+		if owner, ok := owners[c.Path]; ok {
+			owner.Components = append(owner.Components, c)
+		}
+	}
+	return sboms, nil
+}
+
+// OriginReport is the vulnerabilities found in the packages of an image built from the same origin, i.e. the build
+// configuration that fixing them changes.
+type OriginReport struct {
+	Origin string `json:"origin"`
+	// Packages are the packages of the origin the vulnerabilities were found in, as name-version.
+	Packages []string `json:"packages"`
+	// Published is the latest version of the packages in the repository, if it's newer than the installed ones.
+	Published string `json:"published,omitempty"`
+
+	Findings []ImageFinding `json:"findings"`
+}
+
+// ImageFinding is a vulnerability of a package installed in an image.
+type ImageFinding struct {
+	Finding
+	// FixPublished is the version of the package that fixes the vulnerability, if it's published in the repository
+	// but not installed in the image.
+	FixPublished string `json:"fixPublished,omitempty"`
+}
+
+// GroupByOrigin returns the findings grouped by origin, sorted by origin. With the index of the repository the image's
+// packages are installed from, findings of the packages themselves, whose fixed versions are package versions, are
+// annotated with the version that fixes them if it's published, i.e. if all it takes to fix them is rebuilding the
+// image.
+func GroupByOrigin(findings []Finding, published *repository.ApkIndex) []OriginReport {
+	byOrigin := make(map[string]*OriginReport)
+	var origins []string
+	for _, f := range findings {
+		r, ok := byOrigin[f.Origin]
+		if !ok {
+			r = &OriginReport{Origin: f.Origin}
+			byOrigin[f.Origin] = r
+			origins = append(origins, f.Origin)
+		}
+		pkg := f.Package + "-" + f.Version
+		if !slices.Contains(r.Packages, pkg) {
+			r.Packages = append(r.Packages, pkg)
+		}
+
+		imageFinding := ImageFinding{Finding: f}
+		if published != nil {
+			latest := latestPublished(published, f.Package)
+			if latest != "" && dag.CompareVersions(latest, f.Version) > 0 && (r.Published == "" || dag.CompareVersions(latest, r.Published) > 0) {
+				r.Published = latest
+			}
+			if f.Component.Path == "" && f.Component.Name == f.Package && f.FixedVersion != "" &&
+				latest != "" && dag.CompareVersions(latest, f.FixedVersion) >= 0 {
+				imageFinding.FixPublished = latest
+			}
+		}
+		r.Findings = append(r.Findings, imageFinding)
+	}
+
+	sort.Strings(origins)
+	reports := make([]OriginReport, 0, len(origins))
+	for _, origin := range origins {
+		r := byOrigin[origin]
+		sort.Strings(r.Packages)
+		reports = append(reports, *r)
+	}
+	return reports
+}
+
+// latestPublished returns the latest version of the package in the index, or "" if it isn't in it.
+func latestPublished(idx *repository.ApkIndex, name string) string {
+	latest := ""
+	for _, p := range idx.Packages {
+		if p.Name == name && (latest == "" || dag.CompareVersions(p.Version, latest) > 0) {
+			latest = p.Version
+		}
+	}
+	return latest
+}
+
+// WriteImageReport encodes the reports to w, as a table with a section per origin, or as JSON.
+func WriteImageReport(w io.Writer, reports []OriginReport, format Format) error {
+	switch format {
+	case FormatTable:
+		for i, r := range reports {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			title := fmt.Sprintf("%s (%s)", r.Origin, strings.Join(r.Packages, ", "))
+			if r.Published != "" {
+				title += fmt.Sprintf(", %s published", r.Published)
+			}
+			fmt.Fprintln(w, title)
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "  VULNERABILITY\tSEVERITY\tPACKAGE\tCOMPONENT\tFIXED IN\tFIX PUBLISHED")
+			for _, f := range r.Findings {
+				fmt.Fprintf(tw, "  %s\t%s\t%s\t%s %s (%s)\t%s\t%s\n", f.Vulnerability, orDash(f.Severity), f.Package,
+					f.Component.Name, f.Component.Version, f.Component.Ecosystem, orDash(f.FixedVersion), orDash(f.FixPublished))
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatJSON:
+		if reports == nil {
+			reports = []OriginReport{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reports)
+	default:
+		return fmt.Errorf("unknown image report format %q", format)
+	}
+}
@@ -0,0 +1,151 @@
+package scan
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+)
+
+const installed = `C:Q1abc=
+P:py3-requests
+V:2.30.0-r1
+A:x86_64
+o:requests
+F:usr/lib/python3.11/site-packages/requests-2.30.0.dist-info
+R:METADATA
+R:RECORD
+
+P:wolfi-baselayout
+V:20230201-r3
+A:x86_64
+F:etc
+R:os-release
+`
+
+func TestParseInstalled(t *testing.T) {
+	pkgs, err := ParseInstalled(strings.NewReader(installed))
+	require.NoError(t, err)
+	assert.Equal(t, []InstalledPackage{
+		{
+			Name: "py3-requests", Version: "2.30.0-r1", Origin: "requests", Arch: "x86_64",
+			Files: []string{
+				"usr/lib/python3.11/site-packages/requests-2.30.0.dist-info/METADATA",
+				"usr/lib/python3.11/site-packages/requests-2.30.0.dist-info/RECORD",
+			},
+		},
+		{Name: "wolfi-baselayout", Version: "20230201-r3", Arch: "x86_64", Files: []string{"etc/os-release"}},
+	}, pkgs)
+}
+
+func TestCatalogImage(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range []file{
+		{"lib/apk/db/installed", 0o644, installed},
+		{"usr/lib/python3.11/site-packages/requests-2.30.0.dist-info/METADATA", 0o644, "Name: requests\nVersion: 2.30.0\n\n"},
+		{"app/node_modules/semver/package.json", 0o644, `{"name": "semver", "version": "7.5.1"}`},
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: f.name, Typeflag: tar.TypeReg, Mode: f.mode, Size: int64(len(f.content))}))
+		_, err := tw.Write([]byte(f.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	})
+	require.NoError(t, err)
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	require.NoError(t, err)
+
+	sboms, err := CatalogImage(img)
+	require.NoError(t, err)
+	assert.Equal(t, []*SBOM{
+		{
+			Name: "py3-requests", Version: "2.30.0-r1", Origin: "requests", Arch: "x86_64",
+			Components: []Component{{Name: "requests", Version: "2.30.0", Ecosystem: EcosystemPyPI, Path: "usr/lib/python3.11/site-packages/requests-2.30.0.dist-info/METADATA"}},
+		},
+		{Name: "wolfi-baselayout", Version: "20230201-r3", Origin: "wolfi-baselayout", Arch: "x86_64"},
+	}, sboms, "components in files no package installed are left out")
+
+	_, err = CatalogImage(empty.Image)
+	assert.Error(t, err, "images without apk packages can't be scanned")
+}
+
+func TestGroupByOrigin(t *testing.T) {
+	self := func(pkg, version, vulnerability, fixed string) Finding {
+		return Finding{
+			Package: pkg, Version: version, Origin: "curl", Vulnerability: vulnerability, FixedVersion: fixed,
+			Component: Component{Name: pkg, Version: version, Ecosystem: "Wolfi"},
+		}
+	}
+	findings := []Finding{
+		self("libcurl4", "8.1.0-r0", "CVE-2023-38545", "8.4.0-r0"),
+		self("curl", "8.1.0-r0", "CVE-2023-38545", "8.4.0-r0"),
+		self("curl", "8.1.0-r0", "CVE-2023-46218", "8.5.0-r0"),
+		{
+			Package: "py3-requests", Version: "2.30.0-r1", Origin: "requests", Vulnerability: "GHSA-j8r2-6x86-q33q", FixedVersion: "2.31.0",
+			Component: Component{Name: "requests", Version: "2.30.0", Ecosystem: EcosystemPyPI, Path: "usr/lib/python3.11/site-packages/requests-2.30.0.dist-info/METADATA"},
+		},
+	}
+	published := &repository.ApkIndex{Packages: []*repository.Package{
+		{Name: "curl", Version: "8.1.0-r0"},
+		{Name: "curl", Version: "8.4.0-r0"},
+		{Name: "libcurl4", Version: "8.4.0-r0"},
+		{Name: "py3-requests", Version: "2.31.0-r0"},
+	}}
+
+	reports := GroupByOrigin(findings, published)
+	require.Len(t, reports, 2)
+
+	curl := reports[0]
+	assert.Equal(t, "curl", curl.Origin)
+	assert.Equal(t, []string{"curl-8.1.0-r0", "libcurl4-8.1.0-r0"}, curl.Packages)
+	assert.Equal(t, "8.4.0-r0", curl.Published)
+	var fixes []string
+	for _, f := range curl.Findings {
+		fixes = append(fixes, f.Package+" "+f.Vulnerability+" "+f.FixPublished)
+	}
+	assert.Equal(t, []string{
+		"libcurl4 CVE-2023-38545 8.4.0-r0",
+		"curl CVE-2023-38545 8.4.0-r0",
+		"curl CVE-2023-46218 ",
+	}, fixes, "fixes of the packages themselves are published if a version at least the fixed one is")
+
+	requests := reports[1]
+	assert.Equal(t, "2.31.0-r0", requests.Published)
+	assert.Empty(t, requests.Findings[0].FixPublished, "fixed versions of components aren't package versions")
+
+	assert.Empty(t, GroupByOrigin(findings, nil)[0].Findings[0].FixPublished)
+}
+
+func TestWriteImageReport(t *testing.T) {
+	reports := []OriginReport{{
+		Origin:    "curl",
+		Packages:  []string{"curl-8.1.0-r0"},
+		Published: "8.4.0-r0",
+		Findings: []ImageFinding{{
+			Finding: Finding{
+				Package: "curl", Version: "8.1.0-r0", Origin: "curl", Vulnerability: "CVE-2023-38545", Severity: "CRITICAL", FixedVersion: "8.4.0-r0",
+				Component: Component{Name: "curl", Version: "8.1.0-r0", Ecosystem: "Wolfi"},
+			},
+			FixPublished: "8.4.0-r0",
+		}},
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteImageReport(&buf, reports, FormatTable))
+	assert.Equal(t, `curl (curl-8.1.0-r0), 8.4.0-r0 published
+  VULNERABILITY   SEVERITY  PACKAGE  COMPONENT              FIXED IN  FIX PUBLISHED
+  CVE-2023-38545  CRITICAL  curl     curl 8.1.0-r0 (Wolfi)  8.4.0-r0  8.4.0-r0
+`, buf.String())
+}
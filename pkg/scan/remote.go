@@ -0,0 +1,98 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"gitlab.alpinelinux.org/alpine/go/repository"
+
+	"github.com/wolfi-dev/wolfictl/pkg/apk"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+// ResolveRemote returns the packages of the index to scan for a reference like curl or curl@8.1.0-r0: the package, in
+// its latest version unless the reference has one, and with subpackages the other packages built from the same origin
+// in the same version.
+func ResolveRemote(idx *repository.ApkIndex, ref string, subpackages bool) ([]*repository.Package, error) {
+	name, version, _ := strings.Cut(ref, "@")
+
+	var pkg *repository.Package
+	for _, p := range idx.Packages {
+		if p.Name != name || (version != "" && p.Version != version) {
+			continue
+		}
+		if pkg == nil || dag.CompareVersions(p.Version, pkg.Version) > 0 {
+			pkg = p
+		}
+	}
+	if pkg == nil {
+		if version != "" {
+			return nil, fmt.Errorf("package %s is not in the index in version %s", name, version)
+		}
+		return nil, fmt.Errorf("package %s is not in the index", name)
+	}
+
+	pkgs := []*repository.Package{pkg}
+	if subpackages {
+		origin := originOf(pkg)
+		for _, p := range idx.Packages {
+			if p != pkg && originOf(p) == origin && p.Version == pkg.Version {
+				pkgs = append(pkgs, p)
+			}
+		}
+	}
+	return pkgs, nil
+}
+
+func originOf(pkg *repository.Package) string {
+	if pkg.Origin != "" {
+		return pkg.Origin
+	}
+	return pkg.Name
+}
+
+// RemoteOptions configures FetchRemote.
+type RemoteOptions struct {
+	Client *http.Client
+	// RepositoryURL is the URL of the repository the packages are published in, like https://packages.wolfi.dev/os.
+	RepositoryURL string
+	Arch          string
+	// Keys are the keys a package has to be signed with one of, by their file names, like wolfi-signing.rsa.pub.
+	Keys map[string]*rsa.PublicKey
+}
+
+// FetchRemote downloads a package of the index of the repository, checks that it's signed with one of the keys and
+// that it's the one the index lists, and returns its SBOM.
+func FetchRemote(ctx context.Context, pkg *repository.Package, opts RemoteOptions) (*SBOM, error) {
+	u := fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(opts.RepositoryURL, "/"), opts.Arch, pkg.Filename())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := opts.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch %s: got response status %d", u, resp.StatusCode)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch %s: %w", u, err)
+	}
+
+	if err := apk.VerifySignature(b, opts.Keys, pkg.Checksum); err != nil {
+		return nil, fmt.Errorf("unable to verify %s: %w", u, err)
+	}
+	sbom, err := Catalog(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("unable to catalog %s: %w", u, err)
+	}
+	return sbom, nil
+}
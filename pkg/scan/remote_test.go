@@ -0,0 +1,131 @@
+package scan
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" //nolint:gosec // apks are signed and indexed by the SHA-1 of their control segment
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+)
+
+func TestResolveRemote(t *testing.T) {
+	idx := &repository.ApkIndex{Packages: []*repository.Package{
+		{Name: "curl", Version: "8.1.0-r0", Origin: "curl"},
+		{Name: "curl", Version: "8.4.0-r0", Origin: "curl"},
+		{Name: "curl", Version: "8.10.0-r0", Origin: "curl"},
+		{Name: "libcurl4", Version: "8.4.0-r0", Origin: "curl"},
+		{Name: "libcurl4", Version: "8.10.0-r0", Origin: "curl"},
+		{Name: "curl-dev", Version: "8.10.0-r0", Origin: "curl"},
+		{Name: "zlib", Version: "1.3-r0"},
+	}}
+
+	names := func(pkgs []*repository.Package) []string {
+		var got []string
+		for _, p := range pkgs {
+			got = append(got, p.Name+"-"+p.Version)
+		}
+		return got
+	}
+
+	pkgs, err := ResolveRemote(idx, "curl", false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"curl-8.10.0-r0"}, names(pkgs), "the latest version unless one is given")
+
+	pkgs, err = ResolveRemote(idx, "curl@8.4.0-r0", true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"curl-8.4.0-r0", "libcurl4-8.4.0-r0"}, names(pkgs))
+
+	pkgs, err = ResolveRemote(idx, "libcurl4", true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"libcurl4-8.10.0-r0", "curl-8.10.0-r0", "curl-dev-8.10.0-r0"}, names(pkgs))
+
+	pkgs, err = ResolveRemote(idx, "zlib", true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"zlib-1.3-r0"}, names(pkgs), "packages without an origin are their own")
+
+	_, err = ResolveRemote(idx, "curl@7.0.0-r0", false)
+	assert.ErrorContains(t, err, "not in the index in version 7.0.0-r0")
+	_, err = ResolveRemote(idx, "wget", false)
+	assert.ErrorContains(t, err, "not in the index")
+}
+
+// segment returns a gzip stream of a tar archive of the file, ending the archive only if it's the last segment of an
+// apk.
+func segment(t *testing.T, name, content string, last bool) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))}))
+	_, err := tw.Write([]byte(content))
+	require.NoError(t, err)
+	if last {
+		require.NoError(t, tw.Close())
+	} else {
+		require.NoError(t, tw.Flush())
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestFetchRemote(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	data := segment(t, "usr/lib/python3.11/site-packages/requests-2.30.0.dist-info/METADATA", "Name: requests\nVersion: 2.30.0\n\n", true)
+	dataHash := sha256.Sum256(data)
+	control := segment(t, ".PKGINFO", pkginfo+"datahash = "+hex.EncodeToString(dataHash[:])+"\n", false)
+	checksum := sha1.Sum(control) //nolint:gosec // see the import
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA1, checksum[:])
+	require.NoError(t, err)
+	var signed []byte
+	signed = append(signed, segment(t, ".SIGN.RSA.wolfi-signing.rsa.pub", string(sig), false)...)
+	signed = append(signed, control...)
+	signed = append(signed, data...)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/os/x86_64/py3-requests-2.30.0-r1.apk" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(signed)
+	}))
+	defer ts.Close()
+
+	pkg := &repository.Package{Name: "py3-requests", Version: "2.30.0-r1", Origin: "requests", Checksum: checksum[:]}
+	opts := RemoteOptions{
+		Client:        ts.Client(),
+		RepositoryURL: ts.URL + "/os",
+		Arch:          "x86_64",
+		Keys:          map[string]*rsa.PublicKey{"wolfi-signing.rsa.pub": &key.PublicKey},
+	}
+
+	sbom, err := FetchRemote(context.Background(), pkg, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "requests", sbom.Origin)
+	assert.Equal(t, []Component{{Name: "requests", Version: "2.30.0", Ecosystem: EcosystemPyPI, Path: "usr/lib/python3.11/site-packages/requests-2.30.0.dist-info/METADATA"}}, sbom.Components)
+
+	// the handler serves the apk with another data segment from now on
+	signed = append(signed[:len(signed)-len(data):len(signed)-len(data)],
+		segment(t, "usr/lib/python3.11/site-packages/requests-2.31.0.dist-info/METADATA", "Name: requests\nVersion: 2.31.0\n\n", true)...)
+	_, err = FetchRemote(context.Background(), pkg, opts)
+	assert.ErrorContains(t, err, "data of the apk", "content the signature doesn't cover isn't scanned")
+
+	opts.Keys = map[string]*rsa.PublicKey{"other.rsa.pub": &key.PublicKey}
+	_, err = FetchRemote(context.Background(), pkg, opts)
+	assert.ErrorContains(t, err, "isn't a trusted key", "packages not signed with a trusted key aren't scanned")
+
+	_, err = FetchRemote(context.Background(), &repository.Package{Name: "curl", Version: "8.1.0-r0"}, opts)
+	assert.ErrorContains(t, err, "got response status 404")
+}
@@ -0,0 +1,49 @@
+package scan
+
+import (
+	"fmt"
+
+	"github.com/wolfi-dev/wolfictl/pkg/sarif"
+)
+
+// newSARIF returns the SARIF log of the findings, with a rule per vulnerability and a result per finding. Results are
+// located in the build configuration of the origin, the file of the distro repository that fixing them changes, and
+// the ones the advisories settle are suppressed.
+func newSARIF(findings []Finding) *sarif.Log {
+	log := sarif.New()
+	for _, f := range findings {
+		rule := sarif.Rule{
+			ID:                   f.Vulnerability,
+			ShortDescription:     sarif.Message{Text: f.Vulnerability},
+			HelpURI:              "https://osv.dev/vulnerability/" + f.Vulnerability,
+			DefaultConfiguration: sarif.Configuration{Level: sarif.LevelWarning},
+		}
+		if score, ok := sarif.SecuritySeverity(f.Severity); ok {
+			rule.Properties = map[string]string{"security-severity": score}
+		}
+		log.AddRule(rule)
+
+		message := fmt.Sprintf("%s-%s has %s in %s %s (%s)", f.Package, f.Version, f.Vulnerability, f.Component.Name, f.Component.Version, f.Component.Ecosystem)
+		if f.FixedVersion != "" {
+			message += ", fixed in " + f.FixedVersion
+		}
+		result := log.Add(f.Vulnerability, message, f.Origin+".yaml", 0)
+		result.Level = sarif.SeverityLevel(f.Severity)
+		result.PartialFingerprints = map[string]string{"wolfictlFindingId/v1": f.ID}
+		result.Properties = map[string]string{
+			"package": f.Package,
+			"version": f.Version,
+			"origin":  f.Origin,
+		}
+		if f.Component.Path != "" {
+			result.Properties["path"] = f.Component.Path
+		}
+		if f.Advisory != "" {
+			result.Properties["advisory"] = string(f.Advisory)
+		}
+		if f.Triaged != "" {
+			result.Suppressions = []sarif.Suppression{{Kind: "external", Justification: string(f.Triaged)}}
+		}
+	}
+	return log
+}
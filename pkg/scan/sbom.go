@@ -0,0 +1,226 @@
+// Package scan finds the vulnerabilities of built apks: it catalogs the software each apk contains, and matches it
+// against vulnerability data, attributing what it finds to the package and the origin package it's built from.
+package scan
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"debug/buildinfo"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"path"
+	"strings"
+)
+
+// Ecosystems of the components of packages, named like OSV names them.
+const (
+	EcosystemGo   = "Go"
+	EcosystemPyPI = "PyPI"
+	EcosystemNPM  = "npm"
+)
+
+// Component is a piece of software an apk contains, e.g. a Go module compiled into one of its binaries.
+type Component struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Ecosystem string `json:"ecosystem"`
+	// Path is the file of the apk the component was found in.
+	Path string `json:"path"`
+}
+
+// SBOM is the software an apk contains.
+type SBOM struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Origin is the package the apk was built from, the same as the name unless it's a subpackage.
+	Origin string `json:"origin"`
+	Arch   string `json:"arch"`
+
+	Components []Component `json:"components"`
+}
+
+// maxBinarySize is the size of the largest file read for the Go build info it may have, to bound memory usage.
+const maxBinarySize = 512 << 20
+
+// Catalog returns the SBOM of an apk: the package, from its .PKGINFO, and the components found in its files: the Go
+// modules of Go binaries, Python distributions with .dist-info metadata and npm packages in node_modules.
+func Catalog(r io.Reader) (*SBOM, error) {
+	// the segments of an apk are gzip streams of one tar archive split up, which read as one
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read apk: %w", err)
+	}
+	defer zr.Close()
+
+	sbom := &SBOM{}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read apk: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := strings.TrimPrefix(hdr.Name, "./")
+		if name == ".PKGINFO" {
+			if err := readPKGINFO(tr, sbom); err != nil {
+				return nil, fmt.Errorf("unable to read .PKGINFO: %w", err)
+			}
+			continue
+		}
+		components, err := catalogFile(tr, hdr, name)
+		if err != nil {
+			return nil, err
+		}
+		sbom.Components = append(sbom.Components, components...)
+	}
+
+	if sbom.Name == "" {
+		return nil, errors.New("apk has no .PKGINFO")
+	}
+	if sbom.Origin == "" {
+		sbom.Origin = sbom.Name
+	}
+	return sbom, nil
+}
+
+// catalogFile returns the components found in a file.
+func catalogFile(r io.Reader, hdr *tar.Header, name string) ([]Component, error) {
+	var components []Component
+	var err error
+	switch {
+	case isDistInfoMetadata(name):
+		components, err = pythonComponents(r, name)
+	case isNodeModule(name):
+		components, err = npmComponents(r, name)
+	case hdr.Mode&0o111 != 0 && hdr.Size <= maxBinarySize:
+		components, err = goComponents(r, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to catalog %s: %w", name, err)
+	}
+	return components, nil
+}
+
+func readPKGINFO(r io.Reader, sbom *SBOM) error {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		key, value, ok := strings.Cut(s.Text(), " = ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "pkgname":
+			sbom.Name = value
+		case "pkgver":
+			sbom.Version = value
+		case "origin":
+			sbom.Origin = value
+		case "arch":
+			sbom.Arch = value
+		}
+	}
+	return s.Err()
+}
+
+// goComponents returns the Go modules of a Go binary, and the standard library it was built with, or none if the file
+// isn't a Go binary.
+func goComponents(r io.Reader, name string) ([]Component, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(4); err != nil || !bytes.Equal(magic, []byte("\x7fELF")) {
+		// not a binary, e.g. a script
+		return nil, nil //nolint:nilerr
+	}
+	b, err := io.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+	info, err := buildinfo.Read(bytes.NewReader(b))
+	if err != nil {
+		// not a Go binary
+		return nil, nil //nolint:nilerr
+	}
+
+	components := []Component{{
+		Name:      "stdlib",
+		Version:   strings.TrimPrefix(info.GoVersion, "go"),
+		Ecosystem: EcosystemGo,
+		Path:      name,
+	}}
+	if info.Main.Path != "" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		components = append(components, goModule(info.Main.Path, info.Main.Version, name))
+	}
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		if dep.Version == "" {
+			// replaced by a local directory
+			continue
+		}
+		components = append(components, goModule(dep.Path, dep.Version, name))
+	}
+	return components, nil
+}
+
+func goModule(modulePath, version, name string) Component {
+	return Component{Name: modulePath, Version: strings.TrimPrefix(version, "v"), Ecosystem: EcosystemGo, Path: name}
+}
+
+// isDistInfoMetadata reports whether the file is the metadata of an installed Python distribution, like
+// usr/lib/python3.11/site-packages/requests-2.31.0.dist-info/METADATA.
+func isDistInfoMetadata(name string) bool {
+	return path.Base(name) == "METADATA" && strings.HasSuffix(path.Dir(name), ".dist-info")
+}
+
+// isNodeModule reports whether the file is the package.json of an npm package in node_modules, like
+// node_modules/semver/package.json or node_modules/@babel/core/package.json.
+func isNodeModule(name string) bool {
+	if path.Base(name) != "package.json" {
+		return false
+	}
+	dir := path.Dir(path.Dir(name))
+	if strings.HasPrefix(path.Base(dir), "@") {
+		// a scoped package
+		dir = path.Dir(dir)
+	}
+	return path.Base(dir) == "node_modules"
+}
+
+// pythonComponents returns the Python distribution of a .dist-info METADATA file.
+func pythonComponents(r io.Reader, name string) ([]Component, error) {
+	header, err := textproto.NewReader(bufio.NewReader(r)).ReadMIMEHeader()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if header.Get("Name") == "" || header.Get("Version") == "" {
+		return nil, nil
+	}
+	return []Component{{Name: header.Get("Name"), Version: header.Get("Version"), Ecosystem: EcosystemPyPI, Path: name}}, nil
+}
+
+// npmComponents returns the npm package of a package.json in node_modules.
+func npmComponents(r io.Reader, name string) ([]Component, error) {
+	var pkg struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(r).Decode(&pkg); err != nil {
+		// not every package.json is valid JSON, e.g. test fixtures
+		return nil, nil //nolint:nilerr
+	}
+	if pkg.Name == "" || pkg.Version == "" {
+		return nil, nil
+	}
+	return []Component{{Name: pkg.Name, Version: pkg.Version, Ecosystem: EcosystemNPM, Path: name}}, nil
+}
@@ -0,0 +1,369 @@
+package scan
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/mod/semver"
+
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln/osv"
+)
+
+// Matcher finds the vulnerabilities of components.
+type Matcher interface {
+	// Match returns the IDs of the vulnerabilities of each component, in the order of the components.
+	Match(ctx context.Context, components []Component) ([][]string, error)
+}
+
+// Details are what's known about a vulnerability of a component besides its ID.
+type Details struct {
+	// Severity is one of advisory.Severities.
+	Severity string
+	// FixedVersion is the earliest version of the component after its version that fixes the vulnerability, or "" if
+	// there's none yet.
+	FixedVersion string
+}
+
+// Detailer looks up the details of the vulnerabilities of components.
+type Detailer interface {
+	Details(ctx context.Context, id string, component Component) (Details, error)
+}
+
+// VulnScanner is a source of vulnerability data a scan can be run against, e.g. OSV.dev or Grype: it finds the
+// vulnerabilities of components and looks up their details.
+type VulnScanner interface {
+	Matcher
+	Detailer
+}
+
+// OSVMatcher matches components against the vulnerability data of OSV.dev, and looks up the details of the
+// vulnerabilities it finds there too.
+type OSVMatcher struct {
+	// Client queries the OSV.dev API, unless there's a DB.
+	Client *osv.Client
+	// DB is a local snapshot of the vulnerability data to match against instead of querying the API.
+	DB *osv.DB
+
+	records map[string]*osv.Vulnerability
+}
+
+var _ VulnScanner = (*OSVMatcher)(nil)
+
+func (m *OSVMatcher) Match(ctx context.Context, components []Component) ([][]string, error) {
+	if m.DB != nil {
+		vulns := make([][]string, 0, len(components))
+		for _, c := range components {
+			pkg := osv.Package{Name: c.Name, Ecosystem: c.Ecosystem}
+			var ids []string
+			for _, v := range m.DB.Vulnerabilities(pkg) {
+				if v.Affects(pkg, c.Version, versionOrder(c.Ecosystem)) {
+					ids = append(ids, v.ID)
+				}
+			}
+			vulns = append(vulns, ids)
+		}
+		return vulns, nil
+	}
+
+	queries := make([]osv.Query, 0, len(components))
+	for _, c := range components {
+		queries = append(queries, osv.Query{
+			Package: osv.Package{Name: c.Name, Ecosystem: c.Ecosystem},
+			Version: c.Version,
+		})
+	}
+	return m.Client.QueryBatch(ctx, queries)
+}
+
+// versionOrder returns the comparator ordering the versions of OSV ECOSYSTEM ranges of an ecosystem, or nil when
+// they can't be ordered here, as PEP 440 versions of PyPI can't, so only the versions records list match them.
+// Components of other ecosystems than the ones of the contents of apks are the apks themselves.
+func versionOrder(ecosystem string) func(a, b string) int {
+	switch ecosystem {
+	case EcosystemGo, EcosystemNPM:
+		return func(a, b string) int { return semver.Compare("v"+a, "v"+b) }
+	case EcosystemPyPI:
+		return nil
+	default:
+		return dag.CompareVersions
+	}
+}
+
+// Details returns the severity GitHub rates the vulnerability with, of its GHSA if it has another ID, and the version
+// of the component that fixes it.
+func (m *OSVMatcher) Details(ctx context.Context, id string, component Component) (Details, error) {
+	d := Details{Severity: advisory.SeverityUnknown}
+	v, err := m.get(ctx, id)
+	if err != nil || v == nil {
+		return d, err
+	}
+
+	severity := v.DatabaseSpecific.Severity
+	for _, alias := range v.Aliases {
+		if severity != "" {
+			break
+		}
+		if !strings.HasPrefix(alias, "GHSA-") {
+			continue
+		}
+		ghsa, err := m.get(ctx, alias)
+		if err != nil {
+			return d, err
+		}
+		if ghsa != nil {
+			severity = ghsa.DatabaseSpecific.Severity
+		}
+	}
+	d.Severity = advisory.SeverityLevel(severity)
+
+	for _, fixed := range v.FixedVersions(osv.Package{Name: component.Name, Ecosystem: component.Ecosystem}) {
+		if dag.CompareVersions(fixed, component.Version) <= 0 {
+			continue
+		}
+		if d.FixedVersion == "" || dag.CompareVersions(fixed, d.FixedVersion) < 0 {
+			d.FixedVersion = fixed
+		}
+	}
+	return d, nil
+}
+
+// get returns the OSV record of a vulnerability, looking each one up once.
+func (m *OSVMatcher) get(ctx context.Context, id string) (*osv.Vulnerability, error) {
+	if v, ok := m.records[id]; ok {
+		return v, nil
+	}
+	var v *osv.Vulnerability
+	var err error
+	if m.DB != nil {
+		v, err = m.DB.Get(ctx, id)
+	} else {
+		v, err = m.Client.Get(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.records == nil {
+		m.records = make(map[string]*osv.Vulnerability)
+	}
+	m.records[id] = v
+	return v, nil
+}
+
+// Options configures Scan.
+type Options struct {
+	Matcher Matcher
+	// Detailer looks up the severities and fixes of the vulnerabilities found, or nil to not look them up.
+	Detailer Detailer
+
+	// Ecosystem is the ecosystem of the distro the apks are packages of, e.g. "Wolfi", to match the packages
+	// themselves against the vulnerability data of the distro. If empty, only their components are matched.
+	Ecosystem string
+}
+
+// Finding is a vulnerability of a component of an apk.
+type Finding struct {
+	// ID identifies the finding across scans of versions of the package, as long as the component is in the same file.
+	ID string `json:"id"`
+
+	Package string `json:"package"`
+	Version string `json:"version"`
+	// Origin is the package the apk was built from, whose advisories the vulnerability belongs in.
+	Origin string `json:"origin"`
+
+	Component     Component `json:"component"`
+	Vulnerability string    `json:"vulnerability"`
+	Severity      string    `json:"severity,omitempty"`
+	// FixedVersion is the version of the component that fixes the vulnerability, if there's one.
+	FixedVersion string `json:"fixedVersion,omitempty"`
+
+	// Advisory is the event of the latest entry of the advisory of the origin of the vulnerability, if there's one.
+	Advisory advisory.Event `json:"advisory,omitempty"`
+	// Triaged is the event of the advisory of the origin that settles the vulnerability for the version, if there's
+	// one, e.g. a false positive.
+	Triaged advisory.Event `json:"triaged,omitempty"`
+}
+
+// Scan matches the apks and their components against vulnerability data, and returns the vulnerabilities found,
+// sorted by origin, package and vulnerability.
+func Scan(ctx context.Context, sboms []*SBOM, opts Options) ([]Finding, error) {
+	// every component is matched once, however many apks contain it
+	var components []Component
+	indexOf := make(map[Component]int)
+	add := func(c Component) {
+		key := Component{Name: c.Name, Version: c.Version, Ecosystem: c.Ecosystem}
+		if _, ok := indexOf[key]; !ok {
+			indexOf[key] = len(components)
+			components = append(components, key)
+		}
+	}
+	for _, sbom := range sboms {
+		for _, c := range componentsOf(sbom, opts.Ecosystem) {
+			add(c)
+		}
+	}
+	if len(components) == 0 {
+		return nil, nil
+	}
+
+	vulns, err := opts.Matcher.Match(ctx, components)
+	if err != nil {
+		return nil, fmt.Errorf("unable to match components against vulnerability data: %w", err)
+	}
+
+	var findings []Finding
+	for _, sbom := range sboms {
+		for _, c := range componentsOf(sbom, opts.Ecosystem) {
+			i := indexOf[Component{Name: c.Name, Version: c.Version, Ecosystem: c.Ecosystem}]
+			for _, id := range vulns[i] {
+				f := Finding{
+					Package:       sbom.Name,
+					Version:       sbom.Version,
+					Origin:        sbom.Origin,
+					Component:     c,
+					Vulnerability: id,
+				}
+				f.ID = findingID(f)
+				if opts.Detailer != nil {
+					d, err := opts.Detailer.Details(ctx, id, c)
+					if err != nil {
+						return nil, fmt.Errorf("unable to look up the details of %s: %w", id, err)
+					}
+					f.Severity, f.FixedVersion = d.Severity, d.FixedVersion
+				}
+				findings = append(findings, f)
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Origin != b.Origin {
+			return a.Origin < b.Origin
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Vulnerability < b.Vulnerability
+	})
+	return findings, nil
+}
+
+// findingID returns the ID of a finding, a hash of what identifies it but the versions of the package and the
+// component, which change from scan to scan.
+func findingID(f Finding) string {
+	h := sha256.Sum256([]byte(strings.Join([]string{f.Origin, f.Package, f.Component.Ecosystem, f.Component.Name, f.Component.Path, f.Vulnerability}, "\x00")))
+	return hex.EncodeToString(h[:8])
+}
+
+// Triage annotates the findings with the events of the advisories of their origins, and the events that settle them:
+// false positives, fixes that aren't planned, and fixes in the version or an earlier one. The advisories are found under the
+// IDs of the vulnerabilities or their aliases. The triaged findings are dropped unless showTriaged is set, leaving the
+// vulnerabilities that still need triage.
+func Triage(findings []Finding, advisoryCfgs *configs.Index[advisoryconfigs.Document], aliases vuln.Aliases, showTriaged bool) []Finding {
+	var triaged []Finding
+	for _, f := range findings {
+		if docs := advisoryCfgs.Select().WhereName(f.Origin).Configurations(); len(docs) > 0 {
+			f.Advisory, _ = advisory.LatestEvent(docs[0], f.Vulnerability, aliases)
+			if event, ok := advisory.Triaged(docs[0], f.Vulnerability, f.Version, aliases); ok {
+				if !showTriaged {
+					continue
+				}
+				f.Triaged = event
+			}
+		}
+		triaged = append(triaged, f)
+	}
+	return triaged
+}
+
+// Pending returns the requests to record the vulnerabilities of the findings that no advisory of their origin has yet
+// as detected, at the time given, once per origin and vulnerability. Vulnerabilities are recorded under their
+// canonical ID among their aliases, so findings of the same vulnerability under different IDs are recorded once. The
+// findings have to be triaged first.
+func Pending(findings []Finding, aliases vuln.Aliases, now time.Time) []advisory.Request {
+	seen := make(map[advisory.Request]bool)
+	var pending []advisory.Request
+	for _, f := range findings {
+		if f.Advisory != "" || f.Triaged != "" {
+			continue
+		}
+		req := advisory.Request{Package: f.Origin, Vulnerability: vuln.CanonicalID(aliases.Of(f.Vulnerability)...)}
+		if seen[req] {
+			continue
+		}
+		seen[req] = true
+		advisory.EventDetected.Apply(&req)
+		req.Timestamp = now
+		pending = append(pending, req)
+	}
+	return pending
+}
+
+// componentsOf returns the components of the apk to match, including the apk itself if there's an ecosystem to match
+// it in.
+func componentsOf(sbom *SBOM, ecosystem string) []Component {
+	components := sbom.Components
+	if ecosystem != "" {
+		self := Component{Name: sbom.Name, Version: sbom.Version, Ecosystem: ecosystem}
+		components = append([]Component{self}, components...)
+	}
+	return components
+}
+
+// Format is the encoding of findings.
+type Format string
+
+const (
+	FormatTable Format = "table"
+	FormatJSON  Format = "json"
+	FormatSARIF Format = "sarif"
+)
+
+// Formats are the formats findings can be written in.
+var Formats = []Format{FormatTable, FormatJSON, FormatSARIF}
+
+// Write encodes the findings to w. The table has a row per finding, JSON has all there is to know about them, and
+// SARIF has them as results for GitHub code scanning.
+func Write(w io.Writer, findings []Finding, format Format) error {
+	switch format {
+	case FormatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ORIGIN\tPACKAGE\tVULNERABILITY\tSEVERITY\tCOMPONENT\tFIXED IN\tPATH\tADVISORY\tTRIAGED")
+		for _, f := range findings {
+			fmt.Fprintf(tw, "%s\t%s-%s\t%s\t%s\t%s %s (%s)\t%s\t%s\t%s\t%s\n", f.Origin, f.Package, f.Version, f.Vulnerability, orDash(f.Severity),
+				f.Component.Name, f.Component.Version, f.Component.Ecosystem, orDash(f.FixedVersion), orDash(f.Component.Path), orDash(string(f.Advisory)), orDash(string(f.Triaged)))
+		}
+		return tw.Flush()
+	case FormatJSON:
+		if findings == nil {
+			findings = []Finding{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(findings)
+	case FormatSARIF:
+		return newSARIF(findings).Write(w)
+	default:
+		return fmt.Errorf("unknown scan format %q", format)
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
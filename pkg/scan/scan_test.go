@@ -0,0 +1,394 @@
+package scan
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/sarif"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln/osv"
+)
+
+type file struct {
+	name    string
+	mode    int64
+	content string
+}
+
+// buildAPK returns an apk with the files, in one gzip stream.
+func buildAPK(t *testing.T, files ...file) *bytes.Buffer {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, f := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     f.name,
+			Typeflag: tar.TypeReg,
+			Mode:     f.mode,
+			Size:     int64(len(f.content)),
+		}))
+		_, err := tw.Write([]byte(f.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, zw.Close())
+	return &buf
+}
+
+const pkginfo = `# Generated by melange
+pkgname = py3-requests
+pkgver = 2.30.0-r1
+origin = requests
+arch = x86_64
+`
+
+func TestCatalog(t *testing.T) {
+	sbom, err := Catalog(buildAPK(t,
+		file{".PKGINFO", 0o644, pkginfo},
+		file{"usr/lib/python3.11/site-packages/requests-2.30.0.dist-info/METADATA", 0o644, "Metadata-Version: 2.1\nName: requests\nVersion: 2.30.0\n\nPython HTTP for Humans.\n"},
+		file{"usr/lib/node_modules/npm/node_modules/semver/package.json", 0o644, `{"name": "semver", "version": "7.5.1"}`},
+		file{"usr/lib/node_modules/npm/node_modules/@npmcli/git/package.json", 0o644, `{"name": "@npmcli/git", "version": "4.0.4"}`},
+		file{"usr/lib/node_modules/npm/node_modules/semver/test/fixtures/package.json", 0o644, `{`},
+		file{"usr/bin/normalizer", 0o755, "#!/usr/bin/python3\n"},
+	))
+	require.NoError(t, err)
+
+	assert.Equal(t, &SBOM{
+		Name:    "py3-requests",
+		Version: "2.30.0-r1",
+		Origin:  "requests",
+		Arch:    "x86_64",
+		Components: []Component{
+			{Name: "requests", Version: "2.30.0", Ecosystem: EcosystemPyPI, Path: "usr/lib/python3.11/site-packages/requests-2.30.0.dist-info/METADATA"},
+			{Name: "semver", Version: "7.5.1", Ecosystem: EcosystemNPM, Path: "usr/lib/node_modules/npm/node_modules/semver/package.json"},
+			{Name: "@npmcli/git", Version: "4.0.4", Ecosystem: EcosystemNPM, Path: "usr/lib/node_modules/npm/node_modules/@npmcli/git/package.json"},
+		},
+	}, sbom)
+
+	_, err = Catalog(buildAPK(t, file{"usr/bin/normalizer", 0o755, "#!/usr/bin/python3\n"}))
+	assert.Error(t, err, "an apk has a .PKGINFO")
+}
+
+type fakeMatcher map[Component][]string
+
+func (m fakeMatcher) Match(_ context.Context, components []Component) ([][]string, error) {
+	results := make([][]string, 0, len(components))
+	for _, c := range components {
+		results = append(results, m[c])
+	}
+	return results, nil
+}
+
+type fakeDetailer map[string]Details
+
+func (d fakeDetailer) Details(_ context.Context, id string, _ Component) (Details, error) {
+	return d[id], nil
+}
+
+func TestScan(t *testing.T) {
+	semver := Component{Name: "semver", Version: "7.5.1", Ecosystem: EcosystemNPM}
+	sboms := []*SBOM{
+		{Name: "nodejs", Version: "20.3.0-r0", Origin: "nodejs", Components: []Component{
+			{Name: semver.Name, Version: semver.Version, Ecosystem: semver.Ecosystem, Path: "usr/lib/node_modules/npm/node_modules/semver/package.json"},
+		}},
+		{Name: "curl", Version: "8.1.0-r0", Origin: "curl"},
+		{Name: "libcurl4", Version: "8.1.0-r0", Origin: "curl"},
+	}
+	matcher := fakeMatcher{
+		semver: {"GHSA-c2qf-rxjj-qqgw"},
+		{Name: "curl", Version: "8.1.0-r0", Ecosystem: "Wolfi"}:     {"CVE-2023-38545"},
+		{Name: "libcurl4", Version: "8.1.0-r0", Ecosystem: "Wolfi"}: {"CVE-2023-38546", "CVE-2023-38545"},
+	}
+
+	detailer := fakeDetailer{
+		"CVE-2023-38545": {Severity: "CRITICAL", FixedVersion: "8.4.0-r0"},
+		"CVE-2023-38546": {Severity: "LOW", FixedVersion: "8.4.0-r0"},
+	}
+
+	findings, err := Scan(context.Background(), sboms, Options{Matcher: matcher, Detailer: detailer, Ecosystem: "Wolfi"})
+	require.NoError(t, err)
+
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.Origin+" "+f.Package+" "+f.Vulnerability+" "+f.Component.Name)
+	}
+	assert.Equal(t, []string{
+		"curl curl CVE-2023-38545 curl",
+		"curl libcurl4 CVE-2023-38545 libcurl4",
+		"curl libcurl4 CVE-2023-38546 libcurl4",
+		"nodejs nodejs GHSA-c2qf-rxjj-qqgw semver",
+	}, got)
+	assert.Equal(t, "usr/lib/node_modules/npm/node_modules/semver/package.json", findings[3].Component.Path, "findings keep where the component was found")
+	assert.Equal(t, "CRITICAL", findings[0].Severity)
+	assert.Equal(t, "8.4.0-r0", findings[0].FixedVersion)
+	assert.Empty(t, findings[3].Severity)
+
+	ids := make(map[string]bool)
+	for _, f := range findings {
+		ids[f.ID] = true
+	}
+	assert.Len(t, ids, len(findings), "findings have distinct IDs")
+
+	rebuilt := []*SBOM{{Name: "curl", Version: "8.1.0-r1", Origin: "curl"}}
+	rebuiltFindings, err := Scan(context.Background(), rebuilt, Options{Matcher: fakeMatcher{
+		{Name: "curl", Version: "8.1.0-r1", Ecosystem: "Wolfi"}: {"CVE-2023-38545"},
+	}, Ecosystem: "Wolfi"})
+	require.NoError(t, err)
+	require.Len(t, rebuiltFindings, 1)
+	assert.Equal(t, findings[0].ID, rebuiltFindings[0].ID, "findings keep their IDs across versions")
+
+	findings, err = Scan(context.Background(), sboms, Options{Matcher: matcher})
+	require.NoError(t, err)
+	require.Len(t, findings, 1, "without an ecosystem, only the components are matched")
+	assert.Equal(t, "GHSA-c2qf-rxjj-qqgw", findings[0].Vulnerability)
+}
+
+const curlAdvisories = `package:
+  name: curl
+advisories:
+  CVE-2023-38545:
+    - timestamp: 2023-10-11T10:00:00Z
+      status: fixed
+      fixed-version: 8.4.0-r0
+  CVE-2023-38546:
+    - timestamp: 2023-10-11T10:00:00Z
+      status: not_affected
+      justification: vulnerable_code_not_in_execute_path
+  CVE-2023-28322:
+    - timestamp: 2023-10-11T10:00:00Z
+      status: under_investigation
+`
+
+func TestTriage(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "curl.advisories.yaml"), []byte(curlAdvisories), 0o600))
+	advisoryCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(dir))
+	require.NoError(t, err)
+
+	finding := func(version, vulnerability string, event advisory.Event) Finding {
+		return Finding{Package: "libcurl4", Version: version, Origin: "curl", Vulnerability: vulnerability, Advisory: event}
+	}
+	findings := []Finding{
+		finding("8.4.0-r0", "GHSA-wgvw-4c2w-fxwx", ""),
+		finding("8.3.0-r0", "CVE-2023-38545", ""),
+		finding("8.4.0-r0", "CVE-2023-38546", ""),
+		finding("8.4.0-r0", "CVE-2023-28322", ""),
+		{Package: "nodejs", Version: "20.3.0-r0", Origin: "nodejs", Vulnerability: "GHSA-c2qf-rxjj-qqgw"},
+	}
+	aliases := vuln.Aliases{"GHSA-wgvw-4c2w-fxwx": {"CVE-2023-38545"}}
+
+	assert.Equal(t, []Finding{
+		finding("8.3.0-r0", "CVE-2023-38545", advisory.EventFixed),
+		finding("8.4.0-r0", "CVE-2023-28322", advisory.EventDetected),
+		findings[4],
+	}, Triage(findings, advisoryCfgs, aliases, false), "what's settled for the version is left out")
+
+	triaged := Triage(findings, advisoryCfgs, aliases, true)
+	require.Len(t, triaged, len(findings))
+	var events []advisory.Event
+	for _, f := range triaged {
+		events = append(events, f.Triaged)
+	}
+	assert.Equal(t, []advisory.Event{advisory.EventFixed, "", advisory.EventFalsePositive, "", ""}, events)
+}
+
+func TestPending(t *testing.T) {
+	now := time.Date(2023, 10, 12, 0, 0, 0, 0, time.UTC)
+	findings := []Finding{
+		{Package: "curl", Origin: "curl", Vulnerability: "CVE-2023-28322", Advisory: advisory.EventDetected},
+		{Package: "curl", Origin: "curl", Vulnerability: "CVE-2023-38545", Advisory: advisory.EventFixed, Triaged: advisory.EventFixed},
+		{Package: "libcurl4", Origin: "curl", Vulnerability: "CVE-2023-46218"},
+		{Package: "curl", Origin: "curl", Vulnerability: "CVE-2023-46218"},
+		{Package: "nodejs", Origin: "nodejs", Vulnerability: "CVE-2023-46218"},
+		// the same vulnerability as the CVE, found by another matcher
+		{Package: "curl", Origin: "curl", Vulnerability: "GHSA-66p4-3h4c-hcfw"},
+		{Package: "go-tool", Origin: "go-tool", Vulnerability: "GO-2023-2102"},
+	}
+	aliases := vuln.Aliases{
+		"GHSA-66p4-3h4c-hcfw": {"CVE-2023-46218"},
+		"GO-2023-2102":        {"CVE-2023-39325", "GHSA-4374-p667-p6c8"},
+	}
+
+	assert.Equal(t, []advisory.Request{
+		{Package: "curl", Vulnerability: "CVE-2023-46218", Status: vex.StatusUnderInvestigation, Timestamp: now},
+		{Package: "nodejs", Vulnerability: "CVE-2023-46218", Status: vex.StatusUnderInvestigation, Timestamp: now},
+		{Package: "go-tool", Vulnerability: "CVE-2023-39325", Status: vex.StatusUnderInvestigation, Timestamp: now},
+	}, Pending(findings, aliases, now), "vulnerabilities without advisories are pending once per origin, under their canonical ID")
+	assert.Empty(t, Pending(nil, nil, now))
+}
+
+func TestWrite(t *testing.T) {
+	findings := []Finding{{
+		ID:            "2f1b5a0c9d3e4f67",
+		Package:       "libcurl4",
+		Version:       "8.1.0-r0",
+		Origin:        "curl",
+		Component:     Component{Name: "libcurl4", Version: "8.1.0-r0", Ecosystem: "Wolfi"},
+		Vulnerability: "CVE-2023-38545",
+		Severity:      "CRITICAL",
+		FixedVersion:  "8.4.0-r0",
+		Advisory:      advisory.EventDetected,
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, findings, FormatTable))
+	assert.Equal(t, `ORIGIN  PACKAGE            VULNERABILITY   SEVERITY  COMPONENT                  FIXED IN  PATH  ADVISORY  TRIAGED
+curl    libcurl4-8.1.0-r0  CVE-2023-38545  CRITICAL  libcurl4 8.1.0-r0 (Wolfi)  8.4.0-r0  -     detected  -
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, findings, FormatJSON))
+	var decoded []Finding
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, findings, decoded)
+
+	assert.Error(t, Write(&buf, findings, "xml"))
+}
+
+func TestWrite_SARIF(t *testing.T) {
+	finding := Finding{
+		ID:            "2f1b5a0c9d3e4f67",
+		Package:       "libcurl4",
+		Version:       "8.1.0-r0",
+		Origin:        "curl",
+		Component:     Component{Name: "libcurl4", Version: "8.1.0-r0", Ecosystem: "Wolfi"},
+		Vulnerability: "CVE-2023-38545",
+		Severity:      "CRITICAL",
+		FixedVersion:  "8.4.0-r0",
+	}
+	triaged := finding
+	triaged.ID, triaged.Package, triaged.Triaged = "8c0d2e7a41b3f956", "curl", advisory.EventFalsePositive
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, []Finding{finding, triaged}, FormatSARIF))
+
+	var sarifLog sarif.Log
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &sarifLog))
+	assert.Equal(t, "2.1.0", sarifLog.Version)
+	require.Len(t, sarifLog.Runs, 1)
+	run := sarifLog.Runs[0]
+
+	require.Len(t, run.Tool.Driver.Rules, 1, "a rule per vulnerability")
+	assert.Equal(t, "CVE-2023-38545", run.Tool.Driver.Rules[0].ID)
+	assert.Equal(t, "9.0", run.Tool.Driver.Rules[0].Properties["security-severity"])
+
+	require.Len(t, run.Results, 2)
+	result := run.Results[0]
+	assert.Equal(t, sarif.LevelError, result.Level)
+	assert.Equal(t, "libcurl4-8.1.0-r0 has CVE-2023-38545 in libcurl4 8.1.0-r0 (Wolfi), fixed in 8.4.0-r0", result.Message.Text)
+	assert.Equal(t, "curl.yaml", result.Locations[0].PhysicalLocation.ArtifactLocation.URI, "results are located in the build configuration of the origin")
+	assert.Empty(t, result.Suppressions)
+	assert.Nil(t, result.Locations[0].PhysicalLocation.Region)
+	assert.Equal(t, "2f1b5a0c9d3e4f67", result.PartialFingerprints["wolfictlFindingId/v1"])
+	assert.Equal(t, []sarif.Suppression{{Kind: "external", Justification: "false-positive"}}, run.Results[1].Suppressions)
+}
+
+func TestOSVMatcher_Details(t *testing.T) {
+	records := map[string]string{
+		"GO-2023-1704": `{"id": "GO-2023-1704", "aliases": ["CVE-2023-24540", "GHSA-v4m2-x4rp-hv22"], "affected": [
+			{"package": {"name": "stdlib", "ecosystem": "Go"}, "ranges": [{"type": "SEMVER", "events": [
+				{"introduced": "0"}, {"fixed": "1.19.9"}, {"introduced": "1.20.0"}, {"fixed": "1.20.4"}]}]}]}`,
+		"GHSA-v4m2-x4rp-hv22": `{"id": "GHSA-v4m2-x4rp-hv22", "database_specific": {"severity": "CRITICAL"}}`,
+	}
+	requests := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		record, ok := records[strings.TrimPrefix(r.URL.Path, "/v1/vulns/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(record))
+	}))
+	defer ts.Close()
+
+	parsedURL, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	matcher := &OSVMatcher{Client: osv.NewClient(ts.Client(), parsedURL.Host)}
+
+	stdlib := Component{Name: "stdlib", Version: "1.20.1", Ecosystem: EcosystemGo}
+	d, err := matcher.Details(context.Background(), "GO-2023-1704", stdlib)
+	require.NoError(t, err)
+	assert.Equal(t, Details{Severity: "CRITICAL", FixedVersion: "1.20.4"}, d, "the severity of the GHSA, and the first fix after the version")
+
+	_, err = matcher.Details(context.Background(), "GO-2023-1704", stdlib)
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests, "records are looked up once")
+
+	d, err = matcher.Details(context.Background(), "GO-2099-0001", stdlib)
+	require.NoError(t, err)
+	assert.Equal(t, Details{Severity: "UNKNOWN"}, d)
+}
+
+func TestOSVMatcher_DB(t *testing.T) {
+	archives := map[string][]byte{}
+	for ecosystem, records := range map[string]map[string]string{
+		EcosystemGo: {
+			"GO-2023-1704.json": `{"id": "GO-2023-1704", "aliases": ["GHSA-v4m2-x4rp-hv22"], "affected": [
+				{"package": {"name": "stdlib", "ecosystem": "Go"}, "ranges": [{"type": "SEMVER", "events": [
+					{"introduced": "0"}, {"fixed": "1.19.9"}, {"introduced": "1.20.0"}, {"fixed": "1.20.4"}]}]}]}`,
+			"GHSA-v4m2-x4rp-hv22.json": `{"id": "GHSA-v4m2-x4rp-hv22", "database_specific": {"severity": "CRITICAL"}}`,
+		},
+		EcosystemPyPI: {
+			"PYSEC-2023-74.json": `{"id": "PYSEC-2023-74", "affected": [
+				{"package": {"name": "requests", "ecosystem": "PyPI"}, "ranges": [{"type": "ECOSYSTEM", "events": [
+					{"introduced": "2.3.0"}, {"fixed": "2.31.0"}]}], "versions": ["2.3.0", "2.30.0"]}]}`,
+		},
+	} {
+		var archive bytes.Buffer
+		zw := zip.NewWriter(&archive)
+		for name, record := range records {
+			w, err := zw.Create(name)
+			require.NoError(t, err)
+			_, err = w.Write([]byte(record))
+			require.NoError(t, err)
+		}
+		require.NoError(t, zw.Close())
+		archives["/"+ecosystem+"/all.zip"] = archive.Bytes()
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archives[r.URL.Path])
+	}))
+	defer ts.Close()
+
+	dir := filepath.Join(t.TempDir(), "osv-db")
+	_, err := osv.DownloadDB(context.Background(), ts.Client(), ts.URL, dir, []string{EcosystemGo, EcosystemPyPI})
+	require.NoError(t, err)
+	db, err := osv.OpenDB(dir)
+	require.NoError(t, err)
+	matcher := &OSVMatcher{DB: db}
+
+	components := []Component{
+		{Name: "stdlib", Version: "1.20.1", Ecosystem: EcosystemGo},
+		{Name: "stdlib", Version: "1.20.4", Ecosystem: EcosystemGo},
+		{Name: "golang.org/x/net", Version: "0.7.0", Ecosystem: EcosystemGo},
+		{Name: "requests", Version: "2.30.0", Ecosystem: EcosystemPyPI},
+		{Name: "requests", Version: "2.31.0rc1", Ecosystem: EcosystemPyPI},
+	}
+	vulns, err := matcher.Match(context.Background(), components)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"GO-2023-1704"}, nil, nil, {"PYSEC-2023-74"}, nil}, vulns,
+		"PyPI ranges are left out, only the versions records list match")
+
+	d, err := matcher.Details(context.Background(), "GO-2023-1704", components[0])
+	require.NoError(t, err)
+	assert.Equal(t, Details{Severity: "CRITICAL", FixedVersion: "1.20.4"}, d, "details are looked up in the database too")
+}
@@ -0,0 +1,346 @@
+// Package schema validates YAML documents against a JSON schema, like the one melange publishes for its configs, with
+// go-openapi/validate, reporting the line and column of each violation.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/validate"
+	"gopkg.in/yaml.v3"
+)
+
+// Kind is the kind of a violation.
+type Kind string
+
+const (
+	// KindUnknownField is a field the schema doesn't allow.
+	KindUnknownField Kind = "unknown-field"
+	// KindTypeMismatch is a value of another type than the schema expects, e.g. a number where a string is expected.
+	KindTypeMismatch Kind = "type-mismatch"
+	// KindMissingField is a required field that isn't set.
+	KindMissingField Kind = "missing-field"
+	// KindInvalidValue is a value of the right type that the schema doesn't allow, e.g. one not in an enum.
+	KindInvalidValue Kind = "invalid-value"
+)
+
+// Violation is a part of a document that doesn't conform to the schema.
+type Violation struct {
+	Kind Kind
+	// Path is where the violation is in the document, e.g. package.version or pipeline[2].with.
+	Path    string
+	Message string
+	Line    int
+	Column  int
+}
+
+func (v Violation) String() string {
+	if v.Path == "" {
+		return v.Message
+	}
+	return v.Path + ": " + v.Message
+}
+
+// Schema is a JSON schema.
+type Schema struct {
+	// root is the schema as a JSON document, which references like #/$defs/Package are resolved in
+	root map[string]any
+	// validator is compiled from root on the first validation
+	validator *validate.SchemaValidator
+}
+
+// Parse parses a JSON schema.
+func Parse(b []byte) (*Schema, error) {
+	root := make(map[string]any)
+	if err := json.Unmarshal(b, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON schema: %w", err)
+	}
+	return &Schema{root: root}, nil
+}
+
+// Allow adds fields to the properties of the object at path in the documents the schema describes, e.g. update, so
+// extensions of a document that the schema forbids with additionalProperties: false are valid. The fields the schema
+// already describes, and paths it doesn't describe, are left alone.
+func (s *Schema) Allow(path string, fields ...string) error {
+	obj, err := s.resolve(s.root)
+	if err != nil {
+		return err
+	}
+	if path != "" {
+		for _, name := range strings.Split(path, ".") {
+			props, _ := obj["properties"].(map[string]any)
+			prop, ok := props[name].(map[string]any)
+			if !ok {
+				return nil
+			}
+			if obj, err = s.resolve(prop); err != nil {
+				return err
+			}
+		}
+	}
+
+	props, ok := obj["properties"].(map[string]any)
+	if !ok {
+		props = make(map[string]any)
+		obj["properties"] = props
+	}
+	for _, f := range fields {
+		if _, ok := props[f]; !ok {
+			props[f] = map[string]any{}
+		}
+	}
+	s.validator = nil
+	return nil
+}
+
+// resolve follows the $ref of a schema within the document, e.g. #/$defs/Package.
+func (s *Schema) resolve(schema map[string]any) (map[string]any, error) {
+	for {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema, nil
+		}
+		var node any = s.root
+		for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			m, ok := node.(map[string]any)
+			if !ok || !strings.HasPrefix(ref, "#/") {
+				return nil, fmt.Errorf("unresolvable reference %q", ref)
+			}
+			node = m[strings.NewReplacer("~1", "/", "~0", "~").Replace(token)]
+		}
+		if schema, ok = node.(map[string]any); !ok {
+			return nil, fmt.Errorf("unresolvable reference %q", ref)
+		}
+	}
+}
+
+// compile creates the validator of the schema, which go-openapi/validate panics to report invalid schemas with.
+func (s *Schema) compile() (v *validate.SchemaValidator, err error) {
+	if s.validator != nil {
+		return s.validator, nil
+	}
+	b, err := json.Marshal(s.root)
+	if err != nil {
+		return nil, err
+	}
+	schema := &spec.Schema{}
+	if err := json.Unmarshal(b, schema); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON schema: %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	s.validator = validate.NewSchemaValidator(schema, s.root, "", strfmt.Default)
+	return s.validator, nil
+}
+
+// Validate validates a YAML document, returning its violations sorted by line and column.
+func (s *Schema) Validate(doc *yaml.Node) ([]Violation, error) {
+	v, err := s.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	// the validator takes a document as decoded from JSON
+	var data any
+	if err := doc.Decode(&data); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("document can't be represented as JSON: %w", err)
+	}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+
+	root := doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	var violations []Violation
+	for _, err := range v.Validate(data).Errors {
+		violations = append(violations, locate(root, err)...)
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Line != violations[j].Line {
+			return violations[i].Line < violations[j].Line
+		}
+		return violations[i].Column < violations[j].Column
+	})
+	return violations, nil
+}
+
+// combinatorPath is the path quoted by the errors of allOf, anyOf and oneOf, which have no name.
+var combinatorPath = regexp.MustCompile(`^"([^"]*)" (must .*)`)
+
+// locate finds the nodes of a document a validation error is about. The validator leaves array indexes out of the
+// paths of errors, e.g. pipeline.with.uri, so the nodes are looked up through every item of arrays, and the ones the
+// error applies to are kept.
+func locate(root *yaml.Node, err error) []Violation {
+	name, message, kind := "", err.Error(), KindInvalidValue
+	match := func(*yaml.Node) bool { return true }
+	var key string
+
+	if e, ok := err.(*errors.Validation); ok {
+		name = e.Name
+		if _, m, ok := strings.Cut(message, " in "+e.In+" "); ok {
+			message = m
+		}
+		switch e.Code() {
+		case errors.InvalidTypeCode:
+			kind = KindTypeMismatch
+			match = func(n *yaml.Node) bool { return jsonType(n) == fmt.Sprint(e.Value) }
+		case errors.RequiredFailCode:
+			kind = KindMissingField
+			name, key = parent(name)
+			match = func(n *yaml.Node) bool { return n.Kind == yaml.MappingNode && field(n, key) == nil }
+		case errors.UnallowedPropertyCode:
+			kind = KindUnknownField
+			key = fmt.Sprint(e.Value)
+			match = func(n *yaml.Node) bool { return field(n, key) != nil }
+		case errors.EnumFailCode:
+			match = func(n *yaml.Node) bool { return n.Kind == yaml.ScalarNode && n.Value == fmt.Sprint(e.Value) }
+		}
+	} else if m := combinatorPath.FindStringSubmatch(message); m != nil {
+		name, message = m[1], m[2]
+	}
+	name = strings.TrimPrefix(name, ".")
+
+	var violations []Violation
+	add := func(path string, n *yaml.Node) {
+		v := Violation{Kind: kind, Path: path, Message: message, Line: n.Line, Column: n.Column}
+		switch kind {
+		case KindUnknownField:
+			k := fieldKey(n, key)
+			v.Message, v.Line, v.Column = fmt.Sprintf("unknown field %q", key), k.Line, k.Column
+		case KindMissingField:
+			v.Message = fmt.Sprintf("missing required field %q", key)
+		}
+		violations = append(violations, v)
+	}
+
+	candidates := lookup(root, "", segments(name))
+	for _, c := range candidates {
+		if match(c.node) {
+			add(c.path, c.node)
+		}
+	}
+	if len(violations) == 0 && len(candidates) > 0 && kind != KindUnknownField && kind != KindMissingField {
+		add(candidates[0].path, candidates[0].node)
+	}
+	if len(violations) == 0 {
+		violations = append(violations, Violation{Kind: kind, Path: name, Message: message, Line: root.Line, Column: root.Column})
+	}
+	return violations
+}
+
+type candidate struct {
+	path string
+	node *yaml.Node
+}
+
+// lookup returns the nodes at a path of field names, going through every item of the arrays on the way, and at the
+// end of the path, as the validator reports the errors of items at the path of their array.
+func lookup(n *yaml.Node, path string, names []string) []candidate {
+	if n.Kind == yaml.AliasNode {
+		return lookup(n.Alias, path, names)
+	}
+	if n.Kind == yaml.SequenceNode {
+		found := []candidate{}
+		if len(names) == 0 {
+			found = append(found, candidate{path, n})
+		}
+		for i, item := range n.Content {
+			// indexes the validator did keep select the item
+			if len(names) > 0 && names[0] == strconv.Itoa(i) {
+				return lookup(item, fmt.Sprintf("%s[%d]", path, i), names[1:])
+			}
+			found = append(found, lookup(item, fmt.Sprintf("%s[%d]", path, i), names)...)
+		}
+		return found
+	}
+	if len(names) == 0 {
+		return []candidate{{path, n}}
+	}
+	v := field(n, names[0])
+	if v == nil {
+		return nil
+	}
+	if path != "" {
+		path += "."
+	}
+	return lookup(v, path+names[0], names[1:])
+}
+
+// field returns the value of a field of a mapping, nil if it isn't set.
+func field(n *yaml.Node, name string) *yaml.Node {
+	k := fieldKey(n, name)
+	if k == nil {
+		return nil
+	}
+	return k.value
+}
+
+type mappingKey struct {
+	*yaml.Node
+	value *yaml.Node
+}
+
+// fieldKey returns the key of a field of a mapping, nil if it isn't set.
+func fieldKey(n *yaml.Node, name string) *mappingKey {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == name {
+			return &mappingKey{n.Content[i], n.Content[i+1]}
+		}
+	}
+	return nil
+}
+
+func segments(name string) []string {
+	if name == "" {
+		return nil
+	}
+	return strings.Split(name, ".")
+}
+
+func parent(name string) (string, string) {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return "", name
+	}
+	return name[:i], name[i+1:]
+}
+
+// jsonType is the type the validator names the value of a node by.
+func jsonType(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	case yaml.AliasNode:
+		return jsonType(n.Alias)
+	}
+	switch n.ShortTag() {
+	case "!!int", "!!float":
+		return "number"
+	case "!!bool":
+		return "boolean"
+	case "!!null":
+		return "null"
+	}
+	return "string"
+}
@@ -0,0 +1,150 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const testSchema = `{
+  "$ref": "#/$defs/config",
+  "$defs": {
+    "config": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "size": {"type": "string", "enum": ["small", "large"]},
+        "steps": {"type": "array", "items": {"$ref": "#/$defs/step"}},
+        "env": {"patternProperties": {"^[A-Z_]+$": {"type": "string"}}, "additionalProperties": false},
+        "update": {"$ref": "#/$defs/update"}
+      },
+      "required": ["name"],
+      "additionalProperties": false
+    },
+    "step": {
+      "type": "object",
+      "properties": {"uses": {"type": "string"}, "with": {"type": "object", "additionalProperties": {"type": "string"}}},
+      "additionalProperties": false
+    },
+    "update": {"type": "object", "properties": {"enabled": {"type": "boolean"}}, "additionalProperties": false}
+  }
+}`
+
+func TestSchema_Validate(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		doc  string
+		want []Violation
+	}{
+		{
+			name: "valid",
+			doc: `name: foo
+size: small
+steps:
+  - uses: fetch
+    with:
+      uri: https://example.com
+env:
+  CGO_ENABLED: "0"
+`,
+		},
+		{
+			name: "anchors and aliases",
+			doc: `name: &name foo
+env:
+  NAME: *name
+`,
+		},
+		{
+			name: "invalid",
+			doc: `name: [foo]
+size: medium
+steps:
+  - uses: fetch
+    with:
+      strip-components: 1
+  - uses: make
+    workdir: src
+  - uses: fetch
+    with:
+      strip-components: 2
+env:
+  path: /usr/bin
+"odd key": true
+`,
+			want: []Violation{
+				{Kind: KindTypeMismatch, Path: "name", Message: `must be of type string: "array"`, Line: 1, Column: 7},
+				{Kind: KindInvalidValue, Path: "size", Message: "should be one of [small large]", Line: 2, Column: 7},
+				{Kind: KindTypeMismatch, Path: "steps[0].with.strip-components", Message: `must be of type string: "number"`, Line: 6, Column: 25},
+				{Kind: KindUnknownField, Path: "steps[1]", Message: `unknown field "workdir"`, Line: 8, Column: 5},
+				{Kind: KindTypeMismatch, Path: "steps[2].with.strip-components", Message: `must be of type string: "number"`, Line: 11, Column: 25},
+				{Kind: KindUnknownField, Path: "env", Message: `unknown field "path"`, Line: 13, Column: 3},
+				{Kind: KindUnknownField, Message: `unknown field "odd key"`, Line: 14, Column: 1},
+			},
+		},
+		{
+			name: "missing field",
+			doc: `size: small
+`,
+			want: []Violation{
+				{Kind: KindMissingField, Message: `missing required field "name"`, Line: 1, Column: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &yaml.Node{}
+			require.NoError(t, yaml.Unmarshal([]byte(tt.doc), doc))
+			got, err := s.Validate(doc)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSchema_Allow(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	require.NoError(t, err)
+	doc := &yaml.Node{}
+	require.NoError(t, yaml.Unmarshal([]byte(`name: foo
+update:
+  enabled: true
+  pypi:
+    identifier: foo
+`), doc))
+
+	got, err := s.Validate(doc)
+	require.NoError(t, err)
+	assert.Equal(t, []Violation{{Kind: KindUnknownField, Path: "update", Message: `unknown field "pypi"`, Line: 4, Column: 3}}, got)
+
+	require.NoError(t, s.Allow("update", "pypi", "enabled"))
+	require.NoError(t, s.Allow("package", "cpe"), "paths the schema doesn't describe are left alone")
+	got, err = s.Validate(doc)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	require.NoError(t, yaml.Unmarshal([]byte(`name: foo
+update:
+  enabled: "yes"
+`), doc))
+	got, err = s.Validate(doc)
+	require.NoError(t, err)
+	assert.Len(t, got, 1, "the fields the schema describes keep their schema")
+}
+
+func TestParse(t *testing.T) {
+	_, err := Parse([]byte(`{"type": `))
+	assert.ErrorContains(t, err, "failed to parse JSON schema")
+
+	s, err := Parse([]byte(`{"$ref": "#/$defs/nope"}`))
+	require.NoError(t, err)
+	doc := &yaml.Node{}
+	require.NoError(t, yaml.Unmarshal([]byte("foo: bar"), doc))
+	_, err = s.Validate(doc)
+	assert.Error(t, err)
+}
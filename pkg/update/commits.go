@@ -0,0 +1,39 @@
+package update
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+// resolveTagCommit returns the commit a tag of a git repository points to, asking the API of GitHub or GitLab for
+// repositories hosted there, and git ls-remote for other hosts or when the API fails, e.g. because of rate limits
+func (o *Options) resolveTagCommit(repository, tag string) (string, error) {
+	u, err := url.Parse(repository)
+	if err == nil {
+		path := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+		var commit string
+		switch {
+		case u.Host == "github.com":
+			owner, name, _ := strings.Cut(path, "/")
+			client := github.NewClient(o.GitHubHTTPClient.Client)
+			commit, _, err = client.Repositories.GetCommitSHA1(o.context(), owner, name, tag, "")
+		case u.Host == defaultGitLabHost || strings.HasPrefix(u.Host, "gitlab."):
+			s := GitLabService{Client: o.GitLabHTTPClient, Logger: o.Logger}
+			t := gitLabTag{}
+			glm := &melange.GitLabMonitor{Identifier: path, Host: u.Scheme + "://" + u.Host}
+			err = s.get(glm, "repository/tags/"+url.PathEscape(tag), &t)
+			commit = t.Commit.ID
+		}
+		if err == nil && commit != "" {
+			return commit, nil
+		}
+		if err != nil {
+			o.Logger.Printf("failed to resolve tag %s of %s with the %s API, trying git ls-remote: %s", tag, repository, u.Host, err)
+		}
+	}
+	return melange.LsRemoteTag(o.context(), repository, tag)
+}
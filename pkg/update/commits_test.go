@@ -0,0 +1,48 @@
+package update
+
+import (
+	"log"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions_resolveTagCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	signature := &object.Signature{Name: "John Doe", Email: "john@doe.org", When: time.Now()}
+	commit, err := wt.Commit("initial commit", &git.CommitOptions{Author: signature, AllowEmptyCommits: true})
+	require.NoError(t, err)
+
+	_, err = r.CreateTag("v1.0.0", commit, nil)
+	require.NoError(t, err)
+	annotated, err := r.CreateTag("v1.1.0", commit, &git.CreateTagOptions{Tagger: signature, Message: "v1.1.0"})
+	require.NoError(t, err)
+	require.NotEqual(t, commit, annotated.Hash())
+
+	o := Options{Logger: log.New(log.Writer(), "test: ", log.LstdFlags|log.Lmsgprefix)}
+
+	got, err := o.resolveTagCommit(dir, "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, commit.String(), got)
+
+	// annotated tags are peeled to their commit
+	got, err = o.resolveTagCommit(dir, "v1.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, commit.String(), got)
+
+	_, err = o.resolveTagCommit(dir, "v2.0.0")
+	assert.ErrorContains(t, err, "tag v2.0.0 not found")
+}
@@ -0,0 +1,176 @@
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+const (
+	cratesURL = "https://crates.io"
+
+	// crates.io rejects requests without a user agent identifying the client
+	cratesUserAgent = "wolfictl (https://github.com/wolfi-dev/wolfictl)"
+)
+
+// CratesService looks up the latest versions of Rust crates released on crates.io, configured with an update.crates
+// block in their melange config
+type CratesService struct {
+	Client *http2.RLHTTPClient
+	Logger *log.Logger
+
+	// BaseURL of the crates.io API, defaults to https://crates.io
+	BaseURL string
+}
+
+type crateVersion struct {
+	Num      string `json:"num"`
+	Yanked   bool   `json:"yanked"`
+	Checksum string `json:"checksum"`
+}
+
+type crate struct {
+	Versions []crateVersion `json:"versions"`
+}
+
+func (s CratesService) getLatestCratesVersions(melangePackages map[string]*melange.Packages) (packagesToUpdate map[string]NewVersionResults, errorMessages map[string]string) {
+	packagesToUpdate = make(map[string]NewVersionResults)
+	errorMessages = make(map[string]string)
+
+	for packageName, p := range melangePackages {
+		cm := p.CratesMonitor
+		if cm == nil {
+			continue
+		}
+
+		s.Logger.Printf("%s: checking crate %s\n", packageName, cm.Identifier)
+
+		c, err := s.getCrate(cm.Identifier)
+		if err != nil {
+			errorMessages[p.Config.Package.Name] = fmt.Sprintf(
+				"failed getting crates.io versions for package %s, identifier %s: %s",
+				p.Config.Package.Name, cm.Identifier, err.Error(),
+			)
+			continue
+		}
+
+		latest, err := latestCrateVersion(p, c)
+		if err != nil {
+			errorMessages[p.Config.Package.Name] = err.Error()
+			continue
+		}
+		if latest.Version == "" {
+			errorMessages[p.Config.Package.Name] = fmt.Sprintf(
+				"no versions found in crates.io for package %s, identifier %s",
+				p.Config.Package.Name, cm.Identifier,
+			)
+			continue
+		}
+		packagesToUpdate[p.Config.Package.Name] = latest
+	}
+	return packagesToUpdate, errorMessages
+}
+
+// latestCrateVersion returns the latest version of the crate that isn't yanked or a pre-release, satisfies the
+// constraint of the update config and isn't filtered out by it. If the config fetches the crate from crates.io, the
+// download of that version is returned with it.
+func latestCrateVersion(p *melange.Packages, c *crate) (NewVersionResults, error) {
+	cm := p.CratesMonitor
+
+	var constraints version.Constraints
+	if cm.Constraint != "" {
+		var err error
+		constraints, err = version.NewConstraint(cm.Constraint)
+		if err != nil {
+			return NewVersionResults{}, errors.Wrapf(err, "parsing crates constraint %q of package %s", cm.Constraint, p.Config.Package.Name)
+		}
+	}
+
+	var latest *version.Version
+	var checksum string
+	for _, cv := range c.Versions {
+		if cv.Yanked {
+			continue
+		}
+		v, err := version.NewSemver(cv.Num)
+		if err != nil || v.Prerelease() != "" {
+			continue
+		}
+		if constraints != nil && !constraints.Check(v) {
+			continue
+		}
+		ignore, err := ignoreVersion(p.Config.Update, cv.Num)
+		if err != nil {
+			return NewVersionResults{}, err
+		}
+		if ignore {
+			continue
+		}
+		if latest == nil || v.GreaterThan(latest) {
+			latest, checksum = v, cv.Checksum
+		}
+	}
+	if latest == nil {
+		return NewVersionResults{}, nil
+	}
+
+	result := NewVersionResults{Version: latest.Original()}
+	if isCratesDownload(fetchURI(p.Config)) {
+		result.SourceURL = fmt.Sprintf("%s/api/v1/crates/%s/%s/download", cratesURL, cm.Identifier, latest.Original())
+		result.SourceSHA256 = checksum
+	}
+	return result, nil
+}
+
+// isCratesDownload reports whether uri is a crate download of the crates.io API, i.e.
+// https://crates.io/api/v1/crates/<name>/<version>/download
+func isCratesDownload(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	return u.Host == "crates.io" && strings.HasPrefix(u.Path, "/api/v1/crates/") && strings.HasSuffix(u.Path, "/download")
+}
+
+func (s CratesService) getCrate(identifier string) (*crate, error) {
+	baseURL := s.BaseURL
+	if baseURL == "" {
+		baseURL = cratesURL
+	}
+	targetURL := fmt.Sprintf("%s/api/v1/crates/%s", strings.TrimSuffix(baseURL, "/"), url.PathEscape(identifier))
+	req, err := http.NewRequest("GET", targetURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating GET request %s", targetURL)
+	}
+	req.Header.Set("User-Agent", cratesUserAgent)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting URI %s", targetURL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non ok http response for URI %s code: %v", targetURL, resp.StatusCode)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading crates.io response from %s", targetURL)
+	}
+	c := &crate{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, errors.Wrapf(err, "unmarshalling crates.io response from %s", targetURL)
+	}
+	return c, nil
+}
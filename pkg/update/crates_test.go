@@ -0,0 +1,143 @@
+package update
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+func TestCratesService_getLatestCratesVersions(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "crates", "ripgrep.json"))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/crates/ripgrep" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("User-Agent") == "" {
+			http.Error(w, "missing user agent", http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	s := CratesService{
+		Client:  &http2.RLHTTPClient{Client: server.Client(), Ratelimiter: rate.NewLimiter(rate.Inf, 1)},
+		Logger:  log.New(log.Writer(), "test: ", log.LstdFlags|log.Lmsgprefix),
+		BaseURL: server.URL,
+	}
+
+	download := []build.Pipeline{{
+		Uses: "fetch",
+		With: map[string]string{"uri": "https://crates.io/api/v1/crates/ripgrep/${{package.version}}/download"},
+	}}
+
+	tests := []struct {
+		name     string
+		monitor  melange.CratesMonitor
+		update   build.Update
+		pipeline []build.Pipeline
+		want     NewVersionResults
+		wantErr  string
+	}{
+		{
+			name:     "skips yanked and pre-releases",
+			monitor:  melange.CratesMonitor{Identifier: "ripgrep"},
+			pipeline: download,
+			want: NewVersionResults{
+				Version:      "13.0.0",
+				SourceURL:    "https://crates.io/api/v1/crates/ripgrep/13.0.0/download",
+				SourceSHA256: "2c7fb2a7bd1d4ac78bdb78d1ff1bc56a5cd1e94bb92e5e77b40c12ffd2ec4d1e",
+			},
+		},
+		{
+			name:     "constraint",
+			monitor:  melange.CratesMonitor{Identifier: "ripgrep", Constraint: "~> 12.1"},
+			pipeline: download,
+			want: NewVersionResults{
+				Version:      "12.1.1",
+				SourceURL:    "https://crates.io/api/v1/crates/ripgrep/12.1.1/download",
+				SourceSHA256: "7a8e6b2c5d4f3a1e9b0c8d7f6e5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a",
+			},
+		},
+		{
+			name:    "ignore regex patterns",
+			monitor: melange.CratesMonitor{Identifier: "ripgrep"},
+			update:  build.Update{IgnoreRegexPatterns: []string{`^13\.`}},
+			want:    NewVersionResults{Version: "12.1.1"},
+		},
+		{
+			name:    "sources from elsewhere",
+			monitor: melange.CratesMonitor{Identifier: "ripgrep"},
+			pipeline: []build.Pipeline{{
+				Uses: "fetch",
+				With: map[string]string{"uri": "https://github.com/BurntSushi/ripgrep/archive/refs/tags/${{package.version}}.tar.gz"},
+			}},
+			want: NewVersionResults{Version: "13.0.0"},
+		},
+		{
+			name:    "no version satisfies the constraint",
+			monitor: melange.CratesMonitor{Identifier: "ripgrep", Constraint: ">= 15.0"},
+			wantErr: "no versions found",
+		},
+		{
+			name:    "invalid constraint",
+			monitor: melange.CratesMonitor{Identifier: "ripgrep", Constraint: "not a constraint"},
+			wantErr: "parsing crates constraint",
+		},
+		{
+			name:    "unknown crate",
+			monitor: melange.CratesMonitor{Identifier: "nope"},
+			wantErr: "404",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor, pipeline := tt.monitor, tt.pipeline
+			packageConfigs := map[string]*melange.Packages{
+				"ripgrep": {
+					Config: build.Configuration{
+						Package:  build.Package{Name: "ripgrep", Version: "12.1.0"},
+						Update:   tt.update,
+						Pipeline: pipeline,
+					},
+					CratesMonitor: &monitor,
+				},
+			}
+
+			latestVersions, errorMessages := s.getLatestCratesVersions(packageConfigs)
+			if tt.wantErr != "" {
+				assert.Empty(t, latestVersions)
+				assert.Contains(t, errorMessages["ripgrep"], tt.wantErr)
+				return
+			}
+			assert.Empty(t, errorMessages)
+			assert.Equal(t, tt.want, latestVersions["ripgrep"])
+		})
+	}
+}
+
+func Test_isCratesDownload(t *testing.T) {
+	for uri, want := range map[string]bool{
+		"https://crates.io/api/v1/crates/ripgrep/${{package.version}}/download": true,
+		"https://crates.io/api/v1/crates/ripgrep/13.0.0/download":               true,
+		"https://crates.io/crates/ripgrep":                                      false,
+		"https://github.com/BurntSushi/ripgrep/archive/13.0.0.tar.gz":           false,
+		"": false,
+	} {
+		assert.Equalf(t, want, isCratesDownload(uri), "isCratesDownload(%s)", uri)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	gotemplate "text/template"
 
 	"golang.org/x/exp/maps"
+	"golang.org/x/sync/errgroup"
 
 	"chainguard.dev/melange/pkg/build"
 
@@ -194,6 +195,8 @@ type GitHubReleaseOptions struct {
 	// hash is used to create graphql queries, maintain a map of associated configs
 	ConfigsByHash map[string]build.Configuration
 	ErrorMessages map[string]string
+	// Concurrency is how many batches of repositories are queried at once, one at a time if unset
+	Concurrency int
 }
 
 type RepoInfo struct {
@@ -278,6 +281,8 @@ func (o GitHubReleaseOptions) getResultsFromTemplate(templateType string, repos
 	batchSize := 15
 	results := make(map[string]NewVersionResults)
 
+	// the batches are queried at once, and parsed in order once they're all back
+	var batches []string
 	for i := 0; i < len(repos); i += batchSize {
 		end := i + batchSize
 		if end > len(repos) {
@@ -288,13 +293,28 @@ func (o GitHubReleaseOptions) getResultsFromTemplate(templateType string, repos
 		requestData := map[string]interface{}{
 			"RepoList": repoBatch,
 		}
-		requestQuery := template(templateType, requestData)
-
-		b, err := o.get(requestQuery)
-		if err != nil {
-			return nil, err
-		}
+		batches = append(batches, template(templateType, requestData))
+	}
+	bodies := make([][]byte, len(batches))
+	limit := o.Concurrency
+	if limit < 1 {
+		limit = 1
+	}
+	var g errgroup.Group
+	g.SetLimit(limit)
+	for i := range batches {
+		i := i
+		g.Go(func() error {
+			b, err := o.get(batches[i])
+			bodies[i] = b
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
 
+	for _, b := range bodies {
 		var resp interface{}
 		switch templateType {
 		case queryReleases:
@@ -305,7 +325,7 @@ func (o GitHubReleaseOptions) getResultsFromTemplate(templateType string, repos
 			return nil, errors.New("unknown template type")
 		}
 
-		err = json.Unmarshal(b, resp)
+		err := json.Unmarshal(b, resp)
 		if err != nil {
 			return nil, err
 		}
@@ -543,6 +563,10 @@ func (o GitHubReleaseOptions) getLatestVersion(packageNameHash string, versionRe
 }
 
 func (o GitHubReleaseOptions) shouldSkipVersion(v string) bool {
+	return isPreRelease(v)
+}
+
+func isPreRelease(v string) bool {
 	invalid := []string{"alpha", "beta", "rc", "pre"}
 	for _, i := range invalid {
 		if strings.Contains(strings.ToLower(v), i) {
@@ -587,46 +611,57 @@ func (o GitHubReleaseOptions) prepareVersion(nameHash, v, id string) (string, er
 		return "", fmt.Errorf("no github update config found for package %s", id)
 	}
 
+	return prepareTag(c.Update, ghm.TagFilter, ghm.StripPrefix, ghm.StripSuffix, v)
+}
+
+// prepareTag turns an upstream tag into a version using the tag filter, prefix and suffix of a monitor and the rules
+// of the update config, returning an empty version for tags that should be ignored
+func prepareTag(u build.Update, tagFilter, stripPrefix, stripSuffix, v string) (string, error) {
 	// the github graphql query filter matches any occurrence, we want to make that more strict and remove any tags that do not START with the filter
-	if ghm.TagFilter != "" {
-		if !strings.HasPrefix(v, ghm.TagFilter) {
+	if tagFilter != "" {
+		if !strings.HasPrefix(v, tagFilter) {
 			return "", nil
 		}
 	}
 
-	if ghm.StripPrefix != "" {
-		v = strings.TrimPrefix(v, ghm.StripPrefix)
+	if stripPrefix != "" {
+		v = strings.TrimPrefix(v, stripPrefix)
 	}
 
-	if ghm.StripSuffix != "" {
-		v = strings.TrimSuffix(v, ghm.StripSuffix)
+	if stripSuffix != "" {
+		v = strings.TrimSuffix(v, stripSuffix)
 	}
 
-	// ignore versions that match a regex pattern in the melange update config
-	if len(c.Update.IgnoreRegexPatterns) > 0 {
-		for _, pattern := range c.Update.IgnoreRegexPatterns {
-			regex, err := regexp.Compile(pattern)
-			if err != nil {
-				return "", errors.Wrapf(err, "failed to compile regex %s", pattern)
-			}
-
-			if regex.MatchString(v) {
-				return "", nil
-			}
-		}
+	if ignore, err := ignoreVersion(u, v); err != nil || ignore {
+		return "", err
 	}
 
-	if c.Update.VersionSeparator != "" {
-		v = strings.ReplaceAll(v, c.Update.VersionSeparator, ".")
+	if u.VersionSeparator != "" {
+		v = strings.ReplaceAll(v, u.VersionSeparator, ".")
 	}
 
-	if o.shouldSkipVersion(v) {
+	if isPreRelease(v) {
 		return "", nil
 	}
 
 	return v, nil
 }
 
+// ignoreVersion reports whether v matches a regex pattern to ignore in the melange update config
+func ignoreVersion(u build.Update, v string) (bool, error) {
+	for _, pattern := range u.IgnoreRegexPatterns {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to compile regex %s", pattern)
+		}
+
+		if regex.MatchString(v) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func template(tmpl string, data interface{}) string {
 	var buf bytes.Buffer
 	t := gotemplate.Must(gotemplate.New("").Parse(tmpl))
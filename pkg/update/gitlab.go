@@ -0,0 +1,169 @@
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+const (
+	defaultGitLabHost = "gitlab.com"
+
+	// the REST API returns 20 results by default, like the GitHub releases query we look further back in case an old
+	// maintenance release was published after the latest version
+	gitLabPerPage = 50
+)
+
+// GitLabService looks up the latest versions of packages whose upstream is a project on gitlab.com or a self-hosted
+// GitLab, configured with an update.gitlab block in their melange config
+type GitLabService struct {
+	Client *http2.RLHTTPClient
+	Logger *log.Logger
+}
+
+type gitLabCommit struct {
+	ID string `json:"id"`
+}
+
+type gitLabRelease struct {
+	TagName         string       `json:"tag_name"`
+	UpcomingRelease bool         `json:"upcoming_release"`
+	Commit          gitLabCommit `json:"commit"`
+}
+
+type gitLabTag struct {
+	Name   string       `json:"name"`
+	Commit gitLabCommit `json:"commit"`
+}
+
+func (s GitLabService) getLatestGitLabVersions(melangePackages map[string]*melange.Packages) (packagesToUpdate map[string]NewVersionResults, errorMessages map[string]string) {
+	packagesToUpdate = make(map[string]NewVersionResults)
+	errorMessages = make(map[string]string)
+
+	for packageName, p := range melangePackages {
+		glm := p.GitLabMonitor
+		if glm == nil {
+			continue
+		}
+
+		s.Logger.Printf("%s: checking gitlab project %s\n", packageName, glm.Identifier)
+
+		tags, err := s.getTags(glm)
+		if err != nil {
+			errorMessages[p.Config.Package.Name] = fmt.Sprintf(
+				"failed getting gitlab versions for package %s, identifier %s: %s",
+				p.Config.Package.Name, glm.Identifier, err.Error(),
+			)
+			continue
+		}
+
+		versions := make(map[string]string)
+		for tag, commit := range tags {
+			v, err := prepareTag(p.Config.Update, glm.TagFilter, glm.StripPrefix, glm.StripSuffix, tag)
+			if err != nil {
+				errorMessages[p.Config.Package.Name] = err.Error()
+				continue
+			}
+			if v == "" {
+				continue
+			}
+			versions[v] = commit
+		}
+
+		semvers, err := createSemverSlice(versions)
+		if err != nil {
+			errorMessages[p.Config.Package.Name] = errors.Wrapf(err, "failed to create a version slice for %s", glm.Identifier).Error()
+			continue
+		}
+		if len(semvers) == 0 {
+			errorMessages[p.Config.Package.Name] = fmt.Sprintf(
+				"no versions found in gitlab for package %s, identifier %s",
+				p.Config.Package.Name, glm.Identifier,
+			)
+			continue
+		}
+
+		latest := findLatestVersion(semvers)
+		packagesToUpdate[p.Config.Package.Name] = NewVersionResults{Version: latest.Original(), Commit: versions[latest.Original()]}
+	}
+	return packagesToUpdate, errorMessages
+}
+
+// getTags returns the commits of the released tags of the project, or of all its tags if the monitor uses tags
+func (s GitLabService) getTags(glm *melange.GitLabMonitor) (map[string]string, error) {
+	tags := make(map[string]string)
+
+	if glm.UseTags {
+		var resp []gitLabTag
+		if err := s.get(glm, "repository/tags", &resp); err != nil {
+			return nil, err
+		}
+		for _, t := range resp {
+			tags[t.Name] = t.Commit.ID
+		}
+		return tags, nil
+	}
+
+	var resp []gitLabRelease
+	if err := s.get(glm, "releases", &resp); err != nil {
+		return nil, err
+	}
+	for _, r := range resp {
+		// upcoming releases are scheduled for the future and not published yet
+		if r.UpcomingRelease {
+			continue
+		}
+		tags[r.TagName] = r.Commit.ID
+	}
+	return tags, nil
+}
+
+func (s GitLabService) get(glm *melange.GitLabMonitor, resource string, v interface{}) error {
+	targetURL := fmt.Sprintf("%s/api/v4/projects/%s/%s?per_page=%d", gitLabBaseURL(glm.Host), url.PathEscape(glm.Identifier), resource, gitLabPerPage)
+	req, err := http.NewRequest("GET", targetURL, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed creating GET request %s", targetURL)
+	}
+
+	// public projects don't need a token, but it raises the rate limit and gives access to private ones
+	if token := os.Getenv("GITLAB_TOKEN"); token != "" {
+		req.Header.Add("PRIVATE-TOKEN", token)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed getting URI %s", targetURL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("non ok http response for URI %s code: %v", targetURL, resp.StatusCode)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "reading gitlab response from %s", targetURL)
+	}
+	return errors.Wrapf(json.Unmarshal(b, v), "unmarshalling gitlab response from %s", targetURL)
+}
+
+// gitLabBaseURL returns the URL of a GitLab instance, which is https unless the host says otherwise
+func gitLabBaseURL(host string) string {
+	if host == "" {
+		host = defaultGitLabHost
+	}
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	return strings.TrimSuffix(host, "/")
+}
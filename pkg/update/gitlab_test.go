@@ -0,0 +1,101 @@
+package update
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+func TestGitLabService_getLatestGitLabVersions(t *testing.T) {
+	t.Setenv("GITLAB_TOKEN", "secret")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/projects/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("PRIVATE-TOKEN"))
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects/gitlab-org%2Fgitlab-runner/releases":
+			_, _ = w.Write([]byte(`[
+  {"tag_name": "v16.1.0", "upcoming_release": true, "commit": {"id": "1111111111111111111111111111111111111111"}},
+  {"tag_name": "v15.11.1", "commit": {"id": "2222222222222222222222222222222222222222"}},
+  {"tag_name": "v16.0.2", "commit": {"id": "3333333333333333333333333333333333333333"}},
+  {"tag_name": "v16.0.1", "commit": {"id": "4444444444444444444444444444444444444444"}}
+]`))
+		case "/api/v4/projects/GNOME%2Flibxml2/repository/tags":
+			_, _ = w.Write([]byte(`[
+  {"name": "v2.11.0-rc1", "commit": {"id": "5555555555555555555555555555555555555555"}},
+  {"name": "v2_10_4", "commit": {"id": "6666666666666666666666666666666666666666"}},
+  {"name": "v2_10_3", "commit": {"id": "7777777777777777777777777777777777777777"}},
+  {"name": "something-else", "commit": {"id": "8888888888888888888888888888888888888888"}}
+]`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	packageConfigs := map[string]*melange.Packages{
+		"gitlab-runner": {
+			Config: build.Configuration{Package: build.Package{Name: "gitlab-runner", Version: "16.0.1"}},
+			GitLabMonitor: &melange.GitLabMonitor{
+				Host:        server.URL,
+				Identifier:  "gitlab-org/gitlab-runner",
+				StripPrefix: "v",
+			},
+		},
+		"libxml2": {
+			Config: build.Configuration{
+				Package: build.Package{Name: "libxml2", Version: "2.10.3"},
+				Update:  build.Update{VersionSeparator: "_"},
+			},
+			GitLabMonitor: &melange.GitLabMonitor{
+				Host:        server.URL,
+				Identifier:  "GNOME/libxml2",
+				StripPrefix: "v",
+				TagFilter:   "v2",
+				UseTags:     true,
+			},
+		},
+		"missing": {
+			Config: build.Configuration{Package: build.Package{Name: "missing", Version: "1.0.0"}},
+			GitLabMonitor: &melange.GitLabMonitor{
+				Host:       server.URL,
+				Identifier: "nobody/missing",
+			},
+		},
+		"github-hosted": {
+			Config: build.Configuration{
+				Package: build.Package{Name: "github-hosted", Version: "1.0.0"},
+				Update:  build.Update{GitHubMonitor: &build.GitHubMonitor{Identifier: "foo/bar"}},
+			},
+		},
+	}
+
+	s := GitLabService{
+		Client: &http2.RLHTTPClient{Client: server.Client(), Ratelimiter: rate.NewLimiter(rate.Inf, 1)},
+		Logger: log.New(log.Writer(), "test: ", log.LstdFlags|log.Lmsgprefix),
+	}
+	latestVersions, errorMessages := s.getLatestGitLabVersions(packageConfigs)
+
+	assert.Equal(t, map[string]NewVersionResults{
+		"gitlab-runner": {Version: "16.0.2", Commit: "3333333333333333333333333333333333333333"},
+		"libxml2":       {Version: "2.10.4", Commit: "6666666666666666666666666666666666666666"},
+	}, latestVersions)
+	require.Len(t, errorMessages, 1)
+	assert.Contains(t, errorMessages["missing"], "404")
+}
+
+func Test_gitLabBaseURL(t *testing.T) {
+	assert.Equal(t, "https://gitlab.com", gitLabBaseURL(""))
+	assert.Equal(t, "https://gitlab.gnome.org", gitLabBaseURL("gitlab.gnome.org"))
+	assert.Equal(t, "http://localhost:8080", gitLabBaseURL("http://localhost:8080/"))
+}
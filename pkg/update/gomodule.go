@@ -0,0 +1,226 @@
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+const goProxyURL = "https://proxy.golang.org"
+
+// GoModuleService looks up the latest versions of Go modules published on the Go module proxy, configured with an
+// update.go block in their melange config
+type GoModuleService struct {
+	Client *http2.RLHTTPClient
+	Logger *log.Logger
+
+	// BaseURL of the Go module proxy, defaults to https://proxy.golang.org
+	BaseURL string
+}
+
+type goModuleInfo struct {
+	Version string `json:"Version"`
+}
+
+func (s GoModuleService) getLatestGoModuleVersions(melangePackages map[string]*melange.Packages) (packagesToUpdate map[string]NewVersionResults, errorMessages map[string]string) {
+	packagesToUpdate = make(map[string]NewVersionResults)
+	errorMessages = make(map[string]string)
+
+	for packageName, p := range melangePackages {
+		gm := p.GoModuleMonitor
+		if gm == nil {
+			continue
+		}
+
+		s.Logger.Printf("%s: checking go module %s\n", packageName, gm.Identifier)
+
+		versions, err := s.getModuleVersions(gm.Identifier)
+		if err != nil {
+			errorMessages[p.Config.Package.Name] = fmt.Sprintf(
+				"failed getting go module versions for package %s, module %s: %s",
+				p.Config.Package.Name, gm.Identifier, err.Error(),
+			)
+			continue
+		}
+
+		latest, err := latestGoModuleVersion(p, versions)
+		if err != nil {
+			errorMessages[p.Config.Package.Name] = err.Error()
+			continue
+		}
+		if latest.Version == "" {
+			errorMessages[p.Config.Package.Name] = fmt.Sprintf(
+				"no versions found in the go module proxy for package %s, module %s",
+				p.Config.Package.Name, gm.Identifier,
+			)
+			continue
+		}
+		packagesToUpdate[p.Config.Package.Name] = latest
+	}
+	return packagesToUpdate, errorMessages
+}
+
+// latestGoModuleVersion returns the latest tagged version of the module that isn't a pre-release or filtered out by
+// the update config, without its v prefix and +incompatible suffix. Pseudo-versions aren't releases, so a module that
+// only has those is an error.
+func latestGoModuleVersion(p *melange.Packages, versions []string) (NewVersionResults, error) {
+	var latest, pseudo string
+	for _, v := range versions {
+		if module.IsPseudoVersion(v) {
+			pseudo = v
+			continue
+		}
+		if !semver.IsValid(v) || semver.Prerelease(v) != "" {
+			continue
+		}
+		ignore, err := ignoreVersion(p.Config.Update, goModuleVersion(v))
+		if err != nil {
+			return NewVersionResults{}, err
+		}
+		if ignore {
+			continue
+		}
+		if latest == "" || semver.Compare(v, latest) > 0 {
+			latest = v
+		}
+	}
+	if latest == "" {
+		if pseudo != "" {
+			return NewVersionResults{}, fmt.Errorf(
+				"go module %s of package %s has no tagged versions, only the pseudo-version %s",
+				p.GoModuleMonitor.Identifier, p.Config.Package.Name, pseudo,
+			)
+		}
+		return NewVersionResults{}, nil
+	}
+	return NewVersionResults{Version: goModuleVersion(latest)}, nil
+}
+
+// goModuleVersion returns the package version of a module version, e.g. 2.0.0 for v2.0.0+incompatible
+func goModuleVersion(v string) string {
+	return strings.TrimPrefix(strings.TrimSuffix(v, "+incompatible"), "v")
+}
+
+// getModuleVersions returns the versions of the module, and of the later major versions of it published under a major
+// version suffix of its path, e.g. github.com/sigstore/cosign/v2 for github.com/sigstore/cosign. The proxy only lists
+// tagged versions, so for a module without any the pseudo-version of its latest commit is returned.
+func (s GoModuleService) getModuleVersions(modulePath string) ([]string, error) {
+	versions, err := s.listVersions(modulePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		info, err := s.getLatest(modulePath)
+		if err != nil {
+			return nil, err
+		}
+		if info != nil {
+			versions = append(versions, info.Version)
+		}
+	}
+
+	for next := nextMajorPath(modulePath); next != ""; next = nextMajorPath(next) {
+		v, err := s.listVersions(next)
+		if err != nil {
+			return nil, err
+		}
+		if len(v) == 0 {
+			break
+		}
+		versions = append(versions, v...)
+	}
+	return versions, nil
+}
+
+// nextMajorPath returns the path of the next major version of a module, e.g. example.com/foo/v3 for example.com/foo/v2
+// and gopkg.in/yaml.v4 for gopkg.in/yaml.v3, or an empty string if the path isn't valid.
+func nextMajorPath(modulePath string) string {
+	prefix, pathMajor, ok := module.SplitPathVersion(modulePath)
+	if !ok {
+		return ""
+	}
+	major := 1
+	if pathMajor != "" {
+		n, err := strconv.Atoi(strings.TrimLeft(pathMajor, "/.v"))
+		if err != nil {
+			return ""
+		}
+		major = n
+	}
+	if strings.HasPrefix(modulePath, "gopkg.in/") {
+		return fmt.Sprintf("%s.v%d", prefix, major+1)
+	}
+	return fmt.Sprintf("%s/v%d", prefix, major+1)
+}
+
+// listVersions returns the tagged versions of the module the proxy knows of, none if it doesn't know the module
+func (s GoModuleService) listVersions(modulePath string) ([]string, error) {
+	b, err := s.get(modulePath, "@v/list")
+	if err != nil || b == nil {
+		return nil, err
+	}
+	return strings.Fields(string(b)), nil
+}
+
+// getLatest returns the latest version of the module, which is a pseudo-version if it has no tagged versions, or nil
+// if the proxy doesn't know the module
+func (s GoModuleService) getLatest(modulePath string) (*goModuleInfo, error) {
+	b, err := s.get(modulePath, "@latest")
+	if err != nil || b == nil {
+		return nil, err
+	}
+	info := &goModuleInfo{}
+	if err := json.Unmarshal(b, info); err != nil {
+		return nil, errors.Wrapf(err, "unmarshalling go module proxy response for %s", modulePath)
+	}
+	return info, nil
+}
+
+// get returns the body of an endpoint of the module in the proxy, nil if the proxy doesn't know the module
+func (s GoModuleService) get(modulePath, endpoint string) ([]byte, error) {
+	escaped, err := module.EscapePath(modulePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid go module path %q", modulePath)
+	}
+	baseURL := s.BaseURL
+	if baseURL == "" {
+		baseURL = goProxyURL
+	}
+	targetURL := fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(baseURL, "/"), escaped, endpoint)
+	req, err := http.NewRequest("GET", targetURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating GET request %s", targetURL)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting URI %s", targetURL)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		// the proxy answers 404 or 410 for modules and major versions that don't exist
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("non ok http response for URI %s code: %v", targetURL, resp.StatusCode)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading go module proxy response from %s", targetURL)
+	}
+	return b, nil
+}
@@ -0,0 +1,127 @@
+package update
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+func TestGoModuleService_getLatestGoModuleVersions(t *testing.T) {
+	responses := map[string]string{
+		"/github.com/!burnt!sushi/toml/@v/list": "v1.2.0\nv1.3.0\nv1.3.1\nv1.4.0-rc.1\n",
+		"/example.com/cli/@v/list":              "v1.0.0\nv1.9.2\n",
+		"/example.com/cli/v2/@v/list":           "v2.0.0\nv2.1.0\n",
+		"/example.com/cli/v3/@v/list":           "v3.0.0-beta.1\nv3.0.0\n",
+		"/example.com/legacy/@v/list":           "v1.0.0\nv2.0.0+incompatible\n",
+		"/example.com/untagged/@v/list":         "",
+		"/example.com/untagged/@latest":         `{"Version":"v0.0.0-20230515182324-679f6de74032","Time":"2023-05-15T18:23:24Z"}`,
+		"/gopkg.in/yaml.v2/@v/list":             "v2.4.0\n",
+		"/gopkg.in/yaml.v3/@v/list":             "v3.0.0\nv3.0.1\n",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.Error(w, "not found", http.StatusGone)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	s := GoModuleService{
+		Client:  &http2.RLHTTPClient{Client: server.Client(), Ratelimiter: rate.NewLimiter(rate.Inf, 1)},
+		Logger:  log.New(log.Writer(), "test: ", log.LstdFlags|log.Lmsgprefix),
+		BaseURL: server.URL,
+	}
+
+	tests := []struct {
+		name    string
+		module  string
+		update  build.Update
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "escapes the path and skips pre-releases",
+			module: "github.com/BurntSushi/toml",
+			want:   "1.3.1",
+		},
+		{
+			name:   "follows major version suffixes",
+			module: "example.com/cli",
+			want:   "3.0.0",
+		},
+		{
+			name:   "follows major version suffixes from a later major",
+			module: "example.com/cli/v2",
+			want:   "3.0.0",
+		},
+		{
+			name:   "ignore regex patterns",
+			module: "example.com/cli/v2",
+			update: build.Update{IgnoreRegexPatterns: []string{`^3\.`}},
+			want:   "2.1.0",
+		},
+		{
+			name:   "incompatible versions",
+			module: "example.com/legacy",
+			want:   "2.0.0",
+		},
+		{
+			name:   "gopkg.in",
+			module: "gopkg.in/yaml.v2",
+			want:   "3.0.1",
+		},
+		{
+			name:    "only pseudo-versions",
+			module:  "example.com/untagged",
+			wantErr: "only the pseudo-version v0.0.0-20230515182324-679f6de74032",
+		},
+		{
+			name:    "unknown module",
+			module:  "example.com/nope",
+			wantErr: "no versions found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packageConfigs := map[string]*melange.Packages{
+				"foo": {
+					Config: build.Configuration{
+						Package: build.Package{Name: "foo", Version: "1.0.0"},
+						Update:  tt.update,
+					},
+					GoModuleMonitor: &melange.GoModuleMonitor{Identifier: tt.module},
+				},
+			}
+
+			latestVersions, errorMessages := s.getLatestGoModuleVersions(packageConfigs)
+			if tt.wantErr != "" {
+				assert.Empty(t, latestVersions)
+				assert.Contains(t, errorMessages["foo"], tt.wantErr)
+				return
+			}
+			assert.Empty(t, errorMessages)
+			assert.Equal(t, NewVersionResults{Version: tt.want}, latestVersions["foo"])
+		})
+	}
+}
+
+func Test_nextMajorPath(t *testing.T) {
+	for modulePath, want := range map[string]string{
+		"github.com/sigstore/cosign":    "github.com/sigstore/cosign/v2",
+		"github.com/sigstore/cosign/v2": "github.com/sigstore/cosign/v3",
+		"gopkg.in/yaml.v3":              "gopkg.in/yaml.v4",
+		"example.com/foo/v1":            "",
+	} {
+		assert.Equalf(t, want, nextMajorPath(modulePath), "nextMajorPath(%s)", modulePath)
+	}
+}
@@ -0,0 +1,147 @@
+package update
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"golang.org/x/exp/maps"
+
+	"github.com/wolfi-dev/wolfictl/pkg/groups"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+// groupUpdates replaces the updates of the members of the package groups defined in dir that are updated together
+// with a single update of each group, named after it like @texlive, so they're proposed in one pull request. Packages
+// updated manually are left alone, as they get an issue rather than a pull request.
+func (o *Options) groupUpdates(dir string, updates map[string]NewVersionResults) (map[string]NewVersionResults, error) {
+	g, err := groups.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	grouped := make(map[string]string)
+	for _, name := range g.Names() {
+		if !g[name].UpdateTogether {
+			continue
+		}
+		members, err := g.ExpandExisting([]string{groups.Prefix + name}, groups.ConfigExists(dir))
+		if err != nil {
+			return nil, err
+		}
+
+		group := NewVersionResults{Members: make(map[string]NewVersionResults)}
+		for _, m := range members {
+			v, ok := updates[m]
+			if !ok {
+				continue
+			}
+			if pc, ok := o.PackageConfigs[m]; ok && pc.Config.Update.Manual {
+				continue
+			}
+			if other, ok := grouped[m]; ok {
+				o.Logger.Printf("%s is updated with package group %s, not %s", m, other, name)
+				continue
+			}
+			grouped[m] = name
+			group.Members[m] = v
+			delete(updates, m)
+		}
+		if len(group.Members) == 0 {
+			continue
+		}
+		group.Version = groupVersion(group.Members)
+		updates[groups.Prefix+name] = group
+		o.Logger.Printf("updating %d packages of group %s together: %s", len(group.Members), name, strings.Join(sortedMembers(group), ", "))
+	}
+	return updates, nil
+}
+
+// groupVersion is the version of the update of a group, in the title of its pull request: the new version of its
+// members if they share one, e.g. the year of a texlive release, or else a digest of their new versions, so a pull
+// request for the same updates can be told apart from one that needs replacing.
+func groupVersion(members map[string]NewVersionResults) string {
+	names := maps.Keys(members)
+	sort.Strings(names)
+	shared := members[names[0]].Version
+	h := sha256.New()
+	for _, name := range names {
+		if members[name].Version != shared {
+			shared = ""
+		}
+		fmt.Fprintf(h, "%s=%s\n", name, members[name].Version)
+	}
+	if shared != "" {
+		return shared
+	}
+	return "updates-" + hex.EncodeToString(h.Sum(nil))[:8]
+}
+
+func sortedMembers(group NewVersionResults) []string {
+	names := maps.Keys(group.Members)
+	sort.Strings(names)
+	return names
+}
+
+// updateGroup bumps every member of a group updated together on one branch, and proposes them in one pull request.
+// The group is only proposed if all of them could be bumped, so it's never merged half updated.
+func (o *Options) updateGroup(repo *git.Repository, groupName string, update NewVersionResults, ref plumbing.ReferenceName) (cause, errorMessage string, err error) {
+	discard := func(cause, errorMessage string) (string, string, error) {
+		wt, err := repo.Worktree()
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get the worktree: %w", err)
+		}
+		if err := wt.Reset(&git.ResetOptions{Mode: git.HardReset}); err != nil {
+			return "", "", fmt.Errorf("failed to discard the changes of package group %s: %w", groupName, err)
+		}
+		return cause, fmt.Sprintf("package group %s not updated: %s", groupName, errorMessage), nil
+	}
+
+	members := sortedMembers(update)
+	for _, name := range members {
+		cause, errorMessage, err := o.bumpPackage(repo, name, update.Members[name])
+		if err != nil {
+			return "", "", err
+		}
+		if errorMessage != "" {
+			return discard(cause, errorMessage)
+		}
+	}
+	if errorMessage := o.validatePackages(groupName, members); errorMessage != "" {
+		return discard(FailureValidation, errorMessage)
+	}
+	return o.propose(repo, ref, groupName, update)
+}
+
+// pullRequestBody describes the changes of a pull request below the image: the packages of a group and their new
+// versions, the vulnerabilities the update fixes, the checksums recomputed for the sources of the new versions, and the
+// upstream release notes of the package, or of every package of the group
+func (o *Options) pullRequestBody(packageName string, newVersion NewVersionResults) string {
+	security := securityReport(o.fixedVulnerabilities(packageName, newVersion))
+	if len(newVersion.Members) == 0 {
+		return security + checksumReport(o.sourceChecksums[packageName]) + o.validations[packageName] + o.releaseNotes(packageName, newVersion, "Upstream changes")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\nUpdates the packages of group %s together:\n\n", strings.TrimPrefix(packageName, groups.Prefix))
+	var checksums []melange.SourceChecksum
+	for _, name := range sortedMembers(newVersion) {
+		current := ""
+		if pc, ok := o.PackageConfigs[name]; ok {
+			current = pc.Config.Package.Version
+		}
+		fmt.Fprintf(&b, "- %s: %s → %s\n", name, current, newVersion.Members[name].Version)
+		checksums = append(checksums, o.sourceChecksums[name]...)
+	}
+	b.WriteString(security)
+	b.WriteString(checksumReport(checksums))
+	b.WriteString(o.validations[packageName])
+	for _, name := range sortedMembers(newVersion) {
+		b.WriteString(o.releaseNotes(name, newVersion.Members[name], "Upstream changes of "+name))
+	}
+	return b.String()
+}
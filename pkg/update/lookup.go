@@ -0,0 +1,214 @@
+package update
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+// DefaultLookupConcurrency is how many packages every datasource checks at once by default
+const DefaultLookupConcurrency = 4
+
+// datasourceLookup checks the latest versions of packages with one datasource
+type datasourceLookup struct {
+	cause   string
+	enabled bool
+	// batched lookups check all the packages at once, like the GraphQL queries of GitHub, rather than one by one
+	batched bool
+	lookup  func(map[string]*melange.Packages) (map[string]NewVersionResults, map[string]string, error)
+}
+
+type lookupResult struct {
+	found         map[string]NewVersionResults
+	errorMessages map[string]string
+	err           error
+}
+
+// datasourceLookups returns the lookups of all the datasources, in the order their results are recorded, so a
+// package found by several of them gets the version of the last one like before they ran concurrently
+func (o *Options) datasourceLookups() []datasourceLookup {
+	clients := make(map[string]*http2.RLHTTPClient)
+	for _, cause := range []string{
+		FailureReleaseMonitorLookup, FailurePyPILookup, FailureCratesLookup, FailureGoModuleLookup, FailureNpmLookup,
+		FailureRubyGemsLookup, FailureScrapeLookup,
+	} {
+		clients[cause] = o.datasourceClient(cause)
+	}
+
+	return []datasourceLookup{{
+		cause:   FailureGitHubLookup,
+		enabled: o.GithubReleaseQuery,
+		batched: true,
+		lookup: func(packages map[string]*melange.Packages) (map[string]NewVersionResults, map[string]string, error) {
+			g := NewGitHubReleaseOptions(packages, o.GitHubHTTPClient)
+			g.Concurrency = o.Concurrency
+			v, errorMessages, err := g.getLatestGitHubVersions()
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed getting github releases: %w", err)
+			}
+			return v, errorMessages, nil
+		},
+	}, {
+		cause:   FailureReleaseMonitorLookup,
+		enabled: o.ReleaseMonitoringQuery,
+		lookup: func(packages map[string]*melange.Packages) (map[string]NewVersionResults, map[string]string, error) {
+			m := MonitorService{Client: clients[FailureReleaseMonitorLookup], Logger: o.Logger}
+			v, errorMessages := m.getLatestReleaseMonitorVersions(packages)
+			return v, errorMessages, nil
+		},
+	}, {
+		cause:   FailureGitLabLookup,
+		enabled: o.GitLabReleaseQuery,
+		lookup: func(packages map[string]*melange.Packages) (map[string]NewVersionResults, map[string]string, error) {
+			s := GitLabService{Client: o.GitLabHTTPClient, Logger: o.Logger}
+			v, errorMessages := s.getLatestGitLabVersions(packages)
+			return v, errorMessages, nil
+		},
+	}, {
+		cause:   FailurePyPILookup,
+		enabled: o.PyPIQuery,
+		lookup: func(packages map[string]*melange.Packages) (map[string]NewVersionResults, map[string]string, error) {
+			s := PyPIService{Client: clients[FailurePyPILookup], Logger: o.Logger}
+			v, errorMessages := s.getLatestPyPIVersions(packages)
+			return v, errorMessages, nil
+		},
+	}, {
+		cause:   FailureCratesLookup,
+		enabled: o.CratesQuery,
+		lookup: func(packages map[string]*melange.Packages) (map[string]NewVersionResults, map[string]string, error) {
+			s := CratesService{Client: clients[FailureCratesLookup], Logger: o.Logger}
+			v, errorMessages := s.getLatestCratesVersions(packages)
+			return v, errorMessages, nil
+		},
+	}, {
+		cause:   FailureGoModuleLookup,
+		enabled: o.GoModuleQuery,
+		lookup: func(packages map[string]*melange.Packages) (map[string]NewVersionResults, map[string]string, error) {
+			s := GoModuleService{Client: clients[FailureGoModuleLookup], Logger: o.Logger}
+			v, errorMessages := s.getLatestGoModuleVersions(packages)
+			return v, errorMessages, nil
+		},
+	}, {
+		cause:   FailureNpmLookup,
+		enabled: o.NpmQuery,
+		lookup: func(packages map[string]*melange.Packages) (map[string]NewVersionResults, map[string]string, error) {
+			s := NpmService{Client: clients[FailureNpmLookup], Logger: o.Logger}
+			v, errorMessages := s.getLatestNpmVersions(packages)
+			return v, errorMessages, nil
+		},
+	}, {
+		cause:   FailureRubyGemsLookup,
+		enabled: o.RubyGemsQuery,
+		lookup: func(packages map[string]*melange.Packages) (map[string]NewVersionResults, map[string]string, error) {
+			s := RubyGemsService{Client: clients[FailureRubyGemsLookup], Logger: o.Logger}
+			v, errorMessages := s.getLatestRubyGemsVersions(packages)
+			return v, errorMessages, nil
+		},
+	}, {
+		cause:   FailureScrapeLookup,
+		enabled: o.ScrapeQuery,
+		lookup: func(packages map[string]*melange.Packages) (map[string]NewVersionResults, map[string]string, error) {
+			s := ScrapeService{Client: clients[FailureScrapeLookup], Logger: o.Logger}
+			v, errorMessages := s.getLatestScrapeVersions(packages)
+			return v, errorMessages, nil
+		},
+	}}
+}
+
+// lookupLatestVersions checks the packages with all the enabled datasources at once, each with its own rate limit,
+// and records their results in the order of datasourceLookups
+func (o *Options) lookupLatestVersions(latestVersions map[string]NewVersionResults) error {
+	lookups := o.datasourceLookups()
+	results := make([]lookupResult, len(lookups))
+	var wg sync.WaitGroup
+	for i := range lookups {
+		if !lookups[i].enabled {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = o.runLookup(lookups[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, l := range lookups {
+		if !l.enabled {
+			continue
+		}
+		if results[i].err != nil {
+			return results[i].err
+		}
+		o.recordLookup(l.cause, latestVersions, results[i].found, results[i].errorMessages)
+	}
+	return nil
+}
+
+// runLookup splits the packages into Concurrency shares checked at once, unless the lookup is batched
+func (o *Options) runLookup(l datasourceLookup) lookupResult {
+	if l.batched {
+		found, errorMessages, err := l.lookup(o.PackageConfigs)
+		return lookupResult{found: found, errorMessages: errorMessages, err: err}
+	}
+
+	shares := splitPackages(o.PackageConfigs, o.Concurrency)
+	var (
+		mu     sync.Mutex
+		result = lookupResult{
+			found:         make(map[string]NewVersionResults),
+			errorMessages: make(map[string]string),
+		}
+		g errgroup.Group
+	)
+	for _, share := range shares {
+		share := share
+		g.Go(func() error {
+			found, errorMessages, err := l.lookup(share)
+			mu.Lock()
+			defer mu.Unlock()
+			maps.Copy(result.found, found)
+			maps.Copy(result.errorMessages, errorMessages)
+			return err
+		})
+	}
+	result.err = g.Wait()
+	return result
+}
+
+// splitPackages deals the packages into at most n shares of about the same size
+func splitPackages(packages map[string]*melange.Packages, n int) []map[string]*melange.Packages {
+	if n < 1 {
+		n = 1
+	}
+	if n > len(packages) {
+		n = len(packages)
+	}
+	names := maps.Keys(packages)
+	sort.Strings(names)
+	shares := make([]map[string]*melange.Packages, n)
+	for i, name := range names {
+		if shares[i%n] == nil {
+			shares[i%n] = make(map[string]*melange.Packages)
+		}
+		shares[i%n][name] = packages[name]
+	}
+	return shares
+}
+
+// datasourceClient returns the client a datasource sends its requests with: a copy of Client with a rate limiter of
+// its own if DatasourceRateLimits has one for it, so datasources don't wait for each other, or else Client itself
+func (o *Options) datasourceClient(cause string) *http2.RLHTTPClient {
+	limit, ok := o.DatasourceRateLimits[datasourceName(cause)]
+	if !ok || o.Client == nil {
+		return o.Client
+	}
+	return o.Client.WithRateLimit(rate.NewLimiter(limit, 1))
+}
@@ -0,0 +1,85 @@
+package update
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+func TestSplitPackages(t *testing.T) {
+	packages := map[string]*melange.Packages{"a": {}, "b": {}, "c": {}, "d": {}, "e": {}}
+
+	shares := splitPackages(packages, 2)
+	require.Len(t, shares, 2)
+	assert.Len(t, shares[0], 3)
+	assert.Len(t, shares[1], 2)
+
+	assert.Len(t, splitPackages(packages, 0), 1, "no concurrency should check the packages one share")
+	assert.Len(t, splitPackages(packages, 10), 5, "there should be no empty shares")
+}
+
+func TestOptions_runLookup(t *testing.T) {
+	o := Options{
+		Concurrency:    3,
+		PackageConfigs: map[string]*melange.Packages{"a": {}, "b": {}, "c": {}, "d": {}, "e": {}, "f": {}},
+	}
+
+	var (
+		running, most atomic.Int32
+		mu            sync.Mutex
+		seen          []string
+	)
+	result := o.runLookup(datasourceLookup{
+		lookup: func(packages map[string]*melange.Packages) (map[string]NewVersionResults, map[string]string, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := most.Load()
+				if n <= m || most.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+
+			found := make(map[string]NewVersionResults)
+			errorMessages := make(map[string]string)
+			for name := range packages {
+				mu.Lock()
+				seen = append(seen, name)
+				mu.Unlock()
+				if name == "c" {
+					errorMessages[name] = "not found"
+					continue
+				}
+				found[name] = NewVersionResults{Version: "1.0.0"}
+			}
+			return found, errorMessages, nil
+		},
+	})
+
+	require.NoError(t, result.err)
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d", "e", "f"}, seen, "every package should be checked once")
+	assert.Len(t, result.found, 5)
+	assert.Equal(t, map[string]string{"c": "not found"}, result.errorMessages)
+	assert.Equal(t, int32(3), most.Load(), "the shares should be checked at once")
+}
+
+func TestOptions_datasourceClient(t *testing.T) {
+	o := Options{
+		Client:               &http2.RLHTTPClient{Ratelimiter: rate.NewLimiter(rate.Every(5*time.Second), 1)},
+		DatasourceRateLimits: map[string]rate.Limit{"pypi": 10},
+	}
+
+	pypi := o.datasourceClient(FailurePyPILookup)
+	assert.NotSame(t, o.Client.Ratelimiter, pypi.Ratelimiter, "pypi should have a rate limit of its own")
+	assert.Equal(t, rate.Limit(10), pypi.Ratelimiter.Limit())
+	assert.Same(t, o.Client, o.datasourceClient(FailureNpmLookup), "npm should share the rate limit of Client")
+}
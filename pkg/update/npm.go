@@ -0,0 +1,210 @@
+package update
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+const (
+	npmURL = "https://registry.npmjs.org"
+
+	// abbreviated package metadata, which has all that's needed to install a package and is much smaller
+	npmAbbreviatedMetadata = "application/vnd.npm.install-v1+json"
+)
+
+// NpmService looks up the latest versions of packages published on the npm registry, configured with an update.npm
+// block in their melange config
+type NpmService struct {
+	Client *http2.RLHTTPClient
+	Logger *log.Logger
+
+	// BaseURL of the npm registry, defaults to https://registry.npmjs.org
+	BaseURL string
+}
+
+type npmDist struct {
+	Tarball   string `json:"tarball"`
+	Integrity string `json:"integrity"`
+}
+
+type npmVersion struct {
+	Dist       npmDist `json:"dist"`
+	Deprecated string  `json:"deprecated"`
+}
+
+type npmPackage struct {
+	DistTags map[string]string     `json:"dist-tags"`
+	Versions map[string]npmVersion `json:"versions"`
+}
+
+func (s NpmService) getLatestNpmVersions(melangePackages map[string]*melange.Packages) (packagesToUpdate map[string]NewVersionResults, errorMessages map[string]string) {
+	packagesToUpdate = make(map[string]NewVersionResults)
+	errorMessages = make(map[string]string)
+
+	for packageName, p := range melangePackages {
+		nm := p.NpmMonitor
+		if nm == nil {
+			continue
+		}
+
+		s.Logger.Printf("%s: checking npm package %s\n", packageName, nm.Identifier)
+
+		pkg, err := s.getPackage(nm.Identifier)
+		if err != nil {
+			errorMessages[p.Config.Package.Name] = fmt.Sprintf(
+				"failed getting npm versions for package %s, identifier %s: %s",
+				p.Config.Package.Name, nm.Identifier, err.Error(),
+			)
+			continue
+		}
+
+		latest, err := latestNpmVersion(p, pkg)
+		if err != nil {
+			errorMessages[p.Config.Package.Name] = err.Error()
+			continue
+		}
+		if latest.Version == "" {
+			errorMessages[p.Config.Package.Name] = fmt.Sprintf(
+				"no versions found in npm for package %s, identifier %s",
+				p.Config.Package.Name, nm.Identifier,
+			)
+			continue
+		}
+		packagesToUpdate[p.Config.Package.Name] = latest
+	}
+	return packagesToUpdate, errorMessages
+}
+
+// latestNpmVersion returns the version of the package the latest dist-tag points at, or the greatest version before
+// it if that one is filtered out by the update config. Deprecated versions are skipped, and so are pre-releases unless
+// the config includes them, in which case versions after the latest dist-tag are considered too. If the config fetches
+// the package from the npm registry, the tarball of that version is returned with it.
+func latestNpmVersion(p *melange.Packages, pkg *npmPackage) (NewVersionResults, error) {
+	nm := p.NpmMonitor
+
+	var tagged *version.Version
+	if t, ok := pkg.DistTags["latest"]; ok {
+		tagged, _ = version.NewSemver(t)
+	}
+
+	var latest *version.Version
+	var dist npmDist
+	for v, meta := range pkg.Versions {
+		if meta.Deprecated != "" {
+			continue
+		}
+		current, err := version.NewSemver(v)
+		if err != nil {
+			continue
+		}
+		if current.Prerelease() != "" && !nm.PreReleases {
+			continue
+		}
+		if !nm.PreReleases && tagged != nil && current.GreaterThan(tagged) {
+			// e.g. a new major published under another dist-tag
+			continue
+		}
+		ignore, err := ignoreVersion(p.Config.Update, v)
+		if err != nil {
+			return NewVersionResults{}, err
+		}
+		if ignore {
+			continue
+		}
+		if latest == nil || current.GreaterThan(latest) {
+			latest, dist = current, meta.Dist
+		}
+	}
+	if latest == nil {
+		return NewVersionResults{}, nil
+	}
+
+	result := NewVersionResults{Version: latest.Original()}
+	if isNpmTarball(fetchURI(p.Config)) && dist.Tarball != "" {
+		sha512, err := integritySHA512(dist.Integrity)
+		if err != nil {
+			return NewVersionResults{}, errors.Wrapf(err, "npm tarball of package %s version %s", p.Config.Package.Name, latest.Original())
+		}
+		result.SourceURL = dist.Tarball
+		result.SourceSHA512 = sha512
+	}
+	return result, nil
+}
+
+// integritySHA512 returns the hex encoded digest of a subresource integrity string like sha512-<base64>, as found in
+// the dist of npm versions, or an empty string if it isn't a sha512 one
+func integritySHA512(integrity string) (string, error) {
+	for _, i := range strings.Fields(integrity) {
+		digest, ok := strings.CutPrefix(i, "sha512-")
+		if !ok {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(digest)
+		if err != nil {
+			return "", errors.Wrapf(err, "decoding integrity %q", i)
+		}
+		return hex.EncodeToString(b), nil
+	}
+	return "", nil
+}
+
+// isNpmTarball reports whether uri is a tarball of the npm registry, i.e.
+// https://registry.npmjs.org/<name>/-/<name>-<version>.tgz
+func isNpmTarball(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	switch u.Host {
+	case "registry.npmjs.org", "registry.yarnpkg.com":
+		return strings.Contains(u.Path, "/-/")
+	}
+	return false
+}
+
+func (s NpmService) getPackage(identifier string) (*npmPackage, error) {
+	baseURL := s.BaseURL
+	if baseURL == "" {
+		baseURL = npmURL
+	}
+	// the slash of scoped packages, e.g. @babel/core, is escaped
+	targetURL := fmt.Sprintf("%s/%s", strings.TrimSuffix(baseURL, "/"), url.PathEscape(identifier))
+	req, err := http.NewRequest("GET", targetURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating GET request %s", targetURL)
+	}
+	req.Header.Set("Accept", npmAbbreviatedMetadata)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting URI %s", targetURL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non ok http response for URI %s code: %v", targetURL, resp.StatusCode)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading npm response from %s", targetURL)
+	}
+	pkg := &npmPackage{}
+	if err := json.Unmarshal(b, pkg); err != nil {
+		return nil, errors.Wrapf(err, "unmarshalling npm response from %s", targetURL)
+	}
+	return pkg, nil
+}
@@ -0,0 +1,140 @@
+package update
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+func TestNpmService_getLatestNpmVersions(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "npm", "typescript.json"))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/typescript" && r.URL.EscapedPath() != "/@microsoft%2Ftypescript" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	s := NpmService{
+		Client:  &http2.RLHTTPClient{Client: server.Client(), Ratelimiter: rate.NewLimiter(rate.Inf, 1)},
+		Logger:  log.New(log.Writer(), "test: ", log.LstdFlags|log.Lmsgprefix),
+		BaseURL: server.URL,
+	}
+
+	tarball := []build.Pipeline{{
+		Uses: "fetch",
+		With: map[string]string{"uri": "https://registry.npmjs.org/typescript/-/typescript-${{package.version}}.tgz"},
+	}}
+
+	tests := []struct {
+		name     string
+		monitor  melange.NpmMonitor
+		update   build.Update
+		pipeline []build.Pipeline
+		want     NewVersionResults
+		wantErr  string
+	}{
+		{
+			name:     "latest dist-tag",
+			monitor:  melange.NpmMonitor{Identifier: "typescript"},
+			pipeline: tarball,
+			want: NewVersionResults{
+				Version:      "5.1.3",
+				SourceURL:    "https://registry.npmjs.org/typescript/-/typescript-5.1.3.tgz",
+				SourceSHA512: "59b52a0bab7048b2b89ce1dc9b3d45cca155e0c01f2075dade18dfb896a18cf687fdbbc60e80b8faed75ac20fefca07059bcef449bf18e8211d6337a9d62b009",
+			},
+		},
+		{
+			name:     "scoped package",
+			monitor:  melange.NpmMonitor{Identifier: "@microsoft/typescript"},
+			pipeline: tarball,
+			want: NewVersionResults{
+				Version:      "5.1.3",
+				SourceURL:    "https://registry.npmjs.org/typescript/-/typescript-5.1.3.tgz",
+				SourceSHA512: "59b52a0bab7048b2b89ce1dc9b3d45cca155e0c01f2075dade18dfb896a18cf687fdbbc60e80b8faed75ac20fefca07059bcef449bf18e8211d6337a9d62b009",
+			},
+		},
+		{
+			name:    "pre-releases and other dist-tags",
+			monitor: melange.NpmMonitor{Identifier: "typescript", PreReleases: true},
+			want:    NewVersionResults{Version: "6.0.0"},
+		},
+		{
+			name:    "ignore regex patterns skip deprecated versions",
+			monitor: melange.NpmMonitor{Identifier: "typescript"},
+			update:  build.Update{IgnoreRegexPatterns: []string{`^5\.1\.3$`}},
+			want:    NewVersionResults{Version: "5.0.4"},
+		},
+		{
+			name:    "sources from elsewhere",
+			monitor: melange.NpmMonitor{Identifier: "typescript"},
+			pipeline: []build.Pipeline{{
+				Uses: "fetch",
+				With: map[string]string{"uri": "https://github.com/microsoft/TypeScript/archive/refs/tags/v${{package.version}}.tar.gz"},
+			}},
+			want: NewVersionResults{Version: "5.1.3"},
+		},
+		{
+			name:    "everything ignored",
+			monitor: melange.NpmMonitor{Identifier: "typescript"},
+			update:  build.Update{IgnoreRegexPatterns: []string{`^5\.`}},
+			wantErr: "no versions found",
+		},
+		{
+			name:    "unknown package",
+			monitor: melange.NpmMonitor{Identifier: "nope"},
+			wantErr: "404",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := tt.monitor
+			packageConfigs := map[string]*melange.Packages{
+				"typescript": {
+					Config: build.Configuration{
+						Package:  build.Package{Name: "typescript", Version: "5.0.4"},
+						Update:   tt.update,
+						Pipeline: tt.pipeline,
+					},
+					NpmMonitor: &monitor,
+				},
+			}
+
+			latestVersions, errorMessages := s.getLatestNpmVersions(packageConfigs)
+			if tt.wantErr != "" {
+				assert.Empty(t, latestVersions)
+				assert.Contains(t, errorMessages["typescript"], tt.wantErr)
+				return
+			}
+			assert.Empty(t, errorMessages)
+			assert.Equal(t, tt.want, latestVersions["typescript"])
+		})
+	}
+}
+
+func Test_isNpmTarball(t *testing.T) {
+	for uri, want := range map[string]bool{
+		"https://registry.npmjs.org/typescript/-/typescript-${{package.version}}.tgz": true,
+		"https://registry.npmjs.org/@angular/cli/-/cli-16.1.0.tgz":                    true,
+		"https://registry.npmjs.org/typescript":                                       false,
+		"https://github.com/microsoft/TypeScript/archive/v5.1.3.tar.gz":               false,
+		"": false,
+	} {
+		assert.Equalf(t, want, isNpmTarball(uri), "isNpmTarball(%s)", uri)
+	}
+}
@@ -111,7 +111,7 @@ func (o *PackageOptions) UpdatePackageCmd() error {
 	uo.UseGitSign = o.UseGitSign
 
 	// let's work on a branch when updating package versions, so we can create a PR from that branch later
-	ref, err := uo.createBranch(repo)
+	ref, err := uo.createBranch(repo, o.PackageName)
 	if err != nil {
 		return fmt.Errorf("failed to switch to working git branch: %w", err)
 	}
@@ -130,7 +130,7 @@ func (o *PackageOptions) UpdatePackageCmd() error {
 	nvr := NewVersionResults{
 		Version: v,
 	}
-	errorMessage, err := uo.updateGitPackage(repo, o.PackageName, nvr, ref)
+	_, errorMessage, err := uo.updateGitPackage(repo, o.PackageName, nvr, ref)
 	if err != nil {
 		return fmt.Errorf("failed to update package in git repository: %w", err)
 	}
@@ -0,0 +1,249 @@
+package update
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"gopkg.in/yaml.v3"
+
+	"github.com/wolfi-dev/wolfictl/pkg/groups"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+// DefaultPolicyFile is where the update policy of a repository of melange configs is defined, next to its package
+// groups
+const DefaultPolicyFile = ".update-policy.yaml"
+
+// reasons the update of a package is refused by the update policy of the repository
+const (
+	SkipFrozen  = "frozen"
+	SkipCadence = "cadence"
+	SkipMaxJump = "max-jump"
+)
+
+// cadences of updates
+const (
+	CadenceDaily  = "daily"
+	CadenceWeekly = "weekly"
+)
+
+// parts of a version an update can change, from the largest
+const (
+	JumpMajor = "major"
+	JumpMinor = "minor"
+	JumpPatch = "patch"
+)
+
+// Policy is how maintainers want the packages of a repository updated, read from its DefaultPolicyFile. wolfictl
+// update refuses the updates it doesn't allow, even with --force.
+type Policy struct {
+	// Defaults apply to the packages without a rule of their own.
+	Defaults PackagePolicy `yaml:"defaults"`
+	// Packages are the rules of packages, or of package groups like @gnome-core. The rule of a package takes
+	// precedence over the rules of its groups, and settings left empty fall back to the defaults.
+	Packages map[string]PackagePolicy `yaml:"packages"`
+	// Freezes are the windows no updates are proposed in, e.g. during a release.
+	Freezes []Freeze `yaml:"freezes"`
+
+	// the rules of the packages of groups, by package
+	expanded map[string]PackagePolicy
+	// the packages frozen by each freeze, nil for all of them
+	frozen []map[string]bool
+}
+
+// PackagePolicy is how a package is updated.
+type PackagePolicy struct {
+	// Cadence is how often the package is updated at most: daily, the default, or weekly, where a new version is only
+	// proposed once the version of the package is a week old.
+	Cadence string `yaml:"cadence,omitempty"`
+	// MaxJump is the largest part of the version an update may change: major, the default, minor to stay on a major
+	// version, or patch to stay on a minor version.
+	MaxJump string `yaml:"max-jump,omitempty"`
+	// Reviewers are the GitHub users, or teams as org/team, whose review is requested on the pull requests of the
+	// package.
+	Reviewers []string `yaml:"reviewers,omitempty"`
+}
+
+// Freeze is a window of days no updates are proposed in.
+type Freeze struct {
+	Name string `yaml:"name"`
+	// Start and End are the first and last days of the freeze, in UTC.
+	Start time.Time `yaml:"start"`
+	End   time.Time `yaml:"end"`
+	// Packages are the packages or package groups frozen, all of them if empty.
+	Packages []string `yaml:"packages,omitempty"`
+}
+
+// ReadPolicy reads the update policy of a repository of melange configs. A repository without a policy file updates
+// every package daily.
+func ReadPolicy(dir string) (*Policy, error) {
+	path := filepath.Join(dir, DefaultPolicyFile)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Policy{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p := &Policy{}
+	if err := yaml.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("unable to parse update policy %s: %w", path, err)
+	}
+	g, err := groups.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expand(g, groups.ConfigExists(dir)); err != nil {
+		return nil, fmt.Errorf("invalid update policy %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// expand checks the policy and resolves the package groups it refers to, whose members must exist according to exists.
+func (p *Policy) expand(g groups.Groups, exists func(name string) bool) error {
+	rules := []PackagePolicy{p.Defaults}
+	for _, r := range p.Packages {
+		rules = append(rules, r)
+	}
+	for _, r := range rules {
+		switch r.Cadence {
+		case "", CadenceDaily, CadenceWeekly:
+		default:
+			return fmt.Errorf("unknown cadence %q, must be one of: %s, %s", r.Cadence, CadenceDaily, CadenceWeekly)
+		}
+		switch r.MaxJump {
+		case "", JumpMajor, JumpMinor, JumpPatch:
+		default:
+			return fmt.Errorf("unknown max-jump %q, must be one of: %s, %s, %s", r.MaxJump, JumpMajor, JumpMinor, JumpPatch)
+		}
+	}
+
+	// the groups are expanded in alphabetical order, so a package in several groups gets the rule of the first one
+	p.expanded = make(map[string]PackagePolicy)
+	for _, name := range sortedKeys(p.Packages) {
+		if !strings.HasPrefix(name, groups.Prefix) {
+			continue
+		}
+		members, err := g.ExpandExisting([]string{name}, exists)
+		if err != nil {
+			return err
+		}
+		for _, m := range members {
+			if _, ok := p.expanded[m]; !ok {
+				p.expanded[m] = p.Packages[name]
+			}
+		}
+	}
+
+	p.frozen = make([]map[string]bool, len(p.Freezes))
+	for i, f := range p.Freezes {
+		if f.End.Before(f.Start) {
+			return fmt.Errorf("freeze %s ends before it starts", f.Name)
+		}
+		if len(f.Packages) == 0 {
+			continue
+		}
+		members, err := g.ExpandExisting(f.Packages, exists)
+		if err != nil {
+			return err
+		}
+		p.frozen[i] = make(map[string]bool, len(members))
+		for _, m := range members {
+			p.frozen[i][m] = true
+		}
+	}
+	return nil
+}
+
+// For returns the rule of a package: its own, or else the one of its first group, with the defaults for the settings
+// left empty.
+func (p *Policy) For(name string) PackagePolicy {
+	r, ok := p.Packages[name]
+	if !ok {
+		r = p.expanded[name]
+	}
+	if r.Cadence == "" {
+		r.Cadence = p.Defaults.Cadence
+	}
+	if r.MaxJump == "" {
+		r.MaxJump = p.Defaults.MaxJump
+	}
+	if len(r.Reviewers) == 0 {
+		r.Reviewers = p.Defaults.Reviewers
+	}
+	return r
+}
+
+// Frozen returns the freeze a package is in at the given time, nil if none.
+func (p *Policy) Frozen(name string, now time.Time) *Freeze {
+	for i, f := range p.Freezes {
+		if p.frozen != nil && p.frozen[i] != nil && !p.frozen[i][name] {
+			continue
+		}
+		// the end is the last day of the freeze
+		if !now.Before(f.Start) && now.Before(f.End.AddDate(0, 0, 1)) {
+			return &p.Freezes[i]
+		}
+	}
+	return nil
+}
+
+// refuse returns why the policy doesn't allow updating a package from its current version to latest at the given
+// time, or an empty reason if it does. lastChange is when the version of the package last changed, needed for weekly
+// cadences.
+func (p *Policy) refuse(name string, current, latest *version.Version, lastChange func() (time.Time, error), now time.Time) (reason, explanation string, err error) {
+	if f := p.Frozen(name, now); f != nil {
+		return SkipFrozen, fmt.Sprintf("updates of %s are frozen by %s until %s", name, f.Name, f.End.Format("2006-01-02")), nil
+	}
+
+	r := p.For(name)
+	if jump := versionJump(current, latest); jump != "" && !allowsJump(r.MaxJump, jump) {
+		return SkipMaxJump, fmt.Sprintf("%s is a new %s version of %s, the policy only allows %s updates", latest.Original(), jump, name, r.MaxJump), nil
+	}
+	if r.Cadence == CadenceWeekly {
+		changed, err := lastChange()
+		if err != nil {
+			return "", "", err
+		}
+		if next := changed.AddDate(0, 0, 7); now.Before(next) {
+			return SkipCadence, fmt.Sprintf("%s is updated weekly and was last updated on %s, the next update is due on %s", name, changed.Format("2006-01-02"), next.Format("2006-01-02")), nil
+		}
+	}
+	return "", "", nil
+}
+
+// versionJump returns the largest part of the version an update from current to latest changes, or an empty string
+// if only the parts after the patch version change
+func versionJump(current, latest *version.Version) string {
+	c, l := current.Segments(), latest.Segments()
+	for i, jump := range []string{JumpMajor, JumpMinor, JumpPatch} {
+		if i < len(c) && i < len(l) && c[i] != l[i] {
+			return jump
+		}
+	}
+	return ""
+}
+
+// allowsJump tells if a jump is at most as large as maxJump, any jump if maxJump is empty
+func allowsJump(maxJump, jump string) bool {
+	order := map[string]int{JumpPatch: 0, JumpMinor: 1, JumpMajor: 2}
+	return maxJump == "" || order[jump] <= order[maxJump]
+}
+
+// refuseByPolicy returns why the update policy of the repository doesn't allow updating a package from its current
+// version to latest now, or an empty reason if it does
+func (o *Options) refuseByPolicy(pc *melange.Packages, current, latest *version.Version) (reason, explanation string, err error) {
+	if o.policy == nil {
+		return "", "", nil
+	}
+	lastChange := func() (time.Time, error) {
+		return lastVersionChange(pc.Dir, filepath.Join(pc.Dir, pc.Filename))
+	}
+	return o.policy.refuse(pc.Config.Package.Name, current, latest, lastChange, time.Now().UTC())
+}
@@ -0,0 +1,122 @@
+package update
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".package-groups.yaml"), []byte(`
+groups:
+  toolchain:
+    packages: [gcc, binutils, glibc]
+`), 0o600))
+	for _, name := range []string{"gcc", "binutils", "glibc"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+".yaml"), nil, 0o600))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, DefaultPolicyFile), []byte(`
+defaults:
+  reviewers: [wolfi-dev/maintainers]
+packages:
+  "@toolchain":
+    cadence: weekly
+    max-jump: minor
+    reviewers: [alice]
+  glibc:
+    max-jump: patch
+freezes:
+  - name: release-2023-10
+    start: 2023-10-01
+    end: 2023-10-15
+    packages: ["@toolchain"]
+  - name: holidays
+    start: 2023-12-24
+    end: 2023-12-26
+`), 0o600))
+
+	p, err := ReadPolicy(dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, PackagePolicy{Cadence: CadenceWeekly, MaxJump: JumpMinor, Reviewers: []string{"alice"}}, p.For("gcc"), "a package should get the rule of its group")
+	assert.Equal(t, PackagePolicy{MaxJump: JumpPatch, Reviewers: []string{"wolfi-dev/maintainers"}}, p.For("glibc"), "the rule of a package should take precedence over the one of its group")
+	assert.Equal(t, PackagePolicy{Reviewers: []string{"wolfi-dev/maintainers"}}, p.For("curl"))
+
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02 15:04", s)
+		require.NoError(t, err)
+		return d
+	}
+	assert.Equal(t, "release-2023-10", p.Frozen("binutils", day("2023-10-15 23:59")).Name, "the last day should be frozen")
+	assert.Nil(t, p.Frozen("binutils", day("2023-10-16 00:00")))
+	assert.Nil(t, p.Frozen("curl", day("2023-10-10 12:00")), "freezes of some packages shouldn't freeze the others")
+	assert.Equal(t, "holidays", p.Frozen("curl", day("2023-12-25 12:00")).Name)
+}
+
+func TestReadPolicy_missing(t *testing.T) {
+	p, err := ReadPolicy(t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, PackagePolicy{}, p.For("curl"))
+	assert.Nil(t, p.Frozen("curl", time.Now()))
+}
+
+func TestReadPolicy_invalid(t *testing.T) {
+	for _, policy := range []string{
+		"defaults:\n  cadence: hourly\n",
+		"packages:\n  curl:\n    max-jump: epoch\n",
+		"freezes:\n  - name: backwards\n    start: 2023-10-15\n    end: 2023-10-01\n",
+		"packages:\n  \"@toolchain\":\n    cadence: weekly\n",
+	} {
+		dir := t.TempDir()
+		// gcc has no config
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".package-groups.yaml"), []byte("groups:\n  toolchain:\n    packages: [gcc]\n"), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, DefaultPolicyFile), []byte(policy), 0o600))
+		_, err := ReadPolicy(dir)
+		assert.Error(t, err, policy)
+	}
+}
+
+func TestPolicy_refuse(t *testing.T) {
+	p := &Policy{Packages: map[string]PackagePolicy{
+		"openssl": {MaxJump: JumpMinor},
+		"glibc":   {MaxJump: JumpPatch, Cadence: CadenceWeekly},
+	}}
+	require.NoError(t, p.expand(nil, nil))
+	now := time.Date(2023, 10, 10, 0, 0, 0, 0, time.UTC)
+	changed := func(d time.Time) func() (time.Time, error) {
+		return func() (time.Time, error) { return d, nil }
+	}
+	v := func(s string) *version.Version {
+		return version.Must(version.NewVersion(s))
+	}
+
+	tests := []struct {
+		name, pkg, current, latest string
+		lastChange                 time.Time
+		want                       string
+	}{
+		{name: "new major version", pkg: "openssl", current: "1.1.1", latest: "3.0.0", want: SkipMaxJump},
+		{name: "new minor version", pkg: "openssl", current: "3.0.9", latest: "3.1.0"},
+		{name: "new minor version of a patch only package", pkg: "glibc", current: "2.37", latest: "2.38", want: SkipMaxJump},
+		{name: "updated less than a week ago", pkg: "glibc", current: "2.38", latest: "2.38.1", lastChange: now.AddDate(0, 0, -6), want: SkipCadence},
+		{name: "updated a week ago", pkg: "glibc", current: "2.38", latest: "2.38.1", lastChange: now.AddDate(0, 0, -7)},
+		{name: "no rule", pkg: "curl", current: "7.88.1", latest: "8.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, explanation, err := p.refuse(tt.pkg, v(tt.current), v(tt.latest), changed(tt.lastChange), now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, reason, explanation)
+		})
+	}
+
+	_, _, err := p.refuse("glibc", v("2.38"), v("2.38.1"), func() (time.Time, error) { return time.Time{}, errors.New("no git") }, now)
+	assert.Error(t, err, "a weekly cadence can't be checked without the history")
+}
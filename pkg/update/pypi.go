@@ -0,0 +1,200 @@
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/pkg/errors"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+	wolfiversions "github.com/wolfi-dev/wolfictl/pkg/versions"
+)
+
+const pypiURL = "https://pypi.org"
+
+// PEP 440 pre-releases and development releases, e.g. 2.0.0rc1, 1.5b2 or 1.0.dev3
+var pypiPreRelease = regexp.MustCompile(`(?i)\d[-_.]?(a|b|c|rc|alpha|beta|pre|preview|dev)[-_.]?\d*`)
+
+// PyPIService looks up the latest versions of packages released on PyPI, configured with an update.pypi block in
+// their melange config
+type PyPIService struct {
+	Client *http2.RLHTTPClient
+	Logger *log.Logger
+
+	// BaseURL of the PyPI JSON API, defaults to https://pypi.org
+	BaseURL string
+}
+
+type pypiFile struct {
+	PackageType string `json:"packagetype"`
+	URL         string `json:"url"`
+	Yanked      bool   `json:"yanked"`
+	Digests     struct {
+		SHA256 string `json:"sha256"`
+	} `json:"digests"`
+}
+
+type pypiProject struct {
+	Releases map[string][]pypiFile `json:"releases"`
+}
+
+func (s PyPIService) getLatestPyPIVersions(melangePackages map[string]*melange.Packages) (packagesToUpdate map[string]NewVersionResults, errorMessages map[string]string) {
+	packagesToUpdate = make(map[string]NewVersionResults)
+	errorMessages = make(map[string]string)
+
+	for packageName, p := range melangePackages {
+		pm := p.PyPIMonitor
+		if pm == nil {
+			continue
+		}
+
+		s.Logger.Printf("%s: checking pypi project %s\n", packageName, pm.Identifier)
+
+		project, err := s.getProject(pm.Identifier)
+		if err != nil {
+			errorMessages[p.Config.Package.Name] = fmt.Sprintf(
+				"failed getting pypi releases for package %s, identifier %s: %s",
+				p.Config.Package.Name, pm.Identifier, err.Error(),
+			)
+			continue
+		}
+
+		latest, err := s.latestRelease(p, project)
+		if err != nil {
+			errorMessages[p.Config.Package.Name] = err.Error()
+			continue
+		}
+		if latest.Version == "" {
+			errorMessages[p.Config.Package.Name] = fmt.Sprintf(
+				"no releases found in pypi for package %s, identifier %s",
+				p.Config.Package.Name, pm.Identifier,
+			)
+			continue
+		}
+		packagesToUpdate[p.Config.Package.Name] = latest
+	}
+	return packagesToUpdate, errorMessages
+}
+
+// latestRelease returns the latest release of the project that isn't yanked, or filtered out by the update config,
+// along with its sdist if it has one and the config fetches its sources from PyPI
+func (s PyPIService) latestRelease(p *melange.Packages, project *pypiProject) (NewVersionResults, error) {
+	var latest NewVersionResults
+	for v, files := range project.Releases {
+		if len(files) == 0 || allYanked(files) {
+			continue
+		}
+		if !p.PyPIMonitor.PreReleases && pypiPreRelease.MatchString(v) {
+			continue
+		}
+		ignore, err := ignoreVersion(p.Config.Update, v)
+		if err != nil {
+			return NewVersionResults{}, err
+		}
+		if ignore {
+			continue
+		}
+
+		current, err := wolfiversions.NewVersion(v)
+		if err != nil {
+			// e.g. post releases, which can't be compared
+			s.Logger.Printf("%s: skipping pypi release %s: %s", p.Config.Package.Name, v, err)
+			continue
+		}
+		if latest.Version != "" {
+			previous, err := wolfiversions.NewVersion(latest.Version)
+			if err != nil {
+				return NewVersionResults{}, err
+			}
+			if !current.GreaterThan(previous) {
+				continue
+			}
+		}
+
+		latest = NewVersionResults{Version: v}
+		if !isPyPIHosted(fetchURI(p.Config)) {
+			// the sources come from elsewhere, e.g. a git forge
+			continue
+		}
+		for _, f := range files {
+			if f.PackageType == "sdist" && !f.Yanked {
+				latest.SourceURL = f.URL
+				latest.SourceSHA256 = f.Digests.SHA256
+				break
+			}
+		}
+	}
+	return latest, nil
+}
+
+// isPyPIHosted reports whether uri is a file hosted by PyPI
+func isPyPIHosted(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	switch u.Host {
+	case "files.pythonhosted.org", "pypi.io", "pypi.org", "pypi.python.org":
+		return true
+	}
+	return false
+}
+
+// fetchURI returns the uri of the first fetch step of the config, if it has one
+func fetchURI(c build.Configuration) string {
+	for i := range c.Pipeline {
+		if c.Pipeline[i].Uses == "fetch" {
+			return c.Pipeline[i].With["uri"]
+		}
+	}
+	return ""
+}
+
+func allYanked(files []pypiFile) bool {
+	for _, f := range files {
+		if !f.Yanked {
+			return false
+		}
+	}
+	return true
+}
+
+func (s PyPIService) getProject(identifier string) (*pypiProject, error) {
+	baseURL := s.BaseURL
+	if baseURL == "" {
+		baseURL = pypiURL
+	}
+	targetURL := fmt.Sprintf("%s/pypi/%s/json", strings.TrimSuffix(baseURL, "/"), identifier)
+	req, err := http.NewRequest("GET", targetURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating GET request %s", targetURL)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting URI %s", targetURL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non ok http response for URI %s code: %v", targetURL, resp.StatusCode)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading pypi response from %s", targetURL)
+	}
+	project := &pypiProject{}
+	if err := json.Unmarshal(b, project); err != nil {
+		return nil, errors.Wrapf(err, "unmarshalling pypi response from %s", targetURL)
+	}
+	return project, nil
+}
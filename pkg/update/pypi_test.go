@@ -0,0 +1,136 @@
+package update
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+func TestPyPIService_getLatestPyPIVersions(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "pypi", "requests.json"))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pypi/requests/json" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	s := PyPIService{
+		Client:  &http2.RLHTTPClient{Client: server.Client(), Ratelimiter: rate.NewLimiter(rate.Inf, 1)},
+		Logger:  log.New(log.Writer(), "test: ", log.LstdFlags|log.Lmsgprefix),
+		BaseURL: server.URL,
+	}
+
+	sdist := []build.Pipeline{{
+		Uses: "fetch",
+		With: map[string]string{"uri": "https://files.pythonhosted.org/packages/e0/69/122171604bcef06825fa1c05bd9e9b1d43bc9feb8c6c0717c42c92cc6f3c/requests-${{package.version}}.tar.gz"},
+	}}
+
+	tests := []struct {
+		name     string
+		monitor  melange.PyPIMonitor
+		update   build.Update
+		pipeline []build.Pipeline
+		want     NewVersionResults
+		wantErr  bool
+	}{
+		{
+			name:     "skips yanked and pre-releases",
+			monitor:  melange.PyPIMonitor{Identifier: "requests"},
+			pipeline: sdist,
+			want: NewVersionResults{
+				Version:      "2.31.0",
+				SourceURL:    "https://files.pythonhosted.org/packages/9d/be/10918a2eac4ae9f02f6cfe6414b7a155ccd8f7f9d4380d62fd5b955065c3/requests-2.31.0.tar.gz",
+				SourceSHA256: "942c5a758f98d790eaed1a29cb6eefc7ffb0d1cf7af05c3d2791656dbd6ad1e1",
+			},
+		},
+		{
+			name:     "includes pre-releases",
+			monitor:  melange.PyPIMonitor{Identifier: "requests", PreReleases: true},
+			pipeline: sdist,
+			want: NewVersionResults{
+				Version:      "3.0.0rc1",
+				SourceURL:    "https://files.pythonhosted.org/packages/2f/1a/0f4f3d7f2a4b9c8cbd2a7b7d1c3e3f5e6a9f0b1c2d3e4f5a6b7c8d9e0f1a2b3c/requests-3.0.0rc1.tar.gz",
+				SourceSHA256: "3f1a0b6c1e5d9a2b7c4e8f0d6a3b5c7e9f1d2a4b6c8e0f2a4c6e8a0b2d4f6a8c",
+			},
+		},
+		{
+			name:     "ignore regex patterns",
+			monitor:  melange.PyPIMonitor{Identifier: "requests"},
+			update:   build.Update{IgnoreRegexPatterns: []string{`^2\.31\.`}},
+			pipeline: sdist,
+			want: NewVersionResults{
+				Version:      "2.30.0",
+				SourceURL:    "https://files.pythonhosted.org/packages/e0/69/122171604bcef06825fa1c05bd9e9b1d43bc9feb8c6c0717c42c92cc6f3c/requests-2.30.0.tar.gz",
+				SourceSHA256: "239d7d4458afcb28a692cdd298d87542235f4ca8d36d03a15bfc128a6559a2f4",
+			},
+		},
+		{
+			name:    "sources from elsewhere",
+			monitor: melange.PyPIMonitor{Identifier: "requests"},
+			pipeline: []build.Pipeline{{
+				Uses: "fetch",
+				With: map[string]string{"uri": "https://github.com/psf/requests/archive/refs/tags/v${{package.version}}.tar.gz"},
+			}},
+			want: NewVersionResults{Version: "2.31.0"},
+		},
+		{
+			name:    "unknown project",
+			monitor: melange.PyPIMonitor{Identifier: "nope"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor, pipeline := tt.monitor, tt.pipeline
+			packageConfigs := map[string]*melange.Packages{
+				"py3-requests": {
+					Config: build.Configuration{
+						Package:  build.Package{Name: "py3-requests", Version: "2.30.0"},
+						Update:   tt.update,
+						Pipeline: pipeline,
+					},
+					PyPIMonitor: &monitor,
+				},
+			}
+
+			latestVersions, errorMessages := s.getLatestPyPIVersions(packageConfigs)
+			if tt.wantErr {
+				assert.Empty(t, latestVersions)
+				assert.Contains(t, errorMessages["py3-requests"], "404")
+				return
+			}
+			assert.Empty(t, errorMessages)
+			assert.Equal(t, tt.want, latestVersions["py3-requests"])
+		})
+	}
+}
+
+func Test_pypiPreRelease(t *testing.T) {
+	for v, want := range map[string]bool{
+		"2.31.0":      false,
+		"1.0.post1":   false,
+		"3.0.0rc1":    true,
+		"1.5b2":       true,
+		"2.0a1":       true,
+		"1.0.dev3":    true,
+		"4.0.0-alpha": true,
+	} {
+		assert.Equalf(t, want, pypiPreRelease.MatchString(v), "pypiPreRelease(%s)", v)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 
 	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
@@ -22,16 +23,20 @@ type MonitorService struct {
 	Client        *http2.RLHTTPClient
 	Logger        *log.Logger
 	DataMapperURL string
+	// BaseURL of release-monitoring.org, defaults to https://release-monitoring.org
+	BaseURL string
 }
 
 type ReleaseMonitorVersions struct {
 	LatestVersion  string   `json:"latest_version"`
 	StableVersions []string `json:"stable_versions"`
+	Versions       []string `json:"versions"`
 }
 type MonitorServiceName int
 
 const (
-	releaseMonitorURL = "https://release-monitoring.org/api/v2/versions/?project_id=%d"
+	releaseMonitorBaseURL = "https://release-monitoring.org"
+	releaseMonitorPath    = "/api/v2/versions/?project_id=%d"
 )
 
 func (m MonitorService) getLatestReleaseMonitorVersions(melangePackages map[string]*melange.Packages) (packagesToUpdate map[string]NewVersionResults, errorMessages map[string]string) {
@@ -55,46 +60,39 @@ func (m MonitorService) getLatestReleaseMonitorVersions(melangePackages map[stri
 	for packageName := range releaseMonitorPackages {
 		count++
 		p := releaseMonitorPackages[packageName]
-		rm := releaseMonitorPackages[packageName].Config.Update.ReleaseMonitor
-
-		m.Logger.Printf("[%d/%d] %s: checking release monitor using id %d\n", count, size, packageName, rm.Identifier)
+		identifiers := releaseMonitorIdentifiers(p)
+
+		m.Logger.Printf("[%d/%d] %s: checking release monitor using id %s\n", count, size, packageName, formatIdentifiers(identifiers))
+
+		var projects []ReleaseMonitorVersions
+		for _, identifier := range identifiers {
+			versions, err := m.getReleaseVersions(identifier)
+			if err != nil {
+				errorMessages[p.Config.Package.Name] = fmt.Sprintf(
+					"failed getting latest release version for package %s, identifier %d: %s",
+					p.Config.Package.Name, identifier, err.Error(),
+				)
+				break
+			}
+			projects = append(projects, versions)
+		}
+		if len(projects) != len(identifiers) {
+			continue
+		}
 
-		latestVersion, err := m.getLatestReleaseVersion(rm.Identifier)
+		latestVersion, err := latestReleaseMonitorVersion(p, projects)
 		if err != nil {
-			errorMessages[p.Config.Package.Name] = fmt.Sprintf(
-				"failed getting latest release version for package %s, identifier %d: %s",
-				p.Config.Package.Name, rm.Identifier, err.Error(),
-			)
+			errorMessages[p.Config.Package.Name] = fmt.Sprintf("failed filtering release monitor versions for package %s: %s", p.Config.Package.Name, err.Error())
 			continue
 		}
 		if latestVersion == "" {
 			errorMessages[p.Config.Package.Name] = fmt.Sprintf(
-				"no latest version found in release monitor for package %s, identifier %d",
-				p.Config.Package.Name, rm.Identifier,
+				"no latest version found in release monitor for package %s, identifier %s",
+				p.Config.Package.Name, formatIdentifiers(identifiers),
 			)
 			continue
 		}
 
-		// ignore versions that match a regex pattern in the melange update config
-		if len(p.Config.Update.IgnoreRegexPatterns) > 0 {
-			for _, pattern := range p.Config.Update.IgnoreRegexPatterns {
-				regex, err := regexp.Compile(pattern)
-				if err != nil {
-					errorMessages[p.Config.Package.Name] = fmt.Sprintf("failed to compile regex %s", pattern)
-					continue
-				}
-
-				if regex.MatchString(latestVersion) {
-					continue
-				}
-			}
-		}
-
-		// replace any nonstandard version separators
-		if p.Config.Update.VersionSeparator != "" {
-			latestVersion = strings.ReplaceAll(latestVersion, p.Config.Update.VersionSeparator, ".")
-		}
-
 		latestVersionSemver, err := version.NewVersion(latestVersion)
 		if err != nil {
 			errorMessages[p.Config.Package.Name] = fmt.Sprintf(
@@ -109,39 +107,123 @@ func (m MonitorService) getLatestReleaseMonitorVersions(melangePackages map[stri
 	return packagesToUpdate, errorMessages
 }
 
-func (m MonitorService) getLatestReleaseVersion(identifier int) (string, error) {
-	targetURL := fmt.Sprintf(releaseMonitorURL, identifier)
+// releaseMonitorIdentifiers returns the projects on release-monitoring.org of a package, the identifiers of the
+// release-monitor block if it has several, or its identifier
+func releaseMonitorIdentifiers(p *melange.Packages) []int {
+	if p.ReleaseMonitor != nil && len(p.ReleaseMonitor.Identifiers) > 0 {
+		return p.ReleaseMonitor.Identifiers
+	}
+	return []int{p.Config.Update.ReleaseMonitor.Identifier}
+}
+
+func formatIdentifiers(identifiers []int) string {
+	ids := make([]string, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		ids = append(ids, strconv.Itoa(identifier))
+	}
+	return strings.Join(ids, ", ")
+}
+
+// latestReleaseMonitorVersion returns the latest version of the projects of a package that passes its filters, or an
+// empty version if none does. release-monitoring.org sorts versions newest first, and the versions of the first project
+// are checked in its order. When a package bundles several projects, a version is only used once all of them have it.
+func latestReleaseMonitorVersion(p *melange.Packages, projects []ReleaseMonitorVersions) (string, error) {
+	var include, exclude *regexp.Regexp
+	var err error
+	if rm := p.ReleaseMonitor; rm != nil {
+		if rm.Include != "" {
+			if include, err = regexp.Compile(rm.Include); err != nil {
+				return "", errors.Wrapf(err, "failed to compile include regex %s", rm.Include)
+			}
+		}
+		if rm.Exclude != "" {
+			if exclude, err = regexp.Compile(rm.Exclude); err != nil {
+				return "", errors.Wrapf(err, "failed to compile exclude regex %s", rm.Exclude)
+			}
+		}
+	}
+
+	candidates := func(v ReleaseMonitorVersions) []string {
+		if p.ReleaseMonitor.IsStableOnly() {
+			return v.StableVersions
+		}
+		return v.Versions
+	}
+
+	// the versions every other project has
+	bundled := make([]map[string]bool, 0, len(projects))
+	for _, project := range projects[1:] {
+		has := make(map[string]bool)
+		for _, v := range candidates(project) {
+			has[v] = true
+		}
+		bundled = append(bundled, has)
+	}
+
+	for _, v := range candidates(projects[0]) {
+		// ignore versions that match a regex pattern in the melange update config
+		ignore, err := ignoreVersion(p.Config.Update, v)
+		if err != nil {
+			return "", err
+		}
+		if ignore || (include != nil && !include.MatchString(v)) || (exclude != nil && exclude.MatchString(v)) {
+			continue
+		}
+		if !hasVersion(bundled, v) {
+			continue
+		}
+
+		// replace any nonstandard version separators
+		if p.Config.Update.VersionSeparator != "" {
+			v = strings.ReplaceAll(v, p.Config.Update.VersionSeparator, ".")
+		}
+		return v, nil
+	}
+	return "", nil
+}
+
+func hasVersion(projects []map[string]bool, v string) bool {
+	for _, has := range projects {
+		if !has[v] {
+			return false
+		}
+	}
+	return true
+}
+
+func (m MonitorService) getReleaseVersions(identifier int) (ReleaseMonitorVersions, error) {
+	baseURL := m.BaseURL
+	if baseURL == "" {
+		baseURL = releaseMonitorBaseURL
+	}
+	targetURL := baseURL + fmt.Sprintf(releaseMonitorPath, identifier)
 	req, err := http.NewRequest("GET", targetURL, nil)
 	if err != nil {
-		return "", errors.Wrapf(err, "failed creating GET request %s", targetURL)
+		return ReleaseMonitorVersions{}, errors.Wrapf(err, "failed creating GET request %s", targetURL)
 	}
 
 	resp, err := m.Client.Do(req)
 	if err != nil {
-		return "", errors.Wrapf(err, "failed getting URI %s", targetURL)
+		return ReleaseMonitorVersions{}, errors.Wrapf(err, "failed getting URI %s", targetURL)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("non ok http response for URI %s code: %v", targetURL, resp.StatusCode)
+		return ReleaseMonitorVersions{}, fmt.Errorf("non ok http response for URI %s code: %v", targetURL, resp.StatusCode)
 	}
 
 	b, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", errors.Wrap(err, "reading monitor service mapper data file")
+		return ReleaseMonitorVersions{}, errors.Wrap(err, "reading monitor service mapper data file")
 	}
 	return m.parseVersions(b)
 }
 
-func (m MonitorService) parseVersions(rawdata []byte) (string, error) {
+func (m MonitorService) parseVersions(rawdata []byte) (ReleaseMonitorVersions, error) {
 	versions := ReleaseMonitorVersions{}
 	err := json.Unmarshal(rawdata, &versions)
 	if err != nil {
-		return "", errors.Wrap(err, "unmarshalling version data")
-	}
-
-	if len(versions.StableVersions) == 0 {
-		return "", errors.Wrap(err, "no stable version found")
+		return ReleaseMonitorVersions{}, errors.Wrap(err, "unmarshalling version data")
 	}
-	return versions.StableVersions[0], nil
+	return versions, nil
 }
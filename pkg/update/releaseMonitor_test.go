@@ -2,11 +2,19 @@ package update
 
 import (
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"chainguard.dev/melange/pkg/build"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
 )
 
 func TestReleaseMonitor_parseVersions(t *testing.T) {
@@ -26,8 +34,116 @@ func TestReleaseMonitor_parseVersions(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			got, err := m.parseVersions(data)
 			assert.NoError(t, err)
-			assert.Equalf(t, tt.expectedLatestVersion, got, "parseVersions(%v)", tt.name)
-			assert.Equalf(t, tt.expectedLatestVersion, got, "parseVersions(%v)", tt.name)
+			require.NotEmpty(t, got.StableVersions)
+			assert.Equalf(t, tt.expectedLatestVersion, got.StableVersions[0], "parseVersions(%v)", tt.name)
+		})
+	}
+}
+
+func TestMonitorService_getLatestReleaseMonitorVersions(t *testing.T) {
+	projects := map[string]string{
+		"1": "versions",
+		"2": "icu_versions",
+		"3": "bundled_versions",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := projects[r.URL.Query().Get("project_id")]
+		if r.URL.Path != "/api/v2/versions/" || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join("testdata", name+".json"))
+	}))
+	defer server.Close()
+
+	m := MonitorService{
+		Client:  &http2.RLHTTPClient{Client: server.Client(), Ratelimiter: rate.NewLimiter(rate.Inf, 1)},
+		Logger:  log.New(log.Writer(), "test: ", log.LstdFlags|log.Lmsgprefix),
+		BaseURL: server.URL,
+	}
+
+	stableOnly := false
+	tests := []struct {
+		name       string
+		identifier int
+		update     build.Update
+		monitor    *melange.ReleaseMonitor
+		want       string
+		wantErr    string
+	}{
+		{
+			name:       "latest stable version",
+			identifier: 1,
+			want:       "2.3.1",
+		},
+		{
+			name:       "version separator",
+			identifier: 2,
+			update:     build.Update{VersionSeparator: "-"},
+			want:       "72.1",
+		},
+		{
+			name:       "ignore regex patterns",
+			identifier: 1,
+			update:     build.Update{IgnoreRegexPatterns: []string{`^2\.3\.`}},
+			want:       "2.2.53",
+		},
+		{
+			name:       "include and exclude",
+			identifier: 1,
+			monitor:    &melange.ReleaseMonitor{Include: `^2\.2\.`, Exclude: `^2\.2\.53$`},
+			want:       "2.2.52",
+		},
+		{
+			name:    "bundled projects",
+			monitor: &melange.ReleaseMonitor{Identifiers: []int{1, 3}},
+			want:    "2.3.0",
+		},
+		{
+			name:    "unstable versions",
+			monitor: &melange.ReleaseMonitor{Identifiers: []int{3}, StableOnly: &stableOnly},
+			want:    "2.3.1-rc1",
+		},
+		{
+			name:       "everything filtered",
+			identifier: 1,
+			monitor:    &melange.ReleaseMonitor{Include: `^3\.`},
+			wantErr:    "no latest version found in release monitor for package foo, identifier 1",
+		},
+		{
+			name:       "invalid include regex",
+			identifier: 1,
+			monitor:    &melange.ReleaseMonitor{Include: `(`},
+			wantErr:    "failed to compile include regex",
+		},
+		{
+			name:    "unknown bundled project",
+			monitor: &melange.ReleaseMonitor{Identifiers: []int{1, 4}},
+			wantErr: "identifier 4: non ok http response",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update := tt.update
+			update.ReleaseMonitor = &build.ReleaseMonitor{Identifier: tt.identifier}
+			packageConfigs := map[string]*melange.Packages{
+				"foo": {
+					Config: build.Configuration{
+						Package: build.Package{Name: "foo", Version: "2.2.52"},
+						Update:  update,
+					},
+					ReleaseMonitor: tt.monitor,
+				},
+			}
+
+			latestVersions, errorMessages := m.getLatestReleaseMonitorVersions(packageConfigs)
+			if tt.wantErr != "" {
+				assert.Empty(t, latestVersions)
+				assert.Contains(t, errorMessages["foo"], tt.wantErr)
+				return
+			}
+			assert.Empty(t, errorMessages)
+			assert.Equal(t, NewVersionResults{Version: tt.want}, latestVersions["foo"])
 		})
 	}
 }
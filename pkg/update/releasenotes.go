@@ -0,0 +1,231 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/google/go-github/v50/github"
+	"github.com/hashicorp/go-version"
+
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+	wolfiversions "github.com/wolfi-dev/wolfictl/pkg/versions"
+)
+
+const (
+	// maxReleases is how many releases between the current and the new version have their notes in a pull request
+	maxReleases = 5
+	// maxNotesLines is how many lines of the notes of a release, or of a changelog, are kept
+	maxNotesLines = 30
+	// maxReleasePages is how many pages of releases are listed looking for the ones after the current version
+	maxReleasePages = 10
+	// versionPlaceholder stands for the version in the tags of a package, to find what's around it
+	versionPlaceholder = "@VERSION@"
+)
+
+// changelogFiles are the files whose section about a release is used when the repository doesn't publish releases
+var changelogFiles = []string{"CHANGELOG.md", "CHANGES.md", "NEWS.md"}
+
+// upstreamTag is the GitHub repository of a package, like sigstore/cosign, and what its tags add to the versions
+type upstreamTag struct {
+	owner, repo    string
+	prefix, suffix string
+}
+
+func (t upstreamTag) tag(v string) string {
+	return t.prefix + v + t.suffix
+}
+
+// version returns the version of a tag, false if the tag isn't one of a version
+func (t upstreamTag) version(tag string) (*version.Version, bool) {
+	if !strings.HasPrefix(tag, t.prefix) || !strings.HasSuffix(tag, t.suffix) || len(tag) < len(t.prefix)+len(t.suffix) {
+		return nil, false
+	}
+	v, err := wolfiversions.NewVersion(tag[len(t.prefix) : len(tag)-len(t.suffix)])
+	return v, err == nil
+}
+
+// upstreamTagOf returns where the releases of a package are published on GitHub: the repository of its github update
+// config, or else of its first git-checkout step that checks out a tag of a github.com repository depending on the
+// version, false if none
+func upstreamTagOf(cfg build.Configuration) (upstreamTag, bool) {
+	if m := cfg.Update.GitHubMonitor; m != nil {
+		if owner, repo, ok := strings.Cut(m.Identifier, "/"); ok {
+			return upstreamTag{owner: owner, repo: repo, prefix: m.StripPrefix, suffix: m.StripSuffix}, true
+		}
+	}
+
+	for _, p := range cfg.Pipeline {
+		repository, tag := p.With["repository"], p.With["tag"]
+		if p.Uses != "git-checkout" || !strings.HasPrefix(repository, "https://github.com/") || !strings.Contains(tag, "${{") {
+			continue
+		}
+		ownerRepo := strings.TrimSuffix(strings.TrimPrefix(repository, "https://github.com/"), ".git")
+		owner, repo, ok := strings.Cut(strings.Trim(ownerRepo, "/"), "/")
+		if !ok {
+			continue
+		}
+		// the tag of a placeholder version tells what the tags add to the versions
+		c := cfg
+		c.Package.Version = versionPlaceholder
+		vars, err := melange.Variables(&c)
+		if err != nil {
+			continue
+		}
+		t, err := build.MutateStringFromMap(vars, tag)
+		if err != nil {
+			continue
+		}
+		prefix, suffix, ok := strings.Cut(t, versionPlaceholder)
+		if !ok {
+			continue
+		}
+		return upstreamTag{owner: owner, repo: repo, prefix: prefix, suffix: suffix}, true
+	}
+	return upstreamTag{}, false
+}
+
+// mention is an @mention of a user or a team, which GitHub would notify of the pull request
+var mention = regexp.MustCompile("(?m)(^|[^\\w`/])@([A-Za-z0-9][A-Za-z0-9-]*(?:/[A-Za-z0-9_.-]+)?)")
+
+// neutralizeMentions formats the @mentions of notes as code, so the pull request doesn't notify everyone the upstream
+// notes thank
+func neutralizeMentions(notes string) string {
+	return mention.ReplaceAllString(notes, "$1`@$2`")
+}
+
+// releaseNotes is the section of the body of the pull request of a package, under heading, with the notes of the
+// upstream releases since the current version, or an empty string if they couldn't be found. Finding them never fails
+// the update.
+func (o *Options) releaseNotes(packageName string, newVersion NewVersionResults, heading string) string {
+	pc, ok := o.PackageConfigs[packageName]
+	if !o.ReleaseNotes || !ok || o.GitHubHTTPClient == nil {
+		return ""
+	}
+	t, ok := upstreamTagOf(pc.Config)
+	if !ok {
+		return ""
+	}
+	notes, err := fetchReleaseNotes(o.context(), github.NewClient(o.GitHubHTTPClient.Client), t, heading, pc.Config.Package.Version, newVersion.Version)
+	if err != nil {
+		o.Logger.Printf("%s: failed to get the release notes of %s/%s: %s", packageName, t.owner, t.repo, err)
+	}
+	return notes
+}
+
+// fetchReleaseNotes returns the notes of the GitHub releases of a repository after current up to latest, newest
+// first, or else the section about latest of its changelog, along with a link to the changes between the tags
+func fetchReleaseNotes(ctx context.Context, client *github.Client, t upstreamTag, heading, current, latest string) (string, error) {
+	currentVersion, err := wolfiversions.NewVersion(current)
+	if err != nil {
+		return "", err
+	}
+	latestVersion, err := wolfiversions.NewVersion(latest)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n### %s\n\n[%s...%s](https://github.com/%s/%s/compare/%s...%s)\n",
+		heading, t.tag(current), t.tag(latest), t.owner, t.repo, t.tag(current), t.tag(latest))
+
+	type release struct {
+		version *version.Version
+		*github.RepositoryRelease
+	}
+	var between []release
+	// releases are listed newest first, so the ones after the current version come before its own
+	opts := &github.ListOptions{PerPage: 100}
+	for page, reachedCurrent := 0, false; page < maxReleasePages && !reachedCurrent; page++ {
+		releases, resp, err := client.Repositories.ListReleases(ctx, t.owner, t.repo, opts)
+		if err != nil {
+			return b.String(), err
+		}
+		for _, r := range releases {
+			if r.GetTagName() == t.tag(current) {
+				reachedCurrent = true
+			}
+			v, ok := t.version(r.GetTagName())
+			if !ok || r.GetDraft() || r.GetPrerelease() || !v.GreaterThan(currentVersion) || v.GreaterThan(latestVersion) {
+				continue
+			}
+			between = append(between, release{v, r})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	sort.Slice(between, func(i, j int) bool {
+		return between[i].version.GreaterThan(between[j].version)
+	})
+
+	if len(between) > 0 {
+		for i, r := range between {
+			if i == maxReleases {
+				fmt.Fprintf(&b, "\nand %d earlier releases.\n", len(between)-maxReleases)
+				break
+			}
+			fmt.Fprintf(&b, "\n<details><summary><a href=%q>%s</a></summary>\n\n%s\n</details>\n", r.GetHTMLURL(), r.GetTagName(), neutralizeMentions(truncateLines(r.GetBody(), maxNotesLines)))
+		}
+		return b.String(), nil
+	}
+
+	for _, file := range changelogFiles {
+		content, _, resp, err := client.Repositories.GetContents(ctx, t.owner, t.repo, file, &github.RepositoryContentGetOptions{Ref: t.tag(latest)})
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return b.String(), err
+		}
+		text, err := content.GetContent()
+		if err != nil {
+			return b.String(), err
+		}
+		if section := changelogSection(text, latest, current); section != "" {
+			fmt.Fprintf(&b, "\n<details><summary><a href=%q>%s</a></summary>\n\n%s\n</details>\n", content.GetHTMLURL(), file, neutralizeMentions(truncateLines(section, maxNotesLines)))
+			break
+		}
+	}
+	return b.String(), nil
+}
+
+// changelogSection returns the lines of a changelog from the heading of the latest version up to the heading of the
+// current one, or an empty string if the changelog has no heading for the latest version
+func changelogSection(changelog, latest, current string) string {
+	var section []string
+	for _, line := range strings.Split(changelog, "\n") {
+		heading := strings.HasPrefix(line, "#")
+		switch {
+		case section == nil:
+			if heading && containsVersion(line, latest) {
+				section = append(section, line)
+			}
+		case heading && containsVersion(line, current):
+			return strings.TrimSpace(strings.Join(section, "\n"))
+		default:
+			section = append(section, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(section, "\n"))
+}
+
+// containsVersion tells if a line mentions a version, not just a version it's the prefix or suffix of, like 1.2.1 of
+// 1.2.10
+func containsVersion(line, v string) bool {
+	return regexp.MustCompile(`(?:^|[^0-9.])` + regexp.QuoteMeta(v) + `(?:$|[^0-9.]|\.(?:$|[^0-9]))`).MatchString(line)
+}
+
+// truncateLines keeps the first n lines of s, noting how many were left out
+func truncateLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n")), "\n")
+	if len(lines) <= n {
+		return strings.Join(lines, "\n")
+	}
+	return strings.Join(lines[:n], "\n") + fmt.Sprintf("\n\n_%d more lines_", len(lines)-n)
+}
@@ -0,0 +1,169 @@
+package update
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/google/go-github/v50/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+func Test_upstreamTagOf(t *testing.T) {
+	monitored := build.Configuration{Update: build.Update{GitHubMonitor: &build.GitHubMonitor{Identifier: "sigstore/cosign", StripPrefix: "v"}}}
+	got, ok := upstreamTagOf(monitored)
+	require.True(t, ok)
+	assert.Equal(t, upstreamTag{owner: "sigstore", repo: "cosign", prefix: "v"}, got)
+
+	checkout := build.Configuration{
+		Package: build.Package{Name: "jq", Version: "1.7"},
+		Pipeline: []build.Pipeline{{
+			Uses: "git-checkout",
+			With: map[string]string{"repository": "https://github.com/jqlang/jq.git", "tag": "jq-${{package.version}}"},
+		}},
+	}
+	got, ok = upstreamTagOf(checkout)
+	require.True(t, ok)
+	assert.Equal(t, upstreamTag{owner: "jqlang", repo: "jq", prefix: "jq-"}, got)
+
+	checkout.Pipeline[0].With["repository"] = "https://gitlab.com/jqlang/jq.git"
+	_, ok = upstreamTagOf(checkout)
+	assert.False(t, ok, "only GitHub releases are looked up")
+}
+
+func Test_fetchReleaseNotes(t *testing.T) {
+	// listed newest first, a page at a time
+	pages := [][]*github.RepositoryRelease{
+		{
+			{TagName: github.String("v2.2.0-rc.1"), Prerelease: github.Bool(true), Body: github.String("candidate")},
+			{TagName: github.String("v2.1.1"), HTMLURL: github.String("https://github.com/sigstore/cosign/releases/tag/v2.1.1"), Body: github.String("fixes by @alice and @sigstore/maintainers, reported to security@sigstore.dev")},
+		},
+		{
+			{TagName: github.String("v2.1.0"), HTMLURL: github.String("https://github.com/sigstore/cosign/releases/tag/v2.1.0"), Body: github.String("features")},
+			{TagName: github.String("v2.0.2"), Body: github.String("current")},
+			{TagName: github.String("helm-1.0.0"), Body: github.String("another component")},
+		},
+		{
+			{TagName: github.String("v2.0.1"), Body: github.String("older")},
+		},
+	}
+	var listed []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/sigstore/cosign/releases", func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		listed = append(listed, page)
+		i := 0
+		if page != "" {
+			i, _ = strconv.Atoi(page)
+			i--
+		}
+		if i+1 < len(pages) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s?page=%d>; rel="next"`, r.URL.Path, i+2))
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(pages[i]))
+	})
+	mux.HandleFunc("/repos/jqlang/jq/releases", func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte("[]"))
+		assert.NoError(t, err)
+	})
+	mux.HandleFunc("/repos/jqlang/jq/contents/CHANGELOG.md", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/repos/jqlang/jq/contents/NEWS.md", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "jq-1.7", r.URL.Query().Get("ref"))
+		news := "# 1.7\n\n- new builtins\n\n# 1.6\n\n- old news\n"
+		assert.NoError(t, json.NewEncoder(w).Encode(github.RepositoryContent{
+			Encoding: github.String("base64"),
+			Content:  github.String(base64.StdEncoding.EncodeToString([]byte(news))),
+			HTMLURL:  github.String("https://github.com/jqlang/jq/blob/jq-1.7/NEWS.md"),
+		}))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	notes, err := fetchReleaseNotes(context.Background(), client, upstreamTag{owner: "sigstore", repo: "cosign", prefix: "v"}, "Upstream changes", "2.0.2", "2.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", "2"}, listed, "releases should be listed up to the current one")
+	assert.Contains(t, notes, "### Upstream changes\n")
+	assert.Contains(t, notes, "fixes by `@alice` and `@sigstore/maintainers`, reported to security@sigstore.dev", "mentions shouldn't notify anyone")
+	assert.Contains(t, notes, "(https://github.com/sigstore/cosign/compare/v2.0.2...v2.1.1)")
+	assert.Contains(t, notes, "fixes")
+	assert.Contains(t, notes, "features")
+	assert.Less(t, strings.Index(notes, "v2.1.1"), strings.Index(notes, "v2.1.0"), "the newest release should come first")
+	for _, excluded := range []string{"candidate", "current", "another component", "older"} {
+		assert.NotContains(t, notes, excluded)
+	}
+
+	notes, err = fetchReleaseNotes(context.Background(), client, upstreamTag{owner: "jqlang", repo: "jq", prefix: "jq-"}, "Upstream changes", "1.6", "1.7")
+	require.NoError(t, err)
+	assert.Contains(t, notes, "NEWS.md")
+	assert.Contains(t, notes, "new builtins")
+	assert.NotContains(t, notes, "old news")
+}
+
+func Test_changelogSection(t *testing.T) {
+	changelog := "# Changelog\n\n## [1.2.10] - 2023-08-01\n\n- ten\n\n## [1.2.2]\n\n- two\n\n## [1.2.1]\n\n- one\n"
+	assert.Equal(t, "## [1.2.2]\n\n- two", changelogSection(changelog, "1.2.2", "1.2.1"))
+	assert.Equal(t, "## [1.2.10] - 2023-08-01\n\n- ten\n\n## [1.2.2]\n\n- two", changelogSection(changelog, "1.2.10", "1.2.1"), "1.2.1 shouldn't match 1.2.10")
+	assert.Empty(t, changelogSection(changelog, "1.3.0", "1.2.10"))
+}
+
+// apiServer sends the requests to the GitHub API to a test server
+type apiServer struct {
+	url *url.URL
+}
+
+func (s apiServer) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme, r.URL.Host = s.url.Scheme, s.url.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestOptions_pullRequestBody_groupReleaseNotes(t *testing.T) {
+	mux := http.NewServeMux()
+	for _, repo := range []string{"sigstore/cosign", "sigstore/rekor"} {
+		repo := repo
+		mux.HandleFunc("/repos/"+repo+"/releases", func(w http.ResponseWriter, r *http.Request) {
+			assert.NoError(t, json.NewEncoder(w).Encode([]*github.RepositoryRelease{{TagName: github.String("v2.1.0"), Body: github.String("notes of " + repo)}}))
+		})
+	}
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	config := func(name string) *melange.Packages {
+		return &melange.Packages{Config: build.Configuration{
+			Package: build.Package{Name: name, Version: "2.0.0"},
+			Update:  build.Update{GitHubMonitor: &build.GitHubMonitor{Identifier: "sigstore/" + name, StripPrefix: "v"}},
+		}}
+	}
+	o := Options{
+		ReleaseNotes:     true,
+		GitHubHTTPClient: &http2.RLHTTPClient{Client: &http.Client{Transport: apiServer{serverURL}}, Ratelimiter: rate.NewLimiter(rate.Inf, 1)},
+		PackageConfigs:   map[string]*melange.Packages{"cosign": config("cosign"), "rekor": config("rekor")},
+	}
+	body := o.pullRequestBody("@sigstore", NewVersionResults{Members: map[string]NewVersionResults{
+		"cosign": {Version: "2.1.0"},
+		"rekor":  {Version: "2.1.0"},
+	}})
+	assert.Contains(t, body, "### Upstream changes of cosign\n")
+	assert.Contains(t, body, "notes of sigstore/cosign")
+	assert.Contains(t, body, "### Upstream changes of rekor\n")
+	assert.Contains(t, body, "notes of sigstore/rekor")
+}
@@ -0,0 +1,211 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	wolfiversions "github.com/wolfi-dev/wolfictl/pkg/versions"
+)
+
+// StalePackage is how far behind its upstream a package is.
+type StalePackage struct {
+	Package        string `json:"package"`
+	CurrentVersion string `json:"currentVersion"`
+	// LatestVersion is empty if no datasource found a version.
+	LatestVersion string `json:"latestVersion,omitempty"`
+	// Datasource is the datasource that found the latest version, or failed to, e.g. github or pypi.
+	Datasource string `json:"datasource,omitempty"`
+	Outdated   bool   `json:"outdated"`
+	// StaleDays is the number of days since the version of an outdated package was last changed, 0 for packages that
+	// are up to date.
+	StaleDays int    `json:"staleDays"`
+	Error     string `json:"error,omitempty"`
+}
+
+// StalenessReport is the latest versions of the packages of a repository, found without updating any of them.
+type StalenessReport struct {
+	RepoURI     string    `json:"repoURI"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	// Packages are sorted by staleness, most stale first, then by name.
+	Packages []StalePackage `json:"packages"`
+}
+
+// ReportFormat is the encoding of a staleness report.
+type ReportFormat string
+
+const (
+	ReportFormatJSON     ReportFormat = "json"
+	ReportFormatMarkdown ReportFormat = "md"
+)
+
+// Report checks every package of the repository for a new version with its datasource, like Update, but only reports
+// how stale the packages are, without proposing any change. The repository is cloned with its whole history to tell
+// how long outdated packages have been on their version.
+func (o *Options) Report(ctx context.Context) (*StalenessReport, error) {
+	o.withContext(ctx)
+	// the report may be written to stdout
+	_, tempDir, err := o.cloneDepth(0, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	latestVersions, err := o.GetLatestVersions(tempDir, o.PackageNames)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get latest versions")
+	}
+
+	now := time.Now().UTC()
+	report := &StalenessReport{RepoURI: o.RepoURI, GeneratedAt: now}
+	for name, pc := range o.PackageConfigs {
+		p := StalePackage{
+			Package:        name,
+			CurrentVersion: pc.Config.Package.Version,
+			Datasource:     o.datasources[name],
+			Error:          o.ErrorMessages[name],
+		}
+		latest, found := latestVersions[name]
+		if !found && p.Error == "" {
+			p.Error = "no datasource found a version"
+		}
+		if found {
+			p.LatestVersion = latest.Version
+			p.Outdated, err = isOutdated(p.CurrentVersion, p.LatestVersion)
+			if err != nil {
+				p.Error = err.Error()
+			}
+		}
+		if p.Outdated {
+			changed, err := lastVersionChange(tempDir, filepath.Join(pc.Dir, pc.Filename))
+			if err != nil {
+				o.Logger.Printf("%s: failed to find when its version last changed: %s", name, err)
+			} else if !changed.IsZero() {
+				p.StaleDays = int(now.Sub(changed).Hours() / 24)
+			}
+		}
+		report.Packages = append(report.Packages, p)
+	}
+
+	sort.Slice(report.Packages, func(i, j int) bool {
+		a, b := report.Packages[i], report.Packages[j]
+		if a.Outdated != b.Outdated {
+			return a.Outdated
+		}
+		if a.StaleDays != b.StaleDays {
+			return a.StaleDays > b.StaleDays
+		}
+		return a.Package < b.Package
+	})
+	return report, nil
+}
+
+func isOutdated(current, latest string) (bool, error) {
+	c, err := wolfiversions.NewVersion(current)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse current version %s: %w", current, err)
+	}
+	l, err := wolfiversions.NewVersion(latest)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse latest version %s: %w", latest, err)
+	}
+	return c.LessThan(l), nil
+}
+
+// lastVersionChange returns the time of the last commit of the git repository in dir that changed the version of a
+// melange config, zero if it was never committed
+func lastVersionChange(dir, configFile string) (time.Time, error) {
+	if rel, err := filepath.Rel(dir, configFile); err == nil {
+		configFile = rel
+	}
+	cmd := exec.Command("git", "log", "-1", "--format=%ct", "-G^[[:space:]]*version:", "--", configFile)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("git log %s failed: %w", configFile, err)
+	}
+	s := strings.TrimSpace(string(out))
+	if s == "" {
+		return time.Time{}, nil
+	}
+	seconds, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected git log output %q: %w", s, err)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// Outdated returns the packages with a new version.
+func (r StalenessReport) Outdated() []StalePackage {
+	var outdated []StalePackage
+	for _, p := range r.Packages {
+		if p.Outdated {
+			outdated = append(outdated, p)
+		}
+	}
+	return outdated
+}
+
+// Write encodes the report to w. JSON has all the packages, Markdown the outdated ones and the failed lookups.
+func (r StalenessReport) Write(w io.Writer, format ReportFormat) error {
+	switch format {
+	case ReportFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case ReportFormatMarkdown:
+		return r.writeMarkdown(w)
+	default:
+		return fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+func (r StalenessReport) writeMarkdown(w io.Writer) error {
+	var b strings.Builder
+	outdated := r.Outdated()
+	var failed []StalePackage
+	for _, p := range r.Packages {
+		if p.Error != "" {
+			failed = append(failed, p)
+		}
+	}
+	fmt.Fprintf(&b, "# Stale packages\n\n%d of %d packages of %s are outdated, %d could not be checked, as of %s.\n\n",
+		len(outdated), len(r.Packages), r.RepoURI, len(failed), r.GeneratedAt.Format(time.DateOnly))
+
+	if len(outdated) > 0 {
+		b.WriteString("## Outdated\n\n| Package | Current version | Latest version | Stale (days) | Datasource |\n| --- | --- | --- | --- | --- |\n")
+		for _, p := range outdated {
+			fmt.Fprintf(&b, "| %s | %s | %s | %d | %s |\n", p.Package, p.CurrentVersion, p.LatestVersion, p.StaleDays, p.Datasource)
+		}
+	}
+	if len(failed) > 0 {
+		if len(outdated) > 0 {
+			b.WriteString("\n")
+		}
+		sort.SliceStable(failed, func(i, j int) bool {
+			return failed[i].Package < failed[j].Package
+		})
+		b.WriteString("## Failed lookups\n\n| Package | Current version | Datasource | Error |\n| --- | --- | --- | --- |\n")
+		for _, p := range failed {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", p.Package, p.CurrentVersion, p.Datasource, markdownCell(p.Error))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.ReplaceAll(s, "|", `\|`)
+}
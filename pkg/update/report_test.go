@@ -0,0 +1,140 @@
+package update
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_lastVersionChange(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+
+	bumped := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		when   time.Time
+		config string
+	}{
+		{bumped.AddDate(0, -1, 0), "package:\n  name: cheese\n  version: 1.2.3\n  epoch: 0\n"},
+		{bumped, "package:\n  name: cheese\n  version: 1.2.4\n  epoch: 0\n"},
+		// an epoch bump doesn't change the version
+		{bumped.AddDate(0, 1, 0), "package:\n  name: cheese\n  version: 1.2.4\n  epoch: 1\n"},
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "cheese.yaml"), []byte(c.config), 0o600))
+		_, err := wt.Add("cheese.yaml")
+		require.NoError(t, err)
+		_, err = wt.Commit("update cheese", &git.CommitOptions{
+			Author: &object.Signature{Name: "John Doe", Email: "john@doe.org", When: c.when},
+		})
+		require.NoError(t, err)
+	}
+
+	changed, err := lastVersionChange(dir, filepath.Join(dir, "cheese.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, bumped, changed)
+
+	changed, err = lastVersionChange(dir, "wine.yaml")
+	require.NoError(t, err)
+	assert.True(t, changed.IsZero())
+}
+
+func TestStalenessReport_Write(t *testing.T) {
+	r := StalenessReport{
+		RepoURI:     "https://github.com/wolfi-dev/os",
+		GeneratedAt: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+		Packages: []StalePackage{
+			{Package: "cheese", CurrentVersion: "1.2.3", LatestVersion: "1.5.10", Datasource: "github", Outdated: true, StaleDays: 92},
+			{Package: "bread", CurrentVersion: "2.0", LatestVersion: "2.0", Datasource: "release-monitor"},
+			{Package: "wine", CurrentVersion: "1.0", Datasource: "pypi", Error: "failed getting pypi versions | 404"},
+		},
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, r.Write(&b, ReportFormatMarkdown))
+	assert.Equal(t, `# Stale packages
+
+1 of 3 packages of https://github.com/wolfi-dev/os are outdated, 1 could not be checked, as of 2023-06-01.
+
+## Outdated
+
+| Package | Current version | Latest version | Stale (days) | Datasource |
+| --- | --- | --- | --- | --- |
+| cheese | 1.2.3 | 1.5.10 | 92 | github |
+
+## Failed lookups
+
+| Package | Current version | Datasource | Error |
+| --- | --- | --- | --- |
+| wine | 1.0 | pypi | failed getting pypi versions \| 404 |
+`, b.String())
+
+	b.Reset()
+	require.NoError(t, r.Write(&b, ReportFormatJSON))
+	assert.Contains(t, b.String(), `"staleDays": 92`)
+}
+
+func Test_isOutdated(t *testing.T) {
+	outdated, err := isOutdated("1.2.3", "1.10.0")
+	require.NoError(t, err)
+	assert.True(t, outdated)
+
+	outdated, err = isOutdated("1.10.0", "1.10.0")
+	require.NoError(t, err)
+	assert.False(t, outdated)
+
+	_, err = isOutdated("1.0", "latest")
+	assert.Error(t, err)
+}
+
+func TestOptions_Report_stdout(t *testing.T) {
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	config := "package:\n  name: cheese\n  version: 1.2.3\n  epoch: 0\nupdate:\n  enabled: false\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cheese.yaml"), []byte(config), 0o600))
+	_, err = wt.Add("cheese.yaml")
+	require.NoError(t, err)
+	_, err = wt.Commit("add cheese", &git.CommitOptions{Author: &object.Signature{Name: "John Doe", Email: "john@doe.org", When: time.Now()}})
+	require.NoError(t, err)
+
+	// the report is written to stdout, which nothing else may write to
+	stdout := os.Stdout
+	read, write, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = write
+	defer func() { os.Stdout = stdout }()
+
+	o := Options{RepoURI: dir, Logger: log.New(io.Discard, "", 0)}
+	report, err := o.Report(context.Background())
+	os.Stdout = stdout
+	require.NoError(t, write.Close())
+	require.NoError(t, err)
+	written, err := io.ReadAll(read)
+	require.NoError(t, err)
+	assert.Empty(t, string(written))
+
+	out := &bytes.Buffer{}
+	require.NoError(t, report.Write(out, ReportFormatJSON))
+	assert.True(t, json.Valid(out.Bytes()))
+}
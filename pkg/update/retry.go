@@ -0,0 +1,361 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/google/go-github/v50/github"
+
+	"github.com/wolfi-dev/wolfictl/pkg/gh"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+// FailedPullRequest is a package update whose pull request couldn't be created
+type FailedPullRequest struct {
+	Package    string            `json:"package"`
+	NewVersion NewVersionResults `json:"newVersion"`
+	Owner      string            `json:"owner"`
+	RepoName   string            `json:"repoName"`
+	Branch     string            `json:"branch"`
+	Title      string            `json:"title"`
+	// Body is the body of the pull request, with the checksums, validations and release notes of the update, which
+	// are only known while updating the package
+	Body string `json:"body,omitempty"`
+	// Pushed is set if the branch was pushed and only opening the pull request failed, so a retry doesn't need to
+	// update the package again
+	Pushed   bool      `json:"pushed"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failedAt"`
+	// Reviewers are the reviewers the update policy requests for the pull request
+	Reviewers []string `json:"reviewers,omitempty"`
+	// Vulnerabilities are the vulnerabilities with pending advisories the update fixes
+	Vulnerabilities []string `json:"vulnerabilities,omitempty"`
+}
+
+// FailureQueue is the pull requests of update runs that couldn't be created, with the settings of the runs needed to
+// replay them
+type FailureQueue struct {
+	RepoURI               string              `json:"repoURI"`
+	PullRequestBaseBranch string              `json:"pullRequestBaseBranch"`
+	PullRequestTitle      string              `json:"pullRequestTitle"`
+	IssueLabels           []string            `json:"issueLabels,omitempty"`
+	SecurityLabel         string              `json:"securityLabel,omitempty"`
+	UseGitSign            bool                `json:"useGitSign,omitempty"`
+	PullRequests          []FailedPullRequest `json:"pullRequests"`
+}
+
+// ReadFailureQueue reads a failure queue written by an update run, a file that doesn't exist is an empty queue
+func ReadFailureQueue(path string) (*FailureQueue, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &FailureQueue{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read failure queue %s: %w", path, err)
+	}
+	q := &FailureQueue{}
+	if err := json.Unmarshal(b, q); err != nil {
+		return nil, fmt.Errorf("failed to parse failure queue %s: %w", path, err)
+	}
+	return q, nil
+}
+
+// Write saves the queue, or removes the file once the queue is empty
+func (q *FailureQueue) Write(path string) error {
+	if len(q.PullRequests) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove failure queue %s: %w", path, err)
+		}
+		return nil
+	}
+	b, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal failure queue: %w", err)
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write failure queue %s: %w", path, err)
+	}
+	return nil
+}
+
+// retryPullRequest runs a step of creating a pull request, retrying transient failures with exponential backoff
+func (o *Options) retryPullRequest(what string, step func() error) error {
+	backoff := o.PullRequestBackoff
+	for attempt := 1; ; attempt++ {
+		err := step()
+		if err == nil || attempt >= o.PullRequestAttempts || !isTransient(err) || o.context().Err() != nil {
+			return err
+		}
+		o.Logger.Printf("%s failed on attempt %d of %d, retrying in %s: %s", what, attempt, o.PullRequestAttempts, backoff, err)
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-o.context().Done():
+			t.Stop()
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// isTransient reports whether a failure to push a branch or open a pull request may go away by itself, server errors
+// and rate limits do, but failed authentication or a rejected pull request don't
+func isTransient(err error) bool {
+	if errors.Is(err, transport.ErrAuthenticationRequired) || errors.Is(err, transport.ErrAuthorizationFailed) {
+		return false
+	}
+	var rateLimitErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &rateLimitErr) || errors.As(err, &abuseErr) {
+		return true
+	}
+	var responseErr *github.ErrorResponse
+	if errors.As(err, &responseErr) && responseErr.Response != nil {
+		code := responseErr.Response.StatusCode
+		return code >= http.StatusInternalServerError || code == http.StatusTooManyRequests
+	}
+	// anything else, e.g. a dropped connection
+	return true
+}
+
+func (o *Options) queueFailedPullRequest(f FailedPullRequest, err error) {
+	f.Error = err.Error()
+	f.FailedAt = time.Now().UTC()
+	o.failedPullRequests = append(o.failedPullRequests, f)
+}
+
+// saveFailedPullRequests adds the pull requests that failed in this run to the failure queue, replacing the ones
+// queued by earlier runs for the same packages
+func (o *Options) saveFailedPullRequests() error {
+	if o.FailureQueueFile == "" || o.DryRun {
+		return nil
+	}
+	q, err := ReadFailureQueue(o.FailureQueueFile)
+	if err != nil {
+		return err
+	}
+	if err := o.sameRepository(q); err != nil {
+		return err
+	}
+	q.PullRequests = mergeFailedPullRequests(q.PullRequests, o.failedPullRequests, o.proposed)
+	return o.writeFailureQueue(q)
+}
+
+// checkFailureQueue refuses to run when the failures of the run couldn't be saved to the failure queue, because it
+// holds the pull requests of another repository
+func (o *Options) checkFailureQueue() error {
+	if o.FailureQueueFile == "" || o.DryRun {
+		return nil
+	}
+	q, err := ReadFailureQueue(o.FailureQueueFile)
+	if err != nil {
+		return err
+	}
+	return o.sameRepository(q)
+}
+
+// sameRepository checks the pull requests queued are for the repository and base branch of the run, which the queue
+// replays all of its pull requests against
+func (o *Options) sameRepository(q *FailureQueue) error {
+	if len(q.PullRequests) == 0 {
+		return nil
+	}
+	if q.RepoURI != o.RepoURI || q.PullRequestBaseBranch != o.PullRequestBaseBranch {
+		return fmt.Errorf("failure queue %s holds pull requests for %s on %s, retry them or use another --failure-queue-file", o.FailureQueueFile, q.RepoURI, q.PullRequestBaseBranch)
+	}
+	return nil
+}
+
+func (o *Options) writeFailureQueue(q *FailureQueue) error {
+	q.RepoURI = o.RepoURI
+	q.PullRequestBaseBranch = o.PullRequestBaseBranch
+	q.PullRequestTitle = o.PullRequestTitle
+	q.IssueLabels = o.IssueLabels
+	q.SecurityLabel = o.SecurityLabel
+	q.UseGitSign = o.UseGitSign
+	if len(o.failedPullRequests) > 0 {
+		o.Logger.Printf("%d pull requests failed, retry them with 'wolfictl update retry-failed --failure-queue-file %s'", len(o.failedPullRequests), o.FailureQueueFile)
+	}
+	return q.Write(o.FailureQueueFile)
+}
+
+// mergeFailedPullRequests drops the queued pull requests of the packages proposed again, which either succeeded or
+// failed again, and adds the new failures, sorted by package
+func mergeFailedPullRequests(queued, failed []FailedPullRequest, proposed map[string]bool) []FailedPullRequest {
+	merged := make([]FailedPullRequest, 0, len(queued)+len(failed))
+	for _, f := range queued {
+		if !proposed[f.Package] {
+			merged = append(merged, f)
+		}
+	}
+	merged = append(merged, failed...)
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Package < merged[j].Package
+	})
+	return merged
+}
+
+// RetryFailed replays the pull requests of the failure queue, opening the pull requests of the branches that were
+// pushed, and updating the other packages again. The ones that fail again stay in the queue, like the ones not retried
+// yet when ctx is canceled.
+func (o *Options) RetryFailed(ctx context.Context) error {
+	o.withContext(ctx)
+	q, err := ReadFailureQueue(o.FailureQueueFile)
+	if err != nil {
+		return err
+	}
+	if len(q.PullRequests) == 0 {
+		o.Logger.Printf("no failed pull requests to retry in %s", o.FailureQueueFile)
+		return nil
+	}
+	o.RepoURI = q.RepoURI
+	o.PullRequestBaseBranch = q.PullRequestBaseBranch
+	o.PullRequestTitle = q.PullRequestTitle
+	o.IssueLabels = q.IssueLabels
+	o.SecurityLabel = q.SecurityLabel
+	o.UseGitSign = q.UseGitSign
+	// the pull requests retried, the others stay queued
+	o.proposed = make(map[string]bool)
+
+	var unpushed []FailedPullRequest
+	for _, f := range q.PullRequests {
+		if ctx.Err() != nil {
+			break
+		}
+		if !f.Pushed {
+			unpushed = append(unpushed, f)
+			continue
+		}
+		o.proposed[f.Package] = true
+		client := github.NewClient(o.GitHubHTTPClient.Client)
+		gitOpts := gh.GitOptions{
+			GithubClient: client,
+			MaxRetries:   maxPullRequestRetries,
+			Logger:       o.Logger,
+		}
+		newPR := &gh.NewPullRequest{
+			BasePullRequest: gh.BasePullRequest{
+				Owner:                 f.Owner,
+				RepoName:              f.RepoName,
+				Branch:                f.Branch,
+				PullRequestBaseBranch: o.PullRequestBaseBranch,
+			},
+			Title: f.Title,
+			Body:  f.Body,
+		}
+		if newPR.Body == "" {
+			// queued by a version that didn't save the body
+			newPR.Body = wolfiImage
+		}
+		pr, err := o.createPullRequest(gitOpts, newPR, f.NewVersion.ReplaceExistingPRNumber, f)
+		o.reportRetry(f.Package, pr, err)
+	}
+
+	var retryErr error
+	if len(unpushed) > 0 {
+		retryErr = o.retryUnpushed(unpushed)
+	}
+
+	q.PullRequests = mergeFailedPullRequests(q.PullRequests, o.failedPullRequests, o.proposed)
+	if err := o.writeFailureQueue(q); err != nil {
+		return err
+	}
+	if retryErr != nil {
+		return retryErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(o.failedPullRequests) > 0 {
+		return fmt.Errorf("%d of %d pull requests failed again", len(o.failedPullRequests), len(o.proposed))
+	}
+	return nil
+}
+
+// retryUnpushed updates the packages of pull requests whose branch wasn't pushed again, on a fresh clone
+func (o *Options) retryUnpushed(unpushed []FailedPullRequest) error {
+	repo, tempDir, err := o.clone()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	names := make([]string, 0, len(unpushed))
+	for _, f := range unpushed {
+		if len(f.NewVersion.Members) == 0 {
+			names = append(names, f.Package)
+		}
+		// the members of a package group updated together
+		for m := range f.NewVersion.Members {
+			names = append(names, m)
+		}
+	}
+	o.PackageConfigs, err = melange.ReadPackageConfigs(names, tempDir)
+	if err != nil {
+		return fmt.Errorf("failed to get package configs: %w", err)
+	}
+
+	headRef, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get the HEAD ref: %w", err)
+	}
+	for _, f := range unpushed {
+		if err := o.context().Err(); err != nil {
+			return err
+		}
+		wt, err := repo.Worktree()
+		if err != nil {
+			return fmt.Errorf("failed to get the worktree: %w", err)
+		}
+		if err := wt.Checkout(&git.CheckoutOptions{Branch: headRef.Name()}); err != nil {
+			return fmt.Errorf("failed to check out HEAD: %w", err)
+		}
+		ref, err := o.createBranch(repo, f.Package)
+		if err != nil {
+			return fmt.Errorf("failed to create git branch: %w", err)
+		}
+		o.proposed[f.Package] = true
+		o.retryPackage(repo, f, ref)
+	}
+	return nil
+}
+
+func (o *Options) retryPackage(repo *git.Repository, f FailedPullRequest, ref plumbing.ReferenceName) {
+	failures := len(o.failedPullRequests)
+	if len(f.Vulnerabilities) > 0 {
+		if o.securityFixes == nil {
+			o.securityFixes = make(map[string][]string)
+		}
+		o.securityFixes[f.Package] = f.Vulnerabilities
+	}
+	_, errorMessage, err := o.updateGitPackage(repo, f.Package, f.NewVersion, ref)
+	if err == nil && errorMessage != "" {
+		err = errors.New(errorMessage)
+	}
+	if err != nil && len(o.failedPullRequests) == failures {
+		// the update failed before getting to the pull request, keep it queued as it was
+		o.queueFailedPullRequest(f, err)
+	}
+	o.reportRetry(f.Package, "", err)
+}
+
+func (o *Options) reportRetry(packageName, pr string, err error) {
+	switch {
+	case err != nil:
+		o.Logger.Printf("%s: retry failed: %s", packageName, err)
+	case pr != "":
+		o.Logger.Printf("%s: %s", packageName, pr)
+		o.Summary.recordPullRequest()
+	default:
+		o.Logger.Printf("%s: retried", packageName)
+	}
+}
@@ -0,0 +1,272 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/google/go-github/v50/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wolfi-dev/wolfictl/pkg/gh"
+)
+
+func TestOptions_retryPullRequest(t *testing.T) {
+	o := &Options{
+		Logger:              log.New(log.Writer(), "test: ", log.LstdFlags|log.Lmsgprefix),
+		PullRequestAttempts: 3,
+	}
+
+	calls := 0
+	err := o.retryPullRequest("flaky", func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("connection reset by peer")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = o.retryPullRequest("down", func() error {
+		calls++
+		return errors.New("connection reset by peer")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = o.retryPullRequest("rejected", func() error {
+		calls++
+		return transport.ErrAuthorizationFailed
+	})
+	assert.ErrorIs(t, err, transport.ErrAuthorizationFailed)
+	assert.Equal(t, 1, calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	o.withContext(ctx)
+	o.PullRequestBackoff = time.Hour
+	calls = 0
+	err = o.retryPullRequest("canceled", func() error {
+		calls++
+		return errors.New("connection reset by peer")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls, "a canceled run shouldn't retry")
+}
+
+func Test_isTransient(t *testing.T) {
+	response := func(code int) error {
+		return &github.ErrorResponse{Response: &http.Response{StatusCode: code}}
+	}
+	for name, tt := range map[string]struct {
+		err  error
+		want bool
+	}{
+		"server error":         {err: response(http.StatusBadGateway), want: true},
+		"too many requests":    {err: response(http.StatusTooManyRequests), want: true},
+		"rate limit":           {err: &github.AbuseRateLimitError{}, want: true},
+		"validation failed":    {err: response(http.StatusUnprocessableEntity), want: false},
+		"not found":            {err: response(http.StatusNotFound), want: false},
+		"authorization failed": {err: transport.ErrAuthorizationFailed, want: false},
+		"dropped connection":   {err: errors.New("EOF"), want: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isTransient(tt.err))
+		})
+	}
+}
+
+func TestFailureQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "failures.json")
+
+	q, err := ReadFailureQueue(path)
+	require.NoError(t, err)
+	assert.Empty(t, q.PullRequests)
+
+	q.RepoURI = "https://github.com/wolfi-dev/os"
+	q.PullRequests = []FailedPullRequest{{Package: "foo", NewVersion: NewVersionResults{Version: "1.2.3"}, Pushed: true}}
+	require.NoError(t, q.Write(path))
+
+	read, err := ReadFailureQueue(path)
+	require.NoError(t, err)
+	assert.Equal(t, q, read)
+
+	read.PullRequests = nil
+	require.NoError(t, read.Write(path))
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func Test_mergeFailedPullRequests(t *testing.T) {
+	queued := []FailedPullRequest{
+		{Package: "foo", Error: "old"},
+		{Package: "bar", Error: "old"},
+		{Package: "baz", Error: "old"},
+	}
+	failed := []FailedPullRequest{{Package: "bar", Error: "new"}}
+	// foo was proposed again and succeeded, bar failed again and baz wasn't proposed
+	proposed := map[string]bool{"foo": true, "bar": true}
+
+	assert.Equal(t, []FailedPullRequest{
+		{Package: "bar", Error: "new"},
+		{Package: "baz", Error: "old"},
+	}, mergeFailedPullRequests(queued, failed, proposed))
+}
+
+func TestOptions_saveFailedPullRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "failures.json")
+	o := &Options{
+		Logger:                log.New(io.Discard, "", 0),
+		FailureQueueFile:      path,
+		RepoURI:               "https://github.com/wolfi-dev/os",
+		PullRequestBaseBranch: "main",
+		failedPullRequests:    []FailedPullRequest{{Package: "foo", Body: "body of foo"}},
+	}
+	require.NoError(t, o.checkFailureQueue())
+	require.NoError(t, o.saveFailedPullRequests())
+
+	o.RepoURI = "https://github.com/wolfi-dev/enterprise-packages"
+	o.failedPullRequests = []FailedPullRequest{{Package: "bar"}}
+	assert.ErrorContains(t, o.checkFailureQueue(), "holds pull requests for https://github.com/wolfi-dev/os on main")
+	assert.Error(t, o.saveFailedPullRequests())
+
+	q, err := ReadFailureQueue(path)
+	require.NoError(t, err)
+	assert.Equal(t, "https://github.com/wolfi-dev/os", q.RepoURI, "the queue keeps the repository of its pull requests")
+	assert.Equal(t, []FailedPullRequest{{Package: "foo", Body: "body of foo"}}, q.PullRequests)
+}
+
+func TestOptions_createPullRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		wantCalls  int
+		wantQueued bool
+	}{
+		{name: "succeeds after transient failures", statuses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusCreated}, wantCalls: 3},
+		{name: "gives up after all attempts", statuses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}, wantCalls: 3, wantQueued: true},
+		{name: "doesn't retry rejected pull requests", statuses: []int{http.StatusUnprocessableEntity}, wantCalls: 1, wantQueued: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			var reviewers string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost && r.URL.Path == "/repos/wolfi-dev/os/pulls/42/requested_reviewers" {
+					b, _ := io.ReadAll(r.Body)
+					reviewers = string(b)
+					_, _ = w.Write([]byte(`{"number": 42}`))
+					return
+				}
+				if r.Method == http.MethodPost && r.URL.Path == "/repos/wolfi-dev/os/pulls" {
+					status := tt.statuses[calls]
+					calls++
+					w.WriteHeader(status)
+					if status == http.StatusCreated {
+						_, _ = w.Write([]byte(`{"number": 42, "html_url": "https://github.com/wolfi-dev/os/pull/42"}`))
+					} else {
+						_, _ = w.Write([]byte(`{"message": "failed"}`))
+					}
+					return
+				}
+				// labels
+				_, _ = w.Write([]byte(`[]`))
+			}))
+			defer server.Close()
+
+			client := github.NewClient(server.Client())
+			var err error
+			client.BaseURL, err = url.Parse(server.URL + "/")
+			require.NoError(t, err)
+
+			o := &Options{
+				Logger:              log.New(log.Writer(), "test: ", log.LstdFlags|log.Lmsgprefix),
+				PullRequestAttempts: 3,
+				IssueLabels:         []string{"automated pr"},
+			}
+			gitOpts := gh.GitOptions{GithubClient: client, Logger: o.Logger}
+			newPR := &gh.NewPullRequest{
+				BasePullRequest: gh.BasePullRequest{Owner: "wolfi-dev", RepoName: "os", Branch: "refs/heads/wolfictl-foo", PullRequestBaseBranch: "main"},
+				Title:           "foo/1.2.3 package update",
+			}
+			failed := FailedPullRequest{Package: "foo", Owner: "wolfi-dev", RepoName: "os", Branch: newPR.Branch, Title: newPR.Title, Pushed: true,
+				Reviewers: []string{"alice", "wolfi-dev/maintainers"}}
+
+			pr, err := o.createPullRequest(gitOpts, newPR, 0, failed)
+			assert.Equal(t, tt.wantCalls, calls)
+			if !tt.wantQueued {
+				require.NoError(t, err)
+				assert.Equal(t, "https://github.com/wolfi-dev/os/pull/42", pr)
+				assert.Empty(t, o.failedPullRequests)
+				assert.JSONEq(t, `{"reviewers": ["alice"], "team_reviewers": ["maintainers"]}`, reviewers, "the reviewers of the update policy should be requested")
+				return
+			}
+			assert.Error(t, err)
+			require.Len(t, o.failedPullRequests, 1)
+			assert.Equal(t, "foo", o.failedPullRequests[0].Package)
+			assert.True(t, o.failedPullRequests[0].Pushed)
+			assert.NotEmpty(t, o.failedPullRequests[0].Error)
+		})
+	}
+}
+
+func TestOptions_createPullRequest_updateExisting(t *testing.T) {
+	for _, state := range []string{"open", "closed"} {
+		t.Run(state, func(t *testing.T) {
+			var edited, opened bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPatch && r.URL.Path == "/repos/wolfi-dev/os/pulls/7":
+					edited = true
+					_, _ = fmt.Fprintf(w, `{"number": 7, "state": %q, "html_url": "https://github.com/wolfi-dev/os/pull/7"}`, state)
+				case r.Method == http.MethodPost && r.URL.Path == "/repos/wolfi-dev/os/pulls":
+					opened = true
+					w.WriteHeader(http.StatusCreated)
+					_, _ = w.Write([]byte(`{"number": 42, "html_url": "https://github.com/wolfi-dev/os/pull/42"}`))
+				default:
+					// labels
+					_, _ = w.Write([]byte(`[]`))
+				}
+			}))
+			defer server.Close()
+
+			client := github.NewClient(server.Client())
+			var err error
+			client.BaseURL, err = url.Parse(server.URL + "/")
+			require.NoError(t, err)
+
+			o := &Options{Logger: log.New(io.Discard, "", 0), PullRequestAttempts: 1}
+			gitOpts := gh.GitOptions{GithubClient: client, Logger: o.Logger}
+			newPR := &gh.NewPullRequest{
+				BasePullRequest: gh.BasePullRequest{Owner: "wolfi-dev", RepoName: "os", Branch: "refs/heads/wolfictl-update-foo", PullRequestBaseBranch: "main"},
+				Title:           "foo/1.2.4 package update",
+			}
+			failed := FailedPullRequest{Package: "foo", NewVersion: NewVersionResults{Version: "1.2.4", UpdateExistingPRNumber: 7}, Pushed: true}
+
+			pr, err := o.createPullRequest(gitOpts, newPR, 0, failed)
+			require.NoError(t, err)
+			assert.True(t, edited)
+			if state == "open" {
+				assert.False(t, opened, "the open pull request of the branch should be updated")
+				assert.Equal(t, "https://github.com/wolfi-dev/os/pull/7", pr)
+			} else {
+				assert.True(t, opened, "a pull request closed meanwhile should be replaced")
+				assert.Equal(t, "https://github.com/wolfi-dev/os/pull/42", pr)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+const (
+	rubyGemsURL = "https://rubygems.org"
+
+	// the platform of gems that are pure Ruby, or build their extensions on install
+	rubyGemsDefaultPlatform = "ruby"
+)
+
+// RubyGemsService looks up the latest versions of gems released on rubygems.org, configured with an update.rubygems
+// block in their melange config
+type RubyGemsService struct {
+	Client *http2.RLHTTPClient
+	Logger *log.Logger
+
+	// BaseURL of the rubygems.org API, defaults to https://rubygems.org
+	BaseURL string
+}
+
+type gemVersion struct {
+	Number     string `json:"number"`
+	Platform   string `json:"platform"`
+	Prerelease bool   `json:"prerelease"`
+	// SHA is the sha256 of the .gem file
+	SHA string `json:"sha"`
+}
+
+func (s RubyGemsService) getLatestRubyGemsVersions(melangePackages map[string]*melange.Packages) (packagesToUpdate map[string]NewVersionResults, errorMessages map[string]string) {
+	packagesToUpdate = make(map[string]NewVersionResults)
+	errorMessages = make(map[string]string)
+
+	for packageName, p := range melangePackages {
+		rm := p.RubyGemsMonitor
+		if rm == nil {
+			continue
+		}
+
+		s.Logger.Printf("%s: checking gem %s\n", packageName, rm.Identifier)
+
+		versions, err := s.getGemVersions(rm.Identifier)
+		if err != nil {
+			errorMessages[p.Config.Package.Name] = fmt.Sprintf(
+				"failed getting rubygems.org versions for package %s, identifier %s: %s",
+				p.Config.Package.Name, rm.Identifier, err.Error(),
+			)
+			continue
+		}
+
+		latest, err := latestGemVersion(p, versions)
+		if err != nil {
+			errorMessages[p.Config.Package.Name] = err.Error()
+			continue
+		}
+		if latest.Version == "" {
+			errorMessages[p.Config.Package.Name] = fmt.Sprintf(
+				"no versions found in rubygems.org for package %s, identifier %s, platform %s",
+				p.Config.Package.Name, rm.Identifier, gemPlatform(rm),
+			)
+			continue
+		}
+		packagesToUpdate[p.Config.Package.Name] = latest
+	}
+	return packagesToUpdate, errorMessages
+}
+
+// latestGemVersion returns the latest version of the gem that's released for the platform of the update config and
+// isn't filtered out by it. Pre-releases are skipped unless the config includes them. If the config fetches the gem
+// from rubygems.org, the .gem of that version and platform is returned with it.
+func latestGemVersion(p *melange.Packages, versions []gemVersion) (NewVersionResults, error) {
+	rm := p.RubyGemsMonitor
+	platform := gemPlatform(rm)
+
+	var latest *version.Version
+	var gem gemVersion
+	for _, gv := range versions {
+		if gv.Platform != platform {
+			continue
+		}
+		if gv.Prerelease && !rm.PreReleases {
+			continue
+		}
+		v, err := version.NewVersion(gemSemver(gv.Number))
+		if err != nil {
+			continue
+		}
+		ignore, err := ignoreVersion(p.Config.Update, gv.Number)
+		if err != nil {
+			return NewVersionResults{}, err
+		}
+		if ignore {
+			continue
+		}
+		if latest == nil || v.GreaterThan(latest) {
+			latest, gem = v, gv
+		}
+	}
+	if latest == nil {
+		return NewVersionResults{}, nil
+	}
+
+	result := NewVersionResults{Version: gem.Number}
+	if isRubyGemsDownload(fetchURI(p.Config)) {
+		result.SourceURL = gemDownloadURL(rm.Identifier, gem)
+		result.SourceSHA256 = gem.SHA
+	}
+	return result, nil
+}
+
+func gemPlatform(rm *melange.RubyGemsMonitor) string {
+	if rm.Platform == "" {
+		return rubyGemsDefaultPlatform
+	}
+	return rm.Platform
+}
+
+// gemSemver returns a gem version in a form go-version can compare: gems mark pre-releases with a segment starting
+// with a letter, e.g. 1.16.0.rc1, which go-version only understands after a hyphen, e.g. 1.16.0-rc1
+func gemSemver(number string) string {
+	segments := strings.Split(number, ".")
+	for i, s := range segments {
+		if i > 0 && s != "" && (s[0] < '0' || s[0] > '9') {
+			return strings.Join(segments[:i], ".") + "-" + strings.Join(segments[i:], ".")
+		}
+	}
+	return number
+}
+
+// gemDownloadURL returns the .gem of a version of a gem on rubygems.org, whose name has the platform unless it's the
+// default one
+func gemDownloadURL(name string, gem gemVersion) string {
+	if gem.Platform == rubyGemsDefaultPlatform {
+		return fmt.Sprintf("%s/downloads/%s-%s.gem", rubyGemsURL, name, gem.Number)
+	}
+	return fmt.Sprintf("%s/downloads/%s-%s-%s.gem", rubyGemsURL, name, gem.Number, gem.Platform)
+}
+
+// isRubyGemsDownload reports whether uri is a .gem downloaded from rubygems.org, i.e.
+// https://rubygems.org/downloads/<name>-<version>.gem
+func isRubyGemsDownload(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	return u.Host == "rubygems.org" && strings.HasPrefix(u.Path, "/downloads/") && strings.HasSuffix(u.Path, ".gem")
+}
+
+func (s RubyGemsService) getGemVersions(identifier string) ([]gemVersion, error) {
+	baseURL := s.BaseURL
+	if baseURL == "" {
+		baseURL = rubyGemsURL
+	}
+	targetURL := fmt.Sprintf("%s/api/v1/versions/%s.json", strings.TrimSuffix(baseURL, "/"), url.PathEscape(identifier))
+	req, err := http.NewRequest("GET", targetURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating GET request %s", targetURL)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting URI %s", targetURL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non ok http response for URI %s code: %v", targetURL, resp.StatusCode)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading rubygems.org response from %s", targetURL)
+	}
+	var versions []gemVersion
+	if err := json.Unmarshal(b, &versions); err != nil {
+		return nil, errors.Wrapf(err, "unmarshalling rubygems.org response from %s", targetURL)
+	}
+	return versions, nil
+}
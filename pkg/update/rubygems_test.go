@@ -0,0 +1,137 @@
+package update
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+func TestRubyGemsService_getLatestRubyGemsVersions(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "rubygems", "nokogiri.json"))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/versions/nokogiri.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	s := RubyGemsService{
+		Client:  &http2.RLHTTPClient{Client: server.Client(), Ratelimiter: rate.NewLimiter(rate.Inf, 1)},
+		Logger:  log.New(log.Writer(), "test: ", log.LstdFlags|log.Lmsgprefix),
+		BaseURL: server.URL,
+	}
+
+	gem := []build.Pipeline{{
+		Uses: "fetch",
+		With: map[string]string{"uri": "https://rubygems.org/downloads/nokogiri-${{package.version}}.gem"},
+	}}
+
+	tests := []struct {
+		name     string
+		monitor  melange.RubyGemsMonitor
+		update   build.Update
+		pipeline []build.Pipeline
+		want     NewVersionResults
+		wantErr  string
+	}{
+		{
+			name:     "default platform",
+			monitor:  melange.RubyGemsMonitor{Identifier: "nokogiri"},
+			pipeline: gem,
+			want: NewVersionResults{
+				Version:      "1.15.4",
+				SourceURL:    "https://rubygems.org/downloads/nokogiri-1.15.4.gem",
+				SourceSHA256: "e4d4dba3bd55ba2b9a7a6d1f9b2c1cfad3a0b6a4b2c6b9a5d1e2f0c9b8a7d6e5",
+			},
+		},
+		{
+			name:     "platform-specific gem",
+			monitor:  melange.RubyGemsMonitor{Identifier: "nokogiri", Platform: "x86_64-linux"},
+			pipeline: gem,
+			want: NewVersionResults{
+				Version:      "1.15.4",
+				SourceURL:    "https://rubygems.org/downloads/nokogiri-1.15.4-x86_64-linux.gem",
+				SourceSHA256: "b8d8a5bbb1ed0bbfd0d6c1b2f5e0ab9c3e4d2f1a0b9c8d7e6f5a4b3c2d1e0f9a",
+			},
+		},
+		{
+			name:    "pre-releases",
+			monitor: melange.RubyGemsMonitor{Identifier: "nokogiri", PreReleases: true},
+			want:    NewVersionResults{Version: "1.16.0.rc1"},
+		},
+		{
+			name:    "ignore regex patterns",
+			monitor: melange.RubyGemsMonitor{Identifier: "nokogiri"},
+			update:  build.Update{IgnoreRegexPatterns: []string{`^1\.15\.4$`}},
+			want:    NewVersionResults{Version: "1.15.3"},
+		},
+		{
+			name:    "sources from elsewhere",
+			monitor: melange.RubyGemsMonitor{Identifier: "nokogiri"},
+			pipeline: []build.Pipeline{{
+				Uses: "fetch",
+				With: map[string]string{"uri": "https://github.com/sparklemotion/nokogiri/archive/v${{package.version}}.tar.gz"},
+			}},
+			want: NewVersionResults{Version: "1.15.4"},
+		},
+		{
+			name:    "unknown platform",
+			monitor: melange.RubyGemsMonitor{Identifier: "nokogiri", Platform: "aarch64-linux"},
+			wantErr: "no versions found",
+		},
+		{
+			name:    "unknown gem",
+			monitor: melange.RubyGemsMonitor{Identifier: "nope"},
+			wantErr: "404",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := tt.monitor
+			packageConfigs := map[string]*melange.Packages{
+				"ruby3.2-nokogiri": {
+					Config: build.Configuration{
+						Package:  build.Package{Name: "ruby3.2-nokogiri", Version: "1.15.3"},
+						Update:   tt.update,
+						Pipeline: tt.pipeline,
+					},
+					RubyGemsMonitor: &monitor,
+				},
+			}
+
+			latestVersions, errorMessages := s.getLatestRubyGemsVersions(packageConfigs)
+			if tt.wantErr != "" {
+				assert.Empty(t, latestVersions)
+				assert.Contains(t, errorMessages["ruby3.2-nokogiri"], tt.wantErr)
+				return
+			}
+			assert.Empty(t, errorMessages)
+			assert.Equal(t, tt.want, latestVersions["ruby3.2-nokogiri"])
+		})
+	}
+}
+
+func Test_gemSemver(t *testing.T) {
+	for number, want := range map[string]string{
+		"1.15.4":       "1.15.4",
+		"1.16.0.rc1":   "1.16.0-rc1",
+		"7.1.0.beta.2": "7.1.0-beta.2",
+	} {
+		assert.Equalf(t, want, gemSemver(number), "gemSemver(%s)", number)
+	}
+}
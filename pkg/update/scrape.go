@@ -0,0 +1,148 @@
+package update
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+
+	"github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+	wolfiversions "github.com/wolfi-dev/wolfictl/pkg/versions"
+)
+
+const (
+	// the name of the capture group of a scrape pattern that matches the version
+	scrapeVersionGroup = "version"
+
+	// directory listings of big mirrors are a few MB at most
+	maxScrapeBodySize = 32 << 20
+)
+
+// ScrapeService looks up the latest versions of packages whose upstream publishes releases on a plain web page, like
+// the directory listings of GNU mirrors, kernel.org or Apache dist, configured with an update.scrape block in their
+// melange config
+type ScrapeService struct {
+	Client *http2.RLHTTPClient
+	Logger *log.Logger
+}
+
+func (s ScrapeService) getLatestScrapeVersions(melangePackages map[string]*melange.Packages) (packagesToUpdate map[string]NewVersionResults, errorMessages map[string]string) {
+	packagesToUpdate = make(map[string]NewVersionResults)
+	errorMessages = make(map[string]string)
+
+	for packageName, p := range melangePackages {
+		sm := p.ScrapeMonitor
+		if sm == nil {
+			continue
+		}
+
+		s.Logger.Printf("%s: scraping %s\n", packageName, sm.URL)
+
+		pattern, err := scrapePattern(sm.Pattern)
+		if err != nil {
+			errorMessages[p.Config.Package.Name] = fmt.Sprintf("invalid scrape pattern of package %s: %s", p.Config.Package.Name, err.Error())
+			continue
+		}
+
+		page, err := s.getPage(sm.URL)
+		if err != nil {
+			errorMessages[p.Config.Package.Name] = fmt.Sprintf(
+				"failed scraping versions for package %s, url %s: %s",
+				p.Config.Package.Name, sm.URL, err.Error(),
+			)
+			continue
+		}
+
+		semvers, err := scrapeVersions(p, pattern, page)
+		if err != nil {
+			errorMessages[p.Config.Package.Name] = err.Error()
+			continue
+		}
+		if len(semvers) == 0 {
+			errorMessages[p.Config.Package.Name] = fmt.Sprintf(
+				"no versions found scraping %s for package %s, pattern %s",
+				sm.URL, p.Config.Package.Name, sm.Pattern,
+			)
+			continue
+		}
+
+		latest := findLatestVersion(semvers)
+		packagesToUpdate[p.Config.Package.Name] = NewVersionResults{Version: latest.Original()}
+	}
+	return packagesToUpdate, errorMessages
+}
+
+// scrapePattern compiles the pattern of a scrape monitor, which has to capture the version in a group named version,
+// or in its only group
+func scrapePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, errors.New("no pattern set")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if re.SubexpIndex(scrapeVersionGroup) == -1 && re.NumSubexp() != 1 {
+		return nil, fmt.Errorf("pattern %s must capture the version in a group named %s, or in its only group", pattern, scrapeVersionGroup)
+	}
+	return re, nil
+}
+
+// scrapeVersions returns the versions matched by the pattern on the page, which pass the filters of the update config
+// and can be compared. A version usually appears several times on a page, e.g. in the links to its tarball and
+// signature, so duplicates are dropped.
+func scrapeVersions(p *melange.Packages, pattern *regexp.Regexp, page []byte) ([]*version.Version, error) {
+	group := pattern.SubexpIndex(scrapeVersionGroup)
+	if group == -1 {
+		group = 1
+	}
+
+	seen := make(map[string]bool)
+	var semvers []*version.Version
+	for _, match := range pattern.FindAllSubmatch(page, -1) {
+		v, err := prepareTag(p.Config.Update, "", "", "", string(match[group]))
+		if err != nil {
+			return nil, err
+		}
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+
+		semver, err := wolfiversions.NewVersion(v)
+		if err != nil {
+			// pages often have other files matching a loose pattern, e.g. latest
+			continue
+		}
+		semvers = append(semvers, semver)
+	}
+	return semvers, nil
+}
+
+func (s ScrapeService) getPage(targetURL string) ([]byte, error) {
+	req, err := http.NewRequest("GET", targetURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating GET request %s", targetURL)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting URI %s", targetURL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non ok http response for URI %s code: %v", targetURL, resp.StatusCode)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxScrapeBodySize))
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading response from %s", targetURL)
+	}
+	return b, nil
+}
@@ -0,0 +1,100 @@
+package update
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+func TestScrapeService_getLatestScrapeVersions(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "scrape", "make.html"))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/gnu/make/" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	s := ScrapeService{
+		Client: &http2.RLHTTPClient{Client: server.Client(), Ratelimiter: rate.NewLimiter(rate.Inf, 1)},
+		Logger: log.New(log.Writer(), "test: ", log.LstdFlags|log.Lmsgprefix),
+	}
+
+	tests := []struct {
+		name    string
+		monitor melange.ScrapeMonitor
+		update  build.Update
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "named group skips pre-releases and other files",
+			monitor: melange.ScrapeMonitor{URL: server.URL + "/gnu/make/", Pattern: `make-(?P<version>[\w.-]+)\.tar\.gz"`},
+			want:    "4.4.1",
+		},
+		{
+			name:    "only group",
+			monitor: melange.ScrapeMonitor{URL: server.URL + "/gnu/make/", Pattern: `make-(\d+\.\d+(?:\.\d+)?)\.tar\.gz`},
+			want:    "4.4.1",
+		},
+		{
+			name:    "ignore regex patterns",
+			monitor: melange.ScrapeMonitor{URL: server.URL + "/gnu/make/", Pattern: `make-(\d+\.\d+(?:\.\d+)?)\.tar\.gz`},
+			update:  build.Update{IgnoreRegexPatterns: []string{`^4\.4`}},
+			want:    "4.3",
+		},
+		{
+			name:    "ambiguous groups",
+			monitor: melange.ScrapeMonitor{URL: server.URL + "/gnu/make/", Pattern: `(make)-(\d+\.\d+)`},
+			wantErr: "must capture the version",
+		},
+		{
+			name:    "no matches",
+			monitor: melange.ScrapeMonitor{URL: server.URL + "/gnu/make/", Pattern: `automake-(\d+\.\d+)\.tar\.gz`},
+			wantErr: "no versions found",
+		},
+		{
+			name:    "missing page",
+			monitor: melange.ScrapeMonitor{URL: server.URL + "/gnu/nope/", Pattern: `nope-(\d+\.\d+)\.tar\.gz`},
+			wantErr: "404",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := tt.monitor
+			packageConfigs := map[string]*melange.Packages{
+				"make": {
+					Config: build.Configuration{
+						Package: build.Package{Name: "make", Version: "4.3"},
+						Update:  tt.update,
+					},
+					ScrapeMonitor: &monitor,
+				},
+			}
+
+			latestVersions, errorMessages := s.getLatestScrapeVersions(packageConfigs)
+			if tt.wantErr != "" {
+				assert.Empty(t, latestVersions)
+				assert.Contains(t, errorMessages["make"], tt.wantErr)
+				return
+			}
+			assert.Empty(t, errorMessages)
+			assert.Equal(t, NewVersionResults{Version: tt.want}, latestVersions["make"])
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/configs"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+	wolfiversions "github.com/wolfi-dev/wolfictl/pkg/versions"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln/osv"
+)
+
+const (
+	// DefaultOSVURL is the OSV API that tells which versions fix a vulnerability
+	DefaultOSVURL = "https://api.osv.dev"
+	// DefaultSecurityLabel is added to the pull requests of updates that fix vulnerabilities with pending advisories
+	DefaultSecurityLabel = "security"
+)
+
+// wolfiRelease is the release number Wolfi versions end with, e.g. -r0
+var wolfiRelease = regexp.MustCompile(`-r\d+$`)
+
+// osvPackages are the packages the OSV records of the vulnerabilities of a package name it by: the one of the Wolfi
+// ecosystem, and the ones of the registries its update config checks for releases.
+func osvPackages(pc *melange.Packages) []osv.Package {
+	pkgs := []osv.Package{{Ecosystem: "Wolfi", Name: pc.Config.Package.Name}}
+	if m := pc.PyPIMonitor; m != nil && m.Identifier != "" {
+		pkgs = append(pkgs, osv.Package{Ecosystem: "PyPI", Name: m.Identifier})
+	}
+	if m := pc.CratesMonitor; m != nil && m.Identifier != "" {
+		pkgs = append(pkgs, osv.Package{Ecosystem: "crates.io", Name: m.Identifier})
+	}
+	if m := pc.GoModuleMonitor; m != nil && m.Identifier != "" {
+		pkgs = append(pkgs, osv.Package{Ecosystem: "Go", Name: m.Identifier})
+	}
+	if m := pc.NpmMonitor; m != nil && m.Identifier != "" {
+		pkgs = append(pkgs, osv.Package{Ecosystem: "npm", Name: m.Identifier})
+	}
+	if m := pc.RubyGemsMonitor; m != nil && m.Identifier != "" {
+		pkgs = append(pkgs, osv.Package{Ecosystem: "RubyGems", Name: m.Identifier})
+	}
+	return pkgs
+}
+
+// fixedBy tells if an update from current to latest crosses a version the vulnerability is fixed in for one of the
+// packages, i.e. a fixed version after current up to latest. Fixes of other packages the record names, and ranges of
+// git commits, are ignored.
+func fixedBy(v *osv.Vulnerability, pkgs []osv.Package, current, latest *version.Version) bool {
+	for _, pkg := range pkgs {
+		for _, f := range v.FixedVersions(pkg) {
+			if pkg.Ecosystem == "Wolfi" {
+				f = wolfiRelease.ReplaceAllString(f, "")
+			}
+			fixed, err := wolfiversions.NewVersion(f)
+			if err != nil {
+				continue
+			}
+			if fixed.GreaterThan(current) && !fixed.GreaterThan(latest) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fetchOSVVulnerability gets the OSV record of a vulnerability, nil if OSV doesn't know it
+func (o *Options) fetchOSVVulnerability(id string) (*osv.Vulnerability, error) {
+	target := fmt.Sprintf("%s/v1/vulns/%s", strings.TrimSuffix(o.OSVURL, "/"), url.PathEscape(id))
+	req, err := http.NewRequestWithContext(o.context(), http.MethodGet, target, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating GET request %s: %w", target, err)
+	}
+	resp, err := o.OSVHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting URI %s: %w", target, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("non ok http response for URI %s code: %v: %s", target, resp.StatusCode, b)
+	}
+	v := &osv.Vulnerability{}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return nil, fmt.Errorf("failed to parse OSV vulnerability %s: %w", id, err)
+	}
+	return v, nil
+}
+
+// findSecurityFixes finds the vulnerabilities with a pending advisory that the updates fix according to OSV, by
+// package, so their pull requests are proposed first and flagged. Vulnerabilities that can't be looked up are
+// logged, and don't fail the run.
+func (o *Options) findSecurityFixes(updates map[string]NewVersionResults) (map[string][]string, error) {
+	if o.AdvisoriesDir == "" {
+		return nil, nil
+	}
+	advisoryCfgs, err := advisoryconfigs.NewIndex(rwos.DirFS(o.AdvisoriesDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the advisories in %s: %w", o.AdvisoriesDir, err)
+	}
+	return o.securityFixesOf(advisoryCfgs, updates), nil
+}
+
+func (o *Options) securityFixesOf(advisoryCfgs *configs.Index[advisoryconfigs.Document], updates map[string]NewVersionResults) map[string][]string {
+	fixes := make(map[string][]string)
+	for _, name := range sortedKeys(updates) {
+		pc, ok := o.PackageConfigs[name]
+		if !ok {
+			continue
+		}
+		pending := advisory.PendingVulnerabilities(advisoryCfgs.Select().WhereName(name).Configurations())
+		if len(pending) == 0 {
+			continue
+		}
+		current, err := wolfiversions.NewVersion(pc.Config.Package.Version)
+		if err != nil {
+			continue
+		}
+		latest, err := wolfiversions.NewVersion(updates[name].Version)
+		if err != nil {
+			continue
+		}
+		pkgs := osvPackages(pc)
+		for _, id := range pending {
+			v, err := o.fetchOSVVulnerability(id)
+			if err != nil {
+				o.Logger.Printf("%s: failed to check if %s fixes %s: %s", name, updates[name].Version, id, err)
+				continue
+			}
+			if v != nil && fixedBy(v, pkgs, current, latest) {
+				fixes[name] = append(fixes[name], id)
+			}
+		}
+		if len(fixes[name]) > 0 {
+			o.Logger.Printf("%s: %s fixes %s", name, updates[name].Version, strings.Join(fixes[name], ", "))
+		}
+	}
+	return fixes
+}
+
+// fixedVulnerabilities returns the vulnerabilities the update of a package, or of the members of a group, fixes
+func (o *Options) fixedVulnerabilities(packageName string, newVersion NewVersionResults) []string {
+	ids := append([]string{}, o.securityFixes[packageName]...)
+	for m := range newVersion.Members {
+		ids = append(ids, o.securityFixes[m]...)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// updateOrder returns the packages to update with the ones that fix vulnerabilities first, so their pull requests
+// are proposed before a run is canceled or rate limited
+func (o *Options) updateOrder(updates map[string]NewVersionResults) []string {
+	names := sortedKeys(updates)
+	sort.SliceStable(names, func(i, j int) bool {
+		return len(o.fixedVulnerabilities(names[i], updates[names[i]])) > 0 &&
+			len(o.fixedVulnerabilities(names[j], updates[names[j]])) == 0
+	})
+	return names
+}
+
+// securityReport is the section of the body of a pull request listing the vulnerabilities the update fixes
+func securityReport(ids []string) string {
+	if len(ids) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n### Security fixes\n\nThis update fixes vulnerabilities with pending advisories:\n\n")
+	for _, id := range ids {
+		fmt.Fprintf(&b, "- [%s](https://osv.dev/vulnerability/%s)\n", id, id)
+	}
+	return b.String()
+}
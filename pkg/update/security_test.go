@@ -0,0 +1,98 @@
+package update
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+func TestOptions_findSecurityFixes(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "curl.advisories.yaml"), []byte(`package:
+  name: curl
+
+advisories:
+  CVE-2023-0001:
+    - timestamp: 2023-05-01T10:00:00+00:00
+      status: under_investigation
+  CVE-2023-0002:
+    - timestamp: 2023-05-01T10:00:00+00:00
+      status: affected
+  CVE-2023-0003:
+    - timestamp: 2023-05-01T10:00:00+00:00
+      status: under_investigation
+  CVE-2023-0004:
+    - timestamp: 2023-05-01T10:00:00+00:00
+      status: under_investigation
+    - timestamp: 2023-05-02T10:00:00+00:00
+      status: fixed
+      fixed-version: 8.1.0-r0
+  CVE-2023-0005:
+    - timestamp: 2023-05-01T10:00:00+00:00
+      status: under_investigation
+`), 0o600))
+
+	osv := map[string]string{
+		// fixed by the new version
+		"CVE-2023-0001": `{"id": "CVE-2023-0001", "affected": [{"package": {"ecosystem": "Wolfi", "name": "curl"}, "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "8.2.0-r0"}]}]}]}`,
+		// fixed in a later version only
+		"CVE-2023-0002": `{"id": "CVE-2023-0002", "affected": [{"package": {"ecosystem": "Wolfi", "name": "curl"}, "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "8.0.0"}, {"fixed": "8.3.0"}]}]}]}`,
+		// fixed by a commit, which doesn't tell the version, and by the new version of another package
+		"CVE-2023-0003": `{"id": "CVE-2023-0003", "affected": [{"package": {"ecosystem": "Wolfi", "name": "curl"}, "ranges": [{"type": "GIT", "events": [{"introduced": "0"}, {"fixed": "8.2.0"}]}]}, {"package": {"ecosystem": "PyPI", "name": "pycurl"}, "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "8.2.0"}]}]}]}`,
+		// fixed in another branch, and by the new version of the project on PyPI
+		"CVE-2023-0005": `{"id": "CVE-2023-0005", "affected": [{"package": {"ecosystem": "PyPI", "name": "curl"}, "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "7.0.0"}, {"fixed": "7.88.1"}, {"introduced": "8.0.0"}, {"fixed": "v8.1.2"}]}]}]}`,
+	}
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := filepath.Base(r.URL.Path)
+		requested = append(requested, id)
+		body, ok := osv[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, err := w.Write([]byte(body))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	o := Options{
+		Logger:        log.New(io.Discard, "", 0),
+		AdvisoriesDir: dir,
+		OSVURL:        server.URL,
+		OSVHTTPClient: &http2.RLHTTPClient{Client: server.Client(), Ratelimiter: rate.NewLimiter(rate.Inf, 1)},
+		PackageConfigs: map[string]*melange.Packages{
+			"curl": {
+				Config:      build.Configuration{Package: build.Package{Name: "curl", Version: "8.1.1"}},
+				PyPIMonitor: &melange.PyPIMonitor{Identifier: "curl"},
+			},
+			"jq": {Config: build.Configuration{Package: build.Package{Name: "jq", Version: "1.6"}}},
+		},
+	}
+	updates := map[string]NewVersionResults{
+		"curl": {Version: "8.2.0"},
+		"jq":   {Version: "1.7"},
+	}
+	fixes, err := o.findSecurityFixes(updates)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"curl": {"CVE-2023-0001", "CVE-2023-0005"}}, fixes)
+	assert.NotContains(t, requested, "CVE-2023-0004", "only pending advisories should be looked up")
+
+	o.securityFixes = fixes
+	updates["apk-tools"] = NewVersionResults{Version: "2.14.0"}
+	assert.Equal(t, []string{"curl", "apk-tools", "jq"}, o.updateOrder(updates), "updates fixing vulnerabilities should be proposed first")
+	assert.Contains(t, o.pullRequestBody("curl", updates["curl"]), "- [CVE-2023-0005](https://osv.dev/vulnerability/CVE-2023-0005)")
+	assert.NotContains(t, o.pullRequestBody("jq", updates["jq"]), "Security fixes")
+}
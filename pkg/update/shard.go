@@ -0,0 +1,51 @@
+package update
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Shard selects a deterministic subset of packages, so an update run can be split across several jobs that each
+// scan a disjoint part of the repository. Index is 1-based, from 1 to Total.
+type Shard struct {
+	Index int
+	Total int
+}
+
+// ParseShard parses a shard given as index/total, e.g. 3/10.
+func ParseShard(s string) (*Shard, error) {
+	i, t, ok := strings.Cut(s, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid shard %q, expected index/total like 3/10", s)
+	}
+	index, err := strconv.Atoi(i)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shard index %q: %w", i, err)
+	}
+	total, err := strconv.Atoi(t)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shard total %q: %w", t, err)
+	}
+	if total < 1 {
+		return nil, errors.New("shard total must be at least 1")
+	}
+	if index < 1 || index > total {
+		return nil, fmt.Errorf("shard index must be between 1 and %d", total)
+	}
+	return &Shard{Index: index, Total: total}, nil
+}
+
+func (s Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Total)
+}
+
+// Contains reports whether the package belongs to the shard. Packages are assigned by a hash of their name, so the
+// assignment is stable across runs and doesn't depend on which other packages exist.
+func (s Shard) Contains(packageName string) bool {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(packageName))
+	return int(h.Sum32()%uint32(s.Total)) == s.Index-1
+}
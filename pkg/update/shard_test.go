@@ -0,0 +1,37 @@
+package update
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseShard(t *testing.T) {
+	s, err := ParseShard("3/10")
+	require.NoError(t, err)
+	assert.Equal(t, &Shard{Index: 3, Total: 10}, s)
+	assert.Equal(t, "3/10", s.String())
+
+	for _, invalid := range []string{"3", "0/10", "11/10", "1/0", "a/10", "1/b"} {
+		_, err := ParseShard(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestShard_Contains(t *testing.T) {
+	const total = 4
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprintf("package-%d", i)
+		shards := 0
+		for index := 1; index <= total; index++ {
+			if (Shard{Index: index, Total: total}).Contains(name) {
+				shards++
+			}
+		}
+		assert.Equal(t, 1, shards, "%s should be in exactly one shard", name)
+	}
+
+	assert.True(t, Shard{Index: 1, Total: 1}.Contains("anything"))
+}
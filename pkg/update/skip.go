@@ -0,0 +1,92 @@
+package update
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+
+	"github.com/hashicorp/go-version"
+
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+// reasons the update of a package to the version found upstream is refused, unless forced
+const (
+	SkipDowngrade  = "downgrade"
+	SkipReverted   = "reverted"
+	SkipPreRelease = "pre-release"
+)
+
+// DefaultHistoryDepth is how many commits of the repository are cloned by default to find the updates that were
+// reverted
+const DefaultHistoryDepth = 1000
+
+// SkippedUpdate is a version found upstream that a package isn't updated to, and why
+type SkippedUpdate struct {
+	Package        string `json:"package"`
+	CurrentVersion string `json:"currentVersion"`
+	Version        string `json:"version"`
+	Reason         string `json:"reason"`
+	Explanation    string `json:"explanation"`
+}
+
+// revertCommit matches the subject of a commit reverting one of wolfictl update, like
+// Revert "curl/8.1.2 package update (#1234)"
+var revertCommit = regexp.MustCompile(`^Revert "([^/"\s]+)/(\S+) package update`)
+
+// revertedUpdates returns the versions that updates of packages to were reverted, by package, from the subjects of the
+// commits of the clone in dir. Only the history cloned is searched.
+func revertedUpdates(dir string) (map[string]map[string]bool, error) {
+	cmd := exec.Command("git", "log", "--format=%s", `--grep=^Revert "`)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find reverted updates with git log: %w", err)
+	}
+
+	reverted := make(map[string]map[string]bool)
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		m := revertCommit.FindStringSubmatch(s.Text())
+		if m == nil {
+			continue
+		}
+		if reverted[m[1]] == nil {
+			reverted[m[1]] = make(map[string]bool)
+		}
+		reverted[m[1]][m[2]] = true
+	}
+	return reverted, s.Err()
+}
+
+// refuseUpdate returns why a package shouldn't be updated from its current version to latest, or an empty reason if it
+// can be: versions lower than the current one, versions whose update was reverted, and pre-releases, unless the
+// config of the package opts in to them. Datasources that know about yanked releases already skip them.
+func (o *Options) refuseUpdate(pc *melange.Packages, current, latest *version.Version) (reason, explanation string) {
+	name := pc.Config.Package.Name
+	switch {
+	case latest.LessThan(current):
+		return SkipDowngrade, fmt.Sprintf("%s is lower than the current version %s of %s", latest.Original(), current.Original(), name)
+	case o.revertedVersions[name][latest.Original()]:
+		return SkipReverted, fmt.Sprintf("the update of %s to %s was reverted before", name, latest.Original())
+	case isPreRelease(latest.Original()) && !allowsPreReleases(pc):
+		return SkipPreRelease, fmt.Sprintf("%s looks like a pre-release, and the update config of %s doesn't include pre-releases", latest.Original(), name)
+	}
+	return "", ""
+}
+
+// allowsPreReleases tells if the update config of a package opts in to pre-releases
+func allowsPreReleases(pc *melange.Packages) bool {
+	return (pc.PyPIMonitor != nil && pc.PyPIMonitor.PreReleases) ||
+		(pc.NpmMonitor != nil && pc.NpmMonitor.PreReleases) ||
+		(pc.RubyGemsMonitor != nil && pc.RubyGemsMonitor.PreReleases) ||
+		!pc.ReleaseMonitor.IsStableOnly()
+}
+
+// skipUpdate records an update refused for the skipped updates of the run summary
+func (o *Options) skipUpdate(s SkippedUpdate) {
+	o.Logger.Printf("not updating %s to %s: %s", s.Package, s.Version, s.Explanation)
+	o.Summary.recordSkipped(s)
+}
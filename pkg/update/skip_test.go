@@ -0,0 +1,119 @@
+package update
+
+import (
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"chainguard.dev/melange/pkg/build"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+func TestRevertedUpdates(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	for i, subject := range []string{
+		"curl/8.1.2 package update (#1234)",
+		`Revert "curl/8.1.2 package update (#1234)"`,
+		`Revert "jq/1.7 package update"`,
+		`Revert "bump openssl"`,
+		"jq/1.7.1 package update",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), []byte(subject), 0o600))
+		_, err := wt.Add("file")
+		require.NoError(t, err)
+		_, err = wt.Commit(subject, &git.CommitOptions{
+			Author: &object.Signature{Name: "John Doe", Email: "john@doe.org", When: time.Unix(int64(i), 0)},
+		})
+		require.NoError(t, err)
+	}
+
+	got, err := revertedUpdates(dir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]bool{
+		"curl": {"8.1.2": true},
+		"jq":   {"1.7": true},
+	}, got)
+}
+
+func TestOptions_getPackagesToUpdate(t *testing.T) {
+	config := func(name, version string) *melange.Packages {
+		return &melange.Packages{Config: build.Configuration{Package: build.Package{Name: name, Version: version}}}
+	}
+	prereleases := config("typescript", "5.1.6")
+	prereleases.NpmMonitor = &melange.NpmMonitor{Identifier: "typescript", PreReleases: true}
+
+	o := Options{
+		Logger:  log.New(io.Discard, "", 0),
+		Summary: NewRunSummary(),
+		PackageConfigs: map[string]*melange.Packages{
+			"curl":       config("curl", "8.1.2"),
+			"jq":         config("jq", "1.6"),
+			"openssl":    config("openssl", "3.1.1"),
+			"git":        config("git", "2.41.0"),
+			"requests":   config("requests", "2.30.0"),
+			"typescript": prereleases,
+			"zlib":       config("zlib", "1.2.13"),
+		},
+		revertedVersions: map[string]map[string]bool{"jq": {"1.7": true}},
+	}
+	latest := map[string]NewVersionResults{
+		"curl":       {Version: "8.2.0"},
+		"jq":         {Version: "1.7"},
+		"openssl":    {Version: "3.0.9"},
+		"git":        {Version: "2.42.0-rc1"},
+		"requests":   {Version: "2.31.0", SourceURL: "https://files.pythonhosted.org/requests-2.31.0.tar.gz", SourceSHA256: "abc"},
+		"typescript": {Version: "5.2.0-beta"},
+		"zlib":       {Version: "1.2.13"},
+	}
+
+	got, err := o.getPackagesToUpdate(latest)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]NewVersionResults{
+		"curl":       {Version: "8.2.0"},
+		"requests":   {Version: "2.31.0", SourceURL: "https://files.pythonhosted.org/requests-2.31.0.tar.gz", SourceSHA256: "abc"},
+		"typescript": {Version: "5.2.0-beta"},
+	}, got, "the source found by the datasource should be kept, and pre-releases only proposed if the config includes them")
+
+	o.Summary.finish(nil)
+	assert.Equal(t, []SkippedUpdate{
+		{Package: "git", CurrentVersion: "2.41.0", Version: "2.42.0-rc1", Reason: SkipPreRelease, Explanation: "2.42.0-rc1 looks like a pre-release, and the update config of git doesn't include pre-releases"},
+		{Package: "jq", CurrentVersion: "1.6", Version: "1.7", Reason: SkipReverted, Explanation: "the update of jq to 1.7 was reverted before"},
+		{Package: "openssl", CurrentVersion: "3.1.1", Version: "3.0.9", Reason: SkipDowngrade, Explanation: "3.0.9 is lower than the current version 3.1.1 of openssl"},
+	}, o.Summary.Skipped)
+
+	o.Force = true
+	o.Summary = NewRunSummary()
+	got, err = o.getPackagesToUpdate(latest)
+	require.NoError(t, err)
+	assert.Len(t, got, 6, "forced updates should be proposed, but not to the current version")
+	assert.Empty(t, o.Summary.Skipped)
+
+	now := time.Now().UTC()
+	o.policy = &Policy{Freezes: []Freeze{{Name: "release", Start: now.AddDate(0, 0, -1), End: now.AddDate(0, 0, 1)}}}
+	o.Summary = NewRunSummary()
+	got, err = o.getPackagesToUpdate(latest)
+	require.NoError(t, err)
+	assert.Empty(t, got, "the policy should apply even with --force")
+	o.Summary.finish(nil)
+	require.Len(t, o.Summary.Skipped, 6)
+	for _, s := range o.Summary.Skipped {
+		assert.Equal(t, SkipFrozen, s.Reason, s.Package)
+	}
+}
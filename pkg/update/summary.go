@@ -0,0 +1,392 @@
+package update
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+)
+
+// causes used to group failed updates in the run summary
+const (
+	FailureGitHubLookup         = "github-lookup"
+	FailureReleaseMonitorLookup = "release-monitor-lookup"
+	FailureGitLabLookup         = "gitlab-lookup"
+	FailurePyPILookup           = "pypi-lookup"
+	FailureCratesLookup         = "crates-lookup"
+	FailureGoModuleLookup       = "go-module-lookup"
+	FailureNpmLookup            = "npm-lookup"
+	FailureRubyGemsLookup       = "rubygems-lookup"
+	FailureScrapeLookup         = "scrape-lookup"
+	FailureBump                 = "bump"
+	FailureExpectedCommit       = "expected-commit"
+	FailureSourceChecksums      = "source-checksums"
+	FailureMakefile             = "makefile"
+	FailureGitModules           = "gitmodules"
+	FailureProposeChanges       = "propose-changes"
+	FailureValidation           = "validation"
+)
+
+const (
+	apiGitHub         = "github"
+	apiReleaseMonitor = "release-monitor"
+	apiGitLab         = "gitlab"
+	apiOSV            = "osv"
+
+	pushgatewayJob = "wolfictl_update"
+)
+
+// RunSummary is a structured account of a single update run, emitted at the end so the health of the updater can be
+// observed without scraping logs
+type RunSummary struct {
+	StartTime          time.Time        `json:"startTime"`
+	EndTime            time.Time        `json:"endTime"`
+	PackagesScanned    int              `json:"packagesScanned"`
+	PackagesOutdated   int              `json:"packagesOutdated"`
+	PullRequestsOpened int              `json:"pullRequestsOpened"`
+	IssuesOpened       int              `json:"issuesOpened"`
+	Failures           map[string]int   `json:"failures"`
+	APICalls           map[string]int64 `json:"apiCalls"`
+	Error              string           `json:"error,omitempty"`
+	// Shard is the shard the run scanned, e.g. 3/10, empty if it scanned every package
+	Shard string `json:"shard,omitempty"`
+	// Shards is the number of shards merged into the summary by MergeRunSummaries
+	Shards int `json:"shards,omitempty"`
+	// Skipped are the updates refused, e.g. to versions lower than the current ones, sorted by package
+	Skipped []SkippedUpdate `json:"skipped,omitempty"`
+	// SecurityFixes are the vulnerabilities with pending advisories the updates fix, by package
+	SecurityFixes map[string][]string `json:"securityFixes,omitempty"`
+
+	mu       sync.Mutex
+	counters map[string]*http2.CountingTransport
+}
+
+// NewRunSummary returns an empty summary with its start time set
+func NewRunSummary() *RunSummary {
+	return &RunSummary{
+		StartTime: time.Now(),
+		Failures:  make(map[string]int),
+		APICalls:  make(map[string]int64),
+		counters:  make(map[string]*http2.CountingTransport),
+	}
+}
+
+// trackAPICalls wraps the rate limited client so requests made through it are counted against the named service
+func (s *RunSummary) trackAPICalls(service string, c *http2.RLHTTPClient) {
+	if c == nil || c.Client == nil {
+		return
+	}
+	var t *http2.CountingTransport
+	c.Client, t = http2.NewCountingClient(c.Client)
+	s.counters[service] = t
+}
+
+// the record helpers are safe to call on a nil summary so callers that build Options by hand don't need one
+
+func (s *RunSummary) recordFailure(cause string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Failures[cause]++
+}
+
+func (s *RunSummary) recordPullRequest() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.PullRequestsOpened++
+}
+
+func (s *RunSummary) recordIssue() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.IssuesOpened++
+}
+
+func (s *RunSummary) recordSkipped(skipped SkippedUpdate) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Skipped = append(s.Skipped, skipped)
+}
+
+func (s *RunSummary) recordSecurityFixes(fixes map[string][]string) {
+	if s == nil || len(fixes) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.SecurityFixes == nil {
+		s.SecurityFixes = make(map[string][]string)
+	}
+	for name, ids := range fixes {
+		s.SecurityFixes[name] = ids
+	}
+}
+
+// finish stamps the end time and collects the API call counts
+func (s *RunSummary) finish(err error) {
+	s.EndTime = time.Now()
+	for service, t := range s.counters {
+		s.APICalls[service] = t.Count()
+	}
+	if err != nil {
+		s.Error = err.Error()
+	}
+	sortSkipped(s.Skipped)
+}
+
+func sortSkipped(skipped []SkippedUpdate) {
+	sort.Slice(skipped, func(i, j int) bool {
+		return skipped[i].Package < skipped[j].Package
+	})
+}
+
+// WriteJSON writes the summary as indented JSON
+func (s *RunSummary) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// metrics renders the summary in the Prometheus text exposition format
+func (s *RunSummary) metrics() []byte {
+	var b bytes.Buffer
+
+	gauge := func(name, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+
+	gauge("wolfictl_update_packages_scanned", "Number of packages with updates enabled that were checked.")
+	fmt.Fprintf(&b, "wolfictl_update_packages_scanned %d\n", s.PackagesScanned)
+	gauge("wolfictl_update_packages_outdated", "Number of packages with a newer upstream version.")
+	fmt.Fprintf(&b, "wolfictl_update_packages_outdated %d\n", s.PackagesOutdated)
+	gauge("wolfictl_update_pull_requests_opened", "Number of pull requests opened.")
+	fmt.Fprintf(&b, "wolfictl_update_pull_requests_opened %d\n", s.PullRequestsOpened)
+	gauge("wolfictl_update_issues_opened", "Number of issues opened for packages that require a manual update.")
+	fmt.Fprintf(&b, "wolfictl_update_issues_opened %d\n", s.IssuesOpened)
+
+	gauge("wolfictl_update_failures", "Number of failed package updates by cause.")
+	for _, cause := range sortedKeys(s.Failures) {
+		fmt.Fprintf(&b, "wolfictl_update_failures{cause=%q} %d\n", cause, s.Failures[cause])
+	}
+
+	gauge("wolfictl_update_skipped", "Number of updates refused by reason.")
+	skipped := make(map[string]int)
+	for _, u := range s.Skipped {
+		skipped[u.Reason]++
+	}
+	for _, reason := range sortedKeys(skipped) {
+		fmt.Fprintf(&b, "wolfictl_update_skipped{reason=%q} %d\n", reason, skipped[reason])
+	}
+
+	gauge("wolfictl_update_security_fixes", "Number of outdated packages whose update fixes vulnerabilities with pending advisories.")
+	fmt.Fprintf(&b, "wolfictl_update_security_fixes %d\n", len(s.SecurityFixes))
+
+	gauge("wolfictl_update_api_calls", "Number of HTTP requests made by service.")
+	for _, service := range sortedKeys(s.APICalls) {
+		fmt.Fprintf(&b, "wolfictl_update_api_calls{service=%q} %d\n", service, s.APICalls[service])
+	}
+
+	gauge("wolfictl_update_duration_seconds", "Duration of the update run.")
+	fmt.Fprintf(&b, "wolfictl_update_duration_seconds %f\n", s.EndTime.Sub(s.StartTime).Seconds())
+
+	success := 1
+	if s.Error != "" {
+		success = 0
+	}
+	gauge("wolfictl_update_last_run_success", "Whether the update run completed without a fatal error.")
+	fmt.Fprintf(&b, "wolfictl_update_last_run_success %d\n", success)
+	gauge("wolfictl_update_last_run_timestamp_seconds", "Unix time the update run finished.")
+	fmt.Fprintf(&b, "wolfictl_update_last_run_timestamp_seconds %d\n", s.EndTime.Unix())
+
+	return b.Bytes()
+}
+
+// PushMetrics replaces the metrics for the updater job on a Prometheus pushgateway. The metrics of a shard are
+// grouped by its index, so shards don't replace each other's, and the metrics merged from every shard are grouped by
+// shard "all", so they don't replace the ones of unsharded runs
+func (s *RunSummary) PushMetrics(client *http.Client, gatewayURL string) error {
+	targetURL := fmt.Sprintf("%s/metrics/job/%s", strings.TrimSuffix(gatewayURL, "/"), pushgatewayJob)
+	if index, _, ok := strings.Cut(s.Shard, "/"); ok {
+		targetURL += "/shard/" + index
+	} else if s.Shards > 0 {
+		targetURL += "/shard/all"
+	}
+	req, err := http.NewRequest(http.MethodPut, targetURL, bytes.NewReader(s.metrics()))
+	if err != nil {
+		return fmt.Errorf("failed creating PUT request %s: %w", targetURL, err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed pushing metrics to %s: %w", targetURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("non ok http response for URI %s code: %v: %s", targetURL, resp.StatusCode, b)
+	}
+	return nil
+}
+
+// ReadRunSummary reads a summary written by WriteJSON
+func ReadRunSummary(path string) (*RunSummary, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &RunSummary{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("failed to parse run summary %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// MergeRunSummaries combines the summaries of the shards of a run into a summary of the whole run. It fails if the
+// summaries aren't from the shards of a single run, or if any shard is missing or given more than once
+func MergeRunSummaries(summaries ...*RunSummary) (*RunSummary, error) {
+	if len(summaries) == 0 {
+		return nil, errors.New("no run summaries to merge")
+	}
+
+	merged := &RunSummary{
+		Failures: make(map[string]int),
+		APICalls: make(map[string]int64),
+	}
+	seen := make(map[int]bool)
+	total := 0
+	var errs []string
+	for _, s := range summaries {
+		shard, err := ParseShard(s.Shard)
+		if err != nil {
+			return nil, fmt.Errorf("summary isn't from a shard: %w", err)
+		}
+		if total == 0 {
+			total = shard.Total
+		}
+		if shard.Total != total {
+			return nil, fmt.Errorf("shard %s isn't one of %d shards", shard, total)
+		}
+		if seen[shard.Index] {
+			return nil, fmt.Errorf("shard %s given more than once", shard)
+		}
+		seen[shard.Index] = true
+
+		if merged.StartTime.IsZero() || s.StartTime.Before(merged.StartTime) {
+			merged.StartTime = s.StartTime
+		}
+		if s.EndTime.After(merged.EndTime) {
+			merged.EndTime = s.EndTime
+		}
+		merged.PackagesScanned += s.PackagesScanned
+		merged.PackagesOutdated += s.PackagesOutdated
+		merged.PullRequestsOpened += s.PullRequestsOpened
+		merged.IssuesOpened += s.IssuesOpened
+		for cause, n := range s.Failures {
+			merged.Failures[cause] += n
+		}
+		for service, n := range s.APICalls {
+			merged.APICalls[service] += n
+		}
+		merged.Skipped = append(merged.Skipped, s.Skipped...)
+		merged.recordSecurityFixes(s.SecurityFixes)
+		if s.Error != "" {
+			errs = append(errs, fmt.Sprintf("shard %s: %s", shard, s.Error))
+		}
+	}
+
+	var missing []string
+	for i := 1; i <= total; i++ {
+		if !seen[i] {
+			missing = append(missing, Shard{Index: i, Total: total}.String())
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing the summaries of shards %s", strings.Join(missing, ", "))
+	}
+
+	merged.Shards = total
+	sortSkipped(merged.Skipped)
+	sort.Strings(errs)
+	merged.Error = strings.Join(errs, "; ")
+	return merged, nil
+}
+
+// ReportSummary logs the summary, and writes it to the configured file and pushgateway
+func (o *Options) ReportSummary() error {
+	s := o.Summary
+	o.Logger.Printf("scanned %d packages, %d outdated, %d pull requests and %d issues opened, %d failures",
+		s.PackagesScanned, s.PackagesOutdated, s.PullRequestsOpened, s.IssuesOpened, s.totalFailures())
+	if len(s.Skipped) > 0 {
+		o.Logger.Printf("%d updates skipped, use --force to propose them:", len(s.Skipped))
+		for _, u := range s.Skipped {
+			o.Logger.Printf("  %s %s -> %s (%s): %s", u.Package, u.CurrentVersion, u.Version, u.Reason, u.Explanation)
+		}
+	}
+
+	for _, name := range sortedKeys(s.SecurityFixes) {
+		o.Logger.Printf("%s fixes %s", name, strings.Join(s.SecurityFixes[name], ", "))
+	}
+
+	if o.SummaryFile != "" {
+		if o.SummaryFile == "-" {
+			if err := s.WriteJSON(os.Stdout); err != nil {
+				return fmt.Errorf("failed to write run summary: %w", err)
+			}
+		} else {
+			f, err := os.Create(o.SummaryFile)
+			if err != nil {
+				return fmt.Errorf("failed to create run summary file %s: %w", o.SummaryFile, err)
+			}
+			defer f.Close()
+			if err := s.WriteJSON(f); err != nil {
+				return fmt.Errorf("failed to write run summary to %s: %w", o.SummaryFile, err)
+			}
+		}
+	}
+
+	if o.PushgatewayURL != "" {
+		if err := s.PushMetrics(http.DefaultClient, o.PushgatewayURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RunSummary) totalFailures() int {
+	total := 0
+	for _, n := range s.Failures {
+		total += n
+	}
+	return total
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,154 @@
+package update
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
+)
+
+func TestRunSummary_APICalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http2.RLHTTPClient{
+		Client:      http.DefaultClient,
+		Ratelimiter: rate.NewLimiter(rate.Inf, 1),
+	}
+
+	s := NewRunSummary()
+	s.trackAPICalls(apiGitHub, client)
+
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest(http.MethodGet, server.URL, http.NoBody)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	s.finish(nil)
+	assert.Equal(t, int64(3), s.APICalls[apiGitHub])
+}
+
+func TestRunSummary_PushMetrics(t *testing.T) {
+	s := NewRunSummary()
+	s.PackagesScanned = 10
+	s.PackagesOutdated = 3
+	s.recordPullRequest()
+	s.recordPullRequest()
+	s.recordFailure(FailureBump)
+	s.finish(errors.New("boom"))
+
+	var got []byte
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPut, req.Method)
+		assert.Equal(t, "/metrics/job/wolfictl_update", req.URL.Path)
+		var err error
+		got, err = io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	require.NoError(t, s.PushMetrics(server.Client(), server.URL+"/"))
+
+	assert.Contains(t, string(got), "wolfictl_update_packages_scanned 10\n")
+	assert.Contains(t, string(got), "wolfictl_update_packages_outdated 3\n")
+	assert.Contains(t, string(got), "wolfictl_update_pull_requests_opened 2\n")
+	assert.Contains(t, string(got), "wolfictl_update_failures{cause=\"bump\"} 1\n")
+	assert.Contains(t, string(got), "wolfictl_update_last_run_success 0\n")
+}
+
+func TestRunSummary_WriteJSON(t *testing.T) {
+	s := NewRunSummary()
+	s.PackagesScanned = 2
+	s.recordFailure(FailureGitHubLookup)
+	s.recordFailure(FailureGitHubLookup)
+	s.finish(nil)
+
+	var b bytes.Buffer
+	require.NoError(t, s.WriteJSON(&b))
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(b.Bytes(), &got))
+	assert.Equal(t, float64(2), got["packagesScanned"])
+	assert.Equal(t, map[string]any{FailureGitHubLookup: float64(2)}, got["failures"])
+	assert.NotContains(t, got, "error")
+	assert.WithinDuration(t, time.Now(), s.EndTime, time.Minute)
+}
+
+func TestMergeRunSummaries(t *testing.T) {
+	start := time.Date(2023, 5, 1, 2, 0, 0, 0, time.UTC)
+	shard := func(s string, scanned int, failures map[string]int, err string) *RunSummary {
+		return &RunSummary{
+			StartTime:       start,
+			EndTime:         start.Add(time.Duration(scanned) * time.Minute),
+			PackagesScanned: scanned,
+			Failures:        failures,
+			APICalls:        map[string]int64{apiGitHub: int64(scanned)},
+			Error:           err,
+			Shard:           s,
+		}
+	}
+
+	merged, err := MergeRunSummaries(
+		shard("2/2", 5, map[string]int{FailureBump: 1}, "boom"),
+		shard("1/2", 10, map[string]int{FailureBump: 2, FailureMakefile: 1}, ""),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 15, merged.PackagesScanned)
+	assert.Equal(t, map[string]int{FailureBump: 3, FailureMakefile: 1}, merged.Failures)
+	assert.Equal(t, int64(15), merged.APICalls[apiGitHub])
+	assert.Equal(t, start.Add(10*time.Minute), merged.EndTime)
+	assert.Equal(t, "shard 2/2: boom", merged.Error)
+	assert.Empty(t, merged.Shard)
+	assert.Equal(t, 2, merged.Shards)
+
+	_, err = MergeRunSummaries(shard("1/3", 1, nil, ""), shard("3/3", 1, nil, ""))
+	assert.ErrorContains(t, err, "2/3")
+	_, err = MergeRunSummaries(shard("1/2", 1, nil, ""), shard("1/2", 1, nil, ""))
+	assert.Error(t, err)
+	_, err = MergeRunSummaries(shard("1/2", 1, nil, ""), shard("2/3", 1, nil, ""))
+	assert.Error(t, err)
+}
+
+func TestRunSummary_PushMetricsShard(t *testing.T) {
+	s := NewRunSummary()
+	s.Shard = "3/10"
+	s.finish(nil)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/metrics/job/wolfictl_update/shard/3", req.URL.Path)
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	require.NoError(t, s.PushMetrics(server.Client(), server.URL))
+}
+
+func TestRunSummary_PushMetricsMerged(t *testing.T) {
+	s := NewRunSummary()
+	s.Shards = 10
+	s.finish(nil)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/metrics/job/wolfictl_update/shard/all", req.URL.Path)
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	require.NoError(t, s.PushMetrics(server.Client(), server.URL))
+}
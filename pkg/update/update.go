@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -17,17 +18,20 @@ import (
 	"github.com/fatih/color"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/google/go-github/v50/github"
-	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"golang.org/x/exp/maps"
 	"golang.org/x/oauth2"
 	"golang.org/x/time/rate"
 
+	"github.com/wolfi-dev/wolfictl/pkg/builder"
 	"github.com/wolfi-dev/wolfictl/pkg/gh"
 	wgit "github.com/wolfi-dev/wolfictl/pkg/git"
 	"github.com/wolfi-dev/wolfictl/pkg/git/submodules"
+	"github.com/wolfi-dev/wolfictl/pkg/groups"
 	http2 "github.com/wolfi-dev/wolfictl/pkg/http"
 	"github.com/wolfi-dev/wolfictl/pkg/melange"
 )
@@ -43,24 +47,106 @@ type Options struct {
 	DryRun                 bool
 	ReleaseMonitoringQuery bool
 	GithubReleaseQuery     bool
+	GitLabReleaseQuery     bool
+	PyPIQuery              bool
+	CratesQuery            bool
+	GoModuleQuery          bool
+	NpmQuery               bool
+	RubyGemsQuery          bool
+	ScrapeQuery            bool
 	UseGitSign             bool
 	CreateIssues           bool
 	Client                 *http2.RLHTTPClient
 	Logger                 *log.Logger
 	GitHubHTTPClient       *http2.RLHTTPClient
+	GitLabHTTPClient       *http2.RLHTTPClient
 	ErrorMessages          map[string]string
 	IssueLabels            []string
+	SummaryFile            string
+	PushgatewayURL         string
+	Summary                *RunSummary
+
+	// Shard, if set, restricts the run to the packages of that shard, so other jobs can scan the rest.
+	Shard *Shard
+
+	// FailureQueueFile, if set, is where the pull requests that couldn't be created even after retrying are saved,
+	// so RetryFailed can replay them later
+	FailureQueueFile string
+	// PullRequestAttempts and PullRequestBackoff bound the retries of pushing the branch of a pull request and
+	// opening it, the backoff doubles after every attempt
+	PullRequestAttempts int
+	PullRequestBackoff  time.Duration
+	// MaxSourceSize is the limit of the size in bytes of the source archives downloaded to recompute the checksums of
+	// the fetch steps of updated packages
+	MaxSourceSize int64
+	// Concurrency is how many packages every datasource checks at once, the datasources all run at once
+	Concurrency int
+	// DatasourceRateLimits are the rate limits of the requests of datasources sent with Client by datasource, like
+	// pypi, so they don't wait for each other. Datasources without one share the rate limit of Client.
+	DatasourceRateLimits map[string]rate.Limit
+	// Force proposes updates that are refused otherwise: to versions lower than the current one, to versions whose
+	// update was reverted, and to pre-releases of packages that don't opt in to them
+	Force bool
+	// ReleaseNotes adds the notes of the upstream releases since the current version to the pull requests of packages
+	// released on GitHub
+	ReleaseNotes bool
+	// HistoryDepth is how many commits of the repository are cloned to find the updates that were reverted, all of
+	// them if 0
+	HistoryDepth int
+	// AdvisoriesDir, if set, is the advisories repository, to flag the updates that OSV says fix vulnerabilities with
+	// pending advisories. Their pull requests are proposed first, get SecurityLabel, and list the vulnerabilities.
+	AdvisoriesDir string
+	SecurityLabel string
+	// OSVURL is the OSV API, and OSVHTTPClient the client of its requests
+	OSVURL        string
+	OSVHTTPClient *http2.RLHTTPClient
+	// Validate is how bumped packages are checked before their pull request is opened: ValidateNone, ValidateLint or
+	// ValidateBuild. Broken bumps are reported as failures rather than proposed.
+	Validate string
+	// BuildExecutor builds the packages validated with ValidateBuild, make in the directory of the configs if nil
+	BuildExecutor builder.Executor
+
+	failedPullRequests []FailedPullRequest
+	proposed           map[string]bool
+	// the checksums recomputed for the fetch steps of updated packages, reported in their pull requests
+	sourceChecksums map[string][]melange.SourceChecksum
+	// the datasource that found the latest version of each package, or failed to
+	datasources map[string]string
+	// the versions that updates were reverted from, by package
+	revertedVersions map[string]map[string]bool
+	// the update policy of the repository
+	policy *Policy
+	// the vulnerabilities with pending advisories fixed by the updates, by package
+	securityFixes map[string][]string
+	// what was checked of the bumped packages by package, reported in their pull requests
+	validations map[string]string
+	// canceled to stop the run, set by Update, RetryFailed and Report
+	ctx context.Context
 }
 
 type NewVersionResults struct {
-	Version                    string
-	Commit                     string
-	ReplaceExistingIssueNumber int
-	ReplaceExistingPRNumber    int
+	Version                    string `json:"version"`
+	Commit                     string `json:"commit,omitempty"`
+	ReplaceExistingIssueNumber int    `json:"replaceExistingIssueNumber,omitempty"`
+	ReplaceExistingPRNumber    int    `json:"replaceExistingPRNumber,omitempty"`
+	// UpdateExistingPRNumber is the open pull request of an earlier version on the branch of the package, which is
+	// pushed again and retitled rather than superseded
+	UpdateExistingPRNumber int `json:"updateExistingPRNumber,omitempty"`
+
+	// SourceURL and SourceSHA256 or SourceSHA512 are set by datasources that know the source archive of the new
+	// version, e.g. the sdist of a PyPI release or the download of a crate, so the fetch step of the config can be
+	// pointed at it
+	SourceURL    string `json:"sourceURL,omitempty"`
+	SourceSHA256 string `json:"sourceSHA256,omitempty"`
+	SourceSHA512 string `json:"sourceSHA512,omitempty"`
+
+	// Members are the updates of the packages of a group updated together by package, for the update of the group
+	Members map[string]NewVersionResults `json:"members,omitempty"`
 }
 
 const (
 	maxPullRequestRetries = 10
+	updateBranchPrefix    = "wolfictl-update-"
 	bot                   = "wolfi-bot"
 	wolfiImage            = `
 <p align="center">
@@ -69,6 +155,12 @@ const (
 `
 )
 
+// pushing a branch and opening its pull request are retried after 10s, 20s and 40s
+const (
+	defaultPullRequestAttempts = 4
+	defaultPullRequestBackoff  = 10 * time.Second
+)
+
 // New initialise including a map of existing wolfios packages
 func New() Options {
 	ts := oauth2.StaticTokenSource(
@@ -80,43 +172,125 @@ func New() Options {
 
 			// 1 request every (n) second(s) to avoid DOS'ing server
 			Ratelimiter: rate.NewLimiter(rate.Every(5*time.Second), 1),
+			// packages like the version streams of a python library ask about the same project
+			Cache: http2.NewResponseCache(),
 		},
 		GitHubHTTPClient: &http2.RLHTTPClient{
 			Client: oauth2.NewClient(context.Background(), ts),
 
 			// 1 request every (n) second(s) to avoid DOS'ing server. https://docs.github.com/en/rest/guides/best-practices-for-integrators?apiVersion=2022-11-28#dealing-with-secondary-rate-limits
 			Ratelimiter: rate.NewLimiter(rate.Every(5*time.Second), 1),
+			Cache:       http2.NewResponseCache(),
 		},
-		Logger:        log.New(log.Writer(), "wolfictl update: ", log.LstdFlags|log.Lmsgprefix),
-		DefaultBranch: "main",
-		ErrorMessages: make(map[string]string),
-	}
+		GitLabHTTPClient: &http2.RLHTTPClient{
+			Client: http.DefaultClient,
+
+			// 1 request every (n) second(s) to stay well within the rate limits of gitlab.com
+			Ratelimiter: rate.NewLimiter(rate.Every(time.Second), 1),
+			Cache:       http2.NewResponseCache(),
+		},
+		OSVHTTPClient: &http2.RLHTTPClient{
+			Client: http.DefaultClient,
+
+			// a few vulnerabilities are looked up per outdated package at most
+			Ratelimiter: rate.NewLimiter(10, 1),
+			Cache:       http2.NewResponseCache(),
+		},
+		Concurrency:   DefaultLookupConcurrency,
+		HistoryDepth:  DefaultHistoryDepth,
+		ReleaseNotes:  true,
+		OSVURL:        DefaultOSVURL,
+		SecurityLabel: DefaultSecurityLabel,
+		// within the published limits of the APIs, or their crawler policies, e.g. 1 request per second for crates.io
+		DatasourceRateLimits: map[string]rate.Limit{
+			datasourceName(FailureReleaseMonitorLookup): rate.Every(time.Second),
+			datasourceName(FailurePyPILookup):           10,
+			datasourceName(FailureCratesLookup):         rate.Every(time.Second),
+			datasourceName(FailureGoModuleLookup):       10,
+			datasourceName(FailureNpmLookup):            10,
+			datasourceName(FailureRubyGemsLookup):       5,
+			// the pages scraped are on all kinds of servers
+			datasourceName(FailureScrapeLookup): rate.Every(time.Second),
+		},
+		Logger:              log.New(log.Writer(), "wolfictl update: ", log.LstdFlags|log.Lmsgprefix),
+		DefaultBranch:       "main",
+		ErrorMessages:       make(map[string]string),
+		Summary:             NewRunSummary(),
+		PullRequestAttempts: defaultPullRequestAttempts,
+		PullRequestBackoff:  defaultPullRequestBackoff,
+		MaxSourceSize:       melange.DefaultMaxSourceSize,
+	}
+	options.Summary.trackAPICalls(apiReleaseMonitor, options.Client)
+	options.Summary.trackAPICalls(apiGitHub, options.GitHubHTTPClient)
+	options.Summary.trackAPICalls(apiGitLab, options.GitLabHTTPClient)
+	options.Summary.trackAPICalls(apiOSV, options.OSVHTTPClient)
 	return options
 }
 
-func (o *Options) Update() error {
-	// clone the melange config git repo into a temp folder so we can work with it
-	tempDir, err := os.MkdirTemp("", "wolfictl")
+// Update runs the updater and reports a summary of the run, even when it fails part way through, e.g. because ctx was
+// canceled
+func (o *Options) Update(ctx context.Context) error {
+	o.withContext(ctx)
+	if o.Summary == nil {
+		o.Summary = NewRunSummary()
+	}
+	if o.Shard != nil {
+		o.Summary.Shard = o.Shard.String()
+	}
+	err := o.checkFailureQueue()
+	if err == nil {
+		err = o.update()
+	}
+	if qerr := o.saveFailedPullRequests(); qerr != nil {
+		o.Logger.Printf("failed to save the pull requests to retry: %s", qerr)
+	}
+	o.Summary.finish(err)
+	if serr := o.ReportSummary(); serr != nil {
+		if err != nil {
+			o.Logger.Printf("failed to report run summary: %s", serr)
+			return err
+		}
+		return fmt.Errorf("failed to report run summary: %w", serr)
+	}
+	return err
+}
+
+// withContext stops the work of the run once ctx is canceled, including the requests of the HTTP clients
+func (o *Options) withContext(ctx context.Context) {
+	o.ctx = ctx
+	for _, c := range []**http2.RLHTTPClient{&o.Client, &o.GitHubHTTPClient, &o.GitLabHTTPClient, &o.OSVHTTPClient} {
+		if *c != nil {
+			*c = (*c).WithContext(ctx)
+		}
+	}
+}
+
+// context returns the context of the run, for Options used without Update
+func (o *Options) context() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
+}
+
+func (o *Options) update() error {
+	// enough history is cloned to find the updates that were reverted
+	repo, tempDir, err := o.cloneDepth(o.HistoryDepth, os.Stdout)
 	if err != nil {
-		return fmt.Errorf("failed to create temporary folder to clone package configs into: %w", err)
+		return err
 	}
 	if o.DryRun {
 		o.Logger.Printf("using working directory %s", tempDir)
 	} else {
-		defer os.Remove(tempDir)
+		defer os.RemoveAll(tempDir)
 	}
-
-	cloneOpts := &git.CloneOptions{
-		URL:               o.RepoURI,
-		Progress:          os.Stdout,
-		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
-		Auth:              wgit.GetGitAuth(),
-		Depth:             1,
+	o.revertedVersions, err = revertedUpdates(tempDir)
+	if err != nil {
+		return err
 	}
-
-	repo, err := git.PlainClone(tempDir, false, cloneOpts)
+	o.policy, err = ReadPolicy(tempDir)
 	if err != nil {
-		return fmt.Errorf("failed to clone repository %s into %s: %w", o.RepoURI, tempDir, err)
+		return err
 	}
 
 	// get the latest upstream versions available
@@ -124,14 +298,30 @@ func (o *Options) Update() error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to get package updates")
 	}
+	o.Summary.PackagesScanned = len(o.PackageConfigs)
 
 	// compare latest upstream versions with melange package versions and return a map of packages to update
 	packagesToUpdate, err := o.getPackagesToUpdate(latestVersions)
 	if err != nil {
 		return errors.Wrapf(err, "failed to get package updates")
 	}
+	o.Summary.PackagesOutdated = len(packagesToUpdate)
+
+	// updates that fix vulnerabilities with pending advisories are flagged and proposed first
+	o.securityFixes, err = o.findSecurityFixes(packagesToUpdate)
+	if err != nil {
+		return err
+	}
+	o.Summary.recordSecurityFixes(o.securityFixes)
+
+	// the packages of groups updated together are proposed in one pull request per group
+	packagesToUpdate, err = o.groupUpdates(tempDir, packagesToUpdate)
+	if err != nil {
+		return errors.Wrapf(err, "failed to group package updates")
+	}
 
 	// skip packages for which we already have an open issue or pull request
+
 	packagesToUpdate, err = o.removeExistingUpdates(repo, packagesToUpdate)
 	if err != nil {
 		return errors.Wrapf(err, "failed to get package updates")
@@ -159,10 +349,45 @@ func (o *Options) Update() error {
 	return nil
 }
 
+// clone clones the melange config git repo into a temp folder so we can work with it
+func (o *Options) clone() (*git.Repository, string, error) {
+	return o.cloneDepth(1, os.Stdout)
+}
+
+// cloneDepth clones the melange config git repo with the given number of commits of history, all of it if 0, writing
+// the progress of the clone to progress
+func (o *Options) cloneDepth(depth int, progress io.Writer) (*git.Repository, string, error) {
+	tempDir, err := os.MkdirTemp("", "wolfictl")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create temporary folder to clone package configs into: %w", err)
+	}
+
+	cloneOpts := &git.CloneOptions{
+		URL:               o.RepoURI,
+		Progress:          progress,
+		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+		Auth:              wgit.GetGitAuth(),
+		Depth:             depth,
+	}
+
+	repo, err := git.PlainCloneContext(o.context(), tempDir, false, cloneOpts)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, "", fmt.Errorf("failed to clone repository %s into %s: %w", o.RepoURI, tempDir, err)
+	}
+	return repo, tempDir, nil
+}
+
 func (o *Options) GetLatestVersions(dir string, packageNames []string) (map[string]NewVersionResults, error) {
 	var err error
 	latestVersions := make(map[string]NewVersionResults)
 
+	// package groups are defined in the target git repo too
+	packageNames, err = groups.ExpandDir(dir, packageNames, groups.ConfigExists(dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand package groups: %w", err)
+	}
+
 	// first, let's get the melange package(s) from the target git repo, that we want to check for updates
 	o.PackageConfigs, err = melange.ReadPackageConfigs(packageNames, dir)
 	if err != nil {
@@ -177,38 +402,81 @@ func (o *Options) GetLatestVersions(dir string, packageNames []string) (map[stri
 		}
 	}
 
+	// leave the packages of other shards to the jobs running them
+	if o.Shard != nil {
+		for i := range o.PackageConfigs {
+			if !o.Shard.Contains(i) {
+				delete(o.PackageConfigs, i)
+			}
+		}
+		o.Logger.Printf("shard %s: checking %d packages", o.Shard, len(o.PackageConfigs))
+	}
+
 	if len(o.PackageConfigs) == 0 {
 		o.Logger.Printf("no package updates")
 		return nil, nil
 	}
 
-	if o.GithubReleaseQuery {
-		// let's get any versions that use GITHUB first as we can do that using reduced graphql requests
-		g := NewGitHubReleaseOptions(o.PackageConfigs, o.GitHubHTTPClient)
-		v, errorMessages, err := g.getLatestGitHubVersions()
-		if err != nil {
-			return latestVersions, fmt.Errorf("failed getting github releases: %w", err)
-		}
-		maps.Copy(o.ErrorMessages, errorMessages)
-		maps.Copy(latestVersions, v)
+	if err := o.lookupLatestVersions(latestVersions); err != nil {
+		return latestVersions, err
 	}
-
-	if o.ReleaseMonitoringQuery {
-		// get latest versions from https://release-monitoring.org/
-		m := MonitorService{
-			Client: o.Client,
-			Logger: o.Logger,
-		}
-		v, errorMessages := m.getLatestReleaseMonitorVersions(o.PackageConfigs)
-		if err != nil {
-			return nil, fmt.Errorf("failed release monitor versions: %w", err)
-		}
-		maps.Copy(o.ErrorMessages, errorMessages)
-		maps.Copy(latestVersions, v)
+	// the lookups fail fast once canceled, so their results aren't worth acting on
+	if err := o.context().Err(); err != nil {
+		return nil, err
 	}
 	return latestVersions, nil
 }
 
+// recordLookup adds the latest versions a datasource found to latestVersions and records its failures, remembering
+// the datasource that checked each package for the staleness report
+func (o *Options) recordLookup(cause string, latestVersions, found map[string]NewVersionResults, errorMessages map[string]string) {
+	o.recordFailures(cause, errorMessages)
+	maps.Copy(latestVersions, found)
+	if o.datasources == nil {
+		o.datasources = make(map[string]string)
+	}
+	datasource := datasourceName(cause)
+	for name := range found {
+		o.datasources[name] = datasource
+	}
+	for name := range errorMessages {
+		o.datasources[name] = datasource
+	}
+}
+
+// LatestVersion looks up the latest version of a package of the melange configs in dir with the enabled datasources,
+// an empty one if its updates are disabled or no datasource checks it.
+func (o *Options) LatestVersion(ctx context.Context, dir, packageName string) (NewVersionResults, error) {
+	o.withContext(ctx)
+	latest, err := o.GetLatestVersions(dir, []string{packageName})
+	if err != nil {
+		return NewVersionResults{}, err
+	}
+	if msg, ok := o.ErrorMessages[packageName]; ok {
+		return NewVersionResults{}, errors.New(msg)
+	}
+	return latest[packageName], nil
+}
+
+// Datasource returns the datasource that checked a package in GetLatestVersions, like pypi, or an empty string if
+// none did
+func (o *Options) Datasource(packageName string) string {
+	return o.datasources[packageName]
+}
+
+// datasourceName returns the name of the datasource of a lookup failure cause, like pypi for pypi-lookup
+func datasourceName(cause string) string {
+	return strings.TrimSuffix(cause, "-lookup")
+}
+
+// recordFailures keeps error messages to report at the end of the run and counts them against a cause in the summary
+func (o *Options) recordFailures(cause string, errorMessages map[string]string) {
+	maps.Copy(o.ErrorMessages, errorMessages)
+	for range errorMessages {
+		o.Summary.recordFailure(cause)
+	}
+}
+
 // function will iterate over all packages that need to be updated and create a pull request for each change by default unless batch mode which creates a single pull request
 func (o *Options) updatePackagesGitRepository(repo *git.Repository, packagesToUpdate map[string]NewVersionResults) error {
 	// store the HEAD ref to switch back later
@@ -218,7 +486,12 @@ func (o *Options) updatePackagesGitRepository(repo *git.Repository, packagesToUp
 	}
 
 	// Bump packages that need updating
-	for packageName, newVersion := range packagesToUpdate {
+	for _, packageName := range o.updateOrder(packagesToUpdate) {
+		newVersion := packagesToUpdate[packageName]
+		if err := o.context().Err(); err != nil {
+			return err
+		}
+
 		// todo jr remove if this doesn't help
 		// add sleep to see if it helps intermittent "object not found" when pushing
 		time.Sleep(1 * time.Second)
@@ -243,17 +516,17 @@ func (o *Options) updatePackagesGitRepository(repo *git.Repository, packagesToUp
 		o.Logger.Printf("updatePackagesGitRepository: %s git status: %s", packageName, string(rs))
 
 		// let's work on a branch when updating package versions, so we can create a PR from that branch later
-		ref, err := o.createBranch(repo)
+		ref, err := o.createBranch(repo, packageName)
 		if err != nil {
 			return errors.Wrap(err, "failed to create git branch")
 		}
 
-		errorMessage, err := o.updateGitPackage(repo, packageName, newVersion, ref)
+		cause, errorMessage, err := o.updateGitPackage(repo, packageName, newVersion, ref)
 		if err != nil {
 			return err
 		}
 		if errorMessage != "" {
-			o.ErrorMessages[packageName] = errorMessage
+			o.recordFailures(cause, map[string]string{packageName: errorMessage})
 		}
 	}
 
@@ -271,45 +544,108 @@ func debug(wt *git.Worktree) ([]byte, error) {
 	return rs, nil
 }
 
-func (o *Options) updateGitPackage(repo *git.Repository, packageName string, newVersion NewVersionResults, ref plumbing.ReferenceName) (string, error) {
+func (o *Options) updateGitPackage(repo *git.Repository, packageName string, newVersion NewVersionResults, ref plumbing.ReferenceName) (cause, errorMessage string, err error) {
+	if len(newVersion.Members) > 0 {
+		return o.updateGroup(repo, packageName, newVersion, ref)
+	}
+
 	// get the filename from the map of melange configs we loaded at the start
 	config, ok := o.PackageConfigs[packageName]
 	if !ok {
-		return "", fmt.Errorf("no melange config found for package %s", packageName)
+		return "", "", fmt.Errorf("no melange config found for package %s", packageName)
 	}
 
 	// if manual update create an issue rather than a pull request
 	if config.Config.Update.Manual {
-		return o.createNewVersionIssue(repo, packageName, newVersion)
+		errorMessage, err = o.createNewVersionIssue(repo, packageName, newVersion)
+		return "", errorMessage, err
+	}
+
+	cause, errorMessage, err = o.bumpPackage(repo, packageName, newVersion)
+	if err != nil || errorMessage != "" {
+		return cause, errorMessage, err
+	}
+	if errorMessage := o.validatePackages(packageName, []string{packageName}); errorMessage != "" {
+		return FailureValidation, errorMessage, nil
+	}
+	return o.propose(repo, ref, packageName, newVersion)
+}
+
+// bumpPackage updates the melange config of a package to its new version, along with everything that depends on
+// the version, and stages the changes
+func (o *Options) bumpPackage(repo *git.Repository, packageName string, newVersion NewVersionResults) (cause, errorMessage string, err error) {
+	config, ok := o.PackageConfigs[packageName]
+	if !ok {
+		return "", "", fmt.Errorf("no melange config found for package %s", packageName)
 	}
 
 	configFile := filepath.Join(config.Dir, config.Filename)
 	if configFile == "" {
-		return "", fmt.Errorf("no config filename found for package %s", packageName)
+		return "", "", fmt.Errorf("no config filename found for package %s", packageName)
+	}
+
+	if newVersion.SourceURL != "" {
+		rewritten, err := melange.RewriteFetch(configFile, newVersion.Version, newVersion.SourceURL, newVersion.SourceSHA256, newVersion.SourceSHA512)
+		if err != nil {
+			return FailureBump, fmt.Sprintf("failed to rewrite fetch of package %s to %s: %s", packageName, newVersion.SourceURL, err.Error()), nil
+		}
+		if rewritten {
+			o.Logger.Printf("pointed fetch of package %s at %s", packageName, newVersion.SourceURL)
+		}
 	}
 
 	// if new versions are available lets bump the packages in the target melange git repo
-	err := melange.Bump(configFile, newVersion.Version, newVersion.Commit)
+	err = melange.Bump(configFile, newVersion.Version, newVersion.Commit)
 	if err != nil {
 		// add this to the list of messages to print at the end of the update
-		return fmt.Sprintf("failed to bump package %s to version %s: %s", packageName, newVersion.Version, err.Error()), nil
+		return FailureBump, fmt.Sprintf("failed to bump package %s to version %s: %s", packageName, newVersion.Version, err.Error()), nil
+	}
+
+	// datasources that don't know the commit of the new version leave the expected-commit of git-checkout steps
+	// at the previous version, so the tag they check out is resolved instead
+	if newVersion.Commit == "" {
+		commits, err := melange.RefreshExpectedCommits(configFile, o.resolveTagCommit)
+		if err != nil {
+			return FailureExpectedCommit, fmt.Sprintf("failed to resolve the expected commit of package %s version %s: %s", packageName, newVersion.Version, err.Error()), nil
+		}
+		for _, c := range commits {
+			o.Logger.Printf("%s: %s", packageName, c)
+		}
+	}
+
+	// the expected checksums of fetch steps are of the sources of the previous version, and the ones a datasource
+	// published for the new sources are verified
+	published := make(map[string]melange.Digests)
+	if newVersion.SourceURL != "" {
+		published[newVersion.SourceURL] = melange.Digests{SHA256: newVersion.SourceSHA256, SHA512: newVersion.SourceSHA512}
+	}
+	checksums, err := melange.RefreshFetchChecksums(o.context(), configFile, melange.ChecksumOptions{MaxSize: o.MaxSourceSize, Published: published})
+	if err != nil {
+		return FailureSourceChecksums, fmt.Sprintf("failed to refresh source checksums of package %s version %s: %s", packageName, newVersion.Version, err.Error()), nil
+	}
+	for _, c := range checksums {
+		o.Logger.Printf("%s: %s", packageName, c)
+	}
+	if o.sourceChecksums == nil {
+		o.sourceChecksums = make(map[string][]melange.SourceChecksum)
 	}
+	o.sourceChecksums[packageName] = checksums
 
 	worktree, err := repo.Worktree()
 	if err != nil {
-		return "", fmt.Errorf("failed to get git worktree: %w", err)
+		return "", "", fmt.Errorf("failed to get git worktree: %w", err)
 	}
 
 	// this needs to be the relative path set when reading the files initially
 	_, err = worktree.Add(config.Filename)
 	if err != nil {
-		return "", fmt.Errorf("failed to git add %s: %w", configFile, err)
+		return "", "", fmt.Errorf("failed to git add %s: %w", configFile, err)
 	}
 
 	// for now wolfi is using a Makefile, if it exists check if the package is listed and update the version + epoch if it is
 	err = o.updateMakefile(config.Dir, packageName, newVersion.Version, worktree)
 	if err != nil {
-		return fmt.Sprintf("failed to update Makefile: %s", err.Error()), nil
+		return FailureMakefile, fmt.Sprintf("failed to update Makefile: %s", err.Error()), nil
 	}
 
 	// if mapping data has a strip prefix, add it back in to the version for when updating git modules
@@ -323,20 +659,27 @@ func (o *Options) updateGitPackage(repo *git.Repository, packageName string, new
 	// some repos could use git submodules, let's check if a submodule file exists and bump any matching packages
 	err = o.updateGitModules(config.Dir, packageName, latestVersionWithPrefix, worktree)
 	if err != nil {
-		return fmt.Sprintf("failed to update git modules: %s", err.Error()), nil
+		return FailureGitModules, fmt.Sprintf("failed to update git modules: %s", err.Error()), nil
 	}
 
-	// if we're not running in batch mode, lets commit and PR each change
-	if !o.DryRun {
-		pr, err := o.proposeChanges(repo, ref, packageName, newVersion)
-		if err != nil {
-			return fmt.Sprintf("failed to propose changes: %s", err.Error()), nil
-		}
-		if pr != "" {
-			o.Logger.Println(color.GreenString(pr))
-		}
+	return "", "", nil
+}
+
+// propose commits the staged changes of a package, or group of packages, and opens their pull request, unless it's a
+// dry run
+func (o *Options) propose(repo *git.Repository, ref plumbing.ReferenceName, packageName string, newVersion NewVersionResults) (cause, errorMessage string, err error) {
+	if o.DryRun {
+		return "", "", nil
 	}
-	return "", nil
+	pr, err := o.proposeChanges(repo, ref, packageName, newVersion)
+	if err != nil {
+		return FailureProposeChanges, fmt.Sprintf("failed to propose changes: %s", err.Error()), nil
+	}
+	if pr != "" {
+		o.Logger.Println(color.GreenString(pr))
+		o.Summary.recordPullRequest()
+	}
+	return "", "", nil
 }
 
 // this feels very hacky but the Makefile is going away with help from Dag so plan to delete this func soon
@@ -405,16 +748,23 @@ func (o *Options) updateGitModules(dir, packageName, version string, wt *git.Wor
 }
 
 // create a unique branch
-func (o *Options) createBranch(repo *git.Repository) (plumbing.ReferenceName, error) {
-	name := uuid.New().String()
+// updateBranch is the branch of the pull requests of a package, or of a package group, so a later run finds the pull
+// request of an earlier version and pushes the new version to it
+func updateBranch(packageName string) plumbing.ReferenceName {
+	if group, ok := strings.CutPrefix(packageName, groups.Prefix); ok {
+		packageName = "group-" + group
+	}
+	return plumbing.NewBranchReferenceName(updateBranchPrefix + packageName)
+}
 
+func (o *Options) createBranch(repo *git.Repository, packageName string) (plumbing.ReferenceName, error) {
 	headRef, err := repo.Head()
 	if err != nil {
 		return "", errors.Wrap(err, "failed to get repository HEAD")
 	}
 
-	// Create a unique branch to work from
-	branchName := plumbing.NewBranchReferenceName(fmt.Sprintf("wolfictl-%s", name))
+	// Create the branch of the package to work from
+	branchName := updateBranch(packageName)
 
 	// Create the branch reference pointing to the HEAD commit
 	newBranchRef := plumbing.NewHashReference(branchName, headRef.Hash())
@@ -440,7 +790,8 @@ func (o *Options) createBranch(repo *git.Repository) (plumbing.ReferenceName, er
 	return newBranchRef.Name(), nil
 }
 
-// commits package update changes and creates a pull request
+// commits package update changes and creates a pull request, pushing the branch and opening the pull request are
+// retried, and queued to retry later if they still fail
 func (o *Options) proposeChanges(repo *git.Repository, ref plumbing.ReferenceName, packageName string, newVersion NewVersionResults) (string, error) {
 	gitURL, err := wgit.GetRemoteURL(repo)
 	if err != nil {
@@ -478,64 +829,226 @@ func (o *Options) proposeChanges(repo *git.Repository, ref plumbing.ReferenceNam
 	}
 	o.Logger.Printf("proposeChanges: %s git status: %s", packageName, string(rs))
 
+	// the branch of the package replaces the one of an earlier version, unless someone pushed to its pull request
+	remoteRef := ref
+	if existing := newVersion.UpdateExistingPRNumber; existing != 0 {
+		others, err := o.pushedByOthers(repo, ref)
+		if err != nil {
+			return "", fmt.Errorf("failed to check the branch of pull request #%d: %w", existing, err)
+		}
+		if others {
+			head, err := repo.Head()
+			if err != nil {
+				return "", errors.Wrap(err, "failed to get repository HEAD")
+			}
+			remoteRef = plumbing.NewBranchReferenceName(fmt.Sprintf("%s-%s", ref.Short(), head.Hash().String()[:7]))
+			o.Logger.Printf("%s: pull request #%d has commits of others on %s, superseding it with a pull request from %s", packageName, existing, ref.Short(), remoteRef.Short())
+			newVersion.UpdateExistingPRNumber = 0
+			newVersion.ReplaceExistingPRNumber = existing
+			basePullRequest.Branch = remoteRef.String()
+		}
+	}
+
+	// if we have a single version use it in the PR title, this might be a batch with multiple versions so default to a simple title
+	var title string
+	if newVersion.Version != "" {
+		title = fmt.Sprintf(o.PullRequestTitle, packageName, newVersion.Version)
+	} else {
+		title = fmt.Sprintf(o.PullRequestTitle, packageName, "new versions")
+	}
+
+	// Create an NewPullRequest struct which is used to create the real pull request from
+	newPR := &gh.NewPullRequest{
+		BasePullRequest: basePullRequest,
+		Title:           title,
+		Body:            wolfiImage + o.pullRequestBody(packageName, newVersion),
+	}
+
+	if o.proposed == nil {
+		o.proposed = make(map[string]bool)
+	}
+	o.proposed[packageName] = true
+	failed := FailedPullRequest{
+		Package:    packageName,
+		NewVersion: newVersion,
+		Owner:      gitURL.Organisation,
+		RepoName:   gitURL.Name,
+		Branch:     remoteRef.String(),
+		Title:      title,
+		Body:       newPR.Body,
+		// the vulnerabilities fixed are kept for the label of the pull request when it's retried
+		Vulnerabilities: o.fixedVulnerabilities(packageName, newVersion),
+	}
+	if o.policy != nil {
+		failed.Reviewers = o.policy.For(packageName).Reviewers
+	}
+
 	// setup githubReleases auth using standard environment variables
 	pushOpts := &git.PushOptions{
 		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", ref, remoteRef))},
 		Auth:       wgit.GetGitAuth(),
 		Progress:   os.Stdout, // todo remove if this doesn't help: extra logging to help debug intermittent "object not found" when pushing
 	}
 
 	// push the version update changes to our working branch
-	if err := repo.Push(pushOpts); err != nil {
-		if err.Error() == "authorization failed" {
+	err = o.retryPullRequest(fmt.Sprintf("push of branch %s", remoteRef.Short()), func() error {
+		err := repo.PushContext(o.context(), pushOpts)
+		if errors.Is(err, git.NoErrAlreadyUpToDate) {
+			// an earlier attempt pushed the branch but failed to report it
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		o.queueFailedPullRequest(failed, err)
+		if errors.Is(err, transport.ErrAuthorizationFailed) {
 			return "", errors.Wrapf(err, "failed to auth with git provider, does your personal access token have the repo scope? https://github.com/settings/tokens/new?scopes=repo")
 		}
 		return "", fmt.Errorf("failed to git push: %w", err)
 	}
 
 	// now let's create a pull request
+	failed.Pushed = true
+	prLink, err := o.createPullRequest(gitOpts, newPR, newVersion.ReplaceExistingPRNumber, failed)
+	if err != nil && o.context().Err() != nil {
+		// the run was canceled before the pull request was opened, don't leave its branch behind
+		o.rollbackBranch(repo, packageName, remoteRef)
+	}
+	return prLink, err
+}
 
-	// if we have a single version use it in the PR title, this might be a batch with multiple versions so default to a simple title
-	var title string
-	if newVersion.Version != "" {
-		title = fmt.Sprintf(o.PullRequestTitle, packageName, newVersion.Version)
-	} else {
-		title = fmt.Sprintf(o.PullRequestTitle, packageName, "new versions")
+// pushedByOthers tells if the head of the branch of a package on the remote is a commit of someone other than the
+// author of the update just committed to it, e.g. a maintainer fixing up the pull request, which pushing the update
+// would discard
+func (o *Options) pushedByOthers(repo *git.Repository, ref plumbing.ReferenceName) (bool, error) {
+	remoteRef := plumbing.NewRemoteReferenceName("origin", ref.Short())
+	err := repo.FetchContext(o.context(), &git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", ref, remoteRef))},
+		Auth:       wgit.GetGitAuth(),
+	})
+	var noMatch git.NoMatchingRefSpecError
+	switch {
+	case errors.As(err, &noMatch):
+		// the branch is gone, there's nothing to discard
+		return false, nil
+	case err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate):
+		return false, err
 	}
 
-	// Create an NewPullRequest struct which is used to create the real pull request from
-	newPR := &gh.NewPullRequest{
-		BasePullRequest: basePullRequest,
-		Title:           title,
-		Body:            wolfiImage,
+	remote, err := repo.Reference(remoteRef, true)
+	if err != nil {
+		return false, err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return false, err
+	}
+	theirs, err := repo.CommitObject(remote.Hash())
+	if err != nil {
+		return false, err
+	}
+	ours, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return false, err
+	}
+	return !strings.EqualFold(theirs.Author.Email, ours.Author.Email), nil
+}
+
+// rollbackBranch deletes a branch pushed for a pull request that wasn't opened, so it has to be pushed again when the
+// pull request is retried
+func (o *Options) rollbackBranch(repo *git.Repository, packageName string, ref plumbing.ReferenceName) {
+	// the context of the run is done, give the deletion a moment of its own
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := repo.PushContext(ctx, &git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{config.RefSpec(":" + ref.String())},
+		Auth:       wgit.GetGitAuth(),
+	})
+	if err != nil {
+		o.Logger.Printf("%s: failed to delete branch %s of the canceled pull request: %s", packageName, ref.Short(), err)
+		return
+	}
+	o.Logger.Printf("%s: deleted branch %s of the canceled pull request", packageName, ref.Short())
+	for i := range o.failedPullRequests {
+		if o.failedPullRequests[i].Package == packageName {
+			o.failedPullRequests[i].Pushed = false
+		}
 	}
+}
 
-	// create the pull request
-	pr, err := gitOpts.OpenPullRequest(newPR)
-	prLink := pr.GetHTMLURL()
+// createPullRequest opens a pull request from a branch that's been pushed, and closes the pull request it supersedes
+func (o *Options) createPullRequest(gitOpts gh.GitOptions, newPR *gh.NewPullRequest, replaceExistingPRNumber int, failed FailedPullRequest) (string, error) {
+	var pr *github.PullRequest
+	err := o.retryPullRequest(fmt.Sprintf("pull request %q", newPR.Title), func() error {
+		var err error
+		if existing := failed.NewVersion.UpdateExistingPRNumber; existing != 0 {
+			pr, err = gitOpts.EditPullRequest(o.context(), newPR.Owner, newPR.RepoName, existing, newPR.Title, newPR.Body)
+			if err != nil || pr.GetState() != "closed" {
+				return err
+			}
+			// closed since the run listed it, a new pull request is needed for the branch
+			o.Logger.Printf("pull request #%d of %s was closed, opening a new one", existing, newPR.Branch)
+		}
+		pr, err = gitOpts.OpenPullRequest(o.context(), newPR)
+		return err
+	})
 	if err != nil {
+		o.queueFailedPullRequest(failed, err)
 		return "", fmt.Errorf("failed to create pull request: %w", err)
 	}
-	err = gitOpts.LabelIssue(context.Background(), newPR.Owner, newPR.RepoName, *pr.Number, &o.IssueLabels)
+	prLink := pr.GetHTMLURL()
+
+	labels := o.IssueLabels
+	if len(failed.Vulnerabilities) > 0 && o.SecurityLabel != "" {
+		labels = append(append([]string{}, labels...), o.SecurityLabel)
+	}
+	err = gitOpts.LabelIssue(o.context(), newPR.Owner, newPR.RepoName, *pr.Number, &labels)
 	if err != nil {
-		log.Printf("Failed to apply labels [%s] to PR #%d", strings.Join(o.IssueLabels, ","), pr.Number)
+		log.Printf("Failed to apply labels [%s] to PR #%d", strings.Join(labels, ","), pr.Number)
 	}
-	if newVersion.ReplaceExistingPRNumber != 0 {
-		err = gitOpts.ClosePullRequest(context.Background(), gitURL.Organisation, gitURL.Name, newVersion.ReplaceExistingPRNumber)
+	if len(failed.Reviewers) > 0 {
+		err = gitOpts.RequestReviewers(o.context(), newPR.Owner, newPR.RepoName, pr.GetNumber(), failed.Reviewers)
 		if err != nil {
-			return "", errors.Wrapf(err, "failed to close pull request: %d", newVersion.ReplaceExistingPRNumber)
+			o.Logger.Printf("failed to request the review of %s on PR #%d: %s", strings.Join(failed.Reviewers, ", "), pr.GetNumber(), err)
+		}
+	}
+	if replaceExistingPRNumber != 0 {
+		err = gitOpts.ClosePullRequest(o.context(), newPR.Owner, newPR.RepoName, replaceExistingPRNumber)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to close pull request: %d", replaceExistingPRNumber)
 		}
 
 		// comment on the closed PR the new pull request link which supersedes it
 		comment := fmt.Sprintf("superceded by %s", prLink)
-		_, err = gitOpts.CommentIssue(context.Background(), gitURL.Organisation, gitURL.Name, comment, newVersion.ReplaceExistingPRNumber)
+		_, err = gitOpts.CommentIssue(o.context(), newPR.Owner, newPR.RepoName, comment, replaceExistingPRNumber)
 		if err != nil {
-			return "", errors.Wrapf(err, "failed to comment pull request: %d", newVersion.ReplaceExistingPRNumber)
+			return "", errors.Wrapf(err, "failed to comment pull request: %d", replaceExistingPRNumber)
 		}
 	}
 	return prLink, nil
 }
 
+// checksumReport is the section of the body of a pull request reporting the checksums recomputed for the sources of
+// the new version, so reviewers can tell where they come from
+func checksumReport(checksums []melange.SourceChecksum) string {
+	if len(checksums) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n### Source checksums\n\n| Source | Size | sha256 | Verified |\n| --- | --- | --- | --- |\n")
+	for _, c := range checksums {
+		verified := "no digest published"
+		if c.Verified {
+			verified = "matches the published digest"
+		}
+		fmt.Fprintf(&b, "| %s | %d bytes | `%s` | %s |\n", c.URI, c.Size, c.SHA256, verified)
+	}
+	return b.String()
+}
+
 // commit changes to git
 func (o *Options) commitChanges(repo *git.Repository, packageName, latestVersion string) error {
 	worktree, err := repo.Worktree()
@@ -593,21 +1106,21 @@ func (o *Options) createErrorMessageIssue(repo *git.Repository, packageName, mes
 		Comment:     message,
 		Title:       gh.GetErrorIssueTitle(bot, packageName),
 	}
-	existingIssue, err := gitOpts.CheckExistingIssue(context.Background(), i)
+	existingIssue, err := gitOpts.CheckExistingIssue(o.context(), i)
 	if err != nil {
 		return "", err
 	}
 
 	if existingIssue > 0 {
-		exists, err := gitOpts.HasExistingComment(context.Background(), i, existingIssue, message)
+		exists, err := gitOpts.HasExistingComment(o.context(), i, existingIssue, message)
 		if exists {
 			return fmt.Sprintf("existing issue %d already exists for error message: %s", existingIssue, message), err
 		}
 		// if this is a new error add a new comment
-		return gitOpts.CommentIssue(context.Background(), gitURL.Organisation, gitURL.Name, message, existingIssue)
+		return gitOpts.CommentIssue(o.context(), gitURL.Organisation, gitURL.Name, message, existingIssue)
 	}
 
-	return gitOpts.OpenIssue(context.Background(), i)
+	return gitOpts.OpenIssue(o.context(), i)
 }
 
 func (o *Options) createNewVersionIssue(repo *git.Repository, packageName string, version NewVersionResults) (string, error) {
@@ -630,7 +1143,7 @@ func (o *Options) createNewVersionIssue(repo *git.Repository, packageName string
 		Title:       gh.GetUpdateIssueTitle(packageName, version.Version),
 		Labels:      o.IssueLabels,
 	}
-	existingIssue, err := gitOpts.CheckExistingIssue(context.Background(), i)
+	existingIssue, err := gitOpts.CheckExistingIssue(o.context(), i)
 	if err != nil {
 		return "", err
 	}
@@ -639,11 +1152,12 @@ func (o *Options) createNewVersionIssue(repo *git.Repository, packageName string
 		return "", nil
 	}
 
-	issueLink, err := gitOpts.OpenIssue(context.Background(), i)
+	issueLink, err := gitOpts.OpenIssue(o.context(), i)
 	if err != nil {
 		return "", err
 	}
 	o.Logger.Println(color.GreenString(fmt.Sprintf("%s opened issue %s", packageName, issueLink)))
+	o.Summary.recordIssue()
 	return "", nil
 }
 
@@ -671,15 +1185,46 @@ func (o *Options) getPackagesToUpdate(latestVersions map[string]NewVersionResult
 				"%s is on the latest version %s",
 				c.Package.Name, latestVersionSemver.Original(),
 			)
+			continue
 		}
-		if currentVersionSemver.LessThan(latestVersionSemver) {
+		refusal, explanation := o.refuseUpdate(pc, currentVersionSemver, latestVersionSemver)
+		if refusal != "" {
+			if !o.Force {
+				o.skipUpdate(SkippedUpdate{
+					Package:        c.Package.Name,
+					CurrentVersion: c.Package.Version,
+					Version:        latestVersionSemver.Original(),
+					Reason:         refusal,
+					Explanation:    explanation,
+				})
+				continue
+			}
+			o.Logger.Println(color.YellowString(fmt.Sprintf("forcing the update despite: %s", explanation)))
+		}
+		// the policy of the repository applies even with --force
+		reason, explanation, err := o.refuseByPolicy(pc, currentVersionSemver, latestVersionSemver)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			o.skipUpdate(SkippedUpdate{
+				Package:        c.Package.Name,
+				CurrentVersion: c.Package.Version,
+				Version:        latestVersionSemver.Original(),
+				Reason:         reason,
+				Explanation:    explanation,
+			})
+			continue
+		}
+		if refusal == "" {
 			o.Logger.Println(
 				color.GreenString(
 					fmt.Sprintf("there is a new stable version available %s, current wolfi version %s, new %s",
 						c.Package.Name, c.Package.Version, latestVersionSemver.Original())))
-
-			results[c.Package.Name] = NewVersionResults{Version: latestVersionSemver.Original(), Commit: v.Commit}
 		}
+
+		v.Version = latestVersionSemver.Original()
+		results[c.Package.Name] = v
 	}
 	return results, nil
 }
@@ -699,14 +1244,14 @@ func (o *Options) removeExistingUpdates(repo *git.Repository, updates map[string
 		Logger:       o.Logger,
 	}
 
-	openPRs, err := gitOpts.ListPullRequests(context.Background(), gitURL.Organisation, gitURL.Name, "open")
+	openPRs, err := gitOpts.ListPullRequests(o.context(), gitURL.Organisation, gitURL.Name, "open")
 	if err != nil {
 		return updates, errors.Wrapf(err, "failed to list open pull requests for %s/%s", gitURL.Organisation, gitURL.Name)
 	}
 
 	o.processPullRequests(updates, openPRs)
 
-	openIssues, err := gitOpts.ListIssues(context.Background(), gitURL.Organisation, gitURL.Name, "open")
+	openIssues, err := gitOpts.ListIssues(o.context(), gitURL.Organisation, gitURL.Name, "open")
 	if err != nil {
 		return updates, errors.Wrapf(err, "failed to list open issues for %s/%s", gitURL.Organisation, gitURL.Name)
 	}
@@ -716,10 +1261,34 @@ func (o *Options) removeExistingUpdates(repo *git.Repository, updates map[string
 	return updates, nil
 }
 
+// processPullRequests drops the updates that already have a pull request. The pull request of an earlier version on
+// the branch of a package is updated, and any other one whose title is of an earlier version is superseded.
 func (o *Options) processPullRequests(updates map[string]NewVersionResults, prs []*github.PullRequest) {
+	branches := make(map[string]string, len(updates))
+	for packageName := range updates {
+		branches[updateBranch(packageName).Short()] = packageName
+	}
+
 	for _, pr := range prs {
 		prTitle := *pr.Title
 
+		if packageName, ok := branches[pr.GetHead().GetRef()]; ok {
+			v, ok := updates[packageName]
+			if !ok {
+				// dropped for another pull request of the version
+				continue
+			}
+			if o.isSameVersion(packageName, v.Version, prTitle) {
+				o.Logger.Printf("pull request %s already exists for %s\n", pr.GetHTMLURL(), prTitle)
+				delete(updates, packageName)
+				continue
+			}
+			o.Logger.Printf("pull request %s of %s is updated to %s\n", pr.GetHTMLURL(), packageName, v.Version)
+			v.UpdateExistingPRNumber = pr.GetNumber()
+			updates[packageName] = v
+			continue
+		}
+
 		packageName, titleVersion, err := extractPackageVersionFromTitle(prTitle)
 		if err != nil {
 			// ignore if we can't extract a package name and version string
@@ -737,7 +1306,8 @@ func (o *Options) processPullRequests(updates map[string]NewVersionResults, prs
 			continue
 		}
 
-		if o.containsOldVersion(packageName, v.Version, titleVersion, prTitle) {
+		// the version of a group can be a digest of the versions of its members, any other one is replaced
+		if strings.HasPrefix(packageName, groups.Prefix) || o.containsOldVersion(packageName, v.Version, titleVersion, prTitle) {
 			v.ReplaceExistingPRNumber = *pr.Number
 			updates[packageName] = v
 		}
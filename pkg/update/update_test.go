@@ -2,6 +2,7 @@ package update
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
@@ -15,7 +16,9 @@ import (
 
 	"chainguard.dev/melange/pkg/build"
 
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/google/go-github/v50/github"
 
 	"github.com/go-git/go-billy/v5/osfs"
 	"github.com/go-git/go-billy/v5/util"
@@ -26,6 +29,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/maps"
 )
 
 // a bit more than a typical unit test but is useful to test a git branch with melange bump
@@ -166,3 +170,129 @@ func Test_extractVersionFromTitle(t *testing.T) {
 		})
 	}
 }
+
+func TestOptions_processPullRequests(t *testing.T) {
+	pr := func(number int, branch, title string) *github.PullRequest {
+		return &github.PullRequest{
+			Number:  github.Int(number),
+			Title:   github.String(title),
+			HTMLURL: github.String(fmt.Sprintf("https://github.com/wolfi-dev/os/pull/%d", number)),
+			Head:    &github.PullRequestBranch{Ref: github.String(branch)},
+		}
+	}
+	o := &Options{Logger: log.New(io.Discard, "", 0)}
+	updates := map[string]NewVersionResults{
+		"curl":    {Version: "8.2.1"},
+		"jq":      {Version: "1.7"},
+		"openssl": {Version: "3.1.2"},
+		"zlib":    {Version: "1.3"},
+	}
+	o.processPullRequests(updates, []*github.PullRequest{
+		pr(1, "wolfictl-update-curl", "curl/8.2.0 package update"),
+		pr(2, "wolfictl-update-jq", "jq/1.7 package update"),
+		pr(3, "wolfictl-3f9c2a1e-0d4b-4c4e-9a7e-2b6f0c1d5e8a", "openssl/3.1.1 package update"),
+		pr(4, "add-zlib-patch", "zlib: backport a fix"),
+	})
+
+	assert.Equal(t, map[string]NewVersionResults{
+		"curl":    {Version: "8.2.1", UpdateExistingPRNumber: 1},
+		"openssl": {Version: "3.1.2", ReplaceExistingPRNumber: 3},
+		"zlib":    {Version: "1.3"},
+	}, updates, "the pull request on the branch of a package should be updated, older ones on other branches superseded")
+	assert.Equal(t, plumbing.NewBranchReferenceName("wolfictl-update-group-toolchain"), updateBranch("@toolchain"))
+}
+
+func TestOptions_groupUpdates(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".package-groups.yaml"), []byte(`
+groups:
+  texlive:
+    update-together: true
+    packages: [texlive, texlive-full, texmf-dist]
+  tools:
+    update-together: true
+    packages: [texmf-dist, biber]
+  gnome:
+    packages: [glib, gtk-4]
+`), 0o600))
+	for _, name := range []string{"texlive", "texlive-full", "texmf-dist", "biber"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+".yaml"), nil, 0o600))
+	}
+
+	o := Options{
+		Logger: log.New(io.Discard, "", 0),
+		PackageConfigs: map[string]*melange.Packages{
+			"texlive":      {},
+			"texlive-full": {},
+			"texmf-dist":   {},
+			"biber":        {Config: build.Configuration{Update: build.Update{Manual: true}}},
+			"glib":         {},
+		},
+	}
+	got, err := o.groupUpdates(dir, map[string]NewVersionResults{
+		"texlive":      {Version: "20230313"},
+		"texlive-full": {Version: "20230313"},
+		"texmf-dist":   {Version: "2023.66587"},
+		"biber":        {Version: "2.19"},
+		"glib":         {Version: "2.78.0"},
+	})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"@texlive", "biber", "glib"}, maps.Keys(got), "only groups updated together should be grouped, without their manually updated packages")
+	texlive := got["@texlive"]
+	assert.Equal(t, map[string]NewVersionResults{
+		"texlive":      {Version: "20230313"},
+		"texlive-full": {Version: "20230313"},
+		"texmf-dist":   {Version: "2023.66587"},
+	}, texlive.Members, "a package should only be updated with the first group it's in")
+	assert.Regexp(t, `^updates-[0-9a-f]{8}$`, texlive.Version)
+
+	assert.Equal(t, "20230313", groupVersion(map[string]NewVersionResults{
+		"texlive":      {Version: "20230313"},
+		"texlive-full": {Version: "20230313"},
+	}), "a shared version should be the version of the group")
+}
+
+func TestOptions_pushedByOthers(t *testing.T) {
+	bot := &object.Signature{Name: "wolfi-bot", Email: "bot@wolfi.dev", When: time.Unix(0, 0)}
+	maintainer := &object.Signature{Name: "Jane Doe", Email: "jane@doe.org", When: time.Unix(1, 0)}
+	commit := func(r *git.Repository, author *object.Signature) plumbing.Hash {
+		wt, err := r.Worktree()
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(wt.Filesystem.Root(), "file"), []byte(author.When.String()), 0o600))
+		_, err = wt.Add("file")
+		require.NoError(t, err)
+		h, err := wt.Commit("curl/8.2.0 package update", &git.CommitOptions{Author: author})
+		require.NoError(t, err)
+		return h
+	}
+
+	originDir := t.TempDir()
+	origin, err := git.PlainInit(originDir, false)
+	require.NoError(t, err)
+	commit(origin, bot)
+	local, err := git.PlainClone(t.TempDir(), false, &git.CloneOptions{URL: originDir})
+	require.NoError(t, err)
+	commit(local, bot)
+
+	o := Options{Logger: log.New(io.Discard, "", 0)}
+	ref := updateBranch("curl")
+	others, err := o.pushedByOthers(local, ref)
+	require.NoError(t, err)
+	assert.False(t, others, "a branch that isn't pushed has nothing to discard")
+
+	head, err := origin.Head()
+	require.NoError(t, err)
+	require.NoError(t, origin.Storer.SetReference(plumbing.NewHashReference(ref, head.Hash())))
+	others, err = o.pushedByOthers(local, ref)
+	require.NoError(t, err)
+	assert.False(t, others, "the bot pushed the branch")
+
+	wt, err := origin.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, wt.Checkout(&git.CheckoutOptions{Branch: ref}))
+	commit(origin, maintainer)
+	others, err = o.pushedByOthers(local, ref)
+	require.NoError(t, err)
+	assert.True(t, others, "a maintainer pushed to the branch")
+}
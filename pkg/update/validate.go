@@ -0,0 +1,118 @@
+package update
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"chainguard.dev/apko/pkg/build/types"
+
+	"github.com/wolfi-dev/wolfictl/pkg/builder"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/lint"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+// how bumped packages are checked before their pull request is opened. The sources of the new version are always
+// downloaded to refresh the checksums of the fetch steps.
+const (
+	ValidateNone  = "none"
+	ValidateLint  = "lint"
+	ValidateBuild = "build"
+)
+
+// maxBuildLogLines is how many of the last lines of the log of a failed build are reported
+const maxBuildLogLines = 50
+
+// validatePackages lints the bumped configs of packages, and with ValidateBuild builds them too, before their pull
+// request is opened. It returns an error message if any of them is broken or can't be checked, and records what was
+// checked for the body of the pull request otherwise.
+func (o *Options) validatePackages(packageName string, names []string) (errorMessage string) {
+	if o.Validate == "" || o.Validate == ValidateNone {
+		return ""
+	}
+
+	var report strings.Builder
+	report.WriteString("\n### Validation\n\n")
+	for _, name := range names {
+		pc, ok := o.PackageConfigs[name]
+		if !ok {
+			return fmt.Sprintf("no melange config found for package %s", name)
+		}
+
+		l := lint.New(lint.WithPath(pc.Dir), lint.WithPackages(name), lint.WithContext(o.context()))
+		result, err := l.Lint()
+		if err != nil {
+			return fmt.Sprintf("failed to lint package %s: %s", name, err)
+		}
+		var lintErrors, warnings []string
+		for _, r := range result {
+			for _, e := range r.Errors {
+				msg := fmt.Sprintf("[%s]: %s", e.Rule.Name, e.Message)
+				if e.Rule.Severity == lint.SeverityError {
+					lintErrors = append(lintErrors, msg)
+				} else {
+					warnings = append(warnings, msg)
+				}
+			}
+		}
+		if len(lintErrors) > 0 {
+			return fmt.Sprintf("bumped config of package %s fails to lint: %s", name, strings.Join(lintErrors, "; "))
+		}
+		fmt.Fprintf(&report, "- %s: lint passed", name)
+		if len(warnings) > 0 {
+			fmt.Fprintf(&report, " with warnings: %s", strings.Join(warnings, "; "))
+		}
+
+		if o.Validate == ValidateBuild {
+			d, log, err := o.buildPackage(pc)
+			if err != nil {
+				return fmt.Sprintf("bumped package %s fails to build: %s\n\n%s", name, err, lastLines(log, maxBuildLogLines))
+			}
+			fmt.Fprintf(&report, ", built in %s", d.Round(time.Second))
+		}
+		report.WriteString("\n")
+	}
+
+	if o.validations == nil {
+		o.validations = make(map[string]string)
+	}
+	o.validations[packageName] = report.String()
+	return ""
+}
+
+// buildPackage builds the bumped config of a package for the architecture of this machine with BuildExecutor, or
+// with make in the directory of the configs if none is set, and returns how long it took and the log of the build
+func (o *Options) buildPackage(pc *melange.Packages) (time.Duration, string, error) {
+	cfg, err := melange.ReadMelangeConfig(filepath.Join(pc.Dir, pc.Filename))
+	if err != nil {
+		return 0, "", err
+	}
+	var executor builder.Executor = builder.Local{Dir: pc.Dir}
+	if o.BuildExecutor != nil {
+		executor = o.BuildExecutor
+	}
+
+	var log bytes.Buffer
+	t := builder.Task{
+		Config: &dag.Configuration{Configuration: &cfg, Path: filepath.Join(pc.Dir, pc.Filename)},
+		Arch:   types.ParseArchitecture(runtime.GOARCH).ToAPK(),
+		Output: &log,
+	}
+	o.Logger.Printf("%s: building %s", cfg.Package.Name, t.Target())
+	start := time.Now()
+	err = executor.Build(o.context(), t)
+	return time.Since(start), log.String(), err
+}
+
+// lastLines returns the last n lines of s
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
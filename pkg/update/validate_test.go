@@ -0,0 +1,83 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wolfi-dev/wolfictl/pkg/builder"
+	"github.com/wolfi-dev/wolfictl/pkg/melange"
+)
+
+// fakeExecutor fails the builds of the packages in failing
+type fakeExecutor struct {
+	built   []string
+	failing map[string]bool
+}
+
+func (e *fakeExecutor) Sync(context.Context, string) error    { return nil }
+func (e *fakeExecutor) Collect(context.Context, string) error { return nil }
+
+func (e *fakeExecutor) Build(_ context.Context, t builder.Task) error {
+	e.built = append(e.built, t.Target())
+	if e.failing[t.Name()] {
+		fmt.Fprintln(t.Output, "compiling...\nerror: undefined reference to `foo'")
+		return errors.New("exit status 2")
+	}
+	return nil
+}
+
+func TestOptions_validatePackages(t *testing.T) {
+	dir := t.TempDir()
+	config := func(name, repository string) string {
+		return fmt.Sprintf(`package:
+  name: %s
+  version: 1.2.3
+  epoch: 0
+  description: a bumped package
+environment:
+  contents:
+    repositories:
+      - %s
+pipeline:
+  - runs: make
+`, name, repository)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "curl.yaml"), []byte(config("curl", "https://packages.wolfi.dev/bootstrap/stage3")), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "jq.yaml"), []byte(config("jq", "https://packages.wolfi.dev/os")), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "zlib.yaml"), []byte(config("zlib", "https://packages.wolfi.dev/bootstrap/stage3")), 0o600))
+	configs, err := melange.ReadPackageConfigs([]string{"curl", "jq", "zlib"}, dir)
+	require.NoError(t, err)
+
+	executor := &fakeExecutor{failing: map[string]bool{"zlib": true}}
+	o := Options{
+		Logger:         log.New(io.Discard, "", 0),
+		PackageConfigs: configs,
+		Validate:       ValidateLint,
+		BuildExecutor:  executor,
+	}
+
+	assert.Empty(t, o.validatePackages("curl", []string{"curl"}))
+	assert.Contains(t, o.validations["curl"], "- curl: lint passed with warnings: [valid-copyright-header]: copyright header is missing")
+	assert.Empty(t, executor.built, "packages should only be built with --validate build")
+
+	assert.Contains(t, o.validatePackages("jq", []string{"jq"}), "bumped config of package jq fails to lint: [forbidden-repository-used]")
+	assert.NotContains(t, o.validations, "jq")
+
+	o.Validate = ValidateBuild
+	assert.Empty(t, o.validatePackages("curl", []string{"curl"}))
+	assert.Contains(t, o.validations["curl"], ", built in 0s")
+	msg := o.validatePackages("@libs", []string{"curl", "zlib"})
+	assert.Contains(t, msg, "bumped package zlib fails to build: exit status 2")
+	assert.Contains(t, msg, "error: undefined reference to `foo'", "the end of the build log should be reported")
+	assert.Len(t, executor.built, 3)
+	assert.Contains(t, executor.built[0], "curl-1.2.3-r0.apk")
+}
@@ -0,0 +1,162 @@
+package vex
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	purl "github.com/package-url/packageurl-go"
+
+	"github.com/openvex/go-vex/pkg/vex"
+
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+)
+
+// FromAdvisories generates a VEX document from the advisories of the given advisory documents, with a statement for
+// every entry of the advisory of a vulnerability of a package. The packages are identified by their versionless purl,
+// as the advisories apply to the package, not a version of it, except for fixes: the version with the fix is fixed,
+// and the package is affected, as the versions before it are.
+func FromAdvisories(vexCfg Config, docs ...advisoryconfigs.Document) (*vex.VEX, error) {
+	doc := newDocument(vexCfg)
+
+	for i := range docs {
+		name := docs[i].Package.Name
+		subjects := func(fixed string) []statementSubject {
+			versionless := statementSubject{products: []string{packagePURL(vexCfg.Distro, name, "")}}
+			if fixed == "" {
+				return []statementSubject{versionless}
+			}
+			return []statementSubject{versionless, {products: []string{packagePURL(vexCfg.Distro, name, fixed)}, hasFix: true}}
+		}
+		doc.Statements = append(doc.Statements, statementsFromAdvisoryEntries(docs[i].Advisories, subjects)...)
+	}
+
+	return finalizeDocument(&doc)
+}
+
+// FromImageAdvisories generates a VEX document for the image its SBOM describes, from the advisories of the distro
+// packages it contains. The statements are about the image, with the packages as subcomponents. sbomPath is a path
+// to an SPDX SBOM or the reference of an image with an SBOM attached, ideally by digest.
+func FromImageAdvisories(ctx context.Context, vexCfg Config, sbomPath string, docs ...advisoryconfigs.Document) (*vex.VEX, error) {
+	sbom, err := parseSBOM(ctx, sbomPath)
+	if err != nil {
+		return nil, fmt.Errorf("parsing SBOM: %w", err)
+	}
+
+	byName := make(map[string]*advisoryconfigs.Document, len(docs))
+	for i := range docs {
+		byName[docs[i].Package.Name] = &docs[i]
+	}
+
+	doc := newDocument(vexCfg)
+	for product, packagePurls := range extractSBOMPurls(vexCfg, sbom) {
+		for _, p := range packagePurls {
+			advisories, ok := byName[originOf(p)]
+			if !ok {
+				continue
+			}
+			installed := p
+			subjects := func(fixed string) []statementSubject {
+				return []statementSubject{{
+					products:      []string{product},
+					subcomponents: []string{installed.ToString()},
+					hasFix:        fixed != "" && dag.CompareVersions(installed.Version, fixed) >= 0,
+				}}
+			}
+			doc.Statements = append(doc.Statements, statementsFromAdvisoryEntries(advisories.Advisories, subjects)...)
+		}
+	}
+
+	return finalizeDocument(&doc)
+}
+
+func newDocument(vexCfg Config) vex.VEX {
+	doc := vex.New()
+	if vexCfg.Author != "" {
+		doc.Author = vexCfg.Author
+	}
+	if vexCfg.AuthorRole != "" {
+		doc.AuthorRole = vexCfg.AuthorRole
+	}
+	return doc
+}
+
+// finalizeDocument sorts the statements of the document and gives it an ID that only changes with them.
+func finalizeDocument(doc *vex.VEX) (*vex.VEX, error) {
+	vex.SortStatements(doc.Statements, *doc.Timestamp)
+	if _, err := doc.GenerateCanonicalID(); err != nil {
+		return nil, fmt.Errorf("generating doc ID: %w", err)
+	}
+	return doc, nil
+}
+
+// statementsFromAdvisoryEntries returns the statements of the entries of the advisories, which record the triage
+// events as the VEX status they amount to: a false positive is not_affected with its justification, a fix is fixed,
+// and so on.
+//
+// subjects returns what the statements of an entry are about, given the version the fix is in for fixes. A fix is
+// only fixed for the subjects that have it, and affected for the others. Fixes without a fixed version don't say
+// which versions have them, so they stay under investigation.
+func statementsFromAdvisoryEntries(advisories advisoryconfigs.Advisories, subjects func(fixed string) []statementSubject) []vex.Statement {
+	vulns := make([]string, 0, len(advisories))
+	for v := range advisories {
+		vulns = append(vulns, v)
+	}
+	sort.Strings(vulns)
+
+	var stmts []vex.Statement
+	for _, v := range vulns {
+		entries := advisories[v]
+		for i := range entries {
+			var fixed string
+			if entries[i].Status == vex.StatusFixed {
+				fixed = entries[i].FixedVersion
+			}
+			for _, subject := range subjects(fixed) {
+				stmt := vex.Statement{
+					Vulnerability:   v,
+					Status:          entries[i].Status,
+					Justification:   entries[i].Justification,
+					ActionStatement: entries[i].ActionStatement,
+					ImpactStatement: entries[i].ImpactStatement,
+					Products:        subject.products,
+					Subcomponents:   subject.subcomponents,
+					Timestamp:       &entries[i].Timestamp,
+				}
+				switch {
+				case entries[i].Status != vex.StatusFixed:
+				case fixed == "":
+					stmt.Status = vex.StatusUnderInvestigation
+				case !subject.hasFix:
+					stmt.Status = vex.StatusAffected
+					if stmt.ActionStatement == "" {
+						stmt.ActionStatement = fmt.Sprintf("Upgrade to %s or later", fixed)
+					}
+				}
+				stmts = append(stmts, stmt)
+			}
+		}
+	}
+	return stmts
+}
+
+// statementSubject is what a statement is about, and whether it has the fix of the vulnerability, for fixes.
+type statementSubject struct {
+	products, subcomponents []string
+	hasFix                  bool
+}
+
+// packagePURL returns the purl of a version of a distro package, or its versionless purl if version is empty.
+func packagePURL(distro, name, version string) string {
+	return purl.NewPackageURL("apk", distro, name, version, nil, "").ToString()
+}
+
+// originOf returns the name of the package the package of a purl was built by, which advisories are recorded for:
+// its origin qualifier if it has one, or else its name.
+func originOf(p purl.PackageURL) string {
+	if origin := p.Qualifiers.Map()["origin"]; origin != "" {
+		return origin
+	}
+	return p.Name
+}
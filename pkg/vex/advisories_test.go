@@ -0,0 +1,113 @@
+package vex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	advisoryconfigs "github.com/wolfi-dev/wolfictl/pkg/configs/advisory"
+	rwos "github.com/wolfi-dev/wolfictl/pkg/configs/rwfs/os"
+)
+
+func testAdvisories(t *testing.T) []advisoryconfigs.Document {
+	index, err := advisoryconfigs.NewIndex(rwos.DirFS("testdata/advisories"))
+	require.NoError(t, err)
+	return index.Select().Configurations()
+}
+
+func TestFromAdvisories(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "2023-11-01T00:00:00Z")
+
+	doc, err := FromAdvisories(Config{Distro: "wolfi", Author: "wolfi"}, testAdvisories(t)...)
+	require.NoError(t, err)
+
+	assert.Equal(t, "wolfi", doc.Author)
+	assert.NotEmpty(t, doc.ID)
+	require.Len(t, doc.Statements, 6, "a statement per entry, and one about the versions before a fix")
+
+	// statements are sorted by vulnerability, then time
+	first, last := doc.Statements[0], doc.Statements[5]
+	assert.Equal(t, "CVE-2023-45853", first.Vulnerability)
+	assert.Equal(t, []string{"pkg:apk/wolfi/zlib"}, first.Products)
+	assert.Equal(t, vex.StatusNotAffected, first.Status)
+	assert.Equal(t, vex.VulnerableCodeNotPresent, first.Justification)
+	assert.Equal(t, "the minizip code is not built", first.ImpactStatement)
+
+	assert.Equal(t, vex.StatusUnderInvestigation, doc.Statements[1].Status)
+	assert.Equal(t, vex.StatusAffected, doc.Statements[2].Status)
+	assert.Equal(t, []string{"pkg:apk/wolfi/glibc"}, doc.Statements[2].Products)
+	assert.Equal(t, "Upgrade to 2.38-r2 or later", doc.Statements[2].ActionStatement)
+	assert.Equal(t, vex.StatusFixed, doc.Statements[3].Status)
+	assert.Equal(t, []string{"pkg:apk/wolfi/glibc@2.38-r2"}, doc.Statements[3].Products, "only the version with the fix is fixed")
+
+	assert.Equal(t, "GHSA-xxxx-yyyy-zzzz", last.Vulnerability)
+	assert.Equal(t, []string{"pkg:apk/wolfi/curl"}, last.Products)
+
+	again, err := FromAdvisories(Config{Distro: "wolfi", Author: "wolfi"}, testAdvisories(t)...)
+	require.NoError(t, err)
+	assert.Equal(t, doc.ID, again.ID, "the ID is deterministic")
+}
+
+func TestFromImageAdvisories(t *testing.T) {
+	doc, err := FromImageAdvisories(context.Background(), Config{Distro: "wolfi"}, "testdata/git.spdx.json", testAdvisories(t)...)
+	require.NoError(t, err)
+
+	// curl isn't in the image
+	require.Len(t, doc.Statements, 4)
+	for _, s := range doc.Statements {
+		require.Len(t, s.Products, 1)
+		assert.Contains(t, s.Products[0], "pkg:oci/git@sha256:")
+		require.Len(t, s.Subcomponents, 1)
+		assert.Contains(t, []string{
+			"pkg:apk/wolfi/glibc@2.36-r3?arch=x86_64",
+			"pkg:apk/wolfi/zlib@1.2.13-r1?arch=x86_64",
+		}, s.Subcomponents[0])
+	}
+}
+
+func TestToCSAF(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "2023-11-01T00:00:00Z")
+
+	cfg := Config{Distro: "wolfi", Namespace: "https://wolfi.dev"}
+	doc, err := FromAdvisories(cfg, testAdvisories(t)...)
+	require.NoError(t, err)
+
+	csaf := ToCSAF(cfg, doc)
+	assert.Equal(t, "csaf_vex", csaf.Document.Category)
+	assert.Equal(t, CSAFPublisher{Category: "vendor", Name: "wolfi", Namespace: "https://wolfi.dev"}, csaf.Document.Publisher)
+	assert.Equal(t, doc.ID, csaf.Document.Tracking.ID)
+	assert.Equal(t, time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC), csaf.Document.Tracking.CurrentReleaseDate)
+	assert.Len(t, csaf.ProductTree.FullProductNames, 4)
+	assert.Empty(t, csaf.ProductTree.Relationships)
+
+	require.Len(t, csaf.Vulnerabilities, 4, "a vulnerability per vulnerability")
+	fixed := csaf.Vulnerabilities[1]
+	assert.Equal(t, "CVE-2023-4911", fixed.CVE)
+	assert.Equal(t, map[string][]string{"fixed": {"pkg:apk/wolfi/glibc@2.38-r2"}, "known_affected": {"pkg:apk/wolfi/glibc"}}, fixed.ProductStatus, "only the latest entry counts")
+
+	notPlanned := csaf.Vulnerabilities[2]
+	assert.Equal(t, map[string][]string{"known_affected": {"pkg:apk/wolfi/glibc"}}, notPlanned.ProductStatus)
+	assert.Equal(t, []CSAFNote{{Category: "no_fix_planned", Details: "fix not planned", ProductIDs: []string{"pkg:apk/wolfi/glibc"}}}, notPlanned.Remediations)
+
+	notAffected := csaf.Vulnerabilities[0]
+	assert.Equal(t, "CVE-2023-45853", notAffected.CVE)
+	assert.Equal(t, []CSAFNote{{Label: "vulnerable_code_not_present", ProductIDs: []string{"pkg:apk/wolfi/zlib"}}}, notAffected.Flags)
+	assert.Equal(t, []CSAFNote{{Category: "impact", Details: "the minizip code is not built", ProductIDs: []string{"pkg:apk/wolfi/zlib"}}}, notAffected.Threats)
+
+	ghsa := csaf.Vulnerabilities[3]
+	assert.Empty(t, ghsa.CVE)
+	assert.Equal(t, []CSAFID{{SystemName: "GitHub Security Advisory", Text: "GHSA-xxxx-yyyy-zzzz"}}, ghsa.IDs)
+
+	imageDoc, err := FromImageAdvisories(context.Background(), cfg, "testdata/git.spdx.json", testAdvisories(t)...)
+	require.NoError(t, err)
+	imageCSAF := ToCSAF(cfg, imageDoc)
+	require.Len(t, imageCSAF.ProductTree.Relationships, 2, "the glibc and zlib packages of the image")
+	rel := imageCSAF.ProductTree.Relationships[0]
+	assert.Equal(t, "default_component_of", rel.Category)
+	assert.Equal(t, "pkg:apk/wolfi/glibc@2.36-r3?arch=x86_64", rel.ProductReference)
+	assert.Contains(t, imageCSAF.Vulnerabilities[1].ProductStatus["known_affected"], rel.FullProductName.ProductID, "glibc@2.36-r3 is older than the fix")
+	assert.Empty(t, imageCSAF.Vulnerabilities[1].ProductStatus["fixed"])
+}
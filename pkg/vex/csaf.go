@@ -0,0 +1,280 @@
+package vex
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+
+	"github.com/wolfi-dev/wolfictl/pkg/advisory"
+)
+
+// CSAF is a CSAF 2.0 document of the csaf_vex profile, https://docs.oasis-open.org/csaf/csaf/v2.0/csaf-v2.0.html.
+type CSAF struct {
+	Document        CSAFDocument        `json:"document"`
+	ProductTree     CSAFProductTree     `json:"product_tree"`
+	Vulnerabilities []CSAFVulnerability `json:"vulnerabilities"`
+}
+
+type CSAFDocument struct {
+	Category    string        `json:"category"`
+	CSAFVersion string        `json:"csaf_version"`
+	Publisher   CSAFPublisher `json:"publisher"`
+	Title       string        `json:"title"`
+	Tracking    CSAFTracking  `json:"tracking"`
+}
+
+type CSAFPublisher struct {
+	Category  string `json:"category"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+type CSAFTracking struct {
+	ID                 string         `json:"id"`
+	Status             string         `json:"status"`
+	Version            string         `json:"version"`
+	InitialReleaseDate time.Time      `json:"initial_release_date"`
+	CurrentReleaseDate time.Time      `json:"current_release_date"`
+	RevisionHistory    []CSAFRevision `json:"revision_history"`
+}
+
+type CSAFRevision struct {
+	Date    time.Time `json:"date"`
+	Number  string    `json:"number"`
+	Summary string    `json:"summary"`
+}
+
+type CSAFProductTree struct {
+	FullProductNames []CSAFProduct      `json:"full_product_names"`
+	Relationships    []CSAFRelationship `json:"relationships,omitempty"`
+}
+
+type CSAFProduct struct {
+	Name                        string                 `json:"name"`
+	ProductID                   string                 `json:"product_id"`
+	ProductIdentificationHelper *CSAFProductIdentifier `json:"product_identification_helper,omitempty"`
+}
+
+type CSAFProductIdentifier struct {
+	PURL string `json:"purl"`
+}
+
+// CSAFRelationship is a product made of the product of its ProductReference being a component of the one of its
+// RelatesToProductReference, e.g. a package installed in an image.
+type CSAFRelationship struct {
+	Category                  string      `json:"category"`
+	FullProductName           CSAFProduct `json:"full_product_name"`
+	ProductReference          string      `json:"product_reference"`
+	RelatesToProductReference string      `json:"relates_to_product_reference"`
+}
+
+type CSAFVulnerability struct {
+	CVE           string              `json:"cve,omitempty"`
+	IDs           []CSAFID            `json:"ids,omitempty"`
+	ProductStatus map[string][]string `json:"product_status"`
+	Flags         []CSAFNote          `json:"flags,omitempty"`
+	Threats       []CSAFNote          `json:"threats,omitempty"`
+	Remediations  []CSAFNote          `json:"remediations,omitempty"`
+}
+
+type CSAFID struct {
+	SystemName string `json:"system_name"`
+	Text       string `json:"text"`
+}
+
+// CSAFNote is a flag, threat or remediation about products. Flags have a label, the others a category and details.
+type CSAFNote struct {
+	Label      string   `json:"label,omitempty"`
+	Category   string   `json:"category,omitempty"`
+	Details    string   `json:"details,omitempty"`
+	ProductIDs []string `json:"product_ids"`
+}
+
+// csafProductStatuses are the CSAF product statuses of the VEX statuses.
+var csafProductStatuses = map[vex.Status]string{
+	vex.StatusNotAffected:        "known_not_affected",
+	vex.StatusAffected:           "known_affected",
+	vex.StatusFixed:              "fixed",
+	vex.StatusUnderInvestigation: "under_investigation",
+}
+
+// ToCSAF converts a VEX document to a CSAF VEX document. CSAF only has the current status of the products, so the
+// latest statement about each product is the one converted. The products of statements with subcomponents are the
+// subcomponents being a component of the products, like the packages of an image.
+func ToCSAF(vexCfg Config, doc *vex.VEX) *CSAF {
+	statements := make([]vex.Statement, len(doc.Statements))
+	copy(statements, doc.Statements)
+	vex.SortStatements(statements, *doc.Timestamp)
+
+	products := make(map[string]CSAFProduct)
+	relationships := make(map[string]CSAFRelationship)
+	addProduct := func(p string) {
+		products[p] = CSAFProduct{Name: p, ProductID: p, ProductIdentificationHelper: &CSAFProductIdentifier{PURL: p}}
+	}
+
+	// latest statements by vulnerability and product ID, later statements replacing earlier ones
+	latest := make(map[string]map[string]vex.Statement)
+	for i := range statements {
+		s := statements[i]
+		if latest[s.Vulnerability] == nil {
+			latest[s.Vulnerability] = make(map[string]vex.Statement)
+		}
+		for _, p := range s.Products {
+			addProduct(p)
+			if len(s.Subcomponents) == 0 {
+				latest[s.Vulnerability][p] = s
+				continue
+			}
+			for _, sub := range s.Subcomponents {
+				addProduct(sub)
+				id := fmt.Sprintf("%s#%s", p, sub)
+				relationships[id] = CSAFRelationship{
+					Category:                  "default_component_of",
+					FullProductName:           CSAFProduct{Name: fmt.Sprintf("%s as a component of %s", sub, p), ProductID: id},
+					ProductReference:          sub,
+					RelatesToProductReference: p,
+				}
+				latest[s.Vulnerability][id] = s
+			}
+		}
+	}
+
+	csaf := &CSAF{
+		Document: CSAFDocument{
+			Category:    "csaf_vex",
+			CSAFVersion: "2.0",
+			Publisher: CSAFPublisher{
+				Category:  "vendor",
+				Name:      csafPublisherName(vexCfg),
+				Namespace: vexCfg.Namespace,
+			},
+			Title: fmt.Sprintf("VEX document of %s packages", vexCfg.Distro),
+			Tracking: CSAFTracking{
+				ID:                 doc.ID,
+				Status:             "final",
+				Version:            doc.Version,
+				InitialReleaseDate: doc.Timestamp.UTC(),
+				CurrentReleaseDate: doc.Timestamp.UTC(),
+				RevisionHistory: []CSAFRevision{
+					{Date: doc.Timestamp.UTC(), Number: doc.Version, Summary: "Generated from advisory data"},
+				},
+			},
+		},
+		ProductTree: CSAFProductTree{
+			FullProductNames: sortedValues(products),
+			Relationships:    sortedValues(relationships),
+		},
+	}
+
+	vulns := make([]string, 0, len(latest))
+	for v := range latest {
+		vulns = append(vulns, v)
+	}
+	sort.Strings(vulns)
+	for _, v := range vulns {
+		csaf.Vulnerabilities = append(csaf.Vulnerabilities, csafVulnerability(v, latest[v]))
+	}
+
+	return csaf
+}
+
+// csafVulnerability returns the CSAF vulnerability of the latest statements about a vulnerability by product ID.
+func csafVulnerability(id string, statements map[string]vex.Statement) CSAFVulnerability {
+	v := CSAFVulnerability{ProductStatus: make(map[string][]string)}
+	if strings.HasPrefix(id, "CVE-") {
+		v.CVE = id
+	} else {
+		v.IDs = []CSAFID{{SystemName: vulnerabilitySystem(id), Text: id}}
+	}
+
+	productIDs := make([]string, 0, len(statements))
+	for p := range statements {
+		productIDs = append(productIDs, p)
+	}
+	sort.Strings(productIDs)
+
+	// notes with the same label or category and details are about all their products at once
+	type note struct{ label, category, details string }
+	notes := make(map[note][]string)
+	var order []note
+	addNote := func(n note, p string) {
+		if _, ok := notes[n]; !ok {
+			order = append(order, n)
+		}
+		notes[n] = append(notes[n], p)
+	}
+
+	for _, p := range productIDs {
+		s := statements[p]
+		status, ok := csafProductStatuses[s.Status]
+		if !ok {
+			continue
+		}
+		v.ProductStatus[status] = append(v.ProductStatus[status], p)
+
+		switch s.Status {
+		case vex.StatusNotAffected:
+			if s.Justification != "" {
+				addNote(note{label: string(s.Justification)}, p)
+			}
+			if s.ImpactStatement != "" {
+				addNote(note{category: "impact", details: s.ImpactStatement}, p)
+			}
+		case vex.StatusAffected:
+			category := "none_available"
+			if s.ActionStatement == advisory.FixNotPlannedAction {
+				category = "no_fix_planned"
+			}
+			addNote(note{category: category, details: s.ActionStatement}, p)
+		}
+	}
+
+	for _, n := range order {
+		csafNote := CSAFNote{Label: n.label, Category: n.category, Details: n.details, ProductIDs: notes[n]}
+		switch {
+		case n.label != "":
+			v.Flags = append(v.Flags, csafNote)
+		case n.category == "impact":
+			v.Threats = append(v.Threats, csafNote)
+		default:
+			v.Remediations = append(v.Remediations, csafNote)
+		}
+	}
+
+	return v
+}
+
+// vulnerabilitySystem returns the name of the system a vulnerability ID is from.
+func vulnerabilitySystem(id string) string {
+	switch {
+	case strings.HasPrefix(id, "GHSA-"):
+		return "GitHub Security Advisory"
+	case strings.HasPrefix(id, "GO-"):
+		return "Go Vulnerability Database"
+	}
+	return "Vulnerability ID"
+}
+
+func csafPublisherName(vexCfg Config) string {
+	if vexCfg.Author != "" {
+		return vexCfg.Author
+	}
+	return vexCfg.Distro
+}
+
+// sortedValues returns the values of the map sorted by key.
+func sortedValues[T any](m map[string]T) []T {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	values := make([]T, 0, len(keys))
+	for _, k := range keys {
+		values = append(values, m[k])
+	}
+	return values
+}
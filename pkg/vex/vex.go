@@ -32,6 +32,9 @@ import (
 
 type Config struct {
 	Distro, Author, AuthorRole, DistroRepo string
+
+	// Namespace is the URL of the distro, which publishes the CSAF documents.
+	Namespace string
 }
 
 // FromSBOM parses an SPDX SBOM and returns a VEX document describing
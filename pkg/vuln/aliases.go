@@ -0,0 +1,168 @@
+package vuln
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/exp/slices"
+)
+
+// AliasFinder finds the aliases of a vulnerability, the IDs other databases know it by, e.g. the CVE of a GHSA.
+type AliasFinder interface {
+	Aliases(ctx context.Context, id string) ([]string, error)
+}
+
+// Aliases are the aliases of vulnerabilities, by ID.
+type Aliases map[string][]string
+
+// Same reports whether the IDs are of the same vulnerability, being the same or one an alias of the other.
+func (a Aliases) Same(id, other string) bool {
+	return id == other || slices.Contains(a[id], other) || slices.Contains(a[other], id)
+}
+
+// Of returns the ID and its aliases, the ID first.
+func (a Aliases) Of(id string) []string {
+	ids := []string{id}
+	for _, alias := range a[id] {
+		if !slices.Contains(ids, alias) {
+			ids = append(ids, alias)
+		}
+	}
+	return ids
+}
+
+// idPreference is the order in which the kinds of IDs of a vulnerability are preferred, by prefix: CVEs are what most
+// scanners and databases report, then GHSAs.
+var idPreference = []string{"CVE-", "GHSA-"}
+
+// CanonicalID returns the ID of a vulnerability to keep its advisory under, out of the ID and its aliases: a CVE if
+// there's one, otherwise a GHSA, otherwise the first one. IDs of the same kind are preferred in sorted order.
+func CanonicalID(ids ...string) string {
+	if len(ids) == 0 {
+		return ""
+	}
+	sorted := make([]string, len(ids))
+	copy(sorted, ids)
+	sort.Strings(sorted)
+	for _, prefix := range idPreference {
+		for _, id := range sorted {
+			if strings.HasPrefix(id, prefix) {
+				return id
+			}
+		}
+	}
+	return ids[0]
+}
+
+// DefaultAliasCacheDir returns the directory aliases are cached in by default, in the cache directory of the user.
+func DefaultAliasCacheDir() (string, error) {
+	return userCacheDir("aliases")
+}
+
+// AliasResolver finds the aliases of vulnerabilities with all its finders, e.g. OSV and GitHub, and caches them,
+// in CacheDir if it isn't "", as aliases hardly ever change.
+type AliasResolver struct {
+	Finders []AliasFinder
+
+	// CacheDir is the directory the aliases are cached in, or "" to only cache them in memory.
+	CacheDir string
+
+	mu    sync.Mutex
+	cache map[string][]string
+}
+
+// Aliases returns the aliases the finders know of the vulnerability, sorted.
+func (r *AliasResolver) Aliases(ctx context.Context, id string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if aliases, ok := r.cache[id]; ok {
+		return aliases, nil
+	}
+	if aliases, err := r.readCache(id); err == nil {
+		r.remember(id, aliases)
+		return aliases, nil
+	}
+
+	aliases := []string{}
+	for _, f := range r.Finders {
+		found, err := f.Aliases(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find the aliases of %s: %w", id, err)
+		}
+		for _, alias := range found {
+			if alias != id && !slices.Contains(aliases, alias) {
+				aliases = append(aliases, alias)
+			}
+		}
+	}
+	sort.Strings(aliases)
+
+	if err := r.writeCache(id, aliases); err != nil {
+		return nil, fmt.Errorf("unable to cache the aliases of %s: %w", id, err)
+	}
+	r.remember(id, aliases)
+	return aliases, nil
+}
+
+// Resolve returns the aliases of the vulnerabilities.
+func (r *AliasResolver) Resolve(ctx context.Context, ids ...string) (Aliases, error) {
+	aliases := make(Aliases)
+	for _, id := range ids {
+		found, err := r.Aliases(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if len(found) > 0 {
+			aliases[id] = found
+		}
+	}
+	return aliases, nil
+}
+
+func (r *AliasResolver) remember(id string, aliases []string) {
+	if r.cache == nil {
+		r.cache = make(map[string][]string)
+	}
+	r.cache[id] = aliases
+}
+
+func (r *AliasResolver) cachePath(id string) string {
+	return filepath.Join(r.CacheDir, id+".json")
+}
+
+func (r *AliasResolver) readCache(id string) ([]string, error) {
+	if r.CacheDir == "" {
+		return nil, os.ErrNotExist
+	}
+	b, err := os.ReadFile(r.cachePath(id))
+	if err != nil {
+		return nil, err
+	}
+	var aliases []string
+	if err := json.Unmarshal(b, &aliases); err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+func (r *AliasResolver) writeCache(id string, aliases []string) error {
+	if r.CacheDir == "" {
+		return nil
+	}
+	b, err := json.Marshal(aliases)
+	if err != nil {
+		return err
+	}
+	path := r.cachePath(id)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644) //nolint:gosec // cached public vulnerability data
+}
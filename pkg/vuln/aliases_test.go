@@ -0,0 +1,67 @@
+package vuln
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAliasFinder struct {
+	aliases map[string][]string
+	calls   int
+}
+
+func (f *fakeAliasFinder) Aliases(_ context.Context, id string) ([]string, error) {
+	f.calls++
+	return f.aliases[id], nil
+}
+
+func TestAliasResolver(t *testing.T) {
+	ctx := context.Background()
+	osv := &fakeAliasFinder{aliases: map[string][]string{
+		"GO-2023-1573": {"CVE-2023-25153", "GHSA-259w-8hf6-59c2"},
+	}}
+	github := &fakeAliasFinder{aliases: map[string][]string{
+		"GHSA-259w-8hf6-59c2": {"CVE-2023-25153"},
+		"CVE-2023-25153":      {"GHSA-259w-8hf6-59c2"},
+	}}
+	cacheDir := t.TempDir()
+	r := &AliasResolver{Finders: []AliasFinder{osv, github}, CacheDir: cacheDir}
+
+	aliases, err := r.Resolve(ctx, "GO-2023-1573", "CVE-2023-25153", "CVE-2023-0001")
+	require.NoError(t, err)
+	assert.Equal(t, Aliases{
+		"GO-2023-1573":   {"CVE-2023-25153", "GHSA-259w-8hf6-59c2"},
+		"CVE-2023-25153": {"GHSA-259w-8hf6-59c2"},
+	}, aliases)
+	assert.True(t, aliases.Same("GHSA-259w-8hf6-59c2", "CVE-2023-25153"))
+	assert.True(t, aliases.Same("CVE-2023-25153", "GO-2023-1573"), "aliases are symmetric")
+	assert.False(t, aliases.Same("CVE-2023-25153", "CVE-2023-0001"))
+	assert.Equal(t, []string{"CVE-2023-25153", "GHSA-259w-8hf6-59c2"}, aliases.Of("CVE-2023-25153"))
+	assert.Equal(t, []string{"CVE-2023-0001"}, aliases.Of("CVE-2023-0001"))
+
+	// cached in memory
+	_, err = r.Aliases(ctx, "GO-2023-1573")
+	require.NoError(t, err)
+	assert.Equal(t, 3, github.calls)
+
+	// cached on disk, including the vulnerabilities without aliases
+	r = &AliasResolver{Finders: []AliasFinder{osv, github}, CacheDir: cacheDir}
+	cached, err := r.Aliases(ctx, "GO-2023-1573")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CVE-2023-25153", "GHSA-259w-8hf6-59c2"}, cached)
+	none, err := r.Aliases(ctx, "CVE-2023-0001")
+	require.NoError(t, err)
+	assert.Empty(t, none)
+	assert.Equal(t, 3, github.calls)
+}
+
+func TestCanonicalID(t *testing.T) {
+	assert.Equal(t, "CVE-2023-25153", CanonicalID("GO-2023-1573", "GHSA-259w-8hf6-59c2", "CVE-2023-25153"))
+	assert.Equal(t, "GHSA-259w-8hf6-59c2", CanonicalID("GO-2023-1573", "GHSA-259w-8hf6-59c2"))
+	assert.Equal(t, "CVE-2023-0001", CanonicalID("CVE-2023-0002", "CVE-2023-0001"))
+	assert.Equal(t, "GO-2023-1573", CanonicalID("GO-2023-1573"))
+	assert.Equal(t, "", CanonicalID())
+}
@@ -2,6 +2,8 @@ package vuln
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	version "github.com/knqyf263/go-apk-version"
 )
@@ -24,6 +26,24 @@ type Vulnerability struct {
 	ID, URL string
 }
 
+// Details are what's known about a vulnerability of a package, to triage it.
+type Details struct {
+	ID, URL     string
+	Description string
+
+	// Severity is the base severity and score of the vulnerability, e.g. "HIGH 7.5", or empty if it isn't scored yet.
+	Severity string
+
+	// AffectedVersions are the upstream versions of the package the vulnerability affects.
+	AffectedVersions []VersionRange
+
+	// CWEs are the weaknesses the vulnerability is an instance of, e.g. "CWE-787".
+	CWEs []string
+
+	// References are the URLs of advisories, fixes and reports about the vulnerability.
+	References []string
+}
+
 type CPE struct {
 	URI          string
 	VersionRange VersionRange
@@ -42,6 +62,33 @@ type VersionRange struct {
 	VersionRangeUpperInclusive bool
 }
 
+// String returns the range as comparisons with its bounds, e.g. ">= 1.0.0, < 1.2.3".
+func (vr VersionRange) String() string {
+	if vr.SingleVersion != "" {
+		return "= " + vr.SingleVersion
+	}
+
+	var bounds []string
+	if vr.VersionRangeLower != "" {
+		op := ">"
+		if vr.VersionRangeLowerInclusive {
+			op = ">="
+		}
+		bounds = append(bounds, fmt.Sprintf("%s %s", op, vr.VersionRangeLower))
+	}
+	if vr.VersionRangeUpper != "" {
+		op := "<"
+		if vr.VersionRangeUpperInclusive {
+			op = "<="
+		}
+		bounds = append(bounds, fmt.Sprintf("%s %s", op, vr.VersionRangeUpper))
+	}
+	if len(bounds) == 0 {
+		return "all versions"
+	}
+	return strings.Join(bounds, ", ")
+}
+
 // Includes returns a bool indicating whether the given version is contained
 // within the VersionRange.
 //
@@ -0,0 +1,129 @@
+package vuln
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Detailer looks up the details of a vulnerability of a package.
+type Detailer interface {
+	VulnerabilityDetails(ctx context.Context, packageName, id string) (*Details, error)
+}
+
+// Enricher looks up the details of vulnerabilities with the Detailer of their kind of ID, e.g. NVD for CVEs and
+// GitHub for GHSAs, and caches them on disk, so they're available offline once they were looked up.
+type Enricher struct {
+	// Detailers are the detailers of the vulnerability IDs with their key as prefix, e.g. "CVE-".
+	Detailers map[string]Detailer
+
+	// CacheDir is the directory the details are cached in, or "" to not cache them.
+	CacheDir string
+
+	// MaxAge is how long cached details are used before they're looked up again, or 0 to use them forever. Stale
+	// details are still used if looking them up again fails, e.g. offline.
+	MaxAge time.Duration
+
+	now func() time.Time
+}
+
+// DefaultCacheDir returns the directory details are cached in by default, in the cache directory of the user.
+func DefaultCacheDir() (string, error) {
+	return userCacheDir("vulnerabilities")
+}
+
+func userCacheDir(name string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "wolfictl", name), nil
+}
+
+type cachedDetails struct {
+	Fetched time.Time `json:"fetched"`
+	Details *Details  `json:"details"`
+}
+
+// VulnerabilityDetails returns the details of a vulnerability of a package, from the cache if they're in it and not
+// older than MaxAge.
+func (e *Enricher) VulnerabilityDetails(ctx context.Context, packageName, id string) (*Details, error) {
+	cached, cacheErr := e.readCache(packageName, id)
+	if cacheErr == nil && (e.MaxAge == 0 || e.timeNow().Sub(cached.Fetched) < e.MaxAge) {
+		return cached.Details, nil
+	}
+
+	detailer := e.detailerOf(id)
+	if detailer == nil {
+		return nil, fmt.Errorf("no way to look up the details of %s", id)
+	}
+	details, err := detailer.VulnerabilityDetails(ctx, packageName, id)
+	if err != nil {
+		if cacheErr == nil {
+			return cached.Details, nil
+		}
+		return nil, err
+	}
+
+	if err := e.writeCache(packageName, id, cachedDetails{Fetched: e.timeNow(), Details: details}); err != nil {
+		return nil, fmt.Errorf("unable to cache the details of %s: %w", id, err)
+	}
+	return details, nil
+}
+
+func (e *Enricher) detailerOf(id string) Detailer {
+	for prefix, d := range e.Detailers {
+		if strings.HasPrefix(id, prefix) {
+			return d
+		}
+	}
+	return nil
+}
+
+// cachePath returns the path the details of a vulnerability of a package are cached at. They're cached per package,
+// as the versions a vulnerability affects depend on the package.
+func (e *Enricher) cachePath(packageName, id string) string {
+	return filepath.Join(e.CacheDir, packageName, id+".json")
+}
+
+func (e *Enricher) readCache(packageName, id string) (cachedDetails, error) {
+	if e.CacheDir == "" {
+		return cachedDetails{}, errors.New("no cache")
+	}
+	b, err := os.ReadFile(e.cachePath(packageName, id))
+	if err != nil {
+		return cachedDetails{}, err
+	}
+	var cached cachedDetails
+	if err := json.Unmarshal(b, &cached); err != nil {
+		return cachedDetails{}, err
+	}
+	return cached, nil
+}
+
+func (e *Enricher) writeCache(packageName, id string, cached cachedDetails) error {
+	if e.CacheDir == "" {
+		return nil
+	}
+	b, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	path := e.cachePath(packageName, id)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644) //nolint:gosec // cached public vulnerability data
+}
+
+func (e *Enricher) timeNow() time.Time {
+	if e.now != nil {
+		return e.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,69 @@
+package vuln
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDetailer struct {
+	calls int
+	err   error
+}
+
+func (d *fakeDetailer) VulnerabilityDetails(_ context.Context, _, id string) (*Details, error) {
+	d.calls++
+	if d.err != nil {
+		return nil, d.err
+	}
+	return &Details{ID: id, Severity: "HIGH 7.5", AffectedVersions: []VersionRange{{VersionRangeUpper: "1.2.3"}}}, nil
+}
+
+func TestEnricher(t *testing.T) {
+	ctx := context.Background()
+	nvd, github := &fakeDetailer{}, &fakeDetailer{}
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	e := &Enricher{
+		Detailers: map[string]Detailer{"CVE-": nvd, "GHSA-": github},
+		CacheDir:  t.TempDir(),
+		MaxAge:    24 * time.Hour,
+		now:       func() time.Time { return now },
+	}
+
+	details, err := e.VulnerabilityDetails(ctx, "curl", "CVE-2023-0001")
+	require.NoError(t, err)
+	assert.Equal(t, "HIGH 7.5", details.Severity)
+	_, err = e.VulnerabilityDetails(ctx, "containerd", "GHSA-259w-8hf6-59c2")
+	require.NoError(t, err)
+	assert.Equal(t, 1, nvd.calls)
+	assert.Equal(t, 1, github.calls)
+
+	// cached
+	cached, err := e.VulnerabilityDetails(ctx, "curl", "CVE-2023-0001")
+	require.NoError(t, err)
+	assert.Equal(t, details, cached)
+	assert.Equal(t, 1, nvd.calls)
+
+	// cached per package
+	_, err = e.VulnerabilityDetails(ctx, "libcurl", "CVE-2023-0001")
+	require.NoError(t, err)
+	assert.Equal(t, 2, nvd.calls)
+
+	// stale, looked up again, or used if that fails
+	now = now.Add(48 * time.Hour)
+	nvd.err = errors.New("offline")
+	stale, err := e.VulnerabilityDetails(ctx, "curl", "CVE-2023-0001")
+	require.NoError(t, err)
+	assert.Equal(t, details, stale)
+	assert.Equal(t, 3, nvd.calls)
+
+	_, err = e.VulnerabilityDetails(ctx, "curl", "CVE-2023-0002")
+	assert.ErrorContains(t, err, "offline")
+
+	_, err = e.VulnerabilityDetails(ctx, "curl", "GO-2023-0001")
+	assert.ErrorContains(t, err, "no way to look up the details of GO-2023-0001")
+}
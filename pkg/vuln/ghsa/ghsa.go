@@ -0,0 +1,194 @@
+package ghsa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+)
+
+// DefaultHost is the host of the GitHub REST API.
+const DefaultHost = "api.github.com"
+
+var (
+	_ vuln.Detailer    = (*Client)(nil)
+	_ vuln.AliasFinder = (*Client)(nil)
+)
+
+// Client looks up GitHub Security Advisories with the GitHub REST API.
+type Client struct {
+	client      *http.Client
+	serviceHost string
+	token       string
+}
+
+// NewClient returns a Client sending its requests to serviceHost. Requests are authenticated with token if it isn't
+// empty, which raises the rate limit of the API.
+func NewClient(client *http.Client, serviceHost, token string) *Client {
+	return &Client{
+		client:      client,
+		serviceHost: serviceHost,
+		token:       token,
+	}
+}
+
+// Advisory is a global security advisory of the GitHub Advisory Database.
+type Advisory struct {
+	GHSAID      string `json:"ghsa_id"`
+	CVEID       string `json:"cve_id"`
+	HTMLURL     string `json:"html_url"`
+	Summary     string `json:"summary"`
+	Description string `json:"description"`
+	Severity    string `json:"severity"`
+	CVSS        *struct {
+		VectorString string  `json:"vector_string"`
+		Score        float64 `json:"score"`
+	} `json:"cvss"`
+	CWEs []struct {
+		CWEID string `json:"cwe_id"`
+		Name  string `json:"name"`
+	} `json:"cwes"`
+	References      []string `json:"references"`
+	Vulnerabilities []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		VulnerableVersionRange string `json:"vulnerable_version_range"`
+		FirstPatchedVersion    string `json:"first_patched_version"`
+	} `json:"vulnerabilities"`
+}
+
+// VulnerabilityDetails returns what the GitHub Advisory Database knows about a GHSA affecting a package: its
+// description, its severity, its CWEs and references, and the versions it affects of the ecosystem packages named
+// like the package, e.g. of the Go module github.com/containerd/containerd for the package containerd.
+func (c *Client) VulnerabilityDetails(ctx context.Context, packageName, id string) (*vuln.Details, error) {
+	var adv Advisory
+	found, err := c.get(ctx, fmt.Sprintf("https://%s/advisories/%s", c.serviceHost, url.PathEscape(id)), &adv)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("GitHub has no advisory %s", id)
+	}
+
+	return adv.details(packageName), nil
+}
+
+// Aliases returns the CVE of a GHSA, or the GHSAs of a CVE. Other IDs have none GitHub knows of.
+func (c *Client) Aliases(ctx context.Context, id string) ([]string, error) {
+	switch {
+	case strings.HasPrefix(id, "GHSA-"):
+		var adv Advisory
+		found, err := c.get(ctx, fmt.Sprintf("https://%s/advisories/%s", c.serviceHost, url.PathEscape(id)), &adv)
+		if err != nil || !found || adv.CVEID == "" {
+			return nil, err
+		}
+		return []string{adv.CVEID}, nil
+
+	case strings.HasPrefix(id, "CVE-"):
+		var advs []Advisory
+		if _, err := c.get(ctx, fmt.Sprintf("https://%s/advisories?cve_id=%s", c.serviceHost, url.QueryEscape(id)), &advs); err != nil {
+			return nil, err
+		}
+		var aliases []string
+		for _, adv := range advs {
+			aliases = append(aliases, adv.GHSAID)
+		}
+		return aliases, nil
+	}
+	return nil, nil
+}
+
+// get decodes the JSON response to a request of the API into v, and returns false if there's nothing at reqURL.
+func (c *Client) get(ctx context.Context, reqURL string, v any) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, http.NoBody)
+	if err != nil {
+		return false, fmt.Errorf("unable to create request with URL %q: %w", reqURL, err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "wolfictl")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	log.Printf("☎️  sending API request: %s", reqURL)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("unable to complete request to URL %q: %w", reqURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("got unexpected response status %d for request to %q", resp.StatusCode, reqURL)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return false, fmt.Errorf("unable to decode JSON response to URL %q: %w", reqURL, err)
+	}
+	return true, nil
+}
+
+func (adv Advisory) details(packageName string) *vuln.Details {
+	details := &vuln.Details{
+		ID:          adv.GHSAID,
+		URL:         adv.HTMLURL,
+		Description: adv.Summary,
+		References:  adv.References,
+	}
+	if adv.Description != "" {
+		details.Description = fmt.Sprintf("%s\n\n%s", adv.Summary, adv.Description)
+	}
+	if adv.Severity != "" && adv.Severity != "unknown" {
+		details.Severity = strings.ToUpper(adv.Severity)
+		if adv.CVSS != nil && adv.CVSS.Score > 0 {
+			details.Severity = fmt.Sprintf("%s %.1f", details.Severity, adv.CVSS.Score)
+		}
+	}
+	for _, cwe := range adv.CWEs {
+		details.CWEs = append(details.CWEs, cwe.CWEID)
+	}
+	for _, v := range adv.Vulnerabilities {
+		name := v.Package.Name
+		if name != packageName && !strings.HasSuffix(name, "/"+packageName) {
+			continue
+		}
+		if vr, ok := parseVersionRange(v.VulnerableVersionRange); ok {
+			details.AffectedVersions = append(details.AffectedVersions, vr)
+		}
+	}
+	return details
+}
+
+// parseVersionRange parses the vulnerable version ranges of advisories, comparisons with versions separated by
+// commas, e.g. ">= 1.0.0, < 1.2.3", and returns false if it can't.
+func parseVersionRange(s string) (vuln.VersionRange, bool) {
+	var vr vuln.VersionRange
+	for _, comparison := range strings.Split(s, ",") {
+		op, v, ok := strings.Cut(strings.TrimSpace(comparison), " ")
+		if !ok {
+			return vuln.VersionRange{}, false
+		}
+		v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+		switch op {
+		case "=":
+			return vuln.VersionRange{SingleVersion: v}, true
+		case ">=", ">":
+			vr.VersionRangeLower, vr.VersionRangeLowerInclusive = v, op == ">="
+		case "<=", "<":
+			vr.VersionRangeUpper, vr.VersionRangeUpperInclusive = v, op == "<="
+		default:
+			return vuln.VersionRange{}, false
+		}
+	}
+	return vr, true
+}
@@ -0,0 +1,93 @@
+package ghsa
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+)
+
+func TestClient_VulnerabilityDetails(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer some-token", r.Header.Get("Authorization"))
+		serveAdvisories(t, w, r)
+	}))
+	defer ts.Close()
+
+	parsedURL, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	client := NewClient(ts.Client(), parsedURL.Host, "some-token")
+
+	details, err := client.VulnerabilityDetails(context.Background(), "containerd", "GHSA-259w-8hf6-59c2")
+	require.NoError(t, err)
+
+	assert.Equal(t, "GHSA-259w-8hf6-59c2", details.ID)
+	assert.Equal(t, "https://github.com/advisories/GHSA-259w-8hf6-59c2", details.URL)
+	assert.Contains(t, details.Description, "OCI image importer memory exhaustion")
+	assert.Equal(t, "MODERATE 5.5", details.Severity)
+	assert.Equal(t, []string{"CWE-400"}, details.CWEs)
+	assert.Len(t, details.References, 2)
+	assert.Equal(t, []vuln.VersionRange{
+		{VersionRangeUpper: "1.5.18"},
+		{VersionRangeLower: "1.6.0", VersionRangeLowerInclusive: true, VersionRangeUpper: "1.6.18"},
+	}, details.AffectedVersions, "only the versions of containerd, not of nerdctl")
+
+	_, err = client.VulnerabilityDetails(context.Background(), "containerd", "GHSA-aaaa-bbbb-cccc")
+	assert.ErrorContains(t, err, "GitHub has no advisory GHSA-aaaa-bbbb-cccc")
+}
+
+func TestClient_Aliases(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveAdvisories(t, w, r)
+	}))
+	defer ts.Close()
+
+	parsedURL, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	client := NewClient(ts.Client(), parsedURL.Host, "")
+	ctx := context.Background()
+
+	aliases, err := client.Aliases(ctx, "GHSA-259w-8hf6-59c2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CVE-2023-25153"}, aliases)
+
+	aliases, err = client.Aliases(ctx, "CVE-2023-25153")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GHSA-259w-8hf6-59c2"}, aliases)
+
+	aliases, err = client.Aliases(ctx, "GHSA-aaaa-bbbb-cccc")
+	require.NoError(t, err)
+	assert.Empty(t, aliases)
+
+	aliases, err = client.Aliases(ctx, "GO-2023-1573")
+	require.NoError(t, err)
+	assert.Empty(t, aliases)
+}
+
+// serveAdvisories serves the advisory of the testdata, by its GHSA and its CVE.
+func serveAdvisories(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	b, err := os.ReadFile("testdata/GHSA-259w-8hf6-59c2.json")
+	require.NoError(t, err)
+
+	switch {
+	case r.URL.Path == "/advisories/GHSA-259w-8hf6-59c2":
+	case r.URL.Path == "/advisories" && r.URL.Query().Get("cve_id") == "CVE-2023-25153":
+		b = append(append([]byte("["), b...), ']')
+	case r.URL.Path == "/advisories":
+		b = []byte("[]")
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	_, err = w.Write(b)
+	require.NoError(t, err)
+}
@@ -0,0 +1,147 @@
+package nvdapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/facebookincubator/nvdtools/wfn"
+)
+
+// HasCPEMapping returns true if vulnerabilities of the package are searched for
+// with a precise CPE of cpeMappingRules, rather than one made of its name that
+// matches the product of any vendor.
+func HasCPEMapping(packageName string) bool {
+	_, ok := cpeMappingRules[trimVersionSuffix(packageName)]
+	return ok
+}
+
+// trimVersionSuffix chops off any version suffixes from package names like
+// `clang-15` and `go-1.20`.
+func trimVersionSuffix(packageName string) string {
+	if matches := regexWithVersionSuffix.FindStringSubmatch(packageName); len(matches) >= 2 {
+		return matches[1]
+	}
+	return packageName
+}
+
+// SuggestCPEs searches the NVD CPE dictionary for candidate CPEs of a package:
+// the application CPEs of any vendor whose product is named like the package.
+// The candidates whose references are on the host of the package's homepage
+// come first, as they're most likely the package's. Versions are left out, they
+// come from the package.
+func (s *Detector) SuggestCPEs(ctx context.Context, packageName, homepage string) ([]wfn.Attributes, error) {
+	name := trimVersionSuffix(packageName)
+	products := []string{name}
+	if p := strings.ReplaceAll(name, "-", "_"); p != name {
+		// NVD names products with underscores more often than with dashes.
+		products = append(products, p)
+	}
+
+	homepageHost := urlHost(homepage)
+
+	type candidate struct {
+		cpe          wfn.Attributes
+		fromHomepage bool
+	}
+	var candidates []candidate
+	seen := make(map[string]int)
+
+	for _, product := range products {
+		product, err := wfn.WFNize(product)
+		if err != nil {
+			return nil, fmt.Errorf("unable to make a CPE product of %q: %w", name, err)
+		}
+		match := wfn.Attributes{Part: "a", Product: product}
+
+		reqURL := fmt.Sprintf(
+			"https://%s%s?cpeMatchString=%s",
+			s.serviceHost,
+			CPEsEndpoint,
+			url.QueryEscape(match.BindToFmtString()),
+		)
+
+		cpesResponse, err := s.queryCPEs(ctx, reqURL)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range cpesResponse.Products {
+			if p.Cpe.Deprecated {
+				continue
+			}
+			cpe, err := wfn.Parse(p.Cpe.CpeName)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse CPE %q: %w", p.Cpe.CpeName, err)
+			}
+
+			fromHomepage := false
+			for _, ref := range p.Cpe.Refs {
+				if homepageHost != "" && urlHost(ref.Ref) == homepageHost {
+					fromHomepage = true
+					break
+				}
+			}
+
+			key := cpe.Vendor + ":" + cpe.Product
+			if i, ok := seen[key]; ok {
+				// The dictionary has a CPE per version of a product.
+				candidates[i].fromHomepage = candidates[i].fromHomepage || fromHomepage
+				continue
+			}
+			seen[key] = len(candidates)
+			candidates = append(candidates, candidate{
+				cpe:          wfn.Attributes{Part: "a", Vendor: cpe.Vendor, Product: cpe.Product},
+				fromHomepage: fromHomepage,
+			})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].fromHomepage && !candidates[j].fromHomepage
+	})
+
+	cpes := make([]wfn.Attributes, 0, len(candidates))
+	for _, c := range candidates {
+		cpes = append(cpes, c.cpe)
+	}
+	return cpes, nil
+}
+
+type cpeQuery struct {
+	once     sync.Once
+	response CPEsResponse
+	err      error
+}
+
+// queryCPEs queries the CPE dictionary once per URL, for all the packages whose CPEs are suggested.
+func (s *Detector) queryCPEs(ctx context.Context, reqURL string) (*CPEsResponse, error) {
+	s.cpeQueriesMu.Lock()
+	if s.cpeQueries == nil {
+		s.cpeQueries = make(map[string]*cpeQuery)
+	}
+	q, ok := s.cpeQueries[reqURL]
+	if !ok {
+		q = &cpeQuery{}
+		s.cpeQueries[reqURL] = q
+	}
+	s.cpeQueriesMu.Unlock()
+
+	q.once.Do(func() {
+		q.err = s.get(ctx, reqURL, &q.response)
+	})
+	return &q.response, q.err
+}
+
+// urlHost returns the host of a URL without its www. prefix, or "" if it isn't a
+// URL.
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
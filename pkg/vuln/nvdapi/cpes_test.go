@@ -0,0 +1,57 @@
+package nvdapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/facebookincubator/nvdtools/wfn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetector_SuggestCPEs(t *testing.T) {
+	var matchStrings []string
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, CPEsEndpoint, r.URL.Path)
+		matchStrings = append(matchStrings, r.URL.Query().Get("cpeMatchString"))
+
+		f, err := os.Open("testdata/cpes-libexpat.json")
+		require.NoError(t, err)
+		defer f.Close()
+
+		_, err = io.Copy(w, f)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	parsedURL, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	detector := NewDetector(ts.Client(), parsedURL.Host, "some-api-key")
+
+	cpes, err := detector.SuggestCPEs(context.Background(), "libexpat", "https://www.libexpat.github.io")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"cpe:2.3:a:*:libexpat:*:*:*:*:*:*:*:*"}, matchStrings)
+	// The candidate of the homepage comes first, deprecated CPEs are left out.
+	assert.Equal(t, []wfn.Attributes{
+		{Part: "a", Vendor: "libexpat_project", Product: "libexpat"},
+		{Part: "a", Vendor: "example", Product: "libexpat"},
+	}, cpes)
+
+	// the packages of a product share the query of the dictionary
+	_, err = detector.SuggestCPEs(context.Background(), "libexpat-2", "")
+	require.NoError(t, err)
+	assert.Len(t, matchStrings, 1)
+}
+
+func TestHasCPEMapping(t *testing.T) {
+	assert.True(t, HasCPEMapping("curl"))
+	assert.True(t, HasCPEMapping("git"))
+	assert.False(t, HasCPEMapping("libexpat"))
+}
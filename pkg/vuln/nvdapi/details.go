@@ -0,0 +1,103 @@
+package nvdapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+	"golang.org/x/exp/slices"
+)
+
+// VulnerabilityDetails returns what NVD knows about a CVE affecting a package: its description, its severity, its CWEs
+// and references, and the versions of the package it affects, according to the CPE of the package.
+func (s *Detector) VulnerabilityDetails(ctx context.Context, packageName, id string) (*vuln.Details, error) {
+	reqURL := fmt.Sprintf(
+		"https://%s%s?cveId=%s",
+		s.serviceHost,
+		s.serviceEndpoint,
+		url.QueryEscape(id),
+	)
+
+	var cvesResponse CVEsResponse
+	if err := s.get(ctx, reqURL, &cvesResponse); err != nil {
+		return nil, err
+	}
+	if len(cvesResponse.Vulnerabilities) == 0 {
+		return nil, fmt.Errorf("NVD has no CVE %s", id)
+	}
+	cve := cvesResponse.Vulnerabilities[0].Cve
+
+	details := &vuln.Details{
+		ID:       cve.ID,
+		URL:      fmt.Sprintf("https://nvd.nist.gov/vuln/detail/%s", cve.ID),
+		Severity: severity(cve.Metrics),
+	}
+	for _, d := range cve.Descriptions {
+		if d.Lang == "en" {
+			details.Description = d.Value
+			break
+		}
+	}
+	for _, w := range cve.Weaknesses {
+		for _, d := range w.Description {
+			// NVD-CWE-Other and NVD-CWE-noinfo say there's no CWE for it
+			if strings.HasPrefix(d.Value, "CWE-") && !slices.Contains(details.CWEs, d.Value) {
+				details.CWEs = append(details.CWEs, d.Value)
+			}
+		}
+	}
+	for _, r := range cve.References {
+		details.References = append(details.References, r.URL)
+	}
+
+	requestCPE := s.getCPE(packageName)
+	for _, configuration := range cve.Configurations {
+		// as in determineVulnMatch, AND-ed nodes are about the platforms the software runs on
+		if configuration.Operator == "AND" {
+			continue
+		}
+		for _, node := range configuration.Nodes {
+			if node.Negate {
+				continue
+			}
+			for _, cpeMatch := range node.CpeMatch {
+				if !cpeMatch.Vulnerable {
+					continue
+				}
+				match, err := cpeStringsMatch(requestCPE, cpeMatch.Criteria)
+				if err != nil {
+					return nil, err
+				}
+				if !match {
+					continue
+				}
+				vr, err := convertCpeMatchToVersionRange(cpeMatch)
+				if err != nil {
+					if errors.Is(err, errNoVersionData) {
+						continue
+					}
+					return nil, err
+				}
+				details.AffectedVersions = append(details.AffectedVersions, vr)
+			}
+		}
+	}
+
+	return details, nil
+}
+
+// severity returns the base severity and score of the most recent CVSS version NVD scored a CVE with.
+func severity(m Metrics) string {
+	switch {
+	case len(m.CvssMetricV31) > 0:
+		return fmt.Sprintf("%s %.1f", m.CvssMetricV31[0].CvssData.BaseSeverity, m.CvssMetricV31[0].CvssData.BaseScore)
+	case len(m.CvssMetricV30) > 0:
+		return fmt.Sprintf("%s %.1f", m.CvssMetricV30[0].CvssData.BaseSeverity, m.CvssMetricV30[0].CvssData.BaseScore)
+	case len(m.CvssMetricV2) > 0:
+		return fmt.Sprintf("%s %.1f", m.CvssMetricV2[0].BaseSeverity, m.CvssMetricV2[0].CvssData.BaseScore)
+	}
+	return ""
+}
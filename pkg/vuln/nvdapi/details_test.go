@@ -0,0 +1,46 @@
+package nvdapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+)
+
+func TestDetector_VulnerabilityDetails(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "CVE-2020-8927", r.URL.Query().Get("cveId"))
+
+		f, err := os.Open("testdata/brotli.json")
+		require.NoError(t, err)
+		defer f.Close()
+
+		_, err = io.Copy(w, f)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	parsedURL, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	detector := NewDetector(ts.Client(), parsedURL.Host, "some-api-key")
+
+	details, err := detector.VulnerabilityDetails(context.Background(), "brotli", "CVE-2020-8927")
+	require.NoError(t, err)
+
+	assert.Equal(t, "CVE-2020-8927", details.ID)
+	assert.Equal(t, "https://nvd.nist.gov/vuln/detail/CVE-2020-8927", details.URL)
+	assert.Contains(t, details.Description, "A buffer overflow exists in the Brotli library")
+	assert.Equal(t, "MEDIUM 6.5", details.Severity)
+	assert.Equal(t, []vuln.VersionRange{{VersionRangeUpper: "1.0.8"}}, details.AffectedVersions, "only the versions of brotli, not of the distros shipping it")
+	assert.Equal(t, "< 1.0.8", details.AffectedVersions[0].String())
+	assert.Equal(t, []string{"CWE-120", "CWE-130"}, details.CWEs)
+	assert.Contains(t, details.References, "https://github.com/google/brotli/releases/tag/v1.0.9")
+}
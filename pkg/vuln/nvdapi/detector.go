@@ -28,6 +28,11 @@ type Detector struct {
 	serviceHost     string
 	serviceEndpoint string
 	packageToCPE    packageToCPE
+
+	// cpeQueries are the queries of the CPE dictionary by URL, shared by the packages of a product, like go-1.20 and
+	// go-1.21, whose CPEs are suggested concurrently.
+	cpeQueriesMu sync.Mutex
+	cpeQueries   map[string]*cpeQuery
 }
 
 func NewDetector(client *http.Client, serviceHost, apiKey string) *Detector {
@@ -51,6 +56,7 @@ func NewDetector(client *http.Client, serviceHost, apiKey string) *Detector {
 const (
 	DefaultHost  = "services.nvd.nist.gov"
 	CVEsEndpoint = "/rest/json/cves/2.0"
+	CPEsEndpoint = "/rest/json/cpes/2.0"
 )
 
 var (
@@ -231,11 +237,6 @@ func cpeStringsMatch(requestCPE, responseCPE string) (bool, error) {
 var ErrRateLimited = errors.New("we've been rate limited by NVD! 🙊")
 
 func (s *Detector) doSearch(ctx context.Context, cpe string) ([]Cve, error) {
-	err := s.rateLimiter.Wait(ctx)
-	if err != nil {
-		return nil, err
-	}
-
 	// TODO: Deal with pages (not urgent because the default page size is 2,000
 	//  CVEs, and we're searching for single packages at a time.)
 
@@ -251,9 +252,26 @@ func (s *Detector) doSearch(ctx context.Context, cpe string) ([]Cve, error) {
 		cpe,
 	)
 
+	var cvesResponse CVEsResponse
+	if err := s.get(ctx, reqURL, &cvesResponse); err != nil {
+		return nil, err
+	}
+
+	cves := lo.Map(cvesResponse.Vulnerabilities, vulnerabilityToCve)
+
+	return cves, nil
+}
+
+// get sends a request to the NVD API, within its rate limits, and decodes the JSON response into v.
+func (s *Detector) get(ctx context.Context, reqURL string, v any) error {
+	err := s.rateLimiter.Wait(ctx)
+	if err != nil {
+		return err
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("unable to create request with URL %q: %w", reqURL, err)
+		return fmt.Errorf("unable to create request with URL %q: %w", reqURL, err)
 	}
 
 	req.Header["Accept"] = []string{"application/json"}
@@ -266,26 +284,23 @@ func (s *Detector) doSearch(ctx context.Context, cpe string) ([]Cve, error) {
 	log.Printf("☎️  sending API request: %s", reqURL)
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("unable to complete request to URL %q: %w", reqURL, err)
+		return fmt.Errorf("unable to complete request to URL %q: %w", reqURL, err)
 	}
+	defer resp.Body.Close()
+
 	if s := resp.StatusCode; s != http.StatusOK {
 		if s == http.StatusForbidden || s == http.StatusTooManyRequests {
-			return nil, ErrRateLimited
+			return ErrRateLimited
 		}
 
-		return nil, fmt.Errorf("got unexpected response status %d for request to %q. Headers: %+v", s, reqURL, resp.Header)
+		return fmt.Errorf("got unexpected response status %d for request to %q. Headers: %+v", s, reqURL, resp.Header)
 	}
 
-	defer resp.Body.Close()
-
-	var cvesResponse CVEsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&cvesResponse); err != nil {
-		return nil, fmt.Errorf("unable to decode JSON response to URL %q: %w", reqURL, err)
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("unable to decode JSON response to URL %q: %w", reqURL, err)
 	}
 
-	cves := lo.Map(cvesResponse.Vulnerabilities, vulnerabilityToCve)
-
-	return cves, nil
+	return nil
 }
 
 var errNoVersionData = errors.New("CPE has no version data available")
@@ -329,10 +344,7 @@ func convertCpeMatchToVersionRange(m CpeMatch) (vuln.VersionRange, error) {
 }
 
 func (s *Detector) getCPE(packageName string) string {
-	// Chop off any version suffixes from package names like `clang-15` and `go-1.20`.
-	if matches := regexWithVersionSuffix.FindStringSubmatch(packageName); len(matches) >= 2 {
-		packageName = matches[1]
-	}
+	packageName = trimVersionSuffix(packageName)
 
 	// Use a more precise CPE, if we have one. Otherwise, just create a CPE using
 	// the package name as the 'product'.
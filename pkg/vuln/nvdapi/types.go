@@ -124,6 +124,34 @@ type CpeMatch struct {
 	VersionStartIncluding string `json:"versionStartIncluding,omitempty"`
 }
 
+type CPEsResponse struct {
+	ResultsPerPage int       `json:"resultsPerPage"`
+	StartIndex     int       `json:"startIndex"`
+	TotalResults   int       `json:"totalResults"`
+	Format         string    `json:"format"`
+	Version        string    `json:"version"`
+	Timestamp      string    `json:"timestamp"`
+	Products       []Product `json:"products"`
+}
+
+type Product struct {
+	Cpe Cpe `json:"cpe"`
+}
+
+type Cpe struct {
+	Deprecated bool   `json:"deprecated"`
+	CpeName    string `json:"cpeName"`
+	CpeNameID  string `json:"cpeNameId"`
+	Titles     []struct {
+		Title string `json:"title"`
+		Lang  string `json:"lang"`
+	} `json:"titles"`
+	Refs []struct {
+		Ref  string `json:"ref"`
+		Type string `json:"type,omitempty"`
+	} `json:"refs"`
+}
+
 func vulnerabilityToCve(v Vulnerability, _ int) Cve {
 	return v.Cve
 }
@@ -0,0 +1,363 @@
+package osv
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultDBURL is where OSV.dev publishes the records of every ecosystem, as <ecosystem>/all.zip.
+const DefaultDBURL = "https://osv-vulnerabilities.storage.googleapis.com"
+
+// dbMetadataFile is the file of a database directory that has its metadata, next to a <ecosystem>.zip per ecosystem.
+const dbMetadataFile = "metadata.json"
+
+// DefaultDBDir returns the directory the database is kept in by default, in the cache directory of the user.
+func DefaultDBDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "wolfictl", "osv-db"), nil
+}
+
+// DBMetadata describes a snapshot of the records of ecosystems.
+type DBMetadata struct {
+	// Downloaded is when the snapshot was taken.
+	Downloaded time.Time `json:"downloaded"`
+	// Source is the URL the records were downloaded from.
+	Source     string        `json:"source"`
+	Ecosystems []DBEcosystem `json:"ecosystems"`
+}
+
+// DBEcosystem is the archive of the records of an ecosystem.
+type DBEcosystem struct {
+	Name string `json:"name"`
+	// SHA256 is the digest of the archive, to check its integrity by.
+	SHA256  string `json:"sha256"`
+	Records int    `json:"records"`
+}
+
+// Version identifies the snapshot by the digests of its archives, to pin scans to it.
+func (m *DBMetadata) Version() string {
+	h := sha256.New()
+	for _, e := range m.Ecosystems {
+		fmt.Fprintf(h, "%s %s\n", e.Name, e.SHA256)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// Age returns how long ago the snapshot was taken.
+func (m *DBMetadata) Age(now time.Time) time.Duration {
+	return now.Sub(m.Downloaded)
+}
+
+// DownloadDB downloads the records of the ecosystems from baseURL to the database directory, replacing what it had
+// only once all of them are downloaded.
+func DownloadDB(ctx context.Context, client *http.Client, baseURL, dir string, ecosystems []string) (*DBMetadata, error) {
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), ".osv-db-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	m := &DBMetadata{Downloaded: time.Now().UTC(), Source: baseURL}
+	for _, ecosystem := range ecosystems {
+		e, err := downloadEcosystem(ctx, client, baseURL, tmp, ecosystem)
+		if err != nil {
+			return nil, err
+		}
+		m.Ecosystems = append(m.Ecosystems, *e)
+	}
+	if err := writeDBMetadata(tmp, m); err != nil {
+		return nil, err
+	}
+	if err := replaceDir(tmp, dir); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func downloadEcosystem(ctx context.Context, client *http.Client, baseURL, dir, ecosystem string) (*DBEcosystem, error) {
+	name, err := archiveName(ecosystem)
+	if err != nil {
+		return nil, err
+	}
+
+	reqURL := fmt.Sprintf("%s/%s/all.zip", strings.TrimSuffix(baseURL, "/"), url.PathEscape(ecosystem))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request with URL %q: %w", reqURL, err)
+	}
+	req.Header.Set("User-Agent", "wolfictl")
+
+	log.Printf("downloading the OSV records of %s: %s", ecosystem, reqURL)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to complete request to URL %q: %w", reqURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got unexpected response status %d for request to %q", resp.StatusCode, reqURL)
+	}
+
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), resp.Body); err != nil {
+		return nil, fmt.Errorf("unable to download %q: %w", reqURL, err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	records, err := readArchive(path, func(*Vulnerability) {})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the records of %s: %w", ecosystem, err)
+	}
+	return &DBEcosystem{Name: ecosystem, SHA256: hex.EncodeToString(h.Sum(nil)), Records: records}, nil
+}
+
+// archiveName returns the name of the file the records of the ecosystem are archived in. Ecosystem names come from
+// flags and from the metadata of databases being imported, so names that would escape the database directory are
+// rejected.
+func archiveName(ecosystem string) (string, error) {
+	if ecosystem == "" || ecosystem == "." || strings.Contains(ecosystem, "..") || strings.ContainsAny(ecosystem, `/\`) {
+		return "", fmt.Errorf("invalid ecosystem name %q", ecosystem)
+	}
+	return ecosystem + ".zip", nil
+}
+
+// ReadDBMetadata returns the metadata of the database directory, without checking its archives.
+func ReadDBMetadata(dir string) (*DBMetadata, error) {
+	b, err := os.ReadFile(filepath.Join(dir, dbMetadataFile))
+	if err != nil {
+		return nil, err
+	}
+	var m DBMetadata
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("unable to read the metadata of the database %s: %w", dir, err)
+	}
+	for _, e := range m.Ecosystems {
+		if _, err := archiveName(e.Name); err != nil {
+			return nil, fmt.Errorf("unable to read the metadata of the database %s: %w", dir, err)
+		}
+	}
+	return &m, nil
+}
+
+func writeDBMetadata(dir string, m *DBMetadata) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, dbMetadataFile), append(b, '\n'), 0o644) //nolint:gosec // the records are public
+}
+
+// VerifyDB checks that the archives of the database directory are the ones its metadata has the digests of, and
+// returns the metadata.
+func VerifyDB(dir string) (*DBMetadata, error) {
+	m, err := ReadDBMetadata(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range m.Ecosystems {
+		name, err := archiveName(e.Name)
+		if err != nil {
+			return nil, err
+		}
+		digest, err := sha256File(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if digest != e.SHA256 {
+			return nil, fmt.Errorf("the archive of %s in the database %s has digest %s, expected %s", e.Name, dir, digest, e.SHA256)
+		}
+	}
+	return m, nil
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ImportDB verifies the database directory src, e.g. one downloaded on another host and copied over to an
+// air-gapped one, and copies it to the database directory dir, replacing what it had.
+func ImportDB(src, dir string) (*DBMetadata, error) {
+	m, err := VerifyDB(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), ".osv-db-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	names := []string{dbMetadataFile}
+	for _, e := range m.Ecosystems {
+		name, err := archiveName(e.Name)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	for _, name := range names {
+		if err := copyFile(filepath.Join(src, name), filepath.Join(tmp, name)); err != nil {
+			return nil, err
+		}
+	}
+	if err := replaceDir(tmp, dir); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// replaceDir moves the directory tmp to dir, in place of what dir had.
+func replaceDir(tmp, dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dir)
+}
+
+// DB is a snapshot of the records of ecosystems, to match packages against without the OSV.dev API, e.g. in
+// air-gapped builds.
+type DB struct {
+	Metadata *DBMetadata
+
+	records   map[string]*Vulnerability
+	byPackage map[Package][]*Vulnerability
+}
+
+// OpenDB verifies the database directory and reads its records.
+func OpenDB(dir string) (*DB, error) {
+	m, err := VerifyDB(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &DB{
+		Metadata:  m,
+		records:   make(map[string]*Vulnerability),
+		byPackage: make(map[Package][]*Vulnerability),
+	}
+	for _, e := range m.Ecosystems {
+		name, err := archiveName(e.Name)
+		if err != nil {
+			return nil, err
+		}
+		_, err = readArchive(filepath.Join(dir, name), func(v *Vulnerability) {
+			db.records[v.ID] = v
+			seen := make(map[Package]bool)
+			for _, a := range v.Affected {
+				if !seen[a.Package] {
+					seen[a.Package] = true
+					db.byPackage[a.Package] = append(db.byPackage[a.Package], v)
+				}
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the records of %s: %w", e.Name, err)
+		}
+	}
+	return db, nil
+}
+
+// readArchive calls fn with every record of an archive of records, and returns how many there are.
+func readArchive(path string, fn func(*Vulnerability)) (int, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return 0, err
+	}
+	defer zr.Close()
+
+	records := 0
+	for _, f := range zr.File {
+		if !strings.HasSuffix(f.Name, ".json") {
+			continue
+		}
+		v, err := readRecord(f)
+		if err != nil {
+			return 0, fmt.Errorf("unable to read %s: %w", f.Name, err)
+		}
+		fn(v)
+		records++
+	}
+	return records, nil
+}
+
+func readRecord(f *zip.File) (*Vulnerability, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var v Vulnerability
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return nil, err
+	}
+	if v.ID == "" {
+		return nil, errors.New("record has no ID")
+	}
+	return &v, nil
+}
+
+// Get returns the record of a vulnerability, or nil if the database doesn't have it.
+func (db *DB) Get(_ context.Context, id string) (*Vulnerability, error) {
+	return db.records[id], nil
+}
+
+// Vulnerabilities returns the records of the vulnerabilities affecting the package in some version.
+func (db *DB) Vulnerabilities(pkg Package) []*Vulnerability {
+	return db.byPackage[pkg]
+}
@@ -0,0 +1,94 @@
+package osv
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func zipOf(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestDB(t *testing.T) {
+	archives := map[string][]byte{
+		"/Go/all.zip": zipOf(t, map[string]string{
+			"GO-2023-1704.json": `{"id": "GO-2023-1704", "aliases": ["GHSA-v4m2-x4rp-hv22"], "affected": [
+				{"package": {"name": "stdlib", "ecosystem": "Go"}, "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.20.4"}]}]}]}`,
+		}),
+		"/Wolfi/all.zip": zipOf(t, map[string]string{
+			"CGA-0001.json": `{"id": "CGA-0001", "affected": [{"package": {"name": "curl", "ecosystem": "Wolfi"}, "versions": ["8.1.0-r0"]}]}`,
+		}),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := archives[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(b)
+	}))
+	defer ts.Close()
+
+	dir := filepath.Join(t.TempDir(), "osv-db")
+	m, err := DownloadDB(context.Background(), ts.Client(), ts.URL, dir, []string{"Go", "Wolfi"})
+	require.NoError(t, err)
+	require.Len(t, m.Ecosystems, 2)
+	assert.Equal(t, 1, m.Ecosystems[0].Records)
+	assert.Len(t, m.Version(), 12)
+	assert.Less(t, m.Age(time.Now()), time.Minute)
+
+	db, err := OpenDB(dir)
+	require.NoError(t, err)
+	assert.Equal(t, m.Version(), db.Metadata.Version())
+	v, err := db.Get(context.Background(), "GO-2023-1704")
+	require.NoError(t, err)
+	require.NotNil(t, v)
+	assert.Equal(t, []string{"GHSA-v4m2-x4rp-hv22"}, v.Aliases)
+	assert.Len(t, db.Vulnerabilities(Package{Name: "curl", Ecosystem: "Wolfi"}), 1)
+	assert.Empty(t, db.Vulnerabilities(Package{Name: "curl", Ecosystem: "Go"}))
+
+	imported := filepath.Join(t.TempDir(), "imported")
+	im, err := ImportDB(dir, imported)
+	require.NoError(t, err)
+	assert.Equal(t, m.Version(), im.Version())
+	_, err = OpenDB(imported)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(imported, "Go.zip"), archives["/Wolfi/all.zip"], 0o600))
+	_, err = OpenDB(imported)
+	assert.ErrorContains(t, err, "digest", "tampered archives are refused")
+
+	_, err = DownloadDB(context.Background(), ts.Client(), ts.URL, dir, []string{"PyPI"})
+	assert.Error(t, err)
+	_, err = OpenDB(dir)
+	assert.NoError(t, err, "a failed download leaves the database as it was")
+
+	b, err := os.ReadFile(filepath.Join(imported, dbMetadataFile))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(imported, dbMetadataFile), []byte(strings.Replace(string(b), `"Go"`, `"../Go"`, 1)), 0o600))
+	_, err = ImportDB(imported, filepath.Join(t.TempDir(), "escaped"))
+	assert.ErrorContains(t, err, "invalid ecosystem name", "archives outside the database are never read")
+	_, err = DownloadDB(context.Background(), ts.Client(), ts.URL, dir, []string{"../Go"})
+	assert.ErrorContains(t, err, "invalid ecosystem name")
+}
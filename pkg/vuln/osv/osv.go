@@ -0,0 +1,330 @@
+package osv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/mod/semver"
+
+	"github.com/wolfi-dev/wolfictl/pkg/vuln"
+)
+
+// DefaultHost is the host of the OSV.dev API.
+const DefaultHost = "api.osv.dev"
+
+var _ vuln.AliasFinder = (*Client)(nil)
+
+// Client looks up vulnerabilities with the OSV.dev API, which knows of the IDs of many databases: CVEs, GHSAs, Go
+// vulnerabilities (GO-) and more.
+type Client struct {
+	client      *http.Client
+	serviceHost string
+}
+
+// NewClient returns a Client sending its requests to serviceHost.
+func NewClient(client *http.Client, serviceHost string) *Client {
+	return &Client{
+		client:      client,
+		serviceHost: serviceHost,
+	}
+}
+
+// Vulnerability is the part of an OSV record about the IDs of a vulnerability, how severe it is and the versions of
+// packages it affects, https://ossf.github.io/osv-schema/.
+type Vulnerability struct {
+	ID       string     `json:"id"`
+	Summary  string     `json:"summary,omitempty"`
+	Aliases  []string   `json:"aliases"`
+	Affected []Affected `json:"affected,omitempty"`
+
+	DatabaseSpecific struct {
+		// Severity is the severity GitHub rates GHSAs with, e.g. "MODERATE".
+		Severity string `json:"severity,omitempty"`
+	} `json:"database_specific"`
+}
+
+// Affected is a package a vulnerability affects, in the versions of the ranges and the ones listed.
+type Affected struct {
+	Package  Package  `json:"package"`
+	Ranges   []Range  `json:"ranges,omitempty"`
+	Versions []string `json:"versions,omitempty"`
+}
+
+// Range is a range of versions, with events like {"introduced": "0"} or {"fixed": "1.2.3"} at its bounds.
+type Range struct {
+	Type   string              `json:"type"`
+	Events []map[string]string `json:"events"`
+}
+
+// FixedVersions returns the versions of the package that fix the vulnerability. Ranges of git commits are ignored.
+func (v Vulnerability) FixedVersions(pkg Package) []string {
+	var fixed []string
+	for _, a := range v.Affected {
+		if a.Package != pkg {
+			continue
+		}
+		for _, r := range a.Ranges {
+			if r.Type == "GIT" {
+				continue
+			}
+			for _, e := range r.Events {
+				if f, ok := e["fixed"]; ok {
+					fixed = append(fixed, f)
+				}
+			}
+		}
+	}
+	return fixed
+}
+
+// Affects reports whether the vulnerability affects the version of the package. Versions of SEMVER ranges are compared
+// as semantic versions, and the ones of ECOSYSTEM ranges with compare, which orders the versions of the ecosystem.
+// ECOSYSTEM ranges are ignored when compare is nil, leaving only the versions the record lists, and ranges of git
+// commits are always ignored.
+func (v Vulnerability) Affects(pkg Package, version string, compare func(a, b string) int) bool {
+	for _, a := range v.Affected {
+		if a.Package != pkg {
+			continue
+		}
+		if slices.Contains(a.Versions, version) {
+			return true
+		}
+		for _, r := range a.Ranges {
+			switch r.Type {
+			case "SEMVER":
+				if r.contains(version, func(a, b string) int { return semver.Compare("v"+a, "v"+b) }) {
+					return true
+				}
+			case "ECOSYSTEM":
+				if compare != nil && r.contains(version, compare) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// contains reports whether the version is in the range, going through its events from the lowest version up: every
+// introduction at or below the version opens the range, and every fix at or below it, or last affected version below
+// it, closes it again.
+func (r Range) contains(version string, compare func(a, b string) int) bool {
+	type event struct {
+		kind, version string
+	}
+	var events []event
+	for _, e := range r.Events {
+		for kind, v := range e {
+			events = append(events, event{kind, v})
+		}
+	}
+	less := func(a, b string) bool {
+		// "0" is below every version
+		if a == "0" || b == "0" {
+			return a == "0" && b != "0"
+		}
+		return compare(a, b) < 0
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return less(events[i].version, events[j].version)
+	})
+
+	affected := false
+	for _, e := range events {
+		switch e.kind {
+		case "introduced":
+			if !less(version, e.version) {
+				affected = true
+			}
+		case "fixed":
+			if !less(version, e.version) {
+				affected = false
+			}
+		case "last_affected":
+			if less(e.version, version) {
+				affected = false
+			}
+		}
+	}
+	return affected
+}
+
+// Get returns the OSV record of a vulnerability, or nil if OSV.dev doesn't know of the vulnerability.
+func (c *Client) Get(ctx context.Context, id string) (*Vulnerability, error) {
+	reqURL := fmt.Sprintf("https://%s/v1/vulns/%s", c.serviceHost, url.PathEscape(id))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request with URL %q: %w", reqURL, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "wolfictl")
+
+	log.Printf("☎️  sending API request: %s", reqURL)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to complete request to URL %q: %w", reqURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("got unexpected response status %d for request to %q", resp.StatusCode, reqURL)
+	}
+
+	var v Vulnerability
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return nil, fmt.Errorf("unable to decode JSON response to URL %q: %w", reqURL, err)
+	}
+	return &v, nil
+}
+
+// Aliases returns the aliases OSV.dev knows of a vulnerability, none if it doesn't know of the vulnerability.
+func (c *Client) Aliases(ctx context.Context, id string) ([]string, error) {
+	v, err := c.Get(ctx, id)
+	if err != nil || v == nil {
+		return nil, err
+	}
+
+	aliases := v.Aliases
+	if v.ID != id {
+		// the vulnerability is known by another ID, e.g. the GHSA of a Go vulnerability
+		aliases = append(aliases, v.ID)
+	}
+	return aliases, nil
+}
+
+// Package is a package of an ecosystem, e.g. the PyPI package requests.
+type Package struct {
+	Name      string `json:"name"`
+	Ecosystem string `json:"ecosystem"`
+}
+
+// Query asks for the vulnerabilities of a version of a package.
+type Query struct {
+	Package Package `json:"package"`
+	Version string  `json:"version"`
+	// PageToken asks for the next page of the vulnerabilities of a query OSV.dev paginated the results of.
+	PageToken string `json:"page_token,omitempty"`
+}
+
+// maxBatchSize is the most queries the OSV.dev API answers in one batch.
+const maxBatchSize = 1000
+
+// QueryBatch returns the IDs of the vulnerabilities affecting each of the queried package versions, in the order of
+// the queries.
+func (c *Client) QueryBatch(ctx context.Context, queries []Query) ([][]string, error) {
+	results := make([][]string, 0, len(queries))
+	for start := 0; start < len(queries); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(queries) {
+			end = len(queries)
+		}
+		batch, err := c.queryPages(ctx, queries[start:end])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, batch...)
+	}
+	return results, nil
+}
+
+// queryPages queries a batch, then queries the next pages of the results OSV.dev paginated, i.e. ones with a
+// next_page_token, until it has all of them.
+func (c *Client) queryPages(ctx context.Context, queries []Query) ([][]string, error) {
+	queries = append([]Query{}, queries...)
+	results := make([][]string, len(queries))
+	pending := make([]int, len(queries))
+	for i := range pending {
+		pending[i] = i
+	}
+	for len(pending) > 0 {
+		batch := make([]Query, 0, len(pending))
+		for _, i := range pending {
+			batch = append(batch, queries[i])
+		}
+		page, err := c.queryBatch(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+
+		var next []int
+		for j, r := range page {
+			i := pending[j]
+			results[i] = append(results[i], r.ids...)
+			if r.nextPageToken != "" {
+				queries[i].PageToken = r.nextPageToken
+				next = append(next, i)
+			}
+		}
+		pending = next
+	}
+	return results, nil
+}
+
+// batchResult is the page of the vulnerabilities of a query of a batch.
+type batchResult struct {
+	ids           []string
+	nextPageToken string
+}
+
+func (c *Client) queryBatch(ctx context.Context, queries []Query) ([]batchResult, error) {
+	reqURL := fmt.Sprintf("https://%s/v1/querybatch", c.serviceHost)
+
+	body, err := json.Marshal(struct {
+		Queries []Query `json:"queries"`
+	}{queries})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request with URL %q: %w", reqURL, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "wolfictl")
+
+	log.Printf("☎️  sending API request: %s (%d queries)", reqURL, len(queries))
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to complete request to URL %q: %w", reqURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got unexpected response status %d for request to %q", resp.StatusCode, reqURL)
+	}
+
+	var batch struct {
+		Results []struct {
+			Vulns         []Vulnerability `json:"vulns"`
+			NextPageToken string          `json:"next_page_token"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		return nil, fmt.Errorf("unable to decode JSON response to URL %q: %w", reqURL, err)
+	}
+	if len(batch.Results) != len(queries) {
+		return nil, fmt.Errorf("got %d results for %d queries to %q", len(batch.Results), len(queries), reqURL)
+	}
+
+	results := make([]batchResult, 0, len(queries))
+	for _, r := range batch.Results {
+		res := batchResult{nextPageToken: r.NextPageToken}
+		for _, v := range r.Vulns {
+			res.ids = append(res.ids, v.ID)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
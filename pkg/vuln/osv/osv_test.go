@@ -0,0 +1,183 @@
+package osv
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Aliases(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/vulns/GO-2023-1573" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		f, err := os.Open("testdata/GO-2023-1573.json")
+		require.NoError(t, err)
+		defer f.Close()
+
+		_, err = io.Copy(w, f)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	parsedURL, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	client := NewClient(ts.Client(), parsedURL.Host)
+
+	aliases, err := client.Aliases(context.Background(), "GO-2023-1573")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CVE-2023-25153", "GHSA-259w-8hf6-59c2"}, aliases)
+
+	aliases, err = client.Aliases(context.Background(), "CVE-2099-0001")
+	require.NoError(t, err)
+	assert.Empty(t, aliases, "unknown vulnerabilities have no aliases")
+}
+
+func TestClient_Get(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/vulns/GHSA-j8r2-6x86-q33q" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		http.ServeFile(w, r, "testdata/GHSA-j8r2-6x86-q33q.json")
+	}))
+	defer ts.Close()
+
+	parsedURL, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	client := NewClient(ts.Client(), parsedURL.Host)
+
+	v, err := client.Get(context.Background(), "GHSA-j8r2-6x86-q33q")
+	require.NoError(t, err)
+	require.NotNil(t, v)
+	assert.Equal(t, "MODERATE", v.DatabaseSpecific.Severity)
+	assert.Equal(t, []string{"2.31.0"}, v.FixedVersions(Package{Name: "requests", Ecosystem: "PyPI"}))
+	assert.Empty(t, v.FixedVersions(Package{Name: "requests", Ecosystem: "npm"}), "fixes are of the package in its ecosystem")
+
+	v, err = client.Get(context.Background(), "CVE-2099-0001")
+	require.NoError(t, err)
+	assert.Nil(t, v, "unknown vulnerabilities have no record")
+}
+
+func TestClient_QueryBatch(t *testing.T) {
+	vulnerable := map[Query][]string{
+		{Package: Package{Name: "stdlib", Ecosystem: "Go"}, Version: "1.20.1"}:     {"GO-2023-1621", "GO-2023-1704"},
+		{Package: Package{Name: "requests", Ecosystem: "PyPI"}, Version: "2.30.0"}: {"GHSA-j8r2-6x86-q33q"},
+	}
+	requests := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/querybatch" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		requests++
+
+		var batch struct {
+			Queries []Query `json:"queries"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+		assert.LessOrEqual(t, len(batch.Queries), maxBatchSize)
+
+		type result struct {
+			Vulns         []Vulnerability `json:"vulns,omitempty"`
+			NextPageToken string          `json:"next_page_token,omitempty"`
+		}
+		results := make([]result, 0, len(batch.Queries))
+		for _, q := range batch.Queries {
+			// a page per vulnerability
+			page := 0
+			if q.PageToken != "" {
+				page, _ = strconv.Atoi(q.PageToken)
+				q.PageToken = ""
+			}
+			var res result
+			if ids := vulnerable[q]; page < len(ids) {
+				res.Vulns = []Vulnerability{{ID: ids[page]}}
+				if page+1 < len(ids) {
+					res.NextPageToken = strconv.Itoa(page + 1)
+				}
+			}
+			results = append(results, res)
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{"results": results}))
+	}))
+	defer ts.Close()
+
+	parsedURL, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	client := NewClient(ts.Client(), parsedURL.Host)
+
+	queries := make([]Query, maxBatchSize+1)
+	for i := range queries {
+		queries[i] = Query{Package: Package{Name: "semver", Ecosystem: "npm"}, Version: "7.5.4"}
+	}
+	queries[0] = Query{Package: Package{Name: "stdlib", Ecosystem: "Go"}, Version: "1.20.1"}
+	queries[maxBatchSize] = Query{Package: Package{Name: "requests", Ecosystem: "PyPI"}, Version: "2.30.0"}
+
+	results, err := client.QueryBatch(context.Background(), queries)
+	require.NoError(t, err)
+	assert.Equal(t, 3, requests, "queries are split into batches, and the next pages of results are queried")
+	require.Len(t, results, len(queries))
+	assert.Equal(t, []string{"GO-2023-1621", "GO-2023-1704"}, results[0])
+	assert.Empty(t, results[1])
+	assert.Equal(t, []string{"GHSA-j8r2-6x86-q33q"}, results[maxBatchSize])
+}
+
+func TestVulnerability_Affects(t *testing.T) {
+	stdlib := Package{Name: "stdlib", Ecosystem: "Go"}
+	curl := Package{Name: "curl", Ecosystem: "Wolfi"}
+	v := Vulnerability{Affected: []Affected{
+		{Package: stdlib, Ranges: []Range{{Type: "SEMVER", Events: []map[string]string{
+			{"introduced": "0"}, {"fixed": "1.19.9"}, {"introduced": "1.20.0"}, {"fixed": "1.20.4"},
+		}}}},
+		{Package: curl, Ranges: []Range{{Type: "ECOSYSTEM", Events: []map[string]string{
+			{"introduced": "7.69.0-r0"}, {"last_affected": "8.3.0-r0"},
+		}}}, Versions: []string{"7.0.0-r0"}},
+	}}
+	compare := func(a, b string) int {
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	}
+
+	for _, tt := range []struct {
+		pkg      Package
+		version  string
+		affected bool
+	}{
+		{stdlib, "1.18.0", true},
+		{stdlib, "1.19.9", false},
+		{stdlib, "1.20.0", true},
+		{stdlib, "1.20.3", true},
+		{stdlib, "1.20.4", false},
+		{stdlib, "1.21.0", false},
+		{curl, "7.0.0-r0", true},
+		{curl, "7.68.0-r0", false},
+		{curl, "8.3.0-r0", true},
+		{curl, "8.4.0-r0", false},
+		{Package{Name: "curl", Ecosystem: "Alpine"}, "8.3.0-r0", false},
+	} {
+		assert.Equal(t, tt.affected, v.Affects(tt.pkg, tt.version, compare), "%s %s", tt.pkg.Name, tt.version)
+	}
+
+	assert.False(t, v.Affects(curl, "8.3.0-r0", nil), "ECOSYSTEM ranges are ignored without a comparator")
+	assert.True(t, v.Affects(curl, "7.0.0-r0", nil), "listed versions are still affected")
+}
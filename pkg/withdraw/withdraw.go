@@ -0,0 +1,186 @@
+// Package withdraw records package versions withdrawn from a repository, e.g. because their artifacts are broken or
+// vulnerable, and strips them from its index when it's published.
+package withdraw
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gitlab.alpinelinux.org/alpine/go/repository"
+
+	"github.com/wolfi-dev/wolfictl/pkg/dag"
+	"github.com/wolfi-dev/wolfictl/pkg/index"
+)
+
+// DefaultFileName is the name of the file withdrawn packages are recorded in, at the root of the distro repository.
+const DefaultFileName = "withdrawn-packages.txt"
+
+// Withdrawal is a withdrawn package version.
+type Withdrawal struct {
+	// Package is the name and version of the package, like curl-8.1.0-r0.
+	Package string
+	// Reason is why the package was withdrawn, if it was given.
+	Reason string
+}
+
+func (w Withdrawal) String() string {
+	if w.Reason == "" {
+		return w.Package
+	}
+	return fmt.Sprintf("%s # %s", w.Package, w.Reason)
+}
+
+var packageVersion = regexp.MustCompile(`^[^\s#]+-[^-\s#]+-r\d+$`)
+
+// ParsePackage returns the withdrawal of a package version given as name-version, like curl-8.1.0-r0.
+func ParsePackage(s, reason string) (Withdrawal, error) {
+	if !packageVersion.MatchString(s) {
+		return Withdrawal{}, fmt.Errorf("%q is not a package version, like curl-8.1.0-r0", s)
+	}
+	return Withdrawal{Package: s, Reason: strings.TrimSpace(reason)}, nil
+}
+
+// Parse reads the withdrawals of a withdrawn packages file, which has a package version per line, optionally followed
+// by a comment with the reason it was withdrawn. Blank lines and lines with only a comment are ignored.
+func Parse(r io.Reader) ([]Withdrawal, error) {
+	var withdrawals []Withdrawal
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line, reason, _ := strings.Cut(s.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		w, err := ParsePackage(line, reason)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		withdrawals = append(withdrawals, w)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return withdrawals, nil
+}
+
+// Write writes the withdrawals as a withdrawn packages file, sorted, for Parse to read.
+func Write(w io.Writer, withdrawals []Withdrawal) error {
+	sorted := make([]Withdrawal, len(withdrawals))
+	copy(sorted, withdrawals)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Package < sorted[j].Package
+	})
+	for _, wd := range sorted {
+		if _, err := fmt.Fprintln(w, wd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add returns the withdrawals with the added ones, unless they're withdrawn already.
+func Add(withdrawals []Withdrawal, added ...Withdrawal) []Withdrawal {
+	for _, a := range added {
+		if !IsWithdrawn(withdrawals, a.Package) {
+			withdrawals = append(withdrawals, a)
+		}
+	}
+	return withdrawals
+}
+
+// IsWithdrawn reports whether the package version, like curl-8.1.0-r0, is withdrawn.
+func IsWithdrawn(withdrawals []Withdrawal, pkg string) bool {
+	for _, w := range withdrawals {
+		if w.Package == pkg {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the index without the withdrawn packages, and the packages that were removed from it.
+func Filter(idx *repository.ApkIndex, withdrawals []Withdrawal) (filtered *repository.ApkIndex, removed []*repository.Package) {
+	// the signature of the index doesn't cover the filtered one, it has to be signed again
+	filtered = &repository.ApkIndex{Description: idx.Description}
+	for _, pkg := range idx.Packages {
+		if IsWithdrawn(withdrawals, key(pkg)) {
+			removed = append(removed, pkg)
+			continue
+		}
+		filtered.Packages = append(filtered.Packages, pkg)
+	}
+	index.Sort(filtered)
+	return filtered, removed
+}
+
+// Dependent is a dependency of a package of a filtered index that only withdrawn packages provided.
+type Dependent struct {
+	Package    string
+	Dependency string
+	Withdrawn  []string
+}
+
+// DependentsError is returned by Verify when packages of a filtered index depend on withdrawn packages.
+type DependentsError struct {
+	Dependents []Dependent
+}
+
+func (e *DependentsError) Error() string {
+	deps := make([]string, 0, len(e.Dependents))
+	for _, d := range e.Dependents {
+		deps = append(deps, fmt.Sprintf("%s depends on %s, only provided by %s", d.Package, d.Dependency, strings.Join(d.Withdrawn, ", ")))
+	}
+	return fmt.Sprintf("%d dependencies of the remaining packages are only provided by withdrawn packages: %s", len(e.Dependents), strings.Join(deps, "; "))
+}
+
+// Verify checks that no package of the filtered index depends on a withdrawn package, i.e. that every dependency
+// that a removed package satisfied is still satisfied by a remaining one. Dependencies that nothing satisfied to begin
+// with aren't the business of withdrawing packages, and are ignored. It returns a *DependentsError otherwise.
+func Verify(filtered *repository.ApkIndex, removed []*repository.Package) error {
+	removedIdx := &repository.ApkIndex{Packages: removed}
+
+	var dependents []Dependent
+	for _, pkg := range filtered.Packages {
+		for _, dep := range pkg.Dependencies {
+			if strings.HasPrefix(dep, "!") {
+				// a conflict, not a dependency
+				continue
+			}
+			c, err := dag.ParseConstraint(dep)
+			if err != nil {
+				return fmt.Errorf("parsing dependency %q of %s: %w", dep, key(pkg), err)
+			}
+			if len(dag.WhoProvidesInIndex(filtered, c)) > 0 {
+				continue
+			}
+			providers := dag.WhoProvidesInIndex(removedIdx, c)
+			if len(providers) == 0 {
+				continue
+			}
+			d := Dependent{Package: key(pkg), Dependency: dep}
+			for _, p := range providers {
+				d.Withdrawn = append(d.Withdrawn, key(p))
+			}
+			sort.Strings(d.Withdrawn)
+			dependents = append(dependents, d)
+		}
+	}
+	if len(dependents) > 0 {
+		sort.Slice(dependents, func(i, j int) bool {
+			if dependents[i].Package == dependents[j].Package {
+				return dependents[i].Dependency < dependents[j].Dependency
+			}
+			return dependents[i].Package < dependents[j].Package
+		})
+		return &DependentsError{Dependents: dependents}
+	}
+	return nil
+}
+
+func key(pkg *repository.Package) string {
+	return fmt.Sprintf("%s-%s", pkg.Name, pkg.Version)
+}
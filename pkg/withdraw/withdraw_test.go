@@ -0,0 +1,66 @@
+package withdraw
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.alpinelinux.org/alpine/go/repository"
+)
+
+func names(pkgs []*repository.Package) []string {
+	out := make([]string, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		out = append(out, key(pkg))
+	}
+	return out
+}
+
+func TestParse(t *testing.T) {
+	withdrawals, err := Parse(strings.NewReader(`# withdrawn packages
+
+curl-8.1.0-r0 # broken TLS
+py3-foo-bar-1.0.0_rc1-r2
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []Withdrawal{
+		{Package: "curl-8.1.0-r0", Reason: "broken TLS"},
+		{Package: "py3-foo-bar-1.0.0_rc1-r2"},
+	}, withdrawals)
+
+	_, err = Parse(strings.NewReader("curl-8.1.0-r0\ncurl\n"))
+	assert.ErrorContains(t, err, `line 2: "curl" is not a package version`)
+
+	withdrawals = Add(withdrawals, Withdrawal{Package: "curl-8.1.0-r0"}, Withdrawal{Package: "apk-tools-2.14.0-r0", Reason: "corrupt"})
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, withdrawals))
+	assert.Equal(t, "apk-tools-2.14.0-r0 # corrupt\ncurl-8.1.0-r0 # broken TLS\npy3-foo-bar-1.0.0_rc1-r2\n", buf.String())
+}
+
+func TestFilterAndVerify(t *testing.T) {
+	idx := &repository.ApkIndex{Packages: []*repository.Package{
+		{Name: "curl", Version: "8.1.0-r0", Dependencies: []string{"so:libcurl.so.4", "so:libmissing.so.1"}},
+		{Name: "libcurl4", Version: "8.0.1-r0", Provides: []string{"so:libcurl.so.4=4"}},
+		{Name: "libcurl4", Version: "8.1.0-r0", Provides: []string{"so:libcurl.so.4=4"}},
+		{Name: "git", Version: "2.41.0-r0", Dependencies: []string{"openssl>=3.1", "!git-minimal"}},
+		{Name: "openssl", Version: "3.1.0-r0"},
+	}}
+
+	withdrawals := []Withdrawal{{Package: "libcurl4-8.1.0-r0"}}
+	filtered, removed := Filter(idx, withdrawals)
+	assert.Equal(t, []string{"libcurl4-8.1.0-r0"}, names(removed))
+	assert.Len(t, filtered.Packages, 4)
+	assert.NoError(t, Verify(filtered, removed), "an older libcurl4 still provides so:libcurl.so.4, and so:libmissing.so.1 was never provided")
+
+	withdrawals = append(withdrawals, Withdrawal{Package: "libcurl4-8.0.1-r0"}, Withdrawal{Package: "openssl-3.1.0-r0"})
+	filtered, removed = Filter(idx, withdrawals)
+	err := Verify(filtered, removed)
+	var dependents *DependentsError
+	require.ErrorAs(t, err, &dependents)
+	assert.Equal(t, []Dependent{
+		{Package: "curl-8.1.0-r0", Dependency: "so:libcurl.so.4", Withdrawn: []string{"libcurl4-8.0.1-r0", "libcurl4-8.1.0-r0"}},
+		{Package: "git-2.41.0-r0", Dependency: "openssl>=3.1", Withdrawn: []string{"openssl-3.1.0-r0"}},
+	}, dependents.Dependents)
+}